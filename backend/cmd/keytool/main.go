@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+	"github.com/alpyxn/aeterna/backend/internal/logging"
 	"github.com/alpyxn/aeterna/backend/internal/services"
 )
 
@@ -20,6 +22,8 @@ func main() {
 		handleGenerate()
 	case "validate":
 		handleValidate()
+	case "decrypt-log":
+		handleDecryptLog(os.Args[2:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
 		printUsage()
@@ -35,6 +39,7 @@ Usage: keytool <command>
 Commands:
   generate    Generate a new encryption key (outputs to stdout)
   validate    Test key retrieval from configured source
+  decrypt-log Print an encrypted log backup (LOG_ENCRYPT_BACKUPS) to stdout
 
 Examples:
   # Generate a new key and save to file
@@ -43,6 +48,9 @@ Examples:
 
   # Test key retrieval (tries Docker secrets, then file)
   keytool validate
+
+  # Read an encrypted log backup, with the key from Docker secrets or a file
+  keytool decrypt-log /var/log/aeterna-2024-01-02T03-04-05.000.log.gz [/secure/path/to/key]
 `)
 }
 
@@ -84,3 +92,28 @@ func handleValidate() {
 	fmt.Println("Key validation successful")
 	fmt.Println("Encryption and decryption working correctly")
 }
+
+func handleDecryptLog(args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintf(os.Stderr, "Usage: keytool decrypt-log <backup-file> [key-file]\n")
+		os.Exit(1)
+	}
+	keyFile := ""
+	if len(args) == 2 {
+		keyFile = args[1]
+	}
+	services.SetAllowInsecureKeyPerms(common.GetBool("ALLOW_INSECURE_KEY_PERMS", false))
+	services.InitKeyManager(keyFile)
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading log backup: %v\n", err)
+		os.Exit(1)
+	}
+	plain, err := logging.DecryptBackup(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error decrypting log backup: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(plain)
+}
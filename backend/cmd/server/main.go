@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -23,13 +24,28 @@ import (
 )
 
 func main() {
+	startedAt := time.Now()
 	encryptionKeyFile := flag.String("encryption-key-file", "", "Path to file containing encryption key (fallback, must have 0600 permissions)")
 	flag.Parse()
 	cfg := config.Load()
 
 	logging.Init(cfg)
 
-	services.InitKeyManager(*encryptionKeyFile)
+	checkClockSkew(cfg)
+
+	// --encryption-key-file wins; otherwise pick up DATA_DIR/secrets/encryption_key if present.
+	keyFile := *encryptionKeyFile
+	if keyFile == "" && cfg.Database.EncryptionKeyFile != "" {
+		if _, err := os.Stat(cfg.Database.EncryptionKeyFile); err == nil {
+			keyFile = cfg.Database.EncryptionKeyFile
+		}
+	}
+	services.SetAllowInsecureKeyPerms(cfg.Database.AllowInsecureKeyPerms)
+	services.InitKeyManager(keyFile)
+	if err := services.SetEncryptionAlgorithm(cfg.Database.EncryptionAlgorithm); err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+	services.SetStrictCiphertextBinding(cfg.Database.StrictCiphertextBinding)
 
 	cryptoSvc := services.CryptoService{}
 	_, err := cryptoSvc.Encrypt("test")
@@ -41,6 +57,7 @@ func main() {
 			"\n"+
 			"For more information, see: https://github.com/alpyxn/aeterna/blob/main/README.md", err)
 	}
+	services.EnableRecipientEncryption()
 
 	sqliteEnc := database.SQLiteEncryptionConfig{
 		Enabled:     cfg.Database.EncryptionEnabled,
@@ -71,6 +88,8 @@ func main() {
 	if err := database.DB.AutoMigrate(
 		&models.User{},
 		&models.RefreshSession{},
+		&models.RecoveryKey{},
+		&models.AutomationToken{},
 		&models.Message{},
 		&models.MessageReminder{},
 		&models.Settings{},
@@ -79,6 +98,9 @@ func main() {
 		&models.ApplicationSettings{},
 		&models.FarewellLetter{},
 		&models.FarewellAttachment{},
+		&models.StatusTransition{},
+		&models.MessageTombstone{},
+		&models.DeliveredAttachment{},
 	); err != nil {
 		log.Fatal("Failed to migrate database: ", err)
 	}
@@ -106,13 +128,26 @@ func main() {
 	database.DB.Exec("UPDATE farewell_letters SET encrypted_rendered_html = '' WHERE encrypted_rendered_html IS NULL;")
 	database.DB.Exec("UPDATE farewell_letters SET derivatives_pending = 1 WHERE derivatives_pending IS NULL;")
 
-	if err := services.EnsureUploadsDir(cfg.Database.Path); err != nil {
+	if err := services.BindLegacyCiphertexts(); err != nil {
+		slog.Error("Failed to bind legacy ciphertext to records", "error", err)
+	}
+
+	if err := services.EnsureUploadsDir(cfg.Database); err != nil {
 		log.Fatal("Failed to create uploads directory: ", err)
 	}
 
 	// --- Composition root: wire services ---
 	authSvc := services.NewAuthService(cfg)
-	messageSvc := services.MessageService{}
+	recoveryKey, seededUser, err := authSvc.SeedFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure account from SETUP_EMAIL: ", err)
+	}
+	if recoveryKey != "" {
+		// The key goes in the message rather than an attribute so LOG_REDACT
+		// does not truncate it; it is never shown again.
+		slog.Warn("Account configured from environment. Store this recovery key now: "+recoveryKey, "user_id", seededUser.ID)
+	}
+	messageSvc := services.NewMessageService(cfg)
 	fileSvc := services.NewFileService(cfg)
 	farewellSvc := services.FarewellService{}
 	settingsSvc := services.NewSettingsService(cfg)
@@ -123,28 +158,53 @@ func main() {
 	eventStreamSvc := services.NewEventStreamService()
 
 	// Decorate mutating services with event emission.
-	messageSvcWithEvents := services.NewNotifyingMessageService(messageSvc, eventStreamSvc)
+	messageSvcWithEvents := services.NewNotifyingMessageService(
+		services.NewConfirmingMessageService(messageSvc, services.NewHeartbeatConfirmationService(cfg, settingsSvc)),
+		eventStreamSvc,
+	)
 	fileSvcWithEvents := services.NewNotifyingFileService(fileSvc, eventStreamSvc)
 	farewellSvcWithEvents := services.NewNotifyingFarewellService(farewellSvc, eventStreamSvc)
 	settingsSvcWithEvents := services.NewNotifyingSettingsService(settingsSvc, eventStreamSvc)
 	webhookStoreWithEvents := services.NewNotifyingWebhookStore(webhookStore, eventStreamSvc)
 
+	originAllowlist := services.NewOriginAllowlist(cfg.AllowedOriginsOrDefault())
+	if err := originAllowlist.Reload(); err != nil {
+		log.Fatal("Failed to load allowed origins: ", err)
+	}
+
 	// --- Wire handlers ---
-	authH := handlers.NewAuthHandlers(authSvc, cfg)
+	authH := handlers.NewAuthHandlers(authSvc, services.NewLockoutAlertService(cfg, settingsSvc), cfg)
 	messageH := handlers.NewMessageHandlers(messageSvcWithEvents)
-	heartbeatH := handlers.NewHeartbeatHandlers(messageSvcWithEvents, settingsSvc)
+	heartbeatPage, err := handlers.LoadHeartbeatTemplate(cfg.Worker.HeartbeatTemplate)
+	if err != nil {
+		log.Fatalf("Failed to load HEARTBEAT_TEMPLATE: %v", err)
+	}
+	heartbeatH := handlers.NewHeartbeatHandlers(messageSvcWithEvents, settingsSvcWithEvents, heartbeatPage)
 	attachH := handlers.NewAttachmentHandlers(fileSvcWithEvents)
-	settingsH := handlers.NewSettingsHandlers(settingsSvcWithEvents, appSettingsSvc)
-	webhookH := handlers.NewWebhookHandlers(webhookStoreWithEvents)
+	settingsH := handlers.NewSettingsHandlers(settingsSvcWithEvents, appSettingsSvc, originAllowlist)
+	webhookH := handlers.NewWebhookHandlers(webhookStoreWithEvents, services.NewWebhookAllowlistService(cfg))
 	farewellH := handlers.NewFarewellHandlers(farewellSvcWithEvents, fileSvcWithEvents)
 	usersH := handlers.NewUserHandlers(userAdminSvc)
+	recoveryKeysH := handlers.NewRecoveryKeyHandlers(services.NewRecoveryKeyService(cfg))
+	automationTokenSvc := services.NewAutomationTokenService(cfg)
+	automationTokensH := handlers.NewAutomationTokenHandlers(automationTokenSvc)
 	eventsH := handlers.NewEventsHandlers(eventStreamSvc)
 
 	// --- Wire worker ---
-	w := worker.New(settingsSvc, webhookStore, fileSvc, farewellDerivationSvc, cfg)
+	w := worker.New(settingsSvc, webhookStore, fileSvc, farewellDerivationSvc, messageSvcWithEvents, cfg)
+	slog.Info("SMTP retry policy", "max_attempts", cfg.SMTP.MaxAttempts, "retry_base_ms", cfg.SMTP.RetryBaseMS)
+
+	statusH := handlers.NewStatusHandlers(w, startedAt)
+	simulateH := handlers.NewSimulationHandlers(w)
 
 	app := fiber.New(fiber.Config{
-		BodyLimit: 25 * 1024 * 1024,
+		BodyLimit:    25 * 1024 * 1024,
+		ErrorHandler: handlers.ErrorHandler,
+		// c.IP() believes X-Real-IP only from TRUSTED_PROXIES.
+		EnableTrustedProxyCheck: len(cfg.HTTP.TrustedProxies) > 0,
+		TrustedProxies:          cfg.HTTP.TrustedProxies,
+		ProxyHeader:             proxyHeader(cfg),
+		EnableIPValidation:      true,
 	})
 
 	app.Use(handlers.AttachRuntimeFlags(cfg.IsProduction()))
@@ -154,35 +214,36 @@ func main() {
 	}))
 	app.Use(middleware.SecurityHeaders(cfg))
 
-	allowedOrigins := cfg.AllowedOriginsOrDefault()
-
-	if allowedOrigins == "*" {
-		app.Use(cors.New(cors.Config{
-			AllowOriginsFunc: func(origin string) bool { return true },
-			AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-			AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
-			AllowCredentials: true,
-		}))
-	} else {
-		app.Use(cors.New(cors.Config{
-			AllowOrigins:     allowedOrigins,
-			AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-			AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
-			AllowCredentials: true,
-		}))
-	}
+	// Origins are matched per request so changes saved via settings apply without a restart.
+	app.Use(cors.New(cors.Config{
+		AllowOriginsFunc: func(origin string) bool {
+			return middleware.OriginAllowed(origin, originAllowlist.AllowedOrigins(), cfg.HTTP.AllowedOriginsIgnorePort)
+		},
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+		AllowCredentials: true,
+	}))
 
 	app.Use(limiter.New(limiter.Config{
-		Max:        120,
-		Expiration: 1 * time.Minute,
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(429).JSON(fiber.Map{
-				"error": "Too many requests",
-				"code":  "rate_limited",
-			})
-		},
+		Max:          120,
+		Expiration:   1 * time.Minute,
+		LimitReached: middleware.RateLimitReached,
 	}))
 
+	if cfg.HTTP.RequestTimeoutSeconds > 0 {
+		app.Use(middleware.RequestTimeout(time.Duration(cfg.HTTP.RequestTimeoutSeconds) * time.Second))
+	}
+
+	if cfg.App.MaintenanceMode {
+		slog.Warn("Maintenance mode is on: the API is read-only apart from check-ins and the worker is paused")
+		app.Use(middleware.Maintenance())
+	}
+
+	// Reveal is throttled to slow message ID enumeration, setup because it
+	// is a one-time action; api and api/v2 share each group's counters.
+	revealLimiter := middleware.RouteRateLimiter(cfg.HTTP.RevealRateLimitPerMinute)
+	setupLimiter := middleware.RouteRateLimiter(cfg.HTTP.SetupRateLimitPerMinute)
+
 	// Note: CSRF protection is provided by SameSite=Strict cookies.
 	// SameSite=Strict is stronger than Lax and prevents CSRF for same-site origins.
 
@@ -190,42 +251,114 @@ func main() {
 	apiV2 := app.Group("/api/v2")
 
 	// Public routes
-	api.Get("/messages/:id", messageH.GetPublic)
+	api.Get("/messages/:id", revealLimiter, messageH.GetPublic)
+	api.Get("/messages/:id/reveal/attachments", revealLimiter, attachH.ListRevealed)
+	api.Get("/messages/:id/reveal/attachments/:attachmentId", revealLimiter, attachH.DownloadRevealed)
+	api.Get("/status", statusH.Status)
+	api.Get("/status/worker", statusH.WorkerHealth)
 	api.Get("/setup/status", authH.SetupStatus)
-	api.Post("/setup", authH.SetupMasterPassword)
+	api.Post("/setup", setupLimiter, authH.SetupMasterPassword)
 	api.Post("/auth/register", middleware.AuthRateLimiter, authH.Register)
 	api.Post("/auth/login", middleware.AuthRateLimiter, authH.Login)
 	api.Post("/auth/verify", middleware.AuthRateLimiter, authH.VerifyMasterPassword)
 	api.Post("/auth/reset-password", middleware.AuthRateLimiter, authH.ResetMasterPassword)
 	api.Get("/auth/session", authH.SessionStatus)
 	api.Post("/auth/logout", authH.Logout)
-	api.Get("/quick-heartbeat/:token", heartbeatH.QuickHeartbeat)
-	api.Post("/quick-heartbeat/:token", heartbeatH.QuickHeartbeat)
+	registerQuickHeartbeatRoutes(api, heartbeatH)
+	api.Get("/m/:managementToken", messageH.GetManaged)
+	api.Put("/m/:managementToken", messageH.UpdateManaged)
+	api.Delete("/m/:managementToken", messageH.DeleteManaged)
 
 	// Public routes (v2, token-oriented for mobile clients)
-	apiV2.Get("/messages/:id", messageH.GetPublic)
+	apiV2.Get("/messages/:id", revealLimiter, messageH.GetPublic)
+	apiV2.Get("/messages/:id/reveal/attachments", revealLimiter, attachH.ListRevealed)
+	apiV2.Get("/messages/:id/reveal/attachments/:attachmentId", revealLimiter, attachH.DownloadRevealed)
+	apiV2.Get("/status", statusH.Status)
+	apiV2.Get("/status/worker", statusH.WorkerHealth)
 	apiV2.Get("/setup/status", authH.SetupStatus)
-	apiV2.Post("/setup", authH.SetupMasterPasswordV2)
+	apiV2.Post("/setup", setupLimiter, authH.SetupMasterPasswordV2)
 	apiV2.Post("/auth/register", middleware.AuthRateLimiter, authH.RegisterV2)
 	apiV2.Post("/auth/login", middleware.AuthRateLimiter, authH.LoginV2)
 	apiV2.Post("/auth/reset-password", middleware.AuthRateLimiter, authH.ResetMasterPasswordV2)
 	apiV2.Get("/auth/session", authH.SessionStatusV2)
 	apiV2.Post("/auth/refresh", middleware.AuthRateLimiter, authH.RefreshV2)
 	apiV2.Post("/auth/logout", authH.LogoutV2)
+	registerQuickHeartbeatRoutes(apiV2, heartbeatH)
+	apiV2.Get("/m/:managementToken", messageH.GetManaged)
+	apiV2.Put("/m/:managementToken", messageH.UpdateManaged)
+	apiV2.Delete("/m/:managementToken", messageH.DeleteManaged)
+
+	// Automation tokens only reach heartbeat; other requests fall through to
+	// the session-protected route of the same path.
+	api.Post("/heartbeat", middleware.AutomationHeartbeat(automationTokenSvc, messageH.Heartbeat))
+	apiV2.Post("/heartbeat", middleware.AutomationHeartbeat(automationTokenSvc, messageH.Heartbeat))
 
 	// Protected routes
-	mgmt := api.Group("/", middleware.MasterAuth(authSvc, cfg))
-	registerProtectedRoutes(mgmt, messageH, attachH, farewellH, webhookH, settingsH, heartbeatH, usersH, eventsH)
+	mgmtIPs := middleware.IPAllowlist(cfg.HTTP.MgmtIPAllowlist)
+	mgmt := api.Group("/", mgmtIPs, middleware.MasterAuth(authSvc, originAllowlist, cfg))
+	registerProtectedRoutes(mgmt, messageH, attachH, farewellH, webhookH, settingsH, heartbeatH, usersH, recoveryKeysH, automationTokensH, eventsH)
+	mgmt.Get("/status/details", statusH.Details)
 
 	// Protected routes (v2, accepts Authorization: Bearer <token>)
-	mgmtV2 := apiV2.Group("/", middleware.MasterAuthV2(authSvc, cfg))
-	registerProtectedRoutes(mgmtV2, messageH, attachH, farewellH, webhookH, settingsH, heartbeatH, usersH, eventsH)
+	mgmtV2 := apiV2.Group("/", mgmtIPs, middleware.MasterAuthV2(authSvc, originAllowlist, cfg))
+	registerProtectedRoutes(mgmtV2, messageH, attachH, farewellH, webhookH, settingsH, heartbeatH, usersH, recoveryKeysH, automationTokensH, eventsH)
+	mgmtV2.Get("/status/details", statusH.Details)
+
+	// Dry runs of the worker are a debugging aid and stay out of production.
+	if !cfg.IsProduction() {
+		mgmt.Post("/messages/:id/simulate", simulateH.Simulate)
+		mgmtV2.Post("/messages/:id/simulate", simulateH.Simulate)
+	}
 
 	go w.Start()
+	if cfg.Worker.WatchdogMissedTicks > 0 {
+		go worker.NewWatchdog(w, settingsSvc, cfg).Start()
+	}
 
 	log.Fatal(app.Listen(":3000"))
 }
 
+// proxyHeader is the header c.IP() reads behind TRUSTED_PROXIES: X-Real-IP,
+// which the bundled nginx sets to the connecting address, unlike
+// X-Forwarded-For whose first entry the client controls.
+func proxyHeader(cfg config.Config) string {
+	if len(cfg.HTTP.TrustedProxies) == 0 {
+		return ""
+	}
+	return "X-Real-IP"
+}
+
+// checkClockSkew compares the host clock with NTP_SERVER. Triggers and
+// reminders are only as correct as the clock, so a large skew blocks startup
+// in production and is logged loudly elsewhere.
+func checkClockSkew(cfg config.Config) {
+	if cfg.Worker.NTPServer == "" {
+		return
+	}
+	skew, err := services.MeasureClockSkew(cfg.Worker.NTPServer, 5*time.Second)
+	if err != nil {
+		slog.Warn("Clock skew check failed", "ntp_server", cfg.Worker.NTPServer, "error", err)
+		return
+	}
+	limit := time.Duration(cfg.Worker.NTPMaxSkewSeconds) * time.Second
+	if skew <= limit && skew >= -limit {
+		slog.Info("Clock skew within limits", "ntp_server", cfg.Worker.NTPServer, "skew", skew.String())
+		return
+	}
+	if cfg.IsProduction() {
+		log.Fatalf("FATAL: System clock is off by %s compared to %s (limit %s). Fix host time synchronisation before starting.", skew, cfg.Worker.NTPServer, limit)
+	}
+	slog.Error("SYSTEM CLOCK SKEW DETECTED: triggers and reminders will fire at the wrong time", "ntp_server", cfg.Worker.NTPServer, "skew", skew.String(), "limit", limit.String())
+}
+
+// registerQuickHeartbeatRoutes adds the token-authenticated check-in page,
+// its no-JS form post and the one-click confirmation.
+func registerQuickHeartbeatRoutes(group fiber.Router, heartbeatH *handlers.HeartbeatHandlers) {
+	group.Get("/quick-heartbeat/:token", heartbeatH.QuickHeartbeat)
+	group.Post("/quick-heartbeat/:token", heartbeatH.QuickHeartbeat)
+	group.Get("/quick-heartbeat/:token/confirm", heartbeatH.ConfirmQuickHeartbeat)
+}
+
 func registerProtectedRoutes(
 	group fiber.Router,
 	messageH *handlers.MessageHandlers,
@@ -235,17 +368,27 @@ func registerProtectedRoutes(
 	settingsH *handlers.SettingsHandlers,
 	heartbeatH *handlers.HeartbeatHandlers,
 	usersH *handlers.UserHandlers,
+	recoveryKeysH *handlers.RecoveryKeyHandlers,
+	automationTokensH *handlers.AutomationTokenHandlers,
 	eventsH *handlers.EventsHandlers,
 ) {
 	group.Post("/messages", messageH.Create)
 	group.Get("/messages", messageH.List)
+	group.Post("/messages/import", messageH.Import)
+	group.Get("/messages/tombstones", messageH.Tombstones)
 	group.Delete("/messages/:id", messageH.Delete)
 	group.Put("/messages/:id", messageH.Update)
+	group.Post("/messages/:id/rotate-management-token", messageH.RotateManagementToken)
+	group.Get("/messages/:id/content/download", messageH.DownloadContent)
+	group.Get("/messages/:id/transitions", messageH.Transitions)
 	group.Post("/heartbeat", messageH.Heartbeat)
 
 	group.Post("/messages/:id/attachments", attachH.Upload)
 	group.Get("/messages/:id/attachments", attachH.List)
+	group.Put("/messages/:id/attachments/:attachmentId/recipients", attachH.SetRecipients)
 	group.Delete("/messages/:id/attachments/:attachmentId", attachH.Delete)
+	group.Get("/attachments/:id/thumbnail", attachH.Thumbnail)
+	group.Get("/storage", attachH.Storage)
 
 	group.Get("/messages/:id/farewell-letters", farewellH.List)
 	group.Post("/messages/:id/farewell-letters", farewellH.Create)
@@ -258,16 +401,29 @@ func registerProtectedRoutes(
 	group.Delete("/messages/:id/farewell-letters/:letterId/attachments/:attachmentId", farewellH.DeleteAttachment)
 
 	group.Get("/webhooks", webhookH.List)
+	group.Get("/webhooks/allowlist", webhookH.GetAllowlist)
+	group.Put("/webhooks/allowlist", webhookH.SetAllowlist)
 	group.Post("/webhooks", webhookH.Create)
 	group.Put("/webhooks/:id", webhookH.Update)
+	group.Post("/webhooks/:id/test", webhookH.Test)
 	group.Delete("/webhooks/:id", webhookH.Delete)
 
 	group.Get("/settings", settingsH.Get)
 	group.Post("/settings", settingsH.Save)
+	group.Patch("/settings", settingsH.Patch)
 	group.Post("/settings/test", settingsH.TestSMTP)
 	group.Get("/heartbeat-token", heartbeatH.GetToken)
+	group.Post("/heartbeat-token/rotate", heartbeatH.RotateToken)
 
 	group.Get("/users", usersH.List)
 	group.Delete("/users/:id", usersH.Delete)
+
+	group.Get("/recovery-keys", recoveryKeysH.List)
+	group.Post("/recovery-keys", recoveryKeysH.Add)
+	group.Delete("/recovery-keys/:id", recoveryKeysH.Revoke)
+
+	group.Get("/automation-tokens", automationTokensH.List)
+	group.Post("/automation-tokens", automationTokensH.Add)
+	group.Delete("/automation-tokens/:id", automationTokensH.Revoke)
 	group.Get("/events", eventsH.Stream)
 }
@@ -1,17 +1,103 @@
 package common
 
 const (
-	DefaultDatabasePath    = "./data/aeterna.db"
-	DefaultAllowedOrigins  = "http://localhost:5173"
-	DefaultWorkerBaseURL   = "http://localhost:5173"
-	DefaultSessionTTLHours = 168
-	DefaultRefreshTTLHours = 720
-	DefaultLogMaxSize      = 50
-	DefaultLogMaxBackups   = 5
-	DefaultLogMaxAge       = 14
-	DefaultLogCompress     = true
+	DefaultDatabasePath     = "./data/aeterna.db"
+	DefaultDatabaseFilename = "aeterna.db"
+	DefaultAllowedOrigins   = "http://localhost:5173"
+	DefaultWorkerBaseURL    = "http://localhost:5173"
+	DefaultSessionTTLHours  = 168
+	DefaultRefreshTTLHours  = 720
+	DefaultSessionCookie    = "aeterna_session"
+	DefaultLogMaxSize       = 50
+	DefaultLogMaxBackups    = 5
+	DefaultLogMaxAge        = 14
+	DefaultLogCompress      = true
+	DefaultLogRedact        = false
+
+	DefaultLogEncryptBackups = false
+
+	DefaultNTPMaxSkewSeconds    = 60
+	DefaultCreationGraceSeconds = 60
+	MaxAttachmentRetentionDays  = 3650
+
+	DefaultRevealRateLimitPerMinute = 20
+	DefaultSetupRateLimitPerMinute  = 5
+
+	DefaultClamAVTimeoutSeconds = 30
+
+	DefaultRequestTimeoutSeconds = 30
+
+	DefaultAttachmentMediaEnabled   = false
+	DefaultAttachmentMediaMaxFileMB = 20
+	// MaxAttachmentMediaMaxFileMB stays 1 MB under the 25 MB request body
+	// limit, which also has to carry the multipart framing, so a file at the
+	// limit can still be uploaded.
+	MaxAttachmentMediaMaxFileMB = 24
+
+	// TRIGGERED_DELETE_POLICY values. Either way deleting a triggered message
+	// leaves a tombstone; "block" also requires ?force=true.
+	TriggeredDeleteBlock         = "block"
+	TriggeredDeleteAllow         = "allow"
+	DefaultTriggeredDeletePolicy = TriggeredDeleteBlock
+
+	// FILENAME_POLICY values, from least to most rewriting of upload names.
+	FilenamePolicyLenient   = "lenient"
+	FilenamePolicyNormalize = "normalize"
+	FilenamePolicyASCII     = "ascii"
+	DefaultFilenamePolicy   = FilenamePolicyLenient
+
+	DefaultHeartbeatTokenBytes = 32
+	MinHeartbeatTokenBytes     = 16
+
+	DefaultLockoutAlertIntervalMinutes = 60
+
+	DefaultHeartbeatConfirmationIntervalMinutes = 60
+
+	DefaultWebhookMaxConsecutiveFailures = 5
+
+	DefaultSMTPMaxAttempts = 3
+	MaxSMTPMaxAttempts     = 10
+	DefaultSMTPRetryBaseMS = 500
+	MaxSMTPRetryBaseMS     = 60000
+
+	SMTPBodyEncodingAuto            = "auto"
+	SMTPBodyEncodingQuotedPrintable = "quoted-printable"
+	SMTPBodyEncodingBase64          = "base64"
+	DefaultSMTPBodyEncoding         = SMTPBodyEncodingAuto
+
+	// DefaultSMTPSizeWarningKB sits just under the ~102 KB at which Gmail
+	// clips a message.
+	DefaultSMTPSizeWarningKB      = 100
+	SMTPLargeContentInline        = "inline"
+	SMTPLargeContentText          = "text"
+	SMTPLargeContentPDF           = "pdf"
+	DefaultSMTPLargeContentAction = SMTPLargeContentInline
+
+	// DefaultFromName is the email display name when neither the account's
+	// smtp_from_name nor its owner_name is set.
+	DefaultFromName = "Aeterna"
+
+	UndeliverableActionHold    = "hold"
+	UndeliverableActionError   = "error"
+	UndeliverableActionTrigger = "trigger"
+	DefaultUndeliverableAction = UndeliverableActionHold
+
+	PasswordPolicyClasses   = "classes"
+	PasswordPolicyEntropy   = "entropy"
+	DefaultPasswordMinScore = 3
+	MaxPasswordMinScore     = 4
+
+	RecoveryKeyFormatHex      = "hex"
+	RecoveryKeyFormatMnemonic = "mnemonic"
+	DefaultRecoveryKeyBytes   = 10
+	MinRecoveryKeyBytes       = 10
+	MaxRecoveryKeyBytes       = 32
 
 	DefaultDBEncryptionEnabled        = false
 	DefaultDBEncryptionAutoMigrate    = true
 	DefaultDBEncryptionKDFContextFile = "./secrets/db_kdf_context"
+
+	EncryptionAlgorithmAESGCM   = "aes-256-gcm"
+	EncryptionAlgorithmChaCha20 = "chacha20-poly1305"
+	DefaultEncryptionAlgorithm  = EncryptionAlgorithmAESGCM
 )
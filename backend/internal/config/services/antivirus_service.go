@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+)
+
+type AntivirusModule struct{}
+
+func (AntivirusModule) Name() string { return "AntivirusModule" }
+func (AntivirusModule) Section() string {
+	return "antivirus"
+}
+
+func init() {
+	common.Register(AntivirusModule{})
+}
+
+// AntivirusSection configures the optional clamd integration. An empty
+// ClamAVAddr disables scanning.
+type AntivirusSection struct {
+	ClamAVAddr           string
+	ClamAVTimeoutSeconds int
+}
+
+func (AntivirusModule) LoadAndValidate() (AntivirusSection, error) {
+	section := AntivirusSection{
+		ClamAVAddr:           common.GetenvTrim("CLAMAV_ADDR"),
+		ClamAVTimeoutSeconds: common.GetPositiveInt("CLAMAV_TIMEOUT_SECONDS", common.DefaultClamAVTimeoutSeconds),
+	}
+	if section.ClamAVAddr != "" && !strings.HasPrefix(section.ClamAVAddr, "unix:") {
+		if _, _, err := net.SplitHostPort(section.ClamAVAddr); err != nil {
+			return AntivirusSection{}, fmt.Errorf("CLAMAV_ADDR must be host:port or unix:/path/to/clamd.sock: %w", err)
+		}
+	}
+	return section, nil
+}
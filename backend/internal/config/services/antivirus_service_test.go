@@ -0,0 +1,50 @@
+package services
+
+import "testing"
+
+func TestAntivirusModule_Metadata(t *testing.T) {
+	m := AntivirusModule{}
+	if got := m.Name(); got != "AntivirusModule" {
+		t.Fatalf("Name() = %q, want %q", got, "AntivirusModule")
+	}
+	if got := m.Section(); got != "antivirus" {
+		t.Fatalf("Section() = %q, want %q", got, "antivirus")
+	}
+}
+
+func TestAntivirusModule_LoadAndValidate(t *testing.T) {
+	t.Run("unset CLAMAV_ADDR disables scanning", func(t *testing.T) {
+		t.Setenv("CLAMAV_ADDR", "")
+		t.Setenv("CLAMAV_TIMEOUT_SECONDS", "")
+		section, err := AntivirusModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.ClamAVAddr != "" {
+			t.Fatalf("ClamAVAddr = %q, want empty", section.ClamAVAddr)
+		}
+		if section.ClamAVTimeoutSeconds != 30 {
+			t.Fatalf("ClamAVTimeoutSeconds = %d, want 30", section.ClamAVTimeoutSeconds)
+		}
+	})
+
+	t.Run("tcp and unix addresses", func(t *testing.T) {
+		for _, addr := range []string{"clamav:3310", "unix:/run/clamav/clamd.ctl"} {
+			t.Setenv("CLAMAV_ADDR", addr)
+			section, err := AntivirusModule{}.LoadAndValidate()
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", addr, err)
+			}
+			if section.ClamAVAddr != addr {
+				t.Fatalf("ClamAVAddr = %q, want %q", section.ClamAVAddr, addr)
+			}
+		}
+	})
+
+	t.Run("address without port is rejected", func(t *testing.T) {
+		t.Setenv("CLAMAV_ADDR", "clamav")
+		if _, err := (AntivirusModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for CLAMAV_ADDR without a port")
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
 )
 
@@ -17,10 +20,34 @@ func init() {
 
 type AppSection struct {
 	Env string
+	// MaxMessages caps the switches each account may hold; 0 means no limit.
+	MaxMessages int
+	// ValidateRecipientMX rejects recipients whose domain has no MX records.
+	ValidateRecipientMX bool
+	// MaintenanceMode makes the API read-only, apart from check-ins, and pauses
+	// the worker.
+	MaintenanceMode bool
+	// TriggeredDeletePolicy is "block" when deleting a delivered message
+	// needs ?force=true, or "allow" when it does not.
+	TriggeredDeletePolicy string
 }
 
 func (AppModule) LoadAndValidate() (AppSection, error) {
+	maxMessages := common.GetInt("MAX_MESSAGES", 0)
+	if maxMessages < 0 {
+		return AppSection{}, fmt.Errorf("MAX_MESSAGES must not be negative")
+	}
+	triggeredDelete := strings.ToLower(common.WithDefault(common.GetenvTrim("TRIGGERED_DELETE_POLICY"), common.DefaultTriggeredDeletePolicy))
+	if triggeredDelete != common.TriggeredDeleteBlock && triggeredDelete != common.TriggeredDeleteAllow {
+		return AppSection{}, fmt.Errorf("TRIGGERED_DELETE_POLICY must be %q or %q", common.TriggeredDeleteBlock, common.TriggeredDeleteAllow)
+	}
 	return AppSection{
-		Env: common.GetenvTrim("ENV"),
+		Env:         common.GetenvTrim("ENV"),
+		MaxMessages: maxMessages,
+
+		ValidateRecipientMX: common.GetBool("VALIDATE_RECIPIENT_MX", false),
+		MaintenanceMode:     common.GetBool("MAINTENANCE_MODE", false),
+
+		TriggeredDeletePolicy: triggeredDelete,
 	}, nil
 }
@@ -38,4 +38,89 @@ func TestAppModule_LoadAndValidate(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("MAX_MESSAGES", func(t *testing.T) {
+		t.Setenv("MAX_MESSAGES", "")
+		section, err := AppModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MaxMessages != 0 {
+			t.Fatalf("MaxMessages = %d, want 0 (unlimited) by default", section.MaxMessages)
+		}
+
+		t.Setenv("MAX_MESSAGES", "25")
+		section, err = AppModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MaxMessages != 25 {
+			t.Fatalf("MaxMessages = %d, want 25", section.MaxMessages)
+		}
+
+		t.Setenv("MAX_MESSAGES", "-1")
+		if _, err := (AppModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for negative MAX_MESSAGES")
+		}
+	})
+
+	t.Run("VALIDATE_RECIPIENT_MX", func(t *testing.T) {
+		t.Setenv("MAX_MESSAGES", "")
+		t.Setenv("VALIDATE_RECIPIENT_MX", "")
+		section, err := AppModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.ValidateRecipientMX {
+			t.Fatal("ValidateRecipientMX = true, want false by default")
+		}
+
+		t.Setenv("VALIDATE_RECIPIENT_MX", "true")
+		section, err = AppModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !section.ValidateRecipientMX {
+			t.Fatal("ValidateRecipientMX = false, want true")
+		}
+	})
+	t.Run("MAINTENANCE_MODE", func(t *testing.T) {
+		t.Setenv("MAX_MESSAGES", "")
+		t.Setenv("MAINTENANCE_MODE", "")
+		section, err := AppModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MaintenanceMode {
+			t.Fatal("MaintenanceMode = true, want false by default")
+		}
+
+		t.Setenv("MAINTENANCE_MODE", "true")
+		section, err = AppModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !section.MaintenanceMode {
+			t.Fatal("MaintenanceMode = false, want true")
+		}
+	})
+	t.Run("TRIGGERED_DELETE_POLICY", func(t *testing.T) {
+		t.Setenv("MAX_MESSAGES", "")
+		t.Setenv("TRIGGERED_DELETE_POLICY", "")
+		section, err := AppModule{}.LoadAndValidate()
+		if err != nil || section.TriggeredDeletePolicy != "block" {
+			t.Fatalf("got %q (%v), want block by default", section.TriggeredDeletePolicy, err)
+		}
+
+		t.Setenv("TRIGGERED_DELETE_POLICY", "Allow")
+		section, err = AppModule{}.LoadAndValidate()
+		if err != nil || section.TriggeredDeletePolicy != "allow" {
+			t.Fatalf("got %q (%v), want allow", section.TriggeredDeletePolicy, err)
+		}
+
+		t.Setenv("TRIGGERED_DELETE_POLICY", "never")
+		if _, err := (AppModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for unknown TRIGGERED_DELETE_POLICY")
+		}
+	})
 }
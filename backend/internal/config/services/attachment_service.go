@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+)
+
+type AttachmentModule struct{}
+
+func (AttachmentModule) Name() string { return "AttachmentModule" }
+func (AttachmentModule) Section() string {
+	return "attachment"
+}
+
+func init() {
+	common.Register(AttachmentModule{})
+}
+
+// AttachmentSection configures switch attachments. Audio and video files are
+// only accepted when MediaEnabled is set, and may then be up to
+// MediaMaxFileMB each. MaxTotalStorageMB caps the attachments stored by all
+// accounts together; 0 leaves them uncapped. FilenamePolicy decides how far
+// upload filenames are rewritten beyond the usual sanitising.
+type AttachmentSection struct {
+	MediaEnabled      bool
+	MediaMaxFileMB    int
+	MaxTotalStorageMB int
+	FilenamePolicy    string
+}
+
+func (AttachmentModule) LoadAndValidate() (AttachmentSection, error) {
+	section := AttachmentSection{
+		MediaEnabled:      common.GetBool("ATTACHMENT_MEDIA_ENABLED", common.DefaultAttachmentMediaEnabled),
+		MediaMaxFileMB:    common.GetInt("ATTACHMENT_MEDIA_MAX_FILE_MB", common.DefaultAttachmentMediaMaxFileMB),
+		MaxTotalStorageMB: common.GetInt("MAX_TOTAL_STORAGE_MB", 0),
+		FilenamePolicy:    strings.ToLower(common.WithDefault(common.GetenvTrim("FILENAME_POLICY"), common.DefaultFilenamePolicy)),
+	}
+	if section.MediaMaxFileMB < 1 || section.MediaMaxFileMB > common.MaxAttachmentMediaMaxFileMB {
+		return AttachmentSection{}, fmt.Errorf("ATTACHMENT_MEDIA_MAX_FILE_MB must be between 1 and %d", common.MaxAttachmentMediaMaxFileMB)
+	}
+	if section.MaxTotalStorageMB < 0 {
+		return AttachmentSection{}, fmt.Errorf("MAX_TOTAL_STORAGE_MB must not be negative")
+	}
+	switch section.FilenamePolicy {
+	case common.FilenamePolicyLenient, common.FilenamePolicyNormalize, common.FilenamePolicyASCII:
+	default:
+		return AttachmentSection{}, fmt.Errorf("FILENAME_POLICY must be %q, %q or %q", common.FilenamePolicyLenient, common.FilenamePolicyNormalize, common.FilenamePolicyASCII)
+	}
+	return section, nil
+}
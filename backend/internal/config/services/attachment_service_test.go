@@ -0,0 +1,89 @@
+package services
+
+import "testing"
+
+func TestAttachmentModule_Metadata(t *testing.T) {
+	m := AttachmentModule{}
+	if got := m.Name(); got != "AttachmentModule" {
+		t.Fatalf("Name() = %q, want %q", got, "AttachmentModule")
+	}
+	if got := m.Section(); got != "attachment" {
+		t.Fatalf("Section() = %q, want %q", got, "attachment")
+	}
+}
+
+func TestAttachmentModule_LoadAndValidate(t *testing.T) {
+	t.Run("media is off by default", func(t *testing.T) {
+		t.Setenv("ATTACHMENT_MEDIA_ENABLED", "")
+		t.Setenv("ATTACHMENT_MEDIA_MAX_FILE_MB", "")
+		section, err := AttachmentModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MediaEnabled {
+			t.Fatal("MediaEnabled = true, want false")
+		}
+		if section.MediaMaxFileMB != 20 {
+			t.Fatalf("MediaMaxFileMB = %d, want 20", section.MediaMaxFileMB)
+		}
+	})
+
+	t.Run("custom values", func(t *testing.T) {
+		t.Setenv("ATTACHMENT_MEDIA_ENABLED", "true")
+		t.Setenv("ATTACHMENT_MEDIA_MAX_FILE_MB", "24")
+		section, err := AttachmentModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !section.MediaEnabled || section.MediaMaxFileMB != 24 {
+			t.Fatalf("unexpected section: %+v", section)
+		}
+	})
+
+	t.Run("out of range size is rejected", func(t *testing.T) {
+		for _, v := range []string{"0", "-1", "25"} {
+			t.Setenv("ATTACHMENT_MEDIA_MAX_FILE_MB", v)
+			if _, err := (AttachmentModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected error for ATTACHMENT_MEDIA_MAX_FILE_MB=%s", v)
+			}
+		}
+	})
+
+	t.Run("MAX_TOTAL_STORAGE_MB", func(t *testing.T) {
+		t.Setenv("MAX_TOTAL_STORAGE_MB", "")
+		section, err := AttachmentModule{}.LoadAndValidate()
+		if err != nil || section.MaxTotalStorageMB != 0 {
+			t.Fatalf("got %+v (%v), want no cap by default", section, err)
+		}
+
+		t.Setenv("MAX_TOTAL_STORAGE_MB", "2048")
+		section, err = AttachmentModule{}.LoadAndValidate()
+		if err != nil || section.MaxTotalStorageMB != 2048 {
+			t.Fatalf("got %+v (%v), want 2048", section, err)
+		}
+
+		t.Setenv("MAX_TOTAL_STORAGE_MB", "-1")
+		if _, err := (AttachmentModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for negative MAX_TOTAL_STORAGE_MB")
+		}
+	})
+
+	t.Run("FILENAME_POLICY", func(t *testing.T) {
+		t.Setenv("FILENAME_POLICY", "")
+		section, err := AttachmentModule{}.LoadAndValidate()
+		if err != nil || section.FilenamePolicy != "lenient" {
+			t.Fatalf("got %+v (%v), want lenient by default", section, err)
+		}
+
+		t.Setenv("FILENAME_POLICY", "ASCII")
+		section, err = AttachmentModule{}.LoadAndValidate()
+		if err != nil || section.FilenamePolicy != "ascii" {
+			t.Fatalf("got %+v (%v), want ascii", section, err)
+		}
+
+		t.Setenv("FILENAME_POLICY", "strict")
+		if _, err := (AttachmentModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for unknown FILENAME_POLICY")
+		}
+	})
+}
@@ -1,10 +1,13 @@
 package services
 
 import (
+	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthModule struct{}
@@ -24,6 +27,35 @@ type AuthSection struct {
 	AllowRegistration bool
 	MasterPassword    string
 	CookieSecureMode  string
+	// SessionCookieName and SessionCookieDomain keep instances on sibling
+	// subdomains from sharing a session cookie. An empty domain scopes the
+	// cookie to the exact host.
+	SessionCookieName   string
+	SessionCookieDomain string
+	// SessionCookieHostPrefix names the cookie __Host-<name> on HTTPS, which
+	// browsers only accept as Secure, host-only and path=/.
+	SessionCookieHostPrefix bool
+	// HeartbeatTokenBytes is the amount of randomness in newly issued
+	// quick-heartbeat tokens.
+	HeartbeatTokenBytes int
+	// PasswordPolicy selects how new passwords are judged: "classes"
+	// requires upper/lower/digit/special, "entropy" requires an estimated
+	// strength score of at least PasswordMinScore (0-4).
+	PasswordPolicy   string
+	PasswordMinScore int
+	// LockoutAlertIntervalMinutes throttles lockout alert emails per account.
+	LockoutAlertIntervalMinutes int
+	// RecoveryKeyFormat is "hex" (RK-XXXXX-...) or "mnemonic" (one word per
+	// byte); RecoveryKeyBytes is the randomness in each new key.
+	RecoveryKeyFormat string
+	RecoveryKeyBytes  int
+
+	// SetupEmail, with SetupPassword or SetupPasswordHash (a bcrypt hash),
+	// creates the first account at startup when none exists yet.
+	SetupEmail        string
+	SetupPassword     string
+	SetupPasswordHash string
+	SetupOwnerEmail   string
 }
 
 func (AuthModule) LoadAndValidate() (AuthSection, error) {
@@ -34,11 +66,111 @@ func (AuthModule) LoadAndValidate() (AuthSection, error) {
 		cookieMode = ""
 	}
 
+	cookieName := common.WithDefault(common.GetenvTrim("SESSION_COOKIE_NAME"), common.DefaultSessionCookie)
+	if !cookieNamePattern.MatchString(cookieName) {
+		return AuthSection{}, fmt.Errorf("SESSION_COOKIE_NAME must contain only letters, digits and !#$%%&'*+-.^_`|~")
+	}
+	cookieDomain := strings.ToLower(common.GetenvTrim("SESSION_COOKIE_DOMAIN"))
+	if cookieDomain != "" && !isCookieDomain(cookieDomain) {
+		return AuthSection{}, fmt.Errorf("SESSION_COOKIE_DOMAIN must be a host name such as example.com")
+	}
+	if cookieDomain != "" && strings.HasPrefix(cookieName, "__Host-") {
+		return AuthSection{}, fmt.Errorf("SESSION_COOKIE_DOMAIN cannot be set for a __Host- cookie")
+	}
+	hostPrefix := common.GetBool("SESSION_COOKIE_HOST_PREFIX", false)
+	if hostPrefix && cookieDomain != "" {
+		return AuthSection{}, fmt.Errorf("SESSION_COOKIE_DOMAIN cannot be set with SESSION_COOKIE_HOST_PREFIX")
+	}
+	if hostPrefix && cookieMode == "never" {
+		return AuthSection{}, fmt.Errorf("SESSION_COOKIE_HOST_PREFIX requires secure cookies, not AUTH_COOKIE_SECURE_MODE=never")
+	}
+
+	tokenBytes := common.GetPositiveInt("HEARTBEAT_TOKEN_BYTES", common.DefaultHeartbeatTokenBytes)
+	if tokenBytes < common.MinHeartbeatTokenBytes {
+		return AuthSection{}, fmt.Errorf("HEARTBEAT_TOKEN_BYTES must be at least %d", common.MinHeartbeatTokenBytes)
+	}
+
+	policy := strings.ToLower(common.WithDefault(common.GetenvTrim("PASSWORD_POLICY"), common.PasswordPolicyClasses))
+	switch policy {
+	case common.PasswordPolicyClasses, common.PasswordPolicyEntropy:
+	default:
+		return AuthSection{}, fmt.Errorf("PASSWORD_POLICY must be %q or %q", common.PasswordPolicyClasses, common.PasswordPolicyEntropy)
+	}
+	minScore := common.GetInt("PASSWORD_MIN_SCORE", common.DefaultPasswordMinScore)
+	if minScore < 0 || minScore > common.MaxPasswordMinScore {
+		return AuthSection{}, fmt.Errorf("PASSWORD_MIN_SCORE must be between 0 and %d", common.MaxPasswordMinScore)
+	}
+
+	keyFormat := strings.ToLower(common.WithDefault(common.GetenvTrim("RECOVERY_KEY_FORMAT"), common.RecoveryKeyFormatHex))
+	switch keyFormat {
+	case common.RecoveryKeyFormatHex, common.RecoveryKeyFormatMnemonic:
+	default:
+		return AuthSection{}, fmt.Errorf("RECOVERY_KEY_FORMAT must be %q or %q", common.RecoveryKeyFormatHex, common.RecoveryKeyFormatMnemonic)
+	}
+	keyBytes := common.GetInt("RECOVERY_KEY_BYTES", common.DefaultRecoveryKeyBytes)
+	if keyBytes < common.MinRecoveryKeyBytes || keyBytes > common.MaxRecoveryKeyBytes {
+		return AuthSection{}, fmt.Errorf("RECOVERY_KEY_BYTES must be between %d and %d", common.MinRecoveryKeyBytes, common.MaxRecoveryKeyBytes)
+	}
+
+	setupEmail := common.GetenvTrim("SETUP_EMAIL")
+	setupPassword := os.Getenv("SETUP_PASSWORD")
+	setupHash := common.GetenvTrim("MASTER_PASSWORD_HASH")
+	switch {
+	case setupEmail == "" && (setupPassword != "" || setupHash != ""):
+		return AuthSection{}, fmt.Errorf("SETUP_EMAIL is required when SETUP_PASSWORD or MASTER_PASSWORD_HASH is set")
+	case setupEmail != "" && setupPassword == "" && setupHash == "":
+		return AuthSection{}, fmt.Errorf("SETUP_EMAIL requires SETUP_PASSWORD or MASTER_PASSWORD_HASH")
+	case setupPassword != "" && setupHash != "":
+		return AuthSection{}, fmt.Errorf("SETUP_PASSWORD and MASTER_PASSWORD_HASH cannot both be set")
+	}
+	if setupHash != "" {
+		if _, err := bcrypt.Cost([]byte(setupHash)); err != nil {
+			return AuthSection{}, fmt.Errorf("MASTER_PASSWORD_HASH must be a bcrypt hash")
+		}
+	}
+
 	return AuthSection{
 		SessionTTLHours:   common.GetPositiveInt("AUTH_SESSION_TTL_HOURS", common.DefaultSessionTTLHours),
 		RefreshTTLHours:   common.GetPositiveInt("AUTH_REFRESH_TTL_HOURS", common.DefaultRefreshTTLHours),
 		AllowRegistration: os.Getenv("ALLOW_REGISTRATION") == "true",
 		MasterPassword:    os.Getenv("MASTER_PASSWORD"),
 		CookieSecureMode:  cookieMode,
+
+		SessionCookieName:       cookieName,
+		SessionCookieDomain:     cookieDomain,
+		SessionCookieHostPrefix: hostPrefix,
+
+		HeartbeatTokenBytes: tokenBytes,
+		PasswordPolicy:      policy,
+		PasswordMinScore:    minScore,
+
+		LockoutAlertIntervalMinutes: common.GetPositiveInt("LOCKOUT_ALERT_INTERVAL_MINUTES", common.DefaultLockoutAlertIntervalMinutes),
+		RecoveryKeyFormat:           keyFormat,
+		RecoveryKeyBytes:            keyBytes,
+
+		SetupEmail:        setupEmail,
+		SetupPassword:     setupPassword,
+		SetupPasswordHash: setupHash,
+		SetupOwnerEmail:   common.GetenvTrim("SETUP_OWNER_EMAIL"),
 	}, nil
 }
+
+// cookieNamePattern matches an RFC 6265 cookie name (an RFC 2616 token).
+var cookieNamePattern = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+var domainLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// isCookieDomain reports whether domain is a bare host name, optionally with
+// the leading dot older browsers expect, without scheme, port or path.
+func isCookieDomain(domain string) bool {
+	domain = strings.TrimPrefix(domain, ".")
+	if domain == "" || len(domain) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if !domainLabelPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestAuthModule_Metadata(t *testing.T) {
@@ -137,6 +138,204 @@ func TestAuthModule_LoadAndValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("HEARTBEAT_TOKEN_BYTES", func(t *testing.T) {
+		t.Setenv("HEARTBEAT_TOKEN_BYTES", "")
+		section, err := AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.HeartbeatTokenBytes != 32 {
+			t.Fatalf("HeartbeatTokenBytes = %d, want 32 by default", section.HeartbeatTokenBytes)
+		}
+
+		t.Setenv("HEARTBEAT_TOKEN_BYTES", "48")
+		section, err = AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.HeartbeatTokenBytes != 48 {
+			t.Fatalf("HeartbeatTokenBytes = %d, want 48", section.HeartbeatTokenBytes)
+		}
+
+		t.Setenv("HEARTBEAT_TOKEN_BYTES", "8")
+		if _, err := (AuthModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for HEARTBEAT_TOKEN_BYTES below the minimum")
+		}
+	})
+
+	t.Run("PASSWORD_POLICY", func(t *testing.T) {
+		t.Setenv("PASSWORD_POLICY", "")
+		t.Setenv("PASSWORD_MIN_SCORE", "")
+		section, err := AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.PasswordPolicy != "classes" || section.PasswordMinScore != 3 {
+			t.Fatalf("got policy %q score %d, want classes/3 by default", section.PasswordPolicy, section.PasswordMinScore)
+		}
+
+		t.Setenv("PASSWORD_POLICY", "Entropy")
+		t.Setenv("PASSWORD_MIN_SCORE", "4")
+		section, err = AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.PasswordPolicy != "entropy" || section.PasswordMinScore != 4 {
+			t.Fatalf("got policy %q score %d, want entropy/4", section.PasswordPolicy, section.PasswordMinScore)
+		}
+
+		t.Setenv("PASSWORD_MIN_SCORE", "5")
+		if _, err := (AuthModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for PASSWORD_MIN_SCORE above 4")
+		}
+
+		t.Setenv("PASSWORD_MIN_SCORE", "")
+		t.Setenv("PASSWORD_POLICY", "zxcvbn")
+		if _, err := (AuthModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for unknown PASSWORD_POLICY")
+		}
+	})
+
+	t.Run("LOCKOUT_ALERT_INTERVAL_MINUTES", func(t *testing.T) {
+		t.Setenv("PASSWORD_POLICY", "")
+		t.Setenv("LOCKOUT_ALERT_INTERVAL_MINUTES", "")
+		section, err := AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.LockoutAlertIntervalMinutes != 60 {
+			t.Fatalf("got %d, want 60 by default", section.LockoutAlertIntervalMinutes)
+		}
+
+		t.Setenv("LOCKOUT_ALERT_INTERVAL_MINUTES", "15")
+		section, err = AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.LockoutAlertIntervalMinutes != 15 {
+			t.Fatalf("got %d, want 15", section.LockoutAlertIntervalMinutes)
+		}
+	})
+
+	t.Run("RECOVERY_KEY_FORMAT and RECOVERY_KEY_BYTES", func(t *testing.T) {
+		t.Setenv("PASSWORD_POLICY", "")
+		t.Setenv("RECOVERY_KEY_FORMAT", "")
+		t.Setenv("RECOVERY_KEY_BYTES", "")
+		section, err := AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.RecoveryKeyFormat != "hex" || section.RecoveryKeyBytes != 10 {
+			t.Fatalf("got %q/%d, want hex/10 by default", section.RecoveryKeyFormat, section.RecoveryKeyBytes)
+		}
+
+		t.Setenv("RECOVERY_KEY_FORMAT", "Mnemonic")
+		t.Setenv("RECOVERY_KEY_BYTES", "16")
+		section, err = AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.RecoveryKeyFormat != "mnemonic" || section.RecoveryKeyBytes != 16 {
+			t.Fatalf("got %q/%d, want mnemonic/16", section.RecoveryKeyFormat, section.RecoveryKeyBytes)
+		}
+
+		for _, env := range []struct{ format, bytes string }{
+			{"base32", "10"}, {"hex", "9"}, {"hex", "33"},
+		} {
+			t.Setenv("RECOVERY_KEY_FORMAT", env.format)
+			t.Setenv("RECOVERY_KEY_BYTES", env.bytes)
+			if _, err := (AuthModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected error for RECOVERY_KEY_FORMAT=%s RECOVERY_KEY_BYTES=%s", env.format, env.bytes)
+			}
+		}
+	})
+
+	t.Run("SETUP_* seeding", func(t *testing.T) {
+		t.Setenv("PASSWORD_POLICY", "")
+		t.Setenv("SETUP_EMAIL", "")
+		t.Setenv("SETUP_PASSWORD", "")
+		t.Setenv("MASTER_PASSWORD_HASH", "")
+		t.Setenv("SETUP_OWNER_EMAIL", "")
+		section, err := AuthModule{}.LoadAndValidate()
+		if err != nil || section.SetupEmail != "" {
+			t.Fatalf("seeding should be off by default: %+v (%v)", section, err)
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte("Sup3r$ecret!"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("SETUP_EMAIL", " admin@example.com ")
+		t.Setenv("MASTER_PASSWORD_HASH", string(hash))
+		t.Setenv("SETUP_OWNER_EMAIL", "owner@example.com")
+		section, err = AuthModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.SetupEmail != "admin@example.com" || section.SetupPasswordHash != string(hash) || section.SetupOwnerEmail != "owner@example.com" {
+			t.Fatalf("unexpected setup fields: %+v", section)
+		}
+
+		for _, tc := range []struct{ email, password, hash string }{
+			{email: "", password: "Sup3r$ecret!", hash: ""},
+			{email: "admin@example.com", password: "", hash: ""},
+			{email: "admin@example.com", password: "Sup3r$ecret!", hash: string(hash)},
+			{email: "admin@example.com", password: "", hash: "not-a-bcrypt-hash"},
+		} {
+			t.Setenv("SETUP_EMAIL", tc.email)
+			t.Setenv("SETUP_PASSWORD", tc.password)
+			t.Setenv("MASTER_PASSWORD_HASH", tc.hash)
+			if _, err := (AuthModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected an error for %+v", tc)
+			}
+		}
+	})
+
+	t.Run("session cookie name and domain", func(t *testing.T) {
+		t.Setenv("SESSION_COOKIE_NAME", "")
+		t.Setenv("SESSION_COOKIE_DOMAIN", "")
+		section, err := AuthModule{}.LoadAndValidate()
+		if err != nil || section.SessionCookieName != "aeterna_session" || section.SessionCookieDomain != "" {
+			t.Fatalf("unexpected defaults: %q %q (%v)", section.SessionCookieName, section.SessionCookieDomain, err)
+		}
+
+		t.Setenv("SESSION_COOKIE_NAME", "staging_session")
+		t.Setenv("SESSION_COOKIE_DOMAIN", " .Example.COM ")
+		section, err = AuthModule{}.LoadAndValidate()
+		if err != nil || section.SessionCookieName != "staging_session" || section.SessionCookieDomain != ".example.com" {
+			t.Fatalf("unexpected section: %q %q (%v)", section.SessionCookieName, section.SessionCookieDomain, err)
+		}
+
+		for _, tc := range []struct{ name, domain string }{
+			{"bad name", ""}, {"semi;colon", ""}, {"ok", "https://example.com"},
+			{"ok", "example.com:8080"}, {"ok", "-bad.example.com"}, {"ok", "."}, {"__Host-session", "example.com"},
+		} {
+			t.Setenv("SESSION_COOKIE_NAME", tc.name)
+			t.Setenv("SESSION_COOKIE_DOMAIN", tc.domain)
+			if _, err := (AuthModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected an error for name %q, domain %q", tc.name, tc.domain)
+			}
+		}
+	})
+
+	t.Run("session cookie host prefix", func(t *testing.T) {
+		t.Setenv("SESSION_COOKIE_DOMAIN", "")
+		t.Setenv("AUTH_COOKIE_SECURE_MODE", "")
+		t.Setenv("SESSION_COOKIE_HOST_PREFIX", "true")
+		section, err := AuthModule{}.LoadAndValidate()
+		if err != nil || !section.SessionCookieHostPrefix {
+			t.Fatalf("expected the host prefix to be enabled (%v)", err)
+		}
+
+		for env, value := range map[string]string{"SESSION_COOKIE_DOMAIN": "example.com", "AUTH_COOKIE_SECURE_MODE": "never"} {
+			t.Setenv(env, value)
+			if _, err := (AuthModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected an error for %s=%s with the host prefix", env, value)
+			}
+			t.Setenv(env, "")
+		}
+	})
+
 	cookieModeTests := []struct {
 		name     string
 		input    string
@@ -3,6 +3,8 @@ package services
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
 )
@@ -22,6 +24,16 @@ type DatabaseSection struct {
 	Path      string
 	PathIsSet bool
 
+	// DataDir is the optional DATA_DIR root. When set, the database, uploads
+	// and key files default to locations beneath it.
+	DataDir           string
+	UploadsDir        string
+	EncryptionKeyFile string
+	// AllowInsecureKeyPerms loads key files that are not 0600 with a warning
+	// instead of failing, for filesystems that cannot represent Unix
+	// permissions.
+	AllowInsecureKeyPerms bool
+
 	DBHost       string
 	PostgresHost string
 	DatabaseURL  string
@@ -29,23 +41,58 @@ type DatabaseSection struct {
 	EncryptionEnabled        bool
 	EncryptionAutoMigrate    bool
 	EncryptionKDFContextFile string
+
+	// EncryptionAlgorithm is the AEAD used for new field and file
+	// encryption; existing ciphertext records its own algorithm.
+	EncryptionAlgorithm string
+	// StrictCiphertextBinding rejects ciphertext that is not bound to its
+	// record, for use once legacy data has been rebound.
+	StrictCiphertextBinding bool
 }
 
 func (DatabaseModule) LoadAndValidate() (DatabaseSection, error) {
 	rawPath := os.Getenv("DATABASE_PATH")
+	dataDir := common.GetenvTrim("DATA_DIR")
+
+	defaultPath := common.DefaultDatabasePath
+	defaultKDFContextFile := common.DefaultDBEncryptionKDFContextFile
+	encryptionKeyFile := ""
+	if dataDir != "" {
+		defaultPath = filepath.Join(dataDir, common.DefaultDatabaseFilename)
+		defaultKDFContextFile = filepath.Join(dataDir, "secrets", "db_kdf_context")
+		encryptionKeyFile = filepath.Join(dataDir, "secrets", "encryption_key")
+	}
+
 	section := DatabaseSection{
-		Path:         common.WithDefault(common.GetenvTrim("DATABASE_PATH"), common.DefaultDatabasePath),
-		PathIsSet:    rawPath != "",
+		Path:         common.WithDefault(common.GetenvTrim("DATABASE_PATH"), defaultPath),
+		PathIsSet:    rawPath != "" || dataDir != "",
+		DataDir:      dataDir,
 		DBHost:       common.GetenvTrim("DB_HOST"),
 		PostgresHost: common.GetenvTrim("POSTGRES_HOST"),
 		DatabaseURL:  common.GetenvTrim("DATABASE_URL"),
 
 		EncryptionEnabled:        common.GetBool("DB_ENCRYPTION_ENABLED", common.DefaultDBEncryptionEnabled),
 		EncryptionAutoMigrate:    common.GetBool("DB_ENCRYPTION_AUTO_MIGRATE", common.DefaultDBEncryptionAutoMigrate),
-		EncryptionKDFContextFile: common.WithDefault(common.GetenvTrim("DB_ENCRYPTION_KDF_CONTEXT_FILE"), common.DefaultDBEncryptionKDFContextFile),
+		EncryptionKDFContextFile: common.WithDefault(common.GetenvTrim("DB_ENCRYPTION_KDF_CONTEXT_FILE"), defaultKDFContextFile),
+		EncryptionKeyFile:        encryptionKeyFile,
+		AllowInsecureKeyPerms:    common.GetBool("ALLOW_INSECURE_KEY_PERMS", false),
+
+		EncryptionAlgorithm:     strings.ToLower(common.WithDefault(common.GetenvTrim("ENCRYPTION_ALGORITHM"), common.DefaultEncryptionAlgorithm)),
+		StrictCiphertextBinding: common.GetBool("STRICT_CIPHERTEXT_BINDING", false),
+	}
+	switch section.EncryptionAlgorithm {
+	case common.EncryptionAlgorithmAESGCM, common.EncryptionAlgorithmChaCha20:
+	default:
+		return DatabaseSection{}, fmt.Errorf("ENCRYPTION_ALGORITHM must be %q or %q, got %q", common.EncryptionAlgorithmAESGCM, common.EncryptionAlgorithmChaCha20, section.EncryptionAlgorithm)
 	}
+	defaultUploads := filepath.Join(filepath.Dir(section.Path), "uploads")
+	if dataDir != "" {
+		defaultUploads = filepath.Join(dataDir, "uploads")
+	}
+	section.UploadsDir = common.WithDefault(common.GetenvTrim("UPLOADS_DIR"), defaultUploads)
+
 	if common.GetenvTrim("ENV") == "production" && !section.PathIsSet {
-		return DatabaseSection{}, fmt.Errorf("DATABASE_PATH must be set in production")
+		return DatabaseSection{}, fmt.Errorf("DATABASE_PATH or DATA_DIR must be set in production")
 	}
 	return section, nil
 }
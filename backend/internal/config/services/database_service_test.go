@@ -45,6 +45,20 @@ func TestDatabaseModule_LoadAndValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("ALLOW_INSECURE_KEY_PERMS", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("ALLOW_INSECURE_KEY_PERMS", "")
+		section, err := DatabaseModule{}.LoadAndValidate()
+		if err != nil || section.AllowInsecureKeyPerms {
+			t.Fatalf("AllowInsecureKeyPerms should default to false (err %v)", err)
+		}
+		t.Setenv("ALLOW_INSECURE_KEY_PERMS", "true")
+		section, err = DatabaseModule{}.LoadAndValidate()
+		if err != nil || !section.AllowInsecureKeyPerms {
+			t.Fatalf("AllowInsecureKeyPerms should be true (err %v)", err)
+		}
+	})
+
 	t.Run("custom DATABASE_PATH", func(t *testing.T) {
 		t.Setenv("ENV", "")
 		t.Setenv("DATABASE_PATH", "/data/custom.db")
@@ -60,6 +74,61 @@ func TestDatabaseModule_LoadAndValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("DATA_DIR roots database, uploads and secrets", func(t *testing.T) {
+		t.Setenv("ENV", "production")
+		t.Setenv("DATA_DIR", "/srv/aeterna")
+		t.Setenv("DATABASE_PATH", "")
+		t.Setenv("UPLOADS_DIR", "")
+		t.Setenv("DB_ENCRYPTION_KDF_CONTEXT_FILE", "")
+		section, err := DatabaseModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.Path != "/srv/aeterna/aeterna.db" {
+			t.Fatalf("Path = %q, want DATA_DIR/aeterna.db", section.Path)
+		}
+		if section.UploadsDir != "/srv/aeterna/uploads" {
+			t.Fatalf("UploadsDir = %q, want DATA_DIR/uploads", section.UploadsDir)
+		}
+		if section.EncryptionKDFContextFile != "/srv/aeterna/secrets/db_kdf_context" {
+			t.Fatalf("EncryptionKDFContextFile = %q, want DATA_DIR/secrets/db_kdf_context", section.EncryptionKDFContextFile)
+		}
+		if section.EncryptionKeyFile != "/srv/aeterna/secrets/encryption_key" {
+			t.Fatalf("EncryptionKeyFile = %q, want DATA_DIR/secrets/encryption_key", section.EncryptionKeyFile)
+		}
+	})
+
+	t.Run("DATA_DIR defaults yield to explicit paths", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("DATA_DIR", "/srv/aeterna")
+		t.Setenv("DATABASE_PATH", "/db/custom.db")
+		t.Setenv("UPLOADS_DIR", "/mnt/uploads")
+		section, err := DatabaseModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.Path != "/db/custom.db" || section.UploadsDir != "/mnt/uploads" {
+			t.Fatalf("got Path=%q UploadsDir=%q, want explicit overrides", section.Path, section.UploadsDir)
+		}
+	})
+
+	t.Run("uploads default next to database without DATA_DIR", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("DATA_DIR", "")
+		t.Setenv("UPLOADS_DIR", "")
+		t.Setenv("DATABASE_PATH", "/data/custom.db")
+		section, err := DatabaseModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.UploadsDir != "/data/uploads" {
+			t.Fatalf("UploadsDir = %q, want /data/uploads", section.UploadsDir)
+		}
+		if section.EncryptionKeyFile != "" {
+			t.Fatalf("EncryptionKeyFile = %q, want empty without DATA_DIR", section.EncryptionKeyFile)
+		}
+	})
+
 	t.Run("production requires DATABASE_PATH", func(t *testing.T) {
 		t.Setenv("ENV", "production")
 		t.Setenv("DATABASE_PATH", "")
@@ -87,6 +156,32 @@ func TestDatabaseModule_LoadAndValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("ENCRYPTION_ALGORITHM", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("ENCRYPTION_ALGORITHM", "")
+		section, err := DatabaseModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.EncryptionAlgorithm != common.EncryptionAlgorithmAESGCM {
+			t.Fatalf("EncryptionAlgorithm = %q, want AES-GCM by default", section.EncryptionAlgorithm)
+		}
+
+		t.Setenv("ENCRYPTION_ALGORITHM", "ChaCha20-Poly1305")
+		section, err = DatabaseModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.EncryptionAlgorithm != common.EncryptionAlgorithmChaCha20 {
+			t.Fatalf("EncryptionAlgorithm = %q, want %q", section.EncryptionAlgorithm, common.EncryptionAlgorithmChaCha20)
+		}
+
+		t.Setenv("ENCRYPTION_ALGORITHM", "des")
+		if _, err := (DatabaseModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for unsupported ENCRYPTION_ALGORITHM")
+		}
+	})
+
 	t.Run("postgres env vars are captured but not used as path", func(t *testing.T) {
 		t.Setenv("ENV", "")
 		t.Setenv("DATABASE_PATH", "")
@@ -2,7 +2,9 @@ package services
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"strings"
 
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
 )
@@ -21,15 +23,53 @@ func init() {
 type HTTPSection struct {
 	AllowedOrigins      string
 	AllowedOriginsIsSet bool
-	ProxyMode           string
+	// AllowedOriginsIgnorePort matches allowlist entries on scheme and host
+	// only, so a dev server on a different port is still accepted.
+	AllowedOriginsIgnorePort bool
+	// AllowMissingOrigin is STRICT_ORIGIN=false: production then accepts
+	// session requests without an Origin or Referer, leaving them to the
+	// SameSite session cookie, for clients that legitimately omit both.
+	AllowMissingOrigin bool
+	ProxyMode          string
+	// RevealRateLimitPerMinute and SetupRateLimitPerMinute cap requests per
+	// IP to the public reveal and setup endpoints, on top of the global limit.
+	RevealRateLimitPerMinute int
+	SetupRateLimitPerMinute  int
+	// RequestTimeoutSeconds is the deadline on each request's context; 0
+	// disables it.
+	RequestTimeoutSeconds int
+	// TrustedProxies are the proxy addresses whose X-Real-IP header is
+	// believed as the client IP; empty uses the connecting address.
+	TrustedProxies []string
+	// MgmtIPAllowlist restricts the authenticated management API to these
+	// networks; empty allows any address.
+	MgmtIPAllowlist []netip.Prefix
 }
 
 func (HTTPModule) LoadAndValidate() (HTTPSection, error) {
 	rawAllowedOrigins := os.Getenv("ALLOWED_ORIGINS")
 	section := HTTPSection{
-		AllowedOrigins:      common.GetenvTrim("ALLOWED_ORIGINS"),
-		AllowedOriginsIsSet: rawAllowedOrigins != "",
-		ProxyMode:           common.GetenvTrim("PROXY_MODE"),
+		AllowedOrigins:           common.GetenvTrim("ALLOWED_ORIGINS"),
+		AllowedOriginsIsSet:      rawAllowedOrigins != "",
+		AllowedOriginsIgnorePort: common.GetBool("ALLOWED_ORIGINS_IGNORE_PORT", false),
+		AllowMissingOrigin:       !common.GetBool("STRICT_ORIGIN", true),
+		ProxyMode:                common.GetenvTrim("PROXY_MODE"),
+		RevealRateLimitPerMinute: common.GetPositiveInt("REVEAL_RATE_LIMIT_PER_MINUTE", common.DefaultRevealRateLimitPerMinute),
+		SetupRateLimitPerMinute:  common.GetPositiveInt("SETUP_RATE_LIMIT_PER_MINUTE", common.DefaultSetupRateLimitPerMinute),
+		RequestTimeoutSeconds:    common.GetInt("REQUEST_TIMEOUT_SECONDS", common.DefaultRequestTimeoutSeconds),
+	}
+	if section.RequestTimeoutSeconds < 0 {
+		return HTTPSection{}, fmt.Errorf("REQUEST_TIMEOUT_SECONDS must be 0 or more")
+	}
+	trusted, err := parsePrefixList("TRUSTED_PROXIES")
+	if err != nil {
+		return HTTPSection{}, err
+	}
+	for _, prefix := range trusted {
+		section.TrustedProxies = append(section.TrustedProxies, prefix.String())
+	}
+	if section.MgmtIPAllowlist, err = parsePrefixList("MGMT_IP_ALLOWLIST"); err != nil {
+		return HTTPSection{}, err
 	}
 	if common.GetenvTrim("ENV") == "production" && !section.AllowedOriginsIsSet {
 		return HTTPSection{}, fmt.Errorf("ALLOWED_ORIGINS must be set in production")
@@ -39,3 +79,26 @@ func (HTTPModule) LoadAndValidate() (HTTPSection, error) {
 	}
 	return section, nil
 }
+
+// parsePrefixList reads a comma-separated list of CIDRs from key; a bare
+// address stands for itself alone.
+func parsePrefixList(key string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(common.GetenvTrim(key), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a comma-separated list of IP addresses or CIDRs, got %q", key, entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
@@ -48,6 +48,75 @@ func TestHTTPModule_LoadAndValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("ALLOWED_ORIGINS_IGNORE_PORT", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("ALLOWED_ORIGINS", "https://example.com")
+		t.Setenv("ALLOWED_ORIGINS_IGNORE_PORT", "true")
+		section, err := HTTPModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !section.AllowedOriginsIgnorePort {
+			t.Fatal("AllowedOriginsIgnorePort should be true")
+		}
+	})
+
+	t.Run("STRICT_ORIGIN", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("STRICT_ORIGIN", "")
+		section, err := HTTPModule{}.LoadAndValidate()
+		if err != nil || section.AllowMissingOrigin {
+			t.Fatalf("missing origins should be refused by default (err %v)", err)
+		}
+		t.Setenv("STRICT_ORIGIN", "false")
+		section, err = HTTPModule{}.LoadAndValidate()
+		if err != nil || !section.AllowMissingOrigin {
+			t.Fatalf("AllowMissingOrigin should be set by STRICT_ORIGIN=false (err %v)", err)
+		}
+	})
+
+	t.Run("route rate limits", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("REVEAL_RATE_LIMIT_PER_MINUTE", "")
+		t.Setenv("SETUP_RATE_LIMIT_PER_MINUTE", "")
+		section, err := HTTPModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.RevealRateLimitPerMinute != 20 || section.SetupRateLimitPerMinute != 5 {
+			t.Fatalf("got reveal=%d setup=%d, want defaults 20/5", section.RevealRateLimitPerMinute, section.SetupRateLimitPerMinute)
+		}
+
+		t.Setenv("REVEAL_RATE_LIMIT_PER_MINUTE", "10")
+		t.Setenv("SETUP_RATE_LIMIT_PER_MINUTE", "2")
+		section, err = HTTPModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.RevealRateLimitPerMinute != 10 || section.SetupRateLimitPerMinute != 2 {
+			t.Fatalf("got reveal=%d setup=%d, want 10/2", section.RevealRateLimitPerMinute, section.SetupRateLimitPerMinute)
+		}
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("REQUEST_TIMEOUT_SECONDS", "")
+		section, err := HTTPModule{}.LoadAndValidate()
+		if err != nil || section.RequestTimeoutSeconds != 30 {
+			t.Fatalf("got %d (%v), want default 30", section.RequestTimeoutSeconds, err)
+		}
+
+		t.Setenv("REQUEST_TIMEOUT_SECONDS", "0")
+		if section, err = (HTTPModule{}).LoadAndValidate(); err != nil || section.RequestTimeoutSeconds != 0 {
+			t.Fatalf("0 should disable the timeout, got %d (%v)", section.RequestTimeoutSeconds, err)
+		}
+
+		t.Setenv("REQUEST_TIMEOUT_SECONDS", "-1")
+		if _, err := (HTTPModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected an error for a negative timeout")
+		}
+	})
+
 	t.Run("production requires ALLOWED_ORIGINS", func(t *testing.T) {
 		t.Setenv("ENV", "production")
 		t.Setenv("ALLOWED_ORIGINS", "")
@@ -115,4 +184,30 @@ func TestHTTPModule_LoadAndValidate(t *testing.T) {
 			t.Fatalf("ProxyMode = %q, want %q", section.ProxyMode, "simple")
 		}
 	})
+
+	t.Run("MGMT_IP_ALLOWLIST and TRUSTED_PROXIES", func(t *testing.T) {
+		t.Setenv("ENV", "")
+		t.Setenv("ALLOWED_ORIGINS", "")
+		t.Setenv("MGMT_IP_ALLOWLIST", " 10.8.0.1/24, 203.0.113.9 ,2001:db8::/32")
+		t.Setenv("TRUSTED_PROXIES", "172.18.0.2")
+		section, err := HTTPModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []string
+		for _, prefix := range section.MgmtIPAllowlist {
+			got = append(got, prefix.String())
+		}
+		if strings.Join(got, ",") != "10.8.0.0/24,203.0.113.9/32,2001:db8::/32" {
+			t.Fatalf("MgmtIPAllowlist = %v", got)
+		}
+		if len(section.TrustedProxies) != 1 || section.TrustedProxies[0] != "172.18.0.2/32" {
+			t.Fatalf("TrustedProxies = %v", section.TrustedProxies)
+		}
+
+		t.Setenv("MGMT_IP_ALLOWLIST", "home-network")
+		if _, err := (HTTPModule{}).LoadAndValidate(); err == nil || !strings.Contains(err.Error(), "MGMT_IP_ALLOWLIST") {
+			t.Fatalf("expected an error for an invalid entry, got %v", err)
+		}
+	})
 }
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"fmt"
+
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
 )
 
@@ -23,10 +25,15 @@ type LoggingSection struct {
 	MaxBackups int
 	MaxAge     int
 	Compress   bool
+	// Redact masks emails and shortens tokens and URLs in log output.
+	Redact bool
+	// EncryptBackups encrypts rotated LOG_FILE backups with the
+	// application encryption key.
+	EncryptBackups bool
 }
 
 func (LoggingModule) LoadAndValidate() (LoggingSection, error) {
-	return LoggingSection{
+	section := LoggingSection{
 		Level:      common.GetenvTrim("LOG_LEVEL"),
 		Format:     common.GetenvTrim("LOG_FORMAT"),
 		File:       common.GetenvTrim("LOG_FILE"),
@@ -34,5 +41,12 @@ func (LoggingModule) LoadAndValidate() (LoggingSection, error) {
 		MaxBackups: common.GetInt("LOG_MAX_BACKUPS", common.DefaultLogMaxBackups),
 		MaxAge:     common.GetInt("LOG_MAX_AGE", common.DefaultLogMaxAge),
 		Compress:   common.GetBool("LOG_COMPRESS", common.DefaultLogCompress),
-	}, nil
+		Redact:     common.GetBool("LOG_REDACT", common.DefaultLogRedact),
+
+		EncryptBackups: common.GetBool("LOG_ENCRYPT_BACKUPS", common.DefaultLogEncryptBackups),
+	}
+	if section.EncryptBackups && section.File == "" {
+		return LoggingSection{}, fmt.Errorf("LOG_ENCRYPT_BACKUPS requires LOG_FILE")
+	}
+	return section, nil
 }
@@ -25,6 +25,8 @@ func TestLoggingModule_LoadAndValidate(t *testing.T) {
 		t.Setenv("LOG_MAX_BACKUPS", "")
 		t.Setenv("LOG_MAX_AGE", "")
 		t.Setenv("LOG_COMPRESS", "")
+		t.Setenv("LOG_REDACT", "")
+		t.Setenv("LOG_ENCRYPT_BACKUPS", "")
 		section, err := LoggingModule{}.LoadAndValidate()
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -50,6 +52,39 @@ func TestLoggingModule_LoadAndValidate(t *testing.T) {
 		if section.Compress != common.DefaultLogCompress {
 			t.Fatalf("Compress = %v, want %v", section.Compress, common.DefaultLogCompress)
 		}
+		if section.Redact {
+			t.Fatal("Redact should be off by default")
+		}
+		if section.EncryptBackups {
+			t.Fatal("EncryptBackups should be off by default")
+		}
+	})
+
+	t.Run("LOG_REDACT enables redaction", func(t *testing.T) {
+		t.Setenv("LOG_REDACT", "true")
+		section, err := LoggingModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !section.Redact {
+			t.Fatal("Redact should be true")
+		}
+	})
+
+	t.Run("LOG_ENCRYPT_BACKUPS requires LOG_FILE", func(t *testing.T) {
+		t.Setenv("LOG_ENCRYPT_BACKUPS", "true")
+		t.Setenv("LOG_FILE", "")
+		if _, err := (LoggingModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected an error without LOG_FILE")
+		}
+		t.Setenv("LOG_FILE", "/var/log/aeterna.log")
+		section, err := LoggingModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !section.EncryptBackups {
+			t.Fatal("EncryptBackups should be true")
+		}
 	})
 
 	t.Run("custom log level and format", func(t *testing.T) {
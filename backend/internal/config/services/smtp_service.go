@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+)
+
+type SMTPModule struct{}
+
+func (SMTPModule) Name() string { return "SMTPModule" }
+func (SMTPModule) Section() string {
+	return "smtp"
+}
+
+func init() {
+	common.Register(SMTPModule{})
+}
+
+// SMTPSection tunes outgoing mail delivery. Server credentials are per
+// account settings, except that PasswordFile can supply the password.
+type SMTPSection struct {
+	// MaxAttempts is how many times a transient send failure is tried in
+	// total before giving up.
+	MaxAttempts int
+	// RetryBaseMS is the delay before the first retry; it doubles after
+	// every further failure.
+	RetryBaseMS int
+	// PasswordFile, when set, is read for the SMTP password instead of the
+	// value stored in the database. It must have 0600 permissions.
+	PasswordFile string
+	// BodyEncoding is the Content-Transfer-Encoding of text parts: "auto"
+	// sends short-lined ASCII as 7bit and quoted-printable otherwise,
+	// "quoted-printable" and "base64" always use that encoding.
+	BodyEncoding string
+	// PDFFontFile is a TrueType font used for PDF letters. Without it the
+	// built-in font covers Windows-1252 only, and letters using other
+	// characters are sent inline instead.
+	PDFFontFile string
+	// SizeWarningKB logs a warning when an assembled trigger email is larger
+	// than this, since providers such as Gmail silently clip big messages; 0
+	// disables the check. SizeWarningNotifyOwner also emails the owner.
+	SizeWarningKB          int
+	SizeWarningNotifyOwner bool
+	// LargeContentAction is what happens to content whose email body is over
+	// SizeWarningKB: "inline" keeps it in the body, "text" and "pdf" move it
+	// to a message.txt or letter.pdf attachment.
+	LargeContentAction string
+	// DefaultFromName is the From display name for accounts with neither
+	// smtp_from_name nor owner_name set.
+	DefaultFromName string
+}
+
+func (SMTPModule) LoadAndValidate() (SMTPSection, error) {
+	section := SMTPSection{
+		MaxAttempts: common.GetInt("SMTP_MAX_ATTEMPTS", common.DefaultSMTPMaxAttempts),
+		RetryBaseMS: common.GetInt("SMTP_RETRY_BASE_MS", common.DefaultSMTPRetryBaseMS),
+
+		PasswordFile: common.GetenvTrim("SMTP_PASSWORD_FILE"),
+		PDFFontFile:  common.GetenvTrim("PDF_FONT_FILE"),
+		BodyEncoding: strings.ToLower(common.WithDefault(common.GetenvTrim("SMTP_BODY_ENCODING"), common.DefaultSMTPBodyEncoding)),
+
+		SizeWarningKB:          common.GetInt("SMTP_SIZE_WARNING_KB", common.DefaultSMTPSizeWarningKB),
+		SizeWarningNotifyOwner: common.GetBool("SMTP_SIZE_WARNING_NOTIFY_OWNER", false),
+		LargeContentAction:     strings.ToLower(common.WithDefault(common.GetenvTrim("SMTP_LARGE_CONTENT_ACTION"), common.DefaultSMTPLargeContentAction)),
+
+		DefaultFromName: common.WithDefault(common.GetenvTrim("DEFAULT_FROM_NAME"), common.DefaultFromName),
+	}
+	if section.MaxAttempts < 1 || section.MaxAttempts > common.MaxSMTPMaxAttempts {
+		return SMTPSection{}, fmt.Errorf("SMTP_MAX_ATTEMPTS must be between 1 and %d", common.MaxSMTPMaxAttempts)
+	}
+	if section.RetryBaseMS < 0 || section.RetryBaseMS > common.MaxSMTPRetryBaseMS {
+		return SMTPSection{}, fmt.Errorf("SMTP_RETRY_BASE_MS must be between 0 and %d", common.MaxSMTPRetryBaseMS)
+	}
+	switch section.BodyEncoding {
+	case common.SMTPBodyEncodingAuto, common.SMTPBodyEncodingQuotedPrintable, common.SMTPBodyEncodingBase64:
+	default:
+		return SMTPSection{}, fmt.Errorf("SMTP_BODY_ENCODING must be %q, %q or %q", common.SMTPBodyEncodingAuto, common.SMTPBodyEncodingQuotedPrintable, common.SMTPBodyEncodingBase64)
+	}
+	if section.SizeWarningKB < 0 {
+		return SMTPSection{}, fmt.Errorf("SMTP_SIZE_WARNING_KB must not be negative")
+	}
+	switch section.LargeContentAction {
+	case common.SMTPLargeContentInline, common.SMTPLargeContentText, common.SMTPLargeContentPDF:
+	default:
+		return SMTPSection{}, fmt.Errorf("SMTP_LARGE_CONTENT_ACTION must be %q, %q or %q", common.SMTPLargeContentInline, common.SMTPLargeContentText, common.SMTPLargeContentPDF)
+	}
+	if strings.ContainsAny(section.DefaultFromName, "\r\n") || len(section.DefaultFromName) > 100 {
+		return SMTPSection{}, fmt.Errorf("DEFAULT_FROM_NAME must be a single line of at most 100 characters")
+	}
+	if section.PDFFontFile != "" {
+		if info, err := os.Stat(section.PDFFontFile); err != nil || info.IsDir() {
+			return SMTPSection{}, fmt.Errorf("PDF_FONT_FILE must be a readable font file")
+		}
+	}
+	return section, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import "testing"
+
+func TestSMTPModule_Metadata(t *testing.T) {
+	m := SMTPModule{}
+	if got := m.Name(); got != "SMTPModule" {
+		t.Fatalf("Name() = %q, want %q", got, "SMTPModule")
+	}
+	if got := m.Section(); got != "smtp" {
+		t.Fatalf("Section() = %q, want %q", got, "smtp")
+	}
+}
+
+func TestSMTPModule_LoadAndValidate(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("SMTP_MAX_ATTEMPTS", "")
+		t.Setenv("SMTP_RETRY_BASE_MS", "")
+		section, err := SMTPModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MaxAttempts != 3 || section.RetryBaseMS != 500 {
+			t.Fatalf("unexpected defaults: %+v", section)
+		}
+	})
+
+	t.Run("custom values", func(t *testing.T) {
+		t.Setenv("SMTP_MAX_ATTEMPTS", "6")
+		t.Setenv("SMTP_RETRY_BASE_MS", "2000")
+		section, err := SMTPModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MaxAttempts != 6 || section.RetryBaseMS != 2000 {
+			t.Fatalf("unexpected section: %+v", section)
+		}
+	})
+
+	t.Run("SMTP_PASSWORD_FILE", func(t *testing.T) {
+		t.Setenv("SMTP_MAX_ATTEMPTS", "")
+		t.Setenv("SMTP_RETRY_BASE_MS", "")
+		t.Setenv("SMTP_PASSWORD_FILE", " /run/secrets/smtp_password ")
+		section, err := SMTPModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.PasswordFile != "/run/secrets/smtp_password" {
+			t.Fatalf("PasswordFile = %q", section.PasswordFile)
+		}
+	})
+
+	t.Run("SMTP_BODY_ENCODING", func(t *testing.T) {
+		t.Setenv("SMTP_MAX_ATTEMPTS", "")
+		t.Setenv("SMTP_RETRY_BASE_MS", "")
+		t.Setenv("SMTP_BODY_ENCODING", "")
+		if section, err := (SMTPModule{}).LoadAndValidate(); err != nil || section.BodyEncoding != "auto" {
+			t.Fatalf("default BodyEncoding = %q (%v), want auto", section.BodyEncoding, err)
+		}
+		t.Setenv("SMTP_BODY_ENCODING", " Base64 ")
+		if section, err := (SMTPModule{}).LoadAndValidate(); err != nil || section.BodyEncoding != "base64" {
+			t.Fatalf("BodyEncoding = %q (%v), want base64", section.BodyEncoding, err)
+		}
+		t.Setenv("SMTP_BODY_ENCODING", "8bit")
+		if _, err := (SMTPModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected an unknown encoding to be rejected")
+		}
+	})
+
+	t.Run("SMTP_SIZE_WARNING_KB", func(t *testing.T) {
+		t.Setenv("SMTP_MAX_ATTEMPTS", "")
+		t.Setenv("SMTP_RETRY_BASE_MS", "")
+		t.Setenv("SMTP_SIZE_WARNING_KB", "")
+		t.Setenv("SMTP_LARGE_CONTENT_ACTION", "")
+		section, err := SMTPModule{}.LoadAndValidate()
+		if err != nil || section.SizeWarningKB != 100 || section.LargeContentAction != "inline" || section.SizeWarningNotifyOwner {
+			t.Fatalf("defaults = %+v (%v), want 100 KB, inline, no owner notice", section, err)
+		}
+		t.Setenv("SMTP_SIZE_WARNING_KB", "0")
+		t.Setenv("SMTP_LARGE_CONTENT_ACTION", " PDF ")
+		t.Setenv("SMTP_SIZE_WARNING_NOTIFY_OWNER", "true")
+		section, err = SMTPModule{}.LoadAndValidate()
+		if err != nil || section.SizeWarningKB != 0 || section.LargeContentAction != "pdf" || !section.SizeWarningNotifyOwner {
+			t.Fatalf("section = %+v (%v)", section, err)
+		}
+		t.Setenv("SMTP_SIZE_WARNING_KB", "-1")
+		if _, err := (SMTPModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected a negative SMTP_SIZE_WARNING_KB to be rejected")
+		}
+		t.Setenv("SMTP_SIZE_WARNING_KB", "")
+		t.Setenv("SMTP_LARGE_CONTENT_ACTION", "html")
+		if _, err := (SMTPModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected an unknown SMTP_LARGE_CONTENT_ACTION to be rejected")
+		}
+	})
+
+	t.Run("DEFAULT_FROM_NAME", func(t *testing.T) {
+		t.Setenv("SMTP_MAX_ATTEMPTS", "")
+		t.Setenv("SMTP_RETRY_BASE_MS", "")
+		t.Setenv("DEFAULT_FROM_NAME", "")
+		if section, err := (SMTPModule{}).LoadAndValidate(); err != nil || section.DefaultFromName != "Aeterna" {
+			t.Fatalf("expected Aeterna by default, got %q (%v)", section.DefaultFromName, err)
+		}
+		t.Setenv("DEFAULT_FROM_NAME", "  Family Archive ")
+		if section, err := (SMTPModule{}).LoadAndValidate(); err != nil || section.DefaultFromName != "Family Archive" {
+			t.Fatalf("expected the configured name, got %q (%v)", section.DefaultFromName, err)
+		}
+		t.Setenv("DEFAULT_FROM_NAME", "Family\nBcc: x@example.com")
+		if _, err := (SMTPModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected a multi-line DEFAULT_FROM_NAME to be rejected")
+		}
+	})
+
+	t.Run("PDF_FONT_FILE", func(t *testing.T) {
+		t.Setenv("SMTP_MAX_ATTEMPTS", "")
+		t.Setenv("SMTP_RETRY_BASE_MS", "")
+		t.Setenv("PDF_FONT_FILE", t.TempDir())
+		if _, err := (SMTPModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected a directory to be rejected as PDF_FONT_FILE")
+		}
+		t.Setenv("PDF_FONT_FILE", "/nonexistent/font.ttf")
+		if _, err := (SMTPModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected a missing PDF_FONT_FILE to be rejected")
+		}
+	})
+
+	t.Run("out of range values are rejected", func(t *testing.T) {
+		for _, env := range []struct{ attempts, base string }{
+			{"0", "500"}, {"11", "500"}, {"3", "-1"}, {"3", "60001"},
+		} {
+			t.Setenv("SMTP_MAX_ATTEMPTS", env.attempts)
+			t.Setenv("SMTP_RETRY_BASE_MS", env.base)
+			if _, err := (SMTPModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected error for SMTP_MAX_ATTEMPTS=%s SMTP_RETRY_BASE_MS=%s", env.attempts, env.base)
+			}
+		}
+	})
+}
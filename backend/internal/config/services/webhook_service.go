@@ -1,6 +1,14 @@
 package services
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
 )
 
@@ -17,10 +25,60 @@ func init() {
 
 type WebhookSection struct {
 	AllowlistHosts string
+	// MaxConsecutiveFailures disables a webhook after this many failed
+	// deliveries in a row; 0 keeps retrying forever.
+	MaxConsecutiveFailures int
+
+	// ClientCertFile and ClientKeyFile are presented to webhook endpoints
+	// that require mutual TLS.
+	ClientCertFile string
+	ClientKeyFile  string
+	// CAFile replaces the system roots when verifying webhook servers.
+	CAFile string
+	// TLSPins are base64 SHA-256 hashes of subject public keys, one of which
+	// must appear in a webhook server's verified chain.
+	TLSPins []string
 }
 
 func (WebhookModule) LoadAndValidate() (WebhookSection, error) {
-	return WebhookSection{
-		AllowlistHosts: common.GetenvTrim("WEBHOOK_ALLOWLIST_HOSTS"),
-	}, nil
+	maxFailures := common.GetInt("WEBHOOK_MAX_CONSECUTIVE_FAILURES", common.DefaultWebhookMaxConsecutiveFailures)
+	if maxFailures < 0 {
+		return WebhookSection{}, fmt.Errorf("WEBHOOK_MAX_CONSECUTIVE_FAILURES must not be negative")
+	}
+	section := WebhookSection{
+		AllowlistHosts:         common.GetenvTrim("WEBHOOK_ALLOWLIST_HOSTS"),
+		MaxConsecutiveFailures: maxFailures,
+
+		ClientCertFile: common.GetenvTrim("WEBHOOK_CLIENT_CERT_FILE"),
+		ClientKeyFile:  common.GetenvTrim("WEBHOOK_CLIENT_KEY_FILE"),
+		CAFile:         common.GetenvTrim("WEBHOOK_CA_FILE"),
+	}
+	if (section.ClientCertFile == "") != (section.ClientKeyFile == "") {
+		return WebhookSection{}, fmt.Errorf("WEBHOOK_CLIENT_CERT_FILE and WEBHOOK_CLIENT_KEY_FILE must be set together")
+	}
+	if section.ClientCertFile != "" {
+		if _, err := tls.LoadX509KeyPair(section.ClientCertFile, section.ClientKeyFile); err != nil {
+			return WebhookSection{}, fmt.Errorf("WEBHOOK_CLIENT_CERT_FILE/WEBHOOK_CLIENT_KEY_FILE: %w", err)
+		}
+	}
+	if section.CAFile != "" {
+		pem, err := os.ReadFile(section.CAFile)
+		if err != nil {
+			return WebhookSection{}, fmt.Errorf("WEBHOOK_CA_FILE: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return WebhookSection{}, fmt.Errorf("WEBHOOK_CA_FILE contains no PEM certificates")
+		}
+	}
+	for _, pin := range strings.Split(common.GetenvTrim("WEBHOOK_TLS_PINS"), ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		if raw, err := base64.StdEncoding.DecodeString(pin); err != nil || len(raw) != sha256.Size {
+			return WebhookSection{}, fmt.Errorf("WEBHOOK_TLS_PINS entries must be base64 SHA-256 hashes, got %q", pin)
+		}
+		section.TLSPins = append(section.TLSPins, pin)
+	}
+	return section, nil
 }
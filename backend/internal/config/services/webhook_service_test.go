@@ -47,4 +47,62 @@ func TestWebhookModule_LoadAndValidate(t *testing.T) {
 			t.Fatalf("AllowlistHosts = %q, want trimmed value", section.AllowlistHosts)
 		}
 	})
+
+	t.Run("WEBHOOK_MAX_CONSECUTIVE_FAILURES", func(t *testing.T) {
+		t.Setenv("WEBHOOK_MAX_CONSECUTIVE_FAILURES", "")
+		section, err := WebhookModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MaxConsecutiveFailures != 5 {
+			t.Fatalf("MaxConsecutiveFailures = %d, want default 5", section.MaxConsecutiveFailures)
+		}
+
+		t.Setenv("WEBHOOK_MAX_CONSECUTIVE_FAILURES", "0")
+		section, err = WebhookModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.MaxConsecutiveFailures != 0 {
+			t.Fatalf("MaxConsecutiveFailures = %d, want 0", section.MaxConsecutiveFailures)
+		}
+
+		t.Setenv("WEBHOOK_MAX_CONSECUTIVE_FAILURES", "-1")
+		if _, err := (WebhookModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for negative WEBHOOK_MAX_CONSECUTIVE_FAILURES")
+		}
+	})
+
+	t.Run("webhook TLS settings", func(t *testing.T) {
+		t.Setenv("WEBHOOK_MAX_CONSECUTIVE_FAILURES", "")
+		t.Setenv("WEBHOOK_CLIENT_CERT_FILE", "")
+		t.Setenv("WEBHOOK_CLIENT_KEY_FILE", "")
+		t.Setenv("WEBHOOK_CA_FILE", "")
+		pin := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+		t.Setenv("WEBHOOK_TLS_PINS", " "+pin+" ,")
+		section, err := WebhookModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(section.TLSPins) != 1 || section.TLSPins[0] != pin {
+			t.Fatalf("TLSPins = %v, want [%s]", section.TLSPins, pin)
+		}
+
+		for name, env := range map[string]map[string]string{
+			"cert without key":  {"WEBHOOK_CLIENT_CERT_FILE": "/tmp/client.pem"},
+			"missing cert file": {"WEBHOOK_CLIENT_CERT_FILE": "/nonexistent/client.pem", "WEBHOOK_CLIENT_KEY_FILE": "/nonexistent/client.key"},
+			"missing CA file":   {"WEBHOOK_CA_FILE": "/nonexistent/ca.pem"},
+			"malformed pin":     {"WEBHOOK_TLS_PINS": "not-a-hash"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				t.Setenv("WEBHOOK_TLS_PINS", "")
+				for key, value := range env {
+					t.Setenv(key, value)
+				}
+				if _, err := (WebhookModule{}).LoadAndValidate(); err == nil {
+					t.Fatalf("expected error for %v", env)
+				}
+			})
+		}
+	})
 }
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
 )
 
@@ -17,10 +21,95 @@ func init() {
 
 type WorkerSection struct {
 	BaseURL string
+	// NTPServer enables the startup clock-skew check when set.
+	NTPServer         string
+	NTPMaxSkewSeconds int
+	// StartupGraceMinutes defers triggering after a detected outage so the
+	// owner can check in first; 0 disables the grace period.
+	StartupGraceMinutes int
+	// CreationGraceSeconds delays the first trigger of a new switch past one
+	// full trigger duration from creation.
+	CreationGraceSeconds int
+	// AttachmentRetentionDays keeps a triggered switch's attachments
+	// downloadable from its reveal link for that long; 0 deletes them once
+	// they have been emailed.
+	AttachmentRetentionDays int
+	// ShredAfterDelivery overwrites the content of triggered switches once
+	// ContentRetentionDays have passed since delivery; 0 days shreds it on
+	// the next worker tick.
+	ShredAfterDelivery   bool
+	ContentRetentionDays int
+	// HeartbeatTemplate is an optional html/template file replacing the
+	// built-in quick-heartbeat pages.
+	HeartbeatTemplate string
+	// ReminderResendHours re-sends a reminder that got no check-in after this
+	// many hours; 0 sends each reminder once.
+	ReminderResendHours int
+	// UndeliverableAction decides what happens to a due switch when its
+	// owner has neither SMTP nor an enabled webhook: "hold" keeps it active,
+	// "error" parks it in the error status, and "trigger" marks it triggered
+	// without delivering anything.
+	UndeliverableAction string
+	// HeartbeatConfirmationIntervalMinutes throttles the "check-in
+	// received" emails of accounts that enable heartbeat_confirmation.
+	HeartbeatConfirmationIntervalMinutes int
+	// WatchdogMissedTicks emails account owners when the worker has not
+	// completed a tick in this many intervals; 0 disables the watchdog.
+	WatchdogMissedTicks int
 }
 
 func (WorkerModule) LoadAndValidate() (WorkerSection, error) {
+	grace := common.GetInt("STARTUP_GRACE_MINUTES", 0)
+	if grace < 0 {
+		return WorkerSection{}, fmt.Errorf("STARTUP_GRACE_MINUTES must not be negative")
+	}
+	creationGrace := common.GetInt("CREATION_GRACE_SECONDS", common.DefaultCreationGraceSeconds)
+	if creationGrace < 0 {
+		return WorkerSection{}, fmt.Errorf("CREATION_GRACE_SECONDS must not be negative")
+	}
+	retention := common.GetInt("ATTACHMENT_RETENTION_DAYS", 0)
+	if retention < 0 || retention > common.MaxAttachmentRetentionDays {
+		return WorkerSection{}, fmt.Errorf("ATTACHMENT_RETENTION_DAYS must be between 0 and %d", common.MaxAttachmentRetentionDays)
+	}
+	contentRetention := common.GetInt("CONTENT_RETENTION_DAYS", 0)
+	if contentRetention < 0 || contentRetention > common.MaxAttachmentRetentionDays {
+		return WorkerSection{}, fmt.Errorf("CONTENT_RETENTION_DAYS must be between 0 and %d", common.MaxAttachmentRetentionDays)
+	}
+	resend := common.GetInt("REMINDER_RESEND_INTERVAL_HOURS", 0)
+	if resend < 0 {
+		return WorkerSection{}, fmt.Errorf("REMINDER_RESEND_INTERVAL_HOURS must not be negative")
+	}
+	undeliverable := strings.ToLower(common.WithDefault(common.GetenvTrim("UNDELIVERABLE_ACTION"), common.DefaultUndeliverableAction))
+	switch undeliverable {
+	case common.UndeliverableActionHold, common.UndeliverableActionError, common.UndeliverableActionTrigger:
+	default:
+		return WorkerSection{}, fmt.Errorf("UNDELIVERABLE_ACTION must be %q, %q or %q", common.UndeliverableActionHold, common.UndeliverableActionError, common.UndeliverableActionTrigger)
+	}
+	watchdog := common.GetInt("WORKER_WATCHDOG_MISSED_TICKS", 0)
+	if watchdog < 0 || watchdog == 1 {
+		return WorkerSection{}, fmt.Errorf("WORKER_WATCHDOG_MISSED_TICKS must be 0 or at least 2")
+	}
+	heartbeatTemplate := common.GetenvTrim("HEARTBEAT_TEMPLATE")
+	if heartbeatTemplate != "" {
+		if _, err := os.Stat(heartbeatTemplate); err != nil {
+			return WorkerSection{}, fmt.Errorf("HEARTBEAT_TEMPLATE: %w", err)
+		}
+	}
 	return WorkerSection{
-		BaseURL: common.WithDefault(common.GetenvTrim("BASE_URL"), common.DefaultWorkerBaseURL),
+		BaseURL:             common.WithDefault(common.GetenvTrim("BASE_URL"), common.DefaultWorkerBaseURL),
+		NTPServer:           common.GetenvTrim("NTP_SERVER"),
+		NTPMaxSkewSeconds:   common.GetPositiveInt("NTP_MAX_SKEW_SECONDS", common.DefaultNTPMaxSkewSeconds),
+		StartupGraceMinutes: grace,
+		HeartbeatTemplate:   heartbeatTemplate,
+
+		CreationGraceSeconds:    creationGrace,
+		AttachmentRetentionDays: retention,
+		ShredAfterDelivery:      common.GetBool("SHRED_AFTER_DELIVERY", false),
+		ContentRetentionDays:    contentRetention,
+		ReminderResendHours:     resend,
+		UndeliverableAction:     undeliverable,
+
+		HeartbeatConfirmationIntervalMinutes: common.GetPositiveInt("HEARTBEAT_CONFIRMATION_INTERVAL_MINUTES", common.DefaultHeartbeatConfirmationIntervalMinutes),
+		WatchdogMissedTicks:                  watchdog,
 	}, nil
 }
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/alpyxn/aeterna/backend/internal/config/common"
@@ -39,6 +41,251 @@ func TestWorkerModule_LoadAndValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("NTP check disabled by default", func(t *testing.T) {
+		t.Setenv("NTP_SERVER", "")
+		t.Setenv("NTP_MAX_SKEW_SECONDS", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.NTPServer != "" {
+			t.Fatalf("NTPServer = %q, want empty", section.NTPServer)
+		}
+		if section.NTPMaxSkewSeconds != common.DefaultNTPMaxSkewSeconds {
+			t.Fatalf("NTPMaxSkewSeconds = %d, want default %d", section.NTPMaxSkewSeconds, common.DefaultNTPMaxSkewSeconds)
+		}
+	})
+
+	t.Run("custom NTP settings", func(t *testing.T) {
+		t.Setenv("NTP_SERVER", " pool.ntp.org ")
+		t.Setenv("NTP_MAX_SKEW_SECONDS", "5")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.NTPServer != "pool.ntp.org" || section.NTPMaxSkewSeconds != 5 {
+			t.Fatalf("got NTPServer=%q NTPMaxSkewSeconds=%d", section.NTPServer, section.NTPMaxSkewSeconds)
+		}
+	})
+
+	t.Run("startup grace", func(t *testing.T) {
+		t.Setenv("STARTUP_GRACE_MINUTES", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.StartupGraceMinutes != 0 {
+			t.Fatalf("StartupGraceMinutes = %d, want 0 by default", section.StartupGraceMinutes)
+		}
+
+		t.Setenv("STARTUP_GRACE_MINUTES", "120")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.StartupGraceMinutes != 120 {
+			t.Fatalf("StartupGraceMinutes = %d, want 120", section.StartupGraceMinutes)
+		}
+
+		t.Setenv("STARTUP_GRACE_MINUTES", "-5")
+		if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for negative STARTUP_GRACE_MINUTES")
+		}
+	})
+
+	t.Run("CREATION_GRACE_SECONDS", func(t *testing.T) {
+		t.Setenv("CREATION_GRACE_SECONDS", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.CreationGraceSeconds != common.DefaultCreationGraceSeconds {
+			t.Fatalf("CreationGraceSeconds = %d, want default %d", section.CreationGraceSeconds, common.DefaultCreationGraceSeconds)
+		}
+
+		t.Setenv("CREATION_GRACE_SECONDS", "0")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.CreationGraceSeconds != 0 {
+			t.Fatalf("CreationGraceSeconds = %d, want 0", section.CreationGraceSeconds)
+		}
+
+		t.Setenv("CREATION_GRACE_SECONDS", "-1")
+		if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for negative CREATION_GRACE_SECONDS")
+		}
+	})
+
+	t.Run("ATTACHMENT_RETENTION_DAYS", func(t *testing.T) {
+		t.Setenv("ATTACHMENT_RETENTION_DAYS", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.AttachmentRetentionDays != 0 {
+			t.Fatalf("AttachmentRetentionDays = %d, want 0 by default", section.AttachmentRetentionDays)
+		}
+
+		t.Setenv("ATTACHMENT_RETENTION_DAYS", "30")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.AttachmentRetentionDays != 30 {
+			t.Fatalf("AttachmentRetentionDays = %d, want 30", section.AttachmentRetentionDays)
+		}
+
+		for _, bad := range []string{"-1", "3651"} {
+			t.Setenv("ATTACHMENT_RETENTION_DAYS", bad)
+			if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected error for ATTACHMENT_RETENTION_DAYS=%s", bad)
+			}
+		}
+	})
+
+	t.Run("SHRED_AFTER_DELIVERY", func(t *testing.T) {
+		t.Setenv("SHRED_AFTER_DELIVERY", "")
+		t.Setenv("CONTENT_RETENTION_DAYS", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.ShredAfterDelivery || section.ContentRetentionDays != 0 {
+			t.Fatalf("got ShredAfterDelivery=%v ContentRetentionDays=%d, want off and 0 by default", section.ShredAfterDelivery, section.ContentRetentionDays)
+		}
+
+		t.Setenv("SHRED_AFTER_DELIVERY", "true")
+		t.Setenv("CONTENT_RETENTION_DAYS", "7")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !section.ShredAfterDelivery || section.ContentRetentionDays != 7 {
+			t.Fatalf("got ShredAfterDelivery=%v ContentRetentionDays=%d, want on and 7", section.ShredAfterDelivery, section.ContentRetentionDays)
+		}
+
+		for _, bad := range []string{"-1", "3651"} {
+			t.Setenv("CONTENT_RETENTION_DAYS", bad)
+			if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected error for CONTENT_RETENTION_DAYS=%s", bad)
+			}
+		}
+	})
+
+	t.Run("REMINDER_RESEND_INTERVAL_HOURS", func(t *testing.T) {
+		t.Setenv("REMINDER_RESEND_INTERVAL_HOURS", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.ReminderResendHours != 0 {
+			t.Fatalf("ReminderResendHours = %d, want 0 by default", section.ReminderResendHours)
+		}
+
+		t.Setenv("REMINDER_RESEND_INTERVAL_HOURS", "12")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.ReminderResendHours != 12 {
+			t.Fatalf("ReminderResendHours = %d, want 12", section.ReminderResendHours)
+		}
+
+		t.Setenv("REMINDER_RESEND_INTERVAL_HOURS", "-1")
+		if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for negative REMINDER_RESEND_INTERVAL_HOURS")
+		}
+	})
+
+	t.Run("UNDELIVERABLE_ACTION", func(t *testing.T) {
+		t.Setenv("UNDELIVERABLE_ACTION", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.UndeliverableAction != common.UndeliverableActionHold {
+			t.Fatalf("UndeliverableAction = %q, want hold by default", section.UndeliverableAction)
+		}
+
+		t.Setenv("UNDELIVERABLE_ACTION", " Error ")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.UndeliverableAction != common.UndeliverableActionError {
+			t.Fatalf("UndeliverableAction = %q, want error", section.UndeliverableAction)
+		}
+
+		t.Setenv("UNDELIVERABLE_ACTION", "ignore")
+		if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for an unknown UNDELIVERABLE_ACTION")
+		}
+	})
+
+	t.Run("HEARTBEAT_TEMPLATE must exist", func(t *testing.T) {
+		t.Setenv("STARTUP_GRACE_MINUTES", "")
+		path := filepath.Join(t.TempDir(), "heartbeat.html")
+		if err := os.WriteFile(path, []byte("<p>{{.BrandName}}</p>"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("HEARTBEAT_TEMPLATE", path)
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.HeartbeatTemplate != path {
+			t.Fatalf("HeartbeatTemplate = %q, want %q", section.HeartbeatTemplate, path)
+		}
+
+		t.Setenv("HEARTBEAT_TEMPLATE", filepath.Join(t.TempDir(), "missing.html"))
+		if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+			t.Fatal("expected error for missing HEARTBEAT_TEMPLATE")
+		}
+	})
+
+	t.Run("HEARTBEAT_CONFIRMATION_INTERVAL_MINUTES", func(t *testing.T) {
+		t.Setenv("HEARTBEAT_CONFIRMATION_INTERVAL_MINUTES", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.HeartbeatConfirmationIntervalMinutes != common.DefaultHeartbeatConfirmationIntervalMinutes {
+			t.Fatalf("HeartbeatConfirmationIntervalMinutes = %d, want default %d", section.HeartbeatConfirmationIntervalMinutes, common.DefaultHeartbeatConfirmationIntervalMinutes)
+		}
+
+		t.Setenv("HEARTBEAT_CONFIRMATION_INTERVAL_MINUTES", "15")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if section.HeartbeatConfirmationIntervalMinutes != 15 {
+			t.Fatalf("HeartbeatConfirmationIntervalMinutes = %d, want 15", section.HeartbeatConfirmationIntervalMinutes)
+		}
+	})
+
+	t.Run("WORKER_WATCHDOG_MISSED_TICKS", func(t *testing.T) {
+		t.Setenv("WORKER_WATCHDOG_MISSED_TICKS", "")
+		section, err := WorkerModule{}.LoadAndValidate()
+		if err != nil || section.WatchdogMissedTicks != 0 {
+			t.Fatalf("got %d (%v), want the watchdog off by default", section.WatchdogMissedTicks, err)
+		}
+
+		t.Setenv("WORKER_WATCHDOG_MISSED_TICKS", "5")
+		section, err = WorkerModule{}.LoadAndValidate()
+		if err != nil || section.WatchdogMissedTicks != 5 {
+			t.Fatalf("got %d (%v), want 5", section.WatchdogMissedTicks, err)
+		}
+
+		for _, v := range []string{"-1", "1"} {
+			t.Setenv("WORKER_WATCHDOG_MISSED_TICKS", v)
+			if _, err := (WorkerModule{}).LoadAndValidate(); err == nil {
+				t.Fatalf("expected error for WORKER_WATCHDOG_MISSED_TICKS=%s", v)
+			}
+		}
+	})
+
 	t.Run("BASE_URL whitespace is trimmed", func(t *testing.T) {
 		t.Setenv("BASE_URL", "  https://app.example.com  ")
 		section, err := WorkerModule{}.LoadAndValidate()
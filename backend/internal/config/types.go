@@ -6,13 +6,16 @@ import (
 )
 
 type Config struct {
-	App      services.AppSection      `config:"app"`
-	Database services.DatabaseSection `config:"database"`
-	HTTP     services.HTTPSection     `config:"http"`
-	Auth     services.AuthSection     `config:"auth"`
-	Logging  services.LoggingSection  `config:"logging"`
-	Worker   services.WorkerSection   `config:"worker"`
-	Webhook  services.WebhookSection  `config:"webhook"`
+	App        services.AppSection        `config:"app"`
+	Database   services.DatabaseSection   `config:"database"`
+	HTTP       services.HTTPSection       `config:"http"`
+	Auth       services.AuthSection       `config:"auth"`
+	Logging    services.LoggingSection    `config:"logging"`
+	Worker     services.WorkerSection     `config:"worker"`
+	Webhook    services.WebhookSection    `config:"webhook"`
+	Antivirus  services.AntivirusSection  `config:"antivirus"`
+	Attachment services.AttachmentSection `config:"attachment"`
+	SMTP       services.SMTPSection       `config:"smtp"`
 }
 
 type AppConfig = services.AppSection
@@ -22,6 +25,9 @@ type AuthConfig = services.AuthSection
 type LoggingConfig = services.LoggingSection
 type WorkerConfig = services.WorkerSection
 type WebhookConfig = services.WebhookSection
+type AntivirusConfig = services.AntivirusSection
+type AttachmentConfig = services.AttachmentSection
+type SMTPConfig = services.SMTPSection
 
 func (c Config) IsProduction() bool {
 	return c.App.Env == "production"
@@ -54,6 +54,9 @@ func Connect(cfg config.Config, enc SQLiteEncryptionConfig) {
 		mode = "encrypted"
 	}
 	log.Printf("Database connection successfully opened (%s): %s", mode, dbPath)
+	if !enc.Enabled && cfg.IsProduction() {
+		log.Println("WARNING: DB_ENCRYPTION_ENABLED=false. Recipient addresses, owner email and timestamps are stored in cleartext; enable it or keep the data directory on an encrypted disk.")
+	}
 }
 
 func connectEncrypted(dbPath string, enc SQLiteEncryptionConfig) (*gorm.DB, error) {
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// EncryptRecipientEmails seals recipient columns written before they were
+// field-encrypted and fills in the messages' blind index. Rows are resaved
+// through their models, so the registered models.RecipientCipher does the
+// sealing. Safe to call on every startup (idempotent).
+func EncryptRecipientEmails(db *gorm.DB, _ config.Config) error {
+	if !models.RecipientsEncrypted() {
+		return nil
+	}
+	pending := db.Unscoped().Where("recipient_email <> ''")
+
+	var messages []models.Message
+	if err := pending.Session(&gorm.Session{}).Where("recipient_index = '' OR recipient_email NOT LIKE 'enc:%'").Find(&messages).Error; err != nil {
+		return err
+	}
+	for i := range messages {
+		if err := db.Unscoped().Model(&messages[i]).Select("recipient_email", "recipient_index").Updates(&messages[i]).Error; err != nil {
+			return err
+		}
+	}
+	if err := resealRecipients[models.Attachment](db, pending); err != nil {
+		return err
+	}
+	if err := resealRecipients[models.FarewellLetter](db, pending); err != nil {
+		return err
+	}
+	return resealRecipients[models.MessageTombstone](db, pending)
+}
+
+func resealRecipients[T any](db, pending *gorm.DB) error {
+	var rows []T
+	if err := pending.Session(&gorm.Session{}).Where("recipient_email NOT LIKE 'enc:%'").Find(&rows).Error; err != nil {
+		return err
+	}
+	for i := range rows {
+		if err := db.Unscoped().Model(&rows[i]).Select("recipient_email").Updates(&rows[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -41,6 +41,12 @@ var orderedSteps = []Step{
 			return EnsureRefreshSessionIDIntegrity(db)
 		},
 	},
+	{
+		Date:        "20261014",
+		Name:        "recipient_email_encryption",
+		Description: "Field-encrypt recipient_email columns stored in the clear and backfill messages.recipient_index.",
+		Run:         EncryptRecipientEmails,
+	},
 }
 
 // RunPreAutoMigrate executes startup migrations that must happen before AutoMigrate.
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/database/migrations"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// prefixRecipientCipher stands in for the services cipher: it seals by
+// prefixing the row the value is bound to.
+type prefixRecipientCipher struct{}
+
+func (prefixRecipientCipher) Seal(table, id, recipients string) (string, error) {
+	if strings.HasPrefix(recipients, "enc:") {
+		return recipients, nil
+	}
+	return "enc:" + table + "/" + id + ":" + recipients, nil
+}
+
+func (prefixRecipientCipher) Open(table, id, stored string) (string, error) {
+	return strings.TrimPrefix(stored, "enc:"+table+"/"+id+":"), nil
+}
+
+func (prefixRecipientCipher) Index(recipients string) (string, error) {
+	return "," + strings.ToLower(recipients) + ",", nil
+}
+
+func TestEncryptRecipientEmails_SealsPlaintextRows(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(refreshSessionTestDSN(t)), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Message{}, &models.Attachment{}, &models.FarewellLetter{}, &models.MessageTombstone{}); err != nil {
+		t.Fatal(err)
+	}
+	// Rows written before recipients were encrypted.
+	if err := db.Create(&models.Message{
+		ID: "m-legacy", UserID: "u1", Content: "x", KeyFragment: "v1", ManagementToken: "tok-m-legacy",
+		RecipientEmail: "Alice@example.com", TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Attachment{ID: "a-legacy", UserID: "u1", MessageID: "m-legacy", RecipientEmail: "alice@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Attachment{ID: "a-open", UserID: "u1", MessageID: "m-legacy"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	models.SetRecipientCipher(prefixRecipientCipher{})
+	t.Cleanup(func() { models.SetRecipientCipher(nil) })
+	for run := 0; run < 2; run++ {
+		if err := migrations.EncryptRecipientEmails(db, config.Config{}); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	var msg struct{ RecipientEmail, RecipientIndex string }
+	if err := db.Raw("SELECT recipient_email, recipient_index FROM messages WHERE id = ?", "m-legacy").Scan(&msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.RecipientEmail != "enc:messages/m-legacy:Alice@example.com" || msg.RecipientIndex != ",alice@example.com," {
+		t.Fatalf("message recipients = %+v, want sealed once and indexed", msg)
+	}
+	stored := map[string]string{}
+	var attachments []struct{ ID, RecipientEmail string }
+	if err := db.Raw("SELECT id, recipient_email FROM attachments").Scan(&attachments).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, att := range attachments {
+		stored[att.ID] = att.RecipientEmail
+	}
+	if stored["a-legacy"] != "enc:attachments/a-legacy:alice@example.com" || stored["a-open"] != "" {
+		t.Fatalf("attachment recipients = %v, want the restricted one sealed and the open one empty", stored)
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const (
+	lockRetryAttempts  = 4
+	lockRetryBaseDelay = 50 * time.Millisecond
+)
+
+// lockRetrySleep is replaced in tests.
+var lockRetrySleep = time.Sleep
+
+// RetryOnLock runs write again, after a jittered backoff, while SQLite
+// reports the database as locked. busy_timeout already waits for most locks,
+// but WAL mode can still return SQLITE_BUSY straight away, for example when
+// a read transaction must be upgraded to a write. It is meant for the writes
+// whose loss would change what the worker does next, such as recording a
+// heartbeat; write must be safe to run more than once.
+func RetryOnLock(write func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = write(); err == nil || !IsLockError(err) || attempt == lockRetryAttempts {
+			return err
+		}
+		delay := lockRetryBaseDelay << (attempt - 1)
+		slog.Warn("Database locked, retrying write", "attempt", attempt, "error", err)
+		lockRetrySleep(delay/2 + rand.N(delay))
+	}
+}
+
+// IsLockError reports whether err is SQLite's SQLITE_BUSY or SQLITE_LOCKED.
+// The message is matched so the check does not depend on the driver.
+func IsLockError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "SQLITE_BUSY")
+}
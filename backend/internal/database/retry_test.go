@@ -0,0 +1,69 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRetryOnLockRetriesOnlyLockErrors(t *testing.T) {
+	var slept []time.Duration
+	prev := lockRetrySleep
+	lockRetrySleep = func(d time.Duration) { slept = append(slept, d) }
+	t.Cleanup(func() { lockRetrySleep = prev })
+
+	path := filepath.Join(t.TempDir(), "lock.db")
+	open := func() *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(path+"?_busy_timeout=0&_journal_mode=WAL"), &gorm.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.SetMaxOpenConns(1)
+		t.Cleanup(func() { sqlDB.Close() })
+		return db
+	}
+	holder, writer := open(), open()
+	if err := holder.Exec("CREATE TABLE beats (n INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	lock := holder.Begin()
+	if err := lock.Exec("INSERT INTO beats VALUES (1)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err := RetryOnLock(func() error {
+		calls++
+		err := writer.Exec("INSERT INTO beats VALUES (2)").Error
+		if calls == 1 {
+			if !IsLockError(err) {
+				t.Fatalf("expected the driver to report a lock, got %v", err)
+			}
+			lock.Commit()
+		}
+		return err
+	})
+	if err != nil || calls != 2 || len(slept) != 1 {
+		t.Fatalf("expected one retry after the lock was released: err=%v calls=%d sleeps=%v", err, calls, slept)
+	}
+	if slept[0] < lockRetryBaseDelay/2 || slept[0] >= lockRetryBaseDelay*3/2 {
+		t.Fatalf("backoff %s outside the jitter range", slept[0])
+	}
+
+	slept, calls = nil, 0
+	locked := errors.New("database is locked")
+	if err := RetryOnLock(func() error { calls++; return locked }); !errors.Is(err, locked) || calls != lockRetryAttempts {
+		t.Fatalf("expected %d attempts, got %d (%v)", lockRetryAttempts, calls, err)
+	}
+
+	calls = 0
+	other := errors.New("constraint failed")
+	if err := RetryOnLock(func() error { calls++; return other }); !errors.Is(err, other) || calls != 1 {
+		t.Fatalf("other errors must not be retried: %d calls (%v)", calls, err)
+	}
+}
@@ -72,6 +72,47 @@ func (h *AttachmentHandlers) List(c *fiber.Ctx) error {
 	return c.JSON(attachments)
 }
 
+func (h *AttachmentHandlers) Thumbnail(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	data, err := h.files.GetThumbnail(userID, c.Params("id"))
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "image/jpeg")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Send(data)
+}
+
+func (h *AttachmentHandlers) SetRecipients(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	files := withOriginSession(c, h.files)
+
+	var req struct {
+		RecipientEmails []string `json:"recipient_emails"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return writeError(c, services.BadRequest("Invalid request body", err))
+	}
+
+	attachment, err := files.SetRecipients(userID, c.Params("attachmentId"), req.RecipientEmails)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"attachment": attachment,
+	})
+}
+
 func (h *AttachmentHandlers) Delete(c *fiber.Ctx) error {
 	userID, err := currentUserID(c)
 	if err != nil {
@@ -89,3 +130,42 @@ func (h *AttachmentHandlers) Delete(c *fiber.Ctx) error {
 		"message": "Attachment deleted successfully",
 	})
 }
+
+// ListRevealed lists the attachments of a triggered switch that recipients
+// may download from the reveal link (unauthenticated endpoint).
+func (h *AttachmentHandlers) ListRevealed(c *fiber.Ctx) error {
+	attachments, err := h.files.ListRevealable(c.Params("id"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(attachments)
+}
+
+// DownloadRevealed serves one attachment listed by ListRevealed
+// (unauthenticated endpoint).
+func (h *AttachmentHandlers) DownloadRevealed(c *fiber.Ctx) error {
+	filename, mimeType, data, err := h.files.GetRevealable(c.Params("id"), c.Params("attachmentId"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	c.Set(fiber.HeaderContentType, mimeType)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Attachment(filename)
+	return c.Send(data)
+}
+
+// Storage reports the caller's attachment storage against
+// MAX_TOTAL_STORAGE_MB, and to the primary administrator what every account
+// uses together.
+func (h *AttachmentHandlers) Storage(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	usage, err := h.files.StorageUsage(userID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(usage)
+}
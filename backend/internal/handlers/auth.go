@@ -41,12 +41,24 @@ const (
 
 // AuthHandlers groups all authentication-related route handlers.
 type AuthHandlers struct {
-	auth ports.AuthServicePort
-	cfg  config.Config
+	auth     ports.AuthServicePort
+	lockouts ports.LockoutAlertPort
+	cfg      config.Config
 }
 
-func NewAuthHandlers(auth ports.AuthServicePort, cfg config.Config) *AuthHandlers {
-	return &AuthHandlers{auth: auth, cfg: cfg}
+// NewAuthHandlers wires auth routes; lockouts may be nil to skip lockout
+// alerts.
+func NewAuthHandlers(auth ports.AuthServicePort, lockouts ports.LockoutAlertPort, cfg config.Config) *AuthHandlers {
+	return &AuthHandlers{auth: auth, lockouts: lockouts, cfg: cfg}
+}
+
+// recordFailedLogin counts a failed attempt and alerts the targeted account
+// when it locks the client IP out.
+func (h *AuthHandlers) recordFailedLogin(c *fiber.Ctx, email string) {
+	attempts, locked := middleware.RecordFailedLogin(c.IP())
+	if locked && h.lockouts != nil {
+		h.lockouts.NotifyLockout(email, c.IP(), attempts)
+	}
 }
 
 func (h *AuthHandlers) SetupStatus(c *fiber.Ctx) error {
@@ -81,7 +93,7 @@ func (h *AuthHandlers) setupMasterPassword(c *fiber.Ctx, mode sessionMode) error
 		return writeError(c, err)
 	}
 	if configured {
-		return writeError(c, services.NewAPIError(400, "already_configured", "An account already exists. Sign in instead.", nil))
+		return writeError(c, services.NewAPIError(400, services.CodeAlreadyConfigured, "An account already exists. Sign in instead.", nil))
 	}
 
 	var req registerRequest
@@ -152,7 +164,7 @@ func (h *AuthHandlers) login(c *fiber.Ctx, mode sessionMode, requireEmail bool)
 	}
 	user, err := h.auth.Login(req.Email, req.Password)
 	if err != nil {
-		middleware.RecordFailedLogin(c.IP())
+		h.recordFailedLogin(c, req.Email)
 		return writeError(c, err)
 	}
 	middleware.RecordSuccessfulLogin(c.IP())
@@ -169,7 +181,7 @@ func (h *AuthHandlers) VerifyMasterPassword(c *fiber.Ctx) error {
 }
 
 func (h *AuthHandlers) SessionStatus(c *fiber.Ctx) error {
-	token := c.Cookies("aeterna_session")
+	token := c.Cookies(middleware.SessionCookie(c, h.cfg.Auth).Name)
 	userID, err := h.auth.VerifySessionToken(token)
 	if err != nil {
 		return writeError(c, err)
@@ -180,7 +192,7 @@ func (h *AuthHandlers) SessionStatus(c *fiber.Ctx) error {
 func (h *AuthHandlers) SessionStatusV2(c *fiber.Ctx) error {
 	token, ok := middleware.ExtractBearerToken(c.Get("Authorization"))
 	if !ok {
-		token = c.Cookies("aeterna_session")
+		token = c.Cookies(middleware.SessionCookie(c, h.cfg.Auth).Name)
 	}
 	userID, err := h.auth.VerifySessionToken(token)
 	if err != nil {
@@ -197,7 +209,7 @@ func (h *AuthHandlers) RefreshV2(c *fiber.Ctx) error {
 		}
 	}
 	if strings.TrimSpace(req.RefreshToken) == "" {
-		return writeError(c, services.NewAPIError(401, "unauthorized", "Invalid refresh token.", nil))
+		return writeError(c, services.NewAPIError(401, services.CodeUnauthorized, "Invalid refresh token.", nil))
 	}
 	userID, accessToken, accessExp, nextRefreshToken, nextRefreshExp, err := h.auth.RefreshSessionPair(req.RefreshToken)
 	if err != nil {
@@ -237,7 +249,7 @@ func (h *AuthHandlers) resetPassword(c *fiber.Ctx, mode sessionMode) error {
 
 	newRecoveryKey, err := h.auth.ResetPasswordWithRecovery(req.Email, req.RecoveryKey, req.NewPassword)
 	if err != nil {
-		middleware.RecordFailedLogin(c.IP())
+		h.recordFailedLogin(c, req.Email)
 		return writeError(c, err)
 	}
 	middleware.RecordSuccessfulLogin(c.IP())
@@ -295,29 +307,16 @@ func (h *AuthHandlers) issueSessionCookie(c *fiber.Ctx, userID string) error {
 	if err != nil {
 		return err
 	}
-	secure := middleware.ShouldUseSecureCookie(c, h.cfg.Auth.CookieSecureMode)
-	c.Cookie(&fiber.Cookie{
-		Name:     "aeterna_session",
-		Value:    token,
-		Expires:  exp,
-		Path:     "/",
-		HTTPOnly: true,
-		Secure:   secure,
-		SameSite: fiber.CookieSameSiteStrictMode,
-	})
+	cookie := middleware.SessionCookie(c, h.cfg.Auth)
+	cookie.Value = token
+	cookie.Expires = exp
+	c.Cookie(cookie)
 	return nil
 }
 
 func (h *AuthHandlers) clearSessionCookie(c *fiber.Ctx) {
-	secure := middleware.ShouldUseSecureCookie(c, h.cfg.Auth.CookieSecureMode)
-	c.Cookie(&fiber.Cookie{
-		Name:     "aeterna_session",
-		Value:    "",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
-		Path:     "/",
-		HTTPOnly: true,
-		Secure:   secure,
-		SameSite: fiber.CookieSameSiteStrictMode,
-	})
+	cookie := middleware.SessionCookie(c, h.cfg.Auth)
+	cookie.Expires = time.Unix(0, 0)
+	cookie.MaxAge = -1
+	c.Cookie(cookie)
 }
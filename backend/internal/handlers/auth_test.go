@@ -107,7 +107,7 @@ func TestRefreshV2IssuesNewBearerToken(t *testing.T) {
 		refreshNextExp:   refreshExp,
 	}
 	app := fiber.New()
-	app.Post("/api/v2/auth/refresh", NewAuthHandlers(auth, config.Config{}).RefreshV2)
+	app.Post("/api/v2/auth/refresh", NewAuthHandlers(auth, nil, config.Config{}).RefreshV2)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v2/auth/refresh", strings.NewReader(`{"refresh_token":"old-refresh"}`))
 	req.Header.Set("Content-Type", "application/json")
@@ -142,7 +142,7 @@ func TestRefreshV2IssuesNewBearerToken(t *testing.T) {
 
 func TestRefreshV2RequiresRefreshToken(t *testing.T) {
 	app := fiber.New()
-	app.Post("/api/v2/auth/refresh", NewAuthHandlers(fakeAuthService{}, config.Config{}).RefreshV2)
+	app.Post("/api/v2/auth/refresh", NewAuthHandlers(fakeAuthService{}, nil, config.Config{}).RefreshV2)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v2/auth/refresh", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -162,7 +162,7 @@ func TestRefreshV2RejectsInvalidToken(t *testing.T) {
 		refreshErr: services.NewAPIError(401, "unauthorized", "Refresh token has expired.", errors.New("expired")),
 	}
 	app := fiber.New()
-	app.Post("/api/v2/auth/refresh", NewAuthHandlers(auth, config.Config{}).RefreshV2)
+	app.Post("/api/v2/auth/refresh", NewAuthHandlers(auth, nil, config.Config{}).RefreshV2)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v2/auth/refresh", strings.NewReader(`{"refresh_token":"expired-token"}`))
 	req.Header.Set("Content-Type", "application/json")
@@ -176,3 +176,86 @@ func TestRefreshV2RejectsInvalidToken(t *testing.T) {
 		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
 	}
 }
+
+func TestSessionCookieNameAndDomainAreConfigurable(t *testing.T) {
+	cfg := config.Config{Auth: config.AuthConfig{SessionCookieName: "app2_session", SessionCookieDomain: "example.com"}}
+	h := NewAuthHandlers(fakeAuthService{verifyToken: "tok", verifyUser: "u1"}, nil, cfg)
+	app := fiber.New()
+	app.Get("/session", h.SessionStatus)
+	app.Post("/logout", h.Logout)
+
+	for cookie, want := range map[string]int{
+		"app2_session=tok":    http.StatusOK,
+		"aeterna_session=tok": http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/session", nil)
+		req.Header.Set("Cookie", cookie)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("Cookie %q: status = %d, want %d", cookie, resp.StatusCode, want)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/logout", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	setCookie := resp.Header.Get("Set-Cookie")
+	if !strings.HasPrefix(setCookie, "app2_session=;") || !strings.Contains(setCookie, "domain=example.com") {
+		t.Fatalf("Set-Cookie = %q, want the configured name and domain cleared", setCookie)
+	}
+}
+
+func TestSessionCookieHostPrefixOnHTTPS(t *testing.T) {
+	cfg := config.Config{Auth: config.AuthConfig{SessionCookieHostPrefix: true, CookieSecureMode: "always"}}
+	h := NewAuthHandlers(fakeAuthService{verifyToken: "tok", verifyUser: "u1"}, nil, cfg)
+	app := fiber.New()
+	app.Get("/session", h.SessionStatus)
+	app.Post("/logout", h.Logout)
+
+	for cookie, want := range map[string]int{
+		"__Host-aeterna_session=tok": http.StatusOK,
+		"aeterna_session=tok":        http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/session", nil)
+		req.Header.Set("Cookie", cookie)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("Cookie %q: status = %d, want %d", cookie, resp.StatusCode, want)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/logout", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	setCookie := resp.Header.Get("Set-Cookie")
+	if !strings.HasPrefix(setCookie, "__Host-aeterna_session=;") || !strings.Contains(setCookie, "path=/") ||
+		!strings.Contains(setCookie, "secure") || strings.Contains(setCookie, "domain=") {
+		t.Fatalf("Set-Cookie = %q, want a secure host-only __Host- cookie", setCookie)
+	}
+
+	// Over plain HTTP browsers would reject the prefixed cookie, so the bare
+	// name is kept.
+	cfg.Auth.CookieSecureMode = ""
+	app = fiber.New()
+	app.Post("/logout", NewAuthHandlers(fakeAuthService{}, nil, cfg).Logout)
+	resp, err = app.Test(httptest.NewRequest(http.MethodPost, "/logout", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if setCookie := resp.Header.Get("Set-Cookie"); !strings.HasPrefix(setCookie, "aeterna_session=;") {
+		t.Fatalf("Set-Cookie = %q, want the unprefixed name over HTTP", setCookie)
+	}
+}
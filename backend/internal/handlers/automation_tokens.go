@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AutomationTokenHandlers groups route handlers for heartbeat-only
+// automation tokens.
+type AutomationTokenHandlers struct {
+	tokens ports.AutomationTokenServicePort
+}
+
+func NewAutomationTokenHandlers(tokens ports.AutomationTokenServicePort) *AutomationTokenHandlers {
+	return &AutomationTokenHandlers{tokens: tokens}
+}
+
+type addAutomationTokenRequest struct {
+	Label string `json:"label"`
+}
+
+// List returns the caller's automation tokens by label, including revoked
+// ones. Token values are never returned.
+func (h *AutomationTokenHandlers) List(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	tokens, err := h.tokens.List(userID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(tokens)
+}
+
+// Add creates an automation token. The token is only returned in this
+// response.
+func (h *AutomationTokenHandlers) Add(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var req addAutomationTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return writeError(c, services.BadRequest("Invalid request body", err))
+	}
+	value, token, err := h.tokens.Add(userID, req.Label)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"token": value, "automation_token": token})
+}
+
+// Revoke stops an automation token from checking in.
+func (h *AutomationTokenHandlers) Revoke(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return writeError(c, services.BadRequest("Invalid automation token id", err))
+	}
+	token, err := h.tokens.Revoke(userID, uint(id))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(token)
+}
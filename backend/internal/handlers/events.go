@@ -47,10 +47,7 @@ func (h *EventsHandlers) Stream(c *fiber.Ctx) error {
 
 	ch, done, cancel, err := h.stream.Subscribe(userID, clientID, sessionKey)
 	if err != nil {
-		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-			"error": err.Error(),
-			"code":  "sse_limit_exceeded",
-		})
+		return writeError(c, services.NewAPIError(fiber.StatusTooManyRequests, services.CodeSSELimitExceeded, err.Error(), nil))
 	}
 
 	c.Set("Content-Type", "text/event-stream")
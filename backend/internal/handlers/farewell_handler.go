@@ -27,7 +27,7 @@ func parseFarewellPayload(c *fiber.Ctx) (farewellPayload, error) {
 		return farewellPayload{}, services.BadRequest("Invalid request body", err)
 	}
 	if body.DelayMinutes == nil {
-		return farewellPayload{}, services.BadRequest("delay_minutes is required and must be a number", nil)
+		return farewellPayload{}, services.InvalidField("delay_minutes", "delay_minutes is required and must be a number", nil)
 	}
 	return body, nil
 }
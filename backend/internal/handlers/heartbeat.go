@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"html/template"
+	"strings"
+	"time"
+
 	"github.com/alpyxn/aeterna/backend/internal/ports"
 	"github.com/alpyxn/aeterna/backend/internal/services"
 	"github.com/gofiber/fiber/v2"
@@ -10,17 +14,23 @@ import (
 type HeartbeatHandlers struct {
 	messages ports.MessageServicePort
 	settings ports.SettingsServicePort
+	page     *template.Template
 }
 
-func NewHeartbeatHandlers(messages ports.MessageServicePort, settings ports.SettingsServicePort) *HeartbeatHandlers {
-	return &HeartbeatHandlers{messages: messages, settings: settings}
+// NewHeartbeatHandlers renders quick-heartbeat pages with page; nil selects
+// the built-in template.
+func NewHeartbeatHandlers(messages ports.MessageServicePort, settings ports.SettingsServicePort, page *template.Template) *HeartbeatHandlers {
+	if page == nil {
+		page = defaultHeartbeatTemplate
+	}
+	return &HeartbeatHandlers{messages: messages, settings: settings, page: page}
 }
 
 // QuickHeartbeat handles token-based heartbeat (no session auth required).
 func (h *HeartbeatHandlers) QuickHeartbeat(c *fiber.Ctx) error {
 	token := c.Params("token")
 	if token == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Token required"})
+		return writeError(c, services.BadRequest("Token required", nil))
 	}
 
 	settings, err := h.settings.GetByHeartbeatToken(token)
@@ -31,174 +41,92 @@ func (h *HeartbeatHandlers) QuickHeartbeat(c *fiber.Ctx) error {
 	userID := settings.UserID
 
 	if c.Method() == "POST" {
+		// The nonce is only obtainable by loading the prompt, so scanners
+		// that POST straight to the emailed link cannot check in.
+		if isPrefetchRequest(c) || !services.VerifyQuickHeartbeatNonce(token, c.FormValue("nonce"), time.Now()) {
+			return h.renderExpired(c, settings)
+		}
+		if err := h.checkIn(c, userID); err != nil {
+			return writeError(c, err)
+		}
+		return h.renderPage(c, settings, true)
+	}
+
+	if _, ok := services.ReminderActionExtension(c.Query("action")); !ok {
+		return writeError(c, services.BadRequest("Unknown action", nil))
+	}
+	return h.renderPage(c, settings, false)
+}
+
+// ConfirmQuickHeartbeat records a check-in from the one-click link on the
+// quick-heartbeat page, which is only offered when the owner enabled
+// quick_heartbeat_confirm_link; otherwise every request gets the prompt. The
+// link carries a short-lived nonce that is never emailed, and prefetch
+// requests are answered with the prompt instead.
+func (h *HeartbeatHandlers) ConfirmQuickHeartbeat(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return writeError(c, services.BadRequest("Token required", nil))
+	}
+
+	settings, err := h.settings.GetByHeartbeatToken(token)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	if !settings.QuickHeartbeatConfirmLink || isPrefetchRequest(c) || !services.VerifyQuickHeartbeatNonce(token, c.Query("nonce"), time.Now()) {
+		return h.renderExpired(c, settings)
+	}
+
+	if err := h.checkIn(c, settings.UserID); err != nil {
+		return writeError(c, err)
+	}
+	return h.renderPage(c, settings, true)
+}
+
+// checkIn applies the ?action= of a quick-heartbeat link: a check-in of every
+// switch by default, or a deadline extension of the ?message= switch, or of
+// all of them when none is given.
+func (h *HeartbeatHandlers) checkIn(c *fiber.Ctx, userID string) error {
+	by, ok := services.ReminderActionExtension(c.Query("action"))
+	if !ok {
+		return services.BadRequest("Unknown action", nil)
+	}
+	if by == 0 {
 		if err := h.messages.BulkHeartbeat(userID); err != nil {
-			return writeError(c, services.Internal("Failed to update heartbeats", err))
+			return services.Internal("Failed to update heartbeats", err)
 		}
+		return nil
+	}
+	return h.messages.ExtendDeadline(userID, c.Query("message"), by)
+}
+
+// isPrefetchRequest detects browser and mail-client speculative loads.
+func isPrefetchRequest(c *fiber.Ctx) bool {
+	for _, header := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(c.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return false
+}
 
-		html := `<!DOCTYPE html>
-<html>
-<head>
-    <title>Heartbeat Confirmed - Aeterna</title>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1">
-    <style>
-        body {
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-            background: #fafafa;
-            color: #333;
-            min-height: 100vh;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            margin: 0;
-        }
-        .container {
-            text-align: center;
-            padding: 2rem;
-            max-width: 400px;
-        }
-        h1 { font-size: 1.25rem; font-weight: 500; margin-bottom: 0.5rem; }
-        p { color: #666; font-size: 0.9rem; }
-        .footer { margin-top: 2rem; font-size: 0.75rem; color: #999; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>✓ Heartbeat Confirmed</h1>
-        <p>Your check-in has been recorded.</p>
-        <p class="footer">Aeterna</p>
-    </div>
-</body>
-</html>
-`
-		c.Set("Content-Type", "text/html; charset=utf-8")
-		return c.SendString(html)
-	}
-
-	html := `<!DOCTYPE html>
-<html>
-<head>
-    <title>Send Heartbeat - Aeterna</title>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1">
-    <style>
-        body {
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: #333;
-            min-height: 100vh;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            margin: 0;
-            padding: 1rem;
-        }
-        .container {
-            background: white;
-            border-radius: 12px;
-            box-shadow: 0 10px 40px rgba(0,0,0,0.1);
-            text-align: center;
-            padding: 3rem 2rem;
-            max-width: 400px;
-            width: 100%;
-        }
-        h1 {
-            font-size: 1.5rem;
-            font-weight: 600;
-            margin-bottom: 0.5rem;
-            color: #1a1a1a;
-        }
-        p {
-            color: #666;
-            font-size: 0.95rem;
-            margin-bottom: 2rem;
-            line-height: 1.5;
-        }
-        .button {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: white;
-            border: none;
-            padding: 1rem 2rem;
-            font-size: 1rem;
-            font-weight: 600;
-            border-radius: 8px;
-            cursor: pointer;
-            width: 100%;
-            transition: transform 0.2s, box-shadow 0.2s;
-            box-shadow: 0 4px 12px rgba(102, 126, 234, 0.4);
-        }
-        .button:hover {
-            transform: translateY(-2px);
-            box-shadow: 0 6px 20px rgba(102, 126, 234, 0.5);
-        }
-        .button:active {
-            transform: translateY(0);
-        }
-        .button:disabled {
-            opacity: 0.6;
-            cursor: not-allowed;
-            transform: none;
-        }
-        .footer {
-            margin-top: 2rem;
-            font-size: 0.75rem;
-            color: #999;
-        }
-        .loading {
-            display: none;
-            margin-top: 1rem;
-            color: #667eea;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>Send Heartbeat</h1>
-        <p>Click the button below to confirm you are available and reset your dead man's switch timer.</p>
-        <form id="heartbeatForm" method="POST">
-            <button type="submit" class="button" id="heartbeatButton">
-                Send Heartbeat
-            </button>
-            <div class="loading" id="loading">Sending...</div>
-        </form>
-        <p class="footer">Aeterna</p>
-    </div>
-    <script>
-        document.getElementById('heartbeatForm').addEventListener('submit', function(e) {
-            e.preventDefault();
-            const button = document.getElementById('heartbeatButton');
-            const loading = document.getElementById('loading');
-
-            button.disabled = true;
-            loading.style.display = 'block';
-
-            fetch(window.location.href, {
-                method: 'POST',
-                headers: {
-                    'Content-Type': 'application/json'
-                }
-            })
-            .then(response => {
-                if (response.ok) {
-                    return response.text();
-                }
-                throw new Error('Failed to send heartbeat');
-            })
-            .then(html => {
-                document.body.innerHTML = html;
-            })
-            .catch(error => {
-                button.disabled = false;
-                loading.style.display = 'none';
-                alert('Error: ' + error.message);
-            });
-        });
-    </script>
-</body>
-</html>
-`
-	c.Set("Content-Type", "text/html; charset=utf-8")
-	return c.SendString(html)
+// RotateToken issues a new quick-heartbeat token for the authenticated user.
+// Links built from the previous token stop working immediately.
+func (h *HeartbeatHandlers) RotateToken(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	settingsSvc := withOriginSession(c, h.settings)
+	token, err := settingsSvc.RotateHeartbeatToken(userID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"token": token,
+	})
 }
 
 // GetToken returns the quick-heartbeat token for the authenticated user.
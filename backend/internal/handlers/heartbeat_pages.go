@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultBrandName = "Aeterna"
+
+// heartbeatPageData is passed to the quick-heartbeat template, built-in or
+// loaded from HEARTBEAT_TEMPLATE. Confirmed distinguishes the result page from
+// the prompt. On the prompt, ActionURL is the POST target of the check-in form,
+// Nonce must be submitted with it as the "nonce" field, and ConfirmURL is a
+// short-lived one-click GET link for clients that cannot submit forms, set
+// only when the owner enabled quick_heartbeat_confirm_link.
+// Expired is set when a confirmation arrived without a valid nonce. OwnerName
+// is the owner's display name from settings, possibly empty. Description and
+// Result explain the link's ?action=, a plain check-in or a deadline
+// extension, on the prompt and the result page.
+type heartbeatPageData struct {
+	BrandName   string
+	BrandColor  string
+	OwnerName   string
+	Confirmed   bool
+	Expired     bool
+	ActionURL   string
+	Nonce       string
+	ConfirmURL  string
+	Description string
+	Result      string
+}
+
+const heartbeatPromptHTML = `<!DOCTYPE html>
+<html>
+<head>
+    <title>Send Heartbeat - {{.BrandName}}</title>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            background: {{if .BrandColor}}{{.BrandColor}}{{else}}linear-gradient(135deg, #667eea 0%, #764ba2 100%){{end}};
+            color: #333;
+            min-height: 100vh;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            margin: 0;
+            padding: 1rem;
+        }
+        .container {
+            background: white;
+            border-radius: 12px;
+            box-shadow: 0 10px 40px rgba(0,0,0,0.1);
+            text-align: center;
+            padding: 3rem 2rem;
+            max-width: 400px;
+            width: 100%;
+        }
+        h1 {
+            font-size: 1.5rem;
+            font-weight: 600;
+            margin-bottom: 0.5rem;
+            color: #1a1a1a;
+        }
+        p {
+            color: #666;
+            font-size: 0.95rem;
+            margin-bottom: 2rem;
+            line-height: 1.5;
+        }
+        .button {
+            background: {{if .BrandColor}}{{.BrandColor}}{{else}}linear-gradient(135deg, #667eea 0%, #764ba2 100%){{end}};
+            color: white;
+            border: none;
+            padding: 1rem 2rem;
+            font-size: 1rem;
+            font-weight: 600;
+            border-radius: 8px;
+            cursor: pointer;
+            width: 100%;
+            transition: transform 0.2s, box-shadow 0.2s;
+            box-shadow: 0 4px 12px rgba(102, 126, 234, 0.4);
+        }
+        .button:hover {
+            transform: translateY(-2px);
+            box-shadow: 0 6px 20px rgba(102, 126, 234, 0.5);
+        }
+        .button:active {
+            transform: translateY(0);
+        }
+        .button:disabled {
+            opacity: 0.6;
+            cursor: not-allowed;
+            transform: none;
+        }
+        .footer {
+            margin-top: 2rem;
+            font-size: 0.75rem;
+            color: #999;
+        }
+        .fallback {
+            margin: 1rem 0 0;
+            font-size: 0.8rem;
+        }
+        .fallback a { color: #666; }
+        .expired {
+            color: #b45309;
+            font-size: 0.9rem;
+            margin-bottom: 1rem;
+        }
+        .loading {
+            display: none;
+            margin-top: 1rem;
+            color: {{if .BrandColor}}{{.BrandColor}}{{else}}#667eea{{end}};
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Send Heartbeat</h1>
+        <p>{{.Description}}</p>
+        {{if .Expired}}<p class="expired">This page has expired. Press the button again to confirm.</p>{{end}}
+        <form id="heartbeatForm" method="POST" action="{{.ActionURL}}">
+            <input type="hidden" name="nonce" value="{{.Nonce}}">
+            <button type="submit" class="button" id="heartbeatButton">
+                Send Heartbeat
+            </button>
+            <div class="loading" id="loading">Sending...</div>
+        </form>
+        {{if .ConfirmURL}}<p class="fallback">Button not working? <a href="{{.ConfirmURL}}" rel="nofollow noreferrer">Confirm with this link</a></p>{{end}}
+        <p class="footer">{{.BrandName}}</p>
+    </div>
+    <script>
+        document.getElementById('heartbeatForm').addEventListener('submit', function(e) {
+            e.preventDefault();
+            const form = e.target;
+            const button = document.getElementById('heartbeatButton');
+            const loading = document.getElementById('loading');
+
+            button.disabled = true;
+            loading.style.display = 'block';
+
+            fetch(form.action, {
+                method: 'POST',
+                body: new URLSearchParams(new FormData(form))
+            })
+            .then(response => response.text().then(html => {
+                // An expired page comes back as a fresh prompt (403).
+                if (!response.ok && response.status !== 403) {
+                    throw new Error('Failed to send heartbeat');
+                }
+                document.body.innerHTML = html;
+            }))
+            .catch(error => {
+                button.disabled = false;
+                loading.style.display = 'none';
+                alert('Error: ' + error.message);
+            });
+        });
+    </script>
+</body>
+</html>
+`
+
+const heartbeatConfirmedHTML = `<!DOCTYPE html>
+<html>
+<head>
+    <title>Heartbeat Confirmed - {{.BrandName}}</title>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            background: #fafafa;
+            color: #333;
+            min-height: 100vh;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            margin: 0;
+        }
+        .container {
+            text-align: center;
+            padding: 2rem;
+            max-width: 400px;
+        }
+        h1 { font-size: 1.25rem; font-weight: 500; margin-bottom: 0.5rem; }
+        p { color: #666; font-size: 0.9rem; }
+        .footer { margin-top: 2rem; font-size: 0.75rem; color: #999; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1{{if .BrandColor}} style="color: {{.BrandColor}}"{{end}}>✓ Heartbeat Confirmed</h1>
+        <p>{{.Result}}</p>
+        <p class="footer">{{.BrandName}}</p>
+    </div>
+</body>
+</html>
+`
+
+var defaultHeartbeatTemplate = template.Must(template.New("heartbeat").Parse(
+	`{{if .Confirmed}}{{template "confirmed" .}}{{else}}{{template "prompt" .}}{{end}}` +
+		`{{define "prompt"}}` + heartbeatPromptHTML + `{{end}}` +
+		`{{define "confirmed"}}` + heartbeatConfirmedHTML + `{{end}}`,
+))
+
+// LoadHeartbeatTemplate parses the HEARTBEAT_TEMPLATE file, or returns the
+// built-in pages when path is empty.
+func LoadHeartbeatTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return defaultHeartbeatTemplate, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New("heartbeat").Parse(string(raw))
+}
+
+func (h *HeartbeatHandlers) renderPage(c *fiber.Ctx, settings models.Settings, confirmed bool) error {
+	return h.render(c, settings, heartbeatPageData{Confirmed: confirmed})
+}
+
+// renderExpired re-issues the prompt with a fresh nonce after a confirmation
+// that was not backed by one, e.g. from a link scanner or a stale tab.
+func (h *HeartbeatHandlers) renderExpired(c *fiber.Ctx, settings models.Settings) error {
+	c.Status(fiber.StatusForbidden)
+	return h.render(c, settings, heartbeatPageData{Expired: true})
+}
+
+func (h *HeartbeatHandlers) render(c *fiber.Ctx, settings models.Settings, data heartbeatPageData) error {
+	data.BrandName = settings.BrandName
+	data.BrandColor = settings.BrandColor
+	data.OwnerName = settings.OwnerName
+	if data.BrandName == "" {
+		data.BrandName = defaultBrandName
+	}
+	data.Description, data.Result = heartbeatActionText(c.Query("action"))
+	if !data.Confirmed {
+		base := strings.TrimSuffix(c.Path(), "/confirm")
+		query := services.ReminderActionQuery(c.Query("action"), c.Query("message"))
+		data.ActionURL = base + query
+		data.Nonce = services.NewQuickHeartbeatNonce(settings.HeartbeatToken, time.Now())
+		// Mail gateways load the emailed page and follow every link on it
+		// without marking the requests as prefetches, so a link that checks
+		// in is only offered to owners who asked for it.
+		if settings.QuickHeartbeatConfirmLink {
+			separator := "?"
+			if query != "" {
+				separator = "&"
+			}
+			data.ConfirmURL = base + "/confirm" + query + separator + "nonce=" + url.QueryEscape(data.Nonce)
+		}
+	}
+	var buf bytes.Buffer
+	if err := h.page.Execute(&buf, data); err != nil {
+		return writeError(c, services.Internal("Failed to render heartbeat page", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	c.Set("Cache-Control", "no-store")
+	return c.Send(buf.Bytes())
+}
+
+// heartbeatActionText returns the prompt and result texts for a
+// quick-heartbeat action.
+func heartbeatActionText(action string) (description, result string) {
+	by, _ := services.ReminderActionExtension(action)
+	if by == 0 {
+		return "Click the button below to confirm you are available and reset your dead man's switch timer.",
+			"Your check-in has been recorded."
+	}
+	days := fmt.Sprintf("%d days", int(by/(24*time.Hour)))
+	if by == 24*time.Hour {
+		days = "1 day"
+	}
+	return "Click the button below to confirm you are available and push your deadline back by " + days + ".",
+		"Your deadline has been extended by " + days + "."
+}
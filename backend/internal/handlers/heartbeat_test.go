@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeHeartbeatSettings struct {
+	settings models.Settings
+}
+
+func (f fakeHeartbeatSettings) Get(userID string) (models.Settings, error) {
+	return f.settings, nil
+}
+
+func (f fakeHeartbeatSettings) GetByHeartbeatToken(token string) (models.Settings, error) {
+	return f.settings, nil
+}
+
+func (f fakeHeartbeatSettings) Save(userID string, req models.Settings) error {
+	return nil
+}
+
+func (f fakeHeartbeatSettings) RotateHeartbeatToken(userID string) (string, error) {
+	return "rotated", nil
+}
+
+func (f fakeHeartbeatSettings) TestSMTP(context.Context, models.Settings) error {
+	return nil
+}
+
+func quickHeartbeatBody(t *testing.T, handler *HeartbeatHandlers, method string) string {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/quick-heartbeat/:token", handler.QuickHeartbeat)
+	app.Post("/quick-heartbeat/:token", handler.QuickHeartbeat)
+	req := httptest.NewRequest(method, "/quick-heartbeat/tok", nil)
+	if method == http.MethodPost {
+		req = httptest.NewRequest(method, "/quick-heartbeat/tok", strings.NewReader("nonce="+url.QueryEscape(services.NewQuickHeartbeatNonce("tok", time.Now()))))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestQuickHeartbeat_DefaultBranding(t *testing.T) {
+	handler := NewHeartbeatHandlers(fakeMessageService{}, fakeHeartbeatSettings{}, nil)
+	body := quickHeartbeatBody(t, handler, http.MethodGet)
+	if !strings.Contains(body, "<title>Send Heartbeat - Aeterna</title>") || !strings.Contains(body, "#764ba2") {
+		t.Fatalf("default page changed:\n%s", body)
+	}
+}
+
+func TestQuickHeartbeat_CustomBranding(t *testing.T) {
+	handler := NewHeartbeatHandlers(fakeMessageService{}, fakeHeartbeatSettings{settings: models.Settings{
+		BrandName:  "Smith <Family>",
+		BrandColor: "#0a7d4f",
+	}}, nil)
+
+	body := quickHeartbeatBody(t, handler, http.MethodPost)
+	if !strings.Contains(body, "Heartbeat Confirmed - Smith &lt;Family&gt;") {
+		t.Fatalf("brand name not escaped into confirmation page:\n%s", body)
+	}
+	if !strings.Contains(body, "#0a7d4f") {
+		t.Fatalf("brand color missing from confirmation page:\n%s", body)
+	}
+}
+
+func TestQuickHeartbeat_ExternalTemplate(t *testing.T) {
+	page := template.Must(template.New("heartbeat").Parse(`{{if .Confirmed}}done{{else}}prompt{{end}} {{.BrandName}}`))
+	handler := NewHeartbeatHandlers(fakeMessageService{}, fakeHeartbeatSettings{}, page)
+	if body := quickHeartbeatBody(t, handler, http.MethodGet); body != "prompt Aeterna" {
+		t.Fatalf("body = %q", body)
+	}
+	if body := quickHeartbeatBody(t, handler, http.MethodPost); body != "done Aeterna" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+type countingMessageService struct {
+	fakeMessageService
+	bulkCalls *int
+}
+
+func (f countingMessageService) BulkHeartbeat(userID string) error {
+	*f.bulkCalls++
+	return nil
+}
+
+func TestConfirmQuickHeartbeat_RequiresFreshNonceAndIgnoresPrefetch(t *testing.T) {
+	calls := 0
+	handler := NewHeartbeatHandlers(countingMessageService{bulkCalls: &calls}, fakeHeartbeatSettings{settings: models.Settings{HeartbeatToken: "tok", QuickHeartbeatConfirmLink: true}}, nil)
+	app := fiber.New()
+	app.Get("/quick-heartbeat/:token/confirm", handler.ConfirmQuickHeartbeat)
+
+	nonce := url.QueryEscape(services.NewQuickHeartbeatNonce("tok", time.Now()))
+	send := func(path string, headers map[string]string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if body := send("/quick-heartbeat/tok/confirm", nil); calls != 0 || !strings.Contains(body, `action="/quick-heartbeat/tok"`) {
+		t.Fatalf("missing nonce must render the prompt without checking in (calls=%d)", calls)
+	}
+	send("/quick-heartbeat/tok/confirm?nonce="+nonce, map[string]string{"Sec-Purpose": "prefetch"})
+	if calls != 0 {
+		t.Fatal("prefetch must not check in")
+	}
+	if body := send("/quick-heartbeat/tok/confirm?nonce="+nonce, nil); calls != 1 || !strings.Contains(body, "Heartbeat Confirmed") {
+		t.Fatalf("valid nonce must check in (calls=%d)", calls)
+	}
+}
+
+func TestQuickHeartbeatPost_RejectsScannerRequests(t *testing.T) {
+	calls := 0
+	handler := NewHeartbeatHandlers(countingMessageService{bulkCalls: &calls}, fakeHeartbeatSettings{}, nil)
+	app := fiber.New()
+	app.Post("/quick-heartbeat/:token", handler.QuickHeartbeat)
+
+	post := func(body string, headers map[string]string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/quick-heartbeat/tok", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		return resp
+	}
+
+	// A scanner POSTing straight to the emailed link has no nonce.
+	if resp := post("", nil); resp.StatusCode != http.StatusForbidden || calls != 0 {
+		t.Fatalf("POST without nonce: status=%d calls=%d, want 403 and no check-in", resp.StatusCode, calls)
+	}
+	if resp := post("nonce="+url.QueryEscape(services.NewQuickHeartbeatNonce("other-token", time.Now())), nil); resp.StatusCode != http.StatusForbidden || calls != 0 {
+		t.Fatalf("POST with foreign nonce: status=%d calls=%d", resp.StatusCode, calls)
+	}
+	stale := services.NewQuickHeartbeatNonce("tok", time.Now().Add(-services.QuickHeartbeatNonceTTL-time.Minute))
+	if resp := post("nonce="+url.QueryEscape(stale), nil); resp.StatusCode != http.StatusForbidden || calls != 0 {
+		t.Fatalf("POST with stale nonce: status=%d calls=%d", resp.StatusCode, calls)
+	}
+	fresh := "nonce=" + url.QueryEscape(services.NewQuickHeartbeatNonce("tok", time.Now()))
+	if resp := post(fresh, map[string]string{"Purpose": "prefetch"}); resp.StatusCode != http.StatusForbidden || calls != 0 {
+		t.Fatalf("prefetch POST: status=%d calls=%d", resp.StatusCode, calls)
+	}
+	if resp := post(fresh, nil); resp.StatusCode != http.StatusOK || calls != 1 {
+		t.Fatalf("human POST: status=%d calls=%d, want 200 and one check-in", resp.StatusCode, calls)
+	}
+}
+
+func TestQuickHeartbeatActions(t *testing.T) {
+	var extended []string
+	calls := 0
+	handler := NewHeartbeatHandlers(countingMessageService{fakeMessageService: fakeMessageService{extended: &extended}, bulkCalls: &calls}, fakeHeartbeatSettings{settings: models.Settings{HeartbeatToken: "tok", QuickHeartbeatConfirmLink: true}}, nil)
+	app := fiber.New()
+	app.Get("/quick-heartbeat/:token", handler.QuickHeartbeat)
+	app.Get("/quick-heartbeat/:token/confirm", handler.ConfirmQuickHeartbeat)
+
+	get := func(path string) (int, string) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/quick-heartbeat/tok?action=extend&message=m1")
+	if status != http.StatusOK || !strings.Contains(body, "push your deadline back by 7 days") ||
+		!strings.Contains(body, `action="/quick-heartbeat/tok?action=extend&amp;message=m1"`) ||
+		!strings.Contains(body, `href="/quick-heartbeat/tok/confirm?action=extend&amp;message=m1&amp;nonce=`) {
+		t.Fatalf("extend prompt should carry its action: %d\n%s", status, body)
+	}
+	if len(extended) != 0 {
+		t.Fatal("loading the prompt must not extend anything")
+	}
+
+	nonce := url.QueryEscape(services.NewQuickHeartbeatNonce("tok", time.Now()))
+	status, body = get("/quick-heartbeat/tok/confirm?action=extend&message=m1&nonce=" + nonce)
+	if status != http.StatusOK || !strings.Contains(body, "extended by 7 days") {
+		t.Fatalf("extend confirm: %d\n%s", status, body)
+	}
+	if _, _ = get("/quick-heartbeat/tok/confirm?action=snooze&nonce=" + nonce); len(extended) != 2 || extended[0] != "m1:168h0m0s" || extended[1] != ":24h0m0s" {
+		t.Fatalf("extensions = %v", extended)
+	}
+	if calls != 0 {
+		t.Fatalf("extending must not reset the timer (calls=%d)", calls)
+	}
+
+	if status, _ := get("/quick-heartbeat/tok?action=forever"); status != http.StatusBadRequest {
+		t.Fatalf("unknown action: status = %d, want 400", status)
+	}
+	if status, _ := get("/quick-heartbeat/tok/confirm?action=forever&nonce=" + nonce); status != http.StatusBadRequest || len(extended) != 2 || calls != 0 {
+		t.Fatalf("unknown action must change nothing: status = %d", status)
+	}
+}
+
+// hrefPattern finds the links a crawler would follow on a page.
+var hrefPattern = regexp.MustCompile(`href="([^"]*)"`)
+
+func TestQuickHeartbeatPage_LinkCrawlerCannotCheckIn(t *testing.T) {
+	calls := 0
+	var extended []string
+	handler := NewHeartbeatHandlers(countingMessageService{fakeMessageService: fakeMessageService{extended: &extended}, bulkCalls: &calls}, fakeHeartbeatSettings{settings: models.Settings{HeartbeatToken: "tok"}}, nil)
+	app := fiber.New()
+	app.Get("/quick-heartbeat/:token", handler.QuickHeartbeat)
+	app.Get("/quick-heartbeat/:token/confirm", handler.ConfirmQuickHeartbeat)
+
+	// A mail gateway loads the emailed links and follows every link on the
+	// pages it gets back, sending no prefetch headers.
+	queue := []string{"/quick-heartbeat/tok", "/quick-heartbeat/tok?action=extend&message=m1"}
+	seen := map[string]bool{}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		for _, match := range hrefPattern.FindAllStringSubmatch(string(body), -1) {
+			if link := html.UnescapeString(match[1]); strings.HasPrefix(link, "/") {
+				queue = append(queue, link)
+			}
+		}
+	}
+
+	if calls != 0 || len(extended) != 0 {
+		t.Fatalf("crawling %v checked in (calls=%d, extended=%v)", seen, calls, extended)
+	}
+}
+
+// noncePattern finds the nonce the prompt embeds in its form.
+var noncePattern = regexp.MustCompile(`name="nonce" value="([^"]*)"`)
+
+func TestConfirmQuickHeartbeat_RefusesUnmarkedCrawlWhenNotOptedIn(t *testing.T) {
+	calls := 0
+	handler := NewHeartbeatHandlers(countingMessageService{bulkCalls: &calls}, fakeHeartbeatSettings{settings: models.Settings{HeartbeatToken: "tok"}}, nil)
+	app := fiber.New()
+	app.Get("/quick-heartbeat/:token", handler.QuickHeartbeat)
+	app.Get("/quick-heartbeat/:token/confirm", handler.ConfirmQuickHeartbeat)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/quick-heartbeat/tok", nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	match := noncePattern.FindStringSubmatch(string(body))
+	if match == nil {
+		t.Fatalf("prompt has no nonce:\n%s", body)
+	}
+
+	// A crawler that guesses the confirm path and carries the page's fresh
+	// nonce, with no prefetch headers, looks like a person clicking the link.
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/quick-heartbeat/tok/confirm?nonce="+url.QueryEscape(html.UnescapeString(match[1])), nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden || calls != 0 {
+		t.Fatalf("unmarked crawl: status=%d calls=%d, want 403 and no check-in", resp.StatusCode, calls)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/alpyxn/aeterna/backend/internal/models"
@@ -15,6 +16,20 @@ type CreateMessageRequest struct {
 	RecipientEmails []string `json:"recipient_emails"`
 	TriggerDuration int      `json:"trigger_duration"`
 	Reminders       []int    `json:"reminders"`
+	// Duration, when present, sets TriggerDuration in friendlier units.
+	Duration *DurationInput `json:"duration"`
+
+	// RequiredMissedIntervals is how many trigger intervals may pass without
+	// a heartbeat before delivery; 0 means 1.
+	RequiredMissedIntervals int `json:"required_missed_intervals"`
+	// DeliveryWindow limits delivery to certain local hours; omit it to
+	// deliver as soon as the switch fires.
+	DeliveryWindow *models.DeliveryWindow `json:"delivery_window"`
+	// Subject replaces the default subject of the delivered email.
+	Subject string `json:"subject"`
+	// CreationReminders are minutes after creation at which to ask the owner
+	// once whether the switch is still wanted.
+	CreationReminders []int `json:"creation_reminders"`
 }
 
 type UpdateMessageRequest struct {
@@ -23,6 +38,65 @@ type UpdateMessageRequest struct {
 	RecipientEmails []string `json:"recipient_emails"`
 	TriggerDuration int      `json:"trigger_duration"`
 	Reminders       []int    `json:"reminders"`
+	// Duration, when present, sets TriggerDuration in friendlier units.
+	Duration *DurationInput `json:"duration"`
+
+	// RequiredMissedIntervals is how many trigger intervals may pass without
+	// a heartbeat before delivery; 0 leaves the current value unchanged.
+	RequiredMissedIntervals int `json:"required_missed_intervals"`
+	// DeliveryWindow replaces the delivery window when present; an object
+	// with equal start and end hours removes it.
+	DeliveryWindow *models.DeliveryWindow `json:"delivery_window"`
+	// Subject replaces the email subject when present; an empty string
+	// restores the default.
+	Subject *string `json:"subject"`
+	// CreationReminders replaces the creation reminders when present;
+	// reminders that already went out are not repeated.
+	CreationReminders []int `json:"creation_reminders"`
+}
+
+// DurationInput is a trigger duration such as {"value": 30, "unit": "days"}.
+type DurationInput struct {
+	Value int    `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+// durationUnitMinutes maps accepted units to minutes; a month is 30 days and
+// a year 365.
+var durationUnitMinutes = map[string]int{
+	"minute": 1,
+	"hour":   60,
+	"day":    24 * 60,
+	"week":   7 * 24 * 60,
+	"month":  30 * 24 * 60,
+	"year":   365 * 24 * 60,
+}
+
+// resolveTriggerDuration returns the trigger duration in minutes. duration
+// takes precedence over the raw minutes field; sending both with different
+// values is rejected rather than guessing which one was meant.
+func resolveTriggerDuration(minutes int, duration *DurationInput) (int, error) {
+	if duration == nil {
+		return minutes, nil
+	}
+	unit := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(duration.Unit)), "s")
+	factor, ok := durationUnitMinutes[unit]
+	if !ok {
+		return 0, services.InvalidField("duration", fmt.Sprintf("Unknown duration unit %q: use minutes, hours, days, weeks, months or years", duration.Unit), nil)
+	}
+	if duration.Value < 1 {
+		return 0, services.InvalidField("duration", "Duration value must be at least 1", nil)
+	}
+	// Bounded so the multiplication cannot overflow; the service enforces
+	// the exact limit.
+	if duration.Value > services.MaxTriggerDurationMinutes/factor+1 {
+		return 0, services.InvalidField("duration", "Duration cannot exceed 1 year (525600 minutes)", nil)
+	}
+	resolved := duration.Value * factor
+	if minutes != 0 && minutes != resolved {
+		return 0, services.InvalidField("duration", fmt.Sprintf("trigger_duration (%d minutes) and duration (%d %s) disagree; send only one", minutes, duration.Value, duration.Unit), nil)
+	}
+	return resolved, nil
 }
 
 // MessageHandlers groups all switch message route handlers.
@@ -50,7 +124,12 @@ func (h *MessageHandlers) Create(c *fiber.Ctx) error {
 		recipients = []string{strings.TrimSpace(req.RecipientEmail)}
 	}
 
-	msg, err := messages.Create(userID, req.Content, recipients, req.TriggerDuration, req.Reminders)
+	triggerDuration, err := resolveTriggerDuration(req.TriggerDuration, req.Duration)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	msg, err := messages.Create(c.UserContext(), userID, req.Content, recipients, triggerDuration, req.Reminders, req.RequiredMissedIntervals, req.DeliveryWindow, req.Subject, req.CreationReminders)
 	if err != nil {
 		return writeError(c, err)
 	}
@@ -62,25 +141,66 @@ func (h *MessageHandlers) Create(c *fiber.Ctx) error {
 }
 
 // GetPublic reveals content only when the message is triggered (unauthenticated endpoint).
+// ?format=html serves a reveal page for browsers instead of JSON.
 func (h *MessageHandlers) GetPublic(c *fiber.Ctx) error {
 	id := c.Params("id")
 	msg, err := h.messages.GetPublicByID(id)
 	if err != nil {
 		return writeError(c, err)
 	}
+	if c.Query("format") == "html" {
+		return renderRevealPage(c, msg)
+	}
 
 	content := ""
+	attachments := []models.Attachment{}
+	included := []models.DeliveredAttachment{}
 	if msg.Status == models.StatusTriggered {
 		content = msg.Content
+		if msg.RevealAttachments != nil {
+			attachments = msg.RevealAttachments
+		}
+		included = namedIncludedAttachments(msg)
 	}
 
 	return c.JSON(fiber.Map{
-		"content":    content,
-		"status":     msg.Status,
-		"created_at": msg.CreatedAt,
+		"content":                   content,
+		"status":                    msg.Status,
+		"created_at":                msg.CreatedAt,
+		"attachments":               attachments,
+		"included_attachments":      included,
+		"included_attachment_count": len(msg.IncludedAttachments),
+		"shredded":                  msg.ShreddedAt != nil,
 	})
 }
 
+// namedIncludedAttachments returns the delivered files meant for every
+// recipient. Files sent only to some recipients are counted but never named
+// on the reveal link.
+func namedIncludedAttachments(msg models.Message) []models.DeliveredAttachment {
+	named := []models.DeliveredAttachment{}
+	for _, att := range msg.IncludedAttachments {
+		if !att.Restricted {
+			named = append(named, att)
+		}
+	}
+	return named
+}
+
+// RotateManagementToken replaces a switch's management token, revoking any
+// management link shared with the old one.
+func (h *MessageHandlers) RotateManagementToken(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	token, err := withOriginSession(c, h.messages).RotateManagementToken(userID, c.Params("id"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"management_token": token})
+}
+
 func (h *MessageHandlers) Heartbeat(c *fiber.Ctx) error {
 	userID, err := currentUserID(c)
 	if err != nil {
@@ -107,12 +227,69 @@ func (h *MessageHandlers) Heartbeat(c *fiber.Ctx) error {
 	})
 }
 
+// DownloadContent serves a switch's decrypted content as a file download.
+func (h *MessageHandlers) DownloadContent(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	filename, mimeType, data, err := h.messages.ExportContent(userID, c.Params("id"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	// Attachment sets a type from the extension, so ours goes after it.
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, mimeType)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.Send(data)
+}
+
+// Transitions lists a switch's status changes, oldest first, with the
+// reason and actor of each.
+func (h *MessageHandlers) Transitions(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	transitions, err := h.messages.ListTransitions(userID, c.Params("id"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(transitions)
+}
+
+// Tombstones lists what remains of deleted messages that had been delivered.
+func (h *MessageHandlers) Tombstones(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	tombstones, err := h.messages.ListTombstones(userID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(tombstones)
+}
+
 func (h *MessageHandlers) List(c *fiber.Ctx) error {
 	userID, err := currentUserID(c)
 	if err != nil {
 		return writeError(c, err)
 	}
-	messages, err := h.messages.List(userID)
+	var messages []models.Message
+	recipient := strings.TrimSpace(c.Query("recipient"))
+	status := strings.TrimSpace(c.Query("status"))
+	switch {
+	case recipient != "" && status != "":
+		return writeError(c, services.BadRequest("Filter by recipient or by status, not both", nil))
+	case recipient != "":
+		messages, err = h.messages.ListByRecipient(userID, recipient)
+	case status != "":
+		messages, err = h.messages.ListByStatus(userID, models.MessageStatus(strings.ToLower(status)))
+	default:
+		messages, err = h.messages.List(userID)
+	}
 	if err != nil {
 		return writeError(c, err)
 	}
@@ -126,7 +303,7 @@ func (h *MessageHandlers) Delete(c *fiber.Ctx) error {
 	}
 	messages := withOriginSession(c, h.messages)
 	id := c.Params("id")
-	if err := messages.Delete(userID, id); err != nil {
+	if err := messages.Delete(userID, id, c.QueryBool("force")); err != nil {
 		return writeError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "message": "Message deleted successfully"})
@@ -149,7 +326,12 @@ func (h *MessageHandlers) Update(c *fiber.Ctx) error {
 		recipients = []string{strings.TrimSpace(req.RecipientEmail)}
 	}
 
-	msg, err := messages.Update(userID, id, req.Content, recipients, req.TriggerDuration, req.Reminders)
+	triggerDuration, err := resolveTriggerDuration(req.TriggerDuration, req.Duration)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	msg, err := messages.Update(userID, id, req.Content, recipients, triggerDuration, req.Reminders, req.RequiredMissedIntervals, req.DeliveryWindow, req.Subject, req.CreationReminders)
 	if err != nil {
 		return writeError(c, err)
 	}
@@ -160,6 +342,59 @@ func (h *MessageHandlers) Update(c *fiber.Ctx) error {
 	})
 }
 
+// GetManaged, UpdateManaged and DeleteManaged serve /m/:managementToken. The
+// token grants access to exactly one message, so a delegate can manage it
+// without the owner's master password.
+func (h *MessageHandlers) GetManaged(c *fiber.Ctx) error {
+	msg, err := h.messages.GetByManagementToken(c.Params("managementToken"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(msg)
+}
+
+func (h *MessageHandlers) UpdateManaged(c *fiber.Ctx) error {
+	msg, err := h.messages.GetByManagementToken(c.Params("managementToken"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	req := new(UpdateMessageRequest)
+	if err := c.BodyParser(req); err != nil {
+		return writeError(c, services.BadRequest("Invalid request body", err))
+	}
+
+	recipients := normalizeRecipients(req.RecipientEmails)
+	if len(recipients) == 0 && strings.TrimSpace(req.RecipientEmail) != "" {
+		recipients = []string{strings.TrimSpace(req.RecipientEmail)}
+	}
+
+	triggerDuration, err := resolveTriggerDuration(req.TriggerDuration, req.Duration)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	updated, err := h.messages.Update(msg.UserID, msg.ID, req.Content, recipients, triggerDuration, req.Reminders, req.RequiredMissedIntervals, req.DeliveryWindow, req.Subject, req.CreationReminders)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": updated,
+	})
+}
+
+func (h *MessageHandlers) DeleteManaged(c *fiber.Ctx) error {
+	msg, err := h.messages.GetByManagementToken(c.Params("managementToken"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.messages.Delete(msg.UserID, msg.ID, c.QueryBool("force")); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "message": "Message deleted successfully"})
+}
+
 func normalizeRecipients(recipients []string) []string {
 	if len(recipients) == 0 {
 		return nil
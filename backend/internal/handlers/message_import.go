@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportMessageRequest is one record of a message import. RecipientEmail may
+// list several addresses separated by commas or semicolons.
+type ImportMessageRequest struct {
+	Content         string `json:"content"`
+	RecipientEmail  string `json:"recipient_email"`
+	TriggerDuration int    `json:"trigger_duration"`
+}
+
+// Import creates switches in bulk from a JSON array or a CSV file with a
+// content, recipient_email, trigger_duration header. The records arrive as
+// the request body (application/json or text/csv) or as a multipart "file"
+// upload, read as CSV when its name ends in .csv. Nothing is created unless
+// every row is valid; the per-row results are returned either way.
+func (h *MessageHandlers) Import(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	messages := withOriginSession(c, h.messages)
+
+	data, isCSV, err := importPayload(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var records []ImportMessageRequest
+	if isCSV {
+		records, err = parseImportCSV(data)
+	} else {
+		records, err = parseImportJSON(data)
+	}
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	rows := make([]ports.MessageImportRow, len(records))
+	for i, record := range records {
+		rows[i] = ports.MessageImportRow{
+			Content:         record.Content,
+			RecipientEmails: normalizeRecipients(strings.FieldsFunc(record.RecipientEmail, isRecipientSeparator)),
+			TriggerDuration: record.TriggerDuration,
+		}
+	}
+
+	results, err := messages.Import(c.UserContext(), userID, rows)
+	if err != nil {
+		return writeError(c, err)
+	}
+	imported := 0
+	for _, result := range results {
+		if result.ID != "" {
+			imported++
+		}
+	}
+	if imported < len(results) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":    "Some rows are invalid; no messages were imported",
+			"code":     services.CodeBadRequest,
+			"imported": 0,
+			"results":  results,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"imported": imported,
+		"results":  results,
+	})
+}
+
+func isRecipientSeparator(r rune) bool {
+	return r == ',' || r == ';'
+}
+
+// importPayload returns the raw records and whether they are CSV.
+func importPayload(c *fiber.Ctx) ([]byte, bool, error) {
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, false, services.BadRequest("No file provided", err)
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, false, services.BadRequest("Failed to read uploaded file", err)
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, false, services.BadRequest("Failed to read file data", err)
+		}
+		return data, strings.EqualFold(filepath.Ext(fileHeader.Filename), ".csv"), nil
+	}
+	return c.Body(), strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv"), nil
+}
+
+func parseImportJSON(data []byte) ([]ImportMessageRequest, error) {
+	var records []ImportMessageRequest
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, services.BadRequest("Import must be a JSON array of messages", err)
+	}
+	return records, nil
+}
+
+// parseImportCSV reads records by header name, so columns may come in any
+// order; unknown columns are ignored.
+func parseImportCSV(data []byte) ([]ImportMessageRequest, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, services.BadRequest("The import contains no messages", nil)
+		}
+		return nil, services.BadRequest("Invalid CSV", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"content", "recipient_email", "trigger_duration"} {
+		if _, ok := columns[name]; !ok {
+			return nil, services.BadRequest(fmt.Sprintf("CSV header must include %q", name), nil)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i := columns[name]; i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var records []ImportMessageRequest
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, services.BadRequest(fmt.Sprintf("Invalid CSV on row %d", row), err)
+		}
+		duration, err := strconv.Atoi(strings.TrimSpace(field(record, "trigger_duration")))
+		if err != nil {
+			return nil, services.BadRequest(fmt.Sprintf("Row %d: trigger_duration must be a whole number of minutes", row), err)
+		}
+		records = append(records, ImportMessageRequest{
+			Content:         field(record, "content"),
+			RecipientEmail:  field(record, "recipient_email"),
+			TriggerDuration: duration,
+		})
+	}
+}
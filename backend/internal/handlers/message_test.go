@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +12,7 @@ import (
 	"time"
 
 	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
 	"github.com/alpyxn/aeterna/backend/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
@@ -16,14 +20,22 @@ import (
 type fakeMessageService struct {
 	heartbeatResult models.Message
 	heartbeatErr    error
+	publicResult    models.Message
+	transitions     []models.StatusTransition
+	extended        *[]string
+	imported        *[]ports.MessageImportRow
 }
 
-func (f fakeMessageService) Create(userID, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
+func (f fakeMessageService) Create(_ context.Context, userID, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject string, creationReminders []int) (models.Message, error) {
 	return models.Message{}, nil
 }
 
 func (f fakeMessageService) GetPublicByID(id string) (models.Message, error) {
-	return models.Message{}, nil
+	return f.publicResult, nil
+}
+
+func (f fakeMessageService) GetByManagementToken(token string) (models.Message, error) {
+	return models.Message{}, services.NotFound("Message not found", nil)
 }
 
 func (f fakeMessageService) GetByID(userID, id string) (models.Message, error) {
@@ -34,6 +46,14 @@ func (f fakeMessageService) List(userID string) ([]models.Message, error) {
 	return nil, nil
 }
 
+func (f fakeMessageService) ListByRecipient(userID, recipient string) ([]models.Message, error) {
+	return nil, nil
+}
+
+func (f fakeMessageService) ListByStatus(userID string, status models.MessageStatus) ([]models.Message, error) {
+	return nil, nil
+}
+
 func (f fakeMessageService) Heartbeat(userID, id string) (models.Message, error) {
 	if f.heartbeatErr != nil {
 		return models.Message{}, f.heartbeatErr
@@ -45,14 +65,51 @@ func (f fakeMessageService) BulkHeartbeat(userID string) error {
 	return nil
 }
 
-func (f fakeMessageService) Delete(userID, id string) error {
+func (f fakeMessageService) ExtendDeadline(userID, id string, by time.Duration) error {
+	if f.extended != nil {
+		*f.extended = append(*f.extended, fmt.Sprintf("%s:%s", id, by))
+	}
+	return nil
+}
+
+func (f fakeMessageService) Delete(userID, id string, force bool) error {
 	return nil
 }
 
-func (f fakeMessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
+func (f fakeMessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject *string, creationReminders []int) (models.Message, error) {
 	return models.Message{}, nil
 }
 
+func (f fakeMessageService) RotateManagementToken(userID, id string) (string, error) {
+	return "rotated-token", nil
+}
+
+func (f fakeMessageService) ExportContent(userID, id string) (filename, mimeType string, data []byte, err error) {
+	return "aeterna-message-" + id + ".md", "text/markdown; charset=utf-8", []byte("# Hello"), nil
+}
+
+func (f fakeMessageService) ListTransitions(userID, id string) ([]models.StatusTransition, error) {
+	return f.transitions, nil
+}
+
+func (f fakeMessageService) ListTombstones(userID string) ([]models.MessageTombstone, error) {
+	return nil, nil
+}
+
+func (f fakeMessageService) Import(_ context.Context, userID string, rows []ports.MessageImportRow) ([]ports.MessageImportResult, error) {
+	if f.imported != nil {
+		*f.imported = rows
+	}
+	results := make([]ports.MessageImportResult, len(rows))
+	for i, row := range rows {
+		results[i] = ports.MessageImportResult{Row: i + 1, ID: fmt.Sprintf("m%d", i+1)}
+		if row.TriggerDuration < 1 {
+			results[i] = ports.MessageImportResult{Row: i + 1, Error: "Duration must be at least 1 minute", Code: services.CodeBadRequest}
+		}
+	}
+	return results, nil
+}
+
 func TestHeartbeatReturnsComputedScheduleFields(t *testing.T) {
 	lastSeen := time.Date(2026, 5, 29, 12, 0, 0, 0, time.UTC)
 	nextTrigger := lastSeen.Add(90 * time.Minute)
@@ -162,3 +219,291 @@ func TestHeartbeatReturnsUnauthorizedWithoutUserContext(t *testing.T) {
 		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
 	}
 }
+
+type managedMessageService struct {
+	fakeMessageService
+	deleted *string
+}
+
+func (f managedMessageService) GetByManagementToken(token string) (models.Message, error) {
+	if token != "mgmt-token" {
+		return models.Message{}, services.NotFound("Message not found", nil)
+	}
+	return models.Message{ID: "m1", UserID: "owner"}, nil
+}
+
+func (f managedMessageService) Delete(userID, id string, force bool) error {
+	*f.deleted = userID + "/" + id
+	return nil
+}
+
+func TestManagedMessageRoutesScopeToTokenMessage(t *testing.T) {
+	var deleted string
+	handler := NewMessageHandlers(managedMessageService{deleted: &deleted})
+
+	app := fiber.New()
+	app.Get("/api/m/:managementToken", handler.GetManaged)
+	app.Delete("/api/m/:managementToken", handler.DeleteManaged)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/m/wrong-token", nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown token status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/m/mgmt-token", nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodDelete, "/api/m/mgmt-token", nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if deleted != "owner/m1" {
+		t.Fatalf("expected delete scoped to the token's message, got %q", deleted)
+	}
+}
+
+type durationCapturingService struct {
+	fakeMessageService
+	created *int
+}
+
+func (s durationCapturingService) Create(_ context.Context, userID, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject string, creationReminders []int) (models.Message, error) {
+	*s.created = triggerDuration
+	return models.Message{ID: "m-new"}, nil
+}
+
+func TestCreateConvertsDurationObjectToMinutes(t *testing.T) {
+	var created int
+	handler := NewMessageHandlers(durationCapturingService{created: &created})
+	app := fiber.New()
+	app.Post("/api/messages", func(c *fiber.Ctx) error {
+		c.Locals("user_id", "u-test")
+		return handler.Create(c)
+	})
+
+	post := func(body string) int {
+		t.Helper()
+		created = 0
+		req := httptest.NewRequest(http.MethodPost, "/api/messages", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`{"content":"x","recipient_email":"a@a.com","duration":{"value":30,"unit":"days"}}`, 30 * 24 * 60},
+		{`{"content":"x","recipient_email":"a@a.com","duration":{"value":1,"unit":"Week"}}`, 7 * 24 * 60},
+		{`{"content":"x","recipient_email":"a@a.com","trigger_duration":90}`, 90},
+		{`{"content":"x","recipient_email":"a@a.com","trigger_duration":120,"duration":{"value":2,"unit":"hours"}}`, 120},
+	}
+	for _, tc := range cases {
+		if status := post(tc.body); status != http.StatusOK || created != tc.want {
+			t.Fatalf("%s: status %d, trigger_duration %d; want 200 and %d", tc.body, status, created, tc.want)
+		}
+	}
+
+	for _, body := range []string{
+		`{"content":"x","recipient_email":"a@a.com","duration":{"value":3,"unit":"fortnights"}}`,
+		`{"content":"x","recipient_email":"a@a.com","duration":{"value":0,"unit":"days"}}`,
+		`{"content":"x","recipient_email":"a@a.com","trigger_duration":30,"duration":{"value":30,"unit":"days"}}`,
+	} {
+		if status := post(body); status != http.StatusBadRequest || created != 0 {
+			t.Fatalf("%s: status %d, want 400 without creating", body, status)
+		}
+	}
+}
+
+func TestGetPublicRendersHTMLOnRequest(t *testing.T) {
+	handler := NewMessageHandlers(fakeMessageService{
+		publicResult: models.Message{
+			ID: "m1", Status: models.StatusTriggered, Content: "Goodbye <b>friend</b>", AttachmentCount: 2,
+			RevealAttachments: []models.Attachment{{ID: "a1", Filename: "letter.pdf"}},
+		},
+	})
+	app := fiber.New()
+	app.Get("/messages/:id", handler.GetPublic)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/messages/m1?format=html", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Fatalf("Content-Type = %q, want text/html", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+	if !strings.Contains(page, "Goodbye &lt;b&gt;friend&lt;/b&gt;") {
+		t.Fatalf("expected escaped message content in page, got %s", page)
+	}
+	if !strings.Contains(page, `<a href="/messages/m1/reveal/attachments/a1">letter.pdf</a>`) {
+		t.Fatalf("expected a download link for the retained attachment, got %s", page)
+	}
+	if !strings.Contains(page, "1 more attachment,") {
+		t.Fatalf("expected a note about the emailed-only attachment, got %s", page)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/messages/m1", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Fatalf("Content-Type = %q, want JSON by default", got)
+	}
+}
+
+func TestGetPublicReportsAttachmentsAfterCleanup(t *testing.T) {
+	handler := NewMessageHandlers(fakeMessageService{
+		publicResult: models.Message{
+			ID: "m1", Status: models.StatusTriggered, Content: "Goodbye",
+			IncludedAttachments: []models.DeliveredAttachment{
+				{Filename: "will.pdf", Size: 2048},
+				{Filename: "private.txt", Size: 12, Restricted: true},
+			},
+		},
+	})
+	app := fiber.New()
+	app.Get("/messages/:id", handler.GetPublic)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/messages/m1?format=html", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+	if !strings.Contains(page, "2 files were included") || !strings.Contains(page, "<li>will.pdf</li>") {
+		t.Fatalf("expected a note about the deleted files, got %s", page)
+	}
+	if strings.Contains(page, "private.txt") {
+		t.Fatalf("files restricted to some recipients must not be named, got %s", page)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/messages/m1", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var payload struct {
+		Included []models.DeliveredAttachment `json:"included_attachments"`
+		Count    int                          `json:"included_attachment_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Count != 2 || len(payload.Included) != 1 || payload.Included[0].Filename != "will.pdf" || payload.Included[0].Size != 2048 {
+		t.Fatalf("unexpected included attachments %+v", payload)
+	}
+}
+
+func TestGetPublicHTMLHidesUntriggeredContent(t *testing.T) {
+	handler := NewMessageHandlers(fakeMessageService{
+		publicResult: models.Message{ID: "m1", Status: models.StatusActive, Content: "secret words"},
+	})
+	app := fiber.New()
+	app.Get("/messages/:id", handler.GetPublic)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/messages/m1?format=html", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "secret words") {
+		t.Fatalf("content of an untriggered message must not be rendered")
+	}
+}
+
+func TestDownloadContentServesAttachment(t *testing.T) {
+	handler := NewMessageHandlers(fakeMessageService{})
+
+	app := fiber.New()
+	app.Get("/api/messages/:id/content/download", func(c *fiber.Ctx) error {
+		c.Locals("user_id", "u-test")
+		return handler.DownloadContent(c)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/messages/m1/content/download", nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="aeterna-message-m1.md"` {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/markdown; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("Cache-Control = %q, want no-store", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "# Hello" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestImportParsesJSONAndCSV(t *testing.T) {
+	var rows []ports.MessageImportRow
+	handler := NewMessageHandlers(fakeMessageService{imported: &rows})
+	app := fiber.New()
+	app.Post("/api/messages/import", func(c *fiber.Ctx) error {
+		c.Locals("user_id", "u-test")
+		return handler.Import(c)
+	})
+
+	post := func(contentType, body string) int {
+		t.Helper()
+		rows = nil
+		req := httptest.NewRequest(http.MethodPost, "/api/messages/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("application/json", `[{"content":"one","recipient_email":"a@a.com; b@b.com","trigger_duration":60},{"content":"two","recipient_email":"c@c.com","trigger_duration":90}]`); status != http.StatusOK {
+		t.Fatalf("JSON import: status %d, want 200", status)
+	}
+	if len(rows) != 2 || len(rows[0].RecipientEmails) != 2 || rows[1].TriggerDuration != 90 {
+		t.Fatalf("JSON import parsed %+v", rows)
+	}
+
+	csvBody := "trigger_duration,recipient_email,content\n60,\"a@a.com,b@b.com\",\"hello, world\"\n"
+	if status := post("text/csv", csvBody); status != http.StatusOK {
+		t.Fatalf("CSV import: status %d, want 200", status)
+	}
+	if len(rows) != 1 || rows[0].Content != "hello, world" || len(rows[0].RecipientEmails) != 2 || rows[0].TriggerDuration != 60 {
+		t.Fatalf("CSV import parsed %+v", rows)
+	}
+
+	if status := post("application/json", `[{"content":"one","recipient_email":"a@a.com","trigger_duration":0}]`); status != http.StatusBadRequest {
+		t.Fatalf("invalid row: status %d, want 400", status)
+	}
+	for _, body := range []string{"content,recipient_email\nx,a@a.com\n", "content,recipient_email,trigger_duration\nx,a@a.com,soon\n"} {
+		if status := post("text/csv", body); status != http.StatusBadRequest || rows != nil {
+			t.Fatalf("%q: status %d, want 400 before importing", body, status)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecoveryKeyHandlers groups route handlers for additional recovery keys.
+type RecoveryKeyHandlers struct {
+	keys ports.RecoveryKeyServicePort
+}
+
+func NewRecoveryKeyHandlers(keys ports.RecoveryKeyServicePort) *RecoveryKeyHandlers {
+	return &RecoveryKeyHandlers{keys: keys}
+}
+
+type addRecoveryKeyRequest struct {
+	Label string `json:"label"`
+}
+
+// List returns the caller's additional recovery keys by label, including
+// revoked ones. Key values are never returned.
+func (h *RecoveryKeyHandlers) List(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	keys, err := h.keys.List(userID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(keys)
+}
+
+// Add creates a recovery key. The key is only returned in this response.
+func (h *RecoveryKeyHandlers) Add(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var req addRecoveryKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return writeError(c, services.BadRequest("Invalid request body", err))
+	}
+	recoveryKey, key, err := h.keys.Add(userID, req.Label)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"recovery_key": recoveryKey, "key": key})
+}
+
+// Revoke stops a recovery key from resetting the master password.
+func (h *RecoveryKeyHandlers) Revoke(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return writeError(c, services.BadRequest("Invalid recovery key id", err))
+	}
+	key, err := h.keys.Revoke(userID, uint(id))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(key)
+}
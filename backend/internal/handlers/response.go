@@ -21,7 +21,7 @@ func AttachRuntimeFlags(isProduction bool) fiber.Handler {
 func currentUserID(c *fiber.Ctx) (string, error) {
 	uid, ok := c.Locals(middleware.LocalUserIDKey).(string)
 	if !ok || uid == "" {
-		return "", services.NewAPIError(401, "unauthorized", "Unauthorized", nil)
+		return "", services.NewAPIError(401, services.CodeUnauthorized, "Unauthorized", nil)
 	}
 	return uid, nil
 }
@@ -48,12 +48,15 @@ func writeError(c *fiber.Ctx, err error) error {
 	if errors.As(err, &apiErr) {
 		code := apiErr.Code
 		if code == "" {
-			code = "internal_error"
+			code = services.CodeInternal
 		}
 		payload := fiber.Map{
 			"error": apiErr.Message,
 			"code":  code,
 		}
+		if apiErr.Field != "" {
+			payload["field"] = apiErr.Field
+		}
 		if !isProd && apiErr.Err != nil {
 			payload["detail"] = apiErr.Err.Error()
 		}
@@ -61,10 +64,42 @@ func writeError(c *fiber.Ctx, err error) error {
 	}
 	payload := fiber.Map{
 		"error": "Internal server error",
-		"code":  "internal_error",
+		"code":  services.CodeInternal,
 	}
 	if !isProd && err != nil {
 		payload["detail"] = err.Error()
 	}
 	return c.Status(500).JSON(payload)
 }
+
+// ErrorHandler is the app-wide fiber.Config.ErrorHandler. Errors raised
+// outside a handler, such as unknown routes or oversized bodies, get the same
+// payload and a stable code like every writeError response.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return writeError(c, services.NewAPIError(fiberErr.Code, fiberErrorCode(fiberErr.Code), fiberErr.Message, nil))
+	}
+	return writeError(c, err)
+}
+
+func fiberErrorCode(status int) string {
+	switch status {
+	case fiber.StatusUnauthorized:
+		return services.CodeUnauthorized
+	case fiber.StatusForbidden:
+		return services.CodeForbidden
+	case fiber.StatusNotFound:
+		return services.CodeNotFound
+	case fiber.StatusMethodNotAllowed:
+		return services.CodeMethodNotAllowed
+	case fiber.StatusRequestEntityTooLarge:
+		return services.CodePayloadTooLarge
+	case fiber.StatusTooManyRequests:
+		return services.CodeRateLimited
+	}
+	if status < 500 {
+		return services.CodeBadRequest
+	}
+	return services.CodeInternal
+}
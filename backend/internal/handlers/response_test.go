@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/middleware"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestErrorResponsesCarryDocumentedCode(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	heartbeat := NewHeartbeatHandlers(fakeMessageService{}, fakeHeartbeatSettings{}, nil)
+	app.Get("/quick-heartbeat/:token?", heartbeat.QuickHeartbeat)
+	app.Get("/quick-heartbeat-confirm/:token?", heartbeat.ConfirmQuickHeartbeat)
+	app.Get("/plain-error", func(c *fiber.Ctx) error { return writeError(c, errors.New("boom")) })
+	app.Get("/codeless", func(c *fiber.Ctx) error { return writeError(c, &services.APIError{Status: 418, Message: "teapot"}) })
+	app.Get("/returned", func(c *fiber.Ctx) error { return errors.New("unhandled") })
+	app.Get("/fiber-error", func(c *fiber.Ctx) error { return fiber.ErrUnprocessableEntity })
+	app.Post("/upload", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	// fasthttp reports an exceeded BodyLimit as this error, which app.Test
+	// cannot reproduce because it fails the request before a response.
+	app.Put("/upload", func(c *fiber.Ctx) error { return fiber.ErrRequestEntityTooLarge })
+	app.Get("/private", middleware.MasterAuth(&fakeAuthService{}, nil, config.Config{}), func(c *fiber.Ctx) error { return nil })
+	app.Get("/limited", middleware.RateLimitReached)
+	app.Get("/me", func(c *fiber.Ctx) error {
+		_, err := currentUserID(c)
+		return writeError(c, err)
+	})
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"quick heartbeat without token", http.MethodGet, "/quick-heartbeat", "", 400, services.CodeBadRequest},
+		{"confirm heartbeat without token", http.MethodGet, "/quick-heartbeat-confirm", "", 400, services.CodeBadRequest},
+		{"plain error", http.MethodGet, "/plain-error", "", 500, services.CodeInternal},
+		{"api error without code", http.MethodGet, "/codeless", "", 418, services.CodeInternal},
+		{"error returned from handler", http.MethodGet, "/returned", "", 500, services.CodeInternal},
+		{"fiber error", http.MethodGet, "/fiber-error", "", 422, services.CodeBadRequest},
+		{"unknown route", http.MethodGet, "/missing", "", 404, services.CodeNotFound},
+		{"wrong method", http.MethodGet, "/upload", "", 405, services.CodeMethodNotAllowed},
+		{"oversized body", http.MethodPut, "/upload", "", 413, services.CodePayloadTooLarge},
+		{"missing session", http.MethodGet, "/private", "", 401, services.CodeUnauthorized},
+		{"rate limited", http.MethodGet, "/limited", "", 429, services.CodeRateLimited},
+		{"no user in context", http.MethodGet, "/me", "", 401, services.CodeUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.status)
+			}
+			var payload struct {
+				Error string `json:"error"`
+				Code  string `json:"code"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+				t.Fatalf("response is not a JSON error: %v", err)
+			}
+			if payload.Error == "" {
+				t.Fatalf("expected an error message")
+			}
+			if payload.Code != tc.code {
+				t.Fatalf("code = %q, want %q", payload.Code, tc.code)
+			}
+			if !slices.Contains(services.ErrorCodes, payload.Code) {
+				t.Fatalf("code %q is not listed in services.ErrorCodes", payload.Code)
+			}
+		})
+	}
+}
+
+func TestErrorResponseNamesInvalidField(t *testing.T) {
+	app := fiber.New()
+	app.Get("/field", func(c *fiber.Ctx) error {
+		return writeError(c, services.InvalidField("recipient_email", "Invalid email format", nil))
+	})
+	app.Get("/plain", func(c *fiber.Ctx) error { return writeError(c, services.BadRequest("Invalid request body", nil)) })
+
+	for path, want := range map[string]string{"/field": "recipient_email", "/plain": ""} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+			t.Fatalf("response is not JSON: %v", err)
+		}
+		resp.Body.Close()
+		field, present := payload["field"]
+		if want == "" && present {
+			t.Fatalf("%s: unexpected field %v", path, field)
+		}
+		if want != "" && field != want {
+			t.Fatalf("%s: field = %v, want %q", path, field, want)
+		}
+	}
+}
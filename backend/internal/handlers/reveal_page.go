@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// revealPageData is passed to the reveal page served for
+// GET /messages/:id?format=html. Content is empty until the switch has
+// triggered, and again once it has been shredded. Attachments link the
+// retained files anyone with the link may download; EmailedOnly counts the
+// rest, which were only sent by email.
+// Once no files are retained, Included counts the files that were delivered
+// and IncludedNames names those meant for every recipient.
+type revealPageData struct {
+	BrandName     string
+	Triggered     bool
+	Shredded      bool
+	Content       string
+	Attachments   []revealPageAttachment
+	EmailedOnly   int64
+	Included      int
+	IncludedNames []string
+}
+
+type revealPageAttachment struct {
+	Filename string
+	URL      string
+}
+
+const revealPageHTML = `<!DOCTYPE html>
+<html>
+<head>
+    <title>{{if .Triggered}}A message for you{{else}}Message not yet available{{end}} - {{.BrandName}}</title>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <meta name="robots" content="noindex, nofollow">
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            background: #fafafa;
+            color: #333;
+            min-height: 100vh;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            margin: 0;
+            padding: 1rem;
+        }
+        .container {
+            background: white;
+            border-radius: 12px;
+            box-shadow: 0 10px 40px rgba(0,0,0,0.08);
+            padding: 2.5rem 2rem;
+            max-width: 640px;
+            width: 100%;
+        }
+        h1 { font-size: 1.25rem; font-weight: 600; margin: 0 0 1.5rem; color: #1a1a1a; }
+        .content {
+            white-space: pre-wrap;
+            overflow-wrap: anywhere;
+            line-height: 1.6;
+            font-size: 1rem;
+        }
+        .note { color: #666; font-size: 0.9rem; line-height: 1.5; }
+        .attachments { margin-top: 2rem; padding-top: 1rem; border-top: 1px solid #eee; }
+        .attachments ul { padding-left: 1.25rem; line-height: 1.8; }
+        .attachments a { color: #667eea; }
+        .footer { margin-top: 2rem; font-size: 0.75rem; color: #999; text-align: center; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        {{if .Triggered}}
+        <h1>A message for you</h1>
+        {{if .Shredded}}
+        <p class="note">This message was permanently deleted some time after it was delivered.</p>
+        {{else}}
+        <div class="content">{{.Content}}</div>
+        {{end}}
+        {{if or .Attachments .EmailedOnly}}
+        <div class="attachments">
+            {{if .Attachments}}
+            <p class="note">Attachments:</p>
+            <ul>
+                {{range .Attachments}}<li><a href="{{.URL}}">{{.Filename}}</a></li>
+                {{end}}
+            </ul>
+            {{end}}
+            {{if .EmailedOnly}}
+            <p class="note">{{if .Attachments}}{{.EmailedOnly}} more{{else}}This message came with {{.EmailedOnly}}{{end}} attachment{{if gt .EmailedOnly 1}}s{{end}}, delivered to you by email.</p>
+            {{end}}
+        </div>
+        {{else if .Included}}
+        <div class="attachments">
+            <p class="note">{{.Included}} file{{if gt .Included 1}}s were{{else}} was{{end}} included with this message. {{if gt .Included 1}}They are{{else}}It is{{end}} no longer stored here; contact the sender's executor to obtain {{if gt .Included 1}}them{{else}}it{{end}}.</p>
+            {{if .IncludedNames}}
+            <ul>
+                {{range .IncludedNames}}<li>{{.}}</li>
+                {{end}}
+            </ul>
+            {{end}}
+        </div>
+        {{end}}
+        {{else}}
+        <h1>Message not yet available</h1>
+        <p class="note">This message has not been released. It will be delivered to you when the time comes.</p>
+        {{end}}
+        <p class="footer">{{.BrandName}}</p>
+    </div>
+</body>
+</html>
+`
+
+var revealPage = template.Must(template.New("reveal").Parse(revealPageHTML))
+
+// renderRevealPage serves the message as a standalone page a recipient can
+// open straight from the link.
+func renderRevealPage(c *fiber.Ctx, msg models.Message) error {
+	data := revealPageData{
+		BrandName: defaultBrandName,
+		Triggered: msg.Status == models.StatusTriggered,
+		Shredded:  msg.ShreddedAt != nil,
+	}
+	if data.Triggered {
+		data.Content = msg.Content
+		base := c.Path() + "/reveal/attachments/"
+		for _, attachment := range msg.RevealAttachments {
+			data.Attachments = append(data.Attachments, revealPageAttachment{
+				Filename: attachment.Filename,
+				URL:      base + url.PathEscape(attachment.ID),
+			})
+		}
+		data.EmailedOnly = msg.AttachmentCount - int64(len(data.Attachments))
+		if msg.AttachmentCount == 0 {
+			data.Included = len(msg.IncludedAttachments)
+			for _, attachment := range namedIncludedAttachments(msg) {
+				data.IncludedNames = append(data.IncludedNames, attachment.Filename)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := revealPage.Execute(&buf, data); err != nil {
+		return writeError(c, services.Internal("Failed to render message page", err))
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	c.Set("Cache-Control", "no-store")
+	return c.Send(buf.Bytes())
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/alpyxn/aeterna/backend/internal/models"
 	"github.com/alpyxn/aeterna/backend/internal/ports"
 	"github.com/alpyxn/aeterna/backend/internal/services"
@@ -10,18 +13,20 @@ import (
 // settingsResponse embeds tenant settings and adds global registration flags.
 type settingsResponse struct {
 	models.Settings
-	AllowRegistration     bool `json:"allow_registration"`
-	CanManageRegistration bool `json:"can_manage_registration"`
+	AllowRegistration     bool   `json:"allow_registration"`
+	CanManageRegistration bool   `json:"can_manage_registration"`
+	AllowedOrigins        string `json:"allowed_origins,omitempty"`
 }
 
 // SettingsHandlers groups SMTP settings and application configuration handlers.
 type SettingsHandlers struct {
 	settings    ports.SettingsServicePort
 	appSettings ports.ApplicationSettingsServicePort
+	origins     ports.OriginAllowlistPort
 }
 
-func NewSettingsHandlers(settings ports.SettingsServicePort, appSettings ports.ApplicationSettingsServicePort) *SettingsHandlers {
-	return &SettingsHandlers{settings: settings, appSettings: appSettings}
+func NewSettingsHandlers(settings ports.SettingsServicePort, appSettings ports.ApplicationSettingsServicePort, origins ports.OriginAllowlistPort) *SettingsHandlers {
+	return &SettingsHandlers{settings: settings, appSettings: appSettings, origins: origins}
 }
 
 func (h *SettingsHandlers) Get(c *fiber.Ctx) error {
@@ -37,11 +42,15 @@ func (h *SettingsHandlers) Get(c *fiber.Ctx) error {
 	if err != nil {
 		return writeError(c, err)
 	}
-	return c.JSON(settingsResponse{
+	resp := settingsResponse{
 		Settings:              settings,
 		AllowRegistration:     app.AllowRegistration,
 		CanManageRegistration: h.appSettings.CanManageRegistration(userID),
-	})
+	}
+	if resp.CanManageRegistration {
+		resp.AllowedOrigins = h.origins.AllowedOrigins()
+	}
+	return c.JSON(resp)
 }
 
 func (h *SettingsHandlers) Save(c *fiber.Ctx) error {
@@ -49,22 +58,79 @@ func (h *SettingsHandlers) Save(c *fiber.Ctx) error {
 	if err != nil {
 		return writeError(c, err)
 	}
-	settingsSvc := withOriginSession(c, h.settings)
 	var req models.SettingsRequest
 	if err := c.BodyParser(&req); err != nil {
 		return writeError(c, services.BadRequest("Invalid request body", err))
 	}
+	return h.save(c, userID, req)
+}
+
+// Patch updates only the settings present in the body; everything else keeps
+// its stored value, so one field can change without re-sending the rest.
+func (h *SettingsHandlers) Patch(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	current, err := h.settings.Get(userID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	req := models.SettingsRequestFrom(current)
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return writeError(c, services.BadRequest("Invalid request body", err))
+	}
+	return h.save(c, userID, req)
+}
+
+func (h *SettingsHandlers) save(c *fiber.Ctx, userID string, req models.SettingsRequest) error {
+	settingsSvc := withOriginSession(c, h.settings)
+	settings := req.ToSettings()
+	if c.QueryBool("verify") {
+		if err := h.verifySMTP(c.UserContext(), userID, settings); err != nil {
+			return writeError(c, err)
+		}
+	}
+	// Application-wide settings are checked up front but only applied once
+	// the account's settings have saved, so a rejected save changes nothing.
+	if req.AllowRegistration != nil && !h.appSettings.CanManageRegistration(userID) {
+		return writeError(c, services.NewAPIError(403, services.CodeForbidden, "Only the primary administrator can change registration settings.", nil))
+	}
+	if req.AllowedOrigins != nil {
+		if err := h.origins.Validate(userID, *req.AllowedOrigins); err != nil {
+			return writeError(c, err)
+		}
+	}
+	if err := settingsSvc.Save(userID, settings); err != nil {
+		return writeError(c, err)
+	}
 	if req.AllowRegistration != nil {
 		if err := h.appSettings.SetAllowRegistration(userID, *req.AllowRegistration); err != nil {
 			return writeError(c, err)
 		}
 	}
-	if err := settingsSvc.Save(userID, req.ToSettings()); err != nil {
-		return writeError(c, err)
+	if req.AllowedOrigins != nil {
+		if err := h.origins.Set(userID, *req.AllowedOrigins); err != nil {
+			return writeError(c, err)
+		}
 	}
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// verifySMTP runs the connection test before a save requested with
+// ?verify=true. A blank password means the stored one is kept, so that is
+// the one tested.
+func (h *SettingsHandlers) verifySMTP(ctx context.Context, userID string, settings models.Settings) error {
+	if settings.SMTPPass == "" {
+		current, err := h.settings.Get(userID)
+		if err != nil {
+			return err
+		}
+		settings.SMTPPass = current.SMTPPass
+	}
+	return h.settings.TestSMTP(ctx, settings)
+}
+
 func (h *SettingsHandlers) TestSMTP(c *fiber.Ctx) error {
 	if _, err := currentUserID(c); err != nil {
 		return writeError(c, err)
@@ -73,7 +139,7 @@ func (h *SettingsHandlers) TestSMTP(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return writeError(c, services.BadRequest("Invalid request body", err))
 	}
-	if err := h.settings.TestSMTP(req.ToSettings()); err != nil {
+	if err := h.settings.TestSMTP(c.UserContext(), req.ToSettings()); err != nil {
 		return writeError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "message": "Connection successful"})
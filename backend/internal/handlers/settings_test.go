@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/middleware"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type recordingSettingsService struct {
+	fakeHeartbeatSettings
+	saved *models.Settings
+}
+
+func (f recordingSettingsService) Save(userID string, req models.Settings) error {
+	*f.saved = req
+	return nil
+}
+
+func TestSettingsPatchKeepsUnsentFields(t *testing.T) {
+	footer := "Sent by Aeterna"
+	stored := models.Settings{
+		UserID:         "u1",
+		SMTPHost:       "smtp.example.com",
+		SMTPPort:       "587",
+		SMTPUser:       "mailer",
+		SMTPPass:       "stored-pass",
+		SMTPFrom:       "mailer@example.com",
+		OwnerEmail:     "old@example.com",
+		WebhookEnabled: true,
+		WebhookURL:     "https://hooks.example.com/aeterna",
+		WebhookSecret:  "stored-secret",
+		FooterText:     &footer,
+		Timezone:       "Europe/Berlin",
+	}
+	var saved models.Settings
+	svc := recordingSettingsService{fakeHeartbeatSettings{settings: stored}, &saved}
+	h := NewSettingsHandlers(svc, nil, nil)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "u1")
+		return c.Next()
+	})
+	app.Patch("/settings", h.Patch)
+
+	req := httptest.NewRequest(http.MethodPatch, "/settings", strings.NewReader(`{"owner_email":"new@example.com","webhook_enabled":false}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	if saved.OwnerEmail != "new@example.com" || saved.WebhookEnabled {
+		t.Fatalf("sent fields were not applied: %+v", saved)
+	}
+	if saved.SMTPHost != "smtp.example.com" || saved.SMTPPort != "587" || saved.SMTPUser != "mailer" || saved.SMTPFrom != "mailer@example.com" {
+		t.Fatalf("SMTP settings were not preserved: %+v", saved)
+	}
+	if saved.WebhookURL != stored.WebhookURL || saved.Timezone != "Europe/Berlin" {
+		t.Fatalf("unsent fields were not preserved: %+v", saved)
+	}
+	if saved.FooterText == nil || *saved.FooterText != footer {
+		t.Fatalf("footer was not preserved: %v", saved.FooterText)
+	}
+	if saved.SMTPPass != "" || saved.WebhookSecret != "" {
+		t.Fatalf("secrets should be left blank so Save keeps the stored ones, got %q / %q", saved.SMTPPass, saved.WebhookSecret)
+	}
+}
+
+func TestSettingsPatchRejectsInvalidJSON(t *testing.T) {
+	var saved models.Settings
+	h := NewSettingsHandlers(recordingSettingsService{saved: &saved}, nil, nil)
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "u1")
+		return c.Next()
+	})
+	app.Patch("/settings", h.Patch)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPatch, "/settings", strings.NewReader(`{"owner_email":`)))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+type verifyingSettingsService struct {
+	recordingSettingsService
+	tested *models.Settings
+}
+
+func (f verifyingSettingsService) TestSMTP(_ context.Context, req models.Settings) error {
+	*f.tested = req
+	return services.BadRequest("Authentication failed", nil)
+}
+
+func TestSettingsSaveVerifiesSMTPWhenRequested(t *testing.T) {
+	var saved, tested models.Settings
+	stored := models.Settings{SMTPPass: "stored-pass"}
+	svc := verifyingSettingsService{recordingSettingsService{fakeHeartbeatSettings{settings: stored}, &saved}, &tested}
+	h := NewSettingsHandlers(svc, nil, nil)
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "u1")
+		return c.Next()
+	})
+	app.Post("/settings", h.Save)
+
+	body := `{"smtp_host":"smtp.example.com","smtp_port":"587","smtp_user":"mailer"}`
+	req := httptest.NewRequest(http.MethodPost, "/settings?verify=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 from the failed connection test", resp.StatusCode)
+	}
+	if tested.SMTPHost != "smtp.example.com" || tested.SMTPPass != "stored-pass" {
+		t.Fatalf("expected the posted settings to be tested with the stored password, got %+v", tested)
+	}
+	if saved.SMTPHost != "" {
+		t.Fatalf("settings must not be saved when verification fails")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/settings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err = app.Test(req); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected save without verify to skip the connection test, got %v %v", resp, err)
+	}
+}
+
+type recordingOrigins struct {
+	set *string
+}
+
+func (f recordingOrigins) AllowedOrigins() string { return "" }
+
+func (f recordingOrigins) Validate(_ string, origins string) error {
+	if strings.Contains(origins, "*") {
+		return services.BadRequest("Invalid origin", nil)
+	}
+	return nil
+}
+
+func (f recordingOrigins) Set(_ string, origins string) error {
+	*f.set = origins
+	return nil
+}
+
+type failingSettingsService struct {
+	recordingSettingsService
+}
+
+func (f failingSettingsService) Save(string, models.Settings) error {
+	return services.BadRequest("Invalid owner email", nil)
+}
+
+func TestSettingsSaveAppliesOriginsOnlyAfterSettingsSave(t *testing.T) {
+	var saved models.Settings
+	var applied string
+	post := func(svc ports.SettingsServicePort, body string) int {
+		t.Helper()
+		h := NewSettingsHandlers(svc, nil, recordingOrigins{&applied})
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.LocalUserIDKey, "u1")
+			return c.Next()
+		})
+		app.Post("/settings", h.Save)
+		req := httptest.NewRequest(http.MethodPost, "/settings", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	failing := failingSettingsService{recordingSettingsService{saved: &saved}}
+	if status := post(failing, `{"owner_email":"bad","allowed_origins":"https://a.example.com"}`); status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 from the failed save", status)
+	}
+	if applied != "" {
+		t.Fatalf("origins must not change when the settings save fails, got %q", applied)
+	}
+
+	recording := recordingSettingsService{saved: &saved}
+	if status := post(recording, `{"owner_email":"owner@example.com","allowed_origins":"*"}`); status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 for an invalid allowlist", status)
+	}
+	if saved.OwnerEmail != "" {
+		t.Fatalf("settings must not be saved with an invalid allowlist, got %+v", saved)
+	}
+
+	if status := post(recording, `{"owner_email":"owner@example.com","allowed_origins":"https://a.example.com"}`); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if saved.OwnerEmail != "owner@example.com" || applied != "https://a.example.com" {
+		t.Fatalf("expected both settings and origins applied, got %+v / %q", saved, applied)
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SimulationHandlers exposes worker dry runs for debugging trigger timing.
+type SimulationHandlers struct {
+	worker ports.WorkerSimulatorPort
+}
+
+func NewSimulationHandlers(worker ports.WorkerSimulatorPort) *SimulationHandlers {
+	return &SimulationHandlers{worker: worker}
+}
+
+// Simulate reports what the worker would do with a switch right now.
+// It is only routed outside production, and refuses there regardless.
+func (h *SimulationHandlers) Simulate(c *fiber.Ctx) error {
+	if isProd, _ := c.Locals(productionModeLocalKey).(bool); isProd {
+		return writeError(c, services.NotFound("Not found", nil))
+	}
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	sim, err := h.worker.Simulate(userID, c.Params("id"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(sim)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/middleware"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeSimulator struct {
+	calls int
+}
+
+func (f *fakeSimulator) Simulate(userID, messageID string) (ports.SwitchSimulation, error) {
+	f.calls++
+	return ports.SwitchSimulation{MessageID: messageID, Due: true, Action: "trigger"}, nil
+}
+
+func simulateRequest(t *testing.T, production bool, sim *fakeSimulator) *http.Response {
+	t.Helper()
+	app := fiber.New()
+	app.Use(AttachRuntimeFlags(production))
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalUserIDKey, "u1")
+		return c.Next()
+	})
+	app.Post("/api/messages/:id/simulate", NewSimulationHandlers(sim).Simulate)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/api/messages/m1/simulate", nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	return resp
+}
+
+func TestSimulateReportsWorkerDecision(t *testing.T) {
+	sim := &fakeSimulator{}
+	resp := simulateRequest(t, false, sim)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body["message_id"] != "m1" || body["action"] != "trigger" || body["due"] != true {
+		t.Fatalf("unexpected simulation: %v", body)
+	}
+}
+
+func TestSimulateIsUnavailableInProduction(t *testing.T) {
+	sim := &fakeSimulator{}
+	resp := simulateRequest(t, true, sim)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || sim.calls != 0 {
+		t.Fatalf("status = %d, calls = %d; want 404 without running the simulation", resp.StatusCode, sim.calls)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// StatusHandlers exposes process uptime and worker liveness.
+type StatusHandlers struct {
+	worker    ports.WorkerStatusPort
+	startedAt time.Time
+	// isPrimary reports whether a user may see worker error details; nil
+	// means services.IsFirstUser.
+	isPrimary func(userID string) bool
+}
+
+func NewStatusHandlers(worker ports.WorkerStatusPort, startedAt time.Time) *StatusHandlers {
+	return &StatusHandlers{worker: worker, startedAt: startedAt}
+}
+
+// publicWorkerStatus is the worker snapshot served without authentication.
+// The message ID and text of the last error can name any account's switch or
+// recipients, so only when and in which check it happened are shown.
+type publicWorkerStatus struct {
+	ports.WorkerStatus
+	LastError *publicWorkerError `json:"last_error"`
+}
+
+type publicWorkerError struct {
+	At    time.Time `json:"at"`
+	Check string    `json:"check"`
+}
+
+// Status reports "stalled" when the worker has missed more than two ticks,
+// which usually means its goroutine died or is stuck, and "degraded" when
+// its latest run recorded an error, such as a trigger email that failed.
+// Under MAINTENANCE_MODE it reports "maintenance", since the worker
+// deliberately completes no ticks. The route is public, so the last error is
+// reduced to its time and check; Details serves it in full.
+func (h *StatusHandlers) Status(c *fiber.Ctx) error {
+	worker := h.worker.Status()
+	public := publicWorkerStatus{WorkerStatus: worker}
+	if worker.LastError != nil {
+		public.LastError = &publicWorkerError{At: worker.LastError.At, Check: worker.LastError.Check}
+	}
+	return h.respond(c, worker, public)
+}
+
+// Details is Status with the last error's message ID and text, for the
+// primary administrator only.
+func (h *StatusHandlers) Details(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	isPrimary := h.isPrimary
+	if isPrimary == nil {
+		isPrimary = services.IsFirstUser
+	}
+	if !isPrimary(userID) {
+		return writeError(c, services.NewAPIError(403, services.CodeForbidden, "Only the primary administrator can view worker error details.", nil))
+	}
+	worker := h.worker.Status()
+	return h.respond(c, worker, worker)
+}
+
+func (h *StatusHandlers) respond(c *fiber.Ctx, worker ports.WorkerStatus, shown any) error {
+	now := time.Now().UTC()
+	return c.JSON(fiber.Map{
+		"status":         workerState(worker, now),
+		"started_at":     h.startedAt.UTC(),
+		"uptime_seconds": int64(now.Sub(h.startedAt).Seconds()),
+		"worker":         shown,
+	})
+}
+
+// WorkerHealth is a minimal liveness probe for external monitors: it answers
+// 503 while the worker is stalled and 200 otherwise, so a monitor can alert
+// on the status code alone.
+func (h *StatusHandlers) WorkerHealth(c *fiber.Ctx) error {
+	worker := h.worker.Status()
+	state := workerState(worker, time.Now().UTC())
+	if state == "stalled" {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(fiber.Map{
+		"status":       state,
+		"last_tick_at": worker.LastTickAt,
+	})
+}
+
+func workerState(worker ports.WorkerStatus, now time.Time) string {
+	lastActivity := worker.StartedAt
+	if worker.LastTickAt != nil {
+		lastActivity = *worker.LastTickAt
+	}
+	switch {
+	case worker.Maintenance:
+		return "maintenance"
+	case worker.Interval > 0 && now.Sub(lastActivity) > 2*worker.Interval+worker.Interval/2:
+		return "stalled"
+	case worker.LastError != nil && worker.LastRunAt != nil && !worker.LastError.At.Before(*worker.LastRunAt):
+		return "degraded"
+	}
+	return "ok"
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/middleware"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeWorkerStatus struct {
+	status ports.WorkerStatus
+}
+
+func (f fakeWorkerStatus) Status() ports.WorkerStatus {
+	return f.status
+}
+
+func statusResponse(t *testing.T, worker ports.WorkerStatus) map[string]any {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/api/status", NewStatusHandlers(fakeWorkerStatus{status: worker}, time.Now().Add(-time.Hour)).Status)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return body
+}
+
+func TestStatusReportsHealthyWorker(t *testing.T) {
+	last := time.Now().Add(-30 * time.Second)
+	body := statusResponse(t, ports.WorkerStatus{
+		StartedAt:  time.Now().Add(-time.Hour),
+		LastTickAt: &last,
+		NextTickAt: last.Add(time.Minute),
+		Interval:   time.Minute,
+	})
+	if body["status"] != "ok" {
+		t.Fatalf("status = %v, want ok", body["status"])
+	}
+	if uptime, _ := body["uptime_seconds"].(float64); uptime < 3599 {
+		t.Fatalf("uptime_seconds = %v, want about 3600", body["uptime_seconds"])
+	}
+	worker, _ := body["worker"].(map[string]any)
+	if worker["last_tick_at"] == nil {
+		t.Fatalf("expected last_tick_at in %v", body)
+	}
+}
+
+func TestStatusReportsStalledWorker(t *testing.T) {
+	last := time.Now().Add(-10 * time.Minute)
+	body := statusResponse(t, ports.WorkerStatus{
+		StartedAt:  time.Now().Add(-time.Hour),
+		LastTickAt: &last,
+		NextTickAt: last.Add(time.Minute),
+		Interval:   time.Minute,
+	})
+	if body["status"] != "stalled" {
+		t.Fatalf("status = %v, want stalled", body["status"])
+	}
+}
+
+func TestStatusReportsDegradedWorker(t *testing.T) {
+	run := time.Now().Add(-30 * time.Second)
+	worker := ports.WorkerStatus{
+		StartedAt:  time.Now().Add(-time.Hour),
+		LastTickAt: &run,
+		LastRunAt:  &run,
+		NextTickAt: run.Add(time.Minute),
+		Interval:   time.Minute,
+		LastError:  &ports.WorkerError{At: run.Add(time.Second), Check: "heartbeats", MessageID: "m1", Error: "trigger email: 550 rejected"},
+	}
+	body := statusResponse(t, worker)
+	if body["status"] != "degraded" {
+		t.Fatalf("status = %v, want degraded", body["status"])
+	}
+	details, _ := body["worker"].(map[string]any)
+	lastErr, _ := details["last_error"].(map[string]any)
+	if lastErr["check"] != "heartbeats" || lastErr["at"] == nil {
+		t.Fatalf("expected when and where the last error happened in %v", body)
+	}
+	if _, ok := lastErr["message_id"]; ok {
+		t.Fatalf("the public status must not name the switch: %v", lastErr)
+	}
+	if _, ok := lastErr["error"]; ok {
+		t.Fatalf("the public status must not carry the error text: %v", lastErr)
+	}
+
+	// An error from an earlier run no longer degrades the status.
+	worker.LastError.At = run.Add(-time.Minute)
+	if body := statusResponse(t, worker); body["status"] != "ok" {
+		t.Fatalf("status = %v, want ok once a later run succeeded", body["status"])
+	}
+}
+
+func TestStatusDetailsAreForThePrimaryAdministrator(t *testing.T) {
+	run := time.Now().Add(-30 * time.Second)
+	h := NewStatusHandlers(fakeWorkerStatus{status: ports.WorkerStatus{
+		StartedAt: time.Now().Add(-time.Hour),
+		LastRunAt: &run,
+		LastError: &ports.WorkerError{At: run, Check: "heartbeats", MessageID: "m1", Error: "trigger email: 550 rejected"},
+	}}, time.Now().Add(-time.Hour))
+	h.isPrimary = func(userID string) bool { return userID == "admin" }
+	request := func(userID string) (int, map[string]any) {
+		app := fiber.New()
+		app.Get("/api/status/details", func(c *fiber.Ctx) error {
+			c.Locals(middleware.LocalUserIDKey, userID)
+			return h.Details(c)
+		})
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/status/details", nil))
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp.StatusCode, body
+	}
+
+	if code, _ := request("member"); code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d for another account", code, http.StatusForbidden)
+	}
+	code, body := request("admin")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	details, _ := body["worker"].(map[string]any)
+	lastErr, _ := details["last_error"].(map[string]any)
+	if lastErr["message_id"] != "m1" || lastErr["error"] != "trigger email: 550 rejected" {
+		t.Fatalf("expected the full last_error in %v", body)
+	}
+}
+
+func TestWorkerHealthFailsWhileStalled(t *testing.T) {
+	last := time.Now().Add(-10 * time.Minute)
+	worker := ports.WorkerStatus{
+		StartedAt:  time.Now().Add(-time.Hour),
+		LastTickAt: &last,
+		Interval:   time.Minute,
+	}
+	probe := func() int {
+		app := fiber.New()
+		app.Get("/api/status/worker", NewStatusHandlers(fakeWorkerStatus{status: worker}, time.Now()).WorkerHealth)
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/status/worker", nil))
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d while stalled", code, http.StatusServiceUnavailable)
+	}
+	recent := time.Now().Add(-30 * time.Second)
+	worker.LastTickAt = &recent
+	if code := probe(); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d once ticks resume", code, http.StatusOK)
+	}
+}
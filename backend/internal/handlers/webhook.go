@@ -8,18 +8,24 @@ import (
 )
 
 type webhookRequest struct {
-	URL     string `json:"url"`
-	Secret  string `json:"secret"`
-	Enabled bool   `json:"enabled"`
+	URL             string `json:"url"`
+	Secret          string `json:"secret"`
+	Enabled         bool   `json:"enabled"`
+	PayloadTemplate string `json:"payload_template"`
+}
+
+type webhookAllowlistRequest struct {
+	Hosts string `json:"hosts"`
 }
 
 // WebhookHandlers groups webhook CRUD route handlers.
 type WebhookHandlers struct {
-	webhooks ports.WebhookStorePort
+	webhooks  ports.WebhookStorePort
+	allowlist ports.WebhookAllowlistPort
 }
 
-func NewWebhookHandlers(webhooks ports.WebhookStorePort) *WebhookHandlers {
-	return &WebhookHandlers{webhooks: webhooks}
+func NewWebhookHandlers(webhooks ports.WebhookStorePort, allowlist ports.WebhookAllowlistPort) *WebhookHandlers {
+	return &WebhookHandlers{webhooks: webhooks, allowlist: allowlist}
 }
 
 func (h *WebhookHandlers) List(c *fiber.Ctx) error {
@@ -45,9 +51,10 @@ func (h *WebhookHandlers) Create(c *fiber.Ctx) error {
 		return writeError(c, services.BadRequest("Invalid request body", err))
 	}
 	item := models.Webhook{
-		URL:     req.URL,
-		Secret:  req.Secret,
-		Enabled: req.Enabled,
+		URL:             req.URL,
+		Secret:          req.Secret,
+		Enabled:         req.Enabled,
+		PayloadTemplate: req.PayloadTemplate,
 	}
 	created, err := webhookStore.Create(userID, item)
 	if err != nil {
@@ -68,9 +75,10 @@ func (h *WebhookHandlers) Update(c *fiber.Ctx) error {
 		return writeError(c, services.BadRequest("Invalid request body", err))
 	}
 	item := models.Webhook{
-		URL:     req.URL,
-		Secret:  req.Secret,
-		Enabled: req.Enabled,
+		URL:             req.URL,
+		Secret:          req.Secret,
+		Enabled:         req.Enabled,
+		PayloadTemplate: req.PayloadTemplate,
 	}
 	updated, err := webhookStore.Update(userID, id, item)
 	if err != nil {
@@ -79,6 +87,21 @@ func (h *WebhookHandlers) Update(c *fiber.Ctx) error {
 	return c.JSON(updated)
 }
 
+// Test sends a webhook.test delivery, re-enabling the webhook if it had been
+// disabled after repeated failures.
+func (h *WebhookHandlers) Test(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	webhookStore := withOriginSession(c, h.webhooks)
+	tested, err := webhookStore.Test(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(tested)
+}
+
 func (h *WebhookHandlers) Delete(c *fiber.Ctx) error {
 	userID, err := currentUserID(c)
 	if err != nil {
@@ -91,3 +114,31 @@ func (h *WebhookHandlers) Delete(c *fiber.Ctx) error {
 	}
 	return c.JSON(fiber.Map{"success": true})
 }
+
+func (h *WebhookHandlers) GetAllowlist(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	allowlist, err := h.allowlist.Get(userID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(allowlist)
+}
+
+func (h *WebhookHandlers) SetAllowlist(c *fiber.Ctx) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var req webhookAllowlistRequest
+	if err := c.BodyParser(&req); err != nil {
+		return writeError(c, services.BadRequest("Invalid request body", err))
+	}
+	allowlist, err := h.allowlist.Set(userID, req.Hosts)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(allowlist)
+}
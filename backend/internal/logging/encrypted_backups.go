@@ -0,0 +1,172 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// backupMagic starts every encrypted log backup so a scan can tell them
+// from backups still waiting to be encrypted.
+var backupMagic = []byte("aeterna-encrypted-log-v1\n")
+
+// backupContext binds backup ciphertext to its purpose, so it cannot be
+// swapped for a database value encrypted with the same key.
+const backupContext = "log-backup"
+
+// defaultLogMaxSizeMB is lumberjack's size limit when MaxSize is 0.
+const defaultLogMaxSizeMB = 100
+
+// encryptingWriter rotates a lumberjack log itself, so it knows when a
+// backup appears, and then encrypts every plaintext backup with the
+// application key. Compression is done here before encrypting; lumberjack's
+// own compression must be off or it would race with the encryption.
+type encryptingWriter struct {
+	mu       sync.Mutex
+	out      *lumberjack.Logger
+	size     int64
+	maxBytes int64
+	compress bool
+}
+
+func newEncryptingWriter(out *lumberjack.Logger, compress bool) *encryptingWriter {
+	maxMB := out.MaxSize
+	if maxMB <= 0 {
+		maxMB = defaultLogMaxSizeMB
+	}
+	w := &encryptingWriter{out: out, maxBytes: int64(maxMB) * 1024 * 1024, compress: compress}
+	if info, err := os.Stat(out.Filename); err == nil {
+		w.size = info.Size()
+	}
+	return w
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.out.Rotate(); err != nil {
+			return 0, err
+		}
+		w.size = 0
+		go w.encryptBackups()
+	}
+	n, err := w.out.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// encryptBackups encrypts every rotated backup of the log that is not
+// encrypted yet, including ones left over from before LOG_ENCRYPT_BACKUPS
+// was turned on. Failures are reported on stderr, since logging them would
+// write to the file being rotated.
+func (w *encryptingWriter) encryptBackups() {
+	for _, path := range backupFiles(w.out.Filename) {
+		if err := encryptBackup(path, w.compress); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encrypt log backup %s: %v\n", path, err)
+		}
+	}
+}
+
+// backupFiles lists the rotated backups lumberjack keeps next to filename,
+// named name-<timestamp>.ext with an optional .gz suffix.
+func backupFiles(filename string) []string {
+	dir := filepath.Dir(filename)
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filepath.Base(filename), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasSuffix(name, ext) || strings.HasSuffix(name, ext+".gz") {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths
+}
+
+// encryptBackup replaces a plaintext backup with its encryption, gzipped
+// first when compress is set. The result keeps a name lumberjack recognises
+// (adding .gz when compressed) so LOG_MAX_BACKUPS and LOG_MAX_AGE still
+// prune it.
+func encryptBackup(path string, compress bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(data, backupMagic) {
+		return nil
+	}
+	target := path
+	if compress && !isGzip(data) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+		target = path + ".gz"
+	}
+	sealed, err := services.CryptoService{}.EncryptBytesWithContext(data, backupContext)
+	if err != nil {
+		return err
+	}
+
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, append(append([]byte{}, backupMagic...), sealed...), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if target != path {
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// DecryptBackup returns the log lines of an encrypted backup, gunzipping
+// them when the backup was compressed. The encryption key must already be
+// loaded with services.InitKeyManager.
+func DecryptBackup(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, backupMagic) {
+		return nil, errors.New("not an encrypted log backup")
+	}
+	plain, err := services.CryptoService{}.DecryptBytesWithContext(data[len(backupMagic):], backupContext)
+	if err != nil {
+		return nil, err
+	}
+	if !isGzip(plain) {
+		return plain, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
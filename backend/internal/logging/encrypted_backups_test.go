@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+func TestEncryptingWriterEncryptsRotatedBackups(t *testing.T) {
+	key, err := services.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "enc.key")
+	if err := os.WriteFile(keyPath, []byte(key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	services.InitKeyManager(keyPath)
+
+	logPath := filepath.Join(dir, "aeterna.log")
+	w := newEncryptingWriter(&lumberjack.Logger{Filename: logPath}, true)
+	w.maxBytes = 64
+	defer w.out.Close()
+
+	first := []byte(`{"msg":"Switch triggered","recipient":"friend@example.com"}` + "\n")
+	if _, err := w.Write(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(`{"msg":"next file"}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	w.encryptBackups()
+
+	backups := backupFiles(logPath)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("expected one compressed backup, got %v", backups)
+	}
+	data, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, backupMagic) || bytes.Contains(data, []byte("friend@example.com")) {
+		t.Fatal("expected the backup to be encrypted")
+	}
+	plain, err := DecryptBackup(data)
+	if err != nil {
+		t.Fatalf("DecryptBackup: %v", err)
+	}
+	if !bytes.Equal(plain, first) {
+		t.Fatalf("decrypted backup = %q, want %q", plain, first)
+	}
+
+	// A second pass leaves the encrypted backup alone.
+	w.encryptBackups()
+	if again, _ := os.ReadFile(backups[0]); !bytes.Equal(again, data) {
+		t.Fatal("expected an encrypted backup not to be encrypted twice")
+	}
+	if current, _ := os.ReadFile(logPath); !bytes.Contains(current, []byte("next file")) {
+		t.Fatalf("expected the live log to stay plaintext, got %q", current)
+	}
+}
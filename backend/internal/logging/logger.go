@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -13,34 +14,34 @@ func Init(cfg config.Config) *slog.Logger {
 	level := parseLevel(cfg.Logging.Level)
 	format := strings.ToLower(cfg.Logging.Format)
 
-	var output *lumberjack.Logger
+	var output io.Writer = os.Stdout
 	if cfg.Logging.File != "" {
-		output = &lumberjack.Logger{
+		file := &lumberjack.Logger{
 			Filename:   cfg.Logging.File,
 			MaxSize:    cfg.Logging.MaxSize,
 			MaxBackups: cfg.Logging.MaxBackups,
 			MaxAge:     cfg.Logging.MaxAge,
 			Compress:   cfg.Logging.Compress,
 		}
+		output = file
+		if cfg.Logging.EncryptBackups {
+			file.Compress = false
+			output = newEncryptingWriter(file, cfg.Logging.Compress)
+		}
 	}
 
 	handlerOpts := &slog.HandlerOptions{
 		Level: level,
 	}
+	if cfg.Logging.Redact {
+		handlerOpts.ReplaceAttr = RedactAttr
+	}
 
 	var handler slog.Handler
 	if format == "text" {
-		if output != nil {
-			handler = slog.NewTextHandler(output, handlerOpts)
-		} else {
-			handler = slog.NewTextHandler(os.Stdout, handlerOpts)
-		}
+		handler = slog.NewTextHandler(output, handlerOpts)
 	} else {
-		if output != nil {
-			handler = slog.NewJSONHandler(output, handlerOpts)
-		} else {
-			handler = slog.NewJSONHandler(os.Stdout, handlerOpts)
-		}
+		handler = slog.NewJSONHandler(output, handlerOpts)
 	}
 
 	logger := slog.New(handler)
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s"',]+`)
+
+// sensitiveKeys name attributes whose whole value is a credential.
+var sensitiveKeys = []string{"token", "secret", "password", "passphrase", "key"}
+
+// RedactAttr is a slog ReplaceAttr function for LOG_REDACT: emails are
+// masked to their first letter and domain, URLs lose their path and query
+// (webhook and heartbeat URLs embed secrets there), and attributes named
+// like credentials keep only a short prefix.
+func RedactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if isSensitiveKey(a.Key) {
+			return slog.String(a.Key, truncateSecret(a.Value.String()))
+		}
+		return slog.String(a.Key, redactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, redactString(err.Error()))
+		}
+	}
+	return a
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if key == sensitive || strings.HasSuffix(key, "_"+sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactString(s string) string {
+	s = urlPattern.ReplaceAllStringFunc(s, redactURL)
+	return emailPattern.ReplaceAllString(s, "$1***@$2")
+}
+
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "[redacted-url]"
+	}
+	if (parsed.Path == "" || parsed.Path == "/") && parsed.RawQuery == "" {
+		return parsed.Scheme + "://" + parsed.Host
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/…"
+}
+
+func truncateSecret(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= 8 {
+		return "…"
+	}
+	return string(runes[:4]) + "…"
+}
@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactAttrMasksEmailsTokensAndURLs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: RedactAttr}))
+	logger.Warn("Switch triggered",
+		"recipient", "jane@example.com, bob.smith@mail.example.org",
+		"owner", "owner@example.com",
+		"url", "https://hooks.example.com/services/T000/B000/XXXXSECRET?token=abc",
+		"heartbeat_token", "0123456789abcdef",
+		"error", errors.New("550 mailbox jane@example.com unavailable"),
+		"id", "m-1",
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"jane@example.com", "bob.smith@", "owner@example.com", "XXXXSECRET", "token=abc", "0123456789abcdef"} {
+		if strings.Contains(out, leaked) {
+			t.Fatalf("log output leaks %q: %s", leaked, out)
+		}
+	}
+	for _, want := range []string{"j***@example.com", "b***@mail.example.org", "o***@example.com", "https://hooks.example.com/…", "heartbeat_token=0123…", "id=m-1"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log output missing %q: %s", want, out)
+		}
+	}
+}
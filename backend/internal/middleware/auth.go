@@ -7,45 +7,47 @@ import (
 	"time"
 
 	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
 	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
 func unauthorizedResponse(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 		"error": "Unauthorized access. Session required.",
-		"code":  "unauthorized",
+		"code":  services.CodeUnauthorized,
 	})
 }
 
 // MasterAuth returns a middleware that validates the session cookie and enforces the origin allowlist.
-func MasterAuth(auth ports.AuthServicePort, cfg config.Config) fiber.Handler {
-	allowedOrigins := cfg.AllowedOriginsOrDefault()
+func MasterAuth(auth ports.AuthServicePort, origins ports.OriginAllowlistPort, cfg config.Config) fiber.Handler {
 	isProd := cfg.IsProduction()
-	cookieSecureMode := cfg.Auth.CookieSecureMode
+	ignorePort := cfg.HTTP.AllowedOriginsIgnorePort
+	strict := !cfg.HTTP.AllowMissingOrigin
 	return func(c *fiber.Ctx) error {
 		if path := c.Path(); path == "/api/v2" || strings.HasPrefix(path, "/api/v2/") {
 			return c.Next()
 		}
 
-		if token := c.Cookies("aeterna_session"); token != "" {
+		if token := c.Cookies(SessionCookie(c, cfg.Auth).Name); token != "" {
 			userID, err := auth.VerifySessionToken(token)
 			if err == nil {
-				if !enforceOriginAllowlist(c, allowedOrigins, isProd) {
+				if !enforceOriginAllowlist(c, origins.AllowedOrigins(), ignorePort, isProd, strict) {
 					return nil
 				}
 				c.Locals(LocalUserIDKey, userID)
 				c.Locals(LocalSessionKey, auth.SessionKeyFromToken(token))
 				return c.Next()
 			}
-			clearSessionCookieWith(c, cookieSecureMode)
+			clearSessionCookieWith(c, cfg.Auth)
 		}
 
 		return unauthorizedResponse(c)
 	}
 }
 
-func enforceOriginAllowlist(c *fiber.Ctx, allowedOrigins string, isProd bool) bool {
+func enforceOriginAllowlist(c *fiber.Ctx, allowedOrigins string, ignorePort, isProd, strict bool) bool {
 	origin := strings.TrimSpace(c.Get("Origin"))
 
 	if !isProd {
@@ -67,12 +69,12 @@ func enforceOriginAllowlist(c *fiber.Ctx, allowedOrigins string, isProd bool) bo
 	}
 
 	if origin == "" {
-		if !isProd {
+		if !isProd || !strict {
 			return true
 		}
 		_ = c.Status(403).JSON(fiber.Map{
 			"error": "Origin required",
-			"code":  "origin_required",
+			"code":  services.CodeOriginRequired,
 		})
 		return false
 	}
@@ -81,7 +83,7 @@ func enforceOriginAllowlist(c *fiber.Ctx, allowedOrigins string, isProd bool) bo
 	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
 		_ = c.Status(403).JSON(fiber.Map{
 			"error": "Invalid origin",
-			"code":  "invalid_origin",
+			"code":  services.CodeInvalidOrigin,
 		})
 		return false
 	}
@@ -90,35 +92,56 @@ func enforceOriginAllowlist(c *fiber.Ctx, allowedOrigins string, isProd bool) bo
 		allowedOrigins = "http://localhost:5173"
 	}
 
-	for _, entry := range strings.Split(allowedOrigins, ",") {
-		entry = strings.TrimSpace(entry)
-		if entry == "" {
-			continue
-		}
-		if origin == entry {
-			return true
-		}
+	if OriginAllowed(origin, allowedOrigins, ignorePort) {
+		return true
 	}
 
 	_ = c.Status(403).JSON(fiber.Map{
 		"error": "Origin not allowed",
-		"code":  "origin_not_allowed",
+		"code":  services.CodeOriginNotAllowed,
 	})
 	return false
 }
 
-func clearSessionCookieWith(c *fiber.Ctx, cookieSecureMode string) {
-	secure := ShouldUseSecureCookie(c, cookieSecureMode)
-	c.Cookie(&fiber.Cookie{
-		Name:     "aeterna_session",
-		Value:    "",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
+// SessionCookieName is SESSION_COOKIE_NAME, or the default for configs built
+// without the auth section, as in tests.
+func SessionCookieName(auth config.AuthConfig) string {
+	if auth.SessionCookieName != "" {
+		return auth.SessionCookieName
+	}
+	return common.DefaultSessionCookie
+}
+
+// hostCookiePrefix makes browsers refuse the cookie unless it is Secure,
+// host-only and scoped to path /, so a sibling subdomain cannot plant or
+// overwrite it.
+const hostCookiePrefix = "__Host-"
+
+// SessionCookie returns the session cookie's name and attributes for c,
+// without a value or expiry. With SESSION_COOKIE_HOST_PREFIX on, secure
+// requests use the __Host- prefixed name; plain HTTP ones, where browsers
+// would drop such a cookie, keep the bare name.
+func SessionCookie(c *fiber.Ctx, auth config.AuthConfig) *fiber.Cookie {
+	cookie := &fiber.Cookie{
+		Name:     SessionCookieName(auth),
 		Path:     "/",
+		Domain:   auth.SessionCookieDomain,
 		HTTPOnly: true,
-		Secure:   secure,
+		Secure:   ShouldUseSecureCookie(c, auth.CookieSecureMode),
 		SameSite: fiber.CookieSameSiteStrictMode,
-	})
+	}
+	if auth.SessionCookieHostPrefix && cookie.Secure && !strings.HasPrefix(cookie.Name, hostCookiePrefix) {
+		cookie.Name = hostCookiePrefix + cookie.Name
+		cookie.Domain = ""
+	}
+	return cookie
+}
+
+func clearSessionCookieWith(c *fiber.Ctx, auth config.AuthConfig) {
+	cookie := SessionCookie(c, auth)
+	cookie.Expires = time.Unix(0, 0)
+	cookie.MaxAge = -1
+	c.Cookie(cookie)
 }
 
 // ShouldUseSecureCookie returns true when the session cookie should be flagged Secure.
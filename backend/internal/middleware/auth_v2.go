@@ -8,10 +8,10 @@ import (
 
 // MasterAuthV2 accepts Bearer tokens for mobile clients and falls back to cookie auth.
 // Origin allowlist is enforced only for cookie-based browser sessions.
-func MasterAuthV2(auth ports.AuthServicePort, cfg config.Config) fiber.Handler {
-	allowedOrigins := cfg.AllowedOriginsOrDefault()
+func MasterAuthV2(auth ports.AuthServicePort, origins ports.OriginAllowlistPort, cfg config.Config) fiber.Handler {
 	isProd := cfg.IsProduction()
-	cookieSecureMode := cfg.Auth.CookieSecureMode
+	ignorePort := cfg.HTTP.AllowedOriginsIgnorePort
+	strict := !cfg.HTTP.AllowMissingOrigin
 
 	return func(c *fiber.Ctx) error {
 		if token, ok := ExtractBearerToken(c.Get("Authorization")); ok {
@@ -24,17 +24,17 @@ func MasterAuthV2(auth ports.AuthServicePort, cfg config.Config) fiber.Handler {
 			return c.Next()
 		}
 
-		if token := c.Cookies("aeterna_session"); token != "" {
+		if token := c.Cookies(SessionCookie(c, cfg.Auth).Name); token != "" {
 			userID, err := auth.VerifySessionToken(token)
 			if err == nil {
-				if !enforceOriginAllowlist(c, allowedOrigins, isProd) {
+				if !enforceOriginAllowlist(c, origins.AllowedOrigins(), ignorePort, isProd, strict) {
 					return nil
 				}
 				c.Locals(LocalUserIDKey, userID)
 				c.Locals(LocalSessionKey, auth.SessionKeyFromToken(token))
 				return c.Next()
 			}
-			clearSessionCookieWith(c, cookieSecureMode)
+			clearSessionCookieWith(c, cfg.Auth)
 		}
 
 		return unauthorizedResponse(c)
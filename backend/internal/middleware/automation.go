@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AutomationHeartbeat serves heartbeat for requests carrying an automation
+// token as their bearer token. Any other request falls through to the
+// session-protected route registered after it, so automation tokens never
+// reach the rest of the API. No session key is set, and the origin allowlist
+// does not apply since automation does not run in a browser.
+func AutomationHeartbeat(tokens ports.AutomationTokenServicePort, heartbeat fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := ExtractBearerToken(c.Get("Authorization"))
+		if !ok || !services.IsAutomationToken(token) {
+			return c.Next()
+		}
+		userID, err := tokens.Authenticate(token)
+		if err != nil {
+			return unauthorizedResponse(c)
+		}
+		c.Locals(LocalUserIDKey, userID)
+		return heartbeat(c)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeAutomationTokens struct {
+	valid map[string]string
+}
+
+func (f fakeAutomationTokens) List(string) ([]models.AutomationToken, error) { return nil, nil }
+func (f fakeAutomationTokens) Add(string, string) (string, models.AutomationToken, error) {
+	return "", models.AutomationToken{}, nil
+}
+func (f fakeAutomationTokens) Revoke(string, uint) (models.AutomationToken, error) {
+	return models.AutomationToken{}, nil
+}
+func (f fakeAutomationTokens) Authenticate(token string) (string, error) {
+	if userID, ok := f.valid[token]; ok {
+		return userID, nil
+	}
+	return "", services.NewAPIError(401, services.CodeUnauthorized, "Invalid automation token.", nil)
+}
+
+func TestAutomationHeartbeatOnlyHandlesAutomationTokens(t *testing.T) {
+	tokens := fakeAutomationTokens{valid: map[string]string{"aat_good": "u1"}}
+	app := fiber.New()
+	app.Post("/heartbeat", AutomationHeartbeat(tokens, func(c *fiber.Ctx) error {
+		return c.SendString("automation:" + c.Locals(LocalUserIDKey).(string))
+	}))
+	app.Post("/heartbeat", func(c *fiber.Ctx) error {
+		return c.SendString("session")
+	})
+
+	cases := []struct {
+		authorization string
+		status        int
+		body          string
+	}{
+		{"Bearer aat_good", http.StatusOK, "automation:u1"},
+		{"Bearer aat_revoked", http.StatusUnauthorized, ""},
+		{"Bearer session-token", http.StatusOK, "session"},
+		{"", http.StatusOK, "session"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/heartbeat", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Fatalf("%q: status = %d, want %d", tc.authorization, resp.StatusCode, tc.status)
+		}
+		if tc.body != "" && string(body) != tc.body {
+			t.Fatalf("%q: body = %q, want %q", tc.authorization, body, tc.body)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/netip"
+
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// IPAllowlist refuses requests whose client IP is outside allowed. The IP
+// is c.IP(), which only honours X-Real-IP from TRUSTED_PROXIES, so the
+// header cannot be forged to get past the list. An empty list allows
+// everyone.
+func IPAllowlist(allowed []netip.Prefix) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if len(allowed) == 0 {
+			return c.Next()
+		}
+		if addr, err := netip.ParseAddr(c.IP()); err == nil {
+			addr = addr.Unmap()
+			for _, prefix := range allowed {
+				if prefix.Contains(addr) {
+					return c.Next()
+				}
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access from this IP address is not allowed",
+			"code":  services.CodeIPNotAllowed,
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.8.0.0/24"), netip.MustParsePrefix("2001:db8::/32")}
+	for _, tc := range []struct {
+		name    string
+		proxies []string
+		realIP  string
+		want    int
+	}{
+		// app.Test connects from 0.0.0.0.
+		{name: "connecting address outside the list", want: http.StatusForbidden},
+		{name: "trusted proxy forwards an allowed client", proxies: []string{"0.0.0.0/32"}, realIP: "10.8.0.7", want: http.StatusOK},
+		{name: "trusted proxy forwards an IPv6 client", proxies: []string{"0.0.0.0/32"}, realIP: "2001:db8::1", want: http.StatusOK},
+		{name: "trusted proxy forwards another client", proxies: []string{"0.0.0.0/32"}, realIP: "203.0.113.9", want: http.StatusForbidden},
+		{name: "header from an untrusted peer is ignored", proxies: []string{"192.0.2.1/32"}, realIP: "10.8.0.7", want: http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{
+				EnableTrustedProxyCheck: true,
+				TrustedProxies:          tc.proxies,
+				ProxyHeader:             "X-Real-IP",
+				EnableIPValidation:      true,
+			})
+			app.Get("/api/messages", IPAllowlist(allowed), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/api/messages", nil)
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+
+	app := fiber.New()
+	app.Get("/", IPAllowlist(nil), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("an empty allowlist must allow everyone, got %d", resp.StatusCode)
+	}
+}
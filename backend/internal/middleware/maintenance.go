@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maintenanceSessionPaths change only session state, so signing in and out
+// keeps working and the dashboard stays readable during maintenance.
+var maintenanceSessionPaths = map[string]bool{
+	"/api/auth/login":      true,
+	"/api/auth/verify":     true,
+	"/api/auth/logout":     true,
+	"/api/v2/auth/login":   true,
+	"/api/v2/auth/refresh": true,
+	"/api/v2/auth/logout":  true,
+}
+
+// maintenanceCheckInPaths record a check-in. Maintenance must not stop an
+// owner from proving they are alive, or switches would come due as soon as
+// it ends.
+var maintenanceCheckInPaths = map[string]bool{
+	"/api/heartbeat":    true,
+	"/api/v2/heartbeat": true,
+}
+
+// Maintenance answers every state-changing request with 503 while
+// MAINTENANCE_MODE is on. Reads, including the reveal page, are served as
+// usual, and so are check-ins: the heartbeat routes, including automation
+// tokens, and the quick-heartbeat page and its confirm link.
+func Maintenance() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := strings.TrimSuffix(c.Path(), "/")
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		default:
+			if maintenanceSessionPaths[path] || maintenanceCheckInPaths[path] || isQuickHeartbeat(path) {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Aeterna is in maintenance mode; changes are disabled until it ends",
+			"code":  services.CodeMaintenanceMode,
+		})
+	}
+}
+
+// isQuickHeartbeat matches the quick-heartbeat page under /api or /api/v2.
+func isQuickHeartbeat(path string) bool {
+	path = strings.TrimPrefix(path, "/api/v2")
+	path = strings.TrimPrefix(path, "/api")
+	return strings.HasPrefix(path, "/quick-heartbeat/")
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMaintenanceRefusesWritesButCheckIns(t *testing.T) {
+	app := fiber.New()
+	app.Use(Maintenance())
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/api/messages/:id", ok)
+	app.Post("/api/messages", ok)
+	app.Delete("/api/messages/:id", ok)
+	app.Post("/api/auth/login", ok)
+	app.Get("/api/quick-heartbeat/:token", ok)
+	app.Post("/api/quick-heartbeat/:token", ok)
+	app.Get("/api/quick-heartbeat/:token/confirm", ok)
+	app.Post("/api/heartbeat", ok)
+	app.Post("/api/v2/heartbeat", ok)
+	app.Post("/api/v2/quick-heartbeat/:token", ok)
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/messages/m1", http.StatusOK},
+		{http.MethodPost, "/api/messages", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/messages/m1", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/auth/login", http.StatusOK},
+		{http.MethodGet, "/api/quick-heartbeat/tok", http.StatusOK},
+		// Check-ins keep working, so maintenance cannot make switches due.
+		{http.MethodPost, "/api/quick-heartbeat/tok", http.StatusOK},
+		{http.MethodGet, "/api/quick-heartbeat/tok/confirm", http.StatusOK},
+		{http.MethodPost, "/api/heartbeat", http.StatusOK},
+		{http.MethodPost, "/api/v2/heartbeat", http.StatusOK},
+		{http.MethodPost, "/api/v2/quick-heartbeat/tok", http.StatusOK},
+	} {
+		resp, err := app.Test(httptest.NewRequest(tc.method, tc.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Fatalf("%s %s: status = %d, want %d", tc.method, tc.path, resp.StatusCode, tc.want)
+		}
+		if tc.want == http.StatusServiceUnavailable && !strings.Contains(string(body), `"maintenance_mode"`) {
+			t.Fatalf("%s %s: expected the maintenance_mode code, got %s", tc.method, tc.path, body)
+		}
+	}
+}
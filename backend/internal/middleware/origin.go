@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OriginAllowed reports whether origin matches an entry of the comma-separated
+// allowlist. It is shared by the CORS middleware and the session origin check.
+//
+// Entries are scheme://host[:port]. A host of the form "*.example.com" matches
+// any subdomain of example.com (but not example.com itself). With ignorePort
+// set, ports are not compared; otherwise an omitted port means the scheme's
+// default.
+func OriginAllowed(origin, allowedOrigins string, ignorePort bool) bool {
+	if allowedOrigins == "*" {
+		return true
+	}
+	parsed, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return false
+	}
+	for _, entry := range strings.Split(allowedOrigins, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if originMatches(parsed, entry, ignorePort) {
+			return true
+		}
+	}
+	return false
+}
+
+func originMatches(origin *url.URL, entry string, ignorePort bool) bool {
+	rule, err := url.Parse(strings.TrimRight(entry, "/"))
+	if err != nil || rule.Host == "" {
+		return false
+	}
+	if !strings.EqualFold(origin.Scheme, rule.Scheme) {
+		return false
+	}
+	if !ignorePort && originPort(origin) != originPort(rule) {
+		return false
+	}
+
+	host := strings.ToLower(origin.Hostname())
+	pattern := strings.ToLower(rule.Hostname())
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		// Only a whole leading label may be wildcarded, and the match must
+		// end on a label boundary so "*.example.com" rejects
+		// "evil-example.com".
+		if !strings.HasPrefix(suffix, ".") || strings.Contains(suffix, "*") {
+			return false
+		}
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
+
+func originPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return "443"
+	case "http":
+		return "80"
+	}
+	return ""
+}
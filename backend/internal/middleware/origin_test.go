@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	const list = "https://app.example.com, https://*.example.org, http://localhost:5173"
+	cases := []struct {
+		origin     string
+		ignorePort bool
+		want       bool
+	}{
+		{"https://app.example.com", false, true},
+		{"https://app.example.com:443", false, true},
+		{"https://APP.example.com", false, true},
+		{"http://app.example.com", false, false},
+		{"https://app.example.com:8443", false, false},
+		{"https://app.example.com:8443", true, true},
+		{"https://a.example.org", false, true},
+		{"https://a.b.example.org", false, true},
+		{"https://example.org", false, false},
+		{"https://evil-example.org", false, false},
+		{"https://example.org.evil.com", false, false},
+		{"http://a.example.org", false, false},
+		{"http://localhost:5173", false, true},
+		{"http://localhost:3000", false, false},
+		{"http://localhost:3000", true, true},
+		{"not an origin", false, false},
+	}
+	for _, tc := range cases {
+		if got := OriginAllowed(tc.origin, list, tc.ignorePort); got != tc.want {
+			t.Errorf("OriginAllowed(%q, ignorePort=%v) = %v, want %v", tc.origin, tc.ignorePort, got, tc.want)
+		}
+	}
+}
+
+func TestOriginAllowed_RejectsMalformedWildcards(t *testing.T) {
+	for _, entry := range []string{"https://*example.com", "https://a.*.example.com", "https://*"} {
+		if OriginAllowed("https://a.example.com", entry, false) {
+			t.Errorf("entry %q must not match", entry)
+		}
+	}
+}
+
+func TestEnforceOriginAllowlist_MissingOriginFollowsStrictOrigin(t *testing.T) {
+	status := func(strict bool, origin string) int {
+		t.Helper()
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			if !enforceOriginAllowlist(c, "https://app.example.com", false, true, strict) {
+				return nil
+			}
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := status(true, ""); got != http.StatusForbidden {
+		t.Fatalf("strict: missing origin got %d, want 403", got)
+	}
+	if got := status(false, ""); got != http.StatusNoContent {
+		t.Fatalf("lenient: missing origin got %d, want 204", got)
+	}
+	if got := status(false, "https://evil.example.com"); got != http.StatusForbidden {
+		t.Fatalf("lenient: a foreign origin got %d, want 403", got)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alpyxn/aeterna/backend/internal/services"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
 type loginAttempt struct {
@@ -49,7 +51,7 @@ func AuthRateLimiter(c *fiber.Ctx) error {
 		remaining := attempt.LockedUntil.Sub(now).Seconds()
 		return c.Status(429).JSON(fiber.Map{
 			"error":            "Too many failed login attempts. Please try again later.",
-			"code":             "rate_limited",
+			"code":             services.CodeRateLimited,
 			"retry_after_secs": int(remaining),
 		})
 	}
@@ -63,8 +65,9 @@ func AuthRateLimiter(c *fiber.Ctx) error {
 	return c.Next()
 }
 
-// RecordFailedLogin should be called after a failed login attempt
-func RecordFailedLogin(ip string) {
+// RecordFailedLogin should be called after a failed login attempt. It returns
+// the attempt count and whether this failure locked the IP out.
+func RecordFailedLogin(ip string) (attempts int, locked bool) {
 	loginMutex.Lock()
 	defer loginMutex.Unlock()
 
@@ -76,7 +79,7 @@ func RecordFailedLogin(ip string) {
 			Count:   1,
 			LastTry: now,
 		}
-		return
+		return 1, false
 	}
 
 	attempt.Count++
@@ -90,7 +93,9 @@ func RecordFailedLogin(ip string) {
 			lockDuration = MaxLockDuration
 		}
 		attempt.LockedUntil = now.Add(lockDuration)
+		return attempt.Count, true
 	}
+	return attempt.Count, false
 }
 
 // RecordSuccessfulLogin should be called after a successful login
@@ -125,3 +130,22 @@ func init() {
 		}
 	}()
 }
+
+// RateLimitReached answers a request rejected by a limiter.
+func RateLimitReached(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": "Too many requests",
+		"code":  services.CodeRateLimited,
+	})
+}
+
+// RouteRateLimiter allows max requests per IP per minute. Every call gets its
+// own counters, so a route group throttled this way is limited independently
+// of the global limiter and of other groups.
+func RouteRateLimiter(max int) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:          max,
+		Expiration:   1 * time.Minute,
+		LimitReached: RateLimitReached,
+	})
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRouteRateLimiterCountsEachGroupIndependently(t *testing.T) {
+	reveal := RouteRateLimiter(2)
+	setup := RouteRateLimiter(1)
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+
+	app := fiber.New()
+	app.Get("/api/messages/:id", reveal, ok)
+	app.Get("/api/v2/messages/:id", reveal, ok)
+	app.Post("/api/setup", setup, ok)
+
+	status := func(method, path string) int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		if err != nil {
+			t.Fatalf("unexpected request error: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if got := status(http.MethodPost, "/api/setup"); got != http.StatusOK {
+		t.Fatalf("first setup status = %d", got)
+	}
+	if got := status(http.MethodPost, "/api/setup"); got != http.StatusTooManyRequests {
+		t.Fatalf("second setup status = %d, want 429", got)
+	}
+	if got := status(http.MethodGet, "/api/messages/a"); got != http.StatusOK {
+		t.Fatalf("reveal must not share the setup counter, status = %d", got)
+	}
+	if got := status(http.MethodGet, "/api/v2/messages/b"); got != http.StatusOK {
+		t.Fatalf("second reveal status = %d", got)
+	}
+	if got := status(http.MethodGet, "/api/messages/c"); got != http.StatusTooManyRequests {
+		t.Fatalf("v1 and v2 reveal should share one counter, status = %d", got)
+	}
+}
+
+func TestRecordFailedLoginReportsLockout(t *testing.T) {
+	const ip = "203.0.113.9"
+	t.Cleanup(func() { RecordSuccessfulLogin(ip) })
+
+	for i := 1; i < MaxLoginAttempts; i++ {
+		if attempts, locked := RecordFailedLogin(ip); locked || attempts != i {
+			t.Fatalf("attempt %d: got (%d, %v), want (%d, false)", i, attempts, locked, i)
+		}
+	}
+	if attempts, locked := RecordFailedLogin(ip); !locked || attempts != MaxLoginAttempts {
+		t.Fatalf("expected lockout at attempt %d, got (%d, %v)", MaxLoginAttempts, attempts, locked)
+	}
+}
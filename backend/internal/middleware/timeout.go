@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestTimeout puts a deadline of timeout on each request's user context.
+// Fiber cannot preempt a handler, so only work that honours c.UserContext()
+// is cancelled; when such a handler fails after the deadline its response is
+// replaced with a 504.
+func RequestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+		if err == nil && c.Response().StatusCode() < fiber.StatusBadRequest {
+			return nil
+		}
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+			"error": "Request timed out",
+			"code":  services.CodeRequestTimeout,
+		})
+	}
+}
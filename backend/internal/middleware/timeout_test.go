@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequestTimeoutCancelsSlowHandlers(t *testing.T) {
+	app := fiber.New()
+	app.Use(RequestTimeout(50 * time.Millisecond))
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to connect"})
+	})
+	app.Get("/fast", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/late", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return errors.New("dial tcp: i/o timeout")
+	})
+
+	for path, want := range map[string]int{"/slow": http.StatusGatewayTimeout, "/late": http.StatusGatewayTimeout, "/fast": http.StatusOK} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 2000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s: status = %d, want %d", path, resp.StatusCode, want)
+		}
+		if want == http.StatusGatewayTimeout && !strings.Contains(string(body), `"request_timeout"`) {
+			t.Fatalf("%s: body = %s, want the request_timeout code", path, body)
+		}
+	}
+}
@@ -1,7 +1,17 @@
 package models
 
+import "time"
+
 // ApplicationSettings holds global singleton configuration (single row, id = 1).
 type ApplicationSettings struct {
 	ID                uint `gorm:"primaryKey"`
 	AllowRegistration bool `gorm:"column:allow_registration;default:0" json:"allow_registration"`
+	// WorkerLastTickAt is written after every worker tick so a restart can
+	// tell how long the process was down.
+	WorkerLastTickAt *time.Time `gorm:"column:worker_last_tick_at" json:"-"`
+	// AllowedOrigins overrides ALLOWED_ORIGINS when non-empty.
+	AllowedOrigins string `gorm:"column:allowed_origins;not null;default:''" json:"allowed_origins"`
+	// WebhookAllowlistHosts narrows WEBHOOK_ALLOWLIST_HOSTS; it can never
+	// permit a host the environment baseline rejects.
+	WebhookAllowlistHosts string `gorm:"column:webhook_allowlist_hosts;not null;default:''" json:"webhook_allowlist_hosts"`
 }
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,40 @@ type Attachment struct {
 	MimeType    string         `gorm:"not null" json:"mime_type"`
 	CreatedAt   time.Time      `json:"created_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// RecipientEmail limits delivery to a comma-separated subset of the
+	// message's recipients; empty means every recipient receives the file.
+	RecipientEmail string `gorm:"not null;default:'';serializer:recipients" json:"recipient_email"`
+	// ThumbnailPath points at an encrypted JPEG preview for image uploads.
+	ThumbnailPath string `gorm:"not null;default:''" json:"-"`
+	HasThumbnail  bool   `gorm:"-" json:"has_thumbnail"`
+	// ContentHash is a keyed hash of the plaintext; attachments with the same
+	// hash share one encrypted blob on disk.
+	ContentHash string `gorm:"index;not null;default:''" json:"-"`
+}
+
+// DeliversTo reports whether the attachment should be sent to recipient.
+func (a Attachment) DeliversTo(recipient string) bool {
+	if strings.TrimSpace(a.RecipientEmail) == "" {
+		return true
+	}
+	for _, selected := range strings.Split(a.RecipientEmail, ",") {
+		if strings.EqualFold(strings.TrimSpace(selected), strings.TrimSpace(recipient)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AfterFind derives HasThumbnail for API responses and decrypts
+// RecipientEmail.
+func (a *Attachment) AfterFind(tx *gorm.DB) error {
+	a.HasThumbnail = a.ThumbnailPath != ""
+	return openRecipients(a, &a.RecipientEmail)
+}
+
+func (a Attachment) recipientRow() (string, string) {
+	return "attachments", a.ID
 }
 
 // BeforeCreate hook to generate UUID before creating
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AutomationToken is a long-lived bearer token that only permits heartbeats,
+// for cron jobs and uptime monitors. Only a hash of the token is stored.
+type AutomationToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     string     `gorm:"type:text;index;not null" json:"-"`
+	Label      string     `gorm:"not null" json:"label"`
+	TokenHash  string     `gorm:"type:text;uniqueIndex;not null" json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DeliveredAttachment records that a file was attached to a triggered message.
+// It is kept after the encrypted file itself is deleted, so the reveal page
+// can still tell recipients that files were included. Restricted is set for
+// files sent only to some of the recipients; their names are not shown.
+type DeliveredAttachment struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	MessageID  string    `gorm:"type:text;index;not null" json:"-"`
+	UserID     string    `gorm:"type:text;index;not null" json:"-"`
+	Filename   string    `gorm:"not null" json:"filename"`
+	Size       int64     `gorm:"not null" json:"size"`
+	Restricted bool      `gorm:"not null;default:false" json:"-"`
+	CreatedAt  time.Time `json:"-"`
+}
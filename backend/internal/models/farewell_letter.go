@@ -18,7 +18,7 @@ type FarewellLetter struct {
 	ID                 string               `gorm:"type:text;primaryKey" json:"id"`
 	UserID             string               `gorm:"type:text;index" json:"-"`
 	MessageID          string               `gorm:"type:text;not null;index" json:"message_id"`
-	RecipientEmail     string               `gorm:"not null" json:"recipient_email"`
+	RecipientEmail     string               `gorm:"not null;serializer:recipients" json:"recipient_email"`
 	Subject            string               `gorm:"not null" json:"subject"`
 	Content            string               `gorm:"column:encrypted_content;not null" json:"content"`
 	RawContent         string               `gorm:"column:encrypted_content_raw;not null;default:''" json:"-"`
@@ -34,6 +34,15 @@ type FarewellLetter struct {
 	DeletedAt          gorm.DeletedAt       `gorm:"index" json:"-"`
 }
 
+// AfterFind decrypts RecipientEmail.
+func (f *FarewellLetter) AfterFind(tx *gorm.DB) error {
+	return openRecipients(f, &f.RecipientEmail)
+}
+
+func (f FarewellLetter) recipientRow() (string, string) {
+	return "farewell_letters", f.ID
+}
+
 func (f *FarewellLetter) BeforeCreate(tx *gorm.DB) error {
 	if f.ID == "" {
 		f.ID = uuid.NewString()
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,21 +13,39 @@ type MessageStatus string
 const (
 	StatusActive    MessageStatus = "active"
 	StatusTriggered MessageStatus = "triggered"
+	// StatusError parks a switch that came due with no way to deliver it
+	// (UNDELIVERABLE_ACTION=error). A heartbeat makes it active again.
+	StatusError MessageStatus = "error"
+)
+
+// RiskLevel flags how close an active switch is to firing.
+type RiskLevel string
+
+const (
+	RiskLevelOK       RiskLevel = "ok"
+	RiskLevelWarning  RiskLevel = "warning"
+	RiskLevelCritical RiskLevel = "critical"
 )
 
 type Message struct {
-	ID               string            `gorm:"type:text;primaryKey" json:"id"`
-	UserID           string            `gorm:"type:text;index" json:"-"`
-	Content          string            `gorm:"column:encrypted_content;not null" json:"content"`
-	KeyFragment      string            `gorm:"column:key_fragment;not null" json:"-"`
-	ManagementToken  string            `gorm:"column:management_token;not null" json:"-"`
-	RecipientEmail   string            `gorm:"not null" json:"recipient_email"`
+	ID              string `gorm:"type:text;primaryKey" json:"id"`
+	UserID          string `gorm:"type:text;index" json:"-"`
+	Content         string `gorm:"column:encrypted_content;not null" json:"content"`
+	KeyFragment     string `gorm:"column:key_fragment;not null" json:"-"`
+	ManagementToken string `gorm:"column:management_token;not null;index" json:"management_token"`
+	RecipientEmail  string `gorm:"not null;serializer:recipients" json:"recipient_email"`
+	// RecipientIndex holds the keyed blind index of each recipient as
+	// ",<hash>,<hash>,", so a recipient can be matched in SQL while
+	// recipient_email is stored encrypted.
+	RecipientIndex   string            `gorm:"not null;default:''" json:"-"`
+	Subject          string            `json:"subject"`
 	TriggerDuration  int               `gorm:"not null" json:"trigger_duration"`
 	LastSeen         time.Time         `gorm:"not null;default:CURRENT_TIMESTAMP" json:"last_seen"`
 	Status           MessageStatus     `gorm:"default:'active'" json:"status"`
 	TriggeredAt      *time.Time        `json:"triggered_at,omitempty"`
 	NextTriggerAt    *time.Time        `gorm:"-" json:"next_trigger_at,omitempty"`
 	NextReminderAt   *time.Time        `gorm:"-" json:"next_reminder_at,omitempty"`
+	RiskLevel        RiskLevel         `gorm:"-" json:"risk_level,omitempty"`
 	Reminders        []MessageReminder `gorm:"foreignKey:MessageID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"reminders"`
 	CreatedAt        time.Time         `json:"created_at"`
 	UpdatedAt        time.Time         `json:"updated_at"`
@@ -34,6 +53,148 @@ type Message struct {
 	AttachmentCount  int64             `gorm:"-" json:"attachment_count"`
 	FarewellCount    int64             `gorm:"-" json:"farewell_count"`
 	PendingFarewells int64             `gorm:"-" json:"pending_farewells"`
+
+	// RequiredMissedIntervals is how many consecutive TriggerDuration
+	// intervals must pass without a heartbeat before the switch fires.
+	RequiredMissedIntervals int `gorm:"not null;default:1" json:"required_missed_intervals"`
+	// MissedIntervals counts the intervals missed so far; any heartbeat
+	// resets it.
+	MissedIntervals int `gorm:"not null;default:0" json:"missed_intervals"`
+
+	DeliveryWindow DeliveryWindow `gorm:"embedded;embeddedPrefix:delivery_window_" json:"delivery_window"`
+	// DeliveryHeldUntil is set while a switch that has fired waits for its
+	// delivery window to open; any heartbeat clears it and cancels delivery.
+	DeliveryHeldUntil *time.Time `gorm:"column:delivery_held_until" json:"delivery_held_until,omitempty"`
+	// ExtendedUntil is the deadline set by ExtendDeadline. It is kept apart
+	// from LastSeen, which stays the time of the last real check-in; the
+	// switch fires at the later of the two deadlines.
+	ExtendedUntil *time.Time `gorm:"column:extended_until" json:"extended_until,omitempty"`
+	// ShreddedAt is set once SHRED_AFTER_DELIVERY has overwritten the content
+	// of a delivered switch; only its metadata remains.
+	ShreddedAt *time.Time `gorm:"column:shredded_at" json:"shredded_at,omitempty"`
+	// RevealAttachments lists the retained files any recipient may download
+	// from the reveal link; set only on triggered messages by GetPublicByID.
+	RevealAttachments []Attachment `gorm:"-" json:"-"`
+	// IncludedAttachments lists every file delivered with the message, kept
+	// after the files are deleted; set only on triggered messages by
+	// GetPublicByID.
+	IncludedAttachments []DeliveredAttachment `gorm:"-" json:"-"`
+}
+
+// DeliveryWindow restricts delivery to the hours [StartHour, EndHour) in
+// Timezone, or in the owner's time zone when empty. A window wraps past
+// midnight when StartHour is after EndHour; equal hours, the zero value,
+// mean no restriction.
+type DeliveryWindow struct {
+	StartHour int    `gorm:"not null;default:0" json:"start_hour"`
+	EndHour   int    `gorm:"not null;default:0" json:"end_hour"`
+	Timezone  string `json:"timezone"`
+}
+
+// Enabled reports whether the window restricts delivery at all.
+func (w DeliveryWindow) Enabled() bool {
+	return w.StartHour != w.EndHour
+}
+
+// Normalized trims the time zone and clears a window that does not restrict
+// delivery.
+func (w DeliveryWindow) Normalized() DeliveryWindow {
+	if !w.Enabled() {
+		return DeliveryWindow{}
+	}
+	w.Timezone = strings.TrimSpace(w.Timezone)
+	return w
+}
+
+// Location returns the window's time zone, or fallback when it has none.
+func (w DeliveryWindow) Location(fallback *time.Location) *time.Location {
+	if w.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// NextOpen returns now when the window is open (or disabled), otherwise the
+// next time it opens. fallback is used when the window has no time zone.
+func (w DeliveryWindow) NextOpen(now time.Time, fallback *time.Location) time.Time {
+	if !w.Enabled() {
+		return now
+	}
+	local := now.In(w.Location(fallback))
+	hour := local.Hour()
+	if w.StartHour < w.EndHour {
+		if hour >= w.StartHour && hour < w.EndHour {
+			return now
+		}
+	} else if hour >= w.StartHour || hour < w.EndHour {
+		return now
+	}
+	open := time.Date(local.Year(), local.Month(), local.Day(), w.StartHour, 0, 0, 0, local.Location())
+	if !open.After(local) {
+		open = time.Date(local.Year(), local.Month(), local.Day()+1, w.StartHour, 0, 0, 0, local.Location())
+	}
+	return open
+}
+
+// TriggerAt returns when the switch fires if no heartbeat arrives first.
+func (m Message) TriggerAt() time.Time {
+	at := m.LastSeen.Add(m.triggerPeriod())
+	if m.ExtendedUntil != nil && m.ExtendedUntil.After(at) {
+		return *m.ExtendedUntil
+	}
+	return at
+}
+
+// FirstTriggerAt is the earliest a new switch may trigger: its full trigger
+// period plus grace after creation, independent of how LastSeen compares
+// once stored and rounded.
+func (m Message) FirstTriggerAt(grace time.Duration) time.Time {
+	return m.CreatedAt.Add(m.triggerPeriod() + grace)
+}
+
+func (m Message) triggerPeriod() time.Duration {
+	intervals := m.RequiredMissedIntervals
+	if intervals < 1 {
+		intervals = 1
+	}
+	return time.Duration(m.TriggerDuration*intervals) * time.Minute
+}
+
+// MissedIntervalsAt returns how many whole trigger intervals have elapsed
+// at now since the last heartbeat, or since an extended deadline restarted
+// the count.
+func (m Message) MissedIntervalsAt(now time.Time) int {
+	since := m.TriggerAt().Add(-m.triggerPeriod())
+	if m.TriggerDuration < 1 || now.Before(since) {
+		return 0
+	}
+	return int(now.Sub(since) / (time.Duration(m.TriggerDuration) * time.Minute))
+}
+
+// BeforeSave refreshes RecipientIndex from RecipientEmail.
+func (m *Message) BeforeSave(tx *gorm.DB) error {
+	if recipientCipher == nil || m.RecipientEmail == "" {
+		return nil
+	}
+	index, err := recipientCipher.Index(m.RecipientEmail)
+	if err != nil {
+		return err
+	}
+	m.RecipientIndex = index
+	return nil
+}
+
+// AfterFind decrypts RecipientEmail.
+func (m *Message) AfterFind(tx *gorm.DB) error {
+	return openRecipients(m, &m.RecipientEmail)
+}
+
+func (m Message) recipientRow() (string, string) {
+	return "messages", m.ID
 }
 
 // BeforeCreate hook to generate UUID before creating
@@ -48,7 +209,8 @@ func (m *Message) BeforeCreate(tx *gorm.DB) error {
 }
 
 // BeforeDelete cascades the delete to associated FarewellLetters and their attachments,
-// mirroring the soft/hard mode of the parent operation.
+// and to the message's StatusTransitions and DeliveredAttachments unless a MessageTombstone
+// keeps them, mirroring the soft/hard mode of the parent operation.
 //
 // Each query opens a fresh session so chain conditions (Where, Select, Model) don't
 // leak between operations on the same underlying gorm.DB.
@@ -65,6 +227,19 @@ func (m *Message) BeforeDelete(tx *gorm.DB) error {
 		return s
 	}
 
+	var tombstones int64
+	if err := newSession().Model(&MessageTombstone{}).Where("message_id = ?", m.ID).Count(&tombstones).Error; err != nil {
+		return err
+	}
+	if tombstones == 0 {
+		if err := newSession().Where("message_id = ?", m.ID).Delete(&StatusTransition{}).Error; err != nil {
+			return err
+		}
+		if err := newSession().Where("message_id = ?", m.ID).Delete(&DeliveredAttachment{}).Error; err != nil {
+			return err
+		}
+	}
+
 	var letterIDs []string
 	if err := newSession().Model(&FarewellLetter{}).Select("id").Where("message_id = ?", m.ID).Find(&letterIDs).Error; err != nil {
 		return err
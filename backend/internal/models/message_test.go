@@ -1,6 +1,9 @@
 package models
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestMessageBeforeCreateGeneratesValues(t *testing.T) {
 	msg := &Message{}
@@ -27,3 +30,103 @@ func TestMessageBeforeCreatePreservesExistingValues(t *testing.T) {
 		t.Fatalf("expected existing management token to be preserved")
 	}
 }
+
+func TestMessageTriggerAtHonoursRequiredMissedIntervals(t *testing.T) {
+	lastSeen := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := Message{LastSeen: lastSeen, TriggerDuration: 60}
+	if got := msg.TriggerAt(); !got.Equal(lastSeen.Add(time.Hour)) {
+		t.Fatalf("expected default of one interval, got %v", got)
+	}
+	msg.RequiredMissedIntervals = 3
+	if got := msg.TriggerAt(); !got.Equal(lastSeen.Add(3 * time.Hour)) {
+		t.Fatalf("expected three intervals, got %v", got)
+	}
+}
+
+func TestMessageFirstTriggerAtHoldsFreshSwitches(t *testing.T) {
+	// Sub-second creation time: SQLite's datetime() compares whole seconds,
+	// so the worker query can select the row up to a second early.
+	created := time.Date(2026, 1, 1, 12, 0, 0, 900_000_000, time.UTC)
+	msg := Message{CreatedAt: created, LastSeen: created, TriggerDuration: 1}
+	if got := msg.FirstTriggerAt(0); !got.Equal(created.Add(time.Minute)) {
+		t.Fatalf("expected the first trigger one full duration after creation, got %v", got)
+	}
+	grace := time.Minute
+	if got, want := msg.FirstTriggerAt(grace), created.Add(2*time.Minute); !got.Equal(want) {
+		t.Fatalf("expected grace to extend the first trigger to %v, got %v", want, got)
+	}
+	// A heartbeat seconds after creation moves TriggerAt but the grace still
+	// applies to the first period.
+	msg.LastSeen = created.Add(5 * time.Second)
+	if !msg.FirstTriggerAt(grace).After(msg.TriggerAt()) {
+		t.Fatalf("expected grace to outlast the first trigger period")
+	}
+	// Long after creation the grace no longer matters.
+	msg.LastSeen = created.Add(24 * time.Hour)
+	if msg.FirstTriggerAt(grace).After(msg.TriggerAt()) {
+		t.Fatalf("expected grace to apply only around creation")
+	}
+}
+
+func TestMessageMissedIntervalsAt(t *testing.T) {
+	lastSeen := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := Message{LastSeen: lastSeen, TriggerDuration: 60, RequiredMissedIntervals: 3}
+	cases := map[time.Duration]int{
+		-time.Minute:      0,
+		59 * time.Minute:  0,
+		time.Hour:         1,
+		150 * time.Minute: 2,
+	}
+	for offset, want := range cases {
+		if got := msg.MissedIntervalsAt(lastSeen.Add(offset)); got != want {
+			t.Fatalf("offset %v: expected %d missed intervals, got %d", offset, want, got)
+		}
+	}
+}
+
+func TestDeliveryWindowNextOpen(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC) }
+	business := DeliveryWindow{StartHour: 9, EndHour: 17}
+	overnight := DeliveryWindow{StartHour: 22, EndHour: 6}
+	cases := []struct {
+		name   string
+		window DeliveryWindow
+		now    time.Time
+		want   time.Time
+	}{
+		{"disabled", DeliveryWindow{}, at(3, 0), at(3, 0)},
+		{"inside", business, at(12, 30), at(12, 30)},
+		{"before opening", business, at(3, 15), at(9, 0)},
+		{"after closing", business, at(17, 0), at(9, 0).AddDate(0, 0, 1)},
+		{"overnight late", overnight, at(23, 0), at(23, 0)},
+		{"overnight early", overnight, at(5, 59), at(5, 59)},
+		{"overnight closed", overnight, at(6, 0), at(22, 0)},
+	}
+	for _, tc := range cases {
+		if got := tc.window.NextOpen(tc.now, time.UTC); !got.Equal(tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestDeliveryWindowNextOpenUsesTimezone(t *testing.T) {
+	window := DeliveryWindow{StartHour: 9, EndHour: 17, Timezone: "America/New_York"}
+	// 12:00 UTC is 07:00 in New York (EST), two hours before the window opens.
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	if got, want := window.NextOpen(now, time.UTC), time.Date(2026, 1, 15, 14, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDeliveryWindowNextOpenFallsBackToOwnerZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	window := DeliveryWindow{StartHour: 9, EndHour: 17}
+	// 01:00 UTC is 10:00 in Tokyo, inside the window there but not in UTC.
+	now := time.Date(2026, 1, 15, 1, 0, 0, 0, time.UTC)
+	if got := window.NextOpen(now, tokyo); !got.Equal(now) {
+		t.Fatalf("expected the window to be open in the owner's zone, got %v", got)
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MessageTombstone is what remains of a triggered message once it is
+// deleted: enough to show that, when and to whom it was delivered, without
+// its content or attachments. The message's StatusTransitions and
+// DeliveredAttachments are kept while its tombstone exists.
+type MessageTombstone struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	MessageID      string     `gorm:"type:text;index;not null" json:"message_id"`
+	UserID         string     `gorm:"type:text;index;not null" json:"-"`
+	RecipientEmail string     `gorm:"not null;serializer:recipients" json:"recipient_email"`
+	Subject        string     `json:"subject"`
+	CreatedAt      time.Time  `json:"created_at"`
+	TriggeredAt    *time.Time `json:"triggered_at,omitempty"`
+	DeletedAt      time.Time  `gorm:"column:deleted_at;not null" json:"deleted_at"`
+
+	Transitions          []StatusTransition    `gorm:"-" json:"transitions"`
+	DeliveredAttachments []DeliveredAttachment `gorm:"-" json:"delivered_attachments"`
+}
+
+// AfterFind decrypts RecipientEmail.
+func (t *MessageTombstone) AfterFind(tx *gorm.DB) error {
+	return openRecipients(t, &t.RecipientEmail)
+}
+
+// recipientRow binds the recipients to the deleted message, since the
+// tombstone's own ID is only assigned on insert.
+func (t MessageTombstone) recipientRow() (string, string) {
+	return "message_tombstones", t.MessageID
+}
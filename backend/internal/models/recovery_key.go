@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RecoveryKey is an additional recovery key, such as one given to a trusted
+// contact. The account's own key stays in Settings.RecoveryKeyHash; these are
+// listed by label only and can be revoked one by one.
+type RecoveryKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     string     `gorm:"type:text;index;not null" json:"-"`
+	Label      string     `gorm:"not null" json:"label"`
+	KeyHash    string     `gorm:"not null" json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
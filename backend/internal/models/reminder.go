@@ -1,5 +1,20 @@
 package models
 
+import "time"
+
+// Reminder delivery channels.
+const (
+	ReminderChannelEmail   = "email"
+	ReminderChannelNtfy    = "ntfy"
+	ReminderChannelWebhook = "webhook"
+)
+
+// Reminder anchors.
+const (
+	ReminderRelativeToTrigger  = "trigger"
+	ReminderRelativeToCreation = "creation"
+)
+
 // MessageReminder defines a scheduled reminder for a specific Message
 type MessageReminder struct {
 	ID        uint   `gorm:"primaryKey" json:"id"`
@@ -8,4 +23,27 @@ type MessageReminder struct {
 	// How many minutes before triggering the switch to send this reminder
 	MinutesBefore int  `gorm:"not null" json:"minutes_before"`
 	Sent          bool `gorm:"default:0" json:"sent"`
+
+	// RelativeTo is "trigger" for a check-in nudge MinutesBefore ahead of
+	// the deadline, or "creation" for a one-off confirmation MinutesAfter the
+	// switch was created. Creation reminders are not reset by heartbeats.
+	RelativeTo   string `gorm:"not null;default:'trigger'" json:"relative_to"`
+	MinutesAfter int    `gorm:"not null;default:0" json:"minutes_after"`
+
+	// Channel selects how the reminder reaches the owner.
+	Channel string `gorm:"not null;default:'email'" json:"channel"`
+	// Escalation marks rows generated from Settings.ReminderEscalation rather
+	// than chosen on the message; the worker keeps them in sync.
+	Escalation bool `gorm:"default:0" json:"escalation"`
+
+	// LastReminderAt is when the reminder last went out. With
+	// REMINDER_RESEND_INTERVAL_HOURS set, a sent reminder is sent again once
+	// that long has passed, until the owner checks in or the switch triggers.
+	LastReminderAt *time.Time `json:"last_reminder_at,omitempty"`
+}
+
+// FromCreation reports whether the reminder is anchored to the switch's
+// creation rather than its trigger.
+func (r MessageReminder) FromCreation() bool {
+	return r.RelativeTo == ReminderRelativeToCreation
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Settings is per-tenant configuration (one row per user).
 type Settings struct {
 	ID                 uint   `gorm:"primaryKey"`
@@ -17,6 +19,69 @@ type Settings struct {
 	WebhookEnabled     bool   `gorm:"column:webhook_enabled;default:0" json:"webhook_enabled"`
 	OwnerEmail         string `gorm:"column:owner_email" json:"owner_email"`
 	HeartbeatToken     string `gorm:"column:heartbeat_token" json:"-"`
+
+	// IncludeContentInOwnerNotification echoes the delivered message body in
+	// the owner's "Message delivered" email. Off by default for privacy.
+	IncludeContentInOwnerNotification bool `gorm:"column:include_content_in_owner_notification;default:0" json:"include_content_in_owner_notification"`
+	// DeliverAsPDF attaches delivered messages as a formatted PDF letter,
+	// with a short note as the email body.
+	DeliverAsPDF bool `gorm:"column:deliver_as_pdf;default:0" json:"deliver_as_pdf"`
+	// NtfyURL is the full ntfy topic URL (e.g. https://ntfy.sh/my-topic)
+	// used for push reminders.
+	NtfyURL string `gorm:"column:ntfy_url" json:"ntfy_url"`
+	// ReminderEscalation lists extra reminders applied to every active
+	// message as "channel:minutes_before" pairs, e.g. "email:1440,ntfy:60,webhook:15".
+	ReminderEscalation string `gorm:"column:reminder_escalation" json:"reminder_escalation"`
+	// BrandName and BrandColor customise the quick-heartbeat pages; empty
+	// values keep the default Aeterna look.
+	BrandName  string `gorm:"column:brand_name" json:"brand_name"`
+	BrandColor string `gorm:"column:brand_color" json:"brand_color"`
+	// HeartbeatTokenRotationDays regenerates HeartbeatToken on a schedule;
+	// 0 keeps it until rotated by hand.
+	HeartbeatTokenRotationDays int        `gorm:"column:heartbeat_token_rotation_days;default:0" json:"heartbeat_token_rotation_days"`
+	HeartbeatTokenRotatedAt    *time.Time `gorm:"column:heartbeat_token_rotated_at" json:"heartbeat_token_rotated_at,omitempty"`
+	// EmailCheckInEnabled lets the owner check in by replying to a reminder
+	// email; the worker polls IMAPHost with the SMTP credentials.
+	EmailCheckInEnabled bool   `gorm:"column:email_check_in_enabled;default:0" json:"email_check_in_enabled"`
+	IMAPHost            string `gorm:"column:imap_host" json:"imap_host"`
+	IMAPPort            string `gorm:"column:imap_port" json:"imap_port"`
+	// OwnerName identifies the owner to recipients of delivered messages;
+	// OwnerSignature, when set, replaces the default "— OwnerName" sign-off.
+	OwnerName      string `gorm:"column:owner_name" json:"owner_name"`
+	OwnerSignature string `gorm:"column:owner_signature" json:"owner_signature"`
+	// FooterText closes every email; nil keeps the default "Sent by Aeterna"
+	// and an empty string removes the footer.
+	FooterText *string `gorm:"column:footer_text" json:"footer_text"`
+	// Contact* describe a person recipients can reach (e.g. an executor);
+	// when ContactName is set, delivered messages carry them as a .vcf card.
+	ContactName         string `gorm:"column:contact_name" json:"contact_name"`
+	ContactPhone        string `gorm:"column:contact_phone" json:"contact_phone"`
+	ContactEmail        string `gorm:"column:contact_email" json:"contact_email"`
+	ContactRelationship string `gorm:"column:contact_relationship" json:"contact_relationship"`
+	// LockoutAlertsEnabled emails OwnerEmail when failed logins for this
+	// account lock out an IP.
+	LockoutAlertsEnabled bool `gorm:"column:lockout_alerts_enabled;default:0" json:"lockout_alerts_enabled"`
+	// Timezone is the owner's IANA time zone (e.g. "Europe/Berlin"). Times
+	// are stored in UTC; the zone only affects how emails show them and the
+	// default for delivery windows. Empty means UTC.
+	Timezone string `gorm:"column:timezone" json:"timezone"`
+	// ReminderDigest combines the email reminders that come due for this
+	// owner in one worker tick into a single email.
+	ReminderDigest bool `gorm:"column:reminder_digest;default:0" json:"reminder_digest"`
+	// SMTPHeloName is the hostname sent in EHLO/HELO; empty keeps the Go
+	// default of "localhost", which some relays reject.
+	SMTPHeloName string `gorm:"column:smtp_helo_name" json:"smtp_helo_name"`
+	// HeartbeatConfirmation emails OwnerEmail a short "check-in received"
+	// note after each heartbeat, throttled per account.
+	HeartbeatConfirmation bool `gorm:"column:heartbeat_confirmation;default:0" json:"heartbeat_confirmation"`
+	// ReminderActions adds "extend by 7 days" and "snooze 1 day" links to
+	// reminder emails next to the usual check-in link.
+	ReminderActions bool `gorm:"column:reminder_actions;default:0" json:"reminder_actions"`
+	// QuickHeartbeatConfirmLink shows a one-click GET confirm link on the
+	// quick-heartbeat page for clients that cannot submit its form. Link
+	// scanners that follow every link on a page would check in through it,
+	// so it is off unless the owner opts in.
+	QuickHeartbeatConfirmLink bool `gorm:"column:quick_heartbeat_confirm_link;default:0" json:"quick_heartbeat_confirm_link"`
 }
 
 // SettingsRequest is used for receiving settings from API (includes sensitive fields)
@@ -31,8 +96,80 @@ type SettingsRequest struct {
 	WebhookSecret  string `json:"webhook_secret"` // Accepted from API requests
 	WebhookEnabled bool   `json:"webhook_enabled"`
 	OwnerEmail     string `json:"owner_email"`
+
+	IncludeContentInOwnerNotification bool   `json:"include_content_in_owner_notification"`
+	DeliverAsPDF                      bool   `json:"deliver_as_pdf"`
+	NtfyURL                           string `json:"ntfy_url"`
+	ReminderEscalation                string `json:"reminder_escalation"`
+	BrandName                         string `json:"brand_name"`
+	BrandColor                        string `json:"brand_color"`
+	HeartbeatTokenRotationDays        int    `json:"heartbeat_token_rotation_days"`
+	EmailCheckInEnabled               bool   `json:"email_check_in_enabled"`
+	IMAPHost                          string `json:"imap_host"`
+	IMAPPort                          string `json:"imap_port"`
+	OwnerName                         string `json:"owner_name"`
+	OwnerSignature                    string `json:"owner_signature"`
+	// FooterText: omitted keeps the current footer, "" removes it.
+	FooterText *string `json:"footer_text,omitempty"`
+
+	ContactName         string `json:"contact_name"`
+	ContactPhone        string `json:"contact_phone"`
+	ContactEmail        string `json:"contact_email"`
+	ContactRelationship string `json:"contact_relationship"`
+
+	LockoutAlertsEnabled  bool   `json:"lockout_alerts_enabled"`
+	Timezone              string `json:"timezone"`
+	ReminderDigest        bool   `json:"reminder_digest"`
+	SMTPHeloName          string `json:"smtp_helo_name"`
+	HeartbeatConfirmation bool   `json:"heartbeat_confirmation"`
+	ReminderActions       bool   `json:"reminder_actions"`
+	// QuickHeartbeatConfirmLink opts into the one-click confirm link.
+	QuickHeartbeatConfirmLink bool `json:"quick_heartbeat_confirm_link"`
 	// AllowRegistration: only the primary (first) user may set this; persisted in application_settings.
 	AllowRegistration *bool `json:"allow_registration,omitempty"`
+	// AllowedOrigins: primary user only; comma-separated, empty reverts to ALLOWED_ORIGINS.
+	AllowedOrigins *string `json:"allowed_origins,omitempty"`
+}
+
+// SettingsRequestFrom seeds a request with the stored settings, so a partial
+// update can decode only the sent fields over it. Secrets are left blank,
+// which Save treats as "keep the stored value".
+func SettingsRequestFrom(s Settings) SettingsRequest {
+	return SettingsRequest{
+		SMTPHost:       s.SMTPHost,
+		SMTPPort:       s.SMTPPort,
+		SMTPUser:       s.SMTPUser,
+		SMTPFrom:       s.SMTPFrom,
+		SMTPFromName:   s.SMTPFromName,
+		WebhookURL:     s.WebhookURL,
+		WebhookEnabled: s.WebhookEnabled,
+		OwnerEmail:     s.OwnerEmail,
+
+		IncludeContentInOwnerNotification: s.IncludeContentInOwnerNotification,
+		DeliverAsPDF:                      s.DeliverAsPDF,
+		NtfyURL:                           s.NtfyURL,
+		ReminderEscalation:                s.ReminderEscalation,
+		BrandName:                         s.BrandName,
+		BrandColor:                        s.BrandColor,
+		HeartbeatTokenRotationDays:        s.HeartbeatTokenRotationDays,
+		EmailCheckInEnabled:               s.EmailCheckInEnabled,
+		IMAPHost:                          s.IMAPHost,
+		IMAPPort:                          s.IMAPPort,
+		OwnerName:                         s.OwnerName,
+		OwnerSignature:                    s.OwnerSignature,
+		FooterText:                        s.FooterText,
+		ContactName:                       s.ContactName,
+		ContactPhone:                      s.ContactPhone,
+		ContactEmail:                      s.ContactEmail,
+		ContactRelationship:               s.ContactRelationship,
+		LockoutAlertsEnabled:              s.LockoutAlertsEnabled,
+		Timezone:                          s.Timezone,
+		ReminderDigest:                    s.ReminderDigest,
+		SMTPHeloName:                      s.SMTPHeloName,
+		HeartbeatConfirmation:             s.HeartbeatConfirmation,
+		ReminderActions:                   s.ReminderActions,
+		QuickHeartbeatConfirmLink:         s.QuickHeartbeatConfirmLink,
+	}
 }
 
 // ToSettings converts SettingsRequest to Settings model
@@ -48,5 +185,30 @@ func (r SettingsRequest) ToSettings() Settings {
 		WebhookSecret:  r.WebhookSecret,
 		WebhookEnabled: r.WebhookEnabled,
 		OwnerEmail:     r.OwnerEmail,
+
+		IncludeContentInOwnerNotification: r.IncludeContentInOwnerNotification,
+		DeliverAsPDF:                      r.DeliverAsPDF,
+		NtfyURL:                           r.NtfyURL,
+		ReminderEscalation:                r.ReminderEscalation,
+		BrandName:                         r.BrandName,
+		BrandColor:                        r.BrandColor,
+		HeartbeatTokenRotationDays:        r.HeartbeatTokenRotationDays,
+		EmailCheckInEnabled:               r.EmailCheckInEnabled,
+		IMAPHost:                          r.IMAPHost,
+		IMAPPort:                          r.IMAPPort,
+		OwnerName:                         r.OwnerName,
+		OwnerSignature:                    r.OwnerSignature,
+		FooterText:                        r.FooterText,
+		ContactName:                       r.ContactName,
+		ContactPhone:                      r.ContactPhone,
+		ContactEmail:                      r.ContactEmail,
+		ContactRelationship:               r.ContactRelationship,
+		LockoutAlertsEnabled:              r.LockoutAlertsEnabled,
+		Timezone:                          r.Timezone,
+		ReminderDigest:                    r.ReminderDigest,
+		SMTPHeloName:                      r.SMTPHeloName,
+		HeartbeatConfirmation:             r.HeartbeatConfirmation,
+		ReminderActions:                   r.ReminderActions,
+		QuickHeartbeatConfirmLink:         r.QuickHeartbeatConfirmLink,
 	}
 }
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Status transition reasons.
+const (
+	TransitionReasonCreated       = "created"
+	TransitionReasonHeartbeat     = "heartbeat"
+	TransitionReasonExtended      = "extend"
+	TransitionReasonTriggered     = "worker_trigger"
+	TransitionReasonUndeliverable = "undeliverable"
+)
+
+// Status transition actors.
+const (
+	TransitionActorOwner  = "owner"
+	TransitionActorWorker = "worker"
+)
+
+// StatusTransition records one change of a message's status, so the history
+// of when and why a switch fired can be shown later. FromStatus is empty for
+// the transition recorded when the message is created.
+type StatusTransition struct {
+	ID         uint          `gorm:"primaryKey" json:"id"`
+	MessageID  string        `gorm:"type:text;index;not null" json:"message_id"`
+	UserID     string        `gorm:"type:text;index;not null" json:"-"`
+	FromStatus MessageStatus `json:"from_status"`
+	ToStatus   MessageStatus `gorm:"not null" json:"to_status"`
+	Reason     string        `gorm:"not null" json:"reason"`
+	Actor      string        `gorm:"not null" json:"actor"`
+	CreatedAt  time.Time     `gorm:"index" json:"created_at"`
+}
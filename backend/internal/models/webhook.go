@@ -10,4 +10,25 @@ type Webhook struct {
 	Enabled   bool      `gorm:"default:1" json:"enabled"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// PayloadTemplate, when set, is a text/template that replaces the
+	// switch.triggered body. See docs/webhooks.md.
+	PayloadTemplate string `gorm:"not null;default:''" json:"payload_template"`
+
+	// Delivery health. ConsecutiveFailures resets on every success; reaching
+	// WEBHOOK_MAX_CONSECUTIVE_FAILURES clears Enabled and sets AutoDisabledAt.
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastError           string     `gorm:"not null;default:''" json:"last_error,omitempty"`
+	AutoDisabledAt      *time.Time `json:"auto_disabled_at,omitempty"`
+}
+
+// WebhookAllowlist reports the webhook host allowlists in effect. Baseline
+// comes from WEBHOOK_ALLOWLIST_HOSTS and is read-only; Hosts is the stored,
+// editable list. A destination must satisfy both.
+type WebhookAllowlist struct {
+	Baseline string `json:"baseline"`
+	Hosts    string `json:"hosts"`
+	CanEdit  bool   `json:"can_edit"`
 }
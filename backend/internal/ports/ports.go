@@ -1,6 +1,7 @@
 package ports
 
 import (
+	"context"
 	"time"
 
 	"github.com/alpyxn/aeterna/backend/internal/models"
@@ -22,16 +23,77 @@ type AuthServicePort interface {
 	AdditionalRegistrationOpen() (bool, error)
 }
 
+// RecoveryKeyServicePort manages additional recovery keys, such as ones
+// given to trusted contacts.
+type RecoveryKeyServicePort interface {
+	List(userID string) ([]models.RecoveryKey, error)
+	Add(userID, label string) (recoveryKey string, key models.RecoveryKey, err error)
+	Revoke(userID string, id uint) (models.RecoveryKey, error)
+}
+
+// AutomationTokenServicePort manages heartbeat-only bearer tokens for
+// monitoring and scheduled jobs.
+type AutomationTokenServicePort interface {
+	List(userID string) ([]models.AutomationToken, error)
+	Add(userID, label string) (token string, record models.AutomationToken, err error)
+	Revoke(userID string, id uint) (models.AutomationToken, error)
+	Authenticate(token string) (userID string, err error)
+}
+
+// LockoutAlertPort notifies an account owner when failed logins lock out a
+// client IP.
+type LockoutAlertPort interface {
+	NotifyLockout(email, ip string, attempts int)
+}
+
 // MessageServicePort covers switch lifecycle and heartbeat operations.
 type MessageServicePort interface {
-	Create(userID, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error)
+	Create(ctx context.Context, userID, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject string, creationReminders []int) (models.Message, error)
 	GetPublicByID(id string) (models.Message, error)
+	GetByManagementToken(token string) (models.Message, error)
 	GetByID(userID, id string) (models.Message, error)
 	List(userID string) ([]models.Message, error)
+	ListByRecipient(userID, recipient string) ([]models.Message, error)
+	ListByStatus(userID string, status models.MessageStatus) ([]models.Message, error)
 	Heartbeat(userID, id string) (models.Message, error)
 	BulkHeartbeat(userID string) error
-	Delete(userID, id string) error
-	Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error)
+	ExtendDeadline(userID, id string, by time.Duration) error
+	Delete(userID, id string, force bool) error
+	Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject *string, creationReminders []int) (models.Message, error)
+	RotateManagementToken(userID, id string) (string, error)
+	ExportContent(userID, id string) (filename, mimeType string, data []byte, err error)
+	ListTransitions(userID, id string) ([]models.StatusTransition, error)
+	ListTombstones(userID string) ([]models.MessageTombstone, error)
+	Import(ctx context.Context, userID string, rows []MessageImportRow) ([]MessageImportResult, error)
+}
+
+// MessageImportRow is one switch to create in a bulk import.
+type MessageImportRow struct {
+	Content         string
+	RecipientEmails []string
+	TriggerDuration int
+}
+
+// MessageImportResult reports one imported row, numbered from 1. ID is set
+// once the switch was created; Error, Code and Field say why the row was
+// rejected.
+type MessageImportResult struct {
+	Row   int    `json:"row"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+// StorageUsage reports attachment storage. UsedBytes is the caller's own
+// attachments; TotalUsedBytes counts every account towards LimitBytes and is
+// only reported to the primary administrator. Without a cap LimitBytes is 0
+// and AvailableBytes is nil.
+type StorageUsage struct {
+	UsedBytes      int64  `json:"used_bytes"`
+	TotalUsedBytes *int64 `json:"total_used_bytes,omitempty"`
+	LimitBytes     int64  `json:"limit_bytes"`
+	AvailableBytes *int64 `json:"available_bytes"`
 }
 
 // FileServicePort covers attachment storage for switches and farewell letters.
@@ -42,12 +104,17 @@ type FileServicePort interface {
 	GetDecrypted(userID, attachmentID string) (filename, mimeType string, data []byte, err error)
 	ListByMessageID(userID, messageID string) ([]models.Attachment, error)
 	CountByMessageID(userID, messageID string) (int64, error)
+	ListRevealable(messageID string) ([]models.Attachment, error)
+	GetRevealable(messageID, attachmentID string) (filename, mimeType string, data []byte, err error)
+	SetRecipients(userID, attachmentID string, recipientEmails []string) (models.Attachment, error)
+	GetThumbnail(userID, attachmentID string) ([]byte, error)
 	UploadFarewellAttachment(userID, letterID, filename, mimeType string, data []byte) (models.FarewellAttachment, error)
 	ListFarewellAttachmentsByLetterID(userID, letterID string) ([]models.FarewellAttachment, error)
 	CountFarewellAttachmentsByLetterID(userID, letterID string) (int64, error)
 	DeleteFarewellAttachment(userID, attachmentID string) error
 	DeleteFarewellAttachmentsByLetterID(userID, letterID string) error
 	GetFarewellAttachmentDecrypted(userID, attachmentID string) (filename, mimeType string, data []byte, err error)
+	StorageUsage(userID string) (StorageUsage, error)
 }
 
 // FarewellServicePort covers farewell letter CRUD scoped to a switch.
@@ -70,7 +137,8 @@ type SettingsServicePort interface {
 	Get(userID string) (models.Settings, error)
 	GetByHeartbeatToken(token string) (models.Settings, error)
 	Save(userID string, req models.Settings) error
-	TestSMTP(req models.Settings) error
+	RotateHeartbeatToken(userID string) (string, error)
+	TestSMTP(ctx context.Context, req models.Settings) error
 }
 
 // ApplicationSettingsServicePort covers the global (singleton) application settings.
@@ -80,17 +148,103 @@ type ApplicationSettingsServicePort interface {
 	CanManageRegistration(userID string) bool
 }
 
+// OriginAllowlistPort covers the live browser-origin allowlist.
+type OriginAllowlistPort interface {
+	AllowedOrigins() string
+	Validate(actorUserID, origins string) error
+	Set(actorUserID, origins string) error
+}
+
 // WebhookStorePort covers webhook CRUD for a tenant.
 type WebhookStorePort interface {
 	List(userID string) ([]models.Webhook, error)
 	ListEnabledForUser(userID string) ([]models.Webhook, error)
 	Create(userID string, item models.Webhook) (models.Webhook, error)
 	Update(userID, id string, input models.Webhook) (models.Webhook, error)
+	Test(ctx context.Context, userID, id string) (models.Webhook, error)
 	Delete(userID, id string) error
 }
 
+// WebhookAllowlistPort reads and updates the stored webhook host allowlist.
+type WebhookAllowlistPort interface {
+	Get(userID string) (models.WebhookAllowlist, error)
+	Set(actorUserID, hosts string) (models.WebhookAllowlist, error)
+}
+
 // UserAdminServicePort covers administrative user account management.
 type UserAdminServicePort interface {
 	List(actorUserID string) ([]models.UserListItem, error)
 	Delete(actorUserID, targetUserID string) error
 }
+
+// WorkerStatus is a liveness snapshot of the background worker.
+// LastTickAt is the last tick whose checks all completed; LastRunAt is the
+// last tick started, successful or not.
+type WorkerStatus struct {
+	StartedAt  time.Time     `json:"started_at"`
+	LastTickAt *time.Time    `json:"last_tick_at"`
+	LastRunAt  *time.Time    `json:"last_run_at"`
+	NextTickAt time.Time     `json:"next_tick_at"`
+	Interval   time.Duration `json:"-"`
+	// MessagesProcessed counts the switches triggered since the process
+	// started.
+	MessagesProcessed int64        `json:"messages_processed"`
+	LastError         *WorkerError `json:"last_error"`
+	// Maintenance is set while MAINTENANCE_MODE pauses the checks.
+	Maintenance bool `json:"maintenance"`
+}
+
+// WorkerError is the most recent failure the worker recorded, such as an
+// undelivered trigger email or a panicking check.
+type WorkerError struct {
+	At        time.Time `json:"at"`
+	Check     string    `json:"check"`
+	MessageID string    `json:"message_id,omitempty"`
+	Error     string    `json:"error"`
+}
+
+// WorkerStatusPort reports when the worker last completed a tick.
+type WorkerStatusPort interface {
+	Status() WorkerStatus
+}
+
+// SwitchSimulation describes what the worker would do with a switch if it
+// ticked now.
+type SwitchSimulation struct {
+	MessageID string               `json:"message_id"`
+	Status    models.MessageStatus `json:"status"`
+	Now       time.Time            `json:"now"`
+	Deadline  time.Time            `json:"deadline"`
+	Due       bool                 `json:"due"`
+	// Action is one of none, wait, creation_grace, startup_grace,
+	// delivery_window, hold, error or trigger.
+	Action    string              `json:"action"`
+	HeldUntil *time.Time          `json:"held_until,omitempty"`
+	Reminders []SimulatedReminder `json:"reminders"`
+	Channels  SimulatedChannels   `json:"channels"`
+}
+
+// SimulatedReminder is a reminder that would be sent on the next tick.
+type SimulatedReminder struct {
+	ID            uint   `json:"id"`
+	RelativeTo    string `json:"relative_to"`
+	MinutesBefore int    `json:"minutes_before"`
+	MinutesAfter  int    `json:"minutes_after"`
+	Channel       string `json:"channel"`
+	Resend        bool   `json:"resend"`
+	Final         bool   `json:"final"`
+}
+
+// SimulatedChannels lists the delivery channels configured for a switch's
+// owner.
+type SimulatedChannels struct {
+	Email    bool `json:"email"`
+	Webhooks int  `json:"webhooks"`
+	Ntfy     bool `json:"ntfy"`
+}
+
+// WorkerSimulatorPort dry-runs the worker for one switch without delivering
+// anything or changing state.
+type WorkerSimulatorPort interface {
+	Simulate(userID, messageID string) (SwitchSimulation, error)
+}
@@ -20,19 +20,23 @@ const (
 	EventCodeMessageDeleted             = "message.deleted"
 	EventCodeMessageHeartbeat           = "message.heartbeat"
 	EventCodeMessageBulkHeartbeat       = "message.bulk_heartbeat"
+	EventCodeMessageDeadlineExtended    = "message.deadline_extended"
 	EventCodeMessageAttachmentUploaded  = "message.attachment_uploaded"
 	EventCodeMessageAttachmentDeleted   = "message.attachment_deleted"
 	EventCodeMessageFarewellCreated     = "message.farewell_created"
 	EventCodeMessageFarewellUpdated     = "message.farewell_updated"
 	EventCodeMessageFarewellDeleted     = "message.farewell_deleted"
+	EventCodeMessageTokenRotated        = "message.management_token_rotated"
 	EventCodeAttachmentUploaded         = "attachment.uploaded"
 	EventCodeAttachmentDeleted          = "attachment.deleted"
+	EventCodeAttachmentUpdated          = "attachment.updated"
 	EventCodeFarewellCreated            = "farewell.created"
 	EventCodeFarewellUpdated            = "farewell.updated"
 	EventCodeFarewellDeleted            = "farewell.deleted"
 	EventCodeFarewellAttachmentUploaded = "farewell_attachment.uploaded"
 	EventCodeFarewellAttachmentDeleted  = "farewell_attachment.deleted"
 	EventCodeSettingsSaved              = "settings.saved"
+	EventCodeHeartbeatTokenRotated      = "settings.heartbeat_token_rotated"
 	EventCodeWebhookCreated             = "webhook.created"
 	EventCodeWebhookUpdated             = "webhook.updated"
 	EventCodeWebhookDeleted             = "webhook.deleted"
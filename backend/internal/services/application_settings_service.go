@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"time"
 
 	"github.com/alpyxn/aeterna/backend/internal/database"
 	"github.com/alpyxn/aeterna/backend/internal/models"
@@ -27,7 +28,7 @@ func (s ApplicationSettingsService) Get() (models.ApplicationSettings, error) {
 // SetAllowRegistration updates the global flag; only the first (primary) user may call this.
 func (s ApplicationSettingsService) SetAllowRegistration(actorUserID string, allow bool) error {
 	if !IsFirstUser(actorUserID) {
-		return NewAPIError(403, "forbidden", "Only the primary administrator can change registration settings.", nil)
+		return NewAPIError(403, CodeForbidden, "Only the primary administrator can change registration settings.", nil)
 	}
 	var app models.ApplicationSettings
 	err := database.DB.First(&app, applicationSettingsSingletonID).Error
@@ -47,6 +48,36 @@ func (s ApplicationSettingsService) CanManageRegistration(userID string) bool {
 	return IsFirstUser(userID)
 }
 
+// LastWorkerTick returns when the worker last completed a tick, or nil if it
+// never has.
+func (s ApplicationSettingsService) LastWorkerTick() (*time.Time, error) {
+	app, err := s.Get()
+	if err != nil {
+		return nil, err
+	}
+	return app.WorkerLastTickAt, nil
+}
+
+// RecordWorkerTick stores the completion time of the latest worker tick.
+func (s ApplicationSettingsService) RecordWorkerTick(at time.Time) error {
+	return database.DB.Model(&models.ApplicationSettings{}).
+		Where("id = ?", applicationSettingsSingletonID).
+		Update("worker_last_tick_at", at.UTC()).Error
+}
+
+// saveApplicationSetting updates one column of the singleton row.
+func saveApplicationSetting(column string, value any) error {
+	if err := EnsureApplicationSettingsRow(); err != nil {
+		return Internal("Failed to load application settings", err)
+	}
+	if err := database.DB.Model(&models.ApplicationSettings{}).
+		Where("id = ?", applicationSettingsSingletonID).
+		Update(column, value).Error; err != nil {
+		return Internal("Failed to save application settings", err)
+	}
+	return nil
+}
+
 // EnsureApplicationSettingsRow creates the singleton row if missing.
 func EnsureApplicationSettingsRow() error {
 	var n int64
@@ -1,13 +1,12 @@
 package services
 
 import (
-	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -22,10 +21,16 @@ import (
 
 type AuthService struct {
 	cfg config.Config
+	// sendMail and ntfy alert the owner when an additional recovery key is
+	// used; nil skips that channel.
+	sendMail func(settings models.Settings, recipients []string, subject, body string) error
+	ntfy     interface {
+		Send(topicURL, title, message, priority, clickURL string) error
+	}
 }
 
 func NewAuthService(cfg config.Config) AuthService {
-	return AuthService{cfg: cfg}
+	return AuthService{cfg: cfg, sendMail: NewEmailService(cfg).SendPlain, ntfy: NtfyService{}}
 }
 
 type sessionClaims struct {
@@ -61,7 +66,7 @@ func (s AuthService) passwordHashPrefixForUser(userID string) (string, error) {
 	var u models.User
 	if err := database.DB.First(&u, "id = ?", userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", NewAPIError(401, "unauthorized", "Unauthorized access.", nil)
+			return "", NewAPIError(401, CodeUnauthorized, "Unauthorized access.", nil)
 		}
 		return "", Internal("Failed to load user", err)
 	}
@@ -122,22 +127,22 @@ func (s AuthService) IssueSessionPair(userID string) (accessToken string, access
 func (s AuthService) RefreshSessionPair(refreshToken string) (userID, accessToken string, accessExp time.Time, nextRefreshToken string, nextRefreshExp time.Time, err error) {
 	refreshToken = strings.TrimSpace(refreshToken)
 	if refreshToken == "" {
-		return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, "unauthorized", "Invalid refresh token.", nil)
+		return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, CodeUnauthorized, "Invalid refresh token.", nil)
 	}
 
 	currentHash := refreshTokenHash(refreshToken)
 	var current models.RefreshSession
 	if err := database.DB.Where("token_hash = ?", currentHash).First(&current).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, "unauthorized", "Invalid refresh token.", nil)
+			return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, CodeUnauthorized, "Invalid refresh token.", nil)
 		}
 		return "", "", time.Time{}, "", time.Time{}, Internal("Failed to load refresh session", err)
 	}
 	if current.RevokedAt != nil {
-		return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, "unauthorized", "Refresh token has been revoked.", nil)
+		return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, CodeUnauthorized, "Refresh token has been revoked.", nil)
 	}
 	if time.Now().UTC().After(current.ExpiresAt) {
-		return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, "unauthorized", "Refresh token has expired.", nil)
+		return "", "", time.Time{}, "", time.Time{}, NewAPIError(401, CodeUnauthorized, "Refresh token has expired.", nil)
 	}
 
 	sessionID := normalizeSessionID(current.SessionID)
@@ -156,7 +161,7 @@ func (s AuthService) RefreshSessionPair(refreshToken string) (userID, accessToke
 			return Internal("Failed to revoke refresh session", revokeResult.Error)
 		}
 		if revokeResult.RowsAffected != 1 {
-			return NewAPIError(401, "unauthorized", "Invalid refresh token.", nil)
+			return NewAPIError(401, CodeUnauthorized, "Invalid refresh token.", nil)
 		}
 
 		token, exp, issueErr := s.issueRefreshSession(tx, current.UserID, sessionID)
@@ -266,25 +271,25 @@ func (s AuthService) cleanupRefreshSessions(db *gorm.DB, now time.Time) error {
 // VerifySessionToken validates the cookie token and returns the authenticated user ID.
 func (s AuthService) VerifySessionToken(token string) (userID string, err error) {
 	if token == "" {
-		return "", NewAPIError(401, "unauthorized", "Unauthorized access. Session required.", nil)
+		return "", NewAPIError(401, CodeUnauthorized, "Unauthorized access. Session required.", nil)
 	}
 
 	decrypted, err := cryptoService.Decrypt(token)
 	if err != nil {
-		return "", NewAPIError(401, "unauthorized", "Unauthorized access. Session required.", err)
+		return "", NewAPIError(401, CodeUnauthorized, "Unauthorized access. Session required.", err)
 	}
 
 	var claims sessionClaims
 	if err := json.Unmarshal([]byte(decrypted), &claims); err != nil {
-		return "", NewAPIError(401, "unauthorized", "Unauthorized access. Session required.", err)
+		return "", NewAPIError(401, CodeUnauthorized, "Unauthorized access. Session required.", err)
 	}
 
 	if claims.UserID == "" {
-		return "", NewAPIError(401, "unauthorized", "Invalid session", nil)
+		return "", NewAPIError(401, CodeUnauthorized, "Invalid session", nil)
 	}
 
 	if claims.Exp == 0 || time.Now().UTC().After(time.Unix(claims.Exp, 0)) {
-		return "", NewAPIError(401, "unauthorized", "Session expired", nil)
+		return "", NewAPIError(401, CodeUnauthorized, "Session expired", nil)
 	}
 
 	if claims.Hash != "" {
@@ -293,7 +298,7 @@ func (s AuthService) VerifySessionToken(token string) (userID string, err error)
 			return "", err
 		}
 		if claims.Hash != prefix {
-			return "", NewAPIError(401, "unauthorized", "Session expired due to password change", nil)
+			return "", NewAPIError(401, CodeUnauthorized, "Session expired due to password change", nil)
 		}
 	}
 
@@ -328,60 +333,101 @@ func (s AuthService) RegisterFirstUser(email, password, ownerEmail string) (reco
 		return "", models.User{}, err
 	}
 	if n > 0 {
-		return "", models.User{}, NewAPIError(400, "already_configured", "An account already exists. Sign in instead.", nil)
+		return "", models.User{}, NewAPIError(400, CodeAlreadyConfigured, "An account already exists. Sign in instead.", nil)
 	}
 
 	email = s.normalizeEmail(email)
 	if email == "" {
-		return "", models.User{}, BadRequest("Email is required", nil)
+		return "", models.User{}, InvalidField("email", "Email is required", nil)
+	}
+	if err := validationService.ValidateEmail("email", email); err != nil {
+		return "", models.User{}, err
 	}
-	if err := validationService.ValidateEmail(email); err != nil {
+	if err := s.validatePassword("password", password); err != nil {
 		return "", models.User{}, err
 	}
-	if err := validationService.ValidatePassword(password); err != nil {
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", models.User{}, Internal("Failed to hash password", err)
+	}
+	return s.createFirstUser(email, string(hash), ownerEmail)
+}
+
+// SeedFromEnv configures the first account from SETUP_EMAIL and either
+// SETUP_PASSWORD or MASTER_PASSWORD_HASH, so deployments need no interactive
+// setup. It does nothing when no setup email is configured or an account
+// already exists, which makes it safe to run on every start.
+func (s AuthService) SeedFromEnv() (recoveryKey string, user models.User, err error) {
+	seed := s.cfg.Auth
+	if seed.SetupEmail == "" {
+		return "", models.User{}, nil
+	}
+	configured, err := s.IsConfigured()
+	if err != nil || configured {
 		return "", models.User{}, err
 	}
 
+	email := s.normalizeEmail(seed.SetupEmail)
+	if err := validationService.ValidateEmail("email", email); err != nil {
+		return "", models.User{}, err
+	}
+	hash := seed.SetupPasswordHash
+	if hash == "" {
+		if err := s.validatePassword("password", seed.SetupPassword); err != nil {
+			return "", models.User{}, err
+		}
+		raw, err := bcrypt.GenerateFromPassword([]byte(seed.SetupPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return "", models.User{}, Internal("Failed to hash password", err)
+		}
+		hash = string(raw)
+	}
+	return s.createFirstUser(email, hash, seed.SetupOwnerEmail)
+}
+
+// createFirstUser stores the first user with an already hashed password,
+// issuing its recovery key and heartbeat token.
+func (s AuthService) createFirstUser(email, passwordHash, ownerEmail string) (recoveryKey string, user models.User, err error) {
 	ownerEmail = strings.TrimSpace(ownerEmail)
 	if ownerEmail != "" {
-		if err := validationService.ValidateEmail(ownerEmail); err != nil {
+		if err := validationService.ValidateEmail("owner_email", ownerEmail); err != nil {
 			return "", models.User{}, err
 		}
 	} else {
 		ownerEmail = email
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", models.User{}, Internal("Failed to hash password", err)
-	}
-
-	recoveryKey, err = generateRecoveryKey()
+	recoveryKey, err = s.generateRecoveryKey()
 	if err != nil {
 		return "", models.User{}, Internal("Failed to generate recovery key", err)
 	}
 
-	recoveryHash, err := bcrypt.GenerateFromPassword([]byte(recoveryKey), bcrypt.DefaultCost)
+	recoveryHash, err := bcrypt.GenerateFromPassword(recoveryKeySecret(recoveryKey), bcrypt.DefaultCost)
 	if err != nil {
 		return "", models.User{}, Internal("Failed to hash recovery key", err)
 	}
 
-	heartbeatToken, err := cryptoService.GenerateToken(32)
+	heartbeatToken, err := cryptoService.GenerateToken(heartbeatTokenBytes(s.cfg))
 	if err != nil {
 		return "", models.User{}, Internal("Failed to generate heartbeat token", err)
 	}
 
 	user = models.User{
 		Email:        email,
-		PasswordHash: string(hash),
+		PasswordHash: passwordHash,
 	}
 	if err := database.DB.Create(&user).Error; err != nil {
 		return "", models.User{}, Internal("Failed to create user", err)
 	}
 
+	encryptedOwnerEmail, err := encryptOwnerEmail(user.ID, ownerEmail)
+	if err != nil {
+		return "", models.User{}, err
+	}
 	settings := models.Settings{
 		UserID:          user.ID,
-		OwnerEmail:      ownerEmail,
+		OwnerEmail:      encryptedOwnerEmail,
 		RecoveryKeyHash: string(recoveryHash),
 		HeartbeatToken:  heartbeatToken,
 	}
@@ -413,7 +459,7 @@ func (s AuthService) RegisterAdditionalUser(email, password, ownerEmail string)
 		return "", models.User{}, err
 	}
 	if !open {
-		return "", models.User{}, NewAPIError(403, "registration_disabled", "Additional registration is disabled.", nil)
+		return "", models.User{}, NewAPIError(403, CodeRegistrationDisabled, "Additional registration is disabled.", nil)
 	}
 	var n int64
 	if err := database.DB.Model(&models.User{}).Count(&n).Error; err != nil {
@@ -428,12 +474,12 @@ func (s AuthService) RegisterAdditionalUser(email, password, ownerEmail string)
 func (s AuthService) registerUser(email, password, ownerEmail string) (recoveryKey string, user models.User, err error) {
 	email = s.normalizeEmail(email)
 	if email == "" {
-		return "", models.User{}, BadRequest("Email is required", nil)
+		return "", models.User{}, InvalidField("email", "Email is required", nil)
 	}
-	if err := validationService.ValidateEmail(email); err != nil {
+	if err := validationService.ValidateEmail("email", email); err != nil {
 		return "", models.User{}, err
 	}
-	if err := validationService.ValidatePassword(password); err != nil {
+	if err := s.validatePassword("password", password); err != nil {
 		return "", models.User{}, err
 	}
 
@@ -442,12 +488,12 @@ func (s AuthService) registerUser(email, password, ownerEmail string) (recoveryK
 		return "", models.User{}, err
 	}
 	if existing > 0 {
-		return "", models.User{}, NewAPIError(400, "email_taken", "That email is already registered.", nil)
+		return "", models.User{}, NewAPIError(400, CodeEmailTaken, "That email is already registered.", nil)
 	}
 
 	ownerEmail = strings.TrimSpace(ownerEmail)
 	if ownerEmail != "" {
-		if err := validationService.ValidateEmail(ownerEmail); err != nil {
+		if err := validationService.ValidateEmail("owner_email", ownerEmail); err != nil {
 			return "", models.User{}, err
 		}
 	} else {
@@ -459,17 +505,17 @@ func (s AuthService) registerUser(email, password, ownerEmail string) (recoveryK
 		return "", models.User{}, Internal("Failed to hash password", err)
 	}
 
-	recoveryKey, err = generateRecoveryKey()
+	recoveryKey, err = s.generateRecoveryKey()
 	if err != nil {
 		return "", models.User{}, Internal("Failed to generate recovery key", err)
 	}
 
-	recoveryHash, err := bcrypt.GenerateFromPassword([]byte(recoveryKey), bcrypt.DefaultCost)
+	recoveryHash, err := bcrypt.GenerateFromPassword(recoveryKeySecret(recoveryKey), bcrypt.DefaultCost)
 	if err != nil {
 		return "", models.User{}, Internal("Failed to hash recovery key", err)
 	}
 
-	heartbeatToken, err := cryptoService.GenerateToken(32)
+	heartbeatToken, err := cryptoService.GenerateToken(heartbeatTokenBytes(s.cfg))
 	if err != nil {
 		return "", models.User{}, Internal("Failed to generate heartbeat token", err)
 	}
@@ -482,9 +528,13 @@ func (s AuthService) registerUser(email, password, ownerEmail string) (recoveryK
 		return "", models.User{}, Internal("Failed to create user", err)
 	}
 
+	encryptedOwnerEmail, err := encryptOwnerEmail(user.ID, ownerEmail)
+	if err != nil {
+		return "", models.User{}, err
+	}
 	settings := models.Settings{
 		UserID:          user.ID,
-		OwnerEmail:      ownerEmail,
+		OwnerEmail:      encryptedOwnerEmail,
 		RecoveryKeyHash: string(recoveryHash),
 		HeartbeatToken:  heartbeatToken,
 	}
@@ -518,42 +568,55 @@ func (s AuthService) Login(email, password string) (models.User, error) {
 	var user models.User
 	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return models.User{}, NewAPIError(401, "unauthorized", "Invalid email or password.", nil)
+			return models.User{}, NewAPIError(401, CodeUnauthorized, "Invalid email or password.", nil)
 		}
 		return models.User{}, Internal("Failed to load user", err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return models.User{}, NewAPIError(401, "unauthorized", "Invalid email or password.", err)
+		return models.User{}, NewAPIError(401, CodeUnauthorized, "Invalid email or password.", err)
 	}
 	return user, nil
 }
 
 var validationService = ValidationService{}
 
-func generateRecoveryKey() (string, error) {
-	bytes := make([]byte, 10)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+// validatePassword applies the configured PASSWORD_POLICY to the request
+// field named field.
+func (s AuthService) validatePassword(field, password string) error {
+	if s.cfg.Auth.PasswordPolicy == common.PasswordPolicyEntropy {
+		return validationService.ValidatePasswordEntropy(field, password, s.cfg.Auth.PasswordMinScore)
 	}
-	hexStr := strings.ToUpper(hex.EncodeToString(bytes))
-	return fmt.Sprintf("RK-%s-%s-%s-%s", hexStr[0:5], hexStr[5:10], hexStr[10:15], hexStr[15:20]), nil
+	return validationService.ValidatePassword(field, password)
 }
 
-// ResetPasswordWithRecovery uses recovery key + email to set a new password for that account.
+// ResetPasswordWithRecovery uses recovery key + email to set a new password
+// for that account. The account's own key is rotated and the new one
+// returned. An additional key, such as a trusted contact's, is revoked
+// instead; the account key stays as it is, nothing is returned, and the
+// owner is alerted.
 func (s AuthService) ResetPasswordWithRecovery(email, recoveryKey, newPassword string) (newRecoveryKey string, err error) {
-	if err := validationService.ValidatePassword(newPassword); err != nil {
+	if err := s.validatePassword("new_password", newPassword); err != nil {
 		return "", err
 	}
 	email = s.normalizeEmail(email)
 	if email == "" {
-		return "", BadRequest("Email is required", nil)
+		return "", InvalidField("email", "Email is required", nil)
 	}
+	recoveryKey = normalizeRecoveryKey(recoveryKey)
+	// A key is reported as mistyped only once it matches nothing stored, as
+	// mnemonic keys issued before the check word existed fail the check.
+	// An unknown account gets the same answer, so it reveals nothing about
+	// which exist.
+	mistyped := NewAPIError(400, CodeRecoveryKeyMistyped, "Recovery key appears mistyped. Check each character and try again.", nil)
 
 	var user models.User
 	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", NewAPIError(401, "unauthorized", "Invalid recovery request.", nil)
+			if recoveryKeyMistyped(recoveryKey) {
+				return "", mistyped
+			}
+			return "", NewAPIError(401, CodeUnauthorized, "Invalid recovery request.", nil)
 		}
 		return "", Internal("Failed to load user", err)
 	}
@@ -562,11 +625,23 @@ func (s AuthService) ResetPasswordWithRecovery(email, recoveryKey, newPassword s
 	if err := database.DB.Where("user_id = ?", user.ID).First(&settings).Error; err != nil {
 		return "", Internal("Failed to load settings", err)
 	}
-	if settings.RecoveryKeyHash == "" {
-		return "", BadRequest("Recovery key not configured for this account", nil)
-	}
-	if err := bcrypt.CompareHashAndPassword([]byte(settings.RecoveryKeyHash), []byte(recoveryKey)); err != nil {
-		return "", NewAPIError(401, "unauthorized", "Invalid recovery key.", err)
+	// The account's own key is tried first, then any additional key that
+	// has not been revoked. An additional key works once.
+	var additional *models.RecoveryKey
+	if settings.RecoveryKeyHash == "" || bcrypt.CompareHashAndPassword([]byte(settings.RecoveryKeyHash), recoveryKeySecret(recoveryKey)) != nil {
+		additional, err = RecoveryKeyService{cfg: s.cfg}.matchAdditional(user.ID, recoveryKey)
+		if err != nil {
+			return "", err
+		}
+		if additional == nil {
+			if recoveryKeyMistyped(recoveryKey) {
+				return "", mistyped
+			}
+			if settings.RecoveryKeyHash == "" {
+				return "", BadRequest("Recovery key not configured for this account", nil)
+			}
+			return "", NewAPIError(401, CodeUnauthorized, "Invalid recovery key.", nil)
+		}
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
@@ -574,28 +649,52 @@ func (s AuthService) ResetPasswordWithRecovery(email, recoveryKey, newPassword s
 		return "", Internal("Failed to hash new password", err)
 	}
 
-	newRec, err := generateRecoveryKey()
-	if err != nil {
-		return "", Internal("Failed to generate new recovery key", err)
-	}
-	newRecHash, err := bcrypt.GenerateFromPassword([]byte(newRec), bcrypt.DefaultCost)
-	if err != nil {
-		return "", Internal("Failed to hash new recovery key", err)
+	newRec := ""
+	if additional == nil {
+		newRec, err = s.generateRecoveryKey()
+		if err != nil {
+			return "", Internal("Failed to generate new recovery key", err)
+		}
+		newRecHash, err := bcrypt.GenerateFromPassword(recoveryKeySecret(newRec), bcrypt.DefaultCost)
+		if err != nil {
+			return "", Internal("Failed to hash new recovery key", err)
+		}
+		settings.RecoveryKeyHash = string(newRecHash)
 	}
-
 	user.PasswordHash = string(hash)
-	settings.RecoveryKeyHash = string(newRecHash)
 
-	if err := database.DB.Save(&user).Error; err != nil {
-		return "", Internal("Failed to update password", err)
-	}
-	if err := database.DB.Save(&settings).Error; err != nil {
-		return "", Internal("Failed to update recovery key", err)
+	now := time.Now().UTC()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if additional != nil {
+			// Claiming the key first means two resets racing with the
+			// same key cannot both succeed.
+			result := tx.Model(&models.RecoveryKey{}).Where("id = ? AND revoked_at IS NULL", additional.ID).
+				Updates(map[string]any{"last_used_at": now, "revoked_at": now})
+			if result.Error != nil {
+				return Internal("Failed to revoke used recovery key", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return NewAPIError(401, CodeUnauthorized, "Invalid recovery key.", nil)
+			}
+		} else if err := tx.Save(&settings).Error; err != nil {
+			return Internal("Failed to update recovery key", err)
+		}
+		if err := tx.Save(&user).Error; err != nil {
+			return Internal("Failed to update password", err)
+		}
+		if err := tx.Model(&models.RefreshSession{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return Internal("Failed to revoke refresh sessions", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
-	if err := database.DB.Model(&models.RefreshSession{}).
-		Where("user_id = ? AND revoked_at IS NULL", user.ID).
-		Update("revoked_at", time.Now().UTC()).Error; err != nil {
-		return "", Internal("Failed to revoke refresh sessions", err)
+	if additional != nil {
+		slog.Warn("Password reset with an additional recovery key", "user_id", user.ID, "recovery_key_id", additional.ID)
+		s.alertRecoveryKeyUsed(user.ID, *additional, now)
 	}
 
 	return newRec, nil
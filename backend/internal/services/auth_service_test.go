@@ -370,3 +370,51 @@ func TestIssueSessionPair_CleansUpExpiredAndOldRevokedSessions(t *testing.T) {
 		t.Fatalf("expected active session for another user to remain, found %d rows", otherActiveCount)
 	}
 }
+
+func TestSeedFromEnv_ConfiguresFirstAccountOnce(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+
+	if key, _, err := NewAuthService(config.Config{}).SeedFromEnv(); err != nil || key != "" {
+		t.Fatalf("without SETUP_EMAIL nothing should be seeded, got %q (%v)", key, err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass1!"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewAuthService(config.Config{Auth: config.AuthConfig{
+		SetupEmail:        "Admin@Example.com",
+		SetupPasswordHash: string(hash),
+		SetupOwnerEmail:   "owner@example.com",
+	}})
+	key, user, err := svc.SeedFromEnv()
+	if err != nil {
+		t.Fatalf("SeedFromEnv: %v", err)
+	}
+	if !strings.HasPrefix(key, "RK-") || user.Email != "admin@example.com" || user.PasswordHash != string(hash) {
+		t.Fatalf("unexpected seed result: key=%q user=%+v", key, user)
+	}
+	settings, err := (SettingsService{}).Get(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.OwnerEmail != "owner@example.com" || bcrypt.CompareHashAndPassword([]byte(settings.RecoveryKeyHash), []byte(key)) != nil {
+		t.Fatalf("settings not seeded correctly: %+v", settings)
+	}
+	if _, err := svc.Login("admin@example.com", "StrongPass1!"); err != nil {
+		t.Fatalf("seeded account should accept its password: %v", err)
+	}
+
+	again, _, err := svc.SeedFromEnv()
+	if err != nil || again != "" {
+		t.Fatalf("a second start must leave the account alone, got %q (%v)", again, err)
+	}
+	var users int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil || users != 1 {
+		t.Fatalf("users = %d (%v), want 1", users, err)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// AutomationTokenPrefix starts every automation token, so a bearer token
+	// can be told apart from a session token without a lookup.
+	AutomationTokenPrefix = "aat_"
+
+	maxAutomationTokenLabelLength = 100
+	maxAutomationTokens           = 10
+)
+
+// AutomationTokenService manages the heartbeat-only tokens used by
+// monitoring and scheduled jobs.
+type AutomationTokenService struct {
+	cfg config.Config
+}
+
+func NewAutomationTokenService(cfg config.Config) AutomationTokenService {
+	return AutomationTokenService{cfg: cfg}
+}
+
+// IsAutomationToken reports whether token has the automation token prefix.
+func IsAutomationToken(token string) bool {
+	return strings.HasPrefix(token, AutomationTokenPrefix)
+}
+
+// List returns the account's automation tokens, revoked ones included,
+// newest first. Token values are never returned after creation.
+func (s AutomationTokenService) List(userID string) ([]models.AutomationToken, error) {
+	var tokens []models.AutomationToken
+	if err := database.ForTenant(userID).Order("created_at DESC, id DESC").Find(&tokens).Error; err != nil {
+		return nil, Internal("Failed to list automation tokens", err)
+	}
+	return tokens, nil
+}
+
+// Add issues a new automation token under label. The returned value is the
+// only time it is shown.
+func (s AutomationTokenService) Add(userID, label string) (string, models.AutomationToken, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", models.AutomationToken{}, BadRequest("Label is required", nil)
+	}
+	if len(label) > maxAutomationTokenLabelLength {
+		return "", models.AutomationToken{}, BadRequest("Label is too long", nil)
+	}
+	var active int64
+	if err := database.ForTenant(userID).Model(&models.AutomationToken{}).Where("revoked_at IS NULL").Count(&active).Error; err != nil {
+		return "", models.AutomationToken{}, Internal("Failed to count automation tokens", err)
+	}
+	if active >= maxAutomationTokens {
+		return "", models.AutomationToken{}, BadRequest("Revoke an existing automation token before adding another", nil)
+	}
+
+	secret, err := cryptoService.GenerateToken(32)
+	if err != nil {
+		return "", models.AutomationToken{}, err
+	}
+	token := AutomationTokenPrefix + secret
+	record := models.AutomationToken{UserID: userID, Label: label, TokenHash: refreshTokenHash(token)}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return "", models.AutomationToken{}, Internal("Failed to save automation token", err)
+	}
+	slog.Info("Automation token added", "user_id", userID, "automation_token_id", record.ID)
+	return token, record, nil
+}
+
+// Revoke stops a token from working. Revoking an already revoked token is a
+// no-op.
+func (s AutomationTokenService) Revoke(userID string, id uint) (models.AutomationToken, error) {
+	var record models.AutomationToken
+	if err := database.ForTenant(userID).First(&record, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.AutomationToken{}, NotFound("Automation token not found", err)
+		}
+		return models.AutomationToken{}, Internal("Failed to load automation token", err)
+	}
+	if record.RevokedAt != nil {
+		return record, nil
+	}
+	now := time.Now().UTC()
+	if err := database.DB.Model(&record).Update("revoked_at", now).Error; err != nil {
+		return models.AutomationToken{}, Internal("Failed to revoke automation token", err)
+	}
+	record.RevokedAt = &now
+	slog.Info("Automation token revoked", "user_id", userID, "automation_token_id", record.ID)
+	return record, nil
+}
+
+// Authenticate returns the owner of a non-revoked automation token and
+// records its use.
+func (s AutomationTokenService) Authenticate(token string) (string, error) {
+	var record models.AutomationToken
+	if err := database.DB.Where("token_hash = ?", refreshTokenHash(token)).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", NewAPIError(401, CodeUnauthorized, "Invalid automation token.", nil)
+		}
+		return "", Internal("Failed to load automation token", err)
+	}
+	if record.RevokedAt != nil {
+		return "", NewAPIError(401, CodeUnauthorized, "Automation token has been revoked.", nil)
+	}
+	if err := database.DB.Model(&record).Update("last_used_at", time.Now().UTC()).Error; err != nil {
+		slog.Warn("Failed to record automation token use", "error", err, "automation_token_id", record.ID)
+	}
+	return record.UserID, nil
+}
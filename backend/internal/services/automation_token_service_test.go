@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestAutomationTokens(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if err := db.AutoMigrate(&models.AutomationToken{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+
+	tokens := NewAutomationTokenService(config.Config{})
+	if _, _, err := tokens.Add("u1", "  "); err == nil {
+		t.Fatal("a label is required")
+	}
+	value, record, err := tokens.Add("u1", " Uptime monitor ")
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if !IsAutomationToken(value) || record.Label != "Uptime monitor" || record.TokenHash == value {
+		t.Fatalf("unexpected token %q: %+v", value, record)
+	}
+
+	userID, err := tokens.Authenticate(value)
+	if err != nil || userID != "u1" {
+		t.Fatalf("Authenticate = %q (%v), want u1", userID, err)
+	}
+	if _, err := tokens.Authenticate(AutomationTokenPrefix + "unknown"); err == nil {
+		t.Fatal("an unknown token must not authenticate")
+	}
+	listed, err := tokens.List("u1")
+	if err != nil || len(listed) != 1 || listed[0].LastUsedAt == nil {
+		t.Fatalf("List = %+v (%v), want one used token", listed, err)
+	}
+	if other, err := tokens.List("u2"); err != nil || len(other) != 0 {
+		t.Fatalf("another account must not see these tokens: %+v (%v)", other, err)
+	}
+	if _, err := tokens.Revoke("u2", record.ID); err == nil {
+		t.Fatal("another account must not revoke these tokens")
+	}
+
+	if _, err := tokens.Revoke("u1", record.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := tokens.Authenticate(value); err == nil {
+		t.Fatal("a revoked token must not authenticate")
+	}
+}
+
+func TestDeletingUserRemovesAutomationTokens(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if err := db.AutoMigrate(&models.AutomationToken{}, &models.Message{}, &models.MessageReminder{}, &models.MessageTombstone{},
+		&models.Webhook{}, &models.Settings{}, &models.RecoveryKey{}, &models.Attachment{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+	admin := createAuthTestUser(t, db, "u1", "admin@example.com", "password123")
+	member := createAuthTestUser(t, db, "u2", "member@example.com", "password123")
+	db.Model(&member).Update("created_at", admin.CreatedAt.Add(time.Second))
+
+	tokens := NewAutomationTokenService(config.Config{})
+	value, _, err := tokens.Add(member.ID, "Uptime monitor")
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := NewUserAdminService(config.Config{}).Delete(admin.ID, member.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := tokens.Authenticate(value); err == nil {
+		t.Fatal("a deleted account's automation token must not authenticate")
+	}
+	var left int64
+	db.Model(&models.AutomationToken{}).Where("user_id = ?", member.ID).Count(&left)
+	if left != 0 {
+		t.Fatalf("%d automation tokens left for the deleted account", left)
+	}
+}
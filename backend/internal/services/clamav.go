@@ -0,0 +1,81 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamAVChunkSize bounds each INSTREAM chunk; clamd's default StreamMaxLength
+// still applies to the total.
+const clamAVChunkSize = 64 * 1024
+
+// ClamAVScanner streams data to clamd using the INSTREAM command.
+type ClamAVScanner struct {
+	addr    string
+	timeout time.Duration
+}
+
+func NewClamAVScanner(addr string, timeout time.Duration) ClamAVScanner {
+	return ClamAVScanner{addr: addr, timeout: timeout}
+}
+
+// Scan returns the detected signature name, or "" when clamd reports the data
+// clean. A non-nil error means the scan did not complete.
+func (s ClamAVScanner) Scan(data []byte) (string, error) {
+	network, address := "tcp", s.addr
+	if path, ok := strings.CutPrefix(s.addr, "unix:"); ok {
+		network, address = "unix", path
+	}
+	conn, err := net.DialTimeout(network, address, s.timeout)
+	if err != nil {
+		return "", fmt.Errorf("connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return "", err
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", fmt.Errorf("send INSTREAM: %w", err)
+	}
+	var size [4]byte
+	for offset := 0; offset < len(data); offset += clamAVChunkSize {
+		chunk := data[offset:min(offset+clamAVChunkSize, len(data))]
+		binary.BigEndian.PutUint32(size[:], uint32(len(chunk)))
+		if _, err := conn.Write(size[:]); err != nil {
+			return "", fmt.Errorf("send chunk: %w", err)
+		}
+		if _, err := conn.Write(chunk); err != nil {
+			return "", fmt.Errorf("send chunk: %w", err)
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return "", fmt.Errorf("send terminator: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("read clamd reply: %w", err)
+	}
+	return parseClamAVReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamAVReply interprets "stream: OK", "stream: <name> FOUND" and
+// "<reason> ERROR" replies.
+func parseClamAVReply(reply string) (string, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSpace(strings.TrimSuffix(result, " FOUND")), nil
+	default:
+		return "", fmt.Errorf("clamd: %s", reply)
+	}
+}
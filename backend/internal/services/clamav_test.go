@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts one INSTREAM session and replies with infected when the
+// streamed payload contains marker.
+func fakeClamd(t *testing.T, marker []byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				cmd := make([]byte, len("zINSTREAM\x00"))
+				if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var payload bytes.Buffer
+				var size [4]byte
+				for {
+					if _, err := io.ReadFull(conn, size[:]); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size[:])
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(&payload, conn, int64(n)); err != nil {
+						return
+					}
+				}
+				if bytes.Contains(payload.Bytes(), marker) {
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+					return
+				}
+				conn.Write([]byte("stream: OK\x00"))
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamAVScanner_Scan(t *testing.T) {
+	addr := fakeClamd(t, []byte("EICAR"))
+	scanner := NewClamAVScanner(addr, 2*time.Second)
+
+	clean := bytes.Repeat([]byte("a"), clamAVChunkSize*2+10)
+	if sig, err := scanner.Scan(clean); err != nil || sig != "" {
+		t.Fatalf("clean scan = (%q, %v), want no signature", sig, err)
+	}
+
+	infected := append(bytes.Repeat([]byte("a"), clamAVChunkSize), []byte("EICAR")...)
+	sig, err := scanner.Scan(infected)
+	if err != nil {
+		t.Fatalf("infected scan: %v", err)
+	}
+	if sig != "Eicar-Test-Signature" {
+		t.Fatalf("signature = %q, want Eicar-Test-Signature", sig)
+	}
+}
+
+func TestParseClamAVReply_Error(t *testing.T) {
+	if _, err := parseClamAVReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Fatal("expected error reply to surface as an error")
+	}
+}
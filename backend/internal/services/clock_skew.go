@@ -0,0 +1,71 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+// the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+// MeasureClockSkew queries an NTP server with a single SNTP request and
+// returns how far the local clock is ahead (positive) or behind (negative)
+// network time. server may omit the port, in which case 123 is used.
+func MeasureClockSkew(server string, timeout time.Duration) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("ntp dial failed: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, fmt.Errorf("ntp deadline failed: %w", err)
+	}
+
+	// LI=0, VN=4, Mode=3 (client).
+	req := make([]byte, 48)
+	req[0] = 0x23
+	sent := time.Now()
+	putNTPTime(req[40:48], sent)
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("ntp request failed: %w", err)
+	}
+
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, fmt.Errorf("ntp response failed: %w", err)
+	}
+	received := time.Now()
+	if n < 48 {
+		return 0, fmt.Errorf("ntp response too short (%d bytes)", n)
+	}
+	if mode := resp[0] & 0x07; mode != 4 {
+		return 0, fmt.Errorf("unexpected ntp mode %d", mode)
+	}
+	if resp[1] == 0 {
+		return 0, fmt.Errorf("ntp server sent kiss-of-death (stratum 0)")
+	}
+
+	serverReceive := ntpTime(resp[32:40])
+	serverTransmit := ntpTime(resp[40:48])
+	offset := (serverReceive.Sub(sent) + serverTransmit.Sub(received)) / 2
+	return -offset, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(secs, (frac*1e9)>>32)
+}
+
+func putNTPTime(b []byte, t time.Time) {
+	binary.BigEndian.PutUint32(b[0:4], uint32(t.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(b[4:8], uint32((int64(t.Nanosecond())<<32)/1e9))
+}
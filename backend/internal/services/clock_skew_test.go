@@ -0,0 +1,54 @@
+package services
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeNTPServer answers one SNTP request with a clock shifted by offset.
+func fakeNTPServer(t *testing.T, offset time.Duration) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 48)
+		_, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		resp := make([]byte, 48)
+		resp[0] = 0x24 // VN=4, Mode=4 (server)
+		resp[1] = 2
+		now := time.Now().Add(offset)
+		putNTPTime(resp[32:40], now)
+		putNTPTime(resp[40:48], now)
+		_, _ = conn.WriteTo(resp, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestMeasureClockSkew_DetectsLocalClockBehind(t *testing.T) {
+	addr := fakeNTPServer(t, 10*time.Minute)
+	skew, err := MeasureClockSkew(addr, 2*time.Second)
+	if err != nil {
+		t.Fatalf("MeasureClockSkew: %v", err)
+	}
+	if skew > -9*time.Minute || skew < -11*time.Minute {
+		t.Fatalf("skew = %v, want about -10m", skew)
+	}
+}
+
+func TestMeasureClockSkew_InSync(t *testing.T) {
+	addr := fakeNTPServer(t, 0)
+	skew, err := MeasureClockSkew(addr, 2*time.Second)
+	if err != nil {
+		t.Fatalf("MeasureClockSkew: %v", err)
+	}
+	if skew > time.Second || skew < -time.Second {
+		t.Fatalf("skew = %v, want near zero", skew)
+	}
+}
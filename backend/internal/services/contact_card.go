@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+const maxContactFieldLength = 100
+
+var contactPhonePattern = regexp.MustCompile(`^\+?[0-9 ()./-]{3,30}$`)
+
+// normalizeContactCard trims and validates the Contact* settings; an empty
+// ContactName turns the card off and clears the other fields.
+func normalizeContactCard(req *models.Settings) error {
+	req.ContactName = strings.Join(strings.Fields(req.ContactName), " ")
+	req.ContactPhone = strings.TrimSpace(req.ContactPhone)
+	req.ContactEmail = strings.TrimSpace(req.ContactEmail)
+	req.ContactRelationship = strings.Join(strings.Fields(req.ContactRelationship), " ")
+	if req.ContactName == "" {
+		if req.ContactPhone != "" || req.ContactEmail != "" || req.ContactRelationship != "" {
+			return BadRequest("A contact name is required for the contact card", nil)
+		}
+		return nil
+	}
+	for label, value := range map[string]string{"Contact name": req.ContactName, "Contact relationship": req.ContactRelationship} {
+		if len([]rune(value)) > maxContactFieldLength {
+			return BadRequest(fmt.Sprintf("%s must be at most %d characters", label, maxContactFieldLength), nil)
+		}
+	}
+	if req.ContactPhone != "" && !contactPhonePattern.MatchString(req.ContactPhone) {
+		return BadRequest("Contact phone may only contain digits, spaces and + ( ) . / -", nil)
+	}
+	if req.ContactEmail != "" {
+		if err := validationService.ValidateEmail("contact_email", req.ContactEmail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contactCardAttachment renders the owner's contact as a vCard 3.0 file for
+// delivered messages. ok is false when no contact is configured.
+func contactCardAttachment(settings models.Settings) (EmailAttachment, bool) {
+	if settings.ContactName == "" {
+		return EmailAttachment{}, false
+	}
+	lines := []string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"FN:" + escapeVCard(settings.ContactName),
+		"N:" + escapeVCard(settings.ContactName) + ";;;;",
+	}
+	if settings.ContactPhone != "" {
+		lines = append(lines, "TEL;TYPE=VOICE:"+escapeVCard(settings.ContactPhone))
+	}
+	if settings.ContactEmail != "" {
+		lines = append(lines, "EMAIL;TYPE=INTERNET:"+escapeVCard(settings.ContactEmail))
+	}
+	if settings.ContactRelationship != "" {
+		lines = append(lines,
+			"ROLE:"+escapeVCard(settings.ContactRelationship),
+			"NOTE:"+escapeVCard("Relationship: "+settings.ContactRelationship))
+	}
+	lines = append(lines, "END:VCARD")
+	return EmailAttachment{
+		Filename: "contact.vcf",
+		MimeType: "text/vcard",
+		Data:     []byte(strings.Join(lines, "\r\n") + "\r\n"),
+	}, true
+}
+
+// escapeVCard escapes a text value per RFC 6350 section 3.4.
+func escapeVCard(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
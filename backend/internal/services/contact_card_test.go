@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestContactCardAttachment(t *testing.T) {
+	if _, ok := contactCardAttachment(models.Settings{}); ok {
+		t.Fatal("expected no card without a contact name")
+	}
+
+	card, ok := contactCardAttachment(models.Settings{
+		ContactName:         "Doe, John",
+		ContactPhone:        "+1 555 0100",
+		ContactRelationship: "Executor; brother",
+	})
+	if !ok {
+		t.Fatal("expected a card")
+	}
+	if card.Filename != "contact.vcf" || card.MimeType != "text/vcard" {
+		t.Fatalf("unexpected attachment metadata: %+v", card)
+	}
+	data := string(card.Data)
+	for _, want := range []string{
+		"BEGIN:VCARD\r\n",
+		"FN:Doe\\, John\r\n",
+		"TEL;TYPE=VOICE:+1 555 0100\r\n",
+		"ROLE:Executor\\; brother\r\n",
+		"END:VCARD\r\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Fatalf("card %q does not contain %q", data, want)
+		}
+	}
+	if strings.Contains(data, "EMAIL") {
+		t.Fatalf("unset email must be omitted, got %q", data)
+	}
+}
+
+func TestNormalizeContactCard(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings models.Settings
+		wantErr  bool
+	}{
+		{"empty", models.Settings{}, false},
+		{"full", models.Settings{ContactName: "Jane", ContactPhone: "(555) 010-0000", ContactEmail: "jane@example.com", ContactRelationship: "Sister"}, false},
+		{"phone without name", models.Settings{ContactPhone: "555"}, true},
+		{"bad phone", models.Settings{ContactName: "Jane", ContactPhone: "call me\nmaybe"}, true},
+		{"bad email", models.Settings{ContactName: "Jane", ContactEmail: "not-an-email"}, true},
+		{"long name", models.Settings{ContactName: strings.Repeat("a", maxContactFieldLength+1)}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := tc.settings
+			err := normalizeContactCard(&settings)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("normalizeContactCard() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+// Encryption contexts name the record and column a ciphertext belongs to.
+// They are authenticated as AEAD additional data, so ciphertext copied into
+// another row or field no longer decrypts. Changing a format here makes
+// existing bound data unreadable.
+
+var strictCiphertextBinding atomic.Bool
+
+// errUnboundCiphertext is returned in strict mode for ciphertext that is not
+// bound to the context it is read in.
+var errUnboundCiphertext = errors.New("unbound ciphertext rejected by STRICT_CIPHERTEXT_BINDING")
+
+// SetStrictCiphertextBinding (STRICT_CIPHERTEXT_BINDING) makes every read in
+// a bound context reject unbound and header-less legacy ciphertext, so it can
+// no longer be copied between rows. Enable it once BindLegacyCiphertexts has
+// rebound everything written by older versions.
+func SetStrictCiphertextBinding(strict bool) {
+	strictCiphertextBinding.Store(strict)
+}
+
+// MessageContentContext binds a message's encrypted_content.
+func MessageContentContext(messageID string) string {
+	return "messages/" + messageID + "/encrypted_content"
+}
+
+func recipientsContext(table, id string) string {
+	return table + "/" + id + "/recipient_email"
+}
+
+func settingsSecretContext(userID, column string) string {
+	return "settings/" + userID + "/" + column
+}
+
+// Farewell letter columns, bound with FarewellLetterContext.
+const (
+	FarewellContentColumn      = "encrypted_content"
+	FarewellRawContentColumn   = "encrypted_content_raw"
+	FarewellRenderedHTMLColumn = "encrypted_rendered_html"
+)
+
+// FarewellLetterContext binds one encrypted column of a farewell letter.
+func FarewellLetterContext(letterID, column string) string {
+	return "farewell_letters/" + letterID + "/" + column
+}
+
+func webhookSecretContext(webhookID uint) string {
+	return "webhooks/" + strconv.FormatUint(uint64(webhookID), 10) + "/secret"
+}
+
+// Attachment blobs are shared by every attachment of a user with the same
+// content, so they are bound to the owner and keyed content hash rather
+// than to one attachment row.
+func attachmentBlobContext(userID, contentHash string) string {
+	return "attachments/" + userID + "/" + contentHash
+}
+
+func attachmentThumbnailContext(userID, contentHash string) string {
+	return attachmentBlobContext(userID, contentHash) + "/thumbnail"
+}
+
+func farewellAttachmentContext(userID, storagePath string) string {
+	return "farewell_attachments/" + userID + "/" + filepath.Base(storagePath)
+}
+
+// EnableRecipientEncryption encrypts the recipient_email columns of
+// messages, attachments, farewell letters and tombstones from now on.
+// Messages also get a blind index so ListByRecipient can match in SQL.
+func EnableRecipientEncryption() {
+	models.SetRecipientCipher(recipientCipher{})
+}
+
+type recipientCipher struct{}
+
+func (recipientCipher) Seal(table, id, recipients string) (string, error) {
+	return CryptoService{}.EncryptIfNeededWithContext(recipients, recipientsContext(table, id))
+}
+
+func (recipientCipher) Open(table, id, stored string) (string, error) {
+	return CryptoService{}.DecryptIfNeededWithContext(stored, recipientsContext(table, id))
+}
+
+// Index returns ",<hash>,<hash>," with one keyed hash per recipient.
+func (recipientCipher) Index(recipients string) (string, error) {
+	var index strings.Builder
+	index.WriteString(",")
+	for _, recipient := range ParseRecipientEmails(recipients) {
+		hash, err := recipientIndexHash(recipient)
+		if err != nil {
+			return "", err
+		}
+		index.WriteString(hash + ",")
+	}
+	return index.String(), nil
+}
+
+// recipientIndexHash is the blind index entry of one recipient; matching
+// ignores case and surrounding space.
+func recipientIndexHash(recipient string) (string, error) {
+	return CryptoService{}.KeyedHash([]byte("recipient_email:" + strings.ToLower(strings.TrimSpace(recipient))))
+}
+
+// isBoundCiphertext reports whether encoded (base64, optionally with the
+// "enc:" prefix) is already bound to a context.
+func isBoundCiphertext(encoded string) bool {
+	if len(encoded) >= len(cryptoPrefix) && encoded[:len(cryptoPrefix)] == cryptoPrefix {
+		encoded = encoded[len(cryptoPrefix):]
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	version, _, ok := parseCipherHeader(data)
+	return ok && version == cipherFormatBound
+}
+
+// BindLegacyCiphertexts re-encrypts message content, farewell letters,
+// settings and webhook secrets, and attachment files written before
+// ciphertext was bound to its record, and encrypts owner emails stored
+// before they were encrypted at all. Unless STRICT_CIPHERTEXT_BINDING is
+// set, unbound ciphertext is still accepted on read, so this only narrows
+// the window in which rows can be swapped; it is safe to run on every start.
+func BindLegacyCiphertexts() error {
+	crypto := CryptoService{}
+
+	var messages []models.Message
+	if err := database.DB.Unscoped().Select("id", "encrypted_content").Find(&messages).Error; err != nil {
+		return Internal("Failed to load messages for ciphertext binding", err)
+	}
+	bound := 0
+	for _, msg := range messages {
+		if msg.Content == "" || isBoundCiphertext(msg.Content) {
+			continue
+		}
+		plaintext, err := crypto.Decrypt(msg.Content)
+		if err != nil {
+			slog.Error("Failed to decrypt message for ciphertext binding", "error", err, "message_id", msg.ID)
+			continue
+		}
+		encrypted, err := crypto.EncryptWithContext(plaintext, MessageContentContext(msg.ID))
+		if err != nil {
+			return err
+		}
+		if err := database.DB.Unscoped().Model(&models.Message{}).Where("id = ? AND encrypted_content = ?", msg.ID, msg.Content).
+			UpdateColumn("encrypted_content", encrypted).Error; err != nil {
+			return Internal("Failed to bind message ciphertext", err)
+		}
+		bound++
+	}
+
+	var settings []models.Settings
+	if err := database.DB.Select("id", "user_id", "smtp_pass", "webhook_secret", "owner_email").Find(&settings).Error; err != nil {
+		return Internal("Failed to load settings for ciphertext binding", err)
+	}
+	for _, row := range settings {
+		updates := map[string]interface{}{}
+		for column, value := range map[string]string{"smtp_pass": row.SMTPPass, "webhook_secret": row.WebhookSecret, "owner_email": row.OwnerEmail} {
+			if value == "" || isBoundCiphertext(value) {
+				continue
+			}
+			plaintext, err := crypto.DecryptIfNeeded(value)
+			if err != nil {
+				slog.Error("Failed to decrypt settings secret for ciphertext binding", "error", err, "user_id", row.UserID, "column", column)
+				continue
+			}
+			encrypted, err := crypto.EncryptIfNeededWithContext(plaintext, settingsSecretContext(row.UserID, column))
+			if err != nil {
+				return err
+			}
+			updates[column] = encrypted
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := database.DB.Model(&models.Settings{}).Where("id = ?", row.ID).UpdateColumns(updates).Error; err != nil {
+			return Internal("Failed to bind settings ciphertext", err)
+		}
+		bound += len(updates)
+	}
+
+	letters, err := bindLegacyFarewellLetters()
+	if err != nil {
+		return err
+	}
+	bound += letters
+
+	secrets, err := bindLegacyWebhookSecrets()
+	if err != nil {
+		return err
+	}
+	bound += secrets
+
+	files, err := bindLegacyFiles()
+	if err != nil {
+		return err
+	}
+	bound += files
+
+	if bound > 0 {
+		slog.Info("Bound legacy ciphertext to its records", "values", bound)
+	}
+	return nil
+}
+
+// bindLegacyFarewellLetters rebinds the encrypted columns of farewell
+// letters, returning how many values were rewritten.
+func bindLegacyFarewellLetters() (int, error) {
+	crypto := CryptoService{}
+	var letters []models.FarewellLetter
+	if err := database.DB.Unscoped().Select("id", "encrypted_content", "encrypted_content_raw", "encrypted_rendered_html").Find(&letters).Error; err != nil {
+		return 0, Internal("Failed to load farewell letters for ciphertext binding", err)
+	}
+	bound := 0
+	for _, letter := range letters {
+		updates := map[string]interface{}{}
+		for column, value := range map[string]string{
+			FarewellContentColumn:      letter.Content,
+			FarewellRawContentColumn:   letter.RawContent,
+			FarewellRenderedHTMLColumn: letter.RenderedHTML,
+		} {
+			if value == "" || isBoundCiphertext(value) {
+				continue
+			}
+			plaintext, err := crypto.Decrypt(value)
+			if err != nil {
+				slog.Error("Failed to decrypt farewell letter for ciphertext binding", "error", err, "letter_id", letter.ID, "column", column)
+				continue
+			}
+			encrypted, err := crypto.EncryptWithContext(plaintext, FarewellLetterContext(letter.ID, column))
+			if err != nil {
+				return 0, err
+			}
+			updates[column] = encrypted
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := database.DB.Unscoped().Model(&models.FarewellLetter{}).Where("id = ?", letter.ID).UpdateColumns(updates).Error; err != nil {
+			return 0, Internal("Failed to bind farewell letter ciphertext", err)
+		}
+		bound += len(updates)
+	}
+	return bound, nil
+}
+
+// bindLegacyWebhookSecrets rebinds per-webhook signing secrets, returning
+// how many were rewritten.
+func bindLegacyWebhookSecrets() (int, error) {
+	crypto := CryptoService{}
+	var hooks []models.Webhook
+	if err := database.DB.Select("id", "user_id", "secret").Where("secret <> ''").Find(&hooks).Error; err != nil {
+		return 0, Internal("Failed to load webhooks for ciphertext binding", err)
+	}
+	bound := 0
+	for _, hook := range hooks {
+		if isBoundCiphertext(hook.Secret) {
+			continue
+		}
+		plaintext, err := crypto.DecryptIfNeeded(hook.Secret)
+		if err != nil {
+			slog.Error("Failed to decrypt webhook secret for ciphertext binding", "error", err, "webhook_id", hook.ID)
+			continue
+		}
+		encrypted, err := crypto.EncryptIfNeededWithContext(plaintext, webhookSecretContext(hook.ID))
+		if err != nil {
+			return 0, err
+		}
+		if err := database.DB.Model(&models.Webhook{}).Where("id = ? AND secret = ?", hook.ID, hook.Secret).UpdateColumn("secret", encrypted).Error; err != nil {
+			return 0, Internal("Failed to bind webhook secret", err)
+		}
+		bound++
+	}
+	return bound, nil
+}
+
+// bindLegacyFiles rebinds attachment blobs, their thumbnails and farewell
+// attachments, returning how many files were rewritten.
+func bindLegacyFiles() (int, error) {
+	blobRefs.Lock()
+	defer blobRefs.Unlock()
+
+	var attachments []models.Attachment
+	if err := database.DB.Unscoped().Select("user_id", "content_hash", "storage_path", "thumbnail_path").Find(&attachments).Error; err != nil {
+		return 0, Internal("Failed to load attachments for ciphertext binding", err)
+	}
+	files := map[string]string{}
+	for _, att := range attachments {
+		files[att.StoragePath] = attachmentBlobContext(att.UserID, att.ContentHash)
+		if att.ThumbnailPath != "" {
+			files[att.ThumbnailPath] = attachmentThumbnailContext(att.UserID, att.ContentHash)
+		}
+	}
+	var farewell []models.FarewellAttachment
+	if err := database.DB.Unscoped().Select("user_id", "storage_path").Find(&farewell).Error; err != nil {
+		return 0, Internal("Failed to load farewell attachments for ciphertext binding", err)
+	}
+	for _, att := range farewell {
+		files[att.StoragePath] = farewellAttachmentContext(att.UserID, att.StoragePath)
+	}
+
+	bound := 0
+	for path, context := range files {
+		rebound, err := bindLegacyFile(path, context)
+		if err != nil {
+			slog.Error("Failed to bind attachment file", "error", err, "path", path)
+			continue
+		}
+		if rebound {
+			bound++
+		}
+	}
+	return bound, nil
+}
+
+// bindLegacyFile re-encrypts the file at path bound to context unless it
+// already is. The new ciphertext replaces the old one atomically.
+func bindLegacyFile(path, context string) (bool, error) {
+	crypto := CryptoService{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if version, _, ok := parseCipherHeader(data); ok && version == cipherFormatBound {
+		if _, err := crypto.DecryptBytesWithContext(data, context); err == nil {
+			return false, nil
+		}
+	}
+	plaintext, err := crypto.DecryptBytes(data)
+	if err != nil {
+		return false, err
+	}
+	encrypted, err := crypto.EncryptBytesWithContext(plaintext, context)
+	if err != nil {
+		return false, err
+	}
+	tmp := path + ".binding"
+	if err := os.WriteFile(tmp, encrypted, 0600); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestCryptoServiceContextBindsCiphertext(t *testing.T) {
+	initTestKeyManager(t)
+	svc := CryptoService{}
+
+	bound, err := svc.EncryptWithContext("secret", MessageContentContext("m1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := svc.DecryptWithContext(bound, MessageContentContext("m1")); err != nil || got != "secret" {
+		t.Fatalf("expected bound ciphertext to open in its own context: %q, %v", got, err)
+	}
+	for _, other := range []string{MessageContentContext("m2"), ""} {
+		if _, err := svc.DecryptWithContext(bound, other); err == nil {
+			t.Fatalf("expected bound ciphertext to be rejected in context %q", other)
+		}
+	}
+
+	blob, err := svc.EncryptBytesWithContext([]byte("file"), attachmentBlobContext("u1", "hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.DecryptBytesWithContext(blob, attachmentThumbnailContext("u1", "hash")); err == nil {
+		t.Fatal("expected a file blob to be rejected as a thumbnail")
+	}
+
+	legacy, err := svc.Encrypt("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := svc.DecryptWithContext(legacy, MessageContentContext("m1")); err != nil || got != "old" {
+		t.Fatalf("expected unbound ciphertext to stay readable: %q, %v", got, err)
+	}
+}
+
+func TestMessageContentCannotBeMovedBetweenRecords(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	for _, id := range []string{"m-a", "m-b"} {
+		encrypted, err := (CryptoService{}).EncryptWithContext("content of "+id, MessageContentContext(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Message{
+			ID: id, UserID: "u-aad", Content: encrypted, KeyFragment: "v1",
+			ManagementToken: "tok-" + id, RecipientEmail: "a@a.com",
+			TriggerDuration: 60, LastSeen: time.Now().UTC(), Status: models.StatusActive,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	msg, err := (MessageService{}).GetByID("u-aad", "m-a")
+	if err != nil || msg.Content != "content of m-a" {
+		t.Fatalf("expected bound content to decrypt: %q, %v", msg.Content, err)
+	}
+
+	var b models.Message
+	if err := db.First(&b, "id = ?", "m-b").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Message{}).Where("id = ?", "m-a").Update("encrypted_content", b.Content).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (MessageService{}).GetByID("u-aad", "m-a"); err == nil {
+		t.Fatal("expected ciphertext swapped in from another message to fail")
+	}
+}
+
+func TestBindLegacyCiphertextsRebindsMessagesAndSettings(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.AutoMigrate(&models.Settings{}, &models.Webhook{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := CryptoService{}
+	legacyContent, _ := svc.Encrypt("hello")
+	legacyPass, _ := svc.EncryptIfNeeded("smtp-secret")
+	if err := db.Create(&models.Message{
+		ID: "m-legacy", UserID: "u-legacy", Content: legacyContent, KeyFragment: "v1",
+		ManagementToken: "tok", RecipientEmail: "a@a.com",
+		TriggerDuration: 60, LastSeen: time.Now().UTC(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Settings{UserID: "u-legacy", SMTPPass: legacyPass, OwnerEmail: "owner@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	legacyLetter, _ := svc.Encrypt("farewell")
+	if err := db.Create(&models.FarewellLetter{
+		ID: "fl-legacy", UserID: "u-legacy", MessageID: "m-legacy", RecipientEmail: "b@b.com",
+		Subject: "s", Content: legacyLetter, RawContent: legacyLetter, DelayMinutes: 0,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	legacySecret, _ := svc.EncryptIfNeeded("hook-secret")
+	hook := models.Webhook{UserID: "u-legacy", URL: "https://hooks.example.com/a", Secret: legacySecret}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := BindLegacyCiphertexts(); err != nil {
+			t.Fatalf("BindLegacyCiphertexts run %d failed: %v", i+1, err)
+		}
+	}
+
+	var msg models.Message
+	db.First(&msg, "id = ?", "m-legacy")
+	if !isBoundCiphertext(msg.Content) {
+		t.Fatal("expected message content to be bound")
+	}
+	if got, err := svc.DecryptWithContext(msg.Content, MessageContentContext("m-legacy")); err != nil || got != "hello" {
+		t.Fatalf("bound content does not decrypt: %q, %v", got, err)
+	}
+
+	var stored models.Settings
+	db.First(&stored, "user_id = ?", "u-legacy")
+	if !isBoundCiphertext(stored.SMTPPass) {
+		t.Fatal("expected SMTP password to be bound")
+	}
+	if !isBoundCiphertext(stored.OwnerEmail) {
+		t.Fatal("expected the cleartext owner email to be encrypted")
+	}
+	settings, err := (SettingsService{}).Get("u-legacy")
+	if err != nil || settings.SMTPPass != "smtp-secret" || settings.OwnerEmail != "owner@example.com" {
+		t.Fatalf("expected bound secrets to decrypt via settings: %q, %q, %v", settings.SMTPPass, settings.OwnerEmail, err)
+	}
+
+	var letter models.FarewellLetter
+	db.First(&letter, "id = ?", "fl-legacy")
+	for column, value := range map[string]string{FarewellContentColumn: letter.Content, FarewellRawContentColumn: letter.RawContent} {
+		if got, err := svc.DecryptWithContext(value, FarewellLetterContext("fl-legacy", column)); err != nil || got != "farewell" || !isBoundCiphertext(value) {
+			t.Fatalf("expected farewell %s to be bound: %q, %v", column, got, err)
+		}
+	}
+	if letter.RenderedHTML != "" {
+		t.Fatalf("expected empty rendered HTML to stay empty, got %q", letter.RenderedHTML)
+	}
+
+	var storedHook models.Webhook
+	db.First(&storedHook, hook.ID)
+	if !isBoundCiphertext(storedHook.Secret) {
+		t.Fatal("expected webhook secret to be bound")
+	}
+	if got, err := svc.DecryptIfNeededWithContext(storedHook.Secret, webhookSecretContext(hook.ID)); err != nil || got != "hook-secret" {
+		t.Fatalf("bound webhook secret does not decrypt: %q, %v", got, err)
+	}
+}
+
+func TestStrictCiphertextBindingRejectsUnboundCiphertext(t *testing.T) {
+	initTestKeyManager(t)
+	svc := CryptoService{}
+	legacy, err := svc.Encrypt("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bound, err := svc.EncryptWithContext("new", MessageContentContext("m1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetStrictCiphertextBinding(true)
+	t.Cleanup(func() { SetStrictCiphertextBinding(false) })
+
+	if _, err := svc.DecryptWithContext(legacy, MessageContentContext("m1")); err == nil {
+		t.Fatal("expected unbound ciphertext to be rejected in a bound context")
+	}
+	if got, err := svc.DecryptWithContext(bound, MessageContentContext("m1")); err != nil || got != "new" {
+		t.Fatalf("expected bound ciphertext to stay readable: %q, %v", got, err)
+	}
+	if got, err := svc.Decrypt(legacy); err != nil || got != "old" {
+		t.Fatalf("expected unbound reads without a context to keep working: %q, %v", got, err)
+	}
+}
+
+func TestBindLegacyCiphertextsRebindsAttachmentFiles(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.AutoMigrate(&models.Settings{}, &models.Webhook{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := CryptoService{}
+	legacy, err := svc.EncryptBytes([]byte("file body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "blob.enc")
+	if err := os.WriteFile(path, legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Attachment{
+		UserID: "u-files", MessageID: "m-files", Filename: "a.txt", MimeType: "text/plain",
+		StoragePath: path, ContentHash: "hash",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := BindLegacyCiphertexts(); err != nil {
+		t.Fatal(err)
+	}
+
+	SetStrictCiphertextBinding(true)
+	t.Cleanup(func() { SetStrictCiphertextBinding(false) })
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := svc.DecryptBytesWithContext(data, attachmentBlobContext("u-files", "hash")); err != nil || string(got) != "file body" {
+		t.Fatalf("expected the file to be bound to its attachment: %q, %v", got, err)
+	}
+}
+
+func TestFarewellLetterContentCannotBeMovedBetweenRecords(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.Create(&models.Message{
+		ID: "m-fl", UserID: "u-fl", Content: "c", KeyFragment: "v1",
+		ManagementToken: "tok", RecipientEmail: "a@a.com",
+		TriggerDuration: 60, LastSeen: time.Now().UTC(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	a, err := (FarewellService{}).Create("u-fl", "m-fl", "a@example.com", "A", "letter for a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := (FarewellService{}).Create("u-fl", "m-fl", "b@example.com", "B", "letter for b", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored models.FarewellLetter
+	if err := db.First(&stored, "id = ?", a.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.FarewellLetter{}).Where("id = ?", b.ID).UpdateColumns(map[string]interface{}{
+		FarewellContentColumn:    stored.Content,
+		FarewellRawContentColumn: stored.RawContent,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (FarewellService{}).List("u-fl", "m-fl"); err == nil {
+		t.Fatal("expected letter content swapped in from another letter to fail")
+	}
+	if _, err := (CryptoService{}).DecryptWithContext(stored.RawContent, FarewellLetterContext(b.ID, FarewellRawContentColumn)); err == nil {
+		t.Fatal("expected raw content to be bound to its own letter")
+	}
+}
+
+func TestWebhookSecretCannotBeMovedBetweenRecords(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.AutoMigrate(&models.Webhook{}); err != nil {
+		t.Fatal(err)
+	}
+	store := WebhookStore{}
+	a, err := store.Create("u-wh", models.Webhook{URL: "https://93.184.216.34/a", Secret: "secret-a", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.Create("u-wh", models.Webhook{URL: "https://93.184.216.34/b", Secret: "secret-b", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored models.Webhook
+	if err := db.First(&stored, a.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, err := (CryptoService{}).DecryptIfNeededWithContext(stored.Secret, webhookSecretContext(a.ID)); err != nil || got != "secret-a" {
+		t.Fatalf("expected the secret to open under its own webhook: %q, %v", got, err)
+	}
+	if _, err := (CryptoService{}).DecryptIfNeededWithContext(stored.Secret, webhookSecretContext(b.ID)); err == nil {
+		t.Fatal("expected a secret swapped in from another webhook to fail")
+	}
+}
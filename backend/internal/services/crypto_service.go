@@ -1,10 +1,11 @@
 package services
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"io"
 	"log/slog"
 	"sync"
@@ -64,168 +65,200 @@ func (s CryptoService) getOrCreateKey() (string, error) {
 }
 
 func (s CryptoService) Encrypt(plaintext string) (string, error) {
-	keyBase64, err := s.getOrCreateKey()
-	if err != nil {
-		return "", err
-	}
-
-	key, err := base64.StdEncoding.DecodeString(keyBase64)
-	if err != nil {
-		return "", Internal("Invalid encryption key", err)
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", Internal("Failed to create cipher", err)
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", Internal("Failed to create GCM", err)
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", Internal("Failed to generate nonce", err)
-	}
-
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return s.EncryptWithContext(plaintext, "")
 }
 
 func (s CryptoService) Decrypt(encoded string) (string, error) {
-	keyBase64, err := s.getOrCreateKey()
-	if err != nil {
-		return "", err
-	}
+	return s.DecryptWithContext(encoded, "")
+}
 
-	key, err := base64.StdEncoding.DecodeString(keyBase64)
+// EncryptWithContext encrypts plaintext bound to context (e.g. the record
+// and column it is stored in), so the ciphertext fails to decrypt anywhere
+// else. An empty context produces unbound ciphertext.
+func (s CryptoService) EncryptWithContext(plaintext, context string) (string, error) {
+	ciphertext, err := s.seal([]byte(plaintext), context)
 	if err != nil {
-		return "", Internal("Invalid encryption key", err)
+		return "", err
 	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
 
+// DecryptWithContext decrypts ciphertext from EncryptWithContext. Unbound
+// ciphertext written before binding existed is still accepted.
+func (s CryptoService) DecryptWithContext(encoded, context string) (string, error) {
 	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", Internal("Invalid ciphertext", err)
 	}
-
-	block, err := aes.NewCipher(key)
+	plaintext, err := s.open(data, context)
 	if err != nil {
-		return "", Internal("Failed to create cipher", err)
+		return "", Internal("Failed to decrypt message", err)
 	}
+	return string(plaintext), nil
+}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", Internal("Failed to create GCM", err)
-	}
+// EncryptBytes encrypts raw binary data and returns the ciphertext as bytes
+// (algorithm header and nonce prepended)
+func (s CryptoService) EncryptBytes(plaintext []byte) ([]byte, error) {
+	return s.seal(plaintext, "")
+}
 
-	if len(data) < gcm.NonceSize() {
-		return "", Internal("Invalid ciphertext length", nil)
-	}
+// DecryptBytes decrypts raw binary ciphertext produced by EncryptBytes, or a
+// legacy header-less AES-GCM ciphertext, and returns the plaintext bytes
+func (s CryptoService) DecryptBytes(ciphertext []byte) ([]byte, error) {
+	return s.DecryptBytesWithContext(ciphertext, "")
+}
 
-	nonce := data[:gcm.NonceSize()]
-	ciphertext := data[gcm.NonceSize():]
+// EncryptBytesWithContext is EncryptBytes bound to context.
+func (s CryptoService) EncryptBytesWithContext(plaintext []byte, context string) ([]byte, error) {
+	return s.seal(plaintext, context)
+}
 
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+// DecryptBytesWithContext is DecryptBytes for data bound to context.
+func (s CryptoService) DecryptBytesWithContext(ciphertext []byte, context string) ([]byte, error) {
+	plaintext, err := s.open(ciphertext, context)
 	if err != nil {
-		return "", Internal("Failed to decrypt message", err)
+		return nil, Internal("Failed to decrypt data", err)
 	}
-
-	return string(plaintext), nil
+	return plaintext, nil
 }
 
-// EncryptBytes encrypts raw binary data and returns the ciphertext as bytes (nonce prepended)
-func (s CryptoService) EncryptBytes(plaintext []byte) ([]byte, error) {
+func (s CryptoService) rawKey() ([]byte, error) {
 	keyBase64, err := s.getOrCreateKey()
 	if err != nil {
 		return nil, err
 	}
-
 	key, err := base64.StdEncoding.DecodeString(keyBase64)
 	if err != nil {
 		return nil, Internal("Invalid encryption key", err)
 	}
+	return key, nil
+}
 
-	block, err := aes.NewCipher(key)
+// seal encrypts plaintext with the configured algorithm as
+// header || nonce || ciphertext. A non-empty context is authenticated as
+// additional data together with the header.
+func (s CryptoService) seal(plaintext []byte, context string) ([]byte, error) {
+	key, err := s.rawKey()
 	if err != nil {
-		return nil, Internal("Failed to create cipher", err)
+		return nil, err
 	}
-
-	gcm, err := cipher.NewGCM(block)
+	alg := currentCipherAlgorithm()
+	aead, err := newAEAD(alg, key)
 	if err != nil {
-		return nil, Internal("Failed to create GCM", err)
+		return nil, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
+	version := cipherFormatUnbound
+	if context != "" {
+		version = cipherFormatBound
+	}
+	header := []byte{cipherMagic0, cipherMagic1, version, alg}
+	out := make([]byte, 0, cipherHeaderLen+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out = append(out, header...)
+	nonce := make([]byte, aead.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, Internal("Failed to generate nonce", err)
 	}
-
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-	return ciphertext, nil
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, cipherAdditionalData(header, context)), nil
 }
 
-// DecryptBytes decrypts raw binary ciphertext (nonce prepended) and returns the plaintext bytes
-func (s CryptoService) DecryptBytes(ciphertext []byte) ([]byte, error) {
-	keyBase64, err := s.getOrCreateKey()
+// open decrypts data written by seal. Data without a header is legacy
+// AES-256-GCM; since a legacy nonce can start with the header bytes by
+// chance, a headered ciphertext that fails to open is retried as legacy.
+// Bound ciphertext only opens with the context it was sealed with; in
+// strict mode a non-empty context accepts nothing else.
+func (s CryptoService) open(data []byte, context string) ([]byte, error) {
+	key, err := s.rawKey()
 	if err != nil {
 		return nil, err
 	}
-
-	key, err := base64.StdEncoding.DecodeString(keyBase64)
-	if err != nil {
-		return nil, Internal("Invalid encryption key", err)
+	if context != "" && strictCiphertextBinding.Load() {
+		version, alg, ok := parseCipherHeader(data)
+		if !ok || version != cipherFormatBound {
+			return nil, errUnboundCiphertext
+		}
+		return openAEAD(alg, key, data[cipherHeaderLen:], cipherAdditionalData(data[:cipherHeaderLen], context))
 	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, Internal("Failed to create cipher", err)
+	if version, alg, ok := parseCipherHeader(data); ok {
+		var additional []byte
+		if version == cipherFormatBound {
+			additional = cipherAdditionalData(data[:cipherHeaderLen], context)
+		}
+		plaintext, err := openAEAD(alg, key, data[cipherHeaderLen:], additional)
+		if err == nil {
+			return plaintext, nil
+		}
+		if legacy, legacyErr := openAEAD(CipherAESGCM, key, data, nil); legacyErr == nil {
+			return legacy, nil
+		}
+		return nil, err
 	}
+	return openAEAD(CipherAESGCM, key, data, nil)
+}
 
-	gcm, err := cipher.NewGCM(block)
+func openAEAD(alg byte, key, data, additional []byte) ([]byte, error) {
+	aead, err := newAEAD(alg, key)
 	if err != nil {
-		return nil, Internal("Failed to create GCM", err)
+		return nil, err
 	}
-
-	if len(ciphertext) < gcm.NonceSize() {
+	if len(data) < aead.NonceSize() {
 		return nil, Internal("Invalid ciphertext length", nil)
 	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additional)
+}
 
-	nonce := ciphertext[:gcm.NonceSize()]
-	data := ciphertext[gcm.NonceSize():]
-
-	plaintext, err := gcm.Open(nil, nonce, data, nil)
-	if err != nil {
-		return nil, Internal("Failed to decrypt data", err)
-	}
+func (s CryptoService) EncryptIfNeeded(plaintext string) (string, error) {
+	return s.EncryptIfNeededWithContext(plaintext, "")
+}
 
-	return plaintext, nil
+func (s CryptoService) DecryptIfNeeded(value string) (string, error) {
+	return s.DecryptIfNeededWithContext(value, "")
 }
 
-func (s CryptoService) EncryptIfNeeded(plaintext string) (string, error) {
+// EncryptIfNeededWithContext is EncryptIfNeeded bound to context.
+func (s CryptoService) EncryptIfNeededWithContext(plaintext, context string) (string, error) {
 	if plaintext == "" {
 		return "", nil
 	}
 	if len(plaintext) >= len(cryptoPrefix) && plaintext[:len(cryptoPrefix)] == cryptoPrefix {
 		return plaintext, nil
 	}
-	enc, err := s.Encrypt(plaintext)
+	enc, err := s.EncryptWithContext(plaintext, context)
 	if err != nil {
 		return "", err
 	}
 	return cryptoPrefix + enc, nil
 }
 
-func (s CryptoService) DecryptIfNeeded(value string) (string, error) {
+// DecryptIfNeededWithContext is DecryptIfNeeded for values bound to context.
+func (s CryptoService) DecryptIfNeededWithContext(value, context string) (string, error) {
 	if value == "" {
 		return "", nil
 	}
 	if len(value) >= len(cryptoPrefix) && value[:len(cryptoPrefix)] == cryptoPrefix {
-		return s.Decrypt(value[len(cryptoPrefix):])
+		return s.DecryptWithContext(value[len(cryptoPrefix):], context)
 	}
 	return value, nil
 }
 
+// KeyedHash returns a hex HMAC-SHA256 of data under the encryption key, so
+// equal inputs can be matched without storing a plain, guessable digest.
+func (s CryptoService) KeyedHash(data []byte) (string, error) {
+	keyBase64, err := s.getOrCreateKey()
+	if err != nil {
+		return "", err
+	}
+	key, err := base64.StdEncoding.DecodeString(keyBase64)
+	if err != nil {
+		return "", Internal("Invalid encryption key", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
 func (s CryptoService) GenerateToken(length int) (string, error) {
 	buf := make([]byte, length)
 	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecordDeliveredAttachments notes the name and size of every file attached
+// to msg when it triggered, including any that could not be decrypted for
+// sending, before retention deletes the files themselves.
+func RecordDeliveredAttachments(tx *gorm.DB, msg models.Message, attachments []models.Attachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	records := make([]models.DeliveredAttachment, 0, len(attachments))
+	for _, att := range attachments {
+		records = append(records, models.DeliveredAttachment{
+			MessageID:  msg.ID,
+			UserID:     msg.UserID,
+			Filename:   att.Filename,
+			Size:       att.Size,
+			Restricted: strings.TrimSpace(att.RecipientEmail) != "",
+			CreatedAt:  now,
+		})
+	}
+	return tx.Create(&records).Error
+}
+
+func deliveredAttachments(userID, messageID string) ([]models.DeliveredAttachment, error) {
+	records := make([]models.DeliveredAttachment, 0)
+	if err := database.ForTenant(userID).Where("message_id = ?", messageID).Order("id ASC").Find(&records).Error; err != nil {
+		return nil, Internal("Failed to fetch delivered attachments", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/smtp"
+	"sync"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+// maxMessagesPerSMTPConnection bounds how many messages go over one reused
+// connection, below the per-connection limits common relays enforce.
+const maxMessagesPerSMTPConnection = 50
+
+// Batch returns a copy of s that keeps one SMTP connection per server open
+// across sends, so a run of emails such as a tick's reminders costs one
+// connect and login instead of one each. Call done once the run is over to
+// close the connections. Services built with a custom sender, as in tests,
+// are returned unchanged.
+func (s EmailService) Batch() (batch EmailService, done func()) {
+	if s.sender != nil {
+		return s, func() {}
+	}
+	sender := &reusingSMTPSender{conns: map[string]*reusedSMTPConn{}}
+	s.sender = sender
+	return s, sender.close
+}
+
+type reusedSMTPConn struct {
+	client *smtp.Client
+	sent   int
+}
+
+// reusingSMTPSender is the mailSender behind Batch. A connection that fails
+// is dropped, so the retry in sendWithRetry dials a fresh one.
+type reusingSMTPSender struct {
+	// dial opens an authenticated connection; nil means dialSMTP.
+	dial func(settings models.Settings) (*smtp.Client, error)
+
+	mu    sync.Mutex
+	conns map[string]*reusedSMTPConn
+}
+
+// smtpConnKey names the connection settings may reuse. Accounts can share a
+// relay and even a username, so the key carries the owning account and a
+// hash of the password the connection logged in with.
+func smtpConnKey(settings models.Settings) string {
+	pass := sha256.Sum256([]byte(settings.SMTPPass))
+	return settings.UserID + "|" + settings.SMTPHost + ":" + settings.SMTPPort + "|" + settings.SMTPUser + "|" + hex.EncodeToString(pass[:])
+}
+
+func (r *reusingSMTPSender) Send(settings models.Settings, from string, recipients []string, message []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := smtpConnKey(settings)
+	conn := r.conns[key]
+	if conn != nil && (conn.sent >= maxMessagesPerSMTPConnection || conn.client.Reset() != nil) {
+		r.drop(key)
+		conn = nil
+	}
+	if conn == nil {
+		dial := r.dial
+		if dial == nil {
+			dial = dialSMTP
+		}
+		client, err := dial(settings)
+		if err != nil {
+			return err
+		}
+		conn = &reusedSMTPConn{client: client}
+		r.conns[key] = conn
+	}
+
+	if err := deliverSMTP(conn.client, from, recipients, message); err != nil {
+		r.drop(key)
+		return err
+	}
+	conn.sent++
+	return nil
+}
+
+func (r *reusingSMTPSender) drop(key string) {
+	if conn := r.conns[key]; conn != nil {
+		closeSMTP(conn.client)
+		delete(r.conns, key)
+	}
+}
+
+func (r *reusingSMTPSender) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.conns {
+		r.drop(key)
+	}
+}
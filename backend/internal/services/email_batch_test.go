@@ -0,0 +1,127 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+// serveFakeSMTP accepts plaintext SMTP sessions, counting connections and
+// delivered messages.
+func serveFakeSMTP(t *testing.T) (addr string, conns, messages *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	conns, messages = &atomic.Int32{}, &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+				reply("220 fake ESMTP")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+						reply("250 fake")
+					case cmd == "DATA":
+						reply("354 go ahead")
+						for {
+							body, err := r.ReadString('\n')
+							if err != nil {
+								return
+							}
+							if body == ".\r\n" {
+								break
+							}
+						}
+						messages.Add(1)
+						reply("250 queued")
+					case cmd == "QUIT":
+						reply("221 bye")
+						return
+					default:
+						reply("250 ok")
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), conns, messages
+}
+
+func TestReusingSMTPSenderSharesOneConnection(t *testing.T) {
+	addr, conns, messages := serveFakeSMTP(t)
+	sender := &reusingSMTPSender{
+		dial:  func(models.Settings) (*smtp.Client, error) { return smtp.Dial(addr) },
+		conns: map[string]*reusedSMTPConn{},
+	}
+	settings := models.Settings{SMTPHost: "127.0.0.1", SMTPPort: "25", SMTPUser: "owner"}
+
+	for i := 0; i < 3; i++ {
+		if err := sender.Send(settings, "from@example.com", []string{"owner@example.com"}, []byte("Subject: hi\r\n\r\nbody\r\n")); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	sender.close()
+
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("connections = %d, want 1", got)
+	}
+	if got := messages.Load(); got != 3 {
+		t.Fatalf("messages = %d, want 3", got)
+	}
+	if len(sender.conns) != 0 {
+		t.Fatalf("close left %d connections open", len(sender.conns))
+	}
+}
+
+func TestBatchKeepsAccountsOnOneRelayApart(t *testing.T) {
+	addr, conns, messages := serveFakeSMTP(t)
+	batch, done := EmailService{}.Batch()
+	var dialed []string
+	batch.sender.(*reusingSMTPSender).dial = func(settings models.Settings) (*smtp.Client, error) {
+		dialed = append(dialed, settings.UserID+":"+settings.SMTPPass)
+		return smtp.Dial(addr)
+	}
+
+	// Two accounts on the same relay with the same username, as when a
+	// shared mailbox is configured twice; each must log in with its own
+	// password.
+	first := models.Settings{UserID: "u1", SMTPHost: "127.0.0.1", SMTPPort: "25", SMTPUser: "shared", SMTPPass: "one"}
+	second := first
+	second.UserID, second.SMTPPass = "u2", "two"
+	for _, settings := range []models.Settings{first, second, first, second} {
+		if err := batch.SendPlain(settings, []string{"owner@example.com"}, "Reminder", "body"); err != nil {
+			t.Fatalf("send for %s: %v", settings.UserID, err)
+		}
+	}
+	done()
+
+	if got := conns.Load(); got != 2 {
+		t.Fatalf("connections = %d, want one per account", got)
+	}
+	if got := messages.Load(); got != 4 {
+		t.Fatalf("messages = %d, want 4", got)
+	}
+	if len(dialed) != 2 || dialed[0] != "u1:one" || dialed[1] != "u2:two" {
+		t.Fatalf("dialed = %v, want u1 and u2 with their own passwords", dialed)
+	}
+}
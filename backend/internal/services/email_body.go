@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/quotedprintable"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+)
+
+// maxSMTPLineLength is the RFC 5321 limit on a line, excluding CRLF.
+// Servers may wrap, truncate or reject longer lines.
+const maxSMTPLineLength = 998
+
+// bodyTransferEncoding chooses the Content-Transfer-Encoding for a text
+// part. In auto mode body is sent as is only when 7bit can carry it.
+func (s EmailService) bodyTransferEncoding(body string) string {
+	switch s.bodyEncoding {
+	case common.SMTPBodyEncodingQuotedPrintable, common.SMTPBodyEncodingBase64:
+		return s.bodyEncoding
+	}
+	if is7bitSafe(body) {
+		return "7bit"
+	}
+	return common.SMTPBodyEncodingQuotedPrintable
+}
+
+// is7bitSafe reports whether body is ASCII with no line over the SMTP limit.
+func is7bitSafe(body string) bool {
+	for i := 0; i < len(body); i++ {
+		if body[i] >= 0x80 || body[i] == 0 {
+			return false
+		}
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if len(strings.TrimSuffix(line, "\r")) > maxSMTPLineLength {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTextBody writes the Content-Transfer-Encoding header of a text part,
+// the blank line ending its headers and body in that encoding. Multipart
+// callers end the part with CRLF before the next boundary.
+func (s EmailService) writeTextBody(buf *bytes.Buffer, body string) {
+	encoding := s.bodyTransferEncoding(body)
+	buf.WriteString("Content-Transfer-Encoding: " + encoding + "\r\n\r\n")
+	switch encoding {
+	case common.SMTPBodyEncodingQuotedPrintable:
+		// Writes to a bytes.Buffer cannot fail.
+		qp := quotedprintable.NewWriter(buf)
+		_, _ = qp.Write([]byte(body))
+		_ = qp.Close()
+	case common.SMTPBodyEncodingBase64:
+		writeBase64Lines(buf, []byte(body))
+	default:
+		buf.WriteString(body)
+	}
+}
+
+// writeBase64Lines writes data as base64 wrapped at 76 characters, as RFC
+// 2045 requires.
+func writeBase64Lines(buf *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for i := 0; i < len(encoded); i += 76 {
+		end := i + 76
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		buf.WriteString(encoded[i:end])
+		buf.WriteString("\r\n")
+	}
+}
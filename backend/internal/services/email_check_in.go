@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"log/slog"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+)
+
+// DefaultIMAPPort is used when Settings.IMAPPort is empty; only implicit TLS
+// is supported.
+const DefaultIMAPPort = "993"
+
+// emailCheckInTokenPrefix starts every reply token and is what the poller
+// searches subjects for.
+const emailCheckInTokenPrefix = "AET-"
+
+// emailCheckInMaxPerPoll bounds how many replies one poll processes.
+const emailCheckInMaxPerPoll = 50
+
+var emailCheckInTokenPattern = regexp.MustCompile(`(?i)\bAET-[0-9a-f]{20}\b`)
+
+// EmailCheckInToken returns the reply token for messageID. It is derived
+// from the heartbeat token, so rotating that token also retires old replies.
+func EmailCheckInToken(heartbeatToken, messageID string) string {
+	mac := hmac.New(sha256.New, []byte(heartbeatToken))
+	mac.Write([]byte("email-check-in:" + messageID))
+	return emailCheckInTokenPrefix + hex.EncodeToString(mac.Sum(nil))[:20]
+}
+
+// EmailCheckInService polls the owner's mailbox for replies to reminder
+// emails and records a heartbeat for every message whose token it finds.
+// Heartbeats go through messages, so a decorated port publishes them like
+// any other check-in.
+type EmailCheckInService struct {
+	messages ports.MessageServicePort
+	dial     func(host, port string) (*imapClient, error)
+}
+
+func NewEmailCheckInService(messages ports.MessageServicePort) EmailCheckInService {
+	return EmailCheckInService{messages: messages, dial: func(host, port string) (*imapClient, error) {
+		return dialIMAP(host, port, &tls.Config{ServerName: host})
+	}}
+}
+
+// Poll logs in to settings.IMAPHost with the SMTP credentials and returns how
+// many messages were checked in. Replies are only honoured when they come
+// from the owner address and carry a token of one of the owner's active
+// messages; processed replies are marked \Seen.
+func (s EmailCheckInService) Poll(settings models.Settings) (int, error) {
+	if !settings.EmailCheckInEnabled || settings.IMAPHost == "" || settings.OwnerEmail == "" || settings.HeartbeatToken == "" {
+		return 0, nil
+	}
+
+	var messages []models.Message
+	if err := database.ForTenant(settings.UserID).Where("status = ?", models.StatusActive).Find(&messages).Error; err != nil {
+		return 0, Internal("Failed to fetch messages", err)
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+	byToken := make(map[string]string, len(messages))
+	for _, msg := range messages {
+		byToken[strings.ToLower(EmailCheckInToken(settings.HeartbeatToken, msg.ID))] = msg.ID
+	}
+
+	port := settings.IMAPPort
+	if port == "" {
+		port = DefaultIMAPPort
+	}
+	client, err := s.dial(settings.IMAPHost, port)
+	if err != nil {
+		return 0, Internal("Failed to connect to IMAP server", err)
+	}
+	defer client.Close()
+	if err := client.Login(settings.SMTPUser, settings.SMTPPass); err != nil {
+		return 0, Internal("IMAP login failed", err)
+	}
+	if err := client.Select("INBOX"); err != nil {
+		return 0, Internal("Failed to open IMAP inbox", err)
+	}
+	uids, err := client.SearchUnseenSubject(emailCheckInTokenPrefix)
+	if err != nil {
+		return 0, Internal("IMAP search failed", err)
+	}
+	if len(uids) > emailCheckInMaxPerPoll {
+		uids = uids[len(uids)-emailCheckInMaxPerPoll:]
+	}
+
+	checkedIn := map[string]bool{}
+	for _, uid := range uids {
+		fetched, err := client.Fetch(uid)
+		if err != nil {
+			return len(checkedIn), Internal("IMAP fetch failed", err)
+		}
+		ids := matchEmailCheckIn(fetched.Raw, settings.OwnerEmail, byToken)
+		if ids == nil {
+			continue
+		}
+		for _, id := range ids {
+			if checkedIn[id] {
+				continue
+			}
+			if _, err := s.messages.Heartbeat(settings.UserID, id); err != nil {
+				slog.Error("Email check-in heartbeat failed", "error", err, "message_id", id)
+				continue
+			}
+			checkedIn[id] = true
+		}
+		if err := client.MarkSeen(uid); err != nil {
+			slog.Warn("Failed to mark check-in reply as seen", "error", err, "uid", uid)
+		}
+	}
+	return len(checkedIn), nil
+}
+
+// matchEmailCheckIn returns the message IDs whose tokens appear in raw, or
+// nil when raw is not a reply from owner carrying a known token.
+func matchEmailCheckIn(raw []byte, owner string, byToken map[string]string) []string {
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	from, err := mail.ParseAddress(parsed.Header.Get("From"))
+	if err != nil || !strings.EqualFold(from.Address, strings.TrimSpace(owner)) {
+		return nil
+	}
+	// The reminder itself may land in this inbox when the SMTP account is
+	// the owner's, and a vacation responder would otherwise check in for an
+	// absent owner: only genuine, human-sent replies count.
+	if parsed.Header.Get("In-Reply-To") == "" && parsed.Header.Get("References") == "" {
+		return nil
+	}
+	if isAutomatedEmail(parsed.Header) {
+		return nil
+	}
+
+	subject := parsed.Header.Get("Subject")
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+		subject = decoded
+	}
+	var ids []string
+	seen := map[string]bool{}
+	for _, match := range emailCheckInTokenPattern.FindAllString(subject+"\n"+string(raw), -1) {
+		id, ok := byToken[strings.ToLower(match)]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func isAutomatedEmail(h mail.Header) bool {
+	if v := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted"))); v != "" && v != "no" {
+		return true
+	}
+	if h.Get("X-Autoreply") != "" || h.Get("X-Autorespond") != "" {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(h.Get("Precedence"))) {
+	case "bulk", "junk", "list", "auto_reply":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+)
+
+// fakeIMAPServer answers the commands EmailCheckInService sends, serving
+// mailbox keyed by UID, and records which UIDs were marked \Seen.
+func fakeIMAPServer(t *testing.T, conn net.Conn, mailbox map[uint32]string, seen chan<- uint32) {
+	t.Helper()
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK fake IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch {
+		case strings.HasPrefix(cmd, "LOGIN "):
+			if cmd != `LOGIN "owner@example.com" "secret"` {
+				fmt.Fprintf(conn, "%s NO bad credentials\r\n", tag)
+				continue
+			}
+		case strings.HasPrefix(cmd, "UID SEARCH "):
+			var uids []string
+			for uid := range mailbox {
+				uids = append(uids, fmt.Sprint(uid))
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(cmd, "UID FETCH "):
+			var uid uint32
+			fmt.Sscanf(cmd, "UID FETCH %d", &uid)
+			raw := mailbox[uid]
+			fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, len(raw), raw)
+		case strings.HasPrefix(cmd, "UID STORE "):
+			var uid uint32
+			fmt.Sscanf(cmd, "UID STORE %d", &uid)
+			seen <- uid
+		case cmd == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK\r\n", tag)
+			return
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func checkInReply(from, subject string, extraHeaders ...string) string {
+	headers := []string{
+		"From: " + from,
+		"To: aeterna@example.com",
+		"Subject: " + subject,
+		"In-Reply-To: <reminder@example.com>",
+	}
+	headers = append(headers, extraHeaders...)
+	return strings.Join(headers, "\r\n") + "\r\n\r\nI'm fine.\r\n"
+}
+
+func TestEmailCheckInServicePollRecordsHeartbeatFromOwnerReply(t *testing.T) {
+	db := setupTestDB(t)
+	stale := time.Now().Add(-2 * time.Hour).UTC()
+	for _, id := range []string{"m-reply", "m-other"} {
+		if err := db.Create(&models.Message{
+			ID: id, UserID: "u-reply", Content: "x", KeyFragment: "v1",
+			ManagementToken: "tok-" + id, RecipientEmail: "a@a.com",
+			TriggerDuration: 180, LastSeen: stale, Status: models.StatusActive,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings := models.Settings{
+		UserID: "u-reply", OwnerEmail: "owner@example.com", HeartbeatToken: "hb-token",
+		SMTPUser: "owner@example.com", SMTPPass: "secret",
+		EmailCheckInEnabled: true, IMAPHost: "imap.example.com",
+	}
+	token := EmailCheckInToken(settings.HeartbeatToken, "m-reply")
+	mailbox := map[uint32]string{
+		1: checkInReply("Owner <owner@example.com>", "Re: Check-in required ["+token+"]"),
+		2: checkInReply("mallory@example.com", "Re: Check-in required ["+EmailCheckInToken(settings.HeartbeatToken, "m-other")+"]"),
+		3: checkInReply("owner@example.com", "Auto: Check-in required ["+EmailCheckInToken(settings.HeartbeatToken, "m-other")+"]", "Auto-Submitted: auto-replied"),
+	}
+	seen := make(chan uint32, len(mailbox))
+	stream := NewEventStreamService()
+	events, _, cancel, err := stream.Subscribe("u-reply", "web", "sess-reply")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+	svc := EmailCheckInService{messages: NewNotifyingMessageService(MessageService{}, stream), dial: func(host, port string) (*imapClient, error) {
+		if host != "imap.example.com" || port != DefaultIMAPPort {
+			t.Fatalf("unexpected IMAP address %s:%s", host, port)
+		}
+		client, server := net.Pipe()
+		go fakeIMAPServer(t, server, mailbox, seen)
+		return newIMAPClient(client)
+	}}
+
+	n, err := svc.Poll(settings)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected one message checked in, got %d", n)
+	}
+	close(seen)
+	var marked []uint32
+	for uid := range seen {
+		marked = append(marked, uid)
+	}
+	if len(marked) != 1 || marked[0] != 1 {
+		t.Fatalf("expected only the genuine reply to be marked seen, got %v", marked)
+	}
+
+	var reply, other models.Message
+	db.First(&reply, "id = ?", "m-reply")
+	db.First(&other, "id = ?", "m-other")
+	if !reply.LastSeen.After(stale) {
+		t.Fatal("expected the replied-to message to be checked in")
+	}
+	if other.LastSeen.After(stale.Add(time.Second)) {
+		t.Fatal("spoofed and automated replies must not check in")
+	}
+	waitForRealtimeEventType(t, events, ports.EventTypeMessagesChanged, 2*time.Second)
+}
+
+func TestMatchEmailCheckInRequiresReplyHeaders(t *testing.T) {
+	token := EmailCheckInToken("hb", "m1")
+	byToken := map[string]string{strings.ToLower(token): "m1"}
+
+	reminder := "From: owner@example.com\r\nSubject: Check-in required [" + token + "]\r\n\r\nbody\r\n"
+	if ids := matchEmailCheckIn([]byte(reminder), "owner@example.com", byToken); ids != nil {
+		t.Fatalf("the reminder itself must not count as a reply, got %v", ids)
+	}
+
+	reply := checkInReply("OWNER@example.com", "=?UTF-8?Q?R=C3=A9:_Check-in_required_["+token+"]?=")
+	if ids := matchEmailCheckIn([]byte(reply), "owner@example.com", byToken); len(ids) != 1 || ids[0] != "m1" {
+		t.Fatalf("expected encoded reply subject to match, got %v", ids)
+	}
+}
+
+func TestSettingsSaveValidatesEmailCheckIn(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := SettingsService{}
+
+	if err := svc.Save("u1", models.Settings{EmailCheckInEnabled: true, OwnerEmail: "owner@example.com"}); err == nil {
+		t.Fatal("expected missing IMAP host to be rejected")
+	}
+	if err := svc.Save("u1", models.Settings{IMAPHost: "imap.example.com", IMAPPort: "99999"}); err == nil {
+		t.Fatal("expected invalid IMAP port to be rejected")
+	}
+	if err := svc.Save("u1", models.Settings{IMAPHost: "imap://example.com"}); err == nil {
+		t.Fatal("expected URL-style IMAP host to be rejected")
+	}
+	if err := svc.Save("u1", models.Settings{EmailCheckInEnabled: true, OwnerEmail: "owner@example.com", IMAPHost: "imap.example.com"}); err != nil {
+		t.Fatalf("expected valid email check-in settings to save: %v", err)
+	}
+}
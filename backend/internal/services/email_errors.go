@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+)
+
+// SMTPError describes a failed SMTP exchange. Permanent is true for 5xx
+// replies (bad recipient, rejected credentials, policy refusal) that will not
+// succeed on retry; transient 4xx replies and network failures leave it false.
+type SMTPError struct {
+	Stage     string
+	Code      int
+	Permanent bool
+	Err       error
+}
+
+func (e *SMTPError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Stage, e.Err)
+}
+
+func (e *SMTPError) Unwrap() error {
+	return e.Err
+}
+
+// classifySMTPError wraps err in an SMTPError, reading the reply code from
+// net/smtp's textproto errors when the server produced one.
+func classifySMTPError(stage string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var existing *SMTPError
+	if errors.As(err, &existing) {
+		return err
+	}
+	classified := &SMTPError{Stage: stage, Err: err}
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		classified.Code = protoErr.Code
+		classified.Permanent = protoErr.Code >= 500 && protoErr.Code < 600
+	}
+	return classified
+}
+
+// IsPermanentSMTPError reports whether err carries a permanent SMTP failure.
+func IsPermanentSMTPError(err error) bool {
+	var smtpErr *SMTPError
+	return errors.As(err, &smtpErr) && smtpErr.Permanent
+}
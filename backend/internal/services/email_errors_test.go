@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"net/textproto"
+	"testing"
+)
+
+func TestClassifySMTPError_ReplyCodes(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		code      int
+		permanent bool
+	}{
+		{"mailbox full", &textproto.Error{Code: 452, Msg: "mailbox full"}, 452, false},
+		{"unknown user", &textproto.Error{Code: 550, Msg: "no such user"}, 550, true},
+		{"network", errors.New("connection reset"), 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := classifySMTPError("RCPT TO", tc.err)
+			var smtpErr *SMTPError
+			if !errors.As(err, &smtpErr) {
+				t.Fatalf("expected *SMTPError, got %T", err)
+			}
+			if smtpErr.Code != tc.code || smtpErr.Permanent != tc.permanent {
+				t.Fatalf("got code=%d permanent=%v, want code=%d permanent=%v", smtpErr.Code, smtpErr.Permanent, tc.code, tc.permanent)
+			}
+		})
+	}
+}
+
+func TestClassifySMTPError_PassesThroughClassifiedAndNil(t *testing.T) {
+	err := classifySMTPError("auth", &SMTPError{Stage: "auth", Err: errors.New("kept")})
+	if IsPermanentSMTPError(err) {
+		t.Fatal("already classified error must be returned unchanged")
+	}
+	if classifySMTPError("DATA", nil) != nil {
+		t.Fatal("nil error must stay nil")
+	}
+}
+
+func TestSendWithRetry_StopsOnPermanentError(t *testing.T) {
+	calls := 0
+	err := EmailService{}.sendWithRetry(func() error {
+		calls++
+		return classifySMTPError("RCPT TO", &textproto.Error{Code: 550, Msg: "no such user"})
+	})
+	if err == nil || !IsPermanentSMTPError(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
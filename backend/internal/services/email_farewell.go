@@ -2,9 +2,7 @@ package services
 
 import (
 	"bytes"
-	"encoding/base64"
 	"fmt"
-	"mime"
 	"strings"
 
 	"github.com/alpyxn/aeterna/backend/internal/models"
@@ -65,10 +63,7 @@ func (s EmailService) sendFarewellLetterWithBodies(settings models.Settings, rec
 	if from == "" {
 		from = settings.SMTPUser
 	}
-	fromName := settings.SMTPFromName
-	if fromName == "" {
-		fromName = "Aeterna"
-	}
+	fromName := s.senderName(settings)
 
 	from = sanitizeEmailHeader(from)
 	fromName = sanitizeEmailHeader(fromName)
@@ -80,9 +75,9 @@ func (s EmailService) sendFarewellLetterWithBodies(settings models.Settings, rec
 
 	var buf bytes.Buffer
 
-	buf.WriteString(fmt.Sprintf("From: %s <%s>\r\n", fromName, from))
+	buf.WriteString(fmt.Sprintf("From: %s <%s>\r\n", encodeHeaderWord(fromName), from))
 	buf.WriteString(fmt.Sprintf("To: %s\r\n", recipient))
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", encodeHeaderWord(subject)))
 	buf.WriteString("MIME-Version: 1.0\r\n")
 
 	if len(attachments) > 0 {
@@ -92,31 +87,24 @@ func (s EmailService) sendFarewellLetterWithBodies(settings models.Settings, rec
 		buf.WriteString(fmt.Sprintf("--%s\r\n", outerBoundary))
 		buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n\r\n", altBoundary))
 
-		writeAlternativeParts(&buf, altBoundary, plainBody, htmlBody)
+		s.writeAlternativeParts(&buf, altBoundary, plainBody, htmlBody)
 
 		buf.WriteString(fmt.Sprintf("--%s--\r\n", altBoundary))
 		buf.WriteString("\r\n")
 
 		for _, att := range attachments {
+			contentType, disposition := attachmentHeaders(att)
 			buf.WriteString(fmt.Sprintf("--%s\r\n", outerBoundary))
-			buf.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", att.MimeType, mime.QEncoding.Encode("utf-8", att.Filename)))
+			buf.WriteString(fmt.Sprintf("Content-Type: %s\r\n", contentType))
 			buf.WriteString("Content-Transfer-Encoding: base64\r\n")
-			buf.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n\r\n", mime.QEncoding.Encode("utf-8", att.Filename)))
-			encoded := base64.StdEncoding.EncodeToString(att.Data)
-			for i := 0; i < len(encoded); i += 76 {
-				end := i + 76
-				if end > len(encoded) {
-					end = len(encoded)
-				}
-				buf.WriteString(encoded[i:end])
-				buf.WriteString("\r\n")
-			}
+			buf.WriteString(fmt.Sprintf("Content-Disposition: %s\r\n\r\n", disposition))
+			writeBase64Lines(&buf, att.Data)
 		}
 		buf.WriteString(fmt.Sprintf("--%s--\r\n", outerBoundary))
 	} else {
 		// multipart/alternative only
 		buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n\r\n", altBoundary))
-		writeAlternativeParts(&buf, altBoundary, plainBody, htmlBody)
+		s.writeAlternativeParts(&buf, altBoundary, plainBody, htmlBody)
 		buf.WriteString(fmt.Sprintf("--%s--\r\n", altBoundary))
 	}
 
@@ -124,16 +112,14 @@ func (s EmailService) sendFarewellLetterWithBodies(settings models.Settings, rec
 	return s.sendRaw(settings, from, []string{recipient}, message)
 }
 
-func writeAlternativeParts(buf *bytes.Buffer, boundary, plainBody, htmlBody string) {
+func (s EmailService) writeAlternativeParts(buf *bytes.Buffer, boundary, plainBody, htmlBody string) {
 	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
 	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-	buf.WriteString("Content-Transfer-Encoding: 7bit\r\n\r\n")
-	buf.WriteString(plainBody)
+	s.writeTextBody(buf, plainBody)
 	buf.WriteString("\r\n")
 
 	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
 	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
-	buf.WriteString("Content-Transfer-Encoding: 7bit\r\n\r\n")
-	buf.WriteString(htmlBody)
+	s.writeTextBody(buf, htmlBody)
 	buf.WriteString("\r\n")
 }
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestAppendEmailFooter(t *testing.T) {
+	if got := AppendEmailFooter(models.Settings{}, "body"); got != "body\n\n---\nSent by Aeterna" {
+		t.Fatalf("expected default footer, got %q", got)
+	}
+	custom := "With love from the family archive"
+	if got := AppendEmailFooter(models.Settings{FooterText: &custom}, "body"); got != "body\n\n---\n"+custom {
+		t.Fatalf("expected custom footer, got %q", got)
+	}
+	empty := ""
+	if got := AppendEmailFooter(models.Settings{FooterText: &empty}, "body"); got != "body" {
+		t.Fatalf("expected footer removed, got %q", got)
+	}
+
+	_, body := triggeredMessageEmail(models.Settings{FooterText: &empty}, "hello")
+	if strings.Contains(body, "Aeterna") {
+		t.Fatalf("expected delivered message without tool name, got %q", body)
+	}
+}
+
+func TestSettingsSaveKeepsFooterWhenOmitted(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := SettingsService{}
+
+	empty := ""
+	if err := svc.Save("u1", models.Settings{FooterText: &empty}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := svc.Save("u1", models.Settings{OwnerEmail: "owner@example.com"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved, err := svc.Get("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.FooterText == nil || *saved.FooterText != "" {
+		t.Fatalf("expected removed footer to survive a save without footer_text, got %v", saved.FooterText)
+	}
+
+	long := strings.Repeat("a", maxFooterTextLength+1)
+	if err := svc.Save("u1", models.Settings{FooterText: &long}); err == nil {
+		t.Fatal("expected overlong footer to be rejected")
+	}
+}
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+type capturedMail struct {
+	from       string
+	recipients []string
+	message    []byte
+}
+
+type recordingMailSender struct {
+	sent []capturedMail
+}
+
+func (r *recordingMailSender) Send(_ models.Settings, from string, recipients []string, message []byte) error {
+	r.sent = append(r.sent, capturedMail{from: from, recipients: recipients, message: message})
+	return nil
+}
+
+var mimeTestSettings = models.Settings{
+	SMTPHost: "smtp.example.com", SMTPPort: "587",
+	SMTPUser: "mailer", SMTPFrom: "mailer@example.com", OwnerName: "Ada",
+}
+
+func TestSendPlain_MessageBytes(t *testing.T) {
+	sender := &recordingMailSender{}
+	svc := EmailService{sender: sender}
+	if err := svc.SendPlain(mimeTestSettings, []string{"a@example.com", "b@example.com\r\nBcc: x@evil.test"}, "Grüße", "hello"); err != nil {
+		t.Fatalf("SendPlain: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected one message, got %d", len(sender.sent))
+	}
+	got := sender.sent[0]
+	if got.from != "mailer@example.com" || len(got.recipients) != 2 || got.recipients[1] != "b@example.comBcc: x@evil.test" {
+		t.Fatalf("unexpected envelope: %+v", got)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(got.message))
+	if err != nil {
+		t.Fatalf("message does not parse: %v", err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Fatal("header injection through a recipient produced a Bcc header")
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Grüße" {
+		t.Fatalf("Subject = %q (%v), want RFC 2047 encoded Grüße", msg.Header.Get("Subject"), err)
+	}
+	if from := msg.Header.Get("From"); from != "Ada <mailer@example.com>" {
+		t.Fatalf("From = %q", from)
+	}
+	if ct := msg.Header.Get("Content-Type"); ct != "text/plain; charset=UTF-8" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if string(body) != "hello" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestSendWithAttachments_MultipartStructure(t *testing.T) {
+	sender := &recordingMailSender{}
+	svc := EmailService{sender: sender}
+	data := bytes.Repeat([]byte{0x00, 0xff, 0x10, 'a'}, 100)
+	attachments := []EmailAttachment{
+		{Filename: "notes.txt", MimeType: "text/plain", Data: []byte("plain file")},
+		{Filename: "fotoğraf.bin", MimeType: "application/octet-stream", Data: data},
+	}
+	if err := svc.SendWithAttachments(mimeTestSettings, []string{"a@example.com"}, "Files", "see attached", attachments); err != nil {
+		t.Fatalf("SendWithAttachments: %v", err)
+	}
+	raw := sender.sent[0].message
+
+	for i, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line %d exceeds the RFC 5322 limit", i)
+		}
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("message does not parse: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" || params["boundary"] == "" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	text, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("text part: %v", err)
+	}
+	if text.Header.Get("Content-Type") != "text/plain; charset=UTF-8" {
+		t.Fatalf("text part Content-Type = %q", text.Header.Get("Content-Type"))
+	}
+	textBody, _ := io.ReadAll(text)
+	if strings.TrimRight(string(textBody), "\r\n") != "see attached" {
+		t.Fatalf("text body = %q", textBody)
+	}
+
+	for _, want := range attachments {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("attachment %s: %v", want.Filename, err)
+		}
+		if enc := part.Header.Get("Content-Transfer-Encoding"); enc != "base64" {
+			t.Fatalf("attachment %s Content-Transfer-Encoding = %q", want.Filename, enc)
+		}
+		disposition, dparams, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		if err != nil || disposition != "attachment" {
+			t.Fatalf("attachment %s Content-Disposition = %q (%v)", want.Filename, part.Header.Get("Content-Disposition"), err)
+		}
+		if dparams["filename"] != want.Filename {
+			t.Fatalf("filename = %q, want %q", dparams["filename"], want.Filename)
+		}
+		encoded, _ := io.ReadAll(part)
+		for _, line := range strings.Split(strings.TrimRight(string(encoded), "\r\n"), "\r\n") {
+			if len(line) > 76 {
+				t.Fatalf("base64 line of %d chars exceeds 76", len(line))
+			}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+		if err != nil || !bytes.Equal(decoded, want.Data) {
+			t.Fatalf("attachment %s does not round-trip (%v)", want.Filename, err)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Fatalf("expected the closing boundary after the attachments, got %v", err)
+	}
+}
+
+func TestSendTriggeredMessage_UsesPlainMessageWithoutAttachments(t *testing.T) {
+	sender := &recordingMailSender{}
+	svc := EmailService{sender: sender}
+	msg := models.Message{ID: "m1", RecipientEmail: "a@example.com, b@example.com"}
+	if err := svc.SendTriggeredMessage(mimeTestSettings, msg, nil); err != nil {
+		t.Fatalf("SendTriggeredMessage: %v", err)
+	}
+	got := sender.sent[0]
+	if len(got.recipients) != 2 {
+		t.Fatalf("recipients = %v", got.recipients)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(got.message))
+	if err != nil {
+		t.Fatalf("message does not parse: %v", err)
+	}
+	if parsed.Header.Get("Subject") != "A message from Ada" || parsed.Header.Get("To") != "a@example.com, b@example.com" {
+		t.Fatalf("unexpected headers: %v", parsed.Header)
+	}
+	if !strings.HasPrefix(parsed.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected a single-part message, got %q", parsed.Header.Get("Content-Type"))
+	}
+}
+
+func TestSendWithAttachments_FilenamesArriveIntact(t *testing.T) {
+	cases := []struct {
+		name     string
+		filename string
+		mimeType string
+		header   string
+	}{
+		{"plain", "notes.txt", "text/plain", `attachment; filename=notes.txt`},
+		{"spaces", "tax return 2025.pdf", "application/pdf", `attachment; filename="tax return 2025.pdf"`},
+		{"quotes", `the "real" will.txt`, "text/plain", `attachment; filename="the \"real\" will.txt"`},
+		{"backslash", `a\b.txt`, "text/plain", `attachment; filename="a\\b.txt"`},
+		{"unicode", "fotoğraf ünï.png", "image/png", `attachment; filename*=utf-8''foto%C4%9Fraf%20%C3%BCn%C3%AF.png`},
+		{"mime type with params", "data.csv", "text/csv; charset=utf-8", `attachment; filename=data.csv`},
+		{"invalid mime type", "blob", "not a type", `attachment; filename=blob`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := &recordingMailSender{}
+			svc := EmailService{sender: sender}
+			att := EmailAttachment{Filename: tc.filename, MimeType: tc.mimeType, Data: []byte("x")}
+			if err := svc.SendWithAttachments(mimeTestSettings, []string{"a@example.com"}, "Files", "body", []EmailAttachment{att}); err != nil {
+				t.Fatalf("SendWithAttachments: %v", err)
+			}
+			msg, err := mail.ReadMessage(bytes.NewReader(sender.sent[0].message))
+			if err != nil {
+				t.Fatalf("message does not parse: %v", err)
+			}
+			_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			reader := multipart.NewReader(msg.Body, params["boundary"])
+			if _, err := reader.NextPart(); err != nil {
+				t.Fatal(err)
+			}
+			part, err := reader.NextPart()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := part.Header.Get("Content-Disposition"); got != tc.header {
+				t.Fatalf("Content-Disposition = %s, want %s", got, tc.header)
+			}
+			if got := part.FileName(); got != tc.filename {
+				t.Fatalf("FileName() = %q, want %q", got, tc.filename)
+			}
+			_, typeParams, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if err != nil || typeParams["name"] != tc.filename {
+				t.Fatalf("Content-Type name = %q (%v), want %q", typeParams["name"], err, tc.filename)
+			}
+		})
+	}
+}
+
+type flakyMailSender struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyMailSender) Send(models.Settings, string, []string, []byte) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func TestSendWithRetry_UsesConfiguredAttempts(t *testing.T) {
+	cfg := config.Config{SMTP: config.SMTPConfig{MaxAttempts: 5, RetryBaseMS: 0}}
+
+	sender := &flakyMailSender{failures: 4, err: errors.New("connection reset")}
+	svc := NewEmailService(cfg)
+	svc.sender = sender
+	if err := svc.SendPlain(mimeTestSettings, []string{"a@example.com"}, "s", "b"); err != nil {
+		t.Fatalf("expected the fifth attempt to succeed: %v", err)
+	}
+	if sender.calls != 5 {
+		t.Fatalf("calls = %d, want 5", sender.calls)
+	}
+
+	sender = &flakyMailSender{failures: 10, err: errors.New("connection reset")}
+	svc.sender = sender
+	if err := svc.SendPlain(mimeTestSettings, []string{"a@example.com"}, "s", "b"); err == nil {
+		t.Fatal("expected an error once every attempt failed")
+	}
+	if sender.calls != 5 {
+		t.Fatalf("calls = %d, want SMTP_MAX_ATTEMPTS (5)", sender.calls)
+	}
+
+	if attempts, base := (EmailService{}).retryPolicy(); attempts != 3 || base != 500*time.Millisecond {
+		t.Fatalf("zero-value policy = %d, %s; want the defaults", attempts, base)
+	}
+}
+
+func TestTextBodyEncoding_LongAndUnicodeLines(t *testing.T) {
+	longLine := strings.Repeat("All my love, ", 400)[:5000]
+	assertLineLimit := func(t *testing.T, raw []byte) {
+		t.Helper()
+		for i, line := range strings.Split(string(raw), "\r\n") {
+			if len(line) > 998 {
+				t.Fatalf("line %d is %d octets, over the RFC 5321 limit", i, len(line))
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		setting  string
+		body     string
+		encoding string
+	}{
+		{"short ascii stays 7bit", "", "hello\nworld", "7bit"},
+		{"long line", "", longLine, "quoted-printable"},
+		{"non-ascii", "auto", "Grüße, fotoğraf", "quoted-printable"},
+		{"forced base64", "base64", "hello", "base64"},
+		{"forced quoted-printable", "quoted-printable", "hello", "quoted-printable"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sender := &recordingMailSender{}
+			svc := EmailService{sender: sender, bodyEncoding: tc.setting}
+
+			if err := svc.SendPlain(mimeTestSettings, []string{"a@example.com"}, "s", tc.body); err != nil {
+				t.Fatalf("SendPlain: %v", err)
+			}
+			raw := sender.sent[0].message
+			assertLineLimit(t, raw)
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("message does not parse: %v", err)
+			}
+			if got := msg.Header.Get("Content-Transfer-Encoding"); got != tc.encoding {
+				t.Fatalf("Content-Transfer-Encoding = %q, want %q", got, tc.encoding)
+			}
+			if got := decodeTestBody(t, tc.encoding, msg.Body); got != tc.body {
+				t.Fatalf("SendPlain body does not round-trip: got %d bytes, want %d", len(got), len(tc.body))
+			}
+
+			if err := svc.SendWithAttachments(mimeTestSettings, []string{"a@example.com"}, "s", tc.body, []EmailAttachment{{Filename: "a.txt", MimeType: "text/plain", Data: []byte("x")}}); err != nil {
+				t.Fatalf("SendWithAttachments: %v", err)
+			}
+			raw = sender.sent[1].message
+			assertLineLimit(t, raw)
+			msg, err = mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("message does not parse: %v", err)
+			}
+			_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			part, err := multipart.NewReader(msg.Body, params["boundary"]).NextRawPart()
+			if err != nil {
+				t.Fatalf("text part: %v", err)
+			}
+			if got := part.Header.Get("Content-Transfer-Encoding"); got != tc.encoding {
+				t.Fatalf("text part Content-Transfer-Encoding = %q, want %q", got, tc.encoding)
+			}
+			if got := strings.TrimRight(decodeTestBody(t, tc.encoding, part), "\r\n"); got != tc.body {
+				t.Fatalf("text part does not round-trip: got %d bytes, want %d", len(got), len(tc.body))
+			}
+		})
+	}
+}
+
+func decodeTestBody(t *testing.T, encoding string, r io.Reader) string {
+	t.Helper()
+	switch encoding {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s body: %v", encoding, err)
+	}
+	return strings.ReplaceAll(string(body), "\r\n", "\n")
+}
+
+func TestSendTriggeredMessage_DeliverAsPDF(t *testing.T) {
+	initTestKeyManager(t)
+	settings := mimeTestSettings
+	settings.DeliverAsPDF = true
+
+	send := func(t *testing.T, content string) *mail.Message {
+		t.Helper()
+		encrypted, err := (CryptoService{}).EncryptWithContext(content, MessageContentContext("m1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender := &recordingMailSender{}
+		msg := models.Message{ID: "m1", RecipientEmail: "a@example.com", Content: encrypted}
+		if err := (EmailService{sender: sender}).SendTriggeredMessage(settings, msg, nil); err != nil {
+			t.Fatalf("SendTriggeredMessage: %v", err)
+		}
+		parsed, err := mail.ReadMessage(bytes.NewReader(sender.sent[0].message))
+		if err != nil {
+			t.Fatalf("message does not parse: %v", err)
+		}
+		return parsed
+	}
+
+	parsed := send(t, "Dear friend,\n\nCafé au lait, as always.")
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	note, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	noteBody, _ := io.ReadAll(note)
+	if !strings.Contains(string(noteBody), "attached as a PDF") || strings.Contains(string(noteBody), "Dear friend") {
+		t.Fatalf("expected a short note instead of the letter, got %q", noteBody)
+	}
+	letter, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.FileName() != "letter.pdf" {
+		t.Fatalf("attachment = %q, want letter.pdf", letter.FileName())
+	}
+	data, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, letter))
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Fatalf("attachment is not a PDF: %q", data[:min(len(data), 16)])
+	}
+
+	// Without PDF_FONT_FILE the built-in font cannot show ğ, so the letter
+	// goes out inline rather than with missing characters.
+	parsed = send(t, "Fotoğraflar ekte.")
+	if !strings.HasPrefix(parsed.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected an inline fallback, got %q", parsed.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if !strings.Contains(string(body), "Fotoğraflar ekte.") {
+		t.Fatalf("inline fallback lost the content: %q", body)
+	}
+}
+
+func TestSendTriggeredMessage_UsesMessageSubject(t *testing.T) {
+	initTestKeyManager(t)
+	encrypted, err := (CryptoService{}).EncryptWithContext("hello", MessageContentContext("m1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for subject, want := range map[string]string{"": "A message from Ada", "Ein Brief von Jürgen": "Ein Brief von Jürgen"} {
+		sender := &recordingMailSender{}
+		msg := models.Message{ID: "m1", RecipientEmail: "a@example.com", Content: encrypted, Subject: subject}
+		if err := (EmailService{sender: sender}).SendTriggeredMessage(mimeTestSettings, msg, nil); err != nil {
+			t.Fatalf("SendTriggeredMessage: %v", err)
+		}
+		parsed, err := mail.ReadMessage(bytes.NewReader(sender.sent[0].message))
+		if err != nil {
+			t.Fatalf("message does not parse: %v", err)
+		}
+		got, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		if err != nil || got != want {
+			t.Fatalf("Subject = %q (%v), want %q", got, err, want)
+		}
+	}
+}
+
+func TestSendTriggeredMessage_LargeContent(t *testing.T) {
+	initTestKeyManager(t)
+	content := strings.Repeat("A long final letter. ", 200)
+	encrypted, err := (CryptoService{}).EncryptWithContext(content, MessageContentContext("m1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := mimeTestSettings
+	settings.OwnerEmail = "owner@example.com"
+	msg := models.Message{ID: "m1", RecipientEmail: "a@example.com", Content: encrypted}
+
+	sender := &recordingMailSender{}
+	svc := EmailService{sender: sender, sizeWarningBytes: 1024, sizeWarningNotifyOwner: true, largeContentAction: "text"}
+	if err := svc.SendTriggeredMessage(settings, msg, nil); err != nil {
+		t.Fatalf("SendTriggeredMessage: %v", err)
+	}
+	if len(sender.sent) != 2 {
+		t.Fatalf("expected the delivery and an owner notice, got %d emails", len(sender.sent))
+	}
+	delivered := string(sender.sent[0].message)
+	if !strings.Contains(delivered, "filename=message.txt") || !strings.Contains(delivered, "attached as message.txt") {
+		t.Fatalf("large content should travel as message.txt:\n%s", delivered)
+	}
+	if notice := sender.sent[1]; notice.recipients[0] != "owner@example.com" || !strings.Contains(string(notice.message), "Delivered message was large") {
+		t.Fatalf("unexpected owner notice: %s", notice.message)
+	}
+
+	inline := &recordingMailSender{}
+	svc = EmailService{sender: inline, sizeWarningBytes: 1024, largeContentAction: "inline"}
+	if err := svc.SendTriggeredMessage(settings, msg, nil); err != nil {
+		t.Fatalf("SendTriggeredMessage: %v", err)
+	}
+	if len(inline.sent) != 1 || strings.Contains(string(inline.sent[0].message), "message.txt") {
+		t.Fatalf("inline mode should only warn, got %d emails", len(inline.sent))
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestTriggeredMessageEmailNamesOwner(t *testing.T) {
+	subject, body := triggeredMessageEmail(models.Settings{}, "hello")
+	if subject != "A message for you" || !strings.HasPrefix(body, "Someone has arranged") {
+		t.Fatalf("unexpected anonymous email: %q / %q", subject, body)
+	}
+
+	subject, body = triggeredMessageEmail(models.Settings{OwnerName: "Jane Doe"}, "hello")
+	if subject != "A message from Jane Doe" {
+		t.Fatalf("unexpected subject %q", subject)
+	}
+	if !strings.HasPrefix(body, "Jane Doe has arranged") || !strings.Contains(body, "hello\n\n— Jane Doe") {
+		t.Fatalf("expected owner intro and default sign-off, got %q", body)
+	}
+
+	_, body = triggeredMessageEmail(models.Settings{OwnerName: "Jane Doe", OwnerSignature: "Love always,\nMum"}, "hello")
+	if !strings.Contains(body, "hello\n\nLove always,\nMum") || strings.Contains(body, "— Jane Doe") {
+		t.Fatalf("expected custom signature to replace the default, got %q", body)
+	}
+}
+
+func TestSenderNamePrefersSMTPFromName(t *testing.T) {
+	svc := EmailService{}
+	if got := svc.senderName(models.Settings{}); got != "Aeterna" {
+		t.Fatalf("senderName() = %q, want Aeterna", got)
+	}
+	if got := svc.senderName(models.Settings{OwnerName: "Jane Doe"}); got != "Jane Doe" {
+		t.Fatalf("senderName() = %q, want owner name", got)
+	}
+	if got := svc.senderName(models.Settings{OwnerName: "Jane Doe", SMTPFromName: "Family Vault"}); got != "Family Vault" {
+		t.Fatalf("senderName() = %q, want SMTP from name", got)
+	}
+
+	svc = NewEmailService(config.Config{SMTP: config.SMTPConfig{DefaultFromName: "Notary Office"}})
+	if got := svc.senderName(models.Settings{}); got != "Notary Office" {
+		t.Fatalf("senderName() = %q, want DEFAULT_FROM_NAME", got)
+	}
+	if got := svc.senderName(models.Settings{OwnerName: "Jane Doe"}); got != "Jane Doe" {
+		t.Fatalf("senderName() = %q, want owner name over DEFAULT_FROM_NAME", got)
+	}
+}
+
+func TestSettingsSaveNormalizesOwnerIdentity(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := SettingsService{}
+
+	if err := svc.Save("u1", models.Settings{OwnerName: "Jane\r\nBcc: evil@example.com"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved, err := svc.Get("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.OwnerName != "Jane Bcc: evil@example.com" {
+		t.Fatalf("expected owner name collapsed onto one line, got %q", saved.OwnerName)
+	}
+
+	if err := svc.Save("u1", models.Settings{OwnerName: strings.Repeat("a", maxOwnerNameLength+1)}); err == nil {
+		t.Fatal("expected overlong owner name to be rejected")
+	}
+	if err := svc.Save("u1", models.Settings{OwnerSignature: strings.Repeat("a", maxOwnerSignatureLength+1)}); err == nil {
+		t.Fatal("expected overlong signature to be rejected")
+	}
+}
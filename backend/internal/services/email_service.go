@@ -3,18 +3,88 @@ package services
 import (
 	"bytes"
 	"crypto/tls"
-	"encoding/base64"
 	"errors"
 	"fmt"
+	"log/slog"
 	"mime"
 	"net/smtp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
 	"github.com/alpyxn/aeterna/backend/internal/models"
 )
 
-type EmailService struct{}
+type EmailService struct {
+	// sender delivers assembled messages; nil means smtpSender.
+	sender mailSender
+
+	// maxAttempts and retryBase come from SMTP_MAX_ATTEMPTS and
+	// SMTP_RETRY_BASE_MS; zero values fall back to the defaults.
+	maxAttempts int
+	retryBase   time.Duration
+
+	// bodyEncoding is SMTP_BODY_ENCODING; empty means auto.
+	bodyEncoding string
+	// pdfFontFile is PDF_FONT_FILE; empty uses the built-in font.
+	pdfFontFile string
+
+	// sizeWarningBytes, sizeWarningNotifyOwner and largeContentAction come
+	// from SMTP_SIZE_WARNING_KB, SMTP_SIZE_WARNING_NOTIFY_OWNER and
+	// SMTP_LARGE_CONTENT_ACTION; zero values turn the check off.
+	sizeWarningBytes       int
+	sizeWarningNotifyOwner bool
+	largeContentAction     string
+
+	// defaultFromName is DEFAULT_FROM_NAME; empty means "Aeterna".
+	defaultFromName string
+}
+
+func NewEmailService(cfg config.Config) EmailService {
+	return EmailService{
+		maxAttempts: cfg.SMTP.MaxAttempts,
+		retryBase:   time.Duration(cfg.SMTP.RetryBaseMS) * time.Millisecond,
+
+		bodyEncoding: cfg.SMTP.BodyEncoding,
+		pdfFontFile:  cfg.SMTP.PDFFontFile,
+
+		sizeWarningBytes:       cfg.SMTP.SizeWarningKB * 1024,
+		sizeWarningNotifyOwner: cfg.SMTP.SizeWarningNotifyOwner,
+		largeContentAction:     cfg.SMTP.LargeContentAction,
+
+		defaultFromName: cfg.SMTP.DefaultFromName,
+	}
+}
+
+// retryPolicy returns the effective attempt count and first retry delay.
+func (s EmailService) retryPolicy() (int, time.Duration) {
+	maxAttempts, baseDelay := s.maxAttempts, s.retryBase
+	if maxAttempts <= 0 {
+		maxAttempts = common.DefaultSMTPMaxAttempts
+		baseDelay = common.DefaultSMTPRetryBaseMS * time.Millisecond
+	}
+	return maxAttempts, baseDelay
+}
+
+// mailSender hands a fully assembled RFC 5322 message to a mail server.
+type mailSender interface {
+	Send(settings models.Settings, from string, recipients []string, message []byte) error
+}
+
+// smtpSender delivers over net/smtp: implicit TLS on port 465, STARTTLS
+// (required) on any other port.
+type smtpSender struct{}
+
+func (smtpSender) Send(settings models.Settings, from string, recipients []string, message []byte) error {
+	client, err := dialSMTP(settings)
+	if err != nil {
+		return err
+	}
+	defer closeSMTP(client)
+	return deliverSMTP(client, from, recipients, message)
+}
 
 // EmailAttachment represents a file to be attached to an email
 type EmailAttachment struct {
@@ -32,53 +102,225 @@ func sanitizeEmailHeader(s string) string {
 	return s
 }
 
+// encodeHeaderWord RFC 2047-encodes a header value that contains non-ASCII
+// characters; ASCII-only values are returned unchanged.
+func encodeHeaderWord(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return mime.QEncoding.Encode("utf-8", s)
+		}
+	}
+	return s
+}
+
 func (s EmailService) SendTriggeredMessage(settings models.Settings, msg models.Message, attachments []EmailAttachment) error {
 	recipients := ParseRecipientEmails(msg.RecipientEmail)
 	if len(recipients) == 0 {
 		recipients = []string{msg.RecipientEmail}
 	}
-	subject := "A message for you"
 
 	content := msg.Content
 	if msg.Content != "" {
-		decrypted, err := emailCryptoService.Decrypt(msg.Content)
+		decrypted, err := emailCryptoService.DecryptWithContext(msg.Content, MessageContentContext(msg.ID))
 		if err != nil {
 			return err
 		}
 		content = decrypted
 	}
-	body := fmt.Sprintf(`Someone has arranged for this message to be delivered to you.
+	subject, body := triggeredMessageEmail(settings, content)
+	if msg.Subject != "" {
+		subject = msg.Subject
+	}
+	if settings.DeliverAsPDF {
+		letter, err := s.renderLetterPDF(subject, signedContent(settings, content))
+		if err != nil {
+			slog.Warn("Sending message inline instead of as a PDF", "error", err, "message_id", msg.ID)
+		} else {
+			body = pdfLetterNote(settings)
+			attachments = append([]EmailAttachment{{Filename: "letter.pdf", MimeType: "application/pdf", Data: letter}}, attachments...)
+		}
+	}
+	if !settings.DeliverAsPDF && s.sizeWarningBytes > 0 && len(body) > s.sizeWarningBytes {
+		if moved, ok := s.largeContentAttachment(settings, subject, content, msg.ID); ok {
+			body = attachedContentNote(settings, moved.Filename)
+			attachments = append([]EmailAttachment{moved}, attachments...)
+		}
+	}
+	if card, ok := contactCardAttachment(settings); ok {
+		attachments = append(attachments, card)
+	}
 
----
+	var from string
+	var message []byte
+	var err error
+	if len(attachments) > 0 {
+		from, recipients, message, err = s.multipartMessage(settings, recipients, subject, body, attachments)
+	} else {
+		from, recipients, message, err = s.plainMessage(settings, recipients, subject, body)
+	}
+	if err != nil {
+		return err
+	}
+	oversized := s.sizeWarningBytes > 0 && len(message) > s.sizeWarningBytes
+	if oversized {
+		slog.Warn("Trigger email is larger than SMTP_SIZE_WARNING_KB; some providers clip or reject messages this size",
+			"message_id", msg.ID, "bytes", len(message), "threshold_bytes", s.sizeWarningBytes, "attachments", len(attachments))
+	}
+	if err := s.sendRaw(settings, from, recipients, message); err != nil {
+		return err
+	}
+	if oversized && s.sizeWarningNotifyOwner {
+		s.notifyOwnerOfSize(settings, msg, len(message))
+	}
+	return nil
+}
 
-%s
+// largeContentAttachment packs content as the attachment chosen by
+// SMTP_LARGE_CONTENT_ACTION. A PDF that cannot be rendered falls back to
+// text; "inline" returns false.
+func (s EmailService) largeContentAttachment(settings models.Settings, subject, content, messageID string) (EmailAttachment, bool) {
+	switch s.largeContentAction {
+	case common.SMTPLargeContentPDF:
+		letter, err := s.renderLetterPDF(subject, signedContent(settings, content))
+		if err == nil {
+			return EmailAttachment{Filename: "letter.pdf", MimeType: "application/pdf", Data: letter}, true
+		}
+		slog.Warn("Attaching large message as text instead of a PDF", "error", err, "message_id", messageID)
+	case common.SMTPLargeContentText:
+	default:
+		return EmailAttachment{}, false
+	}
+	return EmailAttachment{Filename: "message.txt", MimeType: "text/plain; charset=UTF-8", Data: []byte(signedContent(settings, content))}, true
+}
+
+// attachedContentNote is the email body sent when content too large to show
+// inline travels as filename instead.
+func attachedContentNote(settings models.Settings, filename string) string {
+	body := messageSender(settings) + " has arranged for this message to be delivered to you. It is too long to show in full here, so it is attached as " + filename + "."
+	return AppendEmailFooter(settings, body)
+}
+
+// notifyOwnerOfSize tells the owner a delivered message was large enough
+// that the recipient's provider may have clipped it.
+func (s EmailService) notifyOwnerOfSize(settings models.Settings, msg models.Message, size int) {
+	if settings.OwnerEmail == "" {
+		return
+	}
+	body := fmt.Sprintf("A message to %s was delivered as a %d KB email, over the %d KB warning threshold. Some providers, Gmail among them, clip or reject emails this large, so the recipient may not see all of it.",
+		strings.Join(ParseRecipientEmails(msg.RecipientEmail), ", "), size/1024, s.sizeWarningBytes/1024)
+	if err := s.SendPlain(settings, []string{settings.OwnerEmail}, "Delivered message was large", AppendEmailFooter(settings, body)); err != nil {
+		slog.Error("Failed to send size warning to owner", "error", err, "message_id", msg.ID)
+	}
+}
+
+// DefaultEmailFooter closes every email unless Settings.FooterText overrides it.
+const DefaultEmailFooter = "Sent by Aeterna"
+
+// AppendEmailFooter closes body with the owner's footer; an empty FooterText
+// leaves body without one.
+func AppendEmailFooter(settings models.Settings, body string) string {
+	footer := DefaultEmailFooter
+	if settings.FooterText != nil {
+		footer = *settings.FooterText
+	}
+	if footer == "" {
+		return body
+	}
+	return body + "\n\n---\n" + footer
+}
+
+// triggeredMessageEmail builds the subject and body delivered to recipients,
+// naming the owner and signing off when they configured it.
+func triggeredMessageEmail(settings models.Settings, content string) (subject, body string) {
+	subject = "A message for you"
+	if settings.OwnerName != "" {
+		subject = "A message from " + settings.OwnerName
+	}
+	body = fmt.Sprintf(`%s has arranged for this message to be delivered to you.
 
 ---
 
-Sent by Aeterna`, content)
+%s`, messageSender(settings), signedContent(settings, content))
+	return subject, AppendEmailFooter(settings, body)
+}
 
-	if len(attachments) > 0 {
-		return s.SendWithAttachments(settings, recipients, subject, body, attachments)
+// pdfLetterNote is the email body sent with a message delivered as a PDF.
+func pdfLetterNote(settings models.Settings) string {
+	body := messageSender(settings) + " has arranged for this message to be delivered to you. It is attached as a PDF letter."
+	return AppendEmailFooter(settings, body)
+}
+
+func messageSender(settings models.Settings) string {
+	if settings.OwnerName != "" {
+		return settings.OwnerName
+	}
+	return "Someone"
+}
+
+// signedContent closes content with the owner's sign-off, if any.
+func signedContent(settings models.Settings, content string) string {
+	signature := settings.OwnerSignature
+	if signature == "" && settings.OwnerName != "" {
+		signature = "— " + settings.OwnerName
+	}
+	if signature != "" {
+		content += "\n\n" + signature
 	}
-	return s.SendPlain(settings, recipients, subject, body)
+	return content
+}
+
+// senderName is the From display name: SMTPFromName, else OwnerName, else
+// DEFAULT_FROM_NAME.
+func (s EmailService) senderName(settings models.Settings) string {
+	if settings.SMTPFromName != "" {
+		return settings.SMTPFromName
+	}
+	if settings.OwnerName != "" {
+		return settings.OwnerName
+	}
+	return common.WithDefault(s.defaultFromName, common.DefaultFromName)
+}
+
+// attachmentHeaders returns the Content-Type and Content-Disposition values
+// of an attachment part. mime.FormatMediaType quotes and escapes filenames
+// with spaces or quotes, and RFC 2231-encodes (filename*=) those with
+// non-ASCII characters. Filenames are capped at 255 bytes on upload, so
+// the encoded header stays within the line limit without continuations.
+func attachmentHeaders(att EmailAttachment) (contentType, disposition string) {
+	filename := sanitizeEmailHeader(att.Filename)
+	mediaType, params, err := mime.ParseMediaType(att.MimeType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = filename
+	contentType = mime.FormatMediaType(mediaType, params)
+	disposition = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	return contentType, disposition
 }
 
 // SendWithAttachments sends an email with file attachments using MIME multipart/mixed
 func (s EmailService) SendWithAttachments(settings models.Settings, recipients []string, subject, textBody string, attachments []EmailAttachment) error {
+	from, sanitizedRecipients, message, err := s.multipartMessage(settings, recipients, subject, textBody, attachments)
+	if err != nil {
+		return err
+	}
+	return s.sendRaw(settings, from, sanitizedRecipients, message)
+}
+
+// multipartMessage assembles the multipart/mixed message SendWithAttachments
+// sends, returning the envelope sender and recipients with it.
+func (s EmailService) multipartMessage(settings models.Settings, recipients []string, subject, textBody string, attachments []EmailAttachment) (string, []string, []byte, error) {
 	from := settings.SMTPFrom
 	if from == "" {
 		from = settings.SMTPUser
 	}
-	fromName := settings.SMTPFromName
-	if fromName == "" {
-		fromName = "Aeterna"
-	}
+	fromName := s.senderName(settings)
 
 	// Sanitize headers
 	from = sanitizeEmailHeader(from)
 	fromName = sanitizeEmailHeader(fromName)
 	if len(recipients) == 0 {
-		return fmt.Errorf("at least one recipient is required")
+		return "", nil, nil, fmt.Errorf("at least one recipient is required")
 	}
 	sanitizedRecipients := make([]string, 0, len(recipients))
 	for _, recipient := range recipients {
@@ -91,9 +333,9 @@ func (s EmailService) SendWithAttachments(settings models.Settings, recipients [
 	var buf bytes.Buffer
 
 	// Main headers
-	buf.WriteString(fmt.Sprintf("From: %s <%s>\r\n", fromName, from))
+	buf.WriteString(fmt.Sprintf("From: %s <%s>\r\n", encodeHeaderWord(fromName), from))
 	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(sanitizedRecipients, ", ")))
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", encodeHeaderWord(subject)))
 	buf.WriteString("MIME-Version: 1.0\r\n")
 	buf.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\r\n", boundary))
 	buf.WriteString("\r\n")
@@ -101,57 +343,49 @@ func (s EmailService) SendWithAttachments(settings models.Settings, recipients [
 	// Text body part
 	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
 	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-	buf.WriteString("Content-Transfer-Encoding: 7bit\r\n")
-	buf.WriteString("\r\n")
-	buf.WriteString(textBody)
+	s.writeTextBody(&buf, textBody)
 	buf.WriteString("\r\n")
 
 	// Attachment parts
 	for _, att := range attachments {
+		contentType, disposition := attachmentHeaders(att)
 		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		buf.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n",
-			att.MimeType,
-			mime.QEncoding.Encode("utf-8", att.Filename)))
+		buf.WriteString(fmt.Sprintf("Content-Type: %s\r\n", contentType))
 		buf.WriteString("Content-Transfer-Encoding: base64\r\n")
-		buf.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n",
-			mime.QEncoding.Encode("utf-8", att.Filename)))
+		buf.WriteString(fmt.Sprintf("Content-Disposition: %s\r\n", disposition))
 		buf.WriteString("\r\n")
-
-		// Encode file data as base64 with line wrapping (76 chars per line per RFC 2045)
-		encoded := base64.StdEncoding.EncodeToString(att.Data)
-		for i := 0; i < len(encoded); i += 76 {
-			end := i + 76
-			if end > len(encoded) {
-				end = len(encoded)
-			}
-			buf.WriteString(encoded[i:end])
-			buf.WriteString("\r\n")
-		}
+		writeBase64Lines(&buf, att.Data)
 	}
 
 	// Closing boundary
 	buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
 
-	message := buf.Bytes()
-	return s.sendRaw(settings, from, sanitizedRecipients, message)
+	return from, sanitizedRecipients, buf.Bytes(), nil
 }
 
 // SendPlain sends a plain text email
 func (s EmailService) SendPlain(settings models.Settings, recipients []string, subject, body string) error {
+	from, sanitizedRecipients, message, err := s.plainMessage(settings, recipients, subject, body)
+	if err != nil {
+		return err
+	}
+	return s.sendRaw(settings, from, sanitizedRecipients, message)
+}
+
+// plainMessage assembles the text/plain message SendPlain sends, returning
+// the envelope sender and recipients with it.
+func (s EmailService) plainMessage(settings models.Settings, recipients []string, subject, body string) (string, []string, []byte, error) {
 	from := settings.SMTPFrom
 	if from == "" {
 		from = settings.SMTPUser
 	}
-	fromName := settings.SMTPFromName
-	if fromName == "" {
-		fromName = "Aeterna"
-	}
+	fromName := s.senderName(settings)
 
 	// Sanitize headers to prevent header injection
 	from = sanitizeEmailHeader(from)
 	fromName = sanitizeEmailHeader(fromName)
 	if len(recipients) == 0 {
-		return fmt.Errorf("at least one recipient is required")
+		return "", nil, nil, fmt.Errorf("at least one recipient is required")
 	}
 	sanitizedRecipients := make([]string, 0, len(recipients))
 	for _, recipient := range recipients {
@@ -159,32 +393,29 @@ func (s EmailService) SendPlain(settings models.Settings, recipients []string, s
 	}
 	subject = sanitizeEmailHeader(subject)
 
-	headers := fmt.Sprintf("From: %s <%s>\r\n", fromName, from)
-	headers += fmt.Sprintf("To: %s\r\n", strings.Join(sanitizedRecipients, ", "))
-	headers += fmt.Sprintf("Subject: %s\r\n", subject)
-	headers += "MIME-Version: 1.0\r\n"
-	headers += "Content-Type: text/plain; charset=UTF-8\r\n"
-	headers += "\r\n"
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("From: %s <%s>\r\n", encodeHeaderWord(fromName), from))
+	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(sanitizedRecipients, ", ")))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", encodeHeaderWord(subject)))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	s.writeTextBody(&buf, body)
 
-	message := []byte(headers + body)
-	return s.sendRaw(settings, from, sanitizedRecipients, message)
+	return from, sanitizedRecipients, buf.Bytes(), nil
 }
 
 func (s EmailService) sendRaw(settings models.Settings, from string, recipients []string, message []byte) error {
-	addr := settings.SMTPHost + ":" + settings.SMTPPort
-	if settings.SMTPPort == "465" {
-		return s.sendWithRetry(func() error {
-			return s.sendEmailSSL(settings, addr, from, recipients, message)
-		})
+	var sender mailSender = smtpSender{}
+	if s.sender != nil {
+		sender = s.sender
 	}
 	return s.sendWithRetry(func() error {
-		return s.sendEmailSTARTTLS(settings, addr, from, recipients, message)
+		return sender.Send(settings, from, recipients, message)
 	})
 }
 
 func (s EmailService) sendWithRetry(sendFn func() error) error {
-	const maxAttempts = 3
-	baseDelay := 500 * time.Millisecond
+	maxAttempts, baseDelay := s.retryPolicy()
 
 	var lastErr error
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
@@ -194,8 +425,15 @@ func (s EmailService) sendWithRetry(sendFn func() error) error {
 			lastErr = err
 		}
 
+		// 5xx replies (unknown mailbox, rejected credentials) will not
+		// succeed on a later attempt, so only transient failures are retried.
+		if IsPermanentSMTPError(lastErr) {
+			return lastErr
+		}
+
 		if attempt < maxAttempts {
 			backoff := baseDelay * time.Duration(1<<(attempt-1))
+			slog.Warn("SMTP send failed, retrying", "error", lastErr, "attempt", attempt, "max_attempts", maxAttempts, "backoff", backoff.String())
 			time.Sleep(backoff)
 		}
 	}
@@ -211,99 +449,104 @@ func authWithFallback(client *smtp.Client, username, password, host string) erro
 		// Try LOGIN auth as fallback (for Yandex and others)
 		loginAuth := &emailLoginAuth{username, password}
 		if loginErr := client.Auth(loginAuth); loginErr != nil {
-			return fmt.Errorf("auth failed (PLAIN: %v, LOGIN: %v)", err, loginErr)
+			return classifySMTPError("auth", fmt.Errorf("PLAIN: %v, LOGIN: %w", err, loginErr))
 		}
 	}
 	return nil
 }
 
-func (s EmailService) sendEmailSSL(settings models.Settings, addr, from string, recipients []string, message []byte) error {
+// dialSMTP connects and authenticates to the owner's SMTP server: implicit
+// TLS on port 465, otherwise a mandatory STARTTLS upgrade.
+func dialSMTP(settings models.Settings) (*smtp.Client, error) {
+	addr := settings.SMTPHost + ":" + settings.SMTPPort
 	tlsConfig := &tls.Config{ServerName: settings.SMTPHost}
 
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
-	if err != nil {
-		return fmt.Errorf("TLS dial failed: %v", err)
-	}
-	defer conn.Close()
-
-	client, err := smtp.NewClient(conn, settings.SMTPHost)
-	if err != nil {
-		return fmt.Errorf("SMTP client failed: %v", err)
-	}
-	defer func() {
-		_ = client.Quit()
-	}()
-
-	if err = authWithFallback(client, settings.SMTPUser, settings.SMTPPass, settings.SMTPHost); err != nil {
-		return err
-	}
-
-	if err = client.Mail(from); err != nil {
-		return fmt.Errorf("MAIL FROM failed: %v", err)
-	}
-
-	for _, recipient := range recipients {
-		if err = client.Rcpt(recipient); err != nil {
-			return fmt.Errorf("RCPT TO failed for %s: %v", recipient, err)
+	var client *smtp.Client
+	if settings.SMTPPort == "465" {
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, classifySMTPError("TLS dial", err)
+		}
+		client, err = smtp.NewClient(conn, settings.SMTPHost)
+		if err != nil {
+			_ = conn.Close()
+			return nil, classifySMTPError("SMTP client", err)
+		}
+		if err := helloSMTP(client, settings.SMTPHeloName); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		client, err = smtp.Dial(addr)
+		if err != nil {
+			return nil, classifySMTPError("dial", err)
+		}
+		if err := helloSMTP(client, settings.SMTPHeloName); err != nil {
+			return nil, err
+		}
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			closeSMTP(client)
+			return nil, &SMTPError{
+				Stage:     "STARTTLS",
+				Permanent: true,
+				Err:       fmt.Errorf("STARTTLS is required but the SMTP server (%s) does not support it; refusing to send credentials in plaintext", settings.SMTPHost),
+			}
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			closeSMTP(client)
+			return nil, classifySMTPError("STARTTLS", err)
 		}
 	}
 
-	w, err := client.Data()
-	if err != nil {
-		return fmt.Errorf("DATA failed: %v", err)
-	}
-
-	_, err = w.Write(message)
-	if err != nil {
-		return fmt.Errorf("write failed: %v", err)
+	if err := authWithFallback(client, settings.SMTPUser, settings.SMTPPass, settings.SMTPHost); err != nil {
+		closeSMTP(client)
+		return nil, err
 	}
-
-	return w.Close()
+	return client, nil
 }
 
-func (s EmailService) sendEmailSTARTTLS(settings models.Settings, addr, from string, recipients []string, message []byte) error {
-	client, err := smtp.Dial(addr)
-	if err != nil {
-		return fmt.Errorf("dial failed: %v", err)
-	}
-	defer func() {
-		_ = client.Quit()
-	}()
-
-	tlsConfig := &tls.Config{ServerName: settings.SMTPHost}
-	if ok, _ := client.Extension("STARTTLS"); ok {
-		if err = client.StartTLS(tlsConfig); err != nil {
-			return fmt.Errorf("STARTTLS failed: %v", err)
-		}
-	} else {
-		return fmt.Errorf("STARTTLS is required but the SMTP server (%s) does not support it; refusing to send credentials in plaintext", settings.SMTPHost)
+// helloSMTP sends EHLO with the configured name, if any, before anything
+// else is said on the connection. The client is closed on failure.
+func helloSMTP(client *smtp.Client, name string) error {
+	if name == "" {
+		return nil
 	}
-
-	if err = authWithFallback(client, settings.SMTPUser, settings.SMTPPass, settings.SMTPHost); err != nil {
-		return err
+	if err := client.Hello(name); err != nil {
+		closeSMTP(client)
+		return classifySMTPError("HELO", err)
 	}
+	return nil
+}
 
-	if err = client.Mail(from); err != nil {
-		return fmt.Errorf("MAIL FROM failed: %v", err)
+// deliverSMTP sends one message over an authenticated connection.
+func deliverSMTP(client *smtp.Client, from string, recipients []string, message []byte) error {
+	if err := client.Mail(from); err != nil {
+		return classifySMTPError("MAIL FROM", err)
 	}
 
 	for _, recipient := range recipients {
-		if err = client.Rcpt(recipient); err != nil {
-			return fmt.Errorf("RCPT TO failed for %s: %v", recipient, err)
+		if err := client.Rcpt(recipient); err != nil {
+			return classifySMTPError("RCPT TO for "+recipient, err)
 		}
 	}
 
 	w, err := client.Data()
 	if err != nil {
-		return fmt.Errorf("DATA failed: %v", err)
+		return classifySMTPError("DATA", err)
 	}
 
 	_, err = w.Write(message)
 	if err != nil {
-		return fmt.Errorf("write failed: %v", err)
+		return classifySMTPError("write", err)
 	}
 
-	return w.Close()
+	return classifySMTPError("DATA", w.Close())
+}
+
+// closeSMTP says QUIT and closes the connection even if QUIT fails.
+func closeSMTP(client *smtp.Client) {
+	_ = client.Quit()
+	_ = client.Close()
 }
 
 // ParseRecipientEmails supports comma or newline separated emails saved in recipient_email.
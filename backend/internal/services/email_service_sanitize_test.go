@@ -1,6 +1,7 @@
 package services
 
 import (
+	"mime"
 	"strings"
 	"testing"
 )
@@ -40,3 +41,26 @@ func TestMarkdownToHTML_AllowsSafeLinkWithRelProtection(t *testing.T) {
 		t.Fatalf("expected rel protection attributes, got: %s", out)
 	}
 }
+
+func TestEncodeHeaderWord_LeavesASCIIUnchanged(t *testing.T) {
+	for _, input := range []string{"A message for you", "Aeterna", ""} {
+		if got := encodeHeaderWord(input); got != input {
+			t.Fatalf("encodeHeaderWord(%q) = %q, want unchanged", input, got)
+		}
+	}
+}
+
+func TestEncodeHeaderWord_EncodesNonASCII(t *testing.T) {
+	input := "Grüße von Oma 💌"
+	got := encodeHeaderWord(input)
+	if !strings.HasPrefix(got, "=?utf-8?q?") {
+		t.Fatalf("expected RFC 2047 Q-encoded word, got %q", got)
+	}
+	decoded, err := new(mime.WordDecoder).DecodeHeader(got)
+	if err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if decoded != input {
+		t.Fatalf("decoded header = %q, want %q", decoded, input)
+	}
+}
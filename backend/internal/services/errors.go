@@ -1,10 +1,83 @@
 package services
 
+// Error codes carried in the "code" field of every API error response.
+// Clients branch on these rather than on the human-readable message, so a
+// code must never be renamed or repurposed once shipped; see
+// docs/error-codes.md.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+	CodeAlreadyConfigured    = "already_configured"
+	CodeRegistrationDisabled = "registration_disabled"
+	CodeEmailTaken           = "email_taken"
+	CodeRecoveryKeyMistyped  = "recovery_key_mistyped"
+	CodeCannotDeleteSelf     = "cannot_delete_self"
+	CodeCannotDeletePrimary  = "cannot_delete_primary"
+	CodeOriginRequired       = "origin_required"
+	CodeInvalidOrigin        = "invalid_origin"
+	CodeOriginNotAllowed     = "origin_not_allowed"
+	CodeSSELimitExceeded     = "sse_limit_exceeded"
+	CodeMessageLimitReached  = "message_limit_reached"
+	CodeRecipientDomainNoMX  = "recipient_domain_no_mx"
+	CodeSMTPNotConfigured    = "smtp_not_configured"
+	CodeSMTPConnectionFailed = "smtp_connection_failed"
+	CodeWebhookTestFailed    = "webhook_test_failed"
+	CodeAntivirusUnavailable = "antivirus_unavailable"
+	CodeRequestTimeout       = "request_timeout"
+	CodeStorageFull          = "storage_full"
+	CodeMaintenanceMode      = "maintenance_mode"
+	CodeIPNotAllowed         = "ip_not_allowed"
+	CodeMessageDelivered     = "message_delivered"
+)
+
+// ErrorCodes lists every code the API may return.
+var ErrorCodes = []string{
+	CodeBadRequest,
+	CodeUnauthorized,
+	CodeForbidden,
+	CodeNotFound,
+	CodeMethodNotAllowed,
+	CodePayloadTooLarge,
+	CodeRateLimited,
+	CodeInternal,
+	CodeAlreadyConfigured,
+	CodeRegistrationDisabled,
+	CodeEmailTaken,
+	CodeRecoveryKeyMistyped,
+	CodeCannotDeleteSelf,
+	CodeCannotDeletePrimary,
+	CodeOriginRequired,
+	CodeInvalidOrigin,
+	CodeOriginNotAllowed,
+	CodeSSELimitExceeded,
+	CodeMessageLimitReached,
+	CodeRecipientDomainNoMX,
+	CodeSMTPNotConfigured,
+	CodeSMTPConnectionFailed,
+	CodeWebhookTestFailed,
+	CodeAntivirusUnavailable,
+	CodeRequestTimeout,
+	CodeStorageFull,
+	CodeMaintenanceMode,
+	CodeIPNotAllowed,
+	CodeMessageDelivered,
+}
+
 type APIError struct {
 	Status  int
 	Message string
 	Code    string
-	Err     error
+	// Field names the request field that failed validation, such as
+	// "recipient_email", so clients can show the error inline. Empty when
+	// the error is not about a single field.
+	Field string
+	Err   error
 }
 
 func (e *APIError) Error() string {
@@ -19,13 +92,20 @@ func NewAPIError(status int, code, message string, err error) *APIError {
 }
 
 func BadRequest(message string, err error) *APIError {
-	return NewAPIError(400, "bad_request", message, err)
+	return NewAPIError(400, CodeBadRequest, message, err)
+}
+
+// InvalidField is a BadRequest for the request field named field.
+func InvalidField(field, message string, err error) *APIError {
+	e := BadRequest(message, err)
+	e.Field = field
+	return e
 }
 
 func Internal(message string, err error) *APIError {
-	return NewAPIError(500, "internal_error", message, err)
+	return NewAPIError(500, CodeInternal, message, err)
 }
 
 func NotFound(message string, err error) *APIError {
-	return NewAPIError(404, "not_found", message, err)
+	return NewAPIError(404, CodeNotFound, message, err)
 }
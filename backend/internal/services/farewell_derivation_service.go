@@ -52,15 +52,15 @@ func (s *FarewellDerivationService) ProcessPending(batchSize int) (int, error) {
 }
 
 func (s *FarewellDerivationService) deriveLetter(letter models.FarewellLetter) error {
-	contentCipher := letter.RawContent
+	contentCipher, column := letter.RawContent, FarewellRawContentColumn
 	if strings.TrimSpace(contentCipher) == "" {
-		contentCipher = letter.Content
+		contentCipher, column = letter.Content, FarewellContentColumn
 	}
 	if strings.TrimSpace(contentCipher) == "" {
 		return nil
 	}
 
-	rawMarkdown, err := s.crypto.Decrypt(contentCipher)
+	rawMarkdown, err := s.crypto.DecryptWithContext(contentCipher, FarewellLetterContext(letter.ID, column))
 	if err != nil {
 		return fmt.Errorf("failed to decrypt raw content: %w", err)
 	}
@@ -69,17 +69,17 @@ func (s *FarewellDerivationService) deriveLetter(letter models.FarewellLetter) e
 	renderedHTML := markdownToHTML(safeMarkdown)
 	wordCount := countWordsFromMarkdown(safeMarkdown)
 
-	encryptedSafe, err := s.crypto.Encrypt(safeMarkdown)
+	encryptedSafe, err := s.crypto.EncryptWithContext(safeMarkdown, FarewellLetterContext(letter.ID, FarewellContentColumn))
 	if err != nil {
 		return fmt.Errorf("failed to encrypt sanitized content: %w", err)
 	}
 
-	encryptedRaw, err := s.crypto.Encrypt(rawMarkdown)
+	encryptedRaw, err := s.crypto.EncryptWithContext(rawMarkdown, FarewellLetterContext(letter.ID, FarewellRawContentColumn))
 	if err != nil {
 		return fmt.Errorf("failed to encrypt raw content: %w", err)
 	}
 
-	encryptedHTML, err := s.crypto.Encrypt(renderedHTML)
+	encryptedHTML, err := s.crypto.EncryptWithContext(renderedHTML, FarewellLetterContext(letter.ID, FarewellRenderedHTMLColumn))
 	if err != nil {
 		return fmt.Errorf("failed to encrypt rendered html: %w", err)
 	}
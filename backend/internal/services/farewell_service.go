@@ -8,6 +8,7 @@ import (
 
 	"github.com/alpyxn/aeterna/backend/internal/database"
 	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -24,12 +25,12 @@ func (s FarewellService) Create(userID, messageID, recipientEmail, subject, cont
 		return models.FarewellLetter{}, err
 	}
 
-	if err := farewellValidation.ValidateEmail(recipientEmail); err != nil {
+	if err := farewellValidation.ValidateEmail("recipient_email", recipientEmail); err != nil {
 		return models.FarewellLetter{}, err
 	}
 
 	if subject == "" {
-		return models.FarewellLetter{}, BadRequest("Subject is required", nil)
+		return models.FarewellLetter{}, InvalidField("subject", "Subject is required", nil)
 	}
 
 	if err := farewellValidation.ValidateContent(content); err != nil {
@@ -37,22 +38,25 @@ func (s FarewellService) Create(userID, messageID, recipientEmail, subject, cont
 	}
 
 	if delayMinutes < 0 {
-		return models.FarewellLetter{}, BadRequest("Delay must be zero or positive", nil)
+		return models.FarewellLetter{}, InvalidField("delay_minutes", "Delay must be zero or positive", nil)
 	}
 
 	safeMarkdown := sanitizeFarewellMarkdown(content)
 
-	encryptedSafe, err := farewellCrypto.Encrypt(safeMarkdown)
+	// The ID is chosen up front so the content can be bound to the row.
+	letterID := uuid.NewString()
+	encryptedSafe, err := farewellCrypto.EncryptWithContext(safeMarkdown, FarewellLetterContext(letterID, FarewellContentColumn))
 	if err != nil {
 		return models.FarewellLetter{}, err
 	}
 
-	encryptedRaw, err := farewellCrypto.Encrypt(content)
+	encryptedRaw, err := farewellCrypto.EncryptWithContext(content, FarewellLetterContext(letterID, FarewellRawContentColumn))
 	if err != nil {
 		return models.FarewellLetter{}, err
 	}
 
 	letter := models.FarewellLetter{
+		ID:                 letterID,
 		UserID:             userID,
 		MessageID:          messageID,
 		RecipientEmail:     recipientEmail,
@@ -85,7 +89,7 @@ func (s FarewellService) List(userID, messageID string) ([]models.FarewellLetter
 	}
 
 	for i := range letters {
-		decrypted, err := farewellCrypto.Decrypt(letters[i].Content)
+		decrypted, err := farewellCrypto.DecryptWithContext(letters[i].Content, FarewellLetterContext(letters[i].ID, FarewellContentColumn))
 		if err != nil {
 			return nil, err
 		}
@@ -115,12 +119,12 @@ func (s FarewellService) Update(userID, messageID, id, recipientEmail, subject,
 		return models.FarewellLetter{}, BadRequest("Cannot edit an already-sent farewell letter", nil)
 	}
 
-	if err := farewellValidation.ValidateEmail(recipientEmail); err != nil {
+	if err := farewellValidation.ValidateEmail("recipient_email", recipientEmail); err != nil {
 		return models.FarewellLetter{}, err
 	}
 
 	if subject == "" {
-		return models.FarewellLetter{}, BadRequest("Subject is required", nil)
+		return models.FarewellLetter{}, InvalidField("subject", "Subject is required", nil)
 	}
 
 	if err := farewellValidation.ValidateContent(content); err != nil {
@@ -128,17 +132,17 @@ func (s FarewellService) Update(userID, messageID, id, recipientEmail, subject,
 	}
 
 	if delayMinutes < 0 {
-		return models.FarewellLetter{}, BadRequest("Delay must be zero or positive", nil)
+		return models.FarewellLetter{}, InvalidField("delay_minutes", "Delay must be zero or positive", nil)
 	}
 
 	safeMarkdown := sanitizeFarewellMarkdown(content)
 
-	encryptedSafe, err := farewellCrypto.Encrypt(safeMarkdown)
+	encryptedSafe, err := farewellCrypto.EncryptWithContext(safeMarkdown, FarewellLetterContext(letter.ID, FarewellContentColumn))
 	if err != nil {
 		return models.FarewellLetter{}, err
 	}
 
-	encryptedRaw, err := farewellCrypto.Encrypt(content)
+	encryptedRaw, err := farewellCrypto.EncryptWithContext(content, FarewellLetterContext(letter.ID, FarewellRawContentColumn))
 	if err != nil {
 		return models.FarewellLetter{}, err
 	}
@@ -378,7 +378,7 @@ func TestFarewellCreate_SanitizesStoredContentAndPreservesRaw(t *testing.T) {
 	if strings.TrimSpace(stored.RawContent) == "" {
 		t.Fatal("expected raw encrypted content to be persisted")
 	}
-	rawDecrypted, err := (CryptoService{}).Decrypt(stored.RawContent)
+	rawDecrypted, err := (CryptoService{}).DecryptWithContext(stored.RawContent, FarewellLetterContext(stored.ID, FarewellRawContentColumn))
 	if err != nil {
 		t.Fatalf("failed to decrypt raw content: %v", err)
 	}
@@ -6,10 +6,14 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/alpyxn/aeterna/backend/internal/config"
 	"github.com/alpyxn/aeterna/backend/internal/database"
 	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -26,17 +30,51 @@ var fileCryptoService = CryptoService{}
 var fileValidationService = ValidationService{}
 
 func (s FileService) uploadsDir() string {
-	return filepath.Join(filepath.Dir(s.cfg.Database.Path), "uploads")
+	return GetUploadsDir(s.cfg.Database)
 }
 
-// GetUploadsDir returns the base directory for file uploads given a database path.
-func GetUploadsDir(dbPath string) string {
-	return filepath.Join(filepath.Dir(dbPath), "uploads")
+// GetUploadsDir returns the base directory for file uploads: UPLOADS_DIR,
+// DATA_DIR/uploads, or an uploads directory next to the database.
+func GetUploadsDir(db config.DatabaseConfig) string {
+	if db.UploadsDir != "" {
+		return db.UploadsDir
+	}
+	return filepath.Join(filepath.Dir(db.Path), "uploads")
 }
 
 // EnsureUploadsDir creates the uploads directory if it does not exist.
-func EnsureUploadsDir(dbPath string) error {
-	return os.MkdirAll(GetUploadsDir(dbPath), 0700)
+func EnsureUploadsDir(db config.DatabaseConfig) error {
+	return os.MkdirAll(GetUploadsDir(db), 0700)
+}
+
+// validateUpload checks a switch attachment. Audio and video go through the
+// media rules when ATTACHMENT_MEDIA_ENABLED is set and are otherwise rejected
+// like any other unlisted type.
+func (s FileService) validateUpload(filename string, data []byte) error {
+	if s.cfg.Attachment.MediaEnabled && IsMediaFile(filename) {
+		return fileValidationService.ValidateMediaFile(filename, int64(len(data)), data, s.cfg.Attachment.MediaMaxFileMB)
+	}
+	return fileValidationService.ValidateFile(filename, int64(len(data)), data)
+}
+
+// scanForMalware runs data through clamd when CLAMAV_ADDR is configured.
+// Infected files are rejected and logged; a scanner failure also rejects the
+// upload so nothing unscanned slips through while clamd is down.
+func (s FileService) scanForMalware(userID, filename string, data []byte) error {
+	av := s.cfg.Antivirus
+	if av.ClamAVAddr == "" {
+		return nil
+	}
+	signature, err := NewClamAVScanner(av.ClamAVAddr, time.Duration(av.ClamAVTimeoutSeconds)*time.Second).Scan(data)
+	if err != nil {
+		slog.Error("Antivirus scan failed", "error", err, "user_id", userID, "filename", filename)
+		return NewAPIError(503, CodeAntivirusUnavailable, "Antivirus scan is unavailable; try again later", err)
+	}
+	if signature != "" {
+		slog.Warn("Upload rejected by antivirus", "event", "attachment.infected", "user_id", userID, "filename", filename, "signature", signature)
+		return BadRequest("File rejected by antivirus scan: "+signature, nil)
+	}
+	return nil
 }
 
 // Upload validates, encrypts, and stores a file on disk, then creates a DB record
@@ -53,9 +91,12 @@ func (s FileService) Upload(userID, messageID, filename, mimeType string, data [
 		return models.Attachment{}, BadRequest("Cannot attach files to a triggered message", nil)
 	}
 
-	cleanFilename := fileValidationService.SanitizeFilename(filename)
+	cleanFilename := fileValidationService.SanitizeFilename(fileValidationService.NormalizeFilename(filename, s.cfg.Attachment.FilenamePolicy))
 
-	if err := fileValidationService.ValidateFile(cleanFilename, int64(len(data)), data); err != nil {
+	if err := s.validateUpload(cleanFilename, data); err != nil {
+		return models.Attachment{}, err
+	}
+	if err := s.scanForMalware(userID, cleanFilename, data); err != nil {
 		return models.Attachment{}, err
 	}
 
@@ -70,43 +111,175 @@ func (s FileService) Upload(userID, messageID, filename, mimeType string, data [
 	if totalSize+int64(len(data)) > MaxTotalAttachSize {
 		return models.Attachment{}, BadRequest("Total attachment size exceeds 25 MB limit", nil)
 	}
-
-	encrypted, err := fileCryptoService.EncryptBytes(data)
-	if err != nil {
-		return models.Attachment{}, Internal("Failed to encrypt file", err)
-	}
-
-	userDir := filepath.Join(s.uploadsDir(), userID)
-	msgDir := filepath.Join(userDir, messageID)
-	if err := os.MkdirAll(msgDir, 0700); err != nil {
-		return models.Attachment{}, Internal("Failed to create upload directory", err)
+	if err := s.checkStorageCap(database.DB, int64(len(data))); err != nil {
+		return models.Attachment{}, err
 	}
 
-	storageFilename := uuid.NewString() + ".enc"
-	storagePath := filepath.Join(msgDir, storageFilename)
-
-	if err := os.WriteFile(storagePath, encrypted, 0600); err != nil {
-		return models.Attachment{}, Internal("Failed to write file", err)
+	contentHash, err := fileCryptoService.KeyedHash(data)
+	if err != nil {
+		return models.Attachment{}, Internal("Failed to hash file", err)
 	}
 
 	attachment := models.Attachment{
 		UserID:      userID,
 		MessageID:   messageID,
 		Filename:    cleanFilename,
-		StoragePath: storagePath,
 		Size:        int64(len(data)),
 		MimeType:    mimeType,
+		ContentHash: contentHash,
+	}
+
+	// Identical content already stored for this user is referenced rather
+	// than encrypted and written a second time.
+	blobRefs.Lock()
+	defer blobRefs.Unlock()
+	var existing models.Attachment
+	reused := false
+	if err := database.ForTenant(userID).Where("content_hash = ?", contentHash).First(&existing).Error; err == nil {
+		if _, statErr := os.Stat(existing.StoragePath); statErr == nil {
+			attachment.StoragePath = existing.StoragePath
+			attachment.ThumbnailPath = existing.ThumbnailPath
+			reused = true
+		}
 	}
 
-	if err := database.ForTenant(userID).Create(&attachment).Error; err != nil {
-		os.Remove(storagePath)
-		return models.Attachment{}, Internal("Failed to save attachment record", err)
+	if !reused {
+		encrypted, err := fileCryptoService.EncryptBytesWithContext(data, attachmentBlobContext(userID, contentHash))
+		if err != nil {
+			return models.Attachment{}, Internal("Failed to encrypt file", err)
+		}
+
+		blobDir := filepath.Join(s.uploadsDir(), userID, "blobs")
+		if err := os.MkdirAll(blobDir, 0700); err != nil {
+			return models.Attachment{}, Internal("Failed to create upload directory", err)
+		}
+
+		attachment.StoragePath = filepath.Join(blobDir, contentHash+".enc")
+		if err := os.WriteFile(attachment.StoragePath, encrypted, 0600); err != nil {
+			return models.Attachment{}, Internal("Failed to write file", err)
+		}
+		attachment.ThumbnailPath = s.storeThumbnail(filepath.Join(blobDir, contentHash+".thumb.enc"), attachmentThumbnailContext(userID, contentHash), mimeType, data)
+	}
+	attachment.HasThumbnail = attachment.ThumbnailPath != ""
+
+	if err := s.reserveStorage(attachment.Size, func(tx *gorm.DB) error {
+		return database.TenantTx(tx, userID).Create(&attachment).Error
+	}); err != nil {
+		if !reused {
+			removeBlob(attachment)
+		}
+		return models.Attachment{}, storageReservationError(err)
 	}
 
-	slog.Info("File uploaded", "attachment_id", attachment.ID, "message_id", messageID, "filename", cleanFilename, "size", len(data))
+	slog.Info("File uploaded", "attachment_id", attachment.ID, "message_id", messageID, "filename", cleanFilename, "size", len(data), "deduplicated", reused)
 	return attachment, nil
 }
 
+// blobRefs serializes taking and dropping references to shared blobs, so a
+// blob picked for reuse by an upload cannot be removed by a concurrent
+// delete before the new attachment record is stored.
+var blobRefs sync.Mutex
+
+// deleteAttachments removes the given attachment records and returns those
+// whose blob is no longer referenced, counting the remaining references in
+// the same transaction as the delete. Callers hold blobRefs.
+func deleteAttachments(userID string, attachments []models.Attachment) ([]models.Attachment, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	var released []models.Attachment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		ids := make([]string, len(attachments))
+		for i, att := range attachments {
+			ids[i] = att.ID
+		}
+		if err := database.TenantTx(tx, userID).Unscoped().Where("id IN ?", ids).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(attachments))
+		for _, att := range attachments {
+			if seen[att.StoragePath] {
+				continue
+			}
+			seen[att.StoragePath] = true
+			var refs int64
+			if err := database.TenantTx(tx, userID).Model(&models.Attachment{}).Where("storage_path = ?", att.StoragePath).Count(&refs).Error; err != nil {
+				return err
+			}
+			if refs == 0 {
+				released = append(released, att)
+			}
+		}
+		return nil
+	})
+	return released, err
+}
+
+// removeBlob deletes an attachment's encrypted file and thumbnail.
+func removeBlob(attachment models.Attachment) {
+	if err := os.Remove(attachment.StoragePath); err != nil && !os.IsNotExist(err) {
+		slog.Error("Failed to remove attachment file", "path", attachment.StoragePath, "error", err)
+	}
+	removeThumbnail(attachment)
+}
+
+// storeThumbnail writes an encrypted preview for image uploads and returns its
+// path, or "" when the type is not previewable or generation fails. A missing
+// thumbnail never blocks the upload itself.
+func (s FileService) storeThumbnail(path, context, mimeType string, data []byte) string {
+	if !thumbnailable(mimeType) {
+		return ""
+	}
+	thumb, err := generateThumbnail(mimeType, data)
+	if err != nil {
+		slog.Warn("Thumbnail generation skipped", "mime_type", mimeType, "error", err)
+		return ""
+	}
+	encrypted, err := fileCryptoService.EncryptBytesWithContext(thumb, context)
+	if err != nil {
+		slog.Error("Failed to encrypt thumbnail", "error", err)
+		return ""
+	}
+	if err := os.WriteFile(path, encrypted, 0600); err != nil {
+		slog.Error("Failed to write thumbnail", "path", path, "error", err)
+		return ""
+	}
+	return path
+}
+
+func removeThumbnail(attachment models.Attachment) {
+	if attachment.ThumbnailPath == "" {
+		return
+	}
+	if err := os.Remove(attachment.ThumbnailPath); err != nil && !os.IsNotExist(err) {
+		slog.Error("Failed to remove thumbnail file", "path", attachment.ThumbnailPath, "error", err)
+	}
+}
+
+// GetThumbnail returns the decrypted JPEG preview of an image attachment.
+func (s FileService) GetThumbnail(userID, attachmentID string) ([]byte, error) {
+	var attachment models.Attachment
+	if err := database.ForTenant(userID).First(&attachment, "id = ?", attachmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NotFound("Attachment not found", err)
+		}
+		return nil, Internal("Failed to fetch attachment", err)
+	}
+	if attachment.ThumbnailPath == "" {
+		return nil, NotFound("No thumbnail for this attachment", nil)
+	}
+
+	encrypted, err := os.ReadFile(attachment.ThumbnailPath)
+	if err != nil {
+		return nil, Internal("Failed to read thumbnail file", err)
+	}
+	decrypted, err := fileCryptoService.DecryptBytesWithContext(encrypted, attachmentThumbnailContext(userID, attachment.ContentHash))
+	if err != nil {
+		return nil, Internal("Failed to decrypt thumbnail", err)
+	}
+	return decrypted, nil
+}
+
 // Delete removes a single attachment (file + DB record) scoped to the user
 func (s FileService) Delete(userID, attachmentID string) error {
 	var attachment models.Attachment
@@ -117,13 +290,15 @@ func (s FileService) Delete(userID, attachmentID string) error {
 		return Internal("Failed to fetch attachment", err)
 	}
 
-	if err := os.Remove(attachment.StoragePath); err != nil && !os.IsNotExist(err) {
-		slog.Error("Failed to remove attachment file", "path", attachment.StoragePath, "error", err)
-	}
-
-	if err := database.ForTenant(userID).Unscoped().Delete(&attachment).Error; err != nil {
+	blobRefs.Lock()
+	defer blobRefs.Unlock()
+	released, err := deleteAttachments(userID, []models.Attachment{attachment})
+	if err != nil {
 		return Internal("Failed to delete attachment record", err)
 	}
+	for _, att := range released {
+		removeBlob(att)
+	}
 
 	slog.Info("File deleted", "attachment_id", attachmentID)
 	return nil
@@ -131,20 +306,20 @@ func (s FileService) Delete(userID, attachmentID string) error {
 
 // DeleteByMessageID removes all attachments for a message
 func (s FileService) DeleteByMessageID(userID, messageID string) error {
+	blobRefs.Lock()
+	defer blobRefs.Unlock()
 	var attachments []models.Attachment
 	if err := database.ForTenant(userID).Where("message_id = ?", messageID).Find(&attachments).Error; err != nil {
 		return Internal("Failed to fetch attachments", err)
 	}
 
-	for _, att := range attachments {
-		if err := os.Remove(att.StoragePath); err != nil && !os.IsNotExist(err) {
-			slog.Error("Failed to remove attachment file", "path", att.StoragePath, "error", err)
-		}
-	}
-
-	if err := database.ForTenant(userID).Unscoped().Where("message_id = ?", messageID).Delete(&models.Attachment{}).Error; err != nil {
+	released, err := deleteAttachments(userID, attachments)
+	if err != nil {
 		return Internal("Failed to delete attachment records", err)
 	}
+	for _, att := range released {
+		removeBlob(att)
+	}
 
 	msgDir := filepath.Join(s.uploadsDir(), userID, messageID)
 	os.Remove(msgDir)
@@ -168,7 +343,7 @@ func (s FileService) GetDecrypted(userID, attachmentID string) (filename, mimeTy
 		return "", "", nil, Internal("Failed to read attachment file", err)
 	}
 
-	decrypted, err := fileCryptoService.DecryptBytes(encrypted)
+	decrypted, err := fileCryptoService.DecryptBytesWithContext(encrypted, attachmentBlobContext(userID, attachment.ContentHash))
 	if err != nil {
 		return "", "", nil, Internal("Failed to decrypt attachment", err)
 	}
@@ -194,6 +369,191 @@ func (s FileService) CountByMessageID(userID, messageID string) (int64, error) {
 	return count, nil
 }
 
+// storageUsed sums the recorded size of switch and farewell attachments in
+// scope.
+func storageUsed(scope func() *gorm.DB) (int64, error) {
+	var attachments, farewell int64
+	if err := scope().Model(&models.Attachment{}).Select("COALESCE(SUM(size), 0)").Scan(&attachments).Error; err != nil {
+		return 0, Internal("Failed to measure attachment storage", err)
+	}
+	if err := scope().Model(&models.FarewellAttachment{}).Select("COALESCE(SUM(size), 0)").Scan(&farewell).Error; err != nil {
+		return 0, Internal("Failed to measure attachment storage", err)
+	}
+	return attachments + farewell, nil
+}
+
+func (s FileService) storageLimit() int64 {
+	return int64(s.cfg.Attachment.MaxTotalStorageMB) * 1024 * 1024
+}
+
+// checkStorageCap refuses an upload of size bytes that would take every
+// account's attachments together past MAX_TOTAL_STORAGE_MB, so uploads
+// cannot fill the volume the database and worker depend on. Uploads check
+// early, before encrypting anything, and again in reserveStorage.
+func (s FileService) checkStorageCap(db *gorm.DB, size int64) error {
+	limit := s.storageLimit()
+	if limit <= 0 {
+		return nil
+	}
+	used, err := storageUsed(func() *gorm.DB { return db })
+	if err != nil {
+		return err
+	}
+	if used+size > limit {
+		return NewAPIError(507, CodeStorageFull, fmt.Sprintf("Attachment storage is full (%d MB limit)", s.cfg.Attachment.MaxTotalStorageMB), nil)
+	}
+	return nil
+}
+
+// storageReservations serializes reserveStorage, so two uploads cannot both
+// see room for themselves before either is recorded.
+var storageReservations sync.Mutex
+
+// reserveStorage runs create, which stores the record of an upload of size
+// bytes, in the same transaction as the MAX_TOTAL_STORAGE_MB check.
+func (s FileService) reserveStorage(size int64, create func(tx *gorm.DB) error) error {
+	storageReservations.Lock()
+	defer storageReservations.Unlock()
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkStorageCap(tx, size); err != nil {
+			return err
+		}
+		return create(tx)
+	})
+}
+
+// storageReservationError passes a refused reservation through and reports
+// any other failure as a failed save.
+func storageReservationError(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+	return Internal("Failed to save attachment record", err)
+}
+
+// StorageUsage reports the caller's attachment storage against
+// MAX_TOTAL_STORAGE_MB. Only the primary administrator is told how much
+// every account uses together.
+func (s FileService) StorageUsage(userID string) (ports.StorageUsage, error) {
+	own, err := storageUsed(func() *gorm.DB { return database.ForTenant(userID) })
+	if err != nil {
+		return ports.StorageUsage{}, err
+	}
+	total, err := storageUsed(func() *gorm.DB { return database.DB })
+	if err != nil {
+		return ports.StorageUsage{}, err
+	}
+	usage := ports.StorageUsage{UsedBytes: own, LimitBytes: s.storageLimit()}
+	if IsFirstUser(userID) {
+		usage.TotalUsedBytes = &total
+	}
+	if usage.LimitBytes > 0 {
+		available := max(usage.LimitBytes-total, 0)
+		usage.AvailableBytes = &available
+	}
+	return usage, nil
+}
+
+// ListRevealable returns the attachments a recipient may download from the
+// public reveal link: only for a triggered switch, and only files meant for
+// every recipient, since all of them share the same link.
+func (s FileService) ListRevealable(messageID string) ([]models.Attachment, error) {
+	msg, err := triggeredMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	return revealableAttachments(msg.UserID, msg.ID)
+}
+
+// GetRevealable decrypts one attachment listed by ListRevealable.
+func (s FileService) GetRevealable(messageID, attachmentID string) (filename, mimeType string, data []byte, err error) {
+	msg, err := triggeredMessage(messageID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	var attachment models.Attachment
+	if err := database.ForTenant(msg.UserID).Where("message_id = ? AND recipient_email = ''", msg.ID).First(&attachment, "id = ?", attachmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", "", nil, NotFound("Attachment not found", err)
+		}
+		return "", "", nil, Internal("Failed to fetch attachment", err)
+	}
+	return s.GetDecrypted(msg.UserID, attachment.ID)
+}
+
+// triggeredMessage loads a message for the public reveal routes, answering
+// not found for switches that have not fired so their existence stays hidden.
+func triggeredMessage(messageID string) (models.Message, error) {
+	var msg models.Message
+	if err := database.DB.Select("id", "user_id").Where("status = ?", models.StatusTriggered).First(&msg, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Message{}, NotFound("Message not found", err)
+		}
+		return models.Message{}, Internal("Failed to fetch message", err)
+	}
+	return msg, nil
+}
+
+func revealableAttachments(userID, messageID string) ([]models.Attachment, error) {
+	attachments := make([]models.Attachment, 0)
+	if err := database.ForTenant(userID).Where("message_id = ? AND recipient_email = ''", messageID).Order("created_at ASC").Find(&attachments).Error; err != nil {
+		return nil, Internal("Failed to fetch attachments", err)
+	}
+	return attachments, nil
+}
+
+// SetRecipients restricts an attachment to a subset of its message's
+// recipients. An empty list restores delivery to every recipient.
+func (s FileService) SetRecipients(userID, attachmentID string, recipientEmails []string) (models.Attachment, error) {
+	var attachment models.Attachment
+	if err := database.ForTenant(userID).First(&attachment, "id = ?", attachmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Attachment{}, NotFound("Attachment not found", err)
+		}
+		return models.Attachment{}, Internal("Failed to fetch attachment", err)
+	}
+
+	var msg models.Message
+	if err := database.ForTenant(userID).First(&msg, "id = ?", attachment.MessageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Attachment{}, NotFound("Message not found", err)
+		}
+		return models.Attachment{}, Internal("Failed to fetch message", err)
+	}
+	if msg.Status == models.StatusTriggered {
+		return models.Attachment{}, BadRequest("Cannot change attachments of a triggered message", nil)
+	}
+
+	messageRecipients := ParseRecipientEmails(msg.RecipientEmail)
+	selected := make([]string, 0, len(recipientEmails))
+	for _, recipient := range recipientEmails {
+		recipient = strings.TrimSpace(recipient)
+		if recipient == "" {
+			continue
+		}
+		found := false
+		for _, existing := range messageRecipients {
+			if strings.EqualFold(existing, recipient) {
+				selected = append(selected, existing)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return models.Attachment{}, BadRequest(fmt.Sprintf("%s is not a recipient of this message", recipient), nil)
+		}
+	}
+
+	attachment.RecipientEmail = strings.Join(selected, ",")
+	if err := database.ForTenant(userID).Model(&attachment).Select("recipient_email").Updates(&attachment).Error; err != nil {
+		return models.Attachment{}, Internal("Failed to update attachment recipients", err)
+	}
+
+	slog.Info("Attachment recipients updated", "attachment_id", attachment.ID, "recipients", len(selected))
+	return attachment, nil
+}
+
 // UploadFarewellAttachment validates, encrypts, and stores a file for a farewell letter.
 func (s FileService) UploadFarewellAttachment(userID, letterID, filename, mimeType string, data []byte) (models.FarewellAttachment, error) {
 	var letter models.FarewellLetter
@@ -211,10 +571,13 @@ func (s FileService) UploadFarewellAttachment(userID, letterID, filename, mimeTy
 		return models.FarewellAttachment{}, err
 	}
 
-	cleanFilename := fileValidationService.SanitizeFilename(filename)
+	cleanFilename := fileValidationService.SanitizeFilename(fileValidationService.NormalizeFilename(filename, s.cfg.Attachment.FilenamePolicy))
 	if err := fileValidationService.ValidateFarewellFile(cleanFilename, int64(len(data)), data); err != nil {
 		return models.FarewellAttachment{}, err
 	}
+	if err := s.scanForMalware(userID, cleanFilename, data); err != nil {
+		return models.FarewellAttachment{}, err
+	}
 
 	var existingCount int64
 	database.ForTenant(userID).Model(&models.FarewellAttachment{}).Where("letter_id = ?", letterID).Count(&existingCount)
@@ -227,10 +590,8 @@ func (s FileService) UploadFarewellAttachment(userID, letterID, filename, mimeTy
 	if totalSize+int64(len(data)) > MaxFarewellTotalSize {
 		return models.FarewellAttachment{}, BadRequest("Total attachment size exceeds 50 MB limit", nil)
 	}
-
-	encrypted, err := fileCryptoService.EncryptBytes(data)
-	if err != nil {
-		return models.FarewellAttachment{}, Internal("Failed to encrypt file", err)
+	if err := s.checkStorageCap(database.DB, int64(len(data))); err != nil {
+		return models.FarewellAttachment{}, err
 	}
 
 	letterDir := filepath.Join(s.uploadsDir(), userID, "farewell", letterID)
@@ -241,6 +602,11 @@ func (s FileService) UploadFarewellAttachment(userID, letterID, filename, mimeTy
 	storageFilename := uuid.NewString() + ".enc"
 	storagePath := filepath.Join(letterDir, storageFilename)
 
+	encrypted, err := fileCryptoService.EncryptBytesWithContext(data, farewellAttachmentContext(userID, storagePath))
+	if err != nil {
+		return models.FarewellAttachment{}, Internal("Failed to encrypt file", err)
+	}
+
 	if err := os.WriteFile(storagePath, encrypted, 0600); err != nil {
 		return models.FarewellAttachment{}, Internal("Failed to write file", err)
 	}
@@ -254,9 +620,11 @@ func (s FileService) UploadFarewellAttachment(userID, letterID, filename, mimeTy
 		MimeType:    mimeType,
 	}
 
-	if err := database.ForTenant(userID).Create(&attachment).Error; err != nil {
+	if err := s.reserveStorage(attachment.Size, func(tx *gorm.DB) error {
+		return database.TenantTx(tx, userID).Create(&attachment).Error
+	}); err != nil {
 		os.Remove(storagePath)
-		return models.FarewellAttachment{}, Internal("Failed to save attachment record", err)
+		return models.FarewellAttachment{}, storageReservationError(err)
 	}
 
 	slog.Info("Farewell attachment uploaded", "attachment_id", attachment.ID, "letter_id", letterID, "filename", cleanFilename)
@@ -352,7 +720,7 @@ func (s FileService) GetFarewellAttachmentDecrypted(userID, attachmentID string)
 		return "", "", nil, Internal("Failed to read farewell attachment file", err)
 	}
 
-	decrypted, err := fileCryptoService.DecryptBytes(encrypted)
+	decrypted, err := fileCryptoService.DecryptBytesWithContext(encrypted, farewellAttachmentContext(userID, attachment.StoragePath))
 	if err != nil {
 		return "", "", nil, Internal("Failed to decrypt farewell attachment", err)
 	}
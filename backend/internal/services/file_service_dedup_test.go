@@ -0,0 +1,57 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestFileServiceUpload_DeduplicatesIdenticalContent(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+
+	for _, id := range []string{"m-dedup-1", "m-dedup-2"} {
+		if err := db.Create(&models.Message{
+			ID: id, UserID: "u-dedup", Content: "x", KeyFragment: "v1",
+			ManagementToken: "tok-" + id, RecipientEmail: "a@example.com",
+			TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusActive,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewFileService(config.Config{Database: config.DatabaseConfig{UploadsDir: t.TempDir()}})
+	data := []byte("the same document attached twice")
+
+	first, err := svc.Upload("u-dedup", "m-dedup-1", "doc.txt", "text/plain", data)
+	if err != nil {
+		t.Fatalf("first upload: %v", err)
+	}
+	second, err := svc.Upload("u-dedup", "m-dedup-2", "doc.txt", "text/plain", data)
+	if err != nil {
+		t.Fatalf("second upload: %v", err)
+	}
+	if first.StoragePath != second.StoragePath {
+		t.Fatalf("expected shared blob, got %q and %q", first.StoragePath, second.StoragePath)
+	}
+
+	if err := svc.Delete("u-dedup", first.ID); err != nil {
+		t.Fatalf("delete first: %v", err)
+	}
+	if _, err := os.Stat(second.StoragePath); err != nil {
+		t.Fatalf("blob removed while still referenced: %v", err)
+	}
+	if _, _, got, err := svc.GetDecrypted("u-dedup", second.ID); err != nil || string(got) != string(data) {
+		t.Fatalf("GetDecrypted after partial delete = %q, %v", got, err)
+	}
+
+	if err := svc.DeleteByMessageID("u-dedup", "m-dedup-2"); err != nil {
+		t.Fatalf("delete by message: %v", err)
+	}
+	if _, err := os.Stat(second.StoragePath); !os.IsNotExist(err) {
+		t.Fatalf("blob should be removed once unreferenced, stat err = %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func seedAttachmentForRecipients(t *testing.T) {
+	t.Helper()
+	db := setupTestDB(t)
+	if err := db.Create(&models.Message{
+		ID: "m1", UserID: "u1", Content: "x", KeyFragment: "v1",
+		ManagementToken: "tok", RecipientEmail: "lawyer@example.com,family@example.com",
+		TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Attachment{
+		ID: "a1", UserID: "u1", MessageID: "m1", Filename: "will.pdf",
+		StoragePath: "/tmp/none.enc", Size: 1, MimeType: "application/pdf",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileServiceSetRecipients_RestrictsToMessageRecipients(t *testing.T) {
+	seedAttachmentForRecipients(t)
+
+	att, err := FileService{}.SetRecipients("u1", "a1", []string{"Lawyer@Example.com"})
+	if err != nil {
+		t.Fatalf("SetRecipients: %v", err)
+	}
+	if att.RecipientEmail != "lawyer@example.com" {
+		t.Fatalf("recipient_email = %q, want canonical message recipient", att.RecipientEmail)
+	}
+	if !att.DeliversTo("lawyer@example.com") || att.DeliversTo("family@example.com") {
+		t.Fatalf("unexpected delivery selection for %q", att.RecipientEmail)
+	}
+
+	att, err = FileService{}.SetRecipients("u1", "a1", nil)
+	if err != nil {
+		t.Fatalf("SetRecipients(nil): %v", err)
+	}
+	if att.RecipientEmail != "" || !att.DeliversTo("family@example.com") {
+		t.Fatalf("clearing the selection should restore delivery to everyone, got %q", att.RecipientEmail)
+	}
+}
+
+func TestFileServiceSetRecipients_RejectsUnknownRecipient(t *testing.T) {
+	seedAttachmentForRecipients(t)
+
+	_, err := FileService{}.SetRecipients("u1", "a1", []string{"stranger@example.com"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != 400 {
+		t.Fatalf("expected 400 APIError, got %v", err)
+	}
+}
+
+func TestMessageUpdate_RejectsDroppingAnAttachmentsRecipient(t *testing.T) {
+	seedAttachmentForRecipients(t)
+	initTestKeyManager(t)
+	if _, err := (FileService{}).SetRecipients("u1", "a1", []string{"lawyer@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := (MessageService{}).Update("u1", "m1", "hello", []string{"family@example.com"}, 60, nil, 0, nil, nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != 400 || apiErr.Field != "recipient_email" {
+		t.Fatalf("expected a recipient_email field error, got %v", err)
+	}
+	var msg models.Message
+	if err := database.DB.First(&msg, "id = ?", "m1").Error; err != nil || msg.RecipientEmail != "lawyer@example.com,family@example.com" {
+		t.Fatalf("a rejected update must keep the recipients: %q, %v", msg.RecipientEmail, err)
+	}
+
+	if _, err := (MessageService{}).Update("u1", "m1", "hello", []string{"LAWYER@example.com", "new@example.com"}, 60, nil, 0, nil, nil, nil); err != nil {
+		t.Fatalf("keeping the attachment's recipient should be allowed: %v", err)
+	}
+}
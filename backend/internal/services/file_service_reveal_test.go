@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestFileServiceRevealableAttachments(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.Create(&models.Message{
+		ID: "m-reveal", UserID: "u-reveal", Content: "x", KeyFragment: "v1",
+		ManagementToken: "tok-reveal", RecipientEmail: "a@example.com,b@example.com",
+		TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewFileService(config.Config{Database: config.DatabaseConfig{UploadsDir: t.TempDir()}})
+	shared, err := svc.Upload("u-reveal", "m-reveal", "shared.txt", "text/plain", []byte("for everyone"))
+	if err != nil {
+		t.Fatalf("upload shared: %v", err)
+	}
+	private, err := svc.Upload("u-reveal", "m-reveal", "private.txt", "text/plain", []byte("for a only"))
+	if err != nil {
+		t.Fatalf("upload private: %v", err)
+	}
+	if _, err := svc.SetRecipients("u-reveal", private.ID, []string{"a@example.com"}); err != nil {
+		t.Fatalf("restrict private: %v", err)
+	}
+
+	if _, err := svc.ListRevealable("m-reveal"); err == nil {
+		t.Fatal("expected attachments of an active switch to stay hidden")
+	}
+	if _, _, _, err := svc.GetRevealable("m-reveal", shared.ID); err == nil {
+		t.Fatal("expected downloads from an active switch to be rejected")
+	}
+
+	if err := db.Model(&models.Message{}).Where("id = ?", "m-reveal").Update("status", models.StatusTriggered).Error; err != nil {
+		t.Fatal(err)
+	}
+	list, err := svc.ListRevealable("m-reveal")
+	if err != nil {
+		t.Fatalf("ListRevealable: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != shared.ID {
+		t.Fatalf("expected only the shared attachment, got %+v", list)
+	}
+	filename, _, data, err := svc.GetRevealable("m-reveal", shared.ID)
+	if err != nil || filename != "shared.txt" || string(data) != "for everyone" {
+		t.Fatalf("GetRevealable = %q, %q, %v", filename, data, err)
+	}
+	if _, _, _, err := svc.GetRevealable("m-reveal", private.ID); err == nil {
+		t.Fatal("expected a recipient-restricted attachment to stay off the shared link")
+	}
+	if _, _, _, err := svc.GetRevealable("m-other", shared.ID); err == nil {
+		t.Fatal("expected the attachment to be reachable only through its own message")
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestFileServiceUpload_EnforcesTotalStorageCap(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+
+	for _, m := range []struct{ id, user string }{{"m-cap-1", "u-cap-1"}, {"m-cap-2", "u-cap-2"}} {
+		if err := db.Create(&models.Message{
+			ID: m.id, UserID: m.user, Content: "x", KeyFragment: "v1",
+			ManagementToken: "tok-" + m.id, RecipientEmail: "a@example.com",
+			TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusActive,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewFileService(config.Config{
+		Database:   config.DatabaseConfig{UploadsDir: t.TempDir()},
+		Attachment: config.AttachmentConfig{MaxTotalStorageMB: 1},
+	})
+	if _, err := svc.Upload("u-cap-1", "m-cap-1", "a.txt", "text/plain", bytes.Repeat([]byte("a"), 700*1024)); err != nil {
+		t.Fatalf("first upload: %v", err)
+	}
+
+	// The cap covers every account, so the second user's upload is refused.
+	_, err := svc.Upload("u-cap-2", "m-cap-2", "b.txt", "text/plain", bytes.Repeat([]byte("b"), 400*1024))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != 507 || apiErr.Code != CodeStorageFull {
+		t.Fatalf("expected storage_full, got %v", err)
+	}
+
+	usage, err := svc.StorageUsage("u-cap-2")
+	if err != nil {
+		t.Fatalf("StorageUsage: %v", err)
+	}
+	if usage.UsedBytes != 0 || usage.TotalUsedBytes != nil || usage.LimitBytes != 1024*1024 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if usage.AvailableBytes == nil || *usage.AvailableBytes != 324*1024 {
+		t.Fatalf("AvailableBytes = %v, want %d", usage.AvailableBytes, 324*1024)
+	}
+
+	// Only the primary administrator sees what every account uses together.
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{ID: "u-cap-1", Email: "admin@example.com", PasswordHash: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	admin, err := svc.StorageUsage("u-cap-1")
+	if err != nil || admin.TotalUsedBytes == nil || *admin.TotalUsedBytes != 700*1024 {
+		t.Fatalf("admin usage = %+v (%v), want the total", admin, err)
+	}
+
+	uncapped, err := NewFileService(config.Config{}).StorageUsage("u-cap-1")
+	if err != nil || uncapped.UsedBytes != 700*1024 || uncapped.AvailableBytes != nil {
+		t.Fatalf("uncapped usage = %+v (%v)", uncapped, err)
+	}
+}
+
+func TestReserveStorageKeepsConcurrentUploadsUnderCap(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, models.Message{ID: "m-race", UserID: "u-race"})
+
+	svc := NewFileService(config.Config{Attachment: config.AttachmentConfig{MaxTotalStorageMB: 1}})
+	// Each upload fits on its own, but only two fit under the cap together.
+	// The pause before each record is stored lets every upload check the
+	// cap before any other is recorded, unless reservations are serialized.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = svc.reserveStorage(400*1024, func(tx *gorm.DB) error {
+				time.Sleep(10 * time.Millisecond)
+				return tx.Create(&models.Attachment{
+					ID: fmt.Sprintf("a-race-%d", i), UserID: "u-race", MessageID: "m-race",
+					Filename: "f.txt", StoragePath: fmt.Sprintf("/tmp/race-%d.enc", i), Size: 400 * 1024, MimeType: "text/plain",
+				}).Error
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	usage, err := svc.StorageUsage("u-race")
+	if err != nil {
+		t.Fatalf("StorageUsage: %v", err)
+	}
+	if usage.UsedBytes != 800*1024 {
+		t.Fatalf("used %d bytes of a %d byte cap, want two uploads stored", usage.UsedBytes, usage.LimitBytes)
+	}
+}
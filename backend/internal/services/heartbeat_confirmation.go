@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+)
+
+// HeartbeatConfirmationService emails the owner that a check-in registered
+// and when the next switch is due. Confirmations are opt-in per account
+// (Settings.HeartbeatConfirmation) and throttled to one per
+// HeartbeatConfirmationIntervalMinutes, so frequent check-ins from a script
+// do not fill the inbox.
+type HeartbeatConfirmationService struct {
+	cfg      config.Config
+	settings ports.SettingsServicePort
+	send     func(settings models.Settings, recipients []string, subject, body string) error
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func NewHeartbeatConfirmationService(cfg config.Config, settings ports.SettingsServicePort) *HeartbeatConfirmationService {
+	return &HeartbeatConfirmationService{
+		cfg:      cfg,
+		settings: settings,
+		send:     NewEmailService(cfg).SendPlain,
+		lastSent: map[string]time.Time{},
+	}
+}
+
+// Confirm sends the confirmation in the background, so the heartbeat
+// response does not wait on SMTP.
+func (s *HeartbeatConfirmationService) Confirm(userID string) {
+	go s.confirm(userID, time.Now().UTC())
+}
+
+// confirm reports whether a confirmation was sent.
+func (s *HeartbeatConfirmationService) confirm(userID string, now time.Time) bool {
+	settings, err := s.settings.Get(userID)
+	if err != nil {
+		slog.Error("Failed to load settings for heartbeat confirmation", "error", err, "user_id", userID)
+		return false
+	}
+	if !settings.HeartbeatConfirmation || settings.OwnerEmail == "" || settings.SMTPHost == "" {
+		return false
+	}
+	var active []models.Message
+	if err := database.ForTenant(userID).Where("status = ?", models.StatusActive).Find(&active).Error; err != nil {
+		slog.Error("Failed to load switches for heartbeat confirmation", "error", err, "user_id", userID)
+		return false
+	}
+	if !s.claim(userID, now) {
+		return false
+	}
+
+	next := "You have no active switches."
+	if len(active) > 0 {
+		deadline := active[0].TriggerAt()
+		for _, msg := range active[1:] {
+			if at := msg.TriggerAt(); at.Before(deadline) {
+				deadline = at
+			}
+		}
+		next = "Next deadline: " + FormatOwnerTime(settings, deadline)
+	}
+	body := fmt.Sprintf("Your check-in was received at %s.\n\n%s", FormatOwnerTime(settings, now), next)
+	if err := s.send(settings, []string{settings.OwnerEmail}, "Check-in received", AppendEmailFooter(settings, body)); err != nil {
+		slog.Error("Failed to send heartbeat confirmation", "error", err, "user_id", userID)
+		return false
+	}
+	return true
+}
+
+// claim records a confirmation for userID unless one was sent within the
+// throttle interval.
+func (s *HeartbeatConfirmationService) claim(userID string, now time.Time) bool {
+	minutes := s.cfg.Worker.HeartbeatConfirmationIntervalMinutes
+	if minutes <= 0 {
+		minutes = common.DefaultHeartbeatConfirmationIntervalMinutes
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.lastSent[userID]; ok && now.Sub(last) < time.Duration(minutes)*time.Minute {
+		return false
+	}
+	s.lastSent[userID] = now
+	return true
+}
+
+// confirmingMessageService sends a heartbeat confirmation after every
+// successful check-in; all other calls pass straight through to the base.
+type confirmingMessageService struct {
+	ports.MessageServicePort
+	confirmations *HeartbeatConfirmationService
+}
+
+// NewConfirmingMessageService wraps base so heartbeats, per switch or bulk,
+// trigger confirmations.
+func NewConfirmingMessageService(base ports.MessageServicePort, confirmations *HeartbeatConfirmationService) ports.MessageServicePort {
+	return confirmingMessageService{MessageServicePort: base, confirmations: confirmations}
+}
+
+func (s confirmingMessageService) Heartbeat(userID, id string) (models.Message, error) {
+	msg, err := s.MessageServicePort.Heartbeat(userID, id)
+	if err == nil {
+		s.confirmations.Confirm(userID)
+	}
+	return msg, err
+}
+
+func (s confirmingMessageService) BulkHeartbeat(userID string) error {
+	err := s.MessageServicePort.BulkHeartbeat(userID)
+	if err == nil {
+		s.confirmations.Confirm(userID)
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestHeartbeatConfirmationThrottlesPerAccount(t *testing.T) {
+	db := setupTestDB(t)
+	lastSeen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for id, minutes := range map[string]int{"m-day": 1440, "m-hour": 60} {
+		if err := db.Create(&models.Message{
+			ID: id, UserID: "u1", KeyFragment: "v1", ManagementToken: "tok-" + id, RecipientEmail: "friend@example.com",
+			TriggerDuration: minutes, LastSeen: lastSeen, Status: models.StatusActive,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var bodies []string
+	svc := NewHeartbeatConfirmationService(config.Config{}, lockoutSettingsStub{settings: models.Settings{
+		OwnerEmail: "owner@example.com", SMTPHost: "smtp.example.com", HeartbeatConfirmation: true,
+	}})
+	svc.send = func(_ models.Settings, recipients []string, subject, body string) error {
+		if len(recipients) != 1 || recipients[0] != "owner@example.com" || subject != "Check-in received" {
+			t.Fatalf("unexpected confirmation %v %q", recipients, subject)
+		}
+		bodies = append(bodies, body)
+		return nil
+	}
+
+	if !svc.confirm("u1", lastSeen) {
+		t.Fatal("expected the first check-in to be confirmed")
+	}
+	if !strings.Contains(bodies[0], "Next deadline: "+FormatOwnerTime(models.Settings{}, lastSeen.Add(time.Hour))) {
+		t.Fatalf("confirmation should name the earliest deadline: %q", bodies[0])
+	}
+	if svc.confirm("u1", lastSeen.Add(10*time.Minute)) {
+		t.Fatal("expected a second check-in within the interval to be throttled")
+	}
+	if !svc.confirm("u1", lastSeen.Add(61*time.Minute)) {
+		t.Fatal("expected a confirmation once the interval has passed")
+	}
+}
+
+func TestHeartbeatConfirmationRequiresOptIn(t *testing.T) {
+	setupTestDB(t)
+	svc := NewHeartbeatConfirmationService(config.Config{}, lockoutSettingsStub{settings: models.Settings{
+		OwnerEmail: "owner@example.com", SMTPHost: "smtp.example.com",
+	}})
+	svc.send = func(models.Settings, []string, string, string) error {
+		t.Fatal("no confirmation expected when heartbeat_confirmation is off")
+		return nil
+	}
+	if svc.confirm("u1", time.Now()) {
+		t.Fatal("expected no confirmation without opt-in")
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuickHeartbeatNonceTTL bounds how long a one-click confirm link rendered on
+// the quick-heartbeat page stays valid.
+const QuickHeartbeatNonceTTL = 15 * time.Minute
+
+// NewQuickHeartbeatNonce returns "<unix>.<mac>" bound to token. The nonce only
+// ever appears on the rendered page, never in the emailed link, so a mail
+// client prefetching the email link cannot reach the confirm endpoint.
+func NewQuickHeartbeatNonce(token string, now time.Time) string {
+	issued := strconv.FormatInt(now.Unix(), 10)
+	return issued + "." + quickHeartbeatMAC(token, issued)
+}
+
+// VerifyQuickHeartbeatNonce reports whether nonce was issued for token within
+// QuickHeartbeatNonceTTL of now.
+func VerifyQuickHeartbeatNonce(token, nonce string, now time.Time) bool {
+	issued, mac, ok := strings.Cut(nonce, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(unix, 0))
+	if age < 0 || age > QuickHeartbeatNonceTTL {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(quickHeartbeatMAC(token, issued)))
+}
+
+func quickHeartbeatMAC(token, issued string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte("quick-heartbeat-confirm:" + issued))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuickHeartbeatNonce(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	nonce := NewQuickHeartbeatNonce("tok", now)
+
+	if !VerifyQuickHeartbeatNonce("tok", nonce, now.Add(time.Minute)) {
+		t.Fatal("fresh nonce should verify")
+	}
+	if VerifyQuickHeartbeatNonce("other", nonce, now) {
+		t.Fatal("nonce must be bound to its token")
+	}
+	if VerifyQuickHeartbeatNonce("tok", nonce, now.Add(QuickHeartbeatNonceTTL+time.Second)) {
+		t.Fatal("expired nonce must be rejected")
+	}
+	for _, bad := range []string{"", "garbage", "1800000000.deadbeef"} {
+		if VerifyQuickHeartbeatNonce("tok", bad, now) {
+			t.Fatalf("nonce %q must be rejected", bad)
+		}
+	}
+}
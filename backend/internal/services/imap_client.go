@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapTimeout bounds a whole IMAP session so a stalled server cannot hold up
+// the worker tick.
+const imapTimeout = 30 * time.Second
+
+// imapMaxLiteral caps a single literal read from the server.
+const imapMaxLiteral = 1 << 20
+
+// imapClient is the small subset of IMAP4rev1 (RFC 3501) the email check-in
+// poller needs: LOGIN, SELECT, UID SEARCH, UID FETCH and UID STORE over
+// implicit TLS.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapMessage is one fetched message: its UID and raw RFC 5322 bytes.
+type imapMessage struct {
+	UID uint32
+	Raw []byte
+}
+
+func dialIMAP(host, port string, tlsConfig *tls.Config) (*imapClient, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, port), tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newIMAPClient(conn)
+}
+
+func newIMAPClient(conn net.Conn) (*imapClient, error) {
+	_ = conn.SetDeadline(time.Now().Add(imapTimeout))
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", greeting)
+	}
+	return c, nil
+}
+
+func (c *imapClient) Close() error {
+	_, _ = c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+func (c *imapClient) Login(user, pass string) error {
+	u, err := imapQuote(user)
+	if err != nil {
+		return err
+	}
+	p, err := imapQuote(pass)
+	if err != nil {
+		return err
+	}
+	_, err = c.command("LOGIN " + u + " " + p)
+	return err
+}
+
+func (c *imapClient) Select(mailbox string) error {
+	m, err := imapQuote(mailbox)
+	if err != nil {
+		return err
+	}
+	_, err = c.command("SELECT " + m)
+	return err
+}
+
+// SearchUnseenSubject returns the UIDs of unseen messages whose subject
+// contains needle.
+func (c *imapClient) SearchUnseenSubject(needle string) ([]uint32, error) {
+	n, err := imapQuote(needle)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := c.command("UID SEARCH UNSEEN SUBJECT " + n)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, line := range lines {
+		rest, ok := strings.CutPrefix(string(line), "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UID in SEARCH response: %q", field)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns the raw message for uid without setting \Seen.
+func (c *imapClient) Fetch(uid uint32) (imapMessage, error) {
+	lines, err := c.command(fmt.Sprintf("UID FETCH %d (BODY.PEEK[])", uid))
+	if err != nil {
+		return imapMessage{}, err
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(string(line), "* ") || !strings.Contains(string(line), "FETCH") {
+			continue
+		}
+		raw, ok := imapLiteralAfter(line, "BODY[]")
+		if ok {
+			return imapMessage{UID: uid, Raw: raw}, nil
+		}
+	}
+	return imapMessage{}, fmt.Errorf("message %d not returned by FETCH", uid)
+}
+
+// MarkSeen sets \Seen on uid so it is not polled again.
+func (c *imapClient) MarkSeen(uid uint32) error {
+	_, err := c.command(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid))
+	return err
+}
+
+// command sends one tagged command and returns its untagged responses, with
+// any literals inlined, once the server answers OK.
+func (c *imapClient) command(cmd string) ([][]byte, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	if _, err := io.WriteString(c.conn, tag+" "+cmd+"\r\n"); err != nil {
+		return nil, err
+	}
+	var untagged [][]byte
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		line := string(resp)
+		if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+			if strings.HasPrefix(rest, "OK") {
+				return untagged, nil
+			}
+			verb, _, _ := strings.Cut(cmd, " ")
+			return nil, fmt.Errorf("IMAP %s failed: %s", verb, rest)
+		}
+		untagged = append(untagged, resp)
+	}
+}
+
+// readResponse reads one logical response line, following "{n}" literals.
+func (c *imapClient) readResponse() ([]byte, error) {
+	var out []byte
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, line...)
+		size, ok := imapLiteralSize(line)
+		if !ok {
+			return out, nil
+		}
+		if size > imapMaxLiteral {
+			return nil, fmt.Errorf("IMAP literal of %d bytes exceeds limit", size)
+		}
+		out = append(out, "\r\n"...)
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return nil, err
+		}
+		out = append(out, literal...)
+	}
+}
+
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func imapLiteralSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(line[open+1 : len(line)-1])
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+// imapLiteralAfter extracts the literal following item in a response built
+// by readResponse.
+func imapLiteralAfter(resp []byte, item string) ([]byte, bool) {
+	s := string(resp)
+	idx := strings.Index(s, item+" {")
+	if idx < 0 {
+		return nil, false
+	}
+	rest := s[idx+len(item)+2:]
+	end := strings.Index(rest, "}\r\n")
+	if end < 0 {
+		return nil, false
+	}
+	size, err := strconv.Atoi(rest[:end])
+	if err != nil || size < 0 || end+3+size > len(rest) {
+		return nil, false
+	}
+	start := end + 3
+	return []byte(rest[start : start+size]), true
+}
+
+func imapQuote(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n\x00") {
+		return "", errors.New("IMAP argument contains a line break")
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`, nil
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // KeySource defines the interface for retrieving encryption keys
@@ -119,6 +122,18 @@ type FileKeySource struct {
 	path string
 }
 
+var (
+	allowInsecureKeyPerms atomic.Bool
+	warnedInsecureKeys    sync.Map
+)
+
+// SetAllowInsecureKeyPerms (ALLOW_INSECURE_KEY_PERMS) lets key files that are
+// not 0600 load with a warning, for filesystems that cannot represent Unix
+// permissions.
+func SetAllowInsecureKeyPerms(allow bool) {
+	allowInsecureKeyPerms.Store(allow)
+}
+
 func (s *FileKeySource) Name() string { return "Secure File" }
 func (s *FileKeySource) Available() bool {
 	_, err := os.Stat(s.path)
@@ -134,7 +149,13 @@ func (s *FileKeySource) GetKey() (string, error) {
 
 	mode := info.Mode().Perm()
 	if mode != 0600 {
-		return "", fmt.Errorf("key file %s has insecure permissions %04o (must be 0600)", s.path, mode)
+		if !allowInsecureKeyPerms.Load() {
+			return "", fmt.Errorf("key file %s has insecure permissions %04o (must be 0600)", s.path, mode)
+		}
+		// Warn once per file; SMTP_PASSWORD_FILE is read on every settings load.
+		if _, warned := warnedInsecureKeys.LoadOrStore(s.path, true); !warned {
+			slog.Warn("Key file has insecure permissions; loading it anyway because ALLOW_INSECURE_KEY_PERMS is set. Other local users may be able to read it.", "path", s.path, "mode", fmt.Sprintf("%04o", mode))
+		}
 	}
 
 	data, err := os.ReadFile(s.path)
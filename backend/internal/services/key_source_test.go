@@ -2,6 +2,8 @@ package services
 
 import (
 	"encoding/base64"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -50,3 +52,25 @@ func TestGenerateKey(t *testing.T) {
 		t.Fatalf("expected 32-byte key, got %d", len(decoded))
 	}
 }
+
+func TestFileKeySourceAllowInsecureKeyPerms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "encryption_key")
+	if err := os.WriteFile(path, []byte("secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := &FileKeySource{path: path}
+
+	if _, err := source.GetKey(); err == nil {
+		t.Fatal("expected a 0644 key file to be rejected by default")
+	}
+
+	SetAllowInsecureKeyPerms(true)
+	t.Cleanup(func() { SetAllowInsecureKeyPerms(false) })
+	key, err := source.GetKey()
+	if err != nil || key != "secret" {
+		t.Fatalf("expected the key to load with ALLOW_INSECURE_KEY_PERMS, got %q (%v)", key, err)
+	}
+}
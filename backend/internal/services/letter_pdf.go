@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// renderLetterPDF lays out a delivered message as an A4 letter headed by
+// title. The built-in Helvetica only covers Windows-1252, so unless
+// PDF_FONT_FILE supplies a Unicode font, text it cannot show is an error
+// rather than a letter with missing characters.
+func (s EmailService) renderLetterPDF(title, content string) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(25, 25, 25)
+	pdf.SetAutoPageBreak(true, 25)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Aeterna", true)
+
+	family, titleStyle := "Helvetica", "B"
+	if s.pdfFontFile != "" {
+		font, err := os.ReadFile(s.pdfFontFile)
+		if err != nil {
+			return nil, fmt.Errorf("read PDF_FONT_FILE: %w", err)
+		}
+		family, titleStyle = "letter", ""
+		pdf.AddUTF8FontFromBytes(family, "", font)
+	} else {
+		var err error
+		if title, err = toWindows1252(title); err != nil {
+			return nil, err
+		}
+		if content, err = toWindows1252(content); err != nil {
+			return nil, err
+		}
+	}
+
+	pdf.AddPage()
+	pdf.SetFont(family, titleStyle, 16)
+	pdf.MultiCell(0, 8, title, "", "L", false)
+	pdf.Ln(6)
+	pdf.SetFont(family, "", 11)
+	pdf.MultiCell(0, 6, strings.ReplaceAll(content, "\r\n", "\n"), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("render PDF letter: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// toWindows1252 converts s to the encoding fpdf's core fonts expect.
+func toWindows1252(s string) (string, error) {
+	encoded, err := charmap.Windows1252.NewEncoder().String(s)
+	if err != nil {
+		return "", fmt.Errorf("letter uses characters the built-in PDF font cannot show; set PDF_FONT_FILE: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+)
+
+// LockoutAlertService emails an account's owner when failed logins for it
+// lock out a client IP. Alerts are opt-in per account
+// (Settings.LockoutAlertsEnabled) and throttled to one per
+// LockoutAlertIntervalMinutes so a sustained attack cannot flood the inbox.
+type LockoutAlertService struct {
+	cfg      config.Config
+	settings ports.SettingsServicePort
+	send     func(settings models.Settings, recipients []string, subject, body string) error
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func NewLockoutAlertService(cfg config.Config, settings ports.SettingsServicePort) *LockoutAlertService {
+	return &LockoutAlertService{
+		cfg:      cfg,
+		settings: settings,
+		send:     NewEmailService(cfg).SendPlain,
+		lastSent: map[string]time.Time{},
+	}
+}
+
+// NotifyLockout sends the alert in the background, so the login response
+// takes the same time whether or not email names an existing account.
+func (s *LockoutAlertService) NotifyLockout(email, ip string, attempts int) {
+	slog.Warn("Login locked out after repeated failures", "ip", ip, "attempts", attempts)
+	go s.notify(email, ip, attempts, time.Now())
+}
+
+// notify reports whether an alert was sent.
+func (s *LockoutAlertService) notify(email, ip string, attempts int, now time.Time) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	var user models.User
+	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return false
+	}
+	settings, err := s.settings.Get(user.ID)
+	if err != nil {
+		slog.Error("Failed to load settings for lockout alert", "error", err, "user_id", user.ID)
+		return false
+	}
+	if !settings.LockoutAlertsEnabled || settings.OwnerEmail == "" || settings.SMTPHost == "" {
+		return false
+	}
+	if !s.claim(user.ID, now) {
+		return false
+	}
+
+	subject := "Security alert: failed login attempts on Aeterna"
+	body := fmt.Sprintf("There were %d failed login attempts on your Aeterna account from IP %s, so that address has been temporarily locked out.\n\n"+
+		"Time: %s\n\n"+
+		"If this was you, wait a few minutes and try again. If it was not, someone may be trying to guess your password; consider changing it.",
+		attempts, ip, FormatOwnerTime(settings, now))
+	if err := s.send(settings, []string{settings.OwnerEmail}, subject, AppendEmailFooter(settings, body)); err != nil {
+		slog.Error("Failed to send lockout alert", "error", err, "user_id", user.ID)
+		return false
+	}
+	return true
+}
+
+// claim records an alert for userID unless one was sent within the throttle
+// interval.
+func (s *LockoutAlertService) claim(userID string, now time.Time) bool {
+	minutes := s.cfg.Auth.LockoutAlertIntervalMinutes
+	if minutes <= 0 {
+		minutes = common.DefaultLockoutAlertIntervalMinutes
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.lastSent[userID]; ok && now.Sub(last) < time.Duration(minutes)*time.Minute {
+		return false
+	}
+	s.lastSent[userID] = now
+	return true
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+)
+
+type lockoutSettingsStub struct {
+	ports.SettingsServicePort
+	settings models.Settings
+}
+
+func (s lockoutSettingsStub) Get(userID string) (models.Settings, error) {
+	out := s.settings
+	out.UserID = userID
+	return out, nil
+}
+
+func TestLockoutAlertServiceThrottlesPerAccount(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{ID: "u-lock", Email: "owner@example.com", PasswordHash: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var bodies []string
+	svc := NewLockoutAlertService(config.Config{}, lockoutSettingsStub{settings: models.Settings{
+		OwnerEmail: "owner@example.com", SMTPHost: "smtp.example.com", LockoutAlertsEnabled: true,
+	}})
+	svc.send = func(_ models.Settings, recipients []string, _, body string) error {
+		if len(recipients) != 1 || recipients[0] != "owner@example.com" {
+			t.Fatalf("unexpected recipients %v", recipients)
+		}
+		bodies = append(bodies, body)
+		return nil
+	}
+
+	now := time.Now()
+	if !svc.notify(" Owner@Example.com ", "198.51.100.7", 5, now) {
+		t.Fatal("expected the first lockout to send an alert")
+	}
+	if !strings.Contains(bodies[0], "5 failed login attempts") || !strings.Contains(bodies[0], "198.51.100.7") {
+		t.Fatalf("alert body missing attempt count or IP: %q", bodies[0])
+	}
+	if svc.notify("owner@example.com", "198.51.100.8", 6, now.Add(30*time.Minute)) {
+		t.Fatal("expected a second lockout within the interval to be throttled")
+	}
+	if !svc.notify("owner@example.com", "198.51.100.8", 6, now.Add(61*time.Minute)) {
+		t.Fatal("expected an alert once the interval has passed")
+	}
+	if svc.notify("nobody@example.com", "198.51.100.7", 5, now.Add(2*time.Hour)) {
+		t.Fatal("unknown accounts must not produce an alert")
+	}
+}
+
+func TestLockoutAlertServiceRequiresOptIn(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{ID: "u-off", Email: "owner@example.com", PasswordHash: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := NewLockoutAlertService(config.Config{}, lockoutSettingsStub{settings: models.Settings{
+		OwnerEmail: "owner@example.com", SMTPHost: "smtp.example.com",
+	}})
+	svc.send = func(models.Settings, []string, string, string) error {
+		t.Fatal("no alert expected when lockout alerts are disabled")
+		return nil
+	}
+	if svc.notify("owner@example.com", "198.51.100.7", 5, time.Now()) {
+		t.Fatal("expected no alert without opt-in")
+	}
+}
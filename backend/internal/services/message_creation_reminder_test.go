@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestCreationRemindersSurviveEditsAndHeartbeats(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	sentAt := time.Now().UTC().Add(-time.Hour)
+	if err := db.Create(&models.Message{
+		ID: "m1", UserID: "u1", Content: "x", KeyFragment: "v1",
+		ManagementToken: "tok-m1", RecipientEmail: "a@example.com",
+		TriggerDuration: 60 * 24 * 365, LastSeen: time.Now(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.MessageReminder{
+		MessageID: "m1", RelativeTo: models.ReminderRelativeToCreation, MinutesAfter: 60,
+		Sent: true, LastReminderAt: &sentAt,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	creation := func() map[int]bool {
+		t.Helper()
+		var reminders []models.MessageReminder
+		if err := db.Where("message_id = ? AND relative_to = ?", "m1", models.ReminderRelativeToCreation).Find(&reminders).Error; err != nil {
+			t.Fatal(err)
+		}
+		out := map[int]bool{}
+		for _, r := range reminders {
+			out[r.MinutesAfter] = r.Sent
+		}
+		return out
+	}
+
+	svc := MessageService{}
+	if _, err := svc.Update("u1", "m1", "hello", nil, 60*24*365, []int{60}, 0, nil, nil, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := creation(); len(got) != 1 || !got[60] {
+		t.Fatalf("an update without creation_reminders must keep them, got %v", got)
+	}
+
+	if _, err := svc.Update("u1", "m1", "hello", nil, 60*24*365, []int{60}, 0, nil, nil, []int{60, 24 * 60}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := creation(); len(got) != 2 || !got[60] || got[24*60] {
+		t.Fatalf("the sent reminder must stay sent and the new one pending, got %v", got)
+	}
+
+	if err := svc.BulkHeartbeat("u1"); err != nil {
+		t.Fatalf("BulkHeartbeat: %v", err)
+	}
+	if got := creation(); !got[60] {
+		t.Fatalf("a heartbeat must not re-arm a creation reminder, got %v", got)
+	}
+
+	if _, err := svc.Update("u1", "m1", "hello", nil, 60*24*365, nil, 0, nil, nil, []int{0}); err == nil {
+		t.Fatal("expected an error for a creation reminder at minute 0")
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageHeartbeat_ClearsDeliveryHold(t *testing.T) {
+	db := setupTestDB(t)
+	heldUntil := time.Now().Add(5 * time.Hour).UTC()
+	if err := db.Create(&models.Message{
+		ID: "m-held", UserID: "u-held", Content: "x", KeyFragment: "v1",
+		ManagementToken: "tok", RecipientEmail: "a@a.com",
+		TriggerDuration: 60, LastSeen: time.Now().Add(-2 * time.Hour), Status: models.StatusActive,
+		DeliveryWindow:    models.DeliveryWindow{StartHour: 9, EndHour: 17},
+		DeliveryHeldUntil: &heldUntil,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := (MessageService{}).Heartbeat("u-held", "m-held")
+	if err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+	if msg.DeliveryHeldUntil != nil {
+		t.Fatalf("expected heartbeat to cancel the held delivery, got %v", msg.DeliveryHeldUntil)
+	}
+
+	if err := db.Model(&models.Message{}).Where("id = ?", "m-held").Update("delivery_held_until", heldUntil).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := (MessageService{}).BulkHeartbeat("u-held"); err != nil {
+		t.Fatalf("BulkHeartbeat failed: %v", err)
+	}
+	var stored models.Message
+	if err := db.First(&stored, "id = ?", "m-held").Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.DeliveryHeldUntil != nil {
+		t.Fatalf("expected bulk heartbeat to cancel the held delivery, got %v", stored.DeliveryHeldUntil)
+	}
+	if stored.DeliveryWindow.StartHour != 9 || stored.DeliveryWindow.EndHour != 17 {
+		t.Fatalf("expected the delivery window to persist, got %+v", stored.DeliveryWindow)
+	}
+}
+
+func TestValidateDeliveryWindow(t *testing.T) {
+	v := ValidationService{}
+	for _, ok := range []*models.DeliveryWindow{nil, {}, {StartHour: 22, EndHour: 6, Timezone: "Europe/Berlin"}} {
+		if err := v.ValidateDeliveryWindow(ok); err != nil {
+			t.Fatalf("expected %+v to be accepted: %v", ok, err)
+		}
+	}
+	for _, bad := range []*models.DeliveryWindow{{StartHour: 24}, {EndHour: -1}, {StartHour: 9, EndHour: 17, Timezone: "Mars/Olympus"}} {
+		if err := v.ValidateDeliveryWindow(bad); err == nil {
+			t.Fatalf("expected %+v to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+// markdownPattern matches lines that only make sense as Markdown: headings,
+// list items, block quotes, fenced code and inline links.
+var markdownPattern = regexp.MustCompile("(?m)^(#{1,6} |[-*+] |\\d+\\. |> |```)|\\[[^\\]\\n]+\\]\\([^)\\s]+\\)")
+
+// ExportContent returns a switch's decrypted content as a downloadable file,
+// named .md when the content looks like Markdown and .txt otherwise. Every
+// export is logged, since it takes the plaintext out of Aeterna.
+func (s MessageService) ExportContent(userID, id string) (filename, mimeType string, data []byte, err error) {
+	msg, err := s.GetByID(userID, id)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if msg.ShreddedAt != nil {
+		return "", "", nil, BadRequest("Message content was shredded after delivery", nil)
+	}
+	filename, mimeType = "aeterna-message-"+msg.ID+".txt", "text/plain; charset=utf-8"
+	if markdownPattern.MatchString(msg.Content) {
+		filename, mimeType = "aeterna-message-"+msg.ID+".md", "text/markdown; charset=utf-8"
+	}
+	slog.Info("Message content exported", "user_id", userID, "message_id", msg.ID, "filename", filename)
+	return filename, mimeType, []byte(msg.Content), nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageExportContent(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+
+	cases := []struct {
+		name     string
+		content  string
+		filename string
+		mimeType string
+	}{
+		{"plain text", "Dear Sam,\nthe keys are in the drawer.", ".txt", "text/plain; charset=utf-8"},
+		{"hash without space", "#1 priority: feed the cat", ".txt", "text/plain; charset=utf-8"},
+		{"heading", "# Instructions\n\nCall the lawyer.", ".md", "text/markdown; charset=utf-8"},
+		{"list", "Accounts:\n- bank\n- email", ".md", "text/markdown; charset=utf-8"},
+		{"link", "See [the will](https://example.com/will).", ".md", "text/markdown; charset=utf-8"},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id := "m-export-" + string(rune('a'+i))
+			encrypted, err := (CryptoService{}).EncryptWithContext(tc.content, MessageContentContext(id))
+			if err != nil {
+				t.Fatal(err)
+			}
+			createMessage(t, db, models.Message{ID: id, Content: encrypted})
+
+			filename, mimeType, data, err := (MessageService{}).ExportContent("u1", id)
+			if err != nil {
+				t.Fatalf("ExportContent: %v", err)
+			}
+			if filename != "aeterna-message-"+id+tc.filename || mimeType != tc.mimeType {
+				t.Fatalf("got %q (%s), want extension %s (%s)", filename, mimeType, tc.filename, tc.mimeType)
+			}
+			if string(data) != tc.content {
+				t.Fatalf("content = %q, want %q", data, tc.content)
+			}
+		})
+	}
+
+	if _, _, _, err := (MessageService{}).ExportContent("u2", "m-export-a"); err == nil {
+		t.Fatal("another user must not be able to export the message")
+	}
+}
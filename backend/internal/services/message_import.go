@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"gorm.io/gorm"
+)
+
+// MaxImportRows caps how many switches one import may create.
+const MaxImportRows = 500
+
+// Import creates one switch per row, all or none. Every row is validated
+// as Create validates a single switch; when any row is rejected nothing is
+// created and the results say which rows failed and why. Owner-level
+// problems such as MAX_MESSAGES or missing SMTP settings fail the whole
+// import with an error instead.
+func (s MessageService) Import(ctx context.Context, userID string, rows []ports.MessageImportRow) ([]ports.MessageImportResult, error) {
+	if len(rows) == 0 {
+		return nil, BadRequest("The import contains no messages", nil)
+	}
+	if len(rows) > MaxImportRows {
+		return nil, BadRequest(fmt.Sprintf("An import may contain at most %d messages", MaxImportRows), nil)
+	}
+	// Rechecked inside the import transaction, where the count is final.
+	if err := s.checkMessageLimit(database.DB, userID, len(rows)); err != nil {
+		return nil, err
+	}
+	if err := s.checkDeliveryConfigured(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	results := make([]ports.MessageImportResult, len(rows))
+	prepared := make([]models.Message, len(rows))
+	rejected := false
+	for i, row := range rows {
+		results[i].Row = i + 1
+		msg, err := s.prepareMessage(userID, row.Content, row.RecipientEmails, row.TriggerDuration, 1, nil, "", nil)
+		if err != nil {
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Status >= 500 {
+				return nil, err
+			}
+			results[i].Error = apiErr.Message
+			results[i].Code = apiErr.Code
+			results[i].Field = apiErr.Field
+			rejected = true
+			continue
+		}
+		prepared[i] = msg
+	}
+	if rejected {
+		return results, nil
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkMessageLimit(tx, userID, len(prepared)); err != nil {
+			return err
+		}
+		for i := range prepared {
+			if err := insertMessage(tx, &prepared[i], nil, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range prepared {
+		results[i].ID = prepared[i].ID
+	}
+	return results, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"gorm.io/gorm"
+)
+
+func TestMessageCreate_RejectsOverMaxMessages(t *testing.T) {
+	db := setupTestDB(t)
+	for _, id := range []string{"m1", "m2"} {
+		createMessage(t, db, models.Message{ID: id})
+	}
+
+	svc := NewMessageService(config.Config{App: config.AppConfig{MaxMessages: 2}})
+	_, err := svc.Create(context.Background(), "u1", "hello", []string{"b@b.com"}, 60, nil, 1, nil, "", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeMessageLimitReached || apiErr.Status != 403 {
+		t.Fatalf("expected message_limit_reached, got %v", err)
+	}
+
+	if err := svc.checkMessageLimit(db, "u2", 1); err != nil {
+		t.Fatalf("other accounts are counted separately: %v", err)
+	}
+	if err := (MessageService{}).checkMessageLimit(db, "u1", 1); err != nil {
+		t.Fatalf("zero-value service should be unlimited: %v", err)
+	}
+}
+
+func TestMessageLimit_CountsRowsInsertedInTheTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, models.Message{ID: "m1"})
+
+	svc := NewMessageService(config.Config{App: config.AppConfig{MaxMessages: 2}})
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := svc.checkMessageLimit(tx, "u1", 1); err != nil {
+			t.Fatalf("one slot is still free: %v", err)
+		}
+		createMessage(t, tx, models.Message{ID: "m2"})
+		return svc.checkMessageLimit(tx, "u1", 1)
+	})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeMessageLimitReached {
+		t.Fatalf("expected the uncommitted row to take the last slot, got %v", err)
+	}
+}
+
+func TestMessageImport_CountsEveryRowAgainstMaxMessages(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, models.Message{ID: "m1"})
+
+	svc := NewMessageService(config.Config{App: config.AppConfig{MaxMessages: 2}})
+	rows := []ports.MessageImportRow{
+		{Content: "one", RecipientEmails: []string{"b@b.com"}, TriggerDuration: 60},
+		{Content: "two", RecipientEmails: []string{"c@c.com"}, TriggerDuration: 60},
+	}
+	_, err := svc.Import(context.Background(), "u1", rows)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeMessageLimitReached {
+		t.Fatalf("expected message_limit_reached for two rows with one slot left, got %v", err)
+	}
+	var count int64
+	db.Model(&models.Message{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("count = %d, want nothing imported", count)
+	}
+
+	if _, err := svc.Import(context.Background(), "u1", nil); !errors.As(err, &apiErr) || apiErr.Status != 400 {
+		t.Fatalf("expected an empty import to be rejected, got %v", err)
+	}
+}
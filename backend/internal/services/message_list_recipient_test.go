@@ -0,0 +1,113 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageListByRecipient_ExactMatchWithinRecipientList(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	enableTestRecipientEncryption(t)
+	encrypted, err := (CryptoService{}).Encrypt("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, recipients := range map[string]string{
+		"m-single": "alice@example.com",
+		"m-multi":  "bob@example.com,Alice@Example.com",
+		"m-legacy": "carol@example.com\nalice@example.com",
+		"m-prefix": "malice@example.com",
+	} {
+		createMessage(t, db, models.Message{ID: id, UserID: "u-list", Content: encrypted, RecipientEmail: recipients})
+	}
+	createMessage(t, db, models.Message{ID: "m-other-user", UserID: "u-else", Content: encrypted, RecipientEmail: "alice@example.com"})
+
+	messages, err := (MessageService{}).ListByRecipient("u-list", " ALICE@example.com ")
+	if err != nil {
+		t.Fatalf("ListByRecipient failed: %v", err)
+	}
+	got := map[string]bool{}
+	for _, msg := range messages {
+		got[msg.ID] = true
+		if msg.Content != "hello" {
+			t.Fatalf("expected decrypted content, got %q", msg.Content)
+		}
+	}
+	if len(got) != 3 || !got["m-single"] || !got["m-multi"] || !got["m-legacy"] {
+		t.Fatalf("expected exactly the three messages to alice, got %v", got)
+	}
+}
+
+func TestMessageRecipientEmailIsEncryptedAtRest(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	enableTestRecipientEncryption(t)
+	createMessage(t, db, models.Message{ID: "m-sealed", RecipientEmail: "alice@example.com"})
+
+	var stored struct{ RecipientEmail, RecipientIndex string }
+	if err := db.Raw("SELECT recipient_email, recipient_index FROM messages WHERE id = ?", "m-sealed").Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.RecipientEmail, cryptoPrefix) || strings.Contains(stored.RecipientEmail, "alice") {
+		t.Fatalf("recipient_email stored as %q, want ciphertext", stored.RecipientEmail)
+	}
+	if stored.RecipientIndex == "" || strings.Contains(stored.RecipientIndex, "alice") {
+		t.Fatalf("recipient_index = %q, want keyed hashes", stored.RecipientIndex)
+	}
+
+	var msg models.Message
+	if err := db.First(&msg, "id = ?", "m-sealed").Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.RecipientEmail != "alice@example.com" {
+		t.Fatalf("RecipientEmail = %q after load, want plaintext", msg.RecipientEmail)
+	}
+}
+
+// enableTestRecipientEncryption registers the recipient cipher for the test.
+func enableTestRecipientEncryption(t *testing.T) {
+	t.Helper()
+	EnableRecipientEncryption()
+	t.Cleanup(func() { models.SetRecipientCipher(nil) })
+}
+
+func TestMessageListByStatus_TriggeredArchive(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	encrypted, err := (CryptoService{}).Encrypt("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	earlier, later := now.Add(-48*time.Hour), now.Add(-time.Hour)
+	for _, msg := range []models.Message{
+		{ID: "m-active", UserID: "u-list", Status: models.StatusActive},
+		{ID: "m-sent-earlier", UserID: "u-list", Status: models.StatusTriggered, TriggeredAt: &earlier, CreatedAt: now.Add(-time.Minute)},
+		{ID: "m-sent-later", UserID: "u-list", Status: models.StatusTriggered, TriggeredAt: &later, CreatedAt: now.Add(-72 * time.Hour)},
+		{ID: "m-other-user", UserID: "u-else", Status: models.StatusTriggered, TriggeredAt: &later},
+	} {
+		msg.Content, msg.KeyFragment, msg.ManagementToken = encrypted, "v1", "tok-"+msg.ID
+		msg.RecipientEmail, msg.TriggerDuration, msg.LastSeen = "alice@example.com", 60, now
+		if err := db.Create(&msg).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err := (MessageService{}).ListByStatus("u-list", models.StatusTriggered)
+	if err != nil {
+		t.Fatalf("ListByStatus failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != "m-sent-later" || messages[1].ID != "m-sent-earlier" {
+		t.Fatalf("expected this user's triggered messages, latest delivery first, got %+v", messages)
+	}
+	if messages[0].Content != "hello" {
+		t.Fatalf("expected decrypted content, got %q", messages[0].Content)
+	}
+	if _, err := (MessageService{}).ListByStatus("u-list", "sent"); err == nil {
+		t.Fatal("an unknown status must be rejected")
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageGetByManagementToken(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	encrypted, err := (CryptoService{}).Encrypt("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Message{
+		ID: "m-managed", UserID: "u-managed", Content: encrypted, KeyFragment: "v1",
+		ManagementToken: "mgmt-token", RecipientEmail: "a@a.com",
+		TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := (MessageService{}).GetByManagementToken("mgmt-token")
+	if err != nil {
+		t.Fatalf("GetByManagementToken failed: %v", err)
+	}
+	if msg.ID != "m-managed" || msg.UserID != "u-managed" || msg.Content != "hello" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.NextTriggerAt == nil {
+		t.Fatal("expected schedule to be populated")
+	}
+
+	for _, token := range []string{"", "other-token"} {
+		if _, err := (MessageService{}).GetByManagementToken(token); err == nil {
+			t.Fatalf("expected token %q to be rejected", token)
+		}
+	}
+}
+
+func TestMessageRotateManagementToken(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	encrypted, err := (CryptoService{}).Encrypt("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Message{
+		ID: "m-rotate", UserID: "u-rotate", Content: encrypted, KeyFragment: "v1",
+		ManagementToken: "old-token", RecipientEmail: "a@a.com",
+		TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (MessageService{}).RotateManagementToken("u-other", "m-rotate"); err == nil {
+		t.Fatal("expected another user's rotation to be rejected")
+	}
+	token, err := (MessageService{}).RotateManagementToken("u-rotate", "m-rotate")
+	if err != nil {
+		t.Fatalf("RotateManagementToken failed: %v", err)
+	}
+	if token == "" || token == "old-token" {
+		t.Fatalf("expected a new token, got %q", token)
+	}
+	if _, err := (MessageService{}).GetByManagementToken("old-token"); err == nil {
+		t.Fatal("expected the old token to stop working")
+	}
+	if msg, err := (MessageService{}).GetByManagementToken(token); err != nil || msg.ID != "m-rotate" {
+		t.Fatalf("expected the new token to resolve the message, got %+v, %v", msg, err)
+	}
+}
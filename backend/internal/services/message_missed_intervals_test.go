@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageList_NextTriggerAtSpansRequiredMissedIntervals(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	lastSeen := time.Date(2024, 2, 15, 9, 30, 0, 0, time.UTC)
+	encrypted, err := (CryptoService{}).Encrypt("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Message{
+		ID: "m-missed", UserID: "u-missed", Content: encrypted, KeyFragment: "v1",
+		ManagementToken: "tok", RecipientEmail: "a@a.com",
+		TriggerDuration: 60, LastSeen: lastSeen, Status: models.StatusActive,
+		RequiredMissedIntervals: 3,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := (MessageService{}).List("u-missed")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].NextTriggerAt == nil {
+		t.Fatalf("expected one message with next_trigger_at, got %+v", messages)
+	}
+	if want := lastSeen.Add(3 * time.Hour); !messages[0].NextTriggerAt.Equal(want) {
+		t.Fatalf("expected next_trigger_at=%s, got %s", want, messages[0].NextTriggerAt)
+	}
+}
+
+func TestMessageHeartbeat_ResetsMissedIntervals(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.Create(&models.Message{
+		ID: "m-reset", UserID: "u-reset", Content: "x", KeyFragment: "v1",
+		ManagementToken: "tok", RecipientEmail: "a@a.com",
+		TriggerDuration: 60, LastSeen: time.Now().Add(-2 * time.Hour), Status: models.StatusActive,
+		RequiredMissedIntervals: 3, MissedIntervals: 2,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := (MessageService{}).Heartbeat("u-reset", "m-reset")
+	if err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+	if msg.MissedIntervals != 0 {
+		t.Fatalf("expected missed intervals to reset, got %d", msg.MissedIntervals)
+	}
+
+	if err := db.Model(&models.Message{}).Where("id = ?", "m-reset").Update("missed_intervals", 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := (MessageService{}).BulkHeartbeat("u-reset"); err != nil {
+		t.Fatalf("BulkHeartbeat failed: %v", err)
+	}
+	var stored models.Message
+	if err := db.First(&stored, "id = ?", "m-reset").Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.MissedIntervals != 0 {
+		t.Fatalf("expected bulk heartbeat to reset missed intervals, got %d", stored.MissedIntervals)
+	}
+}
+
+func TestValidateRequiredMissedIntervals(t *testing.T) {
+	v := ValidationService{}
+	for _, ok := range []int{0, 1, MaxRequiredMissedIntervals} {
+		if err := v.ValidateRequiredMissedIntervals(ok); err != nil {
+			t.Fatalf("expected %d to be accepted: %v", ok, err)
+		}
+	}
+	for _, bad := range []int{-1, MaxRequiredMissedIntervals + 1} {
+		err := v.ValidateRequiredMissedIntervals(bad)
+		if err == nil {
+			t.Fatalf("expected %d to be rejected", bad)
+		}
+		if !strings.Contains(err.Error(), "or 0 for the default") {
+			t.Fatalf("expected the message to describe the accepted range, got %q", err.Error())
+		}
+	}
+}
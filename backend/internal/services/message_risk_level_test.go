@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageRiskLevel(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// A 100-minute switch: warning from 25 minutes left, critical from 10.
+	msgSeen := func(minutesAgo int, reminders ...int) models.Message {
+		msg := models.Message{
+			TriggerDuration: 100,
+			LastSeen:        now.Add(-time.Duration(minutesAgo) * time.Minute),
+			Status:          models.StatusActive,
+		}
+		for _, minutes := range reminders {
+			msg.Reminders = append(msg.Reminders, models.MessageReminder{MinutesBefore: minutes})
+		}
+		return msg
+	}
+
+	cases := []struct {
+		name string
+		msg  models.Message
+		want models.RiskLevel
+	}{
+		{"fresh", msgSeen(10), models.RiskLevelOK},
+		{"last quarter", msgSeen(76), models.RiskLevelWarning},
+		{"earliest reminder due", msgSeen(45, 60, 5), models.RiskLevelWarning},
+		{"before earliest reminder", msgSeen(35, 60), models.RiskLevelOK},
+		{"last tenth", msgSeen(91), models.RiskLevelCritical},
+		{"overdue", msgSeen(130), models.RiskLevelCritical},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := messageRiskLevel(tc.msg, now); got != tc.want {
+				t.Fatalf("messageRiskLevel = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnrichMessageSchedule_RiskLevelOnlyForActive(t *testing.T) {
+	msg := models.Message{TriggerDuration: 60, LastSeen: time.Now(), Status: models.StatusTriggered}
+	MessageService{}.enrichMessageSchedule(&msg)
+	if msg.RiskLevel != "" {
+		t.Fatalf("triggered message should carry no risk level, got %q", msg.RiskLevel)
+	}
+	msg.Status = models.StatusActive
+	MessageService{}.enrichMessageSchedule(&msg)
+	if msg.RiskLevel != models.RiskLevelOK {
+		t.Fatalf("RiskLevel = %q, want ok", msg.RiskLevel)
+	}
+}
@@ -1,17 +1,28 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
 	"github.com/alpyxn/aeterna/backend/internal/database"
 	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-type MessageService struct{}
+type MessageService struct {
+	cfg config.Config
+}
+
+func NewMessageService(cfg config.Config) MessageService {
+	return MessageService{cfg: cfg}
+}
 
 var cryptoService = CryptoService{}
 var msgValidationService = ValidationService{}
@@ -29,112 +40,248 @@ type farewellCountRow struct {
 	PendingFarewells int64
 }
 
-func enrichMessageSchedule(msg *models.Message) {
+func (s MessageService) enrichMessageSchedule(msg *models.Message) {
 	if msg == nil {
 		return
 	}
 
-	triggerAt := msg.LastSeen.UTC().Add(time.Duration(msg.TriggerDuration) * time.Minute)
+	triggerAt := msg.TriggerAt().UTC()
 	triggerAtUTC := triggerAt.UTC()
 	msg.NextTriggerAt = &triggerAtUTC
 	msg.NextReminderAt = nil
+	msg.RiskLevel = ""
 
 	if msg.Status != models.StatusActive {
 		return
 	}
+	msg.RiskLevel = messageRiskLevel(*msg, time.Now())
 
+	var lastSent *time.Time
 	for _, reminder := range msg.Reminders {
 		if reminder.Sent {
+			if reminder.LastReminderAt != nil && (lastSent == nil || reminder.LastReminderAt.After(*lastSent)) {
+				lastSent = reminder.LastReminderAt
+			}
 			continue
 		}
 		candidate := triggerAt.Add(-time.Duration(reminder.MinutesBefore) * time.Minute).UTC()
+		if reminder.FromCreation() {
+			candidate = msg.CreatedAt.Add(time.Duration(reminder.MinutesAfter) * time.Minute).UTC()
+		}
 		if msg.NextReminderAt == nil || candidate.Before(*msg.NextReminderAt) {
 			candidateUTC := candidate
 			msg.NextReminderAt = &candidateUTC
 		}
 	}
+
+	// The worker re-sends one interval after the most recent reminder.
+	if resend := time.Duration(s.cfg.Worker.ReminderResendHours) * time.Hour; resend > 0 && lastSent != nil {
+		candidate := lastSent.Add(resend).UTC()
+		if candidate.Before(triggerAt) && (msg.NextReminderAt == nil || candidate.Before(*msg.NextReminderAt)) {
+			msg.NextReminderAt = &candidate
+		}
+	}
 }
 
-func (s MessageService) Create(userID string, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
-	settings, err := msgSettingsService.Get(userID)
+// Risk thresholds, as a share of the time between last_seen and the trigger.
+const (
+	riskWarningShare  = 0.25
+	riskCriticalShare = 0.10
+)
+
+// messageRiskLevel grades an active switch for the dashboard. It is critical
+// in the last tenth of its period (or once overdue, e.g. while it waits for
+// its delivery window). It is a warning once its earliest reminder is due, or
+// in the last quarter of its period when it has no reminders that early.
+func messageRiskLevel(msg models.Message, now time.Time) models.RiskLevel {
+	triggerAt := msg.TriggerAt()
+	remaining := triggerAt.Sub(now)
+	period := triggerAt.Sub(msg.LastSeen)
+	if remaining <= 0 || float64(remaining) <= float64(period)*riskCriticalShare {
+		return models.RiskLevelCritical
+	}
+	warnWithin := time.Duration(float64(period) * riskWarningShare)
+	for _, reminder := range msg.Reminders {
+		if reminder.FromCreation() {
+			continue
+		}
+		if lead := time.Duration(reminder.MinutesBefore) * time.Minute; lead > warnWithin {
+			warnWithin = lead
+		}
+	}
+	if remaining <= warnWithin {
+		return models.RiskLevelWarning
+	}
+	return models.RiskLevelOK
+}
+
+func (s MessageService) Create(ctx context.Context, userID string, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject string, creationReminders []int) (models.Message, error) {
+	// Checked up front to fail before the SMTP test, and again inside the
+	// transaction so concurrent creates cannot both take the last slot.
+	if err := s.checkMessageLimit(database.DB, userID, 1); err != nil {
+		return models.Message{}, err
+	}
+	if err := s.checkDeliveryConfigured(ctx, userID); err != nil {
+		return models.Message{}, err
+	}
+	msg, err := s.prepareMessage(userID, content, recipientEmails, triggerDuration, requiredMissedIntervals, deliveryWindow, subject, creationReminders)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkMessageLimit(tx, userID, 1); err != nil {
+			return err
+		}
+		return insertMessage(tx, &msg, reminders, creationReminders)
+	})
 	if err != nil {
 		return models.Message{}, err
 	}
+
+	msg.Content = content
+	s.enrichMessageSchedule(&msg)
+	return msg, nil
+}
+
+// checkDeliveryConfigured refuses new switches until the owner's SMTP
+// settings are present and reachable. ctx bounds the SMTP test.
+func (s MessageService) checkDeliveryConfigured(ctx context.Context, userID string) error {
+	settings, err := NewSettingsService(s.cfg).Get(userID)
+	if err != nil {
+		return err
+	}
 	if settings.SMTPUser == "" || settings.SMTPHost == "" {
-		return models.Message{}, BadRequest("SMTP_NOT_CONFIGURED: SMTP is not configured. Please go to Settings to configure your email server.", nil)
+		return NewAPIError(400, CodeSMTPNotConfigured, "SMTP_NOT_CONFIGURED: SMTP is not configured. Please go to Settings to configure your email server.", nil)
 	}
 
-	if err := msgSettingsService.TestSMTP(settings); err != nil {
-		return models.Message{}, BadRequest("SMTP_CONNECTION_FAILED: SMTP connection test failed. Please check your email settings.", err)
+	if err := msgSettingsService.TestSMTP(ctx, settings); err != nil {
+		return NewAPIError(400, CodeSMTPConnectionFailed, "SMTP_CONNECTION_FAILED: SMTP connection test failed. Please check your email settings.", err)
 	}
+	return nil
+}
 
+// prepareMessage validates a new switch and returns it with its content
+// encrypted, ready for insertMessage.
+func (s MessageService) prepareMessage(userID string, content string, recipientEmails []string, triggerDuration int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject string, creationReminders []int) (models.Message, error) {
 	if err := msgValidationService.ValidateTriggerDuration(triggerDuration); err != nil {
 		return models.Message{}, err
 	}
+	if err := msgValidationService.ValidateRequiredMissedIntervals(requiredMissedIntervals); err != nil {
+		return models.Message{}, err
+	}
+	if err := msgValidationService.ValidateDeliveryWindow(deliveryWindow); err != nil {
+		return models.Message{}, err
+	}
+	if err := msgValidationService.ValidateCreationReminders(creationReminders); err != nil {
+		return models.Message{}, err
+	}
 
 	if err := msgValidationService.ValidateContent(content); err != nil {
 		return models.Message{}, err
 	}
+	subject = msgValidationService.SanitizeSubject(subject)
+	if err := msgValidationService.ValidateSubject(subject); err != nil {
+		return models.Message{}, err
+	}
 
 	if len(recipientEmails) == 0 {
-		return models.Message{}, BadRequest("At least one recipient email is required", nil)
+		return models.Message{}, InvalidField("recipient_emails", "At least one recipient email is required", nil)
 	}
 	for _, recipientEmail := range recipientEmails {
-		if err := msgValidationService.ValidateEmail(recipientEmail); err != nil {
+		if err := msgValidationService.ValidateEmail("recipient_email", recipientEmail); err != nil {
 			return models.Message{}, err
 		}
 	}
+	if err := s.checkRecipientDomains(recipientEmails); err != nil {
+		return models.Message{}, err
+	}
 
 	normalizedRecipients := strings.Join(recipientEmails, ",")
 	if len(normalizedRecipients) > 2000 {
-		return models.Message{}, BadRequest("Too many recipient emails", nil)
+		return models.Message{}, InvalidField("recipient_emails", "Too many recipient emails", nil)
 	}
 
 	if err := msgValidationService.ValidateEmailListLength(len(recipientEmails)); err != nil {
 		return models.Message{}, err
 	}
 
-	encrypted, err := cryptoService.Encrypt(content)
+	// The ID is assigned up front so the content can be bound to it.
+	id := uuid.NewString()
+	encrypted, err := cryptoService.EncryptWithContext(content, MessageContentContext(id))
 	if err != nil {
 		return models.Message{}, err
 	}
 
+	// LastSeen and CreatedAt share one instant so the worker's first-trigger
+	// check and its LastSeen check agree.
+	now := time.Now().UTC()
 	msg := models.Message{
+		ID:              id,
 		UserID:          userID,
 		Content:         encrypted,
 		KeyFragment:     "v1",
 		RecipientEmail:  normalizedRecipients,
+		Subject:         subject,
 		TriggerDuration: triggerDuration,
-		LastSeen:        time.Now().UTC(),
+		LastSeen:        now,
 		Status:          models.StatusActive,
+		CreatedAt:       now,
+
+		RequiredMissedIntervals: max(requiredMissedIntervals, 1),
+	}
+	if deliveryWindow != nil {
+		msg.DeliveryWindow = deliveryWindow.Normalized()
 	}
+	return msg, nil
+}
 
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&msg).Error; err != nil {
-			return Internal("Failed to create message", err)
-		}
+// insertMessage stores a prepared switch with its reminders and its creation
+// transition inside tx.
+func insertMessage(tx *gorm.DB, msg *models.Message, reminders []int, creationReminders []int) error {
+	if err := tx.Create(msg).Error; err != nil {
+		return Internal("Failed to create message", err)
+	}
+	if err := RecordStatusTransition(tx, *msg, "", models.StatusActive, models.TransitionReasonCreated, models.TransitionActorOwner); err != nil {
+		return Internal("Failed to record status transition", err)
+	}
 
-		for _, minutesBefore := range reminders {
-			reminder := models.MessageReminder{
-				MessageID:     msg.ID,
-				MinutesBefore: minutesBefore,
-				Sent:          false,
-			}
-			if err := tx.Create(&reminder).Error; err != nil {
-				return Internal("Failed to create reminder", err)
-			}
-			msg.Reminders = append(msg.Reminders, reminder)
+	for _, minutesBefore := range reminders {
+		reminder := models.MessageReminder{
+			MessageID:     msg.ID,
+			MinutesBefore: minutesBefore,
+			Sent:          false,
 		}
-		return nil
-	})
-
+		if err := tx.Create(&reminder).Error; err != nil {
+			return Internal("Failed to create reminder", err)
+		}
+		msg.Reminders = append(msg.Reminders, reminder)
+	}
+	created, err := createCreationReminders(tx, msg.ID, creationReminders, nil)
 	if err != nil {
-		return models.Message{}, err
+		return err
 	}
+	msg.Reminders = append(msg.Reminders, created...)
+	return nil
+}
 
-	msg.Content = content
-	enrichMessageSchedule(&msg)
-	return msg, nil
+// checkMessageLimit enforces MAX_MESSAGES for adding more switches, counting
+// through db so it can run inside the transaction that inserts them.
+// Triggered switches still count, since they keep their content until
+// deleted.
+func (s MessageService) checkMessageLimit(db *gorm.DB, userID string, adding int) error {
+	limit := s.cfg.App.MaxMessages
+	if limit <= 0 {
+		return nil
+	}
+	var count int64
+	if err := database.TenantTx(db, userID).Model(&models.Message{}).Count(&count).Error; err != nil {
+		return Internal("Failed to count messages", err)
+	}
+	if count+int64(adding) > int64(limit) {
+		return NewAPIError(403, CodeMessageLimitReached, fmt.Sprintf("This account has reached the limit of %d messages. Delete one to create another.", limit), nil)
+	}
+	return nil
 }
 
 // GetPublicByID loads a message by ID for the unauthenticated reveal endpoint (no tenant check).
@@ -146,7 +293,7 @@ func (s MessageService) GetPublicByID(id string) (models.Message, error) {
 		}
 		return models.Message{}, Internal("Failed to fetch message", err)
 	}
-	decrypted, err := cryptoService.Decrypt(msg.Content)
+	decrypted, err := decryptMessageContent(msg)
 	if err != nil {
 		return models.Message{}, err
 	}
@@ -154,10 +301,49 @@ func (s MessageService) GetPublicByID(id string) (models.Message, error) {
 
 	count, _ := msgFileService.CountByMessageID(msg.UserID, id)
 	msg.AttachmentCount = count
+	if msg.Status == models.StatusTriggered && count > 0 {
+		attachments, err := revealableAttachments(msg.UserID, msg.ID)
+		if err != nil {
+			return models.Message{}, err
+		}
+		msg.RevealAttachments = attachments
+	}
+	if msg.Status == models.StatusTriggered {
+		included, err := deliveredAttachments(msg.UserID, msg.ID)
+		if err != nil {
+			return models.Message{}, err
+		}
+		msg.IncludedAttachments = included
+	}
 
 	return msg, nil
 }
 
+// decryptMessageContent returns msg's plaintext content, or "" once it has
+// been shredded.
+func decryptMessageContent(msg models.Message) (string, error) {
+	if msg.ShreddedAt != nil {
+		return "", nil
+	}
+	return cryptoService.DecryptWithContext(msg.Content, MessageContentContext(msg.ID))
+}
+
+// GetByManagementToken loads the single message a management token grants
+// access to. The token stands in for the owner's session on /m/ routes.
+func (s MessageService) GetByManagementToken(token string) (models.Message, error) {
+	if token == "" {
+		return models.Message{}, NotFound("Message not found", nil)
+	}
+	var owner models.Message
+	if err := database.DB.Select("id", "user_id").First(&owner, "management_token = ?", token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Message{}, NotFound("Message not found", err)
+		}
+		return models.Message{}, Internal("Failed to fetch message", err)
+	}
+	return s.GetByID(owner.UserID, owner.ID)
+}
+
 func (s MessageService) GetByID(userID, id string) (models.Message, error) {
 	var msg models.Message
 	if err := database.ForTenant(userID).Preload("Reminders").First(&msg, "id = ?", id).Error; err != nil {
@@ -166,7 +352,7 @@ func (s MessageService) GetByID(userID, id string) (models.Message, error) {
 		}
 		return models.Message{}, Internal("Failed to fetch message", err)
 	}
-	decrypted, err := cryptoService.Decrypt(msg.Content)
+	decrypted, err := decryptMessageContent(msg)
 	if err != nil {
 		return models.Message{}, err
 	}
@@ -174,14 +360,45 @@ func (s MessageService) GetByID(userID, id string) (models.Message, error) {
 
 	count, _ := msgFileService.CountByMessageID(userID, id)
 	msg.AttachmentCount = count
-	enrichMessageSchedule(&msg)
+	s.enrichMessageSchedule(&msg)
 
 	return msg, nil
 }
 
 func (s MessageService) List(userID string) ([]models.Message, error) {
+	return s.list(userID, database.ForTenant(userID))
+}
+
+// ListByRecipient lists the messages that deliver to recipient. Since
+// recipient_email is encrypted, the exact match runs in SQL against the
+// blind index.
+func (s MessageService) ListByRecipient(userID, recipient string) ([]models.Message, error) {
+	hash, err := recipientIndexHash(recipient)
+	if err != nil {
+		return nil, err
+	}
+	query := database.ForTenant(userID).Where("instr(recipient_index, ?) > 0", ","+hash+",")
+	return s.list(userID, query)
+}
+
+// ListByStatus lists the messages in one status. Triggered messages, the
+// archive of what has been delivered, come most recently delivered first.
+func (s MessageService) ListByStatus(userID string, status models.MessageStatus) ([]models.Message, error) {
+	query := database.ForTenant(userID)
+	switch status {
+	case models.StatusActive, models.StatusError:
+	case models.StatusTriggered:
+		query = query.Order("triggered_at DESC")
+	default:
+		return nil, BadRequest("Status must be active, triggered or error", nil)
+	}
+	return s.list(userID, query.Where("status = ?", status))
+}
+
+// list loads the messages matched by query and decorates them for the API.
+func (s MessageService) list(userID string, query *gorm.DB) ([]models.Message, error) {
 	var messages []models.Message
-	if err := database.ForTenant(userID).Preload("Reminders").Order("created_at DESC").Find(&messages).Error; err != nil {
+	if err := query.Preload("Reminders").Order("created_at DESC").Find(&messages).Error; err != nil {
 		return nil, Internal("Failed to fetch messages", err)
 	}
 
@@ -191,7 +408,7 @@ func (s MessageService) List(userID string) ([]models.Message, error) {
 
 	msgIDs := make([]string, len(messages))
 	for i := range messages {
-		decrypted, err := cryptoService.Decrypt(messages[i].Content)
+		decrypted, err := decryptMessageContent(messages[i])
 		if err != nil {
 			return nil, err
 		}
@@ -233,7 +450,7 @@ func (s MessageService) List(userID string) ([]models.Message, error) {
 			messages[i].FarewellCount = fc.Total
 			messages[i].PendingFarewells = fc.PendingFarewells
 		}
-		enrichMessageSchedule(&messages[i])
+		s.enrichMessageSchedule(&messages[i])
 	}
 
 	return messages, nil
@@ -253,15 +470,45 @@ func (s MessageService) Heartbeat(userID, id string) (models.Message, error) {
 	}
 
 	msg.LastSeen = time.Now().UTC()
-	if err := database.ForTenant(userID).Save(&msg).Error; err != nil {
+	msg.MissedIntervals = 0
+	msg.DeliveryHeldUntil = nil
+	from := msg.Status
+	if msg.Status == models.StatusError {
+		msg.Status = models.StatusActive
+	}
+	// A heartbeat lost to a transient lock could let the switch fire.
+	if err := database.RetryOnLock(func() error { return database.ForTenant(userID).Save(&msg).Error }); err != nil {
 		return models.Message{}, Internal("Failed to update heartbeat", err)
 	}
-	enrichMessageSchedule(&msg)
+	if from != msg.Status {
+		if err := RecordStatusTransition(database.DB, msg, from, msg.Status, models.TransitionReasonHeartbeat, models.TransitionActorOwner); err != nil {
+			slog.Error("Failed to record status transition", "error", err, "message_id", msg.ID)
+		}
+	}
+	s.enrichMessageSchedule(&msg)
 
 	return msg, nil
 }
 
-func (s MessageService) Delete(userID, id string) error {
+// RotateManagementToken issues a new management token for a switch. Links
+// carrying the old token stop working immediately.
+func (s MessageService) RotateManagementToken(userID, id string) (string, error) {
+	token := uuid.NewString()
+	result := database.ForTenant(userID).Model(&models.Message{}).Where("id = ?", id).Update("management_token", token)
+	if result.Error != nil {
+		return "", Internal("Failed to rotate management token", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return "", NotFound("Message not found", nil)
+	}
+	slog.Info("Management token rotated", "user_id", userID, "message_id", id)
+	return token, nil
+}
+
+// Delete removes a message with its attachments and farewell letters. A
+// message that has already been delivered leaves a MessageTombstone, and with
+// TRIGGERED_DELETE_POLICY=block it is only deleted when force is set.
+func (s MessageService) Delete(userID, id string, force bool) error {
 	var msg models.Message
 	if err := database.ForTenant(userID).First(&msg, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -269,6 +516,10 @@ func (s MessageService) Delete(userID, id string) error {
 		}
 		return Internal("Failed to fetch message", err)
 	}
+	triggered := msg.Status == models.StatusTriggered
+	if triggered && !force && s.cfg.App.TriggeredDeletePolicy != common.TriggeredDeleteAllow {
+		return NewAPIError(409, CodeMessageDelivered, "This message has already been delivered. Delete it with force=true; a record of the delivery is kept.", nil)
+	}
 
 	if err := msgFileService.DeleteByMessageID(userID, id); err != nil {
 		return Internal("Failed to delete attachments", err)
@@ -285,32 +536,140 @@ func (s MessageService) Delete(userID, id string) error {
 		}
 	}
 
-	if err := database.ForTenant(userID).Unscoped().Delete(&msg).Error; err != nil {
-		return Internal("Failed to delete message", err)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if triggered {
+			tombstone := models.MessageTombstone{
+				MessageID:      msg.ID,
+				UserID:         msg.UserID,
+				RecipientEmail: msg.RecipientEmail,
+				Subject:        msg.Subject,
+				CreatedAt:      msg.CreatedAt,
+				TriggeredAt:    msg.TriggeredAt,
+				DeletedAt:      time.Now().UTC(),
+			}
+			if err := tx.Create(&tombstone).Error; err != nil {
+				return Internal("Failed to record message tombstone", err)
+			}
+		}
+		if err := database.TenantTx(tx, userID).Unscoped().Delete(&msg).Error; err != nil {
+			return Internal("Failed to delete message", err)
+		}
+		return nil
+	})
+	if err == nil && triggered {
+		slog.Info("Delivered message deleted; tombstone kept", "user_id", userID, "message_id", id, "forced", force)
 	}
+	return err
+}
 
-	return nil
+// ListTombstones returns the records of the account's deleted delivered
+// messages, most recently deleted first, with their status history and
+// delivered files.
+func (s MessageService) ListTombstones(userID string) ([]models.MessageTombstone, error) {
+	tombstones := []models.MessageTombstone{}
+	if err := database.ForTenant(userID).Order("deleted_at DESC, id DESC").Find(&tombstones).Error; err != nil {
+		return nil, Internal("Failed to fetch message tombstones", err)
+	}
+	for i := range tombstones {
+		tombstones[i].Transitions = []models.StatusTransition{}
+		if err := database.ForTenant(userID).Where("message_id = ?", tombstones[i].MessageID).Order("created_at ASC, id ASC").Find(&tombstones[i].Transitions).Error; err != nil {
+			return nil, Internal("Failed to fetch status transitions", err)
+		}
+		delivered, err := deliveredAttachments(userID, tombstones[i].MessageID)
+		if err != nil {
+			return nil, err
+		}
+		tombstones[i].DeliveredAttachments = delivered
+	}
+	return tombstones, nil
 }
 
 // BulkHeartbeat resets last_seen for all active messages of a user and clears sent reminders.
+// Creation reminders are one-off and stay sent.
+// Switches parked in the error status become active again.
 func (s MessageService) BulkHeartbeat(userID string) error {
 	now := time.Now().UTC()
-	return database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := database.TenantTx(tx, userID).Model(&models.Message{}).
-			Where("status = ?", models.StatusActive).
-			Update("last_seen", now).Error; err != nil {
-			return Internal("failed to update heartbeats", err)
-		}
-		if err := tx.Model(&models.MessageReminder{}).
-			Where("message_id IN (SELECT id FROM messages WHERE user_id = ? AND status = ?)", userID, models.StatusActive).
-			Update("sent", false).Error; err != nil {
-			return Internal("failed to reset reminders", err)
-		}
-		return nil
+	return database.RetryOnLock(func() error {
+		return database.DB.Transaction(func(tx *gorm.DB) error {
+			var parked []models.Message
+			if err := database.TenantTx(tx, userID).Select("id", "user_id").
+				Where("status = ?", models.StatusError).Find(&parked).Error; err != nil {
+				return Internal("failed to load parked switches", err)
+			}
+			if err := database.TenantTx(tx, userID).Model(&models.Message{}).
+				Where("status IN ?", []models.MessageStatus{models.StatusActive, models.StatusError}).
+				Updates(map[string]interface{}{"last_seen": now, "missed_intervals": 0, "delivery_held_until": nil, "status": models.StatusActive}).Error; err != nil {
+				return Internal("failed to update heartbeats", err)
+			}
+			if err := tx.Model(&models.MessageReminder{}).
+				Where("message_id IN (SELECT id FROM messages WHERE user_id = ? AND status = ?)", userID, models.StatusActive).
+				Where("relative_to <> ?", models.ReminderRelativeToCreation).
+				Updates(map[string]interface{}{"sent": false, "last_reminder_at": nil}).Error; err != nil {
+				return Internal("failed to reset reminders", err)
+			}
+			for _, msg := range parked {
+				if err := RecordStatusTransition(tx, msg, models.StatusError, models.StatusActive, models.TransitionReasonHeartbeat, models.TransitionActorOwner); err != nil {
+					return Internal("failed to record status transition", err)
+				}
+			}
+			return nil
+		})
 	})
 }
 
-func (s MessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
+// ExtendDeadline pushes back the deadline of switch id, or of every active
+// switch when id is empty, by the given duration from the later of its
+// current deadline and now. The new deadline is stored in extended_until, so
+// last_seen keeps the time of the last check-in and a later check-in never
+// brings the deadline forward. Like a heartbeat it clears missed intervals,
+// held deliveries and sent reminders, and reactivates switches in the error
+// status.
+func (s MessageService) ExtendDeadline(userID, id string, by time.Duration) error {
+	now := time.Now().UTC()
+	return database.RetryOnLock(func() error {
+		return database.DB.Transaction(func(tx *gorm.DB) error {
+			var msgs []models.Message
+			query := database.TenantTx(tx, userID).Where("status IN ?", []models.MessageStatus{models.StatusActive, models.StatusError})
+			if id != "" {
+				query = database.TenantTx(tx, userID).Where("id = ?", id)
+			}
+			if err := query.Find(&msgs).Error; err != nil {
+				return Internal("failed to load switches", err)
+			}
+			if id != "" {
+				if len(msgs) == 0 {
+					return NotFound("Message not found", nil)
+				}
+				if msgs[0].Status == models.StatusTriggered {
+					return BadRequest("Cannot extend a triggered message. The message has already been delivered.", nil)
+				}
+			}
+			for _, msg := range msgs {
+				deadline := msg.TriggerAt()
+				if deadline.Before(now) {
+					deadline = now
+				}
+				if err := database.TenantTx(tx, userID).Model(&models.Message{}).Where("id = ?", msg.ID).
+					Updates(map[string]interface{}{"extended_until": deadline.Add(by), "missed_intervals": 0, "delivery_held_until": nil, "status": models.StatusActive}).Error; err != nil {
+					return Internal("failed to extend deadline", err)
+				}
+				if err := tx.Model(&models.MessageReminder{}).
+					Where("message_id = ? AND relative_to <> ?", msg.ID, models.ReminderRelativeToCreation).
+					Updates(map[string]interface{}{"sent": false, "last_reminder_at": nil}).Error; err != nil {
+					return Internal("failed to reset reminders", err)
+				}
+				if msg.Status == models.StatusError {
+					if err := RecordStatusTransition(tx, msg, models.StatusError, models.StatusActive, models.TransitionReasonExtended, models.TransitionActorOwner); err != nil {
+						return Internal("failed to record status transition", err)
+					}
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (s MessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject *string, creationReminders []int) (models.Message, error) {
 	var msg models.Message
 	if err := database.ForTenant(userID).First(&msg, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -330,33 +689,71 @@ func (s MessageService) Update(userID, id, content string, recipientEmails []str
 	if err := msgValidationService.ValidateTriggerDuration(triggerDuration); err != nil {
 		return models.Message{}, err
 	}
+	if err := msgValidationService.ValidateRequiredMissedIntervals(requiredMissedIntervals); err != nil {
+		return models.Message{}, err
+	}
+	if err := msgValidationService.ValidateDeliveryWindow(deliveryWindow); err != nil {
+		return models.Message{}, err
+	}
+	if err := msgValidationService.ValidateCreationReminders(creationReminders); err != nil {
+		return models.Message{}, err
+	}
+	if subject != nil {
+		msg.Subject = msgValidationService.SanitizeSubject(*subject)
+		if err := msgValidationService.ValidateSubject(msg.Subject); err != nil {
+			return models.Message{}, err
+		}
+	}
 
 	if len(recipientEmails) > 0 {
 		if err := msgValidationService.ValidateEmailListLength(len(recipientEmails)); err != nil {
 			return models.Message{}, err
 		}
 		for _, recipientEmail := range recipientEmails {
-			if err := msgValidationService.ValidateEmail(recipientEmail); err != nil {
+			if err := msgValidationService.ValidateEmail("recipient_email", recipientEmail); err != nil {
 				return models.Message{}, err
 			}
 		}
+		if err := s.checkRecipientDomains(recipientEmails); err != nil {
+			return models.Message{}, err
+		}
+		if err := checkAttachmentRecipients(userID, msg.ID, recipientEmails); err != nil {
+			return models.Message{}, err
+		}
 		msg.RecipientEmail = strings.Join(recipientEmails, ",")
 	}
 
-	encrypted, err := cryptoService.Encrypt(content)
+	encrypted, err := cryptoService.EncryptWithContext(content, MessageContentContext(msg.ID))
 	if err != nil {
 		return models.Message{}, err
 	}
 
 	msg.Content = encrypted
 	msg.TriggerDuration = triggerDuration
+	// 0 keeps the current tolerance so clients unaware of it don't reset it.
+	if requiredMissedIntervals > 0 {
+		msg.RequiredMissedIntervals = requiredMissedIntervals
+	}
+	if deliveryWindow != nil {
+		msg.DeliveryWindow = deliveryWindow.Normalized()
+	}
+	msg.MissedIntervals = 0
+	msg.DeliveryHeldUntil = nil
 	msg.LastSeen = time.Now().UTC()
 	err = database.DB.Transaction(func(tx *gorm.DB) error {
 		if err := database.TenantTx(tx, userID).Save(&msg).Error; err != nil {
 			return Internal("Failed to update message", err)
 		}
 
-		if err := tx.Where("message_id = ?", msg.ID).Delete(&models.MessageReminder{}).Error; err != nil {
+		var previous []models.MessageReminder
+		if err := tx.Where("message_id = ? AND relative_to = ?", msg.ID, models.ReminderRelativeToCreation).Find(&previous).Error; err != nil {
+			return Internal("Failed to fetch creation reminders", err)
+		}
+		stale := tx.Where("message_id = ?", msg.ID)
+		if creationReminders == nil {
+			stale = stale.Where("relative_to <> ?", models.ReminderRelativeToCreation)
+		}
+		if err := stale.Delete(&models.MessageReminder{}).Error; err != nil {
 			return Internal("Failed to delete old reminders", err)
 		}
 
@@ -372,7 +769,15 @@ func (s MessageService) Update(userID, id, content string, recipientEmails []str
 			}
 			msg.Reminders = append(msg.Reminders, reminder)
 		}
-
+		if creationReminders == nil {
+			msg.Reminders = append(msg.Reminders, previous...)
+			return nil
+		}
+		created, err := createCreationReminders(tx, msg.ID, creationReminders, previous)
+		if err != nil {
+			return err
+		}
+		msg.Reminders = append(msg.Reminders, created...)
 		return nil
 	})
 
@@ -381,6 +786,60 @@ func (s MessageService) Update(userID, id, content string, recipientEmails []str
 	}
 
 	msg.Content = content
-	enrichMessageSchedule(&msg)
+	s.enrichMessageSchedule(&msg)
 	return msg, nil
 }
+
+// checkAttachmentRecipients rejects a recipient list that drops someone an
+// attachment of the message is limited to, since the file would otherwise
+// be delivered to no one it was meant for.
+func checkAttachmentRecipients(userID, messageID string, recipientEmails []string) error {
+	var attachments []models.Attachment
+	if err := database.ForTenant(userID).Where("message_id = ?", messageID).Find(&attachments).Error; err != nil {
+		return Internal("Failed to fetch attachments", err)
+	}
+	for _, attachment := range attachments {
+		for _, selected := range ParseRecipientEmails(attachment.RecipientEmail) {
+			kept := false
+			for _, recipient := range recipientEmails {
+				if strings.EqualFold(strings.TrimSpace(recipient), selected) {
+					kept = true
+					break
+				}
+			}
+			if !kept {
+				return InvalidField("recipient_email", fmt.Sprintf("The attachment %s is limited to %s. Change its recipients before removing them from the message.", attachment.Filename, selected), nil)
+			}
+		}
+	}
+	return nil
+}
+
+// createCreationReminders stores a creation reminder for each offset in
+// minutesAfter. An offset that was already sent under previous keeps its
+// sent state, so editing a switch does not ask the same question twice.
+func createCreationReminders(tx *gorm.DB, messageID string, minutesAfter []int, previous []models.MessageReminder) ([]models.MessageReminder, error) {
+	sent := map[int]models.MessageReminder{}
+	for _, reminder := range previous {
+		if reminder.Sent {
+			sent[reminder.MinutesAfter] = reminder
+		}
+	}
+	created := make([]models.MessageReminder, 0, len(minutesAfter))
+	for _, minutes := range minutesAfter {
+		reminder := models.MessageReminder{
+			MessageID:    messageID,
+			RelativeTo:   models.ReminderRelativeToCreation,
+			MinutesAfter: minutes,
+		}
+		if old, ok := sent[minutes]; ok {
+			reminder.Sent = true
+			reminder.LastReminderAt = old.LastReminderAt
+		}
+		if err := tx.Create(&reminder).Error; err != nil {
+			return nil, Internal("Failed to create creation reminder", err)
+		}
+		created = append(created, reminder)
+	}
+	return created, nil
+}
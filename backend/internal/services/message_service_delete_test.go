@@ -1,11 +1,13 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/alpyxn/aeterna/backend/internal/config"
 	"github.com/alpyxn/aeterna/backend/internal/database"
 	"github.com/alpyxn/aeterna/backend/internal/models"
 	"gorm.io/driver/sqlite"
@@ -30,6 +32,10 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&models.Attachment{},
 		&models.FarewellLetter{},
 		&models.FarewellAttachment{},
+		&models.StatusTransition{},
+		&models.MessageTombstone{},
+		&models.DeliveredAttachment{},
+		&models.ApplicationSettings{},
 	); err != nil {
 		t.Fatal(err)
 	}
@@ -39,6 +45,40 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	return db
 }
 
+// createMessage stores msg after filling in the fields every switch needs:
+// an active one-hour switch last seen now, owned by u1.
+func createMessage(t *testing.T, db *gorm.DB, msg models.Message) models.Message {
+	t.Helper()
+	if msg.UserID == "" {
+		msg.UserID = "u1"
+	}
+	if msg.Content == "" {
+		msg.Content = "x"
+	}
+	if msg.KeyFragment == "" {
+		msg.KeyFragment = "v1"
+	}
+	if msg.ManagementToken == "" {
+		msg.ManagementToken = "tok-" + msg.ID
+	}
+	if msg.RecipientEmail == "" {
+		msg.RecipientEmail = "a@a.com"
+	}
+	if msg.TriggerDuration == 0 {
+		msg.TriggerDuration = 60
+	}
+	if msg.LastSeen.IsZero() {
+		msg.LastSeen = time.Now()
+	}
+	if msg.Status == "" {
+		msg.Status = models.StatusActive
+	}
+	if err := db.Create(&msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
 func TestMessageDelete_NoFarewellNoAttachments(t *testing.T) {
 	db := setupTestDB(t)
 	if err := db.Create(&models.Message{
@@ -49,7 +89,7 @@ func TestMessageDelete_NoFarewellNoAttachments(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := (MessageService{}).Delete("u1", "m1"); err != nil {
+	if err := (MessageService{}).Delete("u1", "m1", false); err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
 }
@@ -187,7 +227,7 @@ func TestMessageDelete_WithFarewellLetter(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := (MessageService{}).Delete("u1", "m1"); err != nil {
+	if err := (MessageService{}).Delete("u1", "m1", false); err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
 
@@ -197,3 +237,71 @@ func TestMessageDelete_WithFarewellLetter(t *testing.T) {
 		t.Fatalf("expected 0 farewell letters after delete, got %d", count)
 	}
 }
+
+func TestMessageDelete_TriggeredKeepsTombstone(t *testing.T) {
+	db := setupTestDB(t)
+	triggeredAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	for _, id := range []string{"m-sent", "m-allowed"} {
+		if err := db.Create(&models.Message{
+			ID: id, UserID: "u1", Content: "x", KeyFragment: "v1", Subject: "Goodbye",
+			ManagementToken: "tok-" + id, RecipientEmail: "a@a.com,b@b.com",
+			TriggerDuration: 60, LastSeen: triggeredAt.Add(-time.Hour), Status: models.StatusTriggered, TriggeredAt: &triggeredAt,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+		msg := models.Message{ID: id, UserID: "u1"}
+		if err := RecordStatusTransition(db, msg, models.StatusActive, models.StatusTriggered, models.TransitionReasonTriggered, models.TransitionActorWorker); err != nil {
+			t.Fatal(err)
+		}
+		if err := RecordDeliveredAttachments(db, msg, []models.Attachment{{Filename: "will.pdf", Size: 42}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := (MessageService{}).Delete("u1", "m-sent", false)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != 409 || apiErr.Code != CodeMessageDelivered {
+		t.Fatalf("deleting a delivered message without force: got %v, want 409 %s", err, CodeMessageDelivered)
+	}
+	var count int64
+	db.Model(&models.Message{}).Where("id = ?", "m-sent").Count(&count)
+	if count != 1 {
+		t.Fatal("a refused delete must keep the message")
+	}
+
+	if err := (MessageService{}).Delete("u1", "m-sent", true); err != nil {
+		t.Fatalf("forced Delete failed: %v", err)
+	}
+	allow := MessageService{cfg: config.Config{App: config.AppConfig{TriggeredDeletePolicy: "allow"}}}
+	if err := allow.Delete("u1", "m-allowed", false); err != nil {
+		t.Fatalf("Delete with TRIGGERED_DELETE_POLICY=allow failed: %v", err)
+	}
+
+	db.Unscoped().Model(&models.Message{}).Where("user_id = ?", "u1").Count(&count)
+	if count != 0 {
+		t.Fatalf("expected both messages deleted, %d left", count)
+	}
+	tombstones, err := (MessageService{}).ListTombstones("u1")
+	if err != nil {
+		t.Fatalf("ListTombstones failed: %v", err)
+	}
+	if len(tombstones) != 2 {
+		t.Fatalf("expected two tombstones, got %+v", tombstones)
+	}
+	for _, tombstone := range tombstones {
+		if tombstone.RecipientEmail != "a@a.com,b@b.com" || tombstone.Subject != "Goodbye" ||
+			tombstone.TriggeredAt == nil || !tombstone.TriggeredAt.Equal(triggeredAt) || tombstone.DeletedAt.IsZero() {
+			t.Fatalf("tombstone lost delivery metadata: %+v", tombstone)
+		}
+		if len(tombstone.Transitions) != 1 || tombstone.Transitions[0].Reason != models.TransitionReasonTriggered ||
+			tombstone.Transitions[0].Actor != models.TransitionActorWorker || tombstone.Transitions[0].ToStatus != models.StatusTriggered {
+			t.Fatalf("tombstone lost the trigger transition: %+v", tombstone.Transitions)
+		}
+		if len(tombstone.DeliveredAttachments) != 1 || tombstone.DeliveredAttachments[0].Filename != "will.pdf" || tombstone.DeliveredAttachments[0].Size != 42 {
+			t.Fatalf("tombstone lost the delivered files: %+v", tombstone.DeliveredAttachments)
+		}
+	}
+	if others, _ := (MessageService{}).ListTombstones("u2"); len(others) != 0 {
+		t.Fatalf("tombstones leaked to another account: %+v", others)
+	}
+}
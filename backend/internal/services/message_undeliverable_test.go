@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageHeartbeat_ReactivatesErroredSwitch(t *testing.T) {
+	db := setupTestDB(t)
+	for _, id := range []string{"m-one", "m-bulk"} {
+		createMessage(t, db, models.Message{ID: id, UserID: "u-err", LastSeen: time.Now().Add(-2 * time.Hour), Status: models.StatusError})
+	}
+
+	msg, err := (MessageService{}).Heartbeat("u-err", "m-one")
+	if err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+	if msg.Status != models.StatusActive {
+		t.Fatalf("status = %s, want active", msg.Status)
+	}
+
+	if err := (MessageService{}).BulkHeartbeat("u-err"); err != nil {
+		t.Fatalf("BulkHeartbeat failed: %v", err)
+	}
+	var stored models.Message
+	if err := db.First(&stored, "id = ?", "m-bulk").Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.StatusActive || time.Since(stored.LastSeen) > time.Minute {
+		t.Fatalf("bulk heartbeat should reactivate the switch: %+v", stored)
+	}
+}
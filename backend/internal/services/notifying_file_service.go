@@ -55,6 +55,26 @@ func (s *NotifyingFileService) CountByMessageID(userID, messageID string) (int64
 	return s.base.CountByMessageID(userID, messageID)
 }
 
+func (s *NotifyingFileService) GetThumbnail(userID, attachmentID string) ([]byte, error) {
+	return s.base.GetThumbnail(userID, attachmentID)
+}
+
+func (s *NotifyingFileService) ListRevealable(messageID string) ([]models.Attachment, error) {
+	return s.base.ListRevealable(messageID)
+}
+
+func (s *NotifyingFileService) GetRevealable(messageID, attachmentID string) (filename, mimeType string, data []byte, err error) {
+	return s.base.GetRevealable(messageID, attachmentID)
+}
+
+func (s *NotifyingFileService) SetRecipients(userID, attachmentID string, recipientEmails []string) (models.Attachment, error) {
+	attachment, err := s.base.SetRecipients(userID, attachmentID, recipientEmails)
+	if err == nil {
+		s.notifier.publish(userID, ports.EventTypeAttachmentsChanged, ports.EventCodeAttachmentUpdated, "attachment", attachment.ID, "recipients_updated")
+	}
+	return attachment, err
+}
+
 func (s *NotifyingFileService) UploadFarewellAttachment(userID, letterID, filename, mimeType string, data []byte) (models.FarewellAttachment, error) {
 	attachment, err := s.base.UploadFarewellAttachment(userID, letterID, filename, mimeType, data)
 	if err == nil {
@@ -86,3 +106,7 @@ func (s *NotifyingFileService) DeleteFarewellAttachmentsByLetterID(userID, lette
 func (s *NotifyingFileService) GetFarewellAttachmentDecrypted(userID, attachmentID string) (filename, mimeType string, data []byte, err error) {
 	return s.base.GetFarewellAttachmentDecrypted(userID, attachmentID)
 }
+
+func (s *NotifyingFileService) StorageUsage(userID string) (ports.StorageUsage, error) {
+	return s.base.StorageUsage(userID)
+}
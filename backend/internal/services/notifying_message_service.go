@@ -1,6 +1,9 @@
 package services
 
 import (
+	"context"
+	"time"
+
 	"github.com/alpyxn/aeterna/backend/internal/models"
 	"github.com/alpyxn/aeterna/backend/internal/ports"
 )
@@ -21,8 +24,8 @@ func (s *NotifyingMessageService) WithOriginSession(sessionKey string) ports.Mes
 	}
 }
 
-func (s *NotifyingMessageService) Create(userID, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
-	msg, err := s.base.Create(userID, content, recipientEmails, triggerDuration, reminders)
+func (s *NotifyingMessageService) Create(ctx context.Context, userID, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject string, creationReminders []int) (models.Message, error) {
+	msg, err := s.base.Create(ctx, userID, content, recipientEmails, triggerDuration, reminders, requiredMissedIntervals, deliveryWindow, subject, creationReminders)
 	if err == nil {
 		s.notifier.publish(userID, ports.EventTypeMessagesChanged, ports.EventCodeMessageCreated, "message", msg.ID, "created")
 	}
@@ -33,14 +36,50 @@ func (s *NotifyingMessageService) GetPublicByID(id string) (models.Message, erro
 	return s.base.GetPublicByID(id)
 }
 
+func (s *NotifyingMessageService) GetByManagementToken(token string) (models.Message, error) {
+	return s.base.GetByManagementToken(token)
+}
+
 func (s *NotifyingMessageService) GetByID(userID, id string) (models.Message, error) {
 	return s.base.GetByID(userID, id)
 }
 
+func (s *NotifyingMessageService) ExportContent(userID, id string) (filename, mimeType string, data []byte, err error) {
+	return s.base.ExportContent(userID, id)
+}
+
+func (s *NotifyingMessageService) ListTransitions(userID, id string) ([]models.StatusTransition, error) {
+	return s.base.ListTransitions(userID, id)
+}
+
+func (s *NotifyingMessageService) ListTombstones(userID string) ([]models.MessageTombstone, error) {
+	return s.base.ListTombstones(userID)
+}
+
+func (s *NotifyingMessageService) Import(ctx context.Context, userID string, rows []ports.MessageImportRow) ([]ports.MessageImportResult, error) {
+	results, err := s.base.Import(ctx, userID, rows)
+	if err == nil {
+		for _, result := range results {
+			if result.ID != "" {
+				s.notifier.publish(userID, ports.EventTypeMessagesChanged, ports.EventCodeMessageCreated, "message", result.ID, "created")
+			}
+		}
+	}
+	return results, err
+}
+
 func (s *NotifyingMessageService) List(userID string) ([]models.Message, error) {
 	return s.base.List(userID)
 }
 
+func (s *NotifyingMessageService) ListByRecipient(userID, recipient string) ([]models.Message, error) {
+	return s.base.ListByRecipient(userID, recipient)
+}
+
+func (s *NotifyingMessageService) ListByStatus(userID string, status models.MessageStatus) ([]models.Message, error) {
+	return s.base.ListByStatus(userID, status)
+}
+
 func (s *NotifyingMessageService) Heartbeat(userID, id string) (models.Message, error) {
 	msg, err := s.base.Heartbeat(userID, id)
 	if err == nil {
@@ -57,18 +96,34 @@ func (s *NotifyingMessageService) BulkHeartbeat(userID string) error {
 	return err
 }
 
-func (s *NotifyingMessageService) Delete(userID, id string) error {
-	err := s.base.Delete(userID, id)
+func (s *NotifyingMessageService) ExtendDeadline(userID, id string, by time.Duration) error {
+	err := s.base.ExtendDeadline(userID, id, by)
+	if err == nil {
+		s.notifier.publish(userID, ports.EventTypeMessagesChanged, ports.EventCodeMessageDeadlineExtended, "message", id, "deadline_extended")
+	}
+	return err
+}
+
+func (s *NotifyingMessageService) Delete(userID, id string, force bool) error {
+	err := s.base.Delete(userID, id, force)
 	if err == nil {
 		s.notifier.publish(userID, ports.EventTypeMessagesChanged, ports.EventCodeMessageDeleted, "message", id, "deleted")
 	}
 	return err
 }
 
-func (s *NotifyingMessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
-	msg, err := s.base.Update(userID, id, content, recipientEmails, triggerDuration, reminders)
+func (s *NotifyingMessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject *string, creationReminders []int) (models.Message, error) {
+	msg, err := s.base.Update(userID, id, content, recipientEmails, triggerDuration, reminders, requiredMissedIntervals, deliveryWindow, subject, creationReminders)
 	if err == nil {
 		s.notifier.publish(userID, ports.EventTypeMessagesChanged, ports.EventCodeMessageUpdated, "message", msg.ID, "updated")
 	}
 	return msg, err
 }
+
+func (s *NotifyingMessageService) RotateManagementToken(userID, id string) (string, error) {
+	token, err := s.base.RotateManagementToken(userID, id)
+	if err == nil {
+		s.notifier.publish(userID, ports.EventTypeMessagesChanged, ports.EventCodeMessageTokenRotated, "message", id, "management_token_rotated")
+	}
+	return token, err
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+
 	"github.com/alpyxn/aeterna/backend/internal/models"
 	"github.com/alpyxn/aeterna/backend/internal/ports"
 )
@@ -37,6 +39,14 @@ func (s *NotifyingSettingsService) Save(userID string, req models.Settings) erro
 	return err
 }
 
-func (s *NotifyingSettingsService) TestSMTP(req models.Settings) error {
-	return s.base.TestSMTP(req)
+func (s *NotifyingSettingsService) RotateHeartbeatToken(userID string) (string, error) {
+	token, err := s.base.RotateHeartbeatToken(userID)
+	if err == nil {
+		s.notifier.publish(userID, ports.EventTypeSettingsChanged, ports.EventCodeHeartbeatTokenRotated, "settings", "", "heartbeat_token_rotated")
+	}
+	return token, err
+}
+
+func (s *NotifyingSettingsService) TestSMTP(ctx context.Context, req models.Settings) error {
+	return s.base.TestSMTP(ctx, req)
 }
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/alpyxn/aeterna/backend/internal/models"
@@ -47,6 +48,14 @@ func (s *NotifyingWebhookStore) Update(userID, id string, input models.Webhook)
 	return updated, err
 }
 
+func (s *NotifyingWebhookStore) Test(ctx context.Context, userID, id string) (models.Webhook, error) {
+	tested, err := s.base.Test(ctx, userID, id)
+	if err == nil {
+		s.notifier.publish(userID, ports.EventTypeWebhooksChanged, ports.EventCodeWebhookUpdated, "webhook", fmt.Sprint(tested.ID), "tested")
+	}
+	return tested, err
+}
+
 func (s *NotifyingWebhookStore) Delete(userID, id string) error {
 	err := s.base.Delete(userID, id)
 	if err == nil {
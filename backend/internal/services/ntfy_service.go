@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ntfy priorities, see https://docs.ntfy.sh/publish/#message-priority.
+const (
+	NtfyPriorityDefault = "default"
+	NtfyPriorityHigh    = "high"
+	NtfyPriorityUrgent  = "urgent"
+)
+
+// NtfyService publishes push notifications to an ntfy topic.
+type NtfyService struct{}
+
+// Send posts message to topicURL. clickURL, when set, is opened when the
+// notification is tapped.
+func (s NtfyService) Send(topicURL, title, message, priority, clickURL string) error {
+	req, err := http.NewRequest(http.MethodPost, topicURL, strings.NewReader(message))
+	if err != nil {
+		return Internal("Failed to create ntfy request", err)
+	}
+	req.Header.Set("Title", title)
+	req.Header.Set("Priority", priority)
+	req.Header.Set("Tags", "hourglass")
+	if clickURL != "" {
+		req.Header.Set("Click", clickURL)
+	}
+
+	client := &http.Client{Timeout: 6 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Internal("ntfy request failed", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Internal("ntfy returned non-2xx status", errors.New(resp.Status))
+	}
+	return nil
+}
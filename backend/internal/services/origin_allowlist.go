@@ -0,0 +1,97 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// OriginAllowlist holds the live browser-origin allowlist used by CORS and the
+// session origin check. ALLOWED_ORIGINS is the bootstrap value; a list stored
+// in application settings replaces it until cleared.
+type OriginAllowlist struct {
+	bootstrap string
+	current   atomic.Value
+}
+
+func NewOriginAllowlist(bootstrap string) *OriginAllowlist {
+	o := &OriginAllowlist{bootstrap: bootstrap}
+	o.current.Store(bootstrap)
+	return o
+}
+
+// AllowedOrigins returns the comma-separated list currently in effect.
+func (o *OriginAllowlist) AllowedOrigins() string {
+	return o.current.Load().(string)
+}
+
+// Reload applies the stored override, if any, on top of the bootstrap value.
+func (o *OriginAllowlist) Reload() error {
+	app, err := ApplicationSettingsService{}.Get()
+	if err != nil {
+		return err
+	}
+	o.apply(app.AllowedOrigins)
+	return nil
+}
+
+// Validate reports whether Set would accept origins from actorUserID,
+// without storing them.
+func (o *OriginAllowlist) Validate(actorUserID, origins string) error {
+	_, err := o.normalize(actorUserID, origins)
+	return err
+}
+
+// Set stores a new allowlist, effective immediately. Only the primary
+// administrator may change it; an empty value reverts to ALLOWED_ORIGINS.
+func (o *OriginAllowlist) Set(actorUserID, origins string) error {
+	normalized, err := o.normalize(actorUserID, origins)
+	if err != nil {
+		return err
+	}
+	if err := saveApplicationSetting("allowed_origins", normalized); err != nil {
+		return err
+	}
+	o.apply(normalized)
+	return nil
+}
+
+func (o *OriginAllowlist) normalize(actorUserID, origins string) (string, error) {
+	if !IsFirstUser(actorUserID) {
+		return "", NewAPIError(403, CodeForbidden, "Only the primary administrator can change allowed origins.", nil)
+	}
+	return normalizeOriginList(origins)
+}
+
+func (o *OriginAllowlist) apply(stored string) {
+	if stored == "" {
+		o.current.Store(o.bootstrap)
+		return
+	}
+	o.current.Store(stored)
+}
+
+// normalizeOriginList validates a comma-separated list of scheme://host[:port]
+// origins. A leading "*." subdomain wildcard is allowed; the bare "*" wildcard
+// is only accepted from the environment.
+func normalizeOriginList(value string) (string, error) {
+	var out []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimRight(strings.TrimSpace(entry), "/")
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return "", BadRequest("Allowing every origin (*) can only be configured via ALLOWED_ORIGINS", nil)
+		}
+		parsed, err := url.Parse(entry)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" {
+			return "", BadRequest("Invalid origin: "+entry, err)
+		}
+		if host := parsed.Hostname(); strings.Contains(host, "*") && (!strings.HasPrefix(host, "*.") || strings.Count(host, "*") > 1) {
+			return "", BadRequest("Wildcards are only allowed as a leading subdomain (https://*.example.com): "+entry, nil)
+		}
+		out = append(out, parsed.Scheme+"://"+parsed.Host)
+	}
+	return strings.Join(out, ","), nil
+}
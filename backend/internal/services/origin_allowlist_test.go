@@ -0,0 +1,31 @@
+package services
+
+import "testing"
+
+func TestNormalizeOriginList(t *testing.T) {
+	got, err := normalizeOriginList(" https://app.example.com/ , http://localhost:5173 , https://*.example.org ,, ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "https://app.example.com,http://localhost:5173,https://*.example.org"; got != want {
+		t.Fatalf("normalizeOriginList = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"*", "app.example.com", "ftp://example.com", "https://example.com/path", "https://*example.com", "https://a.*.example.com"} {
+		if _, err := normalizeOriginList(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestOriginAllowlist_EmptyOverrideFallsBackToBootstrap(t *testing.T) {
+	o := NewOriginAllowlist("http://localhost:5173")
+	o.apply("https://new.example.com")
+	if got := o.AllowedOrigins(); got != "https://new.example.com" {
+		t.Fatalf("AllowedOrigins = %q, want stored override", got)
+	}
+	o.apply("")
+	if got := o.AllowedOrigins(); got != "http://localhost:5173" {
+		t.Fatalf("AllowedOrigins = %q, want bootstrap value", got)
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+// ownerTimeLayout renders times in emails, e.g. "2024-06-01 14:00 CEST".
+const ownerTimeLayout = "2006-01-02 15:04 MST"
+
+// OwnerLocation returns the owner's configured time zone, or UTC when none
+// is set or it no longer loads.
+func OwnerLocation(settings models.Settings) *time.Location {
+	if settings.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// FormatOwnerTime renders t in the owner's time zone for email bodies.
+func FormatOwnerTime(settings models.Settings, t time.Time) string {
+	return t.In(OwnerLocation(settings)).Format(ownerTimeLayout)
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestFormatOwnerTimeUsesOwnerTimezone(t *testing.T) {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if got := FormatOwnerTime(models.Settings{}, at); got != "2024-06-01 12:00 UTC" {
+		t.Fatalf("expected UTC without a timezone, got %q", got)
+	}
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	if got := FormatOwnerTime(models.Settings{Timezone: "Europe/Berlin"}, at); got != "2024-06-01 14:00 CEST" {
+		t.Fatalf("expected Berlin summer time, got %q", got)
+	}
+}
+
+func TestSettingsSaveValidatesTimezone(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := SettingsService{}
+	for _, bad := range []string{"Mars/Olympus", "Local"} {
+		if err := svc.Save("u1", models.Settings{Timezone: bad}); err == nil {
+			t.Fatalf("expected time zone %q to be rejected", bad)
+		}
+	}
+	if err := svc.Save("u1", models.Settings{Timezone: " America/New_York "}); err != nil {
+		t.Fatalf("expected a valid IANA zone to save: %v", err)
+	}
+	var stored models.Settings
+	if err := db.First(&stored, "user_id = ?", "u1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Timezone != "America/New_York" {
+		t.Fatalf("expected trimmed time zone to persist, got %q", stored.Timezone)
+	}
+}
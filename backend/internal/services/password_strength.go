@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords is a short, ranked list of passwords and keyboard walks
+// that are tried first by any guessing attack. Rank is the slice index + 1.
+var commonPasswords = []string{
+	"password", "123456", "12345678", "qwerty", "abc123", "123456789", "111111", "1234567", "iloveyou", "123123",
+	"admin", "1234567890", "letmein", "1234", "monkey", "shadow", "sunshine", "12345", "password1", "princess",
+	"azerty", "trustno1", "000000", "welcome", "dragon", "football", "baseball", "master", "hello", "freedom",
+	"whatever", "qazwsx", "login", "starwars", "superman", "michael", "jennifer", "jordan", "hunter", "buster",
+	"soccer", "harley", "batman", "andrew", "tigger", "charlie", "robert", "thomas", "hockey", "ranger",
+	"daniel", "secret", "summer", "winter", "spring", "autumn", "love", "jesus", "ninja", "mustang",
+	"access", "flower", "pepper", "computer", "internet", "cookie", "cheese", "killer", "asdf", "asdfgh",
+	"asdfghjkl", "zxcvbn", "zxcvbnm", "qwertyuiop", "qwertz", "changeme", "default", "root", "user", "test",
+	"guest", "pass", "secure", "aeterna", "deadman", "switch", "heartbeat", "january", "february", "december",
+}
+
+var commonPasswordRank = func() map[string]int {
+	ranks := make(map[string]int, len(commonPasswords))
+	for i, word := range commonPasswords {
+		ranks[word] = i + 1
+	}
+	return ranks
+}()
+
+// leetVariants undo common character substitutions; "1" and "!" are
+// ambiguous between "i" and "l", so both readings are tried.
+var leetVariants = []*strings.Replacer{
+	strings.NewReplacer("4", "a", "@", "a", "8", "b", "3", "e", "6", "g", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t", "+", "t", "2", "z"),
+	strings.NewReplacer("4", "a", "@", "a", "8", "b", "3", "e", "6", "g", "1", "l", "!", "l", "0", "o", "$", "s", "5", "s", "7", "t", "+", "t", "2", "z"),
+}
+
+// PasswordScore estimates how hard password is to guess on zxcvbn's 0-4
+// scale. The password is split into the cheapest sequence of common
+// passwords, repeats, character sequences and brute-forced characters, and
+// the summed guesses are bucketed at 10^3, 10^6, 10^8 and 10^10 (inclusive).
+func PasswordScore(password string) int {
+	guesses := passwordLog10Guesses([]rune(password))
+	switch {
+	case guesses <= 3:
+		return 0
+	case guesses <= 6:
+		return 1
+	case guesses <= 8:
+		return 2
+	case guesses <= 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// ValidatePasswordEntropy applies the "entropy" PASSWORD_POLICY: any mix of
+// characters is accepted as long as PasswordScore reaches minScore.
+func (s ValidationService) ValidatePasswordEntropy(field, password string, minScore int) error {
+	if len(password) < 8 {
+		return InvalidField(field, "Password must be at least 8 characters", nil)
+	}
+	if len(password) > 128 {
+		return InvalidField(field, "Password exceeds maximum length", nil)
+	}
+	if score := PasswordScore(password); score < minScore {
+		return InvalidField(field, fmt.Sprintf("Password is too easy to guess (strength %d of 4, at least %d required). Try a longer passphrase.", score, minScore), nil)
+	}
+	return nil
+}
+
+// passwordLog10Guesses returns log10 of the estimated guesses for runes.
+// Every character costs at least 10 guesses when brute-forced.
+func passwordLog10Guesses(runes []rune) float64 {
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = best[i-1] + 1
+		for j := 0; j < i-2; j++ {
+			if g, ok := patternLog10Guesses(runes[j:i]); ok && best[j]+g < best[i] {
+				best[i] = best[j] + g
+			}
+		}
+	}
+	return best[len(runes)]
+}
+
+// patternLog10Guesses reports the guesses for seg (at least three runes) if
+// it is a repeat, an ascending or descending sequence, or a common password.
+func patternLog10Guesses(seg []rune) (float64, bool) {
+	n := float64(len(seg))
+	if isRepeat(seg) {
+		return math.Log10(10 * n), true
+	}
+	if isSequence(seg) {
+		return math.Log10(26 * n), true
+	}
+
+	lower := strings.ToLower(string(seg))
+	variation := upperCaseVariation(seg)
+	if rank, ok := commonPasswordRank[lower]; ok {
+		return math.Max(1, math.Log10(float64(rank))+variation), true
+	}
+	for _, leet := range leetVariants {
+		if unleeted := leet.Replace(lower); unleeted != lower {
+			if rank, ok := commonPasswordRank[unleeted]; ok {
+				return math.Max(1, math.Log10(float64(rank))+variation+math.Log10(2)), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func isRepeat(seg []rune) bool {
+	for _, r := range seg[1:] {
+		if r != seg[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func isSequence(seg []rune) bool {
+	delta := seg[1] - seg[0]
+	if delta != 1 && delta != -1 {
+		return false
+	}
+	for i := 2; i < len(seg); i++ {
+		if seg[i]-seg[i-1] != delta {
+			return false
+		}
+	}
+	return true
+}
+
+// upperCaseVariation is log10 of the extra guesses needed for the
+// capitalisation of seg: capitalising the first letter or everything is
+// tried first, anything else costs one doubling per upper-case letter.
+func upperCaseVariation(seg []rune) float64 {
+	upper := 0
+	for _, r := range seg {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case upper == len(seg) || (upper == 1 && unicode.IsUpper(seg[0])):
+		return math.Log10(2)
+	default:
+		return float64(upper) * math.Log10(2)
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+)
+
+func TestPasswordScore(t *testing.T) {
+	tests := []struct {
+		password string
+		maxScore int
+		minScore int
+	}{
+		{"Password1!", 0, 0},
+		{"P@ssw0rd1", 1, 0},
+		{"aaaaaaaaaaaa", 0, 0},
+		{"abcdefghijkl", 0, 0},
+		{"Ab1!xY9#", 2, 0},
+		{"correct horse battery staple", 4, 4},
+		{"xk29vq7mn4pt", 4, 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.password, func(t *testing.T) {
+			score := PasswordScore(tc.password)
+			if score < tc.minScore || score > tc.maxScore {
+				t.Fatalf("PasswordScore(%q) = %d, want between %d and %d", tc.password, score, tc.minScore, tc.maxScore)
+			}
+		})
+	}
+}
+
+func TestAuthServiceValidatePasswordPolicy(t *testing.T) {
+	classes := AuthService{}
+	entropy := AuthService{cfg: config.Config{Auth: config.AuthConfig{
+		PasswordPolicy:   common.PasswordPolicyEntropy,
+		PasswordMinScore: common.DefaultPasswordMinScore,
+	}}}
+
+	if err := classes.validatePassword("password", "correct horse battery staple"); err == nil {
+		t.Fatal("class policy should still require upper case, digits and symbols")
+	}
+	if err := classes.validatePassword("password", "Password1!"); err != nil {
+		t.Fatalf("class policy should accept Password1!: %v", err)
+	}
+	if err := entropy.validatePassword("password", "correct horse battery staple"); err != nil {
+		t.Fatalf("entropy policy should accept a long passphrase: %v", err)
+	}
+	for _, weak := range []string{"Password1!", "Ab1!xY9#", "short"} {
+		if err := entropy.validatePassword("password", weak); err == nil {
+			t.Fatalf("entropy policy should reject %q", weak)
+		}
+	}
+}
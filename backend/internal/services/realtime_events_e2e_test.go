@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -10,7 +11,7 @@ import (
 
 type realtimeE2EMessageService struct{}
 
-func (s realtimeE2EMessageService) Create(userID, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
+func (s realtimeE2EMessageService) Create(_ context.Context, userID, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject string, creationReminders []int) (models.Message, error) {
 	return models.Message{ID: "msg-e2e", UserID: userID, LastSeen: time.Now().UTC(), Status: models.StatusActive}, nil
 }
 
@@ -18,6 +19,10 @@ func (s realtimeE2EMessageService) GetPublicByID(id string) (models.Message, err
 	return models.Message{ID: id, LastSeen: time.Now().UTC(), Status: models.StatusActive}, nil
 }
 
+func (s realtimeE2EMessageService) GetByManagementToken(token string) (models.Message, error) {
+	return models.Message{}, NotFound("Message not found", nil)
+}
+
 func (s realtimeE2EMessageService) GetByID(userID, id string) (models.Message, error) {
 	return models.Message{ID: id, UserID: userID, LastSeen: time.Now().UTC(), Status: models.StatusActive}, nil
 }
@@ -26,18 +31,50 @@ func (s realtimeE2EMessageService) List(userID string) ([]models.Message, error)
 	return []models.Message{}, nil
 }
 
+func (s realtimeE2EMessageService) ListByRecipient(userID, recipient string) ([]models.Message, error) {
+	return []models.Message{}, nil
+}
+
+func (s realtimeE2EMessageService) ListByStatus(userID string, status models.MessageStatus) ([]models.Message, error) {
+	return []models.Message{}, nil
+}
+
 func (s realtimeE2EMessageService) Heartbeat(userID, id string) (models.Message, error) {
 	return models.Message{ID: id, UserID: userID, LastSeen: time.Now().UTC(), Status: models.StatusActive}, nil
 }
 
 func (s realtimeE2EMessageService) BulkHeartbeat(userID string) error { return nil }
 
-func (s realtimeE2EMessageService) Delete(userID, id string) error { return nil }
+func (s realtimeE2EMessageService) ExtendDeadline(userID, id string, by time.Duration) error {
+	return nil
+}
+
+func (s realtimeE2EMessageService) Delete(userID, id string, force bool) error { return nil }
 
-func (s realtimeE2EMessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int) (models.Message, error) {
+func (s realtimeE2EMessageService) Update(userID, id, content string, recipientEmails []string, triggerDuration int, reminders []int, requiredMissedIntervals int, deliveryWindow *models.DeliveryWindow, subject *string, creationReminders []int) (models.Message, error) {
 	return models.Message{ID: id, UserID: userID, LastSeen: time.Now().UTC(), Status: models.StatusActive}, nil
 }
 
+func (s realtimeE2EMessageService) RotateManagementToken(userID, id string) (string, error) {
+	return "rotated", nil
+}
+
+func (s realtimeE2EMessageService) ExportContent(userID, id string) (filename, mimeType string, data []byte, err error) {
+	return "", "", nil, nil
+}
+
+func (s realtimeE2EMessageService) ListTransitions(userID, id string) ([]models.StatusTransition, error) {
+	return nil, nil
+}
+
+func (s realtimeE2EMessageService) ListTombstones(userID string) ([]models.MessageTombstone, error) {
+	return nil, nil
+}
+
+func (s realtimeE2EMessageService) Import(_ context.Context, userID string, rows []ports.MessageImportRow) ([]ports.MessageImportResult, error) {
+	return nil, nil
+}
+
 func TestRealtimeEventsE2E_HeartbeatBroadcastsToAllDevicesOfSameUser(t *testing.T) {
 	stream := NewEventStreamService()
 	svc := NewNotifyingMessageService(realtimeE2EMessageService{}, stream)
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recipientMXCacheTTL is how long a domain's MX verdict is reused, so
+// editing a switch does not hit DNS again for every recipient.
+const recipientMXCacheTTL = 10 * time.Minute
+
+// recipientMXCacheSize bounds the number of cached domains. When it is full,
+// expired verdicts are swept and then the oldest is evicted.
+const recipientMXCacheSize = 1024
+
+const recipientMXLookupTimeout = 3 * time.Second
+
+// mxChecker reports whether recipient domains can receive mail, caching
+// definite answers. Resolver failures are not cached.
+type mxChecker struct {
+	lookup     func(ctx context.Context, domain string) ([]*net.MX, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+
+	mu    sync.Mutex
+	cache map[string]mxVerdict
+}
+
+type mxVerdict struct {
+	accepts bool
+	expires time.Time
+}
+
+var recipientMX = &mxChecker{lookup: net.DefaultResolver.LookupMX, lookupHost: net.DefaultResolver.LookupHost, cache: map[string]mxVerdict{}}
+
+// acceptsMail reports whether domain can receive mail: it publishes a usable
+// MX record or, lacking MX records, an A or AAAA record, which RFC 5321
+// treats as an implicit MX. A domain that does not exist, has neither, or
+// publishes the RFC 7505 null MX does not. err is set only when DNS could not
+// give an answer.
+func (c *mxChecker) acceptsMail(domain string, now time.Time) (bool, error) {
+	c.mu.Lock()
+	verdict, ok := c.cache[domain]
+	c.mu.Unlock()
+	if ok && now.Before(verdict.expires) {
+		return verdict.accepts, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recipientMXLookupTimeout)
+	defer cancel()
+	records, err := c.lookup(ctx, domain)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return false, err
+	}
+	accepts := false
+	for _, mx := range records {
+		if host := strings.TrimSuffix(mx.Host, "."); host != "" {
+			accepts = true
+			break
+		}
+	}
+	if len(records) == 0 {
+		addrs, err := c.lookupHost(ctx, domain)
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			return false, err
+		}
+		accepts = len(addrs) > 0
+	}
+
+	c.mu.Lock()
+	c.store(domain, mxVerdict{accepts: accepts, expires: now.Add(recipientMXCacheTTL)}, now)
+	c.mu.Unlock()
+	return accepts, nil
+}
+
+// store caches verdict for domain, making room first when the cache is full.
+// c.mu must be held.
+func (c *mxChecker) store(domain string, verdict mxVerdict, now time.Time) {
+	if _, ok := c.cache[domain]; !ok && len(c.cache) >= recipientMXCacheSize {
+		oldest := ""
+		for cached, v := range c.cache {
+			if !now.Before(v.expires) {
+				delete(c.cache, cached)
+			} else if oldest == "" || v.expires.Before(c.cache[oldest].expires) {
+				oldest = cached
+			}
+		}
+		if len(c.cache) >= recipientMXCacheSize {
+			delete(c.cache, oldest)
+		}
+	}
+	c.cache[domain] = verdict
+}
+
+// checkRecipientDomains rejects recipients whose domain cannot receive mail
+// when VALIDATE_RECIPIENT_MX is set. A DNS failure is logged and let through,
+// so a resolver outage does not block editing switches.
+func (s MessageService) checkRecipientDomains(recipientEmails []string) error {
+	if !s.cfg.App.ValidateRecipientMX {
+		return nil
+	}
+	checked := map[string]bool{}
+	for _, email := range recipientEmails {
+		at := strings.LastIndex(email, "@")
+		if at < 0 {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+		if checked[domain] {
+			continue
+		}
+		checked[domain] = true
+		accepts, err := recipientMX.acceptsMail(domain, time.Now())
+		if err != nil {
+			slog.Warn("Recipient MX lookup failed; accepting recipient", "error", err, "domain", domain)
+			continue
+		}
+		if !accepts {
+			return NewAPIError(400, CodeRecipientDomainNoMX, fmt.Sprintf("The domain %s of recipient %s cannot receive email (no MX or address records). Check the address for typos.", domain, strings.TrimSpace(email)), nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+)
+
+func useFakeMX(t *testing.T, records map[string][]*net.MX, hosts map[string][]string) *int {
+	t.Helper()
+	lookups := 0
+	prev := recipientMX
+	recipientMX = &mxChecker{cache: map[string]mxVerdict{}, lookup: func(_ context.Context, domain string) ([]*net.MX, error) {
+		lookups++
+		if domain == "flaky.example" {
+			return nil, &net.DNSError{Err: "server misbehaving", Name: domain, IsTemporary: true}
+		}
+		mx, ok := records[domain]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: domain, IsNotFound: true}
+		}
+		return mx, nil
+	}, lookupHost: func(_ context.Context, host string) ([]string, error) {
+		addrs, ok := hosts[host]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return addrs, nil
+	}}
+	t.Cleanup(func() { recipientMX = prev })
+	return &lookups
+}
+
+func TestCheckRecipientDomains(t *testing.T) {
+	lookups := useFakeMX(t, map[string][]*net.MX{
+		"gmail.com":      {{Host: "gmail-smtp-in.l.google.com.", Pref: 5}},
+		"nullmx.example": {{Host: ".", Pref: 0}},
+	}, map[string][]string{
+		"implicit.example": {"192.0.2.10"},
+		"nullmx.example":   {"192.0.2.20"},
+	})
+	svc := NewMessageService(config.Config{App: config.AppConfig{ValidateRecipientMX: true}})
+
+	if err := svc.checkRecipientDomains([]string{"a@gmail.com", "b@GMAIL.com"}); err != nil {
+		t.Fatalf("expected gmail.com to be accepted: %v", err)
+	}
+	if *lookups != 1 {
+		t.Fatalf("expected one lookup per distinct domain, got %d", *lookups)
+	}
+
+	if err := svc.checkRecipientDomains([]string{"a@implicit.example"}); err != nil {
+		t.Fatalf("a domain with only an A record should be accepted: %v", err)
+	}
+
+	for _, bad := range []string{"a@gmial.com", "a@nullmx.example"} {
+		err := svc.checkRecipientDomains([]string{"ok@gmail.com", bad})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != CodeRecipientDomainNoMX {
+			t.Fatalf("expected %s to be rejected with %s, got %v", bad, CodeRecipientDomainNoMX, err)
+		}
+	}
+
+	if err := svc.checkRecipientDomains([]string{"a@flaky.example"}); err != nil {
+		t.Fatalf("a DNS failure must not block the recipient: %v", err)
+	}
+
+	before := *lookups
+	if err := svc.checkRecipientDomains([]string{"c@gmail.com"}); err != nil || *lookups != before {
+		t.Fatalf("expected a cached answer (err %v, lookups %d -> %d)", err, before, *lookups)
+	}
+
+	if err := (MessageService{}).checkRecipientDomains([]string{"a@gmial.com"}); err != nil {
+		t.Fatalf("the check must be off unless VALIDATE_RECIPIENT_MX is set: %v", err)
+	}
+}
+
+func TestMXCheckerCacheExpires(t *testing.T) {
+	lookups := useFakeMX(t, map[string][]*net.MX{"example.com": {{Host: "mx.example.com."}}}, nil)
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(time.Minute), now.Add(recipientMXCacheTTL + time.Second)} {
+		if ok, err := recipientMX.acceptsMail("example.com", at); !ok || err != nil {
+			t.Fatalf("acceptsMail = %v, %v", ok, err)
+		}
+	}
+	if *lookups != 2 {
+		t.Fatalf("expected the cached answer to expire after %s, got %d lookups", recipientMXCacheTTL, *lookups)
+	}
+}
+
+func TestMXCheckerCacheIsBounded(t *testing.T) {
+	useFakeMX(t, nil, nil)
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	for i := 0; i < recipientMXCacheSize; i++ {
+		expires := now.Add(time.Duration(i+1) * time.Second)
+		if i%2 == 0 {
+			expires = expired
+		}
+		recipientMX.cache[fmt.Sprintf("d%d.example", i)] = mxVerdict{expires: expires}
+	}
+
+	if _, err := recipientMX.acceptsMail("new.example", now); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(recipientMX.cache); got != recipientMXCacheSize/2+1 {
+		t.Fatalf("expected expired verdicts to be swept, %d cached", got)
+	}
+
+	for i := 0; len(recipientMX.cache) < recipientMXCacheSize; i++ {
+		recipientMX.cache[fmt.Sprintf("live%d.example", i)] = mxVerdict{expires: now.Add(time.Hour)}
+	}
+	if _, err := recipientMX.acceptsMail("newer.example", now); err != nil {
+		t.Fatal(err)
+	}
+	if len(recipientMX.cache) != recipientMXCacheSize {
+		t.Fatalf("the cache grew past %d: %d", recipientMXCacheSize, len(recipientMX.cache))
+	}
+	if _, ok := recipientMX.cache["d1.example"]; ok {
+		t.Fatal("expected the oldest verdict to be evicted")
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+)
+
+const hexDigits = "0123456789ABCDEF"
+
+// recoveryWords spells a mnemonic recovery key, one word per random byte.
+// No two words share their first four letters.
+var recoveryWords = [256]string{
+	"able", "acorn", "adapt", "adult", "affair", "agree", "aisle", "album",
+	"almond", "amber", "anchor", "animal", "answer", "april", "argue", "artist",
+	"atlas", "attic", "auto", "award", "baby", "badge", "balance", "banana",
+	"barrel", "battle", "beauty", "beef", "bench", "bird", "blanket", "blue",
+	"body", "book", "bottle", "brain", "brick", "bronze", "bubble", "budget",
+	"bulb", "burger", "cabin", "canal", "canoe", "canyon", "carbon", "castle",
+	"cattle", "cedar", "chalk", "cherry", "chimney", "cider", "circle", "civil",
+	"claw", "clock", "clump", "coconut", "collect", "comic", "coral", "cotton",
+	"coyote", "cradle", "crater", "credit", "crowd", "crystal", "culture",
+	"cushion", "daisy", "dance", "debate", "deer", "demand", "depth", "detail",
+	"diesel", "disco", "dolphin", "donkey", "dove", "drama", "dream", "duck",
+	"dust", "dynamic", "early", "easel", "eclipse", "editor", "elbow", "eleven",
+	"embark", "empire", "energy", "enjoy", "entry", "escape", "estate",
+	"evening", "exact", "exotic", "faculty", "family", "fashion", "feather",
+	"fiction", "figure", "final", "fitness", "flame", "flight", "fluid",
+	"focus", "forest", "fortune", "frost", "fuel", "gadget", "garden", "genius",
+	"giant", "giraffe", "glove", "gold", "guitar", "habit", "harbor", "hawk",
+	"hero", "hobby", "honey", "hotel", "hybrid", "idea", "image", "income",
+	"indoor", "inner", "island", "jaguar", "jelly", "jigsaw", "journey",
+	"junior", "kernel", "kidney", "kite", "koala", "ladder", "lamp", "lava",
+	"lecture", "lemon", "lizard", "lunar", "magnet", "mango", "market",
+	"melody", "metal", "minute", "mobile", "mosaic", "muscle", "mystery",
+	"nation", "nectar", "nest", "noble", "normal", "number", "nutmeg", "object",
+	"october", "onion", "orange", "orchard", "orient", "outdoor", "oyster",
+	"palace", "paper", "parrot", "pelican", "pepper", "picnic", "pilot",
+	"plastic", "polar", "pottery", "pyramid", "quarter", "quiz", "raccoon",
+	"radio", "raven", "record", "region", "rhythm", "river", "rocket", "rubber",
+	"saddle", "sample", "school", "shadow", "shield", "siren", "slogan",
+	"soccer", "spider", "spring", "stadium", "sugar", "sunset", "swallow",
+	"table", "taxi", "tennis", "thunder", "tiger", "toast", "tongue", "tornado",
+	"tractor", "tulip", "turkey", "tuxedo", "uncle", "update", "urban",
+	"vacuum", "vanilla", "vendor", "vessel", "village", "violin", "vivid",
+	"voyage", "wagon", "wander", "water", "wedding", "wheat", "window", "wolf",
+	"wrestle", "yard", "yoga", "youth", "zero", "zipper",
+}
+
+// generateRecoveryKey returns a new key in RECOVERY_KEY_FORMAT carrying
+// RECOVERY_KEY_BYTES of randomness, followed by a check character (hex) or
+// word (mnemonic) so transcription errors can be told from a wrong key. The
+// defaults give RK-XXXXX-XXXXX-XXXXX-XXXXX-C.
+func (s AuthService) generateRecoveryKey() (string, error) {
+	n := s.cfg.Auth.RecoveryKeyBytes
+	if n <= 0 {
+		n = common.DefaultRecoveryKeyBytes
+	}
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	if s.cfg.Auth.RecoveryKeyFormat == common.RecoveryKeyFormatMnemonic {
+		words := make([]string, 0, n+1)
+		for _, b := range buf {
+			words = append(words, recoveryWords[b])
+		}
+		words = append(words, mnemonicCheckWord(buf))
+		return strings.Join(words, " "), nil
+	}
+
+	payload := strings.ToUpper(hex.EncodeToString(buf))
+	hexStr := payload + string(hexCheckDigit(payload))
+	groups := []string{"RK"}
+	for len(hexStr) > 0 {
+		size := min(5, len(hexStr))
+		groups = append(groups, hexStr[:size])
+		hexStr = hexStr[size:]
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeRecoveryKey forgives the case and spacing of a typed key so it
+// matches the stored hash. Issued hex keys are always upper case.
+func normalizeRecoveryKey(key string) string {
+	fields := strings.Fields(strings.ToLower(key))
+	if len(fields) < 2 {
+		return strings.ToUpper(strings.TrimSpace(key))
+	}
+	return strings.Join(fields, " ")
+}
+
+// recoveryKeySecret is what gets bcrypt-hashed for a key. bcrypt reads at
+// most 72 bytes, which long mnemonics and hex keys exceed, so those are
+// reduced with SHA-256 first; shorter keys, including every key issued
+// before the length was configurable, are hashed as they are.
+func recoveryKeySecret(key string) []byte {
+	if len(key) <= 72 {
+		return []byte(key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return []byte(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+// recoveryKeyMistyped reports whether a normalized key fails its check
+// character or word. Hex keys with an even number of digits predate the
+// check and are never reported.
+func recoveryKeyMistyped(key string) bool {
+	if fields := strings.Fields(key); len(fields) > 1 {
+		index := make(map[string]byte, len(recoveryWords))
+		for i, word := range recoveryWords {
+			index[word] = byte(i)
+		}
+		payload := make([]byte, 0, len(fields)-1)
+		for _, word := range fields {
+			b, ok := index[word]
+			if !ok {
+				return true
+			}
+			payload = append(payload, b)
+		}
+		payload = payload[:len(payload)-1]
+		return mnemonicCheckWord(payload) != fields[len(fields)-1]
+	}
+
+	digits := strings.ReplaceAll(strings.TrimPrefix(key, "RK-"), "-", "")
+	if digits == "" || strings.Trim(digits, hexDigits) != "" {
+		return true
+	}
+	if len(digits)%2 == 0 {
+		return false
+	}
+	last := len(digits) - 1
+	return hexCheckDigit(digits[:last]) != digits[last]
+}
+
+// hexCheckDigit is the Luhn mod 16 check digit of upper-case hex digits. It
+// catches every single-digit error and most swaps of adjacent digits.
+func hexCheckDigit(digits string) byte {
+	sum, factor := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(hexDigits, digits[i])
+		sum += addend/16 + addend%16
+		factor = 3 - factor
+	}
+	return hexDigits[(16-sum%16)%16]
+}
+
+// mnemonicCheckWord derives the trailing check word of a mnemonic key from
+// the bytes spelled by the other words.
+func mnemonicCheckWord(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return recoveryWords[sum[0]]
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+// alertRecoveryKeyUsed tells the owner that an additional recovery key was
+// used to reset their password, by email and over ntfy where configured. A
+// failed alert is logged; the reset has already happened.
+func (s AuthService) alertRecoveryKeyUsed(userID string, key models.RecoveryKey, now time.Time) {
+	settings, err := NewSettingsService(s.cfg).Get(userID)
+	if err != nil {
+		slog.Error("Failed to load settings for recovery key alert", "error", err, "user_id", userID)
+		return
+	}
+	subject := "Security alert: your Aeterna password was reset with a recovery key"
+	body := fmt.Sprintf("The password of your Aeterna account was reset with the additional recovery key %q.\n\n"+
+		"Time: %s\n\n"+
+		"The key has been revoked and your own recovery key is unchanged. If you did not expect this, "+
+		"reset your password with your own recovery key and review your recovery keys.",
+		key.Label, FormatOwnerTime(settings, now))
+
+	if s.sendMail != nil && settings.OwnerEmail != "" && settings.SMTPHost != "" {
+		if err := s.sendMail(settings, []string{settings.OwnerEmail}, subject, AppendEmailFooter(settings, body)); err != nil {
+			slog.Error("Failed to email recovery key alert", "error", err, "user_id", userID)
+		}
+	}
+	if s.ntfy != nil && settings.NtfyURL != "" {
+		if err := s.ntfy.Send(settings.NtfyURL, "Aeterna password reset with a recovery key", body, NtfyPriorityUrgent, ""); err != nil {
+			slog.Error("Failed to send recovery key alert", "error", err, "user_id", userID)
+		}
+	}
+}
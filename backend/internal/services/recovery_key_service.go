@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	maxRecoveryKeyLabelLength = 100
+	// maxRecoveryKeys bounds the additional keys per account, since every
+	// password reset compares against each of them.
+	maxRecoveryKeys = 10
+)
+
+// RecoveryKeyService manages an account's additional recovery keys.
+type RecoveryKeyService struct {
+	cfg config.Config
+}
+
+func NewRecoveryKeyService(cfg config.Config) RecoveryKeyService {
+	return RecoveryKeyService{cfg: cfg}
+}
+
+// List returns the account's additional keys, revoked ones included, newest
+// first. Key values are never returned after creation.
+func (s RecoveryKeyService) List(userID string) ([]models.RecoveryKey, error) {
+	var keys []models.RecoveryKey
+	if err := database.ForTenant(userID).Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, Internal("Failed to list recovery keys", err)
+	}
+	return keys, nil
+}
+
+// Add issues a new recovery key under label. The returned value is the only
+// time it is shown.
+func (s RecoveryKeyService) Add(userID, label string) (string, models.RecoveryKey, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", models.RecoveryKey{}, BadRequest("Label is required", nil)
+	}
+	if len(label) > maxRecoveryKeyLabelLength {
+		return "", models.RecoveryKey{}, BadRequest("Label is too long", nil)
+	}
+	var active int64
+	if err := database.ForTenant(userID).Model(&models.RecoveryKey{}).Where("revoked_at IS NULL").Count(&active).Error; err != nil {
+		return "", models.RecoveryKey{}, Internal("Failed to count recovery keys", err)
+	}
+	if active >= maxRecoveryKeys {
+		return "", models.RecoveryKey{}, BadRequest("Revoke an existing recovery key before adding another", nil)
+	}
+
+	recoveryKey, err := AuthService{cfg: s.cfg}.generateRecoveryKey()
+	if err != nil {
+		return "", models.RecoveryKey{}, Internal("Failed to generate recovery key", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword(recoveryKeySecret(recoveryKey), bcrypt.DefaultCost)
+	if err != nil {
+		return "", models.RecoveryKey{}, Internal("Failed to hash recovery key", err)
+	}
+	key := models.RecoveryKey{UserID: userID, Label: label, KeyHash: string(hash)}
+	if err := database.DB.Create(&key).Error; err != nil {
+		return "", models.RecoveryKey{}, Internal("Failed to save recovery key", err)
+	}
+	slog.Info("Recovery key added", "user_id", userID, "recovery_key_id", key.ID)
+	return recoveryKey, key, nil
+}
+
+// Revoke stops a key from working. Revoking an already revoked key is a
+// no-op.
+func (s RecoveryKeyService) Revoke(userID string, id uint) (models.RecoveryKey, error) {
+	var key models.RecoveryKey
+	if err := database.ForTenant(userID).First(&key, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.RecoveryKey{}, NotFound("Recovery key not found", err)
+		}
+		return models.RecoveryKey{}, Internal("Failed to load recovery key", err)
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+	now := time.Now().UTC()
+	if err := database.DB.Model(&key).Update("revoked_at", now).Error; err != nil {
+		return models.RecoveryKey{}, Internal("Failed to revoke recovery key", err)
+	}
+	key.RevokedAt = &now
+	slog.Info("Recovery key revoked", "user_id", userID, "recovery_key_id", key.ID)
+	return key, nil
+}
+
+// matchAdditional returns the account's non-revoked key matching
+// recoveryKey, if any.
+func (s RecoveryKeyService) matchAdditional(userID, recoveryKey string) (*models.RecoveryKey, error) {
+	var keys []models.RecoveryKey
+	if err := database.ForTenant(userID).Where("revoked_at IS NULL").Find(&keys).Error; err != nil {
+		return nil, Internal("Failed to load recovery keys", err)
+	}
+	for i := range keys {
+		if bcrypt.CompareHashAndPassword([]byte(keys[i].KeyHash), recoveryKeySecret(recoveryKey)) == nil {
+			return &keys[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestAdditionalRecoveryKeys(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}, &models.RecoveryKey{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+
+	auth := NewAuthService(config.Config{})
+	primary, user, err := auth.RegisterFirstUser("user@example.com", "StrongPass1!", "")
+	if err != nil {
+		t.Fatalf("RegisterFirstUser: %v", err)
+	}
+	keys := NewRecoveryKeyService(config.Config{})
+	if _, _, err := keys.Add(user.ID, "   "); err == nil {
+		t.Fatal("a label is required")
+	}
+	contact, added, err := keys.Add(user.ID, " Sister ")
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	spare, spareKey, err := keys.Add(user.ID, "Safe")
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if contact == primary || added.Label != "Sister" || recoveryKeyMistyped(normalizeRecoveryKey(contact)) {
+		t.Fatalf("unexpected key %q: %+v", contact, added)
+	}
+
+	listed, err := keys.List(user.ID)
+	if err != nil || len(listed) != 2 {
+		t.Fatalf("List = %+v (%v)", listed, err)
+	}
+	if other, err := keys.List("someone-else"); err != nil || len(other) != 0 {
+		t.Fatalf("another account must not see these keys: %+v (%v)", other, err)
+	}
+	if _, err := keys.Revoke("someone-else", added.ID); err == nil {
+		t.Fatal("another account must not revoke these keys")
+	}
+
+	if err := db.Model(&models.Settings{}).Where("user_id = ?", user.ID).
+		Updates(map[string]any{"owner_email": "owner@example.com", "smtp_host": "smtp.example.com", "ntfy_url": "https://ntfy.sh/owner"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var mailed, pushed []string
+	auth.sendMail = func(_ models.Settings, recipients []string, _, body string) error {
+		mailed = append(mailed, recipients[0]+": "+body)
+		return nil
+	}
+	auth.ntfy = recordingNtfy(func(topicURL, message string) { pushed = append(pushed, topicURL+": "+message) })
+
+	next, err := auth.ResetPasswordWithRecovery("user@example.com", contact, "NewStrongPass2!")
+	if err != nil {
+		t.Fatalf("reset with an additional key: %v", err)
+	}
+	if next != "" {
+		t.Fatalf("a trusted contact must not receive the account's recovery key, got %q", next)
+	}
+	if len(mailed) != 1 || !strings.HasPrefix(mailed[0], "owner@example.com: ") || !strings.Contains(mailed[0], `"Sister"`) {
+		t.Fatalf("expected the owner to be emailed about the Sister key, got %q", mailed)
+	}
+	if len(pushed) != 1 || !strings.HasPrefix(pushed[0], "https://ntfy.sh/owner: ") {
+		t.Fatalf("expected one ntfy alert, got %q", pushed)
+	}
+	if _, err := auth.ResetPasswordWithRecovery("user@example.com", contact, "OtherStrongPass3!"); err == nil {
+		t.Fatal("an additional key works once")
+	}
+	var used models.RecoveryKey
+	if err := db.First(&used, added.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if used.LastUsedAt == nil || used.RevokedAt == nil {
+		t.Fatalf("the used key should be marked used and revoked: %+v", used)
+	}
+
+	if _, err := keys.Revoke(user.ID, spareKey.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	var apiErr *APIError
+	if _, err := auth.ResetPasswordWithRecovery("user@example.com", spare, "NewStrongPass4!"); !errors.As(err, &apiErr) || apiErr.Status != 401 {
+		t.Fatalf("a revoked key must be rejected, got %v", err)
+	}
+	rotated, err := auth.ResetPasswordWithRecovery("user@example.com", primary, "NewStrongPass5!")
+	if err != nil {
+		t.Fatalf("an additional key must leave the primary key working: %v", err)
+	}
+	if rotated == "" || rotated == primary {
+		t.Fatalf("a reset with the primary key should rotate it, got %q", rotated)
+	}
+	if len(mailed) != 1 {
+		t.Fatalf("a reset with the primary key sends no alert, got %d", len(mailed))
+	}
+}
+
+type recordingNtfy func(topicURL, message string)
+
+func (f recordingNtfy) Send(topicURL, _, message, _, _ string) error {
+	f(topicURL, message)
+	return nil
+}
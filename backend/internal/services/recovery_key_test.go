@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRecoveryWordsHaveDistinctPrefixes(t *testing.T) {
+	seen := map[string]string{}
+	for _, word := range recoveryWords {
+		if len(word) < 4 {
+			t.Fatalf("word %q is shorter than the four-letter prefix", word)
+		}
+		if other, ok := seen[word[:4]]; ok {
+			t.Fatalf("%q and %q share a prefix", word, other)
+		}
+		seen[word[:4]] = word
+	}
+}
+
+func TestGenerateRecoveryKeyFormats(t *testing.T) {
+	key, err := AuthService{}.generateRecoveryKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^RK-[0-9A-F]{5}-[0-9A-F]{5}-[0-9A-F]{5}-[0-9A-F]{5}-[0-9A-F]$`).MatchString(key) {
+		t.Fatalf("default key %q should be the original format plus a check digit", key)
+	}
+
+	long := NewAuthService(config.Config{Auth: config.AuthConfig{RecoveryKeyFormat: "hex", RecoveryKeyBytes: 16}})
+	if key, _ := long.generateRecoveryKey(); !regexp.MustCompile(`^RK(-[0-9A-F]{5}){6}-[0-9A-F]{3}$`).MatchString(key) {
+		t.Fatalf("16-byte key %q has the wrong shape", key)
+	}
+
+	mnemonic := NewAuthService(config.Config{Auth: config.AuthConfig{RecoveryKeyFormat: "mnemonic", RecoveryKeyBytes: 12}})
+	key, err = mnemonic.generateRecoveryKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Fields(key)
+	if len(words) != 13 {
+		t.Fatalf("mnemonic %q has %d words, want 12 plus a check word", key, len(words))
+	}
+	known := map[string]bool{}
+	for _, word := range recoveryWords {
+		known[word] = true
+	}
+	for _, word := range words {
+		if !known[word] {
+			t.Fatalf("mnemonic word %q is not in the wordlist", word)
+		}
+	}
+}
+
+func TestResetPasswordWithMnemonicRecoveryKey(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+
+	svc := NewAuthService(config.Config{Auth: config.AuthConfig{RecoveryKeyFormat: "mnemonic", RecoveryKeyBytes: 32}})
+	key, _, err := svc.RegisterFirstUser("user@example.com", "StrongPass1!", "")
+	if err != nil {
+		t.Fatalf("RegisterFirstUser: %v", err)
+	}
+	// 33 words are far beyond the 72 bytes bcrypt reads.
+	if len(strings.Fields(key)) != 33 {
+		t.Fatalf("expected a 32-word recovery key and check word, got %q", key)
+	}
+
+	// Transcribed keys often differ in case and spacing.
+	typed := "  " + strings.ToUpper(strings.ReplaceAll(key, " ", "   ")) + "\n"
+	next, err := svc.ResetPasswordWithRecovery("user@example.com", typed, "NewStrongPass2!")
+	if err != nil {
+		t.Fatalf("ResetPasswordWithRecovery: %v", err)
+	}
+	if next == key || len(strings.Fields(next)) != 33 {
+		t.Fatalf("expected a fresh mnemonic, got %q", next)
+	}
+	if _, err := svc.ResetPasswordWithRecovery("user@example.com", key, "OtherStrongPass3!"); err == nil {
+		t.Fatal("the used recovery key must stop working")
+	}
+}
+
+func TestRecoveryKeyMistyped(t *testing.T) {
+	for _, format := range []string{"hex", "mnemonic"} {
+		svc := NewAuthService(config.Config{Auth: config.AuthConfig{RecoveryKeyFormat: format}})
+		for i := 0; i < 20; i++ {
+			key, err := svc.generateRecoveryKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recoveryKeyMistyped(normalizeRecoveryKey(key)) {
+				t.Fatalf("a freshly issued %s key %q fails its own check", format, key)
+			}
+		}
+	}
+
+	// RK-3F918-52325-BC743-BB7DA-5 is a valid key; change one digit at a time.
+	valid := "RK-3F918-52325-BC743-BB7DA-5"
+	if recoveryKeyMistyped(valid) {
+		t.Fatalf("%s should pass its check", valid)
+	}
+	for i := 3; i < len(valid); i++ {
+		if valid[i] == '-' {
+			continue
+		}
+		for _, c := range hexDigits {
+			if byte(c) == valid[i] {
+				continue
+			}
+			typo := valid[:i] + string(c) + valid[i+1:]
+			if !recoveryKeyMistyped(typo) {
+				t.Fatalf("single-digit typo %s was not detected", typo)
+			}
+		}
+	}
+	if recoveryKeyMistyped("RK-ABCDE-12345-ABCDE-12345") {
+		t.Fatal("a key issued before check digits must not be reported as mistyped")
+	}
+	for _, garbled := range []string{"RK-ABCDE-1234O-ABCDE-12345-0", "", "easel wagon harbour idea"} {
+		if !recoveryKeyMistyped(normalizeRecoveryKey(garbled)) {
+			t.Fatalf("%q should be reported as mistyped", garbled)
+		}
+	}
+
+	// A wrong word slips past the check word only by a 1 in 256 chance.
+	payload := []byte{3, 1, 4, 1, 5, 9, 2, 6, 5, 3}
+	words := make([]string, 0, len(payload)+1)
+	for _, b := range payload {
+		words = append(words, recoveryWords[b])
+	}
+	words = append(words, mnemonicCheckWord(payload))
+	if recoveryKeyMistyped(strings.Join(words, " ")) {
+		t.Fatalf("%q should pass its check", strings.Join(words, " "))
+	}
+	undetected := 0
+	for _, replacement := range recoveryWords {
+		if replacement == words[4] {
+			continue
+		}
+		typo := append([]string{}, words...)
+		typo[4] = replacement
+		if !recoveryKeyMistyped(strings.Join(typo, " ")) {
+			undetected++
+		}
+	}
+	if undetected > 3 {
+		t.Fatalf("%d of 255 wrong words went undetected", undetected)
+	}
+}
+
+func TestResetPasswordAcceptsMnemonicKeyWithoutCheckWord(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+
+	svc := NewAuthService(config.Config{Auth: config.AuthConfig{RecoveryKeyFormat: "mnemonic", RecoveryKeyBytes: 12}})
+	if _, _, err := svc.RegisterFirstUser("user@example.com", "StrongPass1!", ""); err != nil {
+		t.Fatalf("RegisterFirstUser: %v", err)
+	}
+	// Mnemonic keys were first issued without a check word.
+	var legacy []string
+	for _, b := range []byte{3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 8} {
+		legacy = append(legacy, recoveryWords[b])
+	}
+	key := strings.Join(legacy, " ")
+	if !recoveryKeyMistyped(key) {
+		t.Fatalf("%q should fail the check the old key never had", key)
+	}
+	hash, err := bcrypt.GenerateFromPassword(recoveryKeySecret(key), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Settings{}).Where("1 = 1").Update("recovery_key_hash", string(hash)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.ResetPasswordWithRecovery("user@example.com", key, "NewStrongPass2!"); err != nil {
+		t.Fatalf("a key issued before the check word should still work: %v", err)
+	}
+}
+
+func TestResetPasswordReportsMistypedRecoveryKey(t *testing.T) {
+	db := setupAuthTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}, &models.RecoveryKey{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+
+	svc := NewAuthService(config.Config{})
+	key, _, err := svc.RegisterFirstUser("user@example.com", "StrongPass1!", "")
+	if err != nil {
+		t.Fatalf("RegisterFirstUser: %v", err)
+	}
+	last := key[len(key)-1]
+	typo := key[:len(key)-1] + string(hexDigits[(strings.IndexByte(hexDigits, last)+1)%16])
+
+	var apiErr *APIError
+	if _, err := svc.ResetPasswordWithRecovery("user@example.com", typo, "NewStrongPass2!"); !errors.As(err, &apiErr) || apiErr.Code != CodeRecoveryKeyMistyped {
+		t.Fatalf("expected recovery_key_mistyped, got %v", err)
+	}
+	if _, err := svc.ResetPasswordWithRecovery("user@example.com", strings.ToLower(key), "NewStrongPass2!"); err != nil {
+		t.Fatalf("a lower-case copy of the key should still work: %v", err)
+	}
+}
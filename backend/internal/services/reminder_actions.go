@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/url"
+	"time"
+)
+
+// Quick-heartbeat actions a reminder email can link to with ?action=, so the
+// owner can decide how far to push a deadline without logging in.
+const (
+	ReminderActionReset  = "reset"
+	ReminderActionExtend = "extend"
+	ReminderActionSnooze = "snooze"
+)
+
+// How many days the extend and snooze actions add to a deadline.
+const (
+	ReminderExtendDays = 7
+	ReminderSnoozeDays = 1
+)
+
+// ReminderActionExtension returns how far action pushes a deadline back. A
+// reset, the default for an empty action, is an ordinary check-in and
+// returns 0. ok is false for unknown actions.
+func ReminderActionExtension(action string) (by time.Duration, ok bool) {
+	switch action {
+	case "", ReminderActionReset:
+		return 0, true
+	case ReminderActionExtend:
+		return ReminderExtendDays * 24 * time.Hour, true
+	case ReminderActionSnooze:
+		return ReminderSnoozeDays * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// ReminderActionQuery returns the query string selecting action on a
+// quick-heartbeat link, limited to one switch when messageID is set.
+func ReminderActionQuery(action, messageID string) string {
+	query := url.Values{}
+	if action != "" && action != ReminderActionReset {
+		query.Set("action", action)
+	}
+	if messageID != "" {
+		query.Set("message", messageID)
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestMessageExtendDeadline(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now().UTC()
+	for _, msg := range []models.Message{
+		{ID: "m-due-soon", Status: models.StatusActive, LastSeen: now.Add(-30 * time.Minute)},
+		{ID: "m-parked", Status: models.StatusError, LastSeen: now.Add(-3 * time.Hour), MissedIntervals: 3},
+		{ID: "m-sent", Status: models.StatusTriggered, LastSeen: now.Add(-3 * time.Hour)},
+	} {
+		msg.UserID, msg.Content, msg.KeyFragment = "u-ext", "x", "v1"
+		msg.ManagementToken, msg.RecipientEmail, msg.TriggerDuration = "tok-"+msg.ID, "a@a.com", 60
+		if err := db.Create(&msg).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.MessageReminder{MessageID: "m-due-soon", MinutesBefore: 45, Sent: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (MessageService{}).ExtendDeadline("u-ext", "m-due-soon", 7*24*time.Hour); err != nil {
+		t.Fatalf("ExtendDeadline failed: %v", err)
+	}
+	load := func(id string) models.Message {
+		var msg models.Message
+		if err := db.First(&msg, "id = ?", id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	stored := load("m-due-soon")
+	if want := now.Add(30*time.Minute + 7*24*time.Hour); stored.TriggerAt().Sub(want).Abs() > time.Second {
+		t.Fatalf("deadline = %v, want %v", stored.TriggerAt(), want)
+	}
+	if stored.LastSeen.Sub(now.Add(-30*time.Minute)).Abs() > time.Second || stored.ExtendedUntil == nil {
+		t.Fatalf("extending must keep last_seen as the last check-in and store the deadline apart: %+v", stored)
+	}
+	var reminder models.MessageReminder
+	db.First(&reminder, "message_id = ?", "m-due-soon")
+	if reminder.Sent {
+		t.Fatal("extending should re-arm sent reminders")
+	}
+	if load("m-parked").Status != models.StatusError {
+		t.Fatal("extending one switch must not touch the others")
+	}
+
+	if err := (MessageService{}).ExtendDeadline("u-ext", "", 24*time.Hour); err != nil {
+		t.Fatalf("ExtendDeadline of all switches failed: %v", err)
+	}
+	stored = load("m-parked")
+	if stored.Status != models.StatusActive || stored.MissedIntervals != 0 || stored.TriggerAt().Sub(now.Add(24*time.Hour)).Abs() > 5*time.Second {
+		t.Fatalf("an overdue switch should be active and due a day from now: %+v", stored)
+	}
+	transitions, _ := (MessageService{}).ListTransitions("u-ext", "m-parked")
+	if len(transitions) != 1 || transitions[0].Reason != models.TransitionReasonExtended {
+		t.Fatalf("unexpected transitions: %+v", transitions)
+	}
+	if sent := load("m-sent"); !sent.LastSeen.Before(now.Add(-2*time.Hour)) || sent.ExtendedUntil != nil {
+		t.Fatal("a triggered switch must not be extended")
+	}
+
+	if err := (MessageService{}).BulkHeartbeat("u-ext"); err != nil {
+		t.Fatalf("BulkHeartbeat failed: %v", err)
+	}
+	stored = load("m-due-soon")
+	if stored.LastSeen.Before(now) {
+		t.Fatalf("a check-in should still record last_seen: %v", stored.LastSeen)
+	}
+	if want := now.Add(30*time.Minute + 8*24*time.Hour); stored.TriggerAt().Sub(want).Abs() > time.Second {
+		t.Fatalf("a check-in must not shorten an extension: deadline = %v, want %v", stored.TriggerAt(), want)
+	}
+
+	if err := (MessageService{}).ExtendDeadline("u-ext", "m-sent", time.Hour); err == nil {
+		t.Fatal("expected an error extending a triggered switch")
+	}
+	if err := (MessageService{}).ExtendDeadline("u-other", "m-due-soon", time.Hour); err == nil {
+		t.Fatal("expected not found for another account's switch")
+	}
+}
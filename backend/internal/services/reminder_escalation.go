@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+// ReminderStep is one entry of Settings.ReminderEscalation.
+type ReminderStep struct {
+	Channel       string
+	MinutesBefore int
+}
+
+// ParseReminderEscalation parses "channel:minutes_before" pairs, returning
+// them ordered from the earliest reminder to the last one before trigger.
+func ParseReminderEscalation(raw string) ([]ReminderStep, error) {
+	var steps []ReminderStep
+	seen := map[ReminderStep]bool{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		channel, minutes, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, BadRequest(fmt.Sprintf("Invalid reminder escalation entry %q; expected channel:minutes", entry), nil)
+		}
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		switch channel {
+		case models.ReminderChannelEmail, models.ReminderChannelNtfy, models.ReminderChannelWebhook:
+		default:
+			return nil, BadRequest(fmt.Sprintf("Unknown reminder channel %q", channel), nil)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(minutes))
+		if err != nil || n <= 0 {
+			return nil, BadRequest(fmt.Sprintf("Reminder lead time must be a positive number of minutes: %q", entry), err)
+		}
+		step := ReminderStep{Channel: channel, MinutesBefore: n}
+		if seen[step] {
+			continue
+		}
+		seen[step] = true
+		steps = append(steps, step)
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].MinutesBefore > steps[j].MinutesBefore })
+	return steps, nil
+}
+
+// FormatReminderEscalation renders steps in the canonical stored form.
+func FormatReminderEscalation(steps []ReminderStep) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = step.Channel + ":" + strconv.Itoa(step.MinutesBefore)
+	}
+	return strings.Join(parts, ",")
+}
+
+func validateNtfyURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || strings.Trim(parsed.Path, "/") == "" {
+		return "", BadRequest("ntfy URL must be an https topic URL such as https://ntfy.sh/my-topic", err)
+	}
+	if parsed.User != nil {
+		return "", BadRequest("ntfy URL must not include credentials", nil)
+	}
+	if err := validatePublicHostname(strings.ToLower(parsed.Hostname())); err != nil {
+		return "", BadRequest("ntfy URL host is not allowed", err)
+	}
+	return parsed.String(), nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestParseReminderEscalation(t *testing.T) {
+	steps, err := ParseReminderEscalation(" webhook:15, EMAIL:1440 ,ntfy:60,, email:1440")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := FormatReminderEscalation(steps), "email:1440,ntfy:60,webhook:15"; got != want {
+		t.Fatalf("escalation = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"sms:10", "email", "ntfy:0", "webhook:-5", "email:soon"} {
+		if _, err := ParseReminderEscalation(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSendReminderWebhooks_OmitsContent(t *testing.T) {
+	setupTestDB(t)
+	initTestKeyManager(t)
+	var event string
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event = r.Header.Get("X-Aeterna-Event")
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	msg := models.Message{ID: "m1", Content: "secret", TriggerDuration: 60}
+	reminder := models.MessageReminder{MinutesBefore: 15, Channel: models.ReminderChannelWebhook}
+	if err := (WebhookService{}).SendReminderWebhooks([]models.Webhook{{URL: srv.URL}}, msg, reminder, true); err != nil {
+		t.Fatalf("SendReminderWebhooks: %v", err)
+	}
+	if event != "switch.reminder" {
+		t.Fatalf("X-Aeterna-Event = %q, want switch.reminder", event)
+	}
+	if _, ok := payload["content"]; ok {
+		t.Fatal("reminder payload must not include message content")
+	}
+	if payload["final"] != true || payload["minutes_before"] != float64(15) {
+		t.Fatalf("unexpected payload: %v", payload)
+	}
+}
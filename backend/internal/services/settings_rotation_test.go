@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestRotateHeartbeatToken_InvalidatesOldToken(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Settings{UserID: "u1", HeartbeatToken: "old-token"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewSettingsService(config.Config{})
+	svc.cfg.Auth.HeartbeatTokenBytes = 48
+	token, err := svc.RotateHeartbeatToken("u1")
+	if err != nil {
+		t.Fatalf("RotateHeartbeatToken: %v", err)
+	}
+	if len(token) != 64 {
+		t.Fatalf("token length = %d, want 64 base64 characters for 48 bytes", len(token))
+	}
+
+	if _, err := svc.GetByHeartbeatToken("old-token"); err == nil {
+		t.Fatal("old token must stop working after rotation")
+	}
+	got, err := svc.GetByHeartbeatToken(token)
+	if err != nil || got.UserID != "u1" {
+		t.Fatalf("new token lookup = (%q, %v), want u1", got.UserID, err)
+	}
+	if got.HeartbeatTokenRotatedAt == nil {
+		t.Fatal("rotation time must be recorded")
+	}
+
+	if _, err := svc.RotateHeartbeatToken("missing"); err == nil {
+		t.Fatal("expected error for a user without settings")
+	}
+}
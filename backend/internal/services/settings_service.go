@@ -1,17 +1,39 @@
 package services
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"net"
 	"net/smtp"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
 	"github.com/alpyxn/aeterna/backend/internal/database"
 	"github.com/alpyxn/aeterna/backend/internal/models"
 	"gorm.io/gorm"
 )
 
+const (
+	maxBrandNameLength            = 60
+	maxHeartbeatTokenRotationDays = 365
+	maxOwnerNameLength            = 100
+	maxOwnerSignatureLength       = 1000
+	maxFooterTextLength           = 500
+)
+
+// smtpPorts are the submission ports mail providers actually use; anything
+// else is almost always a typo that would only surface when a switch fires.
+var smtpPorts = map[string]bool{"25": true, "465": true, "587": true, "2525": true}
+
+var brandColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
 type SettingsService struct {
 	cfg config.Config
 }
@@ -29,36 +51,98 @@ func (s SettingsService) Get(userID string) (models.Settings, error) {
 		}
 		return models.Settings{}, Internal("Failed to fetch settings", result.Error)
 	}
-	if settings.SMTPPass != "" {
-		decrypted, err := cryptoService.DecryptIfNeeded(settings.SMTPPass)
+	// SMTP_PASSWORD_FILE takes precedence over the stored password and is
+	// held to the same 0600 rule as the encryption key file.
+	if s.cfg.SMTP.PasswordFile != "" {
+		password, err := (&FileKeySource{path: s.cfg.SMTP.PasswordFile}).GetKey()
+		if err != nil {
+			return models.Settings{}, Internal("Failed to read SMTP_PASSWORD_FILE", err)
+		}
+		settings.SMTPPass = password
+	} else if settings.SMTPPass != "" {
+		decrypted, err := cryptoService.DecryptIfNeededWithContext(settings.SMTPPass, settingsSecretContext(userID, "smtp_pass"))
 		if err != nil {
 			return models.Settings{}, err
 		}
 		settings.SMTPPass = decrypted
 	}
 	if settings.WebhookSecret != "" {
-		decrypted, err := cryptoService.DecryptIfNeeded(settings.WebhookSecret)
+		decrypted, err := cryptoService.DecryptIfNeededWithContext(settings.WebhookSecret, settingsSecretContext(userID, "webhook_secret"))
 		if err != nil {
 			return models.Settings{}, err
 		}
 		settings.WebhookSecret = decrypted
 	}
+	ownerEmail, err := decryptOwnerEmail(settings)
+	if err != nil {
+		return models.Settings{}, err
+	}
+	settings.OwnerEmail = ownerEmail
 	return settings, nil
 }
 
+// encryptOwnerEmail and decryptOwnerEmail keep the owner's address out of
+// the database in cleartext. Nothing is looked up by it, so it needs no
+// blind index. Rows written before it was encrypted are read as they are.
+func encryptOwnerEmail(userID, email string) (string, error) {
+	return cryptoService.EncryptIfNeededWithContext(email, settingsSecretContext(userID, "owner_email"))
+}
+
+func decryptOwnerEmail(settings models.Settings) (string, error) {
+	return cryptoService.DecryptIfNeededWithContext(settings.OwnerEmail, settingsSecretContext(settings.UserID, "owner_email"))
+}
+
 // GetByHeartbeatToken resolves settings for the quick-heartbeat public link.
+// Only the current token matches; rotated tokens stop working immediately.
 func (s SettingsService) GetByHeartbeatToken(token string) (models.Settings, error) {
+	if token == "" {
+		return models.Settings{}, NewAPIError(403, CodeForbidden, "Invalid token", nil)
+	}
 	var settings models.Settings
 	result := database.DB.Where("heartbeat_token = ?", token).First(&settings)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return models.Settings{}, NewAPIError(403, "forbidden", "Invalid token", nil)
+			return models.Settings{}, NewAPIError(403, CodeForbidden, "Invalid token", nil)
 		}
 		return models.Settings{}, Internal("Failed to fetch settings", result.Error)
 	}
+	ownerEmail, err := decryptOwnerEmail(settings)
+	if err != nil {
+		return models.Settings{}, err
+	}
+	settings.OwnerEmail = ownerEmail
 	return settings, nil
 }
 
+// RotateHeartbeatToken replaces the user's quick-heartbeat token, invalidating
+// every link issued with the old one.
+func (s SettingsService) RotateHeartbeatToken(userID string) (string, error) {
+	token, err := cryptoService.GenerateToken(heartbeatTokenBytes(s.cfg))
+	if err != nil {
+		return "", err
+	}
+	result := database.DB.Model(&models.Settings{}).Where("user_id = ?", userID).Updates(map[string]any{
+		"heartbeat_token":            token,
+		"heartbeat_token_rotated_at": time.Now().UTC(),
+	})
+	if result.Error != nil {
+		return "", Internal("Failed to rotate heartbeat token", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return "", NotFound("Settings not found", nil)
+	}
+	return token, nil
+}
+
+// heartbeatTokenBytes falls back to the default for configs built without
+// the auth section, as in tests.
+func heartbeatTokenBytes(cfg config.Config) int {
+	if cfg.Auth.HeartbeatTokenBytes > 0 {
+		return cfg.Auth.HeartbeatTokenBytes
+	}
+	return common.DefaultHeartbeatTokenBytes
+}
+
 func (s SettingsService) Save(userID string, req models.Settings) error {
 	req.WebhookURL = strings.TrimSpace(req.WebhookURL)
 	if req.WebhookEnabled && req.WebhookURL == "" {
@@ -71,20 +155,99 @@ func (s SettingsService) Save(userID string, req models.Settings) error {
 		}
 		req.WebhookURL = validatedURL
 	}
+	req.NtfyURL = strings.TrimSpace(req.NtfyURL)
+	if req.NtfyURL != "" {
+		validatedURL, err := validateNtfyURL(req.NtfyURL)
+		if err != nil {
+			return err
+		}
+		req.NtfyURL = validatedURL
+	}
+	steps, err := ParseReminderEscalation(req.ReminderEscalation)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if step.Channel == models.ReminderChannelNtfy && req.NtfyURL == "" {
+			return BadRequest("An ntfy URL is required for ntfy reminders", nil)
+		}
+	}
+	req.ReminderEscalation = FormatReminderEscalation(steps)
+	req.BrandName = strings.TrimSpace(req.BrandName)
+	if len([]rune(req.BrandName)) > maxBrandNameLength {
+		return BadRequest(fmt.Sprintf("Brand name must be at most %d characters", maxBrandNameLength), nil)
+	}
+	req.BrandColor = strings.ToLower(strings.TrimSpace(req.BrandColor))
+	if req.BrandColor != "" && !brandColorPattern.MatchString(req.BrandColor) {
+		return BadRequest("Brand color must be a hex color such as #667eea", nil)
+	}
+	// OwnerName ends up in From and Subject headers, so it must stay on one
+	// line; the signature is body text and may span several.
+	req.OwnerName = strings.Join(strings.Fields(req.OwnerName), " ")
+	if len([]rune(req.OwnerName)) > maxOwnerNameLength {
+		return BadRequest(fmt.Sprintf("Owner name must be at most %d characters", maxOwnerNameLength), nil)
+	}
+	if strings.IndexFunc(req.OwnerName, unicode.IsControl) >= 0 {
+		return BadRequest("Owner name must not contain control characters", nil)
+	}
+	req.OwnerSignature = strings.TrimSpace(strings.ReplaceAll(req.OwnerSignature, "\r\n", "\n"))
+	if len([]rune(req.OwnerSignature)) > maxOwnerSignatureLength {
+		return BadRequest(fmt.Sprintf("Owner signature must be at most %d characters", maxOwnerSignatureLength), nil)
+	}
+	if req.FooterText != nil {
+		footer := strings.TrimSpace(strings.ReplaceAll(*req.FooterText, "\r\n", "\n"))
+		if len([]rune(footer)) > maxFooterTextLength {
+			return BadRequest(fmt.Sprintf("Footer text must be at most %d characters", maxFooterTextLength), nil)
+		}
+		req.FooterText = &footer
+	}
+	if err := normalizeContactCard(&req); err != nil {
+		return err
+	}
+	if req.HeartbeatTokenRotationDays < 0 || req.HeartbeatTokenRotationDays > maxHeartbeatTokenRotationDays {
+		return BadRequest(fmt.Sprintf("Heartbeat token rotation must be between 0 and %d days", maxHeartbeatTokenRotationDays), nil)
+	}
+	req.IMAPHost = strings.TrimSpace(req.IMAPHost)
+	req.IMAPPort = strings.TrimSpace(req.IMAPPort)
+	if req.IMAPHost != "" && strings.ContainsAny(req.IMAPHost, "/:@ ") {
+		return BadRequest("IMAP host must be a hostname such as imap.example.com", nil)
+	}
+	if req.IMAPPort != "" {
+		if port, err := strconv.Atoi(req.IMAPPort); err != nil || port < 1 || port > 65535 {
+			return BadRequest("IMAP port must be a number between 1 and 65535", err)
+		}
+	}
+	if err := validateSMTPSettings(&req); err != nil {
+		return err
+	}
+	if req.EmailCheckInEnabled && (req.IMAPHost == "" || req.OwnerEmail == "") {
+		return BadRequest("Email check-in requires an IMAP host and an owner email", nil)
+	}
+	req.Timezone = strings.TrimSpace(req.Timezone)
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "Local" {
+			return BadRequest("Time zone must be an IANA name such as Europe/Berlin", err)
+		}
+	}
 	if req.SMTPPass != "" {
-		encrypted, err := cryptoService.EncryptIfNeeded(req.SMTPPass)
+		encrypted, err := cryptoService.EncryptIfNeededWithContext(req.SMTPPass, settingsSecretContext(userID, "smtp_pass"))
 		if err != nil {
 			return err
 		}
 		req.SMTPPass = encrypted
 	}
 	if req.WebhookSecret != "" {
-		encrypted, err := cryptoService.EncryptIfNeeded(req.WebhookSecret)
+		encrypted, err := cryptoService.EncryptIfNeededWithContext(req.WebhookSecret, settingsSecretContext(userID, "webhook_secret"))
 		if err != nil {
 			return err
 		}
 		req.WebhookSecret = encrypted
 	}
+	ownerEmail, err := encryptOwnerEmail(userID, req.OwnerEmail)
+	if err != nil {
+		return err
+	}
+	req.OwnerEmail = ownerEmail
 
 	req.UserID = userID
 
@@ -108,12 +271,43 @@ func (s SettingsService) Save(userID string, req models.Settings) error {
 	}
 	existing.SMTPFrom = req.SMTPFrom
 	existing.SMTPFromName = req.SMTPFromName
+	existing.SMTPHeloName = req.SMTPHeloName
 	existing.WebhookURL = req.WebhookURL
 	if req.WebhookSecret != "" {
 		existing.WebhookSecret = req.WebhookSecret
 	}
 	existing.WebhookEnabled = req.WebhookEnabled
 	existing.OwnerEmail = req.OwnerEmail
+	existing.IncludeContentInOwnerNotification = req.IncludeContentInOwnerNotification
+	existing.DeliverAsPDF = req.DeliverAsPDF
+	existing.NtfyURL = req.NtfyURL
+	existing.ReminderEscalation = req.ReminderEscalation
+	existing.BrandName = req.BrandName
+	existing.BrandColor = req.BrandColor
+	if req.HeartbeatTokenRotationDays > 0 && existing.HeartbeatTokenRotationDays == 0 {
+		// Start the rotation clock now rather than rotating immediately.
+		now := time.Now().UTC()
+		existing.HeartbeatTokenRotatedAt = &now
+	}
+	existing.HeartbeatTokenRotationDays = req.HeartbeatTokenRotationDays
+	existing.EmailCheckInEnabled = req.EmailCheckInEnabled
+	existing.OwnerName = req.OwnerName
+	existing.OwnerSignature = req.OwnerSignature
+	if req.FooterText != nil {
+		existing.FooterText = req.FooterText
+	}
+	existing.ContactName = req.ContactName
+	existing.ContactPhone = req.ContactPhone
+	existing.ContactEmail = req.ContactEmail
+	existing.ContactRelationship = req.ContactRelationship
+	existing.LockoutAlertsEnabled = req.LockoutAlertsEnabled
+	existing.Timezone = req.Timezone
+	existing.ReminderDigest = req.ReminderDigest
+	existing.HeartbeatConfirmation = req.HeartbeatConfirmation
+	existing.ReminderActions = req.ReminderActions
+	existing.QuickHeartbeatConfirmLink = req.QuickHeartbeatConfirmLink
+	existing.IMAPHost = req.IMAPHost
+	existing.IMAPPort = req.IMAPPort
 
 	if err := database.DB.Save(&existing).Error; err != nil {
 		return Internal("Failed to save settings", err)
@@ -122,36 +316,101 @@ func (s SettingsService) Save(userID string, req models.Settings) error {
 	return nil
 }
 
-func (s SettingsService) TestSMTP(req models.Settings) error {
+// validateSMTPSettings normalizes the SMTP fields and rejects values that
+// could never deliver. Nothing is checked until a host is set.
+func validateSMTPSettings(req *models.Settings) error {
+	req.SMTPHost = strings.TrimSpace(req.SMTPHost)
+	req.SMTPPort = strings.TrimSpace(req.SMTPPort)
+	req.SMTPFrom = strings.TrimSpace(req.SMTPFrom)
+	req.SMTPHeloName = strings.TrimSpace(req.SMTPHeloName)
+	if req.SMTPHost == "" {
+		return nil
+	}
+	if strings.ContainsAny(req.SMTPHost, "/:@ ") {
+		return BadRequest("SMTP host must be a hostname such as smtp.example.com", nil)
+	}
+	if _, err := strconv.Atoi(req.SMTPPort); err != nil {
+		return BadRequest("SMTP port must be a number", err)
+	}
+	if !smtpPorts[req.SMTPPort] {
+		return BadRequest("SMTP port must be 25, 465, 587 or 2525", nil)
+	}
+	if req.SMTPFrom != "" {
+		if err := (ValidationService{}).ValidateEmail("smtp_from", req.SMTPFrom); err != nil {
+			return InvalidField("smtp_from", "SMTP from address must be a valid email address", err)
+		}
+	}
+	if req.SMTPHeloName != "" && !isHostname(req.SMTPHeloName) {
+		return BadRequest("SMTP HELO name must be a hostname such as mail.example.com", nil)
+	}
+	return nil
+}
+
+// isHostname reports whether name is a fully qualified DNS hostname: dot
+// separated labels of letters, digits and inner hyphens, as RFC 5321
+// expects in EHLO.
+func isHostname(name string) bool {
+	if len(name) > 253 || !strings.Contains(name, ".") {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TestSMTP connects and authenticates with req's SMTP settings. The
+// connection is closed as soon as ctx is done, so an unresponsive server
+// cannot hold the caller past its deadline.
+func (s SettingsService) TestSMTP(ctx context.Context, req models.Settings) error {
 	if req.SMTPHost == "" || req.SMTPPort == "" {
 		return BadRequest("SMTP host and port are required", nil)
 	}
 	if req.SMTPUser == "" || req.SMTPPass == "" {
 		return BadRequest("SMTP username and password are required for test", nil)
 	}
+	req.SMTPHeloName = strings.TrimSpace(req.SMTPHeloName)
+	if req.SMTPHeloName != "" && !isHostname(req.SMTPHeloName) {
+		return BadRequest("SMTP HELO name must be a hostname such as mail.example.com", nil)
+	}
 
 	addr := req.SMTPHost + ":" + req.SMTPPort
 	tlsConfig := &tls.Config{ServerName: req.SMTPHost}
 
-	var client *smtp.Client
-	var err error
+	raw, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return BadRequest("Failed to connect", err)
+	}
+	defer raw.Close()
+	stop := context.AfterFunc(ctx, func() { raw.Close() })
+	defer stop()
 
+	conn := raw
 	if req.SMTPPort == "465" {
-		conn, dialErr := tls.Dial("tcp", addr, tlsConfig)
-		if dialErr != nil {
-			return BadRequest("Failed to connect (SSL)", dialErr)
-		}
-		client, err = smtp.NewClient(conn, req.SMTPHost)
-		if err != nil {
-			conn.Close()
-			return BadRequest("Failed to create client", err)
+		tlsConn := tls.Client(raw, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return BadRequest("Failed to connect (SSL)", err)
 		}
-	} else {
-		client, err = smtp.Dial(addr)
-		if err != nil {
-			return BadRequest("Failed to connect", err)
+		conn = tlsConn
+	}
+	client, err := smtp.NewClient(conn, req.SMTPHost)
+	if err != nil {
+		return BadRequest("Failed to create client", err)
+	}
+	if req.SMTPHeloName != "" {
+		if err := client.Hello(req.SMTPHeloName); err != nil {
+			client.Close()
+			return BadRequest("HELO rejected", err)
 		}
-
+	}
+	if req.SMTPPort != "465" {
 		if ok, _ := client.Extension("STARTTLS"); ok {
 			if err := client.StartTLS(tlsConfig); err != nil {
 				client.Close()
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestSettingsSaveValidatesSMTP(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := SettingsService{}
+
+	rejected := map[string]models.Settings{
+		"non-numeric port": {SMTPHost: "smtp.example.com", SMTPPort: "58x"},
+		"missing port":     {SMTPHost: "smtp.example.com"},
+		"uncommon port":    {SMTPHost: "smtp.example.com", SMTPPort: "5870"},
+		"URL-style host":   {SMTPHost: "smtp://example.com", SMTPPort: "587"},
+		"invalid from":     {SMTPHost: "smtp.example.com", SMTPPort: "587", SMTPFrom: "not-an-email"},
+		"bare HELO name":   {SMTPHost: "smtp.example.com", SMTPPort: "587", SMTPHeloName: "mailer"},
+		"HELO with space":  {SMTPHost: "smtp.example.com", SMTPPort: "587", SMTPHeloName: "mail example.com"},
+		"HELO label dash":  {SMTPHost: "smtp.example.com", SMTPPort: "587", SMTPHeloName: "-mail.example.com"},
+	}
+	for name, settings := range rejected {
+		if err := svc.Save("u1", settings); err == nil {
+			t.Fatalf("%s: expected SMTP settings to be rejected", name)
+		}
+	}
+
+	if err := svc.Save("u1", models.Settings{SMTPPort: "whatever"}); err != nil {
+		t.Fatalf("expected SMTP fields to be unchecked without a host: %v", err)
+	}
+	if err := svc.Save("u1", models.Settings{SMTPHost: " smtp.example.com ", SMTPPort: " 465 ", SMTPFrom: "mailer@example.com", SMTPHeloName: " mail.example.com "}); err != nil {
+		t.Fatalf("expected valid SMTP settings to save: %v", err)
+	}
+	saved, err := svc.Get("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.SMTPHost != "smtp.example.com" || saved.SMTPPort != "465" {
+		t.Fatalf("expected SMTP host and port to be trimmed, got %q:%q", saved.SMTPHost, saved.SMTPPort)
+	}
+	if saved.SMTPHeloName != "mail.example.com" {
+		t.Fatalf("SMTPHeloName = %q, want it trimmed and saved", saved.SMTPHeloName)
+	}
+}
+
+func TestDialSMTPSendsHeloName(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	greeting := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("220 fake ESMTP\r\n"))
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		greeting <- strings.TrimSpace(line)
+		// No STARTTLS offered, so the client gives up here.
+		conn.Write([]byte("250 fake\r\n"))
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	_, err = dialSMTP(models.Settings{SMTPHost: host, SMTPPort: port, SMTPHeloName: "mail.example.com"})
+	if err == nil {
+		t.Fatal("expected dialSMTP to refuse a server without STARTTLS")
+	}
+	if got := <-greeting; got != "EHLO mail.example.com" {
+		t.Fatalf("greeting = %q, want EHLO with the configured name", got)
+	}
+}
+
+func TestSettingsGetPrefersSMTPPasswordFile(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+	if err := (SettingsService{}).Save("u1", models.Settings{SMTPHost: "smtp.example.com", SMTPPort: "587", SMTPUser: "mailer", SMTPPass: "stored-pass"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "smtp_password")
+	if err := os.WriteFile(path, []byte("file-pass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewSettingsService(config.Config{SMTP: config.SMTPConfig{PasswordFile: path}})
+	got, err := svc.Get("u1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.SMTPPass != "file-pass" {
+		t.Fatalf("SMTPPass = %q, want the file contents", got.SMTPPass)
+	}
+
+	if stored, err := (SettingsService{}).Get("u1"); err != nil || stored.SMTPPass != "stored-pass" {
+		t.Fatalf("without the file the stored password is used, got %q (%v)", stored.SMTPPass, err)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Get("u1"); err == nil || !strings.Contains(err.Error(), "insecure permissions") {
+		t.Fatalf("expected a world-readable password file to be refused, got %v", err)
+	}
+}
+
+func TestSettingsOwnerEmailEncryptedAtRest(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	initTestKeyManager(t)
+	svc := SettingsService{}
+	if err := svc.Save("u1", models.Settings{OwnerEmail: "owner@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored models.Settings
+	if err := db.First(&stored, "user_id = ?", "u1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored.OwnerEmail, "owner") || !isBoundCiphertext(stored.OwnerEmail) {
+		t.Fatalf("owner email stored as %q, want bound ciphertext", stored.OwnerEmail)
+	}
+	if got, err := svc.Get("u1"); err != nil || got.OwnerEmail != "owner@example.com" {
+		t.Fatalf("Get OwnerEmail = %q (%v)", got.OwnerEmail, err)
+	}
+
+	// Saving what Get returned must not encrypt twice.
+	if err := svc.Save("u1", models.Settings{OwnerEmail: "owner@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := svc.Get("u1"); err != nil || got.OwnerEmail != "owner@example.com" {
+		t.Fatalf("after a second save OwnerEmail = %q (%v)", got.OwnerEmail, err)
+	}
+}
+
+func TestTestSMTPGivesUpWhenContextEnds(t *testing.T) {
+	// The listener accepts connections but never sends a greeting.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = SettingsService{}.TestSMTP(ctx, models.Settings{SMTPHost: host, SMTPPort: port, SMTPUser: "u", SMTPPass: "p"})
+	if err == nil {
+		t.Fatal("expected the test against a silent server to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("TestSMTP took %s, want it to stop at the context deadline", elapsed)
+	}
+}
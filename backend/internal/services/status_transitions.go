@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecordStatusTransition stores a change of msg's status from one value to
+// another. tx may be a transaction so the record commits with the change.
+func RecordStatusTransition(tx *gorm.DB, msg models.Message, from, to models.MessageStatus, reason, actor string) error {
+	return tx.Create(&models.StatusTransition{
+		MessageID:  msg.ID,
+		UserID:     msg.UserID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		Actor:      actor,
+		CreatedAt:  time.Now().UTC(),
+	}).Error
+}
+
+// ListTransitions returns a switch's status history, oldest first.
+func (s MessageService) ListTransitions(userID, id string) ([]models.StatusTransition, error) {
+	var msg models.Message
+	if err := database.ForTenant(userID).Select("id").First(&msg, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NotFound("Message not found", err)
+		}
+		return nil, Internal("Failed to fetch message", err)
+	}
+	transitions := []models.StatusTransition{}
+	if err := database.ForTenant(userID).Where("message_id = ?", id).Order("created_at ASC, id ASC").Find(&transitions).Error; err != nil {
+		return nil, Internal("Failed to fetch status transitions", err)
+	}
+	return transitions, nil
+}
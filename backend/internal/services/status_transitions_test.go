@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestHeartbeatRecordsStatusTransitions(t *testing.T) {
+	db := setupTestDB(t)
+	for _, msg := range []models.Message{
+		{ID: "m-one", Status: models.StatusError},
+		{ID: "m-bulk", Status: models.StatusError},
+		{ID: "m-active", Status: models.StatusActive},
+	} {
+		msg.UserID, msg.Content, msg.KeyFragment = "u-tr", "x", "v1"
+		msg.ManagementToken, msg.RecipientEmail = "tok-"+msg.ID, "a@a.com"
+		msg.TriggerDuration, msg.LastSeen = 60, time.Now().Add(-2*time.Hour)
+		if err := db.Create(&msg).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := (MessageService{}).Heartbeat("u-tr", "m-one"); err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+	if _, err := (MessageService{}).Heartbeat("u-tr", "m-one"); err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+	if err := (MessageService{}).BulkHeartbeat("u-tr"); err != nil {
+		t.Fatalf("BulkHeartbeat failed: %v", err)
+	}
+
+	for _, id := range []string{"m-one", "m-bulk"} {
+		transitions, err := (MessageService{}).ListTransitions("u-tr", id)
+		if err != nil {
+			t.Fatalf("ListTransitions(%s) failed: %v", id, err)
+		}
+		if len(transitions) != 1 {
+			t.Fatalf("%s: want one transition, got %+v", id, transitions)
+		}
+		tr := transitions[0]
+		if tr.FromStatus != models.StatusError || tr.ToStatus != models.StatusActive ||
+			tr.Reason != models.TransitionReasonHeartbeat || tr.Actor != models.TransitionActorOwner {
+			t.Fatalf("%s: unexpected transition %+v", id, tr)
+		}
+	}
+	transitions, err := (MessageService{}).ListTransitions("u-tr", "m-active")
+	if err != nil || len(transitions) != 0 {
+		t.Fatalf("a heartbeat that keeps a switch active records nothing: %+v, %v", transitions, err)
+	}
+
+	if _, err := (MessageService{}).ListTransitions("u-other", "m-one"); err == nil {
+		t.Fatal("another account must not see the history")
+	}
+
+	if err := (MessageService{}).Delete("u-tr", "m-one", false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	var left int64
+	db.Model(&models.StatusTransition{}).Where("message_id = ?", "m-one").Count(&left)
+	if left != 0 {
+		t.Fatalf("deleting a message should delete its transitions, %d left", left)
+	}
+}
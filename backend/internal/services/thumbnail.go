@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+
+	// Register decoders for the formats we thumbnail.
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// ThumbnailMaxDimension bounds the longest side of generated previews.
+	ThumbnailMaxDimension = 256
+	// thumbnailMaxSourcePixels guards against decompression bombs.
+	thumbnailMaxSourcePixels = 40_000_000
+)
+
+var errNoThumbnail = errors.New("thumbnail not supported for this file")
+
+// thumbnailable reports whether a preview can be generated for mimeType.
+func thumbnailable(mimeType string) bool {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// generateThumbnail decodes an image and returns a JPEG preview whose longest
+// side is at most ThumbnailMaxDimension.
+func generateThumbnail(mimeType string, data []byte) ([]byte, error) {
+	if !thumbnailable(mimeType) {
+		return nil, errNoThumbnail
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > thumbnailMaxSourcePixels {
+		return nil, errNoThumbnail
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, downscale(src, ThumbnailMaxDimension), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// downscale box-filters src so its longest side fits within maxDim. Images
+// already small enough are only flattened onto white.
+func downscale(src image.Image, maxDim int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if w > maxDim || h > maxDim {
+		if w >= h {
+			dw, dh = maxDim, max(1, h*maxDim/w)
+		} else {
+			dw, dh = max(1, w*maxDim/h), maxDim
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := b.Min.Y + y*h/dh
+		y1 := max(y0+1, b.Min.Y+(y+1)*h/dh)
+		for x := 0; x < dw; x++ {
+			x0 := b.Min.X + x*w/dw
+			x1 := max(x0+1, b.Min.X+(x+1)*w/dw)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					// Composite onto white so transparent PNG/GIF areas do not turn black.
+					white := 0xffff - ca
+					r += uint64(cr + white)
+					g += uint64(cg + white)
+					bl += uint64(cb + white)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: 0xffff})
+		}
+	}
+	return dst
+}
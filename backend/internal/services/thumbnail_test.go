@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateThumbnail_ScalesLongestSide(t *testing.T) {
+	thumb, err := generateThumbnail("image/png", encodeTestPNG(t, 1000, 500))
+	if err != nil {
+		t.Fatalf("generateThumbnail: %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if cfg.Width != ThumbnailMaxDimension || cfg.Height != ThumbnailMaxDimension/2 {
+		t.Fatalf("thumbnail = %dx%d, want %dx%d", cfg.Width, cfg.Height, ThumbnailMaxDimension, ThumbnailMaxDimension/2)
+	}
+}
+
+func TestGenerateThumbnail_KeepsSmallImageSize(t *testing.T) {
+	thumb, err := generateThumbnail("image/png", encodeTestPNG(t, 40, 30))
+	if err != nil {
+		t.Fatalf("generateThumbnail: %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if cfg.Width != 40 || cfg.Height != 30 {
+		t.Fatalf("thumbnail = %dx%d, want 40x30", cfg.Width, cfg.Height)
+	}
+}
+
+func TestGenerateThumbnail_SkipsNonImages(t *testing.T) {
+	if _, err := generateThumbnail("application/pdf", []byte("%PDF-1.4")); !errors.Is(err, errNoThumbnail) {
+		t.Fatalf("expected errNoThumbnail, got %v", err)
+	}
+}
+
+func TestGenerateThumbnail_DecodesWebP(t *testing.T) {
+	// A 1x1 lossless WebP.
+	data, err := base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := generateThumbnail("image/webp", data)
+	if err != nil {
+		t.Fatalf("generateThumbnail: %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if cfg.Width != 1 || cfg.Height != 1 {
+		t.Fatalf("thumbnail = %dx%d, want 1x1", cfg.Width, cfg.Height)
+	}
+}
@@ -24,7 +24,7 @@ func NewUserAdminService(cfg config.Config) UserAdminService {
 // List returns all accounts when the actor is the primary (first) user.
 func (s UserAdminService) List(actorUserID string) ([]models.UserListItem, error) {
 	if !IsFirstUser(actorUserID) {
-		return nil, NewAPIError(403, "forbidden", "Only the primary administrator can list users.", nil)
+		return nil, NewAPIError(403, CodeForbidden, "Only the primary administrator can list users.", nil)
 	}
 
 	var first models.User
@@ -55,16 +55,16 @@ func (s UserAdminService) List(actorUserID string) ([]models.UserListItem, error
 // Delete removes a non-primary user and all tenant data when the actor is primary.
 func (s UserAdminService) Delete(actorUserID, targetUserID string) error {
 	if !IsFirstUser(actorUserID) {
-		return NewAPIError(403, "forbidden", "Only the primary administrator can delete users.", nil)
+		return NewAPIError(403, CodeForbidden, "Only the primary administrator can delete users.", nil)
 	}
 	if targetUserID == "" {
 		return BadRequest("User id is required", nil)
 	}
 	if actorUserID == targetUserID {
-		return NewAPIError(400, "cannot_delete_self", "You cannot delete your own account.", nil)
+		return NewAPIError(400, CodeCannotDeleteSelf, "You cannot delete your own account.", nil)
 	}
 	if IsFirstUser(targetUserID) {
-		return NewAPIError(400, "cannot_delete_primary", "The primary administrator account cannot be deleted.", nil)
+		return NewAPIError(400, CodeCannotDeletePrimary, "The primary administrator account cannot be deleted.", nil)
 	}
 
 	var target models.User
@@ -99,6 +99,11 @@ func (s UserAdminService) Delete(actorUserID, targetUserID string) error {
 	}
 
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Tombstones go first so the message deletes cascade to the
+		// transitions and delivered files they kept.
+		if err := tx.Where("user_id = ?", targetUserID).Delete(&models.MessageTombstone{}).Error; err != nil {
+			return Internal("Failed to delete message tombstones", err)
+		}
 		for _, msg := range msgs {
 			if err := tx.Unscoped().Where("message_id = ?", msg.ID).Delete(&models.MessageReminder{}).Error; err != nil {
 				return Internal("Failed to delete reminders", err)
@@ -113,6 +118,12 @@ func (s UserAdminService) Delete(actorUserID, targetUserID string) error {
 		if err := tx.Unscoped().Where("user_id = ?", targetUserID).Delete(&models.Settings{}).Error; err != nil {
 			return Internal("Failed to delete settings", err)
 		}
+		if err := tx.Unscoped().Where("user_id = ?", targetUserID).Delete(&models.RecoveryKey{}).Error; err != nil {
+			return Internal("Failed to delete recovery keys", err)
+		}
+		if err := tx.Where("user_id = ?", targetUserID).Delete(&models.AutomationToken{}).Error; err != nil {
+			return Internal("Failed to delete automation tokens", err)
+		}
 		if err := tx.Unscoped().Where("user_id = ?", targetUserID).Delete(&models.Attachment{}).Error; err != nil {
 			return Internal("Failed to delete attachment records", err)
 		}
@@ -125,6 +136,6 @@ func (s UserAdminService) Delete(actorUserID, targetUserID string) error {
 		return err
 	}
 
-	_ = os.RemoveAll(filepath.Join(GetUploadsDir(s.cfg.Database.Path), targetUserID))
+	_ = os.RemoveAll(filepath.Join(GetUploadsDir(s.cfg.Database), targetUserID))
 	return nil
 }
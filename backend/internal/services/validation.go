@@ -1,12 +1,20 @@
 package services
 
 import (
+	"bytes"
+	"fmt"
 	"html"
 	"net/http"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"golang.org/x/text/unicode/norm"
 )
 
 type ValidationService struct{}
@@ -16,6 +24,8 @@ const (
 	MinContentLength     = 1
 	MaxEmailLength       = 254
 	MaxRecipientEmails   = 20
+	MaxSubjectLength     = 200
+	MaxCreationReminders = 5
 	MaxFileSize          = 10 * 1024 * 1024 // 10 MB
 	MaxTotalAttachSize   = 25 * 1024 * 1024 // 25 MB
 	MaxAttachmentsPerMsg = 5
@@ -72,21 +82,84 @@ var AllowedFarewellMIMEPrefixes = append(
 	"audio/", "video/", "application/ogg",
 )
 
+// AllowedMediaExtensions are the audio and video formats a switch may carry
+// when ATTACHMENT_MEDIA_ENABLED is set. Each maps to a check of the file's
+// leading bytes, since http.DetectContentType misses MP3s without an ID3 tag
+// and does not recognise every MP4 brand.
+var AllowedMediaExtensions = map[string]func([]byte) bool{
+	".mp3":  isMP3,
+	".m4a":  isISOBaseMedia,
+	".ogg":  isOgg,
+	".mp4":  isISOBaseMedia,
+	".webm": isWebM,
+}
+
+// AllowedMediaMIMEPrefixes are the MIME types accepted alongside
+// AllowedMediaExtensions. application/ogg is what http.DetectContentType
+// reports for Ogg files.
+var AllowedMediaMIMEPrefixes = []string{"audio/", "video/", "application/ogg"}
+
+// detectMediaContentType is http.DetectContentType plus the untagged MP3 and
+// ISO base media signatures it reports as application/octet-stream.
+func detectMediaContentType(data []byte) string {
+	detected := http.DetectContentType(data)
+	if detected != "application/octet-stream" {
+		return detected
+	}
+	switch {
+	case isMP3(data):
+		return "audio/mpeg"
+	case isISOBaseMedia(data) && bytes.HasPrefix(data[8:], []byte("M4A ")):
+		return "audio/mp4"
+	case isISOBaseMedia(data):
+		return "video/mp4"
+	}
+	return detected
+}
+
+func isMP3(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("ID3")) {
+		return true
+	}
+	// An untagged file starts directly with an MPEG audio frame sync.
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0
+}
+
+func isISOBaseMedia(data []byte) bool {
+	return len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp"))
+}
+
+func isOgg(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("OggS"))
+}
+
+func isWebM(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3})
+}
+
+// IsMediaFile reports whether filename has one of AllowedMediaExtensions.
+func IsMediaFile(filename string) bool {
+	_, ok := AllowedMediaExtensions[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
 
-func (s ValidationService) ValidateEmail(email string) error {
+// ValidateEmail checks one address; field names the request field it came
+// from and is reported with any error.
+func (s ValidationService) ValidateEmail(field, email string) error {
 	email = strings.TrimSpace(email)
 
 	if email == "" {
-		return BadRequest("Email is required", nil)
+		return InvalidField(field, "Email is required", nil)
 	}
 
 	if len(email) > MaxEmailLength {
-		return BadRequest("Email address is too long", nil)
+		return InvalidField(field, "Email address is too long", nil)
 	}
 
 	if !emailRegex.MatchString(email) {
-		return BadRequest("Invalid email format", nil)
+		return InvalidField(field, "Invalid email format", nil)
 	}
 
 	// Check for common dangerous patterns
@@ -94,7 +167,7 @@ func (s ValidationService) ValidateEmail(email string) error {
 	dangerousPatterns := []string{"<script", "javascript:", "data:", "vbscript:"}
 	for _, pattern := range dangerousPatterns {
 		if strings.Contains(lowerEmail, pattern) {
-			return BadRequest("Invalid email format", nil)
+			return InvalidField(field, "Invalid email format", nil)
 		}
 	}
 
@@ -103,39 +176,60 @@ func (s ValidationService) ValidateEmail(email string) error {
 
 func (s ValidationService) ValidateEmailListLength(count int) error {
 	if count < 1 {
-		return BadRequest("At least one recipient email is required", nil)
+		return InvalidField("recipient_emails", "At least one recipient email is required", nil)
 	}
 	if count > MaxRecipientEmails {
-		return BadRequest("Too many recipient emails (max 20)", nil)
+		return InvalidField("recipient_emails", "Too many recipient emails (max 20)", nil)
 	}
 	return nil
 }
 
 func (s ValidationService) ValidateContent(content string) error {
 	if len(content) < MinContentLength {
-		return BadRequest("Content is required", nil)
+		return InvalidField("content", "Content is required", nil)
 	}
 
 	if len(content) > MaxContentLength {
-		return BadRequest("Content exceeds maximum length of 50000 characters", nil)
+		return InvalidField("content", "Content exceeds maximum length of 50000 characters", nil)
 	}
 
 	return nil
 }
 
+// SanitizeSubject trims an email subject and folds line breaks, tabs and
+// repeated spaces into single spaces, so it always fits on one header line.
+func (s ValidationService) SanitizeSubject(subject string) string {
+	return strings.Join(strings.Fields(subject), " ")
+}
+
+// ValidateSubject checks a sanitized email subject; empty means the default.
+func (s ValidationService) ValidateSubject(subject string) error {
+	if utf8.RuneCountInString(subject) > MaxSubjectLength {
+		return InvalidField("subject", fmt.Sprintf("Subject exceeds maximum length of %d characters", MaxSubjectLength), nil)
+	}
+	for _, r := range subject {
+		if unicode.IsControl(r) {
+			return InvalidField("subject", "Subject must not contain control characters", nil)
+		}
+	}
+	return nil
+}
+
 func (s ValidationService) SanitizeContent(content string) string {
 	// HTML escape to prevent XSS
 	sanitized := html.EscapeString(content)
 	return sanitized
 }
 
-func (s ValidationService) ValidatePassword(password string) error {
+// ValidatePassword applies the "classes" PASSWORD_POLICY; field names the
+// request field reported with any error.
+func (s ValidationService) ValidatePassword(field, password string) error {
 	if len(password) < 8 {
-		return BadRequest("Password must be at least 8 characters", nil)
+		return InvalidField(field, "Password must be at least 8 characters", nil)
 	}
 
 	if len(password) > 128 {
-		return BadRequest("Password exceeds maximum length", nil)
+		return InvalidField(field, "Password exceeds maximum length", nil)
 	}
 
 	var (
@@ -159,28 +253,75 @@ func (s ValidationService) ValidatePassword(password string) error {
 	}
 
 	if !hasUpper {
-		return BadRequest("Password must contain at least one uppercase letter", nil)
+		return InvalidField(field, "Password must contain at least one uppercase letter", nil)
 	}
 	if !hasLower {
-		return BadRequest("Password must contain at least one lowercase letter", nil)
+		return InvalidField(field, "Password must contain at least one lowercase letter", nil)
 	}
 	if !hasNumber {
-		return BadRequest("Password must contain at least one number", nil)
+		return InvalidField(field, "Password must contain at least one number", nil)
 	}
 	if !hasSpecial {
-		return BadRequest("Password must contain at least one special character (!@#$%^&* etc.)", nil)
+		return InvalidField(field, "Password must contain at least one special character (!@#$%^&* etc.)", nil)
 	}
 
 	return nil
 }
 
+// MaxTriggerDurationMinutes is one year.
+const MaxTriggerDurationMinutes = 525600
+
 // ValidateTriggerDuration validates the trigger duration in minutes
 func (s ValidationService) ValidateTriggerDuration(duration int) error {
 	if duration < 1 {
-		return BadRequest("Duration must be at least 1 minute", nil)
+		return InvalidField("trigger_duration", "Duration must be at least 1 minute", nil)
 	}
-	if duration > 525600 {
-		return BadRequest("Duration cannot exceed 1 year (525600 minutes)", nil)
+	if duration > MaxTriggerDurationMinutes {
+		return InvalidField("trigger_duration", "Duration cannot exceed 1 year (525600 minutes)", nil)
+	}
+	return nil
+}
+
+// MaxRequiredMissedIntervals caps how many missed intervals a switch may
+// tolerate before triggering.
+const MaxRequiredMissedIntervals = 10
+
+// ValidateRequiredMissedIntervals validates the missed-interval tolerance; 0
+// is accepted and means the default of 1.
+func (s ValidationService) ValidateRequiredMissedIntervals(intervals int) error {
+	if intervals < 0 || intervals > MaxRequiredMissedIntervals {
+		return InvalidField("required_missed_intervals", fmt.Sprintf("Required missed intervals must be between 1 and %d, or 0 for the default", MaxRequiredMissedIntervals), nil)
+	}
+	return nil
+}
+
+// ValidateCreationReminders checks the minutes-after-creation offsets of a
+// switch's creation reminders.
+func (s ValidationService) ValidateCreationReminders(minutesAfter []int) error {
+	if len(minutesAfter) > MaxCreationReminders {
+		return InvalidField("creation_reminders", fmt.Sprintf("At most %d creation reminders are allowed", MaxCreationReminders), nil)
+	}
+	for _, minutes := range minutesAfter {
+		if minutes < 1 {
+			return InvalidField("creation_reminders", "Creation reminders must be at least 1 minute after creation", nil)
+		}
+	}
+	return nil
+}
+
+// ValidateDeliveryWindow checks the hours (0-23) and IANA time zone of an
+// optional delivery window.
+func (s ValidationService) ValidateDeliveryWindow(window *models.DeliveryWindow) error {
+	if window == nil {
+		return nil
+	}
+	if window.StartHour < 0 || window.StartHour > 23 || window.EndHour < 0 || window.EndHour > 23 {
+		return InvalidField("delivery_window", "Delivery window hours must be between 0 and 23", nil)
+	}
+	if tz := strings.TrimSpace(window.Timezone); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return InvalidField("delivery_window", "Invalid delivery window time zone", err)
+		}
 	}
 	return nil
 }
@@ -191,6 +332,8 @@ type fileValidationOptions struct {
 	extensions   map[string]bool
 	extErrMsg    string
 	mimePrefixes []string
+	// detect sniffs the content type; nil uses http.DetectContentType.
+	detect func([]byte) string
 }
 
 func (s ValidationService) validateFileWith(filename string, size int64, data []byte, opts fileValidationOptions) error {
@@ -207,7 +350,11 @@ func (s ValidationService) validateFileWith(filename string, size int64, data []
 	if !opts.extensions[ext] {
 		return BadRequest(opts.extErrMsg, nil)
 	}
-	detectedMIME := http.DetectContentType(data)
+	detect := opts.detect
+	if detect == nil {
+		detect = http.DetectContentType
+	}
+	detectedMIME := detect(data)
 	for _, prefix := range opts.mimePrefixes {
 		if strings.HasPrefix(detectedMIME, prefix) {
 			return nil
@@ -227,6 +374,29 @@ func (s ValidationService) ValidateFile(filename string, size int64, data []byte
 	})
 }
 
+// ValidateMediaFile validates an audio or video switch attachment: extension,
+// magic bytes, MIME type, and the configured media size limit.
+func (s ValidationService) ValidateMediaFile(filename string, size int64, data []byte, maxSizeMB int) error {
+	extensions := make(map[string]bool, len(AllowedMediaExtensions))
+	for ext := range AllowedMediaExtensions {
+		extensions[ext] = true
+	}
+	if err := s.validateFileWith(filename, size, data, fileValidationOptions{
+		maxSize:      int64(maxSizeMB) * 1024 * 1024,
+		sizeErrMsg:   fmt.Sprintf("File exceeds maximum size of %d MB", maxSizeMB),
+		extensions:   extensions,
+		extErrMsg:    "File type not allowed. Allowed: MP3, M4A, OGG, MP4, WEBM",
+		mimePrefixes: AllowedMediaMIMEPrefixes,
+		detect:       detectMediaContentType,
+	}); err != nil {
+		return err
+	}
+	if !AllowedMediaExtensions[strings.ToLower(filepath.Ext(filename))](data) {
+		return BadRequest("File content does not match its extension", nil)
+	}
+	return nil
+}
+
 // ValidateFarewellFile validates a farewell letter attachment using provider-ceiling limits.
 func (s ValidationService) ValidateFarewellFile(filename string, size int64, data []byte) error {
 	return s.validateFileWith(filename, size, data, fileValidationOptions{
@@ -238,6 +408,42 @@ func (s ValidationService) ValidateFarewellFile(filename string, size int64, dat
 	})
 }
 
+// NormalizeFilename applies FILENAME_POLICY to an upload name before it is
+// sanitised. "normalize" converts it to NFC and drops bidi control
+// characters, which can make "invoice\u202Efdp.exe" display as
+// "invoiceexe.pdf"; "ascii" additionally transliterates to ASCII, folding
+// accents and replacing anything else, homoglyphs included, with "_".
+// "lenient" returns the name unchanged.
+func (s ValidationService) NormalizeFilename(filename, policy string) string {
+	switch policy {
+	case common.FilenamePolicyNormalize:
+		return stripBidiControls(norm.NFC.String(filename))
+	case common.FilenamePolicyASCII:
+		var b strings.Builder
+		for _, r := range norm.NFKD.String(stripBidiControls(filename)) {
+			switch {
+			case unicode.Is(unicode.Mn, r):
+			case r < utf8.RuneSelf:
+				b.WriteRune(r)
+			default:
+				b.WriteByte('_')
+			}
+		}
+		return b.String()
+	default:
+		return filename
+	}
+}
+
+func stripBidiControls(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Bidi_Control, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
 // SanitizeFilename cleans a filename to prevent path traversal and other attacks
 func (s ValidationService) SanitizeFilename(filename string) string {
 	// Extract just the base name (no directory path)
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
 )
 
 func TestValidateEmail(t *testing.T) {
@@ -23,7 +26,7 @@ func TestValidateEmail(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := svc.ValidateEmail(tc.email)
+			err := svc.ValidateEmail("email", tc.email)
 			if tc.wantErr && err == nil {
 				t.Fatalf("expected error, got nil")
 			}
@@ -52,7 +55,7 @@ func TestValidatePassword(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := svc.ValidatePassword(tc.password)
+			err := svc.ValidatePassword("password", tc.password)
 			if tc.wantErr && err == nil {
 				t.Fatalf("expected error, got nil")
 			}
@@ -92,6 +95,33 @@ func TestSanitizeFilename(t *testing.T) {
 	}
 }
 
+func TestNormalizeFilename(t *testing.T) {
+	svc := ValidationService{}
+
+	tests := []struct {
+		name   string
+		policy string
+		input  string
+		want   string
+	}{
+		{name: "lenient keeps bidi controls", policy: "lenient", input: "invoice\u202efdp.exe", want: "invoice\u202efdp.exe"},
+		{name: "unset policy is lenient", policy: "", input: "cafe\u0301.txt", want: "cafe\u0301.txt"},
+		{name: "normalize composes to NFC", policy: "normalize", input: "cafe\u0301.txt", want: "caf\u00e9.txt"},
+		{name: "normalize strips bidi controls", policy: "normalize", input: "invoice\u202efdp.exe", want: "invoicefdp.exe"},
+		{name: "ascii folds accents", policy: "ascii", input: "R\u00e9sum\u00e9.pdf", want: "Resume.pdf"},
+		{name: "ascii replaces homoglyphs", policy: "ascii", input: "p\u0430ypal.pdf", want: "p_ypal.pdf"},
+		{name: "ascii strips bidi controls", policy: "ascii", input: "a\u2067b.txt", want: "ab.txt"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := svc.NormalizeFilename(tc.input, tc.policy); got != tc.want {
+				t.Fatalf("NormalizeFilename(%q, %q) = %q, want %q", tc.input, tc.policy, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestValidateTriggerDuration(t *testing.T) {
 	svc := ValidationService{}
 
@@ -152,6 +182,71 @@ func TestValidateFile(t *testing.T) {
 	}
 }
 
+func TestValidateMediaFile(t *testing.T) {
+	svc := ValidationService{}
+
+	id3 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x00"), make([]byte, 32)...)
+	frameSync := append([]byte{0xFF, 0xFB, 0x90, 0x64}, make([]byte, 32)...)
+	mp4 := append([]byte{0x00, 0x00, 0x00, 0x18}, []byte("ftypisom\x00\x00\x02\x00isomiso2mp41")...)
+	m4a := append([]byte{0x00, 0x00, 0x00, 0x18}, []byte("ftypM4A \x00\x00\x02\x00M4A isom")...)
+	ogg := append([]byte("OggS\x00\x02"), make([]byte, 32)...)
+	webm := append([]byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x86, 0x81, 0x01}, []byte("webm")...)
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		size     int64
+		wantErr  bool
+	}{
+		{name: "tagged mp3", filename: "voice.mp3", data: id3},
+		{name: "untagged mp3", filename: "voice.mp3", data: frameSync},
+		{name: "m4a", filename: "voice.m4a", data: m4a},
+		{name: "ogg", filename: "voice.ogg", data: ogg},
+		{name: "mp4", filename: "video.mp4", data: mp4},
+		{name: "webm", filename: "video.webm", data: webm},
+		{name: "uppercase extension", filename: "VIDEO.MP4", data: mp4},
+		{name: "text renamed to mp3", filename: "voice.mp3", data: []byte("hello world"), wantErr: true},
+		{name: "png renamed to mp4", filename: "video.mp4", data: []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}, wantErr: true},
+		{name: "webm bytes as ogg", filename: "voice.ogg", data: webm, wantErr: true},
+		{name: "over the media limit", filename: "video.mp4", data: mp4, size: 2*1024*1024 + 1, wantErr: true},
+		{name: "unsupported media type", filename: "video.avi", data: mp4, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			size := tc.size
+			if size == 0 {
+				size = int64(len(tc.data))
+			}
+			err := svc.ValidateMediaFile(tc.filename, size, tc.data, 2)
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileServiceValidateUpload_MediaGate(t *testing.T) {
+	ogg := append([]byte("OggS\x00\x02"), make([]byte, 32)...)
+
+	off := NewFileService(config.Config{})
+	if err := off.validateUpload("voice.ogg", ogg); err == nil {
+		t.Fatal("media must be rejected while ATTACHMENT_MEDIA_ENABLED is off")
+	}
+
+	on := NewFileService(config.Config{Attachment: config.AttachmentConfig{MediaEnabled: true, MediaMaxFileMB: 20}})
+	if err := on.validateUpload("voice.ogg", ogg); err != nil {
+		t.Fatalf("expected media to be accepted, got %v", err)
+	}
+	if err := on.validateUpload("note.txt", []byte("hello world")); err != nil {
+		t.Fatalf("non-media files must still pass the regular rules, got %v", err)
+	}
+}
+
 func TestValidateContent(t *testing.T) {
 	svc := ValidationService{}
 
@@ -169,6 +264,24 @@ func TestValidateContent(t *testing.T) {
 	}
 }
 
+func TestSubjectSanitizedAndValidated(t *testing.T) {
+	svc := ValidationService{}
+	if got := svc.SanitizeSubject("  A letter\r\nBcc: someone@example.com\tfrom Jane  "); got != "A letter Bcc: someone@example.com from Jane" {
+		t.Fatalf("SanitizeSubject = %q, want it folded onto one line", got)
+	}
+	if err := svc.ValidateSubject("A letter from Jane"); err != nil {
+		t.Fatalf("expected a valid subject, got %v", err)
+	}
+	if err := svc.ValidateSubject(strings.Repeat("é", MaxSubjectLength)); err != nil {
+		t.Fatalf("the limit counts characters, not bytes: %v", err)
+	}
+	for _, bad := range []string{strings.Repeat("a", MaxSubjectLength+1), "bell\a"} {
+		if err := svc.ValidateSubject(bad); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
 func TestSanitizeContent(t *testing.T) {
 	svc := ValidationService{}
 	in := "<script>alert('x')</script>"
@@ -183,7 +296,7 @@ func TestSanitizeContent(t *testing.T) {
 
 func TestValidateEmailReturnsAPIError(t *testing.T) {
 	svc := ValidationService{}
-	err := svc.ValidateEmail("invalid")
+	err := svc.ValidateEmail("email", "invalid")
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -195,3 +308,32 @@ func TestValidateEmailReturnsAPIError(t *testing.T) {
 		t.Fatalf("expected status 400, got %d", apiErr.Status)
 	}
 }
+
+func TestValidationErrorsNameTheField(t *testing.T) {
+	svc := ValidationService{}
+	cases := []struct {
+		err   error
+		field string
+	}{
+		{svc.ValidateEmail("recipient_email", "invalid"), "recipient_email"},
+		{svc.ValidateEmail("owner_email", ""), "owner_email"},
+		{svc.ValidateEmailListLength(0), "recipient_emails"},
+		{svc.ValidateContent(""), "content"},
+		{svc.ValidateSubject(strings.Repeat("a", MaxSubjectLength+1)), "subject"},
+		{svc.ValidatePassword("new_password", "short"), "new_password"},
+		{svc.ValidatePasswordEntropy("password", "aaaaaaaa", 3), "password"},
+		{svc.ValidateTriggerDuration(0), "trigger_duration"},
+		{svc.ValidateRequiredMissedIntervals(-1), "required_missed_intervals"},
+		{svc.ValidateCreationReminders([]int{0}), "creation_reminders"},
+		{svc.ValidateDeliveryWindow(&models.DeliveryWindow{StartHour: 24}), "delivery_window"},
+	}
+	for _, tc := range cases {
+		var apiErr *APIError
+		if !errors.As(tc.err, &apiErr) {
+			t.Fatalf("expected *APIError for %s, got %v", tc.field, tc.err)
+		}
+		if apiErr.Field != tc.field {
+			t.Fatalf("field = %q, want %q", apiErr.Field, tc.field)
+		}
+	}
+}
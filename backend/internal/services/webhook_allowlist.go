@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+// WebhookAllowlistService manages the stored webhook host allowlist. The
+// WEBHOOK_ALLOWLIST_HOSTS value from the environment stays an immutable
+// baseline; the stored list can only narrow it.
+type WebhookAllowlistService struct {
+	cfg config.Config
+}
+
+func NewWebhookAllowlistService(cfg config.Config) WebhookAllowlistService {
+	return WebhookAllowlistService{cfg: cfg}
+}
+
+func (s WebhookAllowlistService) Get(userID string) (models.WebhookAllowlist, error) {
+	app, err := ApplicationSettingsService{}.Get()
+	if err != nil {
+		return models.WebhookAllowlist{}, err
+	}
+	return models.WebhookAllowlist{
+		Baseline: strings.TrimSpace(s.cfg.Webhook.AllowlistHosts),
+		Hosts:    app.WebhookAllowlistHosts,
+		CanEdit:  IsFirstUser(userID),
+	}, nil
+}
+
+// Set replaces the stored allowlist, effective immediately for new and
+// existing webhooks. Only the primary administrator may change it; an empty
+// value leaves just the baseline in force.
+func (s WebhookAllowlistService) Set(actorUserID, hosts string) (models.WebhookAllowlist, error) {
+	if !IsFirstUser(actorUserID) {
+		return models.WebhookAllowlist{}, NewAPIError(403, CodeForbidden, "Only the primary administrator can change the webhook allowlist.", nil)
+	}
+	normalized, err := normalizeWebhookAllowlist(hosts, s.cfg.Webhook.AllowlistHosts)
+	if err != nil {
+		return models.WebhookAllowlist{}, err
+	}
+	if err := saveApplicationSetting("webhook_allowlist_hosts", normalized); err != nil {
+		return models.WebhookAllowlist{}, err
+	}
+	return s.Get(actorUserID)
+}
+
+// normalizeWebhookAllowlist validates a comma-separated list of hosts and
+// ".suffix" entries. Every entry must already be permitted by baseline so an
+// administrator cannot be misled into thinking a host was opened up.
+func normalizeWebhookAllowlist(value, baseline string) (string, error) {
+	var out []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		host := strings.TrimPrefix(entry, ".")
+		if host == "" || strings.ContainsAny(host, "/:@*? ") {
+			return "", BadRequest("Invalid webhook allowlist entry: "+entry, nil)
+		}
+		if err := enforceWebhookAllowlist(entry, baseline); err != nil {
+			return "", BadRequest("Webhook allowlist entry is not permitted by WEBHOOK_ALLOWLIST_HOSTS: "+entry, nil)
+		}
+		out = append(out, entry)
+	}
+	return strings.Join(out, ","), nil
+}
+
+// enforceEffectiveWebhookAllowlist requires hostname to pass both the
+// environment baseline and the stored list, read live from settings.
+func enforceEffectiveWebhookAllowlist(hostname, baseline string) error {
+	if err := enforceWebhookAllowlist(hostname, baseline); err != nil {
+		return err
+	}
+	app, err := ApplicationSettingsService{}.Get()
+	if err != nil {
+		return err
+	}
+	return enforceWebhookAllowlist(hostname, app.WebhookAllowlistHosts)
+}
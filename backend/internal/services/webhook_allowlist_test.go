@@ -0,0 +1,69 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestNormalizeWebhookAllowlist(t *testing.T) {
+	got, err := normalizeWebhookAllowlist(" Hooks.Example.com , .svc.example.com ,, ", ".example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "hooks.example.com,.svc.example.com"; got != want {
+		t.Fatalf("normalizeWebhookAllowlist = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"other.org", ".com", "https://hooks.example.com", "hooks.example.com:8443", "*.example.com"} {
+		if _, err := normalizeWebhookAllowlist(bad, ".example.com"); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestEnforceEffectiveWebhookAllowlist_StoredListOnlyNarrows(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.Create(&models.ApplicationSettings{ID: applicationSettingsSingletonID, WebhookAllowlistHosts: "hooks.example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enforceEffectiveWebhookAllowlist("hooks.example.com", ".example.com"); err != nil {
+		t.Fatalf("expected host on both lists to pass: %v", err)
+	}
+	if err := enforceEffectiveWebhookAllowlist("api.example.com", ".example.com"); err == nil {
+		t.Fatal("expected host outside the stored list to be rejected")
+	}
+
+	if err := db.Model(&models.ApplicationSettings{}).Where("id = ?", applicationSettingsSingletonID).
+		Update("webhook_allowlist_hosts", "other.org").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := enforceEffectiveWebhookAllowlist("other.org", ".example.com"); err == nil {
+		t.Fatal("stored list must not loosen the baseline")
+	}
+}
+
+func TestSendTriggerWebhooks_RechecksLiveAllowlist(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.Create(&models.ApplicationSettings{ID: applicationSettingsSingletonID, WebhookAllowlistHosts: "hooks.example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	svc := NewWebhookService(config.Config{})
+	if err := svc.SendTriggerWebhooks([]models.Webhook{{URL: srv.URL}}, models.Message{ID: "m1"}); err == nil {
+		t.Fatal("expected delivery to a host outside the stored allowlist to fail")
+	}
+	if called {
+		t.Fatal("webhook must not be sent to a host outside the allowlist")
+	}
+}
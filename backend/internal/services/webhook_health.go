@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxWebhookErrorLength bounds the stored LastError so a verbose upstream
+// failure cannot bloat the row.
+const maxWebhookErrorLength = 500
+
+// recordDelivery updates hook's delivery health after an attempt. Once
+// WEBHOOK_MAX_CONSECUTIVE_FAILURES deliveries in a row have failed, the
+// webhook is disabled and the owner is emailed; it stays off until they
+// re-enable it or a test delivery succeeds. Unsaved webhooks (ID 0) are not
+// tracked.
+func (s WebhookService) recordDelivery(hook models.Webhook, deliveryErr error, now time.Time) {
+	if hook.ID == 0 {
+		return
+	}
+	scope := database.ForTenant(hook.UserID).Model(&models.Webhook{}).Where("id = ?", hook.ID)
+	if deliveryErr == nil {
+		if err := scope.UpdateColumns(map[string]any{
+			"consecutive_failures": 0,
+			"last_success_at":      now,
+			"last_error":           "",
+		}).Error; err != nil {
+			slog.Error("Failed to record webhook delivery", "error", err, "webhook_id", hook.ID)
+		}
+		return
+	}
+
+	lastError := deliveryErr.Error()
+	if len(lastError) > maxWebhookErrorLength {
+		lastError = lastError[:maxWebhookErrorLength]
+	}
+	if err := scope.UpdateColumns(map[string]any{
+		"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+		"last_failure_at":      now,
+		"last_error":           lastError,
+	}).Error; err != nil {
+		slog.Error("Failed to record webhook failure", "error", err, "webhook_id", hook.ID)
+		return
+	}
+
+	limit := s.cfg.Webhook.MaxConsecutiveFailures
+	if limit <= 0 {
+		return
+	}
+	// The enabled condition makes the disable happen, and the alert go out,
+	// exactly once even if deliveries overlap.
+	result := database.ForTenant(hook.UserID).Model(&models.Webhook{}).
+		Where("id = ? AND enabled = ? AND consecutive_failures >= ?", hook.ID, true, limit).
+		UpdateColumns(map[string]any{"enabled": false, "auto_disabled_at": now})
+	if result.Error != nil {
+		slog.Error("Failed to disable failing webhook", "error", result.Error, "webhook_id", hook.ID)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	slog.Warn("Webhook disabled after repeated failures", "webhook_id", hook.ID, "failures", limit)
+	s.alertDisabled(hook, limit, lastError, now)
+}
+
+// alertDisabled emails the owner that hook was switched off.
+func (s WebhookService) alertDisabled(hook models.Webhook, failures int, lastError string, now time.Time) {
+	settings, err := NewSettingsService(s.cfg).Get(hook.UserID)
+	if err != nil {
+		slog.Error("Failed to load settings for webhook alert", "error", err, "user_id", hook.UserID)
+		return
+	}
+	if settings.OwnerEmail == "" || settings.SMTPHost == "" {
+		return
+	}
+	send := s.send
+	if send == nil {
+		send = NewEmailService(s.cfg).SendPlain
+	}
+
+	subject := "Aeterna webhook disabled"
+	body := fmt.Sprintf("Your webhook %s failed %d times in a row and has been disabled, so it will not be called when a switch triggers.\n\n"+
+		"Last error: %s\n"+
+		"Time: %s\n\n"+
+		"Once the endpoint is fixed, re-enable the webhook in Aeterna or send it a test delivery.",
+		hook.URL, failures, lastError, FormatOwnerTime(settings, now))
+	if err := send(settings, []string{settings.OwnerEmail}, subject, AppendEmailFooter(settings, body)); err != nil {
+		slog.Error("Failed to send webhook alert", "error", err, "user_id", hook.UserID)
+	}
+}
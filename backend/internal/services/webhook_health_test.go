@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestWebhookAutoDisablesAfterConsecutiveFailures(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.AutoMigrate(&models.Webhook{}, &models.Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Settings{UserID: "u1", OwnerEmail: "owner@example.com", SMTPHost: "smtp.example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	status := http.StatusInternalServerError
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	hook := models.Webhook{UserID: "u1", URL: srv.URL, Enabled: true}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var alerts []string
+	cfg := config.Config{Webhook: config.WebhookConfig{MaxConsecutiveFailures: 3}}
+	svc := NewWebhookService(cfg)
+	svc.send = func(_ models.Settings, recipients []string, _, body string) error {
+		if len(recipients) != 1 || recipients[0] != "owner@example.com" {
+			t.Fatalf("unexpected recipients %v", recipients)
+		}
+		alerts = append(alerts, body)
+		return nil
+	}
+	msg := models.Message{ID: "m1", RecipientEmail: "a@example.com", Status: models.StatusTriggered}
+	reload := func() models.Webhook {
+		t.Helper()
+		var got models.Webhook
+		if err := db.First(&got, hook.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := svc.SendTriggerWebhooks([]models.Webhook{hook}, msg); err == nil {
+			t.Fatal("expected the failing webhook to return an error")
+		}
+	}
+	if got := reload(); !got.Enabled || got.ConsecutiveFailures != 2 || got.LastFailureAt == nil || !strings.Contains(got.LastError, "500") {
+		t.Fatalf("unexpected health after two failures: %+v", got)
+	}
+
+	// A success in between resets the streak.
+	status = http.StatusNoContent
+	if err := svc.SendTriggerWebhooks([]models.Webhook{hook}, msg); err != nil {
+		t.Fatalf("SendTriggerWebhooks: %v", err)
+	}
+	if got := reload(); got.ConsecutiveFailures != 0 || got.LastSuccessAt == nil || got.LastError != "" {
+		t.Fatalf("success should reset the failure streak: %+v", got)
+	}
+
+	status = http.StatusBadGateway
+	for i := 0; i < 4; i++ {
+		_ = svc.SendTriggerWebhooks([]models.Webhook{hook}, msg)
+	}
+	got := reload()
+	if got.Enabled || got.AutoDisabledAt == nil {
+		t.Fatalf("expected the webhook to be disabled after 3 failures: %+v", got)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("expected exactly one owner alert, got %d", len(alerts))
+	}
+	if !strings.Contains(alerts[0], srv.URL) || !strings.Contains(alerts[0], "3 times in a row") {
+		t.Fatalf("alert body missing URL or failure count: %q", alerts[0])
+	}
+
+	// A failed test leaves it disabled; a successful one turns it back on.
+	store := NewWebhookStore(cfg)
+	id := fmt.Sprint(got.ID)
+	var apiErr *APIError
+	if _, err := store.Test(context.Background(), "u1", id); !errors.As(err, &apiErr) || apiErr.Code != CodeWebhookTestFailed {
+		t.Fatalf("expected webhook_test_failed, got %v", err)
+	}
+	if reload().Enabled {
+		t.Fatal("a failed test must not re-enable the webhook")
+	}
+	status = http.StatusOK
+	tested, err := store.Test(context.Background(), "u1", id)
+	if err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	if !tested.Enabled || tested.AutoDisabledAt != nil || tested.ConsecutiveFailures != 0 {
+		t.Fatalf("a successful test should re-enable the webhook: %+v", tested)
+	}
+}
+
+func TestWebhookTestKeepsManuallyDisabledWebhookOff(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.AutoMigrate(&models.Webhook{}); err != nil {
+		t.Fatal(err)
+	}
+	var event string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event = r.Header.Get("X-Aeterna-Event")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	hook := models.Webhook{UserID: "u1", URL: srv.URL, Enabled: true}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&hook).Update("enabled", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tested, err := NewWebhookStore(config.Config{}).Test(context.Background(), "u1", fmt.Sprint(hook.ID))
+	if err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	if event != "webhook.test" {
+		t.Fatalf("X-Aeterna-Event = %q, want webhook.test", event)
+	}
+	if tested.Enabled {
+		t.Fatal("a webhook the owner disabled must stay disabled after a test")
+	}
+}
+
+func TestWebhookConfigErrorsAreNotDeliveryFailures(t *testing.T) {
+	db := setupTestDB(t)
+	initTestKeyManager(t)
+	if err := db.AutoMigrate(&models.Webhook{}); err != nil {
+		t.Fatal(err)
+	}
+	hook := models.Webhook{UserID: "u1", URL: "https://hooks.example.com/aeterna", Enabled: true}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	msg := models.Message{ID: "m1", RecipientEmail: "a@example.com", Status: models.StatusTriggered}
+	for name, section := range map[string]config.WebhookConfig{
+		"unloadable TLS file": {CAFile: "/nonexistent/ca.pem"},
+		"narrowed allowlist":  {AllowlistHosts: "other.example.com"},
+	} {
+		section.MaxConsecutiveFailures = 1
+		if err := NewWebhookService(config.Config{Webhook: section}).SendTriggerWebhooks([]models.Webhook{hook}, msg); err == nil {
+			t.Fatalf("%s: expected the configuration error to be returned", name)
+		}
+		var got models.Webhook
+		if err := db.First(&got, hook.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !got.Enabled || got.ConsecutiveFailures != 0 || got.LastFailureAt != nil {
+			t.Fatalf("%s must not count against the webhook: %+v", name, got)
+		}
+	}
+}
@@ -2,21 +2,54 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/alpyxn/aeterna/backend/internal/config"
 	"github.com/alpyxn/aeterna/backend/internal/models"
 )
 
-type WebhookService struct{}
+type WebhookService struct {
+	cfg config.Config
+	// send delivers the auto-disable alert; nil means EmailService.SendPlain.
+	send func(settings models.Settings, recipients []string, subject, body string) error
+}
+
+func NewWebhookService(cfg config.Config) WebhookService {
+	return WebhookService{cfg: cfg}
+}
 
+// WebhookSchemaVersion identifies the shape of triggerPayload. Bump it whenever
+// fields are added, renamed or removed so consumers can branch on it.
+const WebhookSchemaVersion = "2"
+
+// webhookOwnerReference identifies the account behind a delivery without
+// revealing its email: a keyed hash of the user ID, so it is stable for as
+// long as the encryption key is and cannot be recomputed by the receiver.
+func webhookOwnerReference(userID string) (string, error) {
+	ref, err := cryptoService.KeyedHash([]byte("webhook-owner:" + userID))
+	if err != nil {
+		return "", Internal("Failed to derive owner reference", err)
+	}
+	return ref, nil
+}
+
+// triggerPayload is the switch.triggered body. IsTest is false on every real
+// event and true only on webhook.test; consumers that act irreversibly must
+// ignore payloads where it is true.
 type triggerPayload struct {
+	SchemaVersion   string    `json:"schema_version"`
 	Event           string    `json:"event"`
+	IsTest          bool      `json:"is_test"`
+	OwnerReference  string    `json:"owner_reference"`
 	MessageID       string    `json:"message_id"`
 	RecipientEmail  string    `json:"recipient_email"`
 	RecipientEmails []string  `json:"recipient_emails"`
@@ -34,15 +67,21 @@ func (s WebhookService) SendTriggerWebhooks(webhooks []models.Webhook, msg model
 
 	content := msg.Content
 	if msg.Content != "" {
-		decrypted, err := cryptoService.Decrypt(msg.Content)
+		decrypted, err := cryptoService.DecryptWithContext(msg.Content, MessageContentContext(msg.ID))
 		if err != nil {
 			return err
 		}
 		content = decrypted
 	}
+	ownerRef, err := webhookOwnerReference(msg.UserID)
+	if err != nil {
+		return err
+	}
 
 	payload := triggerPayload{
+		SchemaVersion:   WebhookSchemaVersion,
 		Event:           "switch.triggered",
+		OwnerReference:  ownerRef,
 		MessageID:       msg.ID,
 		RecipientEmail:  msg.RecipientEmail,
 		RecipientEmails: ParseRecipientEmails(msg.RecipientEmail),
@@ -53,60 +92,221 @@ func (s WebhookService) SendTriggerWebhooks(webhooks []models.Webhook, msg model
 		CreatedAt:       msg.CreatedAt,
 	}
 
-	body, err := json.Marshal(payload)
+	body, err := canonicalJSON(payload)
+	if err != nil {
+		return Internal("Failed to encode webhook payload", err)
+	}
+
+	return s.post(webhooks, payload.Event, func(hook models.Webhook) ([]byte, error) {
+		if hook.PayloadTemplate == "" {
+			return body, nil
+		}
+		return renderWebhookTemplate(hook.PayloadTemplate, payload)
+	})
+}
+
+type reminderPayload struct {
+	SchemaVersion  string    `json:"schema_version"`
+	Event          string    `json:"event"`
+	IsTest         bool      `json:"is_test"`
+	OwnerReference string    `json:"owner_reference"`
+	MessageID      string    `json:"message_id"`
+	RelativeTo     string    `json:"relative_to"`
+	MinutesBefore  int       `json:"minutes_before"`
+	MinutesAfter   int       `json:"minutes_after"`
+	Final          bool      `json:"final"`
+	TriggerAt      time.Time `json:"trigger_at"`
+	LastSeen       time.Time `json:"last_seen"`
+}
+
+// SendReminderWebhooks notifies webhooks that a check-in is due before msg
+// triggers. The message content is never included.
+func (s WebhookService) SendReminderWebhooks(webhooks []models.Webhook, msg models.Message, reminder models.MessageReminder, final bool) error {
+	if len(webhooks) == 0 {
+		return nil
+	}
+	ownerRef, err := webhookOwnerReference(msg.UserID)
+	if err != nil {
+		return err
+	}
+	payload := reminderPayload{
+		SchemaVersion:  WebhookSchemaVersion,
+		Event:          "switch.reminder",
+		OwnerReference: ownerRef,
+		MessageID:      msg.ID,
+		RelativeTo:     reminder.RelativeTo,
+		MinutesBefore:  reminder.MinutesBefore,
+		MinutesAfter:   reminder.MinutesAfter,
+		Final:          final,
+		TriggerAt:      msg.TriggerAt(),
+		LastSeen:       msg.LastSeen,
+	}
+	body, err := canonicalJSON(payload)
+	if err != nil {
+		return Internal("Failed to encode webhook payload", err)
+	}
+	return s.post(webhooks, payload.Event, func(models.Webhook) ([]byte, error) { return body, nil })
+}
+
+// canonicalJSON encodes v with object keys sorted bytewise at every level,
+// no insignificant whitespace and no HTML escaping, so the signed bytes do not
+// depend on struct field order and a consumer that re-serializes the payload
+// the same way reproduces them exactly. See docs/webhooks.md.
+func canonicalJSON(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(generic); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+type testPayload struct {
+	SchemaVersion  string    `json:"schema_version"`
+	Event          string    `json:"event"`
+	IsTest         bool      `json:"is_test"`
+	OwnerReference string    `json:"owner_reference"`
+	WebhookID      uint      `json:"webhook_id"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// SendTestWebhook delivers a webhook.test event to hook alone, giving up when
+// ctx ends. The result is not counted towards its delivery health; the caller
+// decides what a successful test means.
+func (s WebhookService) SendTestWebhook(ctx context.Context, hook models.Webhook) error {
+	ownerRef, err := webhookOwnerReference(hook.UserID)
+	if err != nil {
+		return err
+	}
+	payload := testPayload{
+		SchemaVersion:  WebhookSchemaVersion,
+		Event:          "webhook.test",
+		IsTest:         true,
+		OwnerReference: ownerRef,
+		WebhookID:      hook.ID,
+		SentAt:         time.Now().UTC(),
+	}
+	body, err := canonicalJSON(payload)
 	if err != nil {
 		return Internal("Failed to encode webhook payload", err)
 	}
+	client, err := s.newClient()
+	if err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, hook, payload.Event, body)
+	if err != nil {
+		return err
+	}
+	return deliver(client, req)
+}
 
+func (s WebhookService) newClient() (*http.Client, error) {
+	tlsConfig, err := webhookTLSConfig(s.cfg.Webhook)
+	if err != nil {
+		return nil, Internal("Invalid webhook TLS configuration", err)
+	}
 	client := &http.Client{Timeout: 6 * time.Second}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+	return client, nil
+}
+
+// post delivers the body built for each webhook and records each outcome in
+// its delivery health. It returns the last failure, if any. Only requests
+// that were sent count towards delivery health: a configuration error, such
+// as an unloadable TLS file, a payload template that does not render or a
+// host the allowlist no longer permits, fails before anything is sent.
+func (s WebhookService) post(webhooks []models.Webhook, event string, bodyFor func(models.Webhook) ([]byte, error)) error {
+	client, err := s.newClient()
+	if err != nil {
+		return err
+	}
 	var lastErr error
 	for _, hook := range webhooks {
-		if hook.URL == "" {
-			lastErr = BadRequest("Webhook URL is required", nil)
-			continue
+		body, err := bodyFor(hook)
+		var req *http.Request
+		if err == nil {
+			req, err = s.newRequest(context.Background(), hook, event, body)
 		}
-		secret := ""
-		if hook.Secret != "" {
-			decrypted, err := cryptoService.DecryptIfNeeded(hook.Secret)
-			if err != nil {
-				lastErr = err
-				continue
-			}
-			secret = decrypted
+		if err == nil {
+			err = deliver(client, req)
+			s.recordDelivery(hook, err, time.Now().UTC())
 		}
-
-		req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewBuffer(body))
 		if err != nil {
-			lastErr = Internal("Failed to create webhook request", err)
-			continue
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-Aeterna-Event", payload.Event)
-
-		if secret != "" {
-			mac := hmac.New(sha256.New, []byte(secret))
-			mac.Write(body)
-			signature := hex.EncodeToString(mac.Sum(nil))
-			req.Header.Set("X-Aeterna-Signature", signature)
+			lastErr = err
 		}
+	}
+	return lastErr
+}
 
-		resp, err := client.Do(req)
+// newRequest builds the POST of body to one webhook, signing it with the
+// webhook secret: X-Aeterna-Signature is the hex HMAC-SHA256 of exactly the
+// bytes sent.
+func (s WebhookService) newRequest(ctx context.Context, hook models.Webhook, event string, body []byte) (*http.Request, error) {
+	if hook.URL == "" {
+		return nil, BadRequest("Webhook URL is required", nil)
+	}
+	// Re-check the live allowlist: it may have been narrowed since the
+	// webhook was saved.
+	parsed, err := url.Parse(hook.URL)
+	if err != nil {
+		return nil, BadRequest("Invalid webhook URL", err)
+	}
+	if err := enforceEffectiveWebhookAllowlist(strings.ToLower(parsed.Hostname()), s.cfg.Webhook.AllowlistHosts); err != nil {
+		return nil, err
+	}
+	secret := ""
+	if hook.Secret != "" {
+		decrypted, err := cryptoService.DecryptIfNeededWithContext(hook.Secret, webhookSecretContext(hook.ID))
 		if err != nil {
-			lastErr = Internal("Webhook request failed", err)
-			continue
+			return nil, err
 		}
-		resp.Body.Close()
+		secret = decrypted
+	}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			lastErr = Internal("Webhook returned non-2xx status", errors.New(resp.Status))
-			continue
-		}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, Internal("Failed to create webhook request", err)
 	}
 
-	if lastErr != nil {
-		return lastErr
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Aeterna-Event", event)
+	req.Header.Set("X-Aeterna-Schema-Version", WebhookSchemaVersion)
+
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		signature := hex.EncodeToString(mac.Sum(nil))
+		req.Header.Set("X-Aeterna-Signature", signature)
 	}
+	return req, nil
+}
 
+// deliver sends req and checks for a 2xx response.
+func deliver(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return Internal("Webhook request failed", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Internal("Webhook returned non-2xx status", errors.New(resp.Status))
+	}
 	return nil
 }
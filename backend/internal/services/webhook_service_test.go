@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+func TestSendTriggerWebhooks_IncludesSchemaVersion(t *testing.T) {
+	setupTestDB(t)
+	initTestKeyManager(t)
+	var header string
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Aeterna-Schema-Version")
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	msg := models.Message{ID: "m1", RecipientEmail: "a@example.com", Status: models.StatusTriggered}
+	if err := (WebhookService{}).SendTriggerWebhooks([]models.Webhook{{URL: srv.URL}}, msg); err != nil {
+		t.Fatalf("SendTriggerWebhooks: %v", err)
+	}
+	if header != WebhookSchemaVersion {
+		t.Fatalf("X-Aeterna-Schema-Version = %q, want %q", header, WebhookSchemaVersion)
+	}
+	if payload["schema_version"] != WebhookSchemaVersion {
+		t.Fatalf("schema_version = %v, want %q", payload["schema_version"], WebhookSchemaVersion)
+	}
+}
+
+func TestSendTriggerWebhooks_SignsCanonicalBody(t *testing.T) {
+	setupTestDB(t)
+	initTestKeyManager(t)
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Aeterna-Signature")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	msg := models.Message{ID: "m1", RecipientEmail: "a@example.com, b@example.com", Status: models.StatusTriggered}
+	if err := (WebhookService{}).SendTriggerWebhooks([]models.Webhook{{URL: srv.URL, Secret: "s3cret"}}, msg); err != nil {
+		t.Fatalf("SendTriggerWebhooks: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Fatalf("X-Aeterna-Signature = %q, want HMAC of the body %q", signature, want)
+	}
+
+	// A consumer that decodes the body and re-serializes it canonically must
+	// get back exactly the signed bytes.
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	rebuilt, err := canonicalJSON(decoded)
+	if err != nil {
+		t.Fatalf("canonicalJSON: %v", err)
+	}
+	if !bytes.Equal(rebuilt, body) {
+		t.Fatalf("body is not canonical:\n got %s\nwant %s", body, rebuilt)
+	}
+	if !bytes.HasPrefix(body, []byte(`{"content":"","created_at":`)) {
+		t.Fatalf("expected keys in sorted order, got %s", body)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	got, err := canonicalJSON(map[string]any{"b": []any{map[string]any{"z": 1, "a": "<&>"}}, "a": 1.5})
+	if err != nil {
+		t.Fatalf("canonicalJSON: %v", err)
+	}
+	if want := `{"a":1.5,"b":[{"a":"<&>","z":1}]}`; string(got) != want {
+		t.Fatalf("canonicalJSON = %s, want %s", got, want)
+	}
+}
+
+func TestSendTriggerWebhooks_RendersPayloadTemplate(t *testing.T) {
+	setupTestDB(t)
+	initTestKeyManager(t)
+	bodies := map[string][]byte{}
+	signatures := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodies[r.URL.Path], _ = io.ReadAll(r.Body)
+		signatures[r.URL.Path] = r.Header.Get("X-Aeterna-Signature")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tmpl := `{"text":{{json (printf "Switch %s fired" .MessageID)}},"to":{{json .RecipientEmails}}}`
+	hooks := []models.Webhook{
+		{URL: srv.URL + "/templated", Secret: "s3cret", PayloadTemplate: tmpl},
+		{URL: srv.URL + "/default"},
+	}
+	msg := models.Message{ID: "m1", RecipientEmail: "a@example.com, b@example.com", Status: models.StatusTriggered}
+	if err := (WebhookService{}).SendTriggerWebhooks(hooks, msg); err != nil {
+		t.Fatalf("SendTriggerWebhooks: %v", err)
+	}
+
+	want := `{"text":"Switch m1 fired","to":["a@example.com","b@example.com"]}`
+	if got := string(bodies["/templated"]); got != want {
+		t.Fatalf("templated body = %s, want %s", got, want)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(want))
+	if signatures["/templated"] != hex.EncodeToString(mac.Sum(nil)) {
+		t.Fatal("signature does not cover the rendered body")
+	}
+	if !bytes.HasPrefix(bodies["/default"], []byte(`{"content":`)) {
+		t.Fatalf("webhook without a template should get the default payload, got %s", bodies["/default"])
+	}
+}
+
+func TestValidateWebhookTemplate(t *testing.T) {
+	for _, ok := range []string{"", `{"id":{{json .MessageID}}}`, `{{range .RecipientEmails}}{{.}} {{end}}`} {
+		if err := validateWebhookTemplate(ok); err != nil {
+			t.Fatalf("expected %q to be valid: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{`{{.MessageID`, `{{.NoSuchField}}`, `{{undefinedFunc .Content}}`} {
+		if err := validateWebhookTemplate(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestWebhookPayloadsFlagTestsAndReferenceOwner(t *testing.T) {
+	setupTestDB(t)
+	initTestKeyManager(t)
+	var payloads []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		payloads = append(payloads, payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	hook := models.Webhook{UserID: "u1", URL: srv.URL}
+	svc := WebhookService{}
+	if err := svc.SendTestWebhook(context.Background(), hook); err != nil {
+		t.Fatalf("SendTestWebhook: %v", err)
+	}
+	msg := models.Message{ID: "m1", UserID: "u1", RecipientEmail: "a@example.com", Status: models.StatusTriggered}
+	if err := svc.SendTriggerWebhooks([]models.Webhook{hook}, msg); err != nil {
+		t.Fatalf("SendTriggerWebhooks: %v", err)
+	}
+	msg.UserID = "u2"
+	if err := svc.SendTriggerWebhooks([]models.Webhook{hook}, msg); err != nil {
+		t.Fatalf("SendTriggerWebhooks: %v", err)
+	}
+
+	test, real, other := payloads[0], payloads[1], payloads[2]
+	if test["is_test"] != true || real["is_test"] != false {
+		t.Fatalf("is_test = %v (test), %v (trigger); want true and false", test["is_test"], real["is_test"])
+	}
+	ref, _ := real["owner_reference"].(string)
+	if len(ref) != 64 || test["owner_reference"] != ref {
+		t.Fatalf("expected the same 64-character reference on both events, got %q and %v", ref, test["owner_reference"])
+	}
+	if other["owner_reference"] == ref {
+		t.Fatal("different accounts must have different owner references")
+	}
+}
+
+func TestSendTestWebhookGivesUpWhenContextEnds(t *testing.T) {
+	setupTestDB(t)
+	initTestKeyManager(t)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := WebhookService{}.SendTestWebhook(ctx, models.Webhook{UserID: "u1", URL: srv.URL})
+	if err == nil {
+		t.Fatal("expected the test delivery to fail once the context ended")
+	}
+}
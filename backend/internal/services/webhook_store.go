@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"net"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alpyxn/aeterna/backend/internal/config"
 	"github.com/alpyxn/aeterna/backend/internal/database"
@@ -50,23 +52,37 @@ func (s WebhookStore) Create(userID string, item models.Webhook) (models.Webhook
 		return models.Webhook{}, err
 	}
 	item.URL = validatedURL
-	item.Secret = strings.TrimSpace(item.Secret)
-	if item.Secret != "" {
-		encrypted, err := cryptoService.EncryptIfNeeded(item.Secret)
-		if err != nil {
-			return models.Webhook{}, err
-		}
-		item.Secret = encrypted
+	if err := validateWebhookTemplate(item.PayloadTemplate); err != nil {
+		return models.Webhook{}, err
 	}
+	secret := strings.TrimSpace(item.Secret)
+	item.Secret = ""
 	item.UserID = userID
-	if err := database.DB.Create(&item).Error; err != nil {
-		return models.Webhook{}, Internal("Failed to create webhook", err)
+	// The secret is bound to the webhook's ID, which the insert assigns.
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&item).Error; err != nil {
+			return Internal("Failed to create webhook", err)
+		}
+		if secret == "" {
+			return nil
+		}
+		encrypted, err := cryptoService.EncryptIfNeededWithContext(secret, webhookSecretContext(item.ID))
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&item).UpdateColumn("secret", encrypted).Error; err != nil {
+			return Internal("Failed to create webhook", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Webhook{}, err
 	}
 	item.Secret = ""
 	return item, nil
 }
 
-func (s WebhookStore) Update(userID, id string, input models.Webhook) (models.Webhook, error) {
+func (s WebhookStore) find(userID, id string) (models.Webhook, error) {
 	parsedID, err := strconv.Atoi(id)
 	if err != nil {
 		return models.Webhook{}, BadRequest("Invalid webhook id", err)
@@ -78,6 +94,14 @@ func (s WebhookStore) Update(userID, id string, input models.Webhook) (models.We
 		}
 		return models.Webhook{}, Internal("Failed to fetch webhook", err)
 	}
+	return existing, nil
+}
+
+func (s WebhookStore) Update(userID, id string, input models.Webhook) (models.Webhook, error) {
+	existing, err := s.find(userID, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
 	input.URL = strings.TrimSpace(input.URL)
 	if input.URL == "" {
 		return models.Webhook{}, BadRequest("Webhook URL is required", nil)
@@ -86,9 +110,12 @@ func (s WebhookStore) Update(userID, id string, input models.Webhook) (models.We
 	if err != nil {
 		return models.Webhook{}, err
 	}
+	if err := validateWebhookTemplate(input.PayloadTemplate); err != nil {
+		return models.Webhook{}, err
+	}
 	secret := strings.TrimSpace(input.Secret)
 	if secret != "" {
-		encrypted, err := cryptoService.EncryptIfNeeded(secret)
+		encrypted, err := cryptoService.EncryptIfNeededWithContext(secret, webhookSecretContext(existing.ID))
 		if err != nil {
 			return models.Webhook{}, err
 		}
@@ -97,9 +124,16 @@ func (s WebhookStore) Update(userID, id string, input models.Webhook) (models.We
 		secret = existing.Secret
 	}
 
+	if input.Enabled && !existing.Enabled {
+		// Turning the webhook back on is the explicit re-enable after an
+		// automatic disable, so the failure streak starts over.
+		existing.ConsecutiveFailures = 0
+		existing.AutoDisabledAt = nil
+	}
 	existing.URL = validatedURL
 	existing.Secret = secret
 	existing.Enabled = input.Enabled
+	existing.PayloadTemplate = input.PayloadTemplate
 
 	if err := database.DB.Save(&existing).Error; err != nil {
 		return models.Webhook{}, Internal("Failed to update webhook", err)
@@ -108,6 +142,32 @@ func (s WebhookStore) Update(userID, id string, input models.Webhook) (models.We
 	return existing, nil
 }
 
+// Test sends a webhook.test delivery, bounded by ctx. If it succeeds and the
+// webhook had been disabled after repeated failures, it is enabled again; a
+// webhook the owner switched off stays off.
+func (s WebhookStore) Test(ctx context.Context, userID, id string) (models.Webhook, error) {
+	existing, err := s.find(userID, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if err := NewWebhookService(s.cfg).SendTestWebhook(ctx, existing); err != nil {
+		return models.Webhook{}, NewAPIError(400, CodeWebhookTestFailed, "Webhook test failed", err)
+	}
+	now := time.Now().UTC()
+	existing.ConsecutiveFailures = 0
+	existing.LastSuccessAt = &now
+	existing.LastError = ""
+	if existing.AutoDisabledAt != nil {
+		existing.Enabled = true
+		existing.AutoDisabledAt = nil
+	}
+	if err := database.DB.Save(&existing).Error; err != nil {
+		return models.Webhook{}, Internal("Failed to update webhook", err)
+	}
+	existing.Secret = ""
+	return existing, nil
+}
+
 func (s WebhookStore) Delete(userID, id string) error {
 	parsedID, err := strconv.Atoi(id)
 	if err != nil {
@@ -163,9 +223,15 @@ func validateWebhookHostname(hostname, rawAllowlist string) error {
 	if hostname == "" {
 		return BadRequest("Invalid webhook URL host", nil)
 	}
-	if err := enforceWebhookAllowlist(hostname, rawAllowlist); err != nil {
+	if err := enforceEffectiveWebhookAllowlist(hostname, rawAllowlist); err != nil {
 		return err
 	}
+	return validatePublicHostname(hostname)
+}
+
+// validatePublicHostname rejects local names and hosts that are, or resolve
+// to, private addresses.
+func validatePublicHostname(hostname string) error {
 	if hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") || strings.HasSuffix(hostname, ".local") {
 		return BadRequest("Webhook URL host is not allowed", nil)
 	}
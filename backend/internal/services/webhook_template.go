@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+	"time"
+)
+
+// maxWebhookTemplateLength bounds a stored payload template.
+const maxWebhookTemplateLength = 16 * 1024
+
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, so strings such as .Content can be
+	// embedded without breaking the document.
+	"json": func(v any) (string, error) {
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	},
+}
+
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// validateWebhookTemplate checks that a payload template compiles and renders
+// against sample data, so unknown fields are rejected when the webhook is
+// saved rather than when a switch triggers.
+func validateWebhookTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+	if len(text) > maxWebhookTemplateLength {
+		return BadRequest("Payload template is too long", nil)
+	}
+	tmpl, err := parseWebhookTemplate(text)
+	if err != nil {
+		return BadRequest("Invalid payload template", err)
+	}
+	now := time.Now().UTC()
+	sample := triggerPayload{
+		SchemaVersion:   WebhookSchemaVersion,
+		Event:           "switch.triggered",
+		OwnerReference:  "0000000000000000000000000000000000000000000000000000000000000000",
+		MessageID:       "00000000-0000-0000-0000-000000000000",
+		RecipientEmail:  "recipient@example.com",
+		RecipientEmails: []string{"recipient@example.com"},
+		Content:         "Example content",
+		TriggerDuration: 60,
+		LastSeen:        now,
+		Status:          "triggered",
+		CreatedAt:       now,
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return BadRequest("Invalid payload template", err)
+	}
+	return nil
+}
+
+// renderWebhookTemplate renders payload through a webhook's template. The
+// output is sent, and signed, exactly as rendered.
+func renderWebhookTemplate(text string, payload triggerPayload) ([]byte, error) {
+	tmpl, err := parseWebhookTemplate(text)
+	if err != nil {
+		return nil, BadRequest("Invalid payload template", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, payload); err != nil {
+		return nil, Internal("Failed to render payload template", err)
+	}
+	return buf.Bytes(), nil
+}
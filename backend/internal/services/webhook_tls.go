@@ -0,0 +1,66 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+)
+
+// webhookTLSConfig builds the TLS client settings for webhook delivery from
+// WEBHOOK_CLIENT_CERT_FILE/WEBHOOK_CLIENT_KEY_FILE (mutual TLS),
+// WEBHOOK_CA_FILE and WEBHOOK_TLS_PINS. It returns nil when none is set. The
+// files are read on every call, so rotated certificates are picked up
+// without a restart.
+func webhookTLSConfig(section config.WebhookConfig) (*tls.Config, error) {
+	if section.ClientCertFile == "" && section.CAFile == "" && len(section.TLSPins) == 0 {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if section.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(section.ClientCertFile, section.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load webhook client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if section.CAFile != "" {
+		pem, err := os.ReadFile(section.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read webhook CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("webhook CA file contains no PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(section.TLSPins) > 0 {
+		pins := make(map[string]bool, len(section.TLSPins))
+		for _, pin := range section.TLSPins {
+			pins[pin] = true
+		}
+		// Only verified chains are checked: a server could append any
+		// certificate to what it presents, but not to a chain that verifies.
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					if pins[base64.StdEncoding.EncodeToString(sum[:])] {
+						return nil
+					}
+				}
+			}
+			return errors.New("webhook server certificate does not match WEBHOOK_TLS_PINS")
+		}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func issueTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func spkiPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestWebhookDeliveryWithMutualTLS(t *testing.T) {
+	setupTestDB(t)
+	initTestKeyManager(t)
+	ca := issueTestCert(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "test CA"}, IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}, nil)
+	server := issueTestCert(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "127.0.0.1"}, IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	client := issueTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "aeterna"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	serverPair, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	var clientCN string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverPair}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := writeTestFile(t, "ca.pem", ca.certPEM)
+	certFile := writeTestFile(t, "client.pem", client.certPEM)
+	keyFile := writeTestFile(t, "client.key", client.keyPEM)
+	hook := models.Webhook{URL: srv.URL, Enabled: true}
+
+	cases := []struct {
+		name    string
+		section config.WebhookConfig
+		wantErr bool
+	}{
+		{"system roots do not trust the server", config.WebhookConfig{}, true},
+		{"server demands a client certificate", config.WebhookConfig{CAFile: caFile}, true},
+		{"mutual TLS", config.WebhookConfig{CAFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile}, false},
+		{"matching pin", config.WebhookConfig{CAFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile, TLSPins: []string{spkiPin(ca.cert)}}, false},
+		{"mismatched pin", config.WebhookConfig{CAFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile, TLSPins: []string{spkiPin(client.cert)}}, true},
+		{"unreadable certificate", config.WebhookConfig{CAFile: caFile, ClientCertFile: certFile, ClientKeyFile: caFile}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientCN = ""
+			err := NewWebhookService(config.Config{Webhook: tc.section}).SendTestWebhook(context.Background(), hook)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected delivery to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendTestWebhook: %v", err)
+			}
+			if clientCN != "aeterna" {
+				t.Fatalf("server saw client certificate %q, want aeterna", clientCN)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+)
+
+// reminderChannel delivers a reminder over one of the channels a
+// models.MessageReminder can name.
+type reminderChannel interface {
+	SendReminder(settings models.Settings, msg models.Message, req models.MessageReminder, final bool) error
+}
+
+// errReminderDeferred is returned by a channel that did not send the
+// reminder now but will later, so it must not be marked sent yet.
+var errReminderDeferred = errors.New("reminder deferred")
+
+// ntfySender is the part of services.NtfyService the worker uses.
+type ntfySender interface {
+	Send(topicURL, title, message, priority, clickURL string) error
+}
+
+// defaultReminderChannels registers the channels other than email.
+func defaultReminderChannels(w *Worker) map[string]reminderChannel {
+	return map[string]reminderChannel{
+		models.ReminderChannelNtfy:    ntfyReminderChannel{w},
+		models.ReminderChannelWebhook: webhookReminderChannel{w},
+	}
+}
+
+// reminderChannel returns the channel registered for name. Email, the
+// default, is bound to the tick's mailer and digests.
+func (w *Worker) reminderChannel(name string, mail mailer, digests *reminderDigests) reminderChannel {
+	if channel, ok := w.reminderChannels[name]; ok {
+		return channel
+	}
+	return emailReminderChannel{worker: w, mail: mail, digests: digests}
+}
+
+type emailReminderChannel struct {
+	worker  *Worker
+	mail    mailer
+	digests *reminderDigests
+}
+
+// SendReminder emails the owner, or queues the reminder for the owner's
+// digest when they opted into one.
+func (c emailReminderChannel) SendReminder(settings models.Settings, msg models.Message, req models.MessageReminder, final bool) error {
+	if settings.OwnerEmail == "" || settings.SMTPHost == "" {
+		return errReminderDeferred
+	}
+	if settings.ReminderDigest && !req.FromCreation() {
+		c.digests.add(settings, digestEntry{msg: msg, req: req, final: final})
+		return errReminderDeferred
+	}
+	return c.worker.sendReminderEmail(c.mail, settings, msg, req, final)
+}
+
+type ntfyReminderChannel struct {
+	worker *Worker
+}
+
+// SendReminder pushes the reminder to the owner's ntfy topic. Topics are
+// often readable by anyone who knows their name, so the notification links
+// to the dashboard, which needs a sign-in, and never to the quick-heartbeat
+// token.
+func (c ntfyReminderChannel) SendReminder(settings models.Settings, msg models.Message, _ models.MessageReminder, final bool) error {
+	if settings.NtfyURL == "" {
+		return fmt.Errorf("no ntfy URL configured")
+	}
+	title, priority := "Aeterna check-in required", services.NtfyPriorityHigh
+	if final {
+		title, priority = "Final Aeterna check-in required", services.NtfyPriorityUrgent
+	}
+	body := fmt.Sprintf("A scheduled message will be sent in %s unless you check in. Sign in to Aeterna to check in.", reminderRemaining(msg))
+	return c.worker.ntfy.Send(settings.NtfyURL, title, body, priority, c.worker.cfg.Worker.BaseURL)
+}
+
+type webhookReminderChannel struct {
+	worker *Worker
+}
+
+// SendReminder posts the reminder to the owner's enabled webhooks.
+func (c webhookReminderChannel) SendReminder(_ models.Settings, msg models.Message, req models.MessageReminder, final bool) error {
+	webhooks, err := c.worker.webhooks.ListEnabledForUser(msg.UserID)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return fmt.Errorf("no enabled webhooks")
+	}
+	return c.worker.webhook.SendReminderWebhooks(webhooks, msg, req, final)
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// Simulate reports what the next tick would do with one of userID's
+// switches, following the same checks as checkReminders and checkHeartbeats
+// in the same order. Nothing is sent and nothing is written, so escalation
+// reminders that have not been synced yet are not listed.
+func (w *Worker) Simulate(userID, messageID string) (ports.SwitchSimulation, error) {
+	var msg models.Message
+	if err := database.ForTenant(userID).First(&msg, "id = ?", messageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ports.SwitchSimulation{}, services.NotFound("Message not found", err)
+		}
+		return ports.SwitchSimulation{}, services.Internal("Failed to load message", err)
+	}
+
+	now := time.Now().UTC()
+	sim := ports.SwitchSimulation{
+		MessageID: msg.ID,
+		Status:    msg.Status,
+		Now:       now,
+		Deadline:  msg.TriggerAt().UTC(),
+		Reminders: []ports.SimulatedReminder{},
+	}
+
+	settings, err := w.settings.Get(msg.UserID)
+	if err != nil {
+		return ports.SwitchSimulation{}, err
+	}
+	webhooks, err := w.webhooks.ListEnabledForUser(msg.UserID)
+	if err != nil {
+		return ports.SwitchSimulation{}, err
+	}
+	sim.Channels = ports.SimulatedChannels{
+		Email:    settings.SMTPHost != "",
+		Webhooks: len(webhooks),
+		Ntfy:     settings.NtfyURL != "",
+	}
+
+	var reminders []models.MessageReminder
+	if err := w.dueReminders().Where("message_reminders.message_id = ?", msg.ID).Find(&reminders).Error; err != nil {
+		return ports.SwitchSimulation{}, services.Internal("Failed to load reminders", err)
+	}
+	for _, req := range dropRedundantResends(reminders) {
+		sim.Reminders = append(sim.Reminders, ports.SimulatedReminder{
+			ID:            req.ID,
+			RelativeTo:    req.RelativeTo,
+			MinutesBefore: req.MinutesBefore,
+			MinutesAfter:  req.MinutesAfter,
+			Channel:       req.Channel,
+			Resend:        req.Sent,
+			Final:         isFinalReminder(req),
+		})
+	}
+
+	sim.Due = msg.Status == models.StatusActive && msg.TriggerAt().Before(now)
+	sim.Action, sim.HeldUntil = w.simulatedAction(msg, settings, webhooks, now, sim.Due)
+	return sim, nil
+}
+
+// simulatedAction mirrors the decisions checkHeartbeats and triggerSwitch
+// make for a switch, without their side effects.
+func (w *Worker) simulatedAction(msg models.Message, settings models.Settings, webhooks []models.Webhook, now time.Time, due bool) (action string, heldUntil *time.Time) {
+	switch {
+	case msg.Status != models.StatusActive:
+		return "none", nil
+	case !due:
+		return "wait", nil
+	case now.Before(msg.FirstTriggerAt(time.Duration(w.cfg.Worker.CreationGraceSeconds) * time.Second)):
+		return "creation_grace", nil
+	case w.inStartupGrace():
+		return "startup_grace", nil
+	}
+	if msg.DeliveryWindow.Enabled() {
+		if opens := msg.DeliveryWindow.NextOpen(now, services.OwnerLocation(settings)).UTC(); opens.After(now) {
+			return "delivery_window", &opens
+		}
+	}
+	if settings.SMTPHost == "" && len(webhooks) == 0 {
+		switch w.cfg.Worker.UndeliverableAction {
+		case common.UndeliverableActionTrigger:
+		case common.UndeliverableActionError:
+			return "error", nil
+		default:
+			return "hold", nil
+		}
+	}
+	return "trigger", nil
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+)
+
+// Watchdog runs beside the worker and emails every account owner once the
+// worker has gone WORKER_WATCHDOG_MISSED_TICKS intervals without completing a
+// tick, the silent failure in which no switch fires. A second email follows
+// when ticks resume.
+type Watchdog struct {
+	worker   ports.WorkerStatusPort
+	settings ports.SettingsServicePort
+	cfg      config.Config
+	send     func(settings models.Settings, recipients []string, subject, body string) error
+
+	alerted bool
+}
+
+func NewWatchdog(worker ports.WorkerStatusPort, settings ports.SettingsServicePort, cfg config.Config) *Watchdog {
+	return &Watchdog{
+		worker:   worker,
+		settings: settings,
+		cfg:      cfg,
+		send:     services.NewEmailService(cfg).SendPlain,
+	}
+}
+
+func (d *Watchdog) Start() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := runRecovered(func() { d.check(time.Now().UTC()) }, "check", "watchdog"); err != nil {
+			slog.Error("Worker watchdog check failed", "error", err)
+		}
+	}
+}
+
+// check compares the worker's last completed tick with now and alerts on the
+// transition to stalled and back.
+func (d *Watchdog) check(now time.Time) {
+	status := d.worker.Status()
+	if status.Maintenance {
+		return
+	}
+	last := status.StartedAt
+	if status.LastTickAt != nil {
+		last = *status.LastTickAt
+	}
+	stalled := now.Sub(last) > time.Duration(d.cfg.Worker.WatchdogMissedTicks)*status.Interval
+
+	switch {
+	case stalled && !d.alerted:
+		d.alerted = true
+		slog.Error("Worker watchdog: no completed tick", "last_tick_at", last, "missed_ticks", d.cfg.Worker.WatchdogMissedTicks)
+		d.notify("Aeterna has stopped checking your switches", `The Aeterna background worker has not completed a check since %s.
+
+Until it recovers, scheduled messages are not delivered and reminders are not sent. Please restart the server or check its logs.`, last)
+	case !stalled && d.alerted:
+		d.alerted = false
+		slog.Info("Worker watchdog: ticks resumed", "last_tick_at", last)
+		d.notify("Aeterna is checking your switches again", "The Aeterna background worker completed a check at %s and is running again.", last)
+	}
+}
+
+// notify emails every owner with email delivery configured. body contains
+// one %s for the last tick in the owner's time zone.
+func (d *Watchdog) notify(subject, body string, at time.Time) {
+	var userIDs []string
+	if err := database.DB.Model(&models.Settings{}).Where("owner_email <> '' AND smtp_host <> ''").Pluck("user_id", &userIDs).Error; err != nil {
+		slog.Error("Failed to load owners for watchdog alert", "error", err)
+		return
+	}
+	for _, userID := range userIDs {
+		settings, err := d.settings.Get(userID)
+		if err != nil {
+			slog.Error("Failed to load settings for watchdog alert", "error", err, "user_id", userID)
+			continue
+		}
+		text := services.AppendEmailFooter(settings, fmt.Sprintf(body, services.FormatOwnerTime(settings, at)))
+		if err := d.send(settings, []string{settings.OwnerEmail}, subject, text); err != nil {
+			slog.Error("Failed to send watchdog alert", "error", err, "user_id", userID)
+		}
+	}
+}
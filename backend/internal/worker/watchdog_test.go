@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+)
+
+type fakeWorkerStatus struct {
+	status *ports.WorkerStatus
+}
+
+func (f fakeWorkerStatus) Status() ports.WorkerStatus {
+	return *f.status
+}
+
+func TestWatchdogAlertsOnceAndOnRecovery(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.Create(&models.Settings{UserID: "u1", OwnerEmail: "owner@example.com", SMTPHost: "smtp.example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Settings{UserID: "u2", OwnerEmail: "other@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	last := now.Add(-10 * time.Minute)
+	status := &ports.WorkerStatus{StartedAt: now.Add(-time.Hour), LastTickAt: &last, Interval: time.Minute}
+	var subjects []string
+	d := NewWatchdog(fakeWorkerStatus{status: status}, fakeSettings{settings: models.Settings{OwnerEmail: "owner@example.com", SMTPHost: "smtp.example.com"}},
+		config.Config{Worker: config.WorkerConfig{WatchdogMissedTicks: 3}})
+	d.send = func(settings models.Settings, recipients []string, subject, body string) error {
+		if settings.UserID != "u1" {
+			t.Fatalf("alert sent to %q, want only the owner with SMTP configured", settings.UserID)
+		}
+		subjects = append(subjects, subject)
+		return nil
+	}
+
+	d.check(now)
+	d.check(now.Add(time.Minute))
+	if len(subjects) != 1 || !strings.Contains(subjects[0], "stopped") {
+		t.Fatalf("subjects = %q, want a single stall alert", subjects)
+	}
+
+	recent := now.Add(time.Minute)
+	status.LastTickAt = &recent
+	d.check(now.Add(2 * time.Minute))
+	d.check(now.Add(2 * time.Minute))
+	if len(subjects) != 2 || !strings.Contains(subjects[1], "again") {
+		t.Fatalf("subjects = %q, want a single recovery email after the alert", subjects)
+	}
+}
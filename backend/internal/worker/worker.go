@@ -1,16 +1,21 @@
 package worker
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/config/common"
 	"github.com/alpyxn/aeterna/backend/internal/database"
 	"github.com/alpyxn/aeterna/backend/internal/models"
 	"github.com/alpyxn/aeterna/backend/internal/ports"
 	"github.com/alpyxn/aeterna/backend/internal/services"
+	"gorm.io/gorm"
 )
 
 // Worker runs the background goroutine that checks heartbeats, reminders, and farewell letters.
@@ -19,36 +24,241 @@ type Worker struct {
 	webhooks           ports.WebhookStorePort
 	files              ports.FileServicePort
 	farewellDerivation ports.FarewellDerivationPort
-	email              services.EmailService
-	webhook            services.WebhookService
+	email              mailer
+	crypto             services.CryptoService
+	webhook            webhookSender
+	ntfy               ntfySender
+	emailCheckIn       services.EmailCheckInService
+	appSettings        services.ApplicationSettingsService
 	cfg                config.Config
+
+	// reminderChannels delivers reminders that do not go by email.
+	reminderChannels map[string]reminderChannel
+
+	// graceUntil is set when startup detects an outage; until then due
+	// switches get an urgent check-in email instead of being triggered.
+	graceUntil    time.Time
+	graceNotified map[string]bool
+
+	// undeliverable tracks the due switches held for lack of a delivery
+	// channel, so the owner is alerted once and the log is not flooded.
+	// checkHeartbeats drops switches that are no longer due.
+	undeliverable map[string]undeliverableState
+
+	lastEmailCheckIn time.Time
+
+	mu         sync.RWMutex
+	startedAt  time.Time
+	lastTickAt *time.Time
+	lastRunAt  *time.Time
+	processed  int64
+	lastError  *ports.WorkerError
+}
+
+// mailer is the part of services.EmailService the worker sends through.
+type mailer interface {
+	SendPlain(settings models.Settings, recipients []string, subject, body string) error
+	SendTriggeredMessage(settings models.Settings, msg models.Message, attachments []services.EmailAttachment) error
+	SendFarewellLetterPreRendered(settings models.Settings, recipientEmail, subject, safeMarkdown, renderedHTML string, attachments []services.EmailAttachment) error
+}
+
+// webhookSender is the part of services.WebhookService the worker uses.
+type webhookSender interface {
+	SendTriggerWebhooks(webhooks []models.Webhook, msg models.Message) error
+	SendReminderWebhooks(webhooks []models.Webhook, msg models.Message, reminder models.MessageReminder, final bool) error
+}
+
+// tickInterval is how often the worker checks reminders and heartbeats.
+const tickInterval = 1 * time.Minute
+
+// emailCheckInInterval is how often reply check-ins are polled over IMAP.
+const emailCheckInInterval = 5 * time.Minute
+
+// maxWorkerErrorLength bounds the error text kept for the status endpoint.
+const maxWorkerErrorLength = 500
+
+// undeliverableLogInterval is how often a held undeliverable switch is logged
+// again while it stays due.
+const undeliverableLogInterval = time.Hour
+
+// undeliverableState is what the worker remembers about a held switch.
+type undeliverableState struct {
+	alerted  bool
+	loggedAt time.Time
 }
 
+// downtimeGapThreshold is how long the worker must have been silent before a
+// restart counts as an outage for STARTUP_GRACE_MINUTES.
+const downtimeGapThreshold = 5 * time.Minute
+
 func New(
 	settings ports.SettingsServicePort,
 	webhooks ports.WebhookStorePort,
 	files ports.FileServicePort,
 	farewellDerivation ports.FarewellDerivationPort,
+	messages ports.MessageServicePort,
 	cfg config.Config,
 ) *Worker {
-	return &Worker{
+	w := &Worker{
 		settings:           settings,
 		webhooks:           webhooks,
 		files:              files,
 		farewellDerivation: farewellDerivation,
+		email:              services.NewEmailService(cfg),
+		webhook:            services.NewWebhookService(cfg),
+		ntfy:               services.NtfyService{},
+		emailCheckIn:       services.NewEmailCheckInService(messages),
 		cfg:                cfg,
+		startedAt:          time.Now().UTC(),
+
+		undeliverable: make(map[string]undeliverableState),
 	}
+	w.reminderChannels = defaultReminderChannels(w)
+	return w
 }
 
 func (w *Worker) Start() {
-	ticker := time.NewTicker(1 * time.Minute)
+	w.beginStartupGrace()
+
+	ticker := time.NewTicker(tickInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		w.checkFarewellDerivatives()
-		w.checkReminders()
-		w.checkHeartbeats()
-		w.checkFarewellLetters()
+		w.runTick()
+	}
+}
+
+// runTick runs one pass of the background checks. Each check is isolated so
+// a panic in one still lets the others run; the tick is only recorded as
+// successful when every check returned normally.
+func (w *Worker) runTick() {
+	now := time.Now().UTC()
+	w.mu.Lock()
+	w.lastRunAt = &now
+	w.mu.Unlock()
+
+	// In maintenance nothing is checked, sent or written. The tick is not
+	// recorded either, so once maintenance ends the pause counts as
+	// downtime and STARTUP_GRACE_MINUTES holds back switches that came due.
+	if w.cfg.App.MaintenanceMode {
+		return
+	}
+
+	ok := true
+	for _, check := range []struct {
+		name string
+		run  func()
+	}{
+		{"farewell_derivatives", w.checkFarewellDerivatives},
+		{"email_check_ins", w.checkEmailCheckIns},
+		{"reminders", w.checkReminders},
+		{"heartbeats", w.checkHeartbeats},
+		{"farewell_letters", w.checkFarewellLetters},
+		{"heartbeat_token_rotation", w.checkHeartbeatTokenRotation},
+		{"retained_attachments", w.checkRetainedAttachments},
+		{"content_shredding", w.checkContentShredding},
+	} {
+		if err := runRecovered(check.run, "check", check.name); err != nil {
+			w.recordError(check.name, "", err)
+			ok = false
+		}
+	}
+	if ok {
+		w.recordTick()
+	}
+}
+
+// runRecovered calls fn and returns an error describing the panic, if it
+// panicked. A panic is logged with attrs and the stack trace instead of
+// propagating.
+func runRecovered(fn func(), attrs ...any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Worker panic recovered", append(attrs, "panic", r, "stack", string(debug.Stack()))...)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// Status reports when the worker started, last ran and last completed a
+// tick, how many switches it has triggered and its most recent failure.
+func (w *Worker) Status() ports.WorkerStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	status := ports.WorkerStatus{
+		StartedAt:         w.startedAt,
+		Interval:          tickInterval,
+		MessagesProcessed: w.processed,
+		Maintenance:       w.cfg.App.MaintenanceMode,
+	}
+	next := w.startedAt.Add(tickInterval)
+	if w.lastTickAt != nil {
+		last := *w.lastTickAt
+		status.LastTickAt = &last
+		next = last.Add(tickInterval)
+	}
+	if w.lastRunAt != nil {
+		run := *w.lastRunAt
+		status.LastRunAt = &run
+	}
+	if w.lastError != nil {
+		lastErr := *w.lastError
+		status.LastError = &lastErr
+	}
+	status.NextTickAt = next
+	return status
+}
+
+// recordError keeps err as the worker's last error for the status endpoint.
+// Callers still log it; this only saves operators a trip to the logs.
+func (w *Worker) recordError(check, messageID string, err error) {
+	text := err.Error()
+	if len(text) > maxWorkerErrorLength {
+		text = text[:maxWorkerErrorLength]
+	}
+	w.mu.Lock()
+	w.lastError = &ports.WorkerError{At: time.Now().UTC(), Check: check, MessageID: messageID, Error: text}
+	w.mu.Unlock()
+}
+
+// beginStartupGrace opens the STARTUP_GRACE_MINUTES window when the last
+// recorded tick is old enough to indicate the process was down.
+func (w *Worker) beginStartupGrace() {
+	if w.cfg.Worker.StartupGraceMinutes <= 0 {
+		return
+	}
+	lastTick, err := w.appSettings.LastWorkerTick()
+	if err != nil {
+		slog.Error("Failed to read last worker tick", "error", err)
+		return
+	}
+	if lastTick == nil {
+		return
+	}
+	gap := time.Since(*lastTick)
+	if gap < downtimeGapThreshold {
+		return
+	}
+	w.graceUntil = time.Now().Add(time.Duration(w.cfg.Worker.StartupGraceMinutes) * time.Minute)
+	w.graceNotified = make(map[string]bool)
+	slog.Warn("Worker downtime detected, deferring triggers", "downtime", gap.Round(time.Second).String(), "grace_until", w.graceUntil.UTC())
+}
+
+func (w *Worker) inStartupGrace() bool {
+	return !w.graceUntil.IsZero() && time.Now().Before(w.graceUntil)
+}
+
+func (w *Worker) recordTick() {
+	now := time.Now().UTC()
+	w.mu.Lock()
+	w.lastTickAt = &now
+	w.mu.Unlock()
+
+	if err := w.appSettings.RecordWorkerTick(now); err != nil {
+		slog.Error("Failed to record worker tick", "error", err)
 	}
 }
 
@@ -68,75 +278,400 @@ func (w *Worker) checkFarewellDerivatives() {
 }
 
 func (w *Worker) checkReminders() {
+	w.syncEscalationReminders()
+
 	var reminders []models.MessageReminder
+	if err := w.dueReminders().Find(&reminders).Error; err != nil {
+		slog.Error("Error checking reminders", "error", err)
+		return
+	}
+
+	mail, done := w.batchMailer()
+	defer done()
+	digests := &reminderDigests{byUser: map[string]*reminderDigest{}}
+	for _, req := range dropRedundantResends(reminders) {
+		if err := runRecovered(func() { w.processReminder(mail, req, digests) }, "reminder_id", req.ID, "message_id", req.MessageID); err != nil {
+			w.recordError("reminders", req.MessageID, err)
+		}
+	}
+	for _, digest := range digests.ordered {
+		if err := runRecovered(func() { w.sendReminderDigest(mail, digest) }, "user_id", digest.settings.UserID); err != nil {
+			w.recordError("reminders", "", err)
+		}
+	}
+}
+
+// reminderDigest collects the email reminders due for one owner with
+// reminder_digest on, so the tick can send them as one email.
+type reminderDigest struct {
+	settings models.Settings
+	entries  []digestEntry
+}
+
+type digestEntry struct {
+	msg   models.Message
+	req   models.MessageReminder
+	final bool
+}
+
+type reminderDigests struct {
+	byUser  map[string]*reminderDigest
+	ordered []*reminderDigest
+}
 
-	err := database.DB.Table("message_reminders").
+func (d *reminderDigests) add(settings models.Settings, entry digestEntry) {
+	digest := d.byUser[settings.UserID]
+	if digest == nil {
+		digest = &reminderDigest{settings: settings}
+		d.byUser[settings.UserID] = digest
+		d.ordered = append(d.ordered, digest)
+	}
+	digest.entries = append(digest.entries, entry)
+}
+
+// batchMailer returns a mailer that reuses SMTP connections until done is
+// called, when w.email supports it, so many reminders coming due in one tick
+// do not each connect and log in to the relay.
+func (w *Worker) batchMailer() (mail mailer, done func()) {
+	if batcher, ok := w.email.(interface {
+		Batch() (services.EmailService, func())
+	}); ok {
+		return batcher.Batch()
+	}
+	return w.email, func() {}
+}
+
+// dueReminders selects the reminders of active switches that are due to be
+// sent now, including re-sends under REMINDER_RESEND_INTERVAL_HOURS. Creation
+// reminders are due MinutesAfter the switch was created and never re-sent.
+func (w *Worker) dueReminders() *gorm.DB {
+	pending := database.DB.Where("message_reminders.sent = ?", false)
+	if w.cfg.Worker.ReminderResendHours > 0 {
+		// A sent reminder goes out again once no reminder for its message
+		// has been sent for a full interval.
+		cutoff := time.Now().UTC().Add(-time.Duration(w.cfg.Worker.ReminderResendHours) * time.Hour)
+		pending = pending.Or("message_reminders.relative_to <> ? AND message_reminders.last_reminder_at <= ? AND NOT EXISTS (SELECT 1 FROM message_reminders recent WHERE recent.message_id = message_reminders.message_id AND recent.last_reminder_at > ?)", models.ReminderRelativeToCreation, cutoff, cutoff)
+	}
+
+	return database.DB.Table("message_reminders").
 		Select("message_reminders.*").
 		Joins("JOIN messages ON messages.id = message_reminders.message_id").
 		Where("messages.status = ?", models.StatusActive).
-		Where("message_reminders.sent = ?", false).
-		Where("datetime('now') >= datetime(messages.last_seen, '+' || CAST((messages.trigger_duration - message_reminders.minutes_before) AS TEXT) || ' minutes')").
-		Find(&reminders).Error
+		Where(pending).
+		Where("CASE WHEN message_reminders.relative_to = ? "+
+			"THEN datetime('now') >= datetime(messages.created_at, '+' || CAST(message_reminders.minutes_after AS TEXT) || ' minutes') "+
+			"ELSE datetime('now') >= datetime(MAX(datetime(messages.last_seen, '+' || CAST(messages.trigger_duration * MAX(messages.required_missed_intervals, 1) AS TEXT) || ' minutes'), COALESCE(datetime(messages.extended_until), '')), '-' || CAST(message_reminders.minutes_before AS TEXT) || ' minutes') END",
+			models.ReminderRelativeToCreation)
+}
 
-	if err != nil {
-		slog.Error("Error checking reminders", "error", err)
-		return
+// dropRedundantResends keeps a single re-send per message, the one closest
+// to the trigger, and none at all for a message that also has a reminder due
+// for the first time, so one tick never nudges the owner twice.
+func dropRedundantResends(reminders []models.MessageReminder) []models.MessageReminder {
+	resend := map[string]models.MessageReminder{}
+	firstSend := map[string]bool{}
+	for _, req := range reminders {
+		if !req.Sent {
+			firstSend[req.MessageID] = true
+			continue
+		}
+		if current, ok := resend[req.MessageID]; !ok || req.MinutesBefore < current.MinutesBefore {
+			resend[req.MessageID] = req
+		}
 	}
-
+	out := make([]models.MessageReminder, 0, len(reminders))
 	for _, req := range reminders {
-		var msg models.Message
-		if err := database.DB.First(&msg, "id = ?", req.MessageID).Error; err != nil {
+		if !req.Sent {
+			out = append(out, req)
+		} else if !firstSend[req.MessageID] && resend[req.MessageID].ID == req.ID {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+// syncEscalationReminders materialises each user's ReminderEscalation as
+// reminder rows on their active messages, so escalation steps are scheduled,
+// marked sent and reset on heartbeat like any other reminder.
+func (w *Worker) syncEscalationReminders() {
+	var owners []models.Settings
+	if err := database.DB.Where("reminder_escalation <> ''").Find(&owners).Error; err != nil {
+		slog.Error("Failed to load reminder escalation settings", "error", err)
+		return
+	}
+	for _, owner := range owners {
+		steps, err := services.ParseReminderEscalation(owner.ReminderEscalation)
+		if err != nil {
+			slog.Error("Invalid reminder escalation", "error", err, "user_id", owner.UserID)
 			continue
 		}
-		if msg.UserID == "" {
+		var messages []models.Message
+		if err := database.ForTenant(owner.UserID).Preload("Reminders").
+			Where("status = ?", models.StatusActive).Find(&messages).Error; err != nil {
+			slog.Error("Failed to load messages for reminder escalation", "error", err, "user_id", owner.UserID)
+			continue
+		}
+		for _, msg := range messages {
+			syncMessageEscalation(msg, steps)
+		}
+	}
+}
+
+func syncMessageEscalation(msg models.Message, steps []services.ReminderStep) {
+	wanted := map[services.ReminderStep]bool{}
+	for _, step := range steps {
+		wanted[step] = true
+	}
+	for _, existing := range msg.Reminders {
+		if existing.FromCreation() {
 			continue
 		}
-		settings, err := w.settings.Get(msg.UserID)
-		if err != nil || settings.OwnerEmail == "" || settings.SMTPHost == "" {
+		step := services.ReminderStep{Channel: existing.Channel, MinutesBefore: existing.MinutesBefore}
+		if !wanted[step] && existing.Escalation {
+			if err := database.DB.Delete(&existing).Error; err != nil {
+				slog.Error("Failed to remove stale escalation reminder", "error", err, "reminder_id", existing.ID)
+			}
+		}
+		delete(wanted, step)
+	}
+	for _, step := range steps {
+		if !wanted[step] {
 			continue
 		}
-		w.sendReminderEmail(settings, msg, req)
+		reminder := models.MessageReminder{
+			MessageID:     msg.ID,
+			MinutesBefore: step.MinutesBefore,
+			Channel:       step.Channel,
+			Escalation:    true,
+		}
+		if err := database.DB.Create(&reminder).Error; err != nil {
+			slog.Error("Failed to create escalation reminder", "error", err, "message_id", msg.ID)
+		}
+	}
+}
+
+func (w *Worker) processReminder(mail mailer, req models.MessageReminder, digests *reminderDigests) {
+	var msg models.Message
+	if err := database.DB.First(&msg, "id = ?", req.MessageID).Error; err != nil {
+		return
+	}
+	if msg.UserID == "" {
+		return
+	}
+	settings, err := w.settings.Get(msg.UserID)
+	if err != nil {
+		return
+	}
+
+	final := isFinalReminder(req)
+
+	err = w.reminderChannel(req.Channel, mail, digests).SendReminder(settings, msg, req, final)
+	if errors.Is(err, errReminderDeferred) {
+		return
+	}
+	if err != nil && req.Channel != models.ReminderChannelEmail && settings.OwnerEmail != "" && settings.SMTPHost != "" {
+		// A push or webhook failure must not leave the owner unwarned.
+		slog.Warn("Reminder channel failed, falling back to email", "error", err, "channel", req.Channel, "message_id", msg.ID)
+		err = w.sendReminderEmail(mail, settings, msg, req, final)
+	}
+	if err != nil {
+		slog.Error("Failed to send reminder", "error", err, "channel", req.Channel, "message_id", msg.ID)
+		w.recordError("reminders", msg.ID, err)
+		return
+	}
+	markReminderSent(req, final)
+}
+
+func markReminderSent(req models.MessageReminder, final bool) {
+	if err := database.DB.Model(&req).Updates(map[string]any{"sent": true, "last_reminder_at": time.Now().UTC()}).Error; err != nil {
+		slog.Error("Failed to mark reminder as sent", "error", err, "reminder_id", req.ID)
+	}
+	slog.Info("Reminder sent", "channel", req.Channel, "message_id", req.MessageID, "relative_to", req.RelativeTo, "minutes_before", req.MinutesBefore, "minutes_after", req.MinutesAfter, "final", final, "resend", req.Sent)
+}
+
+// sendReminderDigest sends an owner's collected reminders as one "N
+// messages need your check-in" email whose quick-heartbeat link checks in
+// all of them. A digest of one is sent as the usual reminder, which keeps
+// its reply-to-check-in token.
+func (w *Worker) sendReminderDigest(mail mailer, digest *reminderDigest) {
+	settings := digest.settings
+	if len(digest.entries) == 1 {
+		entry := digest.entries[0]
+		if err := w.sendReminderEmail(mail, settings, entry.msg, entry.req, entry.final); err != nil {
+			slog.Error("Failed to send reminder", "error", err, "channel", entry.req.Channel, "message_id", entry.msg.ID)
+			w.recordError("reminders", entry.msg.ID, err)
+			return
+		}
+		markReminderSent(entry.req, entry.final)
+		return
+	}
+
+	subject := fmt.Sprintf("%d messages need your check-in", len(digest.entries))
+	final := false
+	var lines strings.Builder
+	for _, entry := range digest.entries {
+		fmt.Fprintf(&lines, "- To %s: sent in %s (at %s)", formatRecipients(entry.msg.RecipientEmail), reminderRemaining(entry.msg), services.FormatOwnerTime(settings, entry.msg.TriggerAt()))
+		if entry.final {
+			final = true
+			lines.WriteString(", final reminder")
+		}
+		lines.WriteString("\n")
+	}
+	if final {
+		subject = "Final check-in required: " + subject
+	}
+	body := fmt.Sprintf(`You have %d scheduled messages that will be sent unless you confirm:
+
+%s
+One check-in confirms all of them:
+%s%s`, len(digest.entries), lines.String(), w.quickHeartbeatLink(settings), w.reminderActionLinks(settings, ""))
+	body = services.AppendEmailFooter(settings, body)
+
+	if err := mail.SendPlain(settings, []string{settings.OwnerEmail}, subject, body); err != nil {
+		slog.Error("Failed to send reminder digest", "error", err, "user_id", settings.UserID, "messages", len(digest.entries))
+		w.recordError("reminders", "", err)
+		return
+	}
+	for _, entry := range digest.entries {
+		markReminderSent(entry.req, entry.final)
 	}
 }
 
-func (w *Worker) sendReminderEmail(settings models.Settings, msg models.Message, reminder models.MessageReminder) {
-	lastSeen := msg.LastSeen
-	triggerTime := lastSeen.Add(time.Duration(msg.TriggerDuration) * time.Minute)
-	remaining := time.Until(triggerTime)
+// isFinalReminder reports whether req is the last reminder before its switch
+// triggers, so channels can make it as attention-grabbing as they support.
+func isFinalReminder(req models.MessageReminder) bool {
+	if req.FromCreation() {
+		return false
+	}
+	var later int64
+	database.DB.Model(&models.MessageReminder{}).
+		Where("message_id = ? AND relative_to <> ? AND minutes_before < ?", req.MessageID, models.ReminderRelativeToCreation, req.MinutesBefore).
+		Count(&later)
+	return later == 0
+}
+
+func reminderRemaining(msg models.Message) string {
+	remaining := time.Until(msg.TriggerAt())
 
-	var remainingStr string
 	if remaining.Hours() > 24 {
 		days := int(remaining.Hours() / 24)
-		remainingStr = fmt.Sprintf("%d day(s)", days)
+		return fmt.Sprintf("%d day(s)", days)
 	} else if remaining.Hours() > 1 {
-		remainingStr = fmt.Sprintf("%.0f hour(s)", remaining.Hours())
-	} else {
-		remainingStr = fmt.Sprintf("%.0f minute(s)", remaining.Minutes())
+		return fmt.Sprintf("%.0f hour(s)", remaining.Hours())
 	}
+	return fmt.Sprintf("%.0f minute(s)", remaining.Minutes())
+}
 
-	quickLink := fmt.Sprintf("%s/api/quick-heartbeat/%s", w.cfg.Worker.BaseURL, settings.HeartbeatToken)
+func (w *Worker) quickHeartbeatLink(settings models.Settings) string {
+	return fmt.Sprintf("%s/api/quick-heartbeat/%s", w.cfg.Worker.BaseURL, settings.HeartbeatToken)
+}
 
+// reminderActionLinks offers extend and snooze links below the check-in link
+// when Settings.ReminderActions is on. messageID limits them to one switch;
+// empty applies them to all of the owner's active switches.
+func (w *Worker) reminderActionLinks(settings models.Settings, messageID string) string {
+	if !settings.ReminderActions {
+		return ""
+	}
+	link := w.quickHeartbeatLink(settings)
+	return fmt.Sprintf("\n\nOr move the deadline instead:\nExtend by %d days: %s%s\nSnooze %d day: %s%s",
+		services.ReminderExtendDays, link, services.ReminderActionQuery(services.ReminderActionExtend, messageID),
+		services.ReminderSnoozeDays, link, services.ReminderActionQuery(services.ReminderActionSnooze, messageID))
+}
+
+func (w *Worker) sendReminderEmail(mail mailer, settings models.Settings, msg models.Message, req models.MessageReminder, final bool) error {
+	if req.FromCreation() {
+		return w.sendCreationReminderEmail(mail, settings, msg)
+	}
 	subject := "Check-in required"
-	body := fmt.Sprintf(`You have a scheduled message that will be sent in %s unless you confirm.
+	if final {
+		subject = "Final check-in required"
+	}
+	replyHint := ""
+	if settings.EmailCheckInEnabled && settings.HeartbeatToken != "" {
+		subject += " [" + services.EmailCheckInToken(settings.HeartbeatToken, msg.ID) + "]"
+		replyHint = "\n\nOr simply reply to this email to check in for this message."
+	}
+	body := fmt.Sprintf(`You have a scheduled message that will be sent in %s (at %s) unless you confirm.
 
 Recipient: %s
 
 To confirm you are available, click the link below:
-%s
+%s%s%s`, reminderRemaining(msg), services.FormatOwnerTime(settings, msg.TriggerAt()), formatRecipients(msg.RecipientEmail), w.quickHeartbeatLink(settings), w.reminderActionLinks(settings, msg.ID), replyHint)
+	body = services.AppendEmailFooter(settings, body)
 
----
-Sent by Aeterna`, remainingStr, formatRecipients(msg.RecipientEmail), quickLink)
+	return mail.SendPlain(settings, []string{settings.OwnerEmail}, subject, body)
+}
 
-	err := w.email.SendPlain(settings, []string{settings.OwnerEmail}, subject, body)
-	if err != nil {
-		slog.Error("Failed to send reminder email", "error", err, "owner", settings.OwnerEmail)
+// sendCreationReminderEmail asks the owner once whether a switch they set up
+// a while ago is still wanted. No check-in is needed to keep it.
+func (w *Worker) sendCreationReminderEmail(mail mailer, settings models.Settings, msg models.Message) error {
+	body := fmt.Sprintf(`On %s you set up a scheduled message. Did you still mean to?
+
+Recipient: %s
+It will be sent in %s (at %s) unless you check in.
+
+If you no longer want it, delete it in Aeterna. Otherwise nothing needs to be done.`, services.FormatOwnerTime(settings, msg.CreatedAt), formatRecipients(msg.RecipientEmail), reminderRemaining(msg), services.FormatOwnerTime(settings, msg.TriggerAt()))
+	body = services.AppendEmailFooter(settings, body)
+
+	return mail.SendPlain(settings, []string{settings.OwnerEmail}, "Is this scheduled message still wanted?", body)
+}
+
+// checkHeartbeatTokenRotation regenerates quick-heartbeat tokens whose
+// HeartbeatTokenRotationDays have elapsed. Later reminders carry the new link.
+func (w *Worker) checkHeartbeatTokenRotation() {
+	var scheduled []models.Settings
+	if err := database.DB.Where("heartbeat_token_rotation_days > 0").Find(&scheduled).Error; err != nil {
+		slog.Error("Failed to load heartbeat token rotation settings", "error", err)
 		return
 	}
+	now := time.Now().UTC()
+	for _, settings := range scheduled {
+		if settings.HeartbeatTokenRotatedAt == nil {
+			if err := database.DB.Model(&models.Settings{}).Where("id = ?", settings.ID).
+				Update("heartbeat_token_rotated_at", now).Error; err != nil {
+				slog.Error("Failed to start heartbeat token rotation", "error", err, "user_id", settings.UserID)
+			}
+			continue
+		}
+		if now.Before(settings.HeartbeatTokenRotatedAt.AddDate(0, 0, settings.HeartbeatTokenRotationDays)) {
+			continue
+		}
+		if _, err := w.settings.RotateHeartbeatToken(settings.UserID); err != nil {
+			slog.Error("Failed to rotate heartbeat token", "error", err, "user_id", settings.UserID)
+			continue
+		}
+		slog.Info("Heartbeat token rotated", "user_id", settings.UserID, "rotation_days", settings.HeartbeatTokenRotationDays)
+	}
+}
 
-	if err := database.DB.Model(&reminder).Update("sent", true).Error; err != nil {
-		slog.Error("Failed to mark reminder as sent", "error", err, "reminder_id", reminder.ID)
+// checkEmailCheckIns polls the mailbox of every owner with email check-in
+// enabled, at most once per emailCheckInInterval.
+func (w *Worker) checkEmailCheckIns() {
+	if time.Since(w.lastEmailCheckIn) < emailCheckInInterval {
+		return
+	}
+	w.lastEmailCheckIn = time.Now()
+
+	var enabled []models.Settings
+	if err := database.DB.Where("email_check_in_enabled = ?", true).Find(&enabled).Error; err != nil {
+		slog.Error("Failed to load email check-in settings", "error", err)
+		return
+	}
+	for _, row := range enabled {
+		settings, err := w.settings.Get(row.UserID)
+		if err != nil {
+			slog.Error("Failed to load settings for email check-in", "error", err, "user_id", row.UserID)
+			continue
+		}
+		n, err := w.emailCheckIn.Poll(settings)
+		if err != nil {
+			slog.Error("Email check-in poll failed", "error", err, "user_id", row.UserID)
+		}
+		if n > 0 {
+			slog.Info("Email check-in recorded", "user_id", row.UserID, "messages", n)
+		}
 	}
-	slog.Info("Reminder email sent", "owner", settings.OwnerEmail, "message_id", msg.ID, "minutes_before", reminder.MinutesBefore)
 }
 
 func (w *Worker) checkHeartbeats() {
@@ -150,24 +685,220 @@ func (w *Worker) checkHeartbeats() {
 		slog.Error("Error checking heartbeats", "error", err)
 		return
 	}
+	w.forgetUndeliverable(messages)
 
 	for _, msg := range messages {
 		if msg.UserID == "" {
 			continue
 		}
-		w.triggerSwitch(msg)
+		if !msg.TriggerAt().Before(time.Now()) {
+			w.recordMissedIntervals(msg)
+			continue
+		}
+		if time.Now().Before(msg.FirstTriggerAt(time.Duration(w.cfg.Worker.CreationGraceSeconds) * time.Second)) {
+			continue
+		}
+		if w.inStartupGrace() {
+			runRecovered(func() { w.sendPostOutageCheckIn(msg) }, "message_id", msg.ID)
+			continue
+		}
+		if w.holdForDeliveryWindow(msg) {
+			continue
+		}
+		if err := runRecovered(func() { w.triggerSwitch(msg) }, "message_id", msg.ID); err != nil {
+			w.recordError("heartbeats", msg.ID, err)
+		}
 	}
 }
 
-func (w *Worker) triggerSwitch(msg models.Message) {
-	slog.Warn("Switch triggered", "recipient", formatRecipients(msg.RecipientEmail), "id", msg.ID)
+// checkRetainedAttachments deletes the attachments of switches that
+// triggered more than ATTACHMENT_RETENTION_DAYS ago, ending their
+// availability on the reveal link.
+func (w *Worker) checkRetainedAttachments() {
+	days := w.cfg.Worker.AttachmentRetentionDays
+	if days == 0 {
+		return
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+	var messages []models.Message
+	err := database.DB.Select("id", "user_id").
+		Where("status = ? AND triggered_at < ?", models.StatusTriggered, cutoff).
+		Where("EXISTS (SELECT 1 FROM attachments WHERE attachments.message_id = messages.id)").
+		Find(&messages).Error
+	if err != nil {
+		slog.Error("Error checking retained attachments", "error", err)
+		return
+	}
+	for _, msg := range messages {
+		if err := w.files.DeleteByMessageID(msg.UserID, msg.ID); err != nil {
+			slog.Error("Failed to delete retained attachments", "error", err, "message_id", msg.ID)
+			continue
+		}
+		slog.Info("Retained attachments expired", "message_id", msg.ID)
+	}
+}
+
+// checkContentShredding overwrites the content and key fragment of switches
+// delivered more than CONTENT_RETENTION_DAYS ago when SHRED_AFTER_DELIVERY is
+// on, together with their farewell letters' content and any retained
+// attachment files, so delivered plaintext does not linger. Recipients,
+// timestamps and status are kept. A switch with a farewell letter still
+// pending waits until it has been sent.
+func (w *Worker) checkContentShredding() {
+	if !w.cfg.Worker.ShredAfterDelivery {
+		return
+	}
+	now := time.Now().UTC()
+	cutoff := now.AddDate(0, 0, -w.cfg.Worker.ContentRetentionDays)
+	var messages []models.Message
+	err := database.DB.Select("id", "user_id").
+		Where("status = ? AND triggered_at <= ? AND shredded_at IS NULL", models.StatusTriggered, cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM farewell_letters WHERE farewell_letters.message_id = messages.id AND farewell_letters.status = ? AND farewell_letters.deleted_at IS NULL)", models.FarewellStatusPending).
+		Find(&messages).Error
+	if err != nil {
+		slog.Error("Failed to find delivered content to shred", "error", err)
+		return
+	}
+	shredded := 0
+	for _, msg := range messages {
+		if err := w.shredDelivered(msg, now); err != nil {
+			slog.Error("Failed to shred delivered content", "error", err, "message_id", msg.ID)
+			continue
+		}
+		shredded++
+	}
+	if shredded > 0 {
+		slog.Info("Delivered content shredded", "count", shredded)
+	}
+}
+
+// shredDelivered deletes msg's retained attachments, then clears its
+// farewell letters and content in one transaction, marking it shredded last
+// so a failure is retried on the next tick.
+func (w *Worker) shredDelivered(msg models.Message, now time.Time) error {
+	if err := w.files.DeleteByMessageID(msg.UserID, msg.ID); err != nil {
+		return fmt.Errorf("delete retained attachments: %w", err)
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.FarewellLetter{}).Where("message_id = ?", msg.ID).
+			UpdateColumns(map[string]any{
+				services.FarewellContentColumn:      "",
+				services.FarewellRawContentColumn:   "",
+				services.FarewellRenderedHTMLColumn: "",
+			}).Error; err != nil {
+			return fmt.Errorf("shred farewell letters: %w", err)
+		}
+		return tx.Model(&models.Message{}).Where("id = ? AND shredded_at IS NULL", msg.ID).
+			UpdateColumns(map[string]any{"encrypted_content": "", "key_fragment": "", "shredded_at": now}).Error
+	})
+}
+
+// recordMissedIntervals updates the missed-interval counter of a switch that
+// tolerates more than one missed heartbeat and has not yet run out of them.
+func (w *Worker) recordMissedIntervals(msg models.Message) {
+	missed := msg.MissedIntervalsAt(time.Now())
+	if missed == msg.MissedIntervals {
+		return
+	}
+	if err := unchangedSinceLoad(msg).
+		Update("missed_intervals", missed).Error; err != nil {
+		slog.Error("Failed to record missed interval", "error", err, "message_id", msg.ID)
+		return
+	}
+	slog.Warn("Heartbeat interval missed", "message_id", msg.ID, "missed", missed, "required", msg.RequiredMissedIntervals)
+}
+
+// unchangedSinceLoad scopes an update to msg as the worker loaded it, so a
+// heartbeat or deadline extension arriving meanwhile is not overwritten.
+func unchangedSinceLoad(msg models.Message) *gorm.DB {
+	query := database.DB.Model(&models.Message{}).Where("id = ? AND last_seen = ?", msg.ID, msg.LastSeen)
+	if msg.ExtendedUntil == nil {
+		return query.Where("extended_until IS NULL")
+	}
+	return query.Where("extended_until = ?", *msg.ExtendedUntil)
+}
+
+// holdForDeliveryWindow reports whether a fired switch must wait for its
+// delivery window, recording when the window opens. The update is guarded
+// by unchangedSinceLoad.
+func (w *Worker) holdForDeliveryWindow(msg models.Message) bool {
+	if !msg.DeliveryWindow.Enabled() {
+		return false
+	}
+	owner := time.UTC
+	if settings, err := w.settings.Get(msg.UserID); err == nil {
+		owner = services.OwnerLocation(settings)
+	}
+	now := time.Now()
+	opens := msg.DeliveryWindow.NextOpen(now, owner)
+	if !opens.After(now) {
+		return false
+	}
+	if msg.DeliveryHeldUntil != nil && msg.DeliveryHeldUntil.Equal(opens) {
+		return true
+	}
+	heldUntil := opens.UTC()
+	if err := unchangedSinceLoad(msg).
+		Update("delivery_held_until", heldUntil).Error; err != nil {
+		slog.Error("Failed to record delivery hold", "error", err, "message_id", msg.ID)
+		return true
+	}
+	slog.Info("Delivery held until window opens", "message_id", msg.ID, "until", heldUntil)
+	return true
+}
+
+// sendPostOutageCheckIn warns the owner once per switch that it came due while
+// the server was down and will be delivered when the grace period ends.
+func (w *Worker) sendPostOutageCheckIn(msg models.Message) {
+	if w.graceNotified[msg.ID] {
+		return
+	}
+	settings, err := w.settings.Get(msg.UserID)
+	if err != nil || settings.OwnerEmail == "" || settings.SMTPHost == "" {
+		slog.Warn("Switch deferred by startup grace without owner notification", "id", msg.ID, "grace_until", w.graceUntil.UTC())
+		w.graceNotified[msg.ID] = true
+		return
+	}
+
+	subject := "Urgent: check-in required after server outage"
+	body := fmt.Sprintf(`The server running your dead man's switch was offline, and one of your scheduled messages came due in the meantime.
+
+Delivery has been paused until %s. If you do not check in before then, the message will be sent.
+
+Recipient: %s
+
+To confirm you are available, click the link below:
+%s`, services.FormatOwnerTime(settings, w.graceUntil), formatRecipients(msg.RecipientEmail), w.quickHeartbeatLink(settings))
+	body = services.AppendEmailFooter(settings, body)
+
+	if err := w.email.SendPlain(settings, []string{settings.OwnerEmail}, subject, body); err != nil {
+		slog.Error("Failed to send post-outage check-in", "error", err, "owner", settings.OwnerEmail)
+		return
+	}
+	w.graceNotified[msg.ID] = true
+	slog.Info("Post-outage check-in sent", "owner", settings.OwnerEmail, "message_id", msg.ID)
+}
 
+func (w *Worker) triggerSwitch(msg models.Message) {
 	settings, err := w.settings.Get(msg.UserID)
 	if err != nil {
 		slog.Error("Failed to load SMTP settings", "error", err, "user_id", msg.UserID)
 		settings = models.Settings{}
 	}
 
+	webhooks, err := w.webhooks.ListEnabledForUser(msg.UserID)
+	if err != nil {
+		slog.Error("Failed to load webhooks", "error", err)
+	}
+	if settings.SMTPHost == "" && len(webhooks) == 0 && w.cfg.Worker.UndeliverableAction != common.UndeliverableActionTrigger {
+		w.handleUndeliverable(settings, msg)
+		return
+	}
+	delete(w.undeliverable, msg.ID)
+
+	slog.Warn("Switch triggered", "recipient", formatRecipients(msg.RecipientEmail), "id", msg.ID)
+
+	var loaded []models.Attachment
 	var emailAttachments []services.EmailAttachment
 	attachments, err := w.files.ListByMessageID(msg.UserID, msg.ID)
 	if err != nil {
@@ -179,6 +910,7 @@ func (w *Worker) triggerSwitch(msg models.Message) {
 				slog.Error("Failed to decrypt attachment", "error", err, "attachment_id", att.ID)
 				continue
 			}
+			loaded = append(loaded, att)
 			emailAttachments = append(emailAttachments, services.EmailAttachment{
 				Filename: filename,
 				MimeType: mimeType,
@@ -187,37 +919,57 @@ func (w *Worker) triggerSwitch(msg models.Message) {
 		}
 	}
 
-	if settings.SMTPHost != "" {
-		err := w.email.SendTriggeredMessage(settings, msg, emailAttachments)
-		if err != nil {
-			slog.Error("Failed to send email", "error", err, "recipient", formatRecipients(msg.RecipientEmail))
+	var failures []string
+	for _, batch := range batchByAttachmentSelection(msg.RecipientEmail, loaded, emailAttachments) {
+		batchMsg := msg
+		batchMsg.RecipientEmail = strings.Join(batch.recipients, ",")
+		if settings.SMTPHost != "" {
+			err := w.email.SendTriggeredMessage(settings, batchMsg, batch.attachments)
+			if err != nil {
+				slog.Error("Failed to send email", "error", err, "permanent", services.IsPermanentSMTPError(err), "recipient", formatRecipients(batchMsg.RecipientEmail))
+				w.recordError("heartbeats", msg.ID, fmt.Errorf("trigger email: %w", err))
+				failures = append(failures, fmt.Sprintf("Email to %s: %s", formatRecipients(batchMsg.RecipientEmail), deliveryFailureReason(err)))
+			} else {
+				slog.Info("Email sent successfully", "recipient", formatRecipients(batchMsg.RecipientEmail), "attachments", len(batch.attachments))
+			}
 		} else {
-			slog.Info("Email sent successfully", "recipient", formatRecipients(msg.RecipientEmail), "attachments", len(emailAttachments))
+			slog.Info("Mock email", "recipient", formatRecipients(batchMsg.RecipientEmail), "attachments", len(batch.attachments))
 		}
-	} else {
-		slog.Info("Mock email", "recipient", formatRecipients(msg.RecipientEmail), "attachments", len(emailAttachments))
 	}
 
-	webhooks, err := w.webhooks.ListEnabledForUser(msg.UserID)
-	if err != nil {
-		slog.Error("Failed to load webhooks", "error", err)
-	} else if len(webhooks) > 0 {
+	if len(webhooks) > 0 {
 		slog.Info("Webhook delivery attempt", "count", len(webhooks), "recipient", formatRecipients(msg.RecipientEmail))
 		if err := w.webhook.SendTriggerWebhooks(webhooks, msg); err != nil {
 			slog.Error("Failed to deliver webhook", "error", err, "recipient", formatRecipients(msg.RecipientEmail))
+			w.recordError("heartbeats", msg.ID, fmt.Errorf("trigger webhook: %w", err))
+			failures = append(failures, fmt.Sprintf("Webhook: %v", err))
 		} else {
 			slog.Info("Webhook delivered", "count", len(webhooks), "recipient", formatRecipients(msg.RecipientEmail))
 		}
 	}
 
 	now := time.Now().UTC()
+	from := msg.Status
 	msg.Status = models.StatusTriggered
 	msg.TriggeredAt = &now
-	if err := database.ForTenant(msg.UserID).Save(&msg).Error; err != nil {
+	msg.DeliveryHeldUntil = nil
+	// Losing this write to a lock would deliver the switch again next tick.
+	if err := database.RetryOnLock(func() error { return database.ForTenant(msg.UserID).Save(&msg).Error }); err != nil {
 		slog.Error("Failed to persist triggered status", "error", err, "message_id", msg.ID)
+		w.recordError("heartbeats", msg.ID, fmt.Errorf("persist triggered status: %w", err))
+	} else {
+		if err := services.RecordStatusTransition(database.DB, msg, from, models.StatusTriggered, models.TransitionReasonTriggered, models.TransitionActorWorker); err != nil {
+			slog.Error("Failed to record status transition", "error", err, "message_id", msg.ID)
+		}
+		if err := services.RecordDeliveredAttachments(database.DB, msg, attachments); err != nil {
+			slog.Error("Failed to record delivered attachments", "error", err, "message_id", msg.ID)
+		}
 	}
+	w.mu.Lock()
+	w.processed++
+	w.mu.Unlock()
 
-	if len(attachments) > 0 {
+	if len(attachments) > 0 && w.cfg.Worker.AttachmentRetentionDays == 0 {
 		if err := w.files.DeleteByMessageID(msg.UserID, msg.ID); err != nil {
 			slog.Error("Failed to clean up attachments", "error", err, "message_id", msg.ID)
 		} else {
@@ -226,11 +978,138 @@ func (w *Worker) triggerSwitch(msg models.Message) {
 	}
 
 	if settings.OwnerEmail != "" && settings.SMTPHost != "" {
-		w.sendOwnerNotification(settings, msg, webhooks)
+		w.sendOwnerNotification(settings, msg, webhooks, failures)
+	}
+}
+
+// deliveryFailureReason explains a failed trigger email to the owner,
+// telling a rejection the server will repeat from one that outlasted the
+// retries.
+func deliveryFailureReason(err error) string {
+	var smtpErr *services.SMTPError
+	if errors.As(err, &smtpErr) && smtpErr.Permanent {
+		return fmt.Sprintf("%v. The mail server rejected it permanently (code %d), so it was not retried; check the recipient address and your SMTP settings.", err, smtpErr.Code)
+	}
+	return fmt.Sprintf("%v. The failure persisted through every retry; check that your SMTP server is reachable.", err)
+}
+
+// handleUndeliverable deals with a due switch whose owner has neither SMTP
+// nor an enabled webhook, so triggering it would deliver nothing. By default
+// it stays active and is retried every tick, logged once an hour; with
+// UNDELIVERABLE_ACTION=error it moves to the error status instead. The owner
+// is alerted over ntfy, when configured, once each time the switch comes due.
+func (w *Worker) handleUndeliverable(settings models.Settings, msg models.Message) {
+	state := w.undeliverable[msg.ID]
+	defer func() { w.undeliverable[msg.ID] = state }()
+
+	if w.cfg.Worker.UndeliverableAction == common.UndeliverableActionError {
+		var marked int64
+		if err := database.RetryOnLock(func() error {
+			result := unchangedSinceLoad(msg).
+				Update("status", models.StatusError)
+			marked = result.RowsAffected
+			return result.Error
+		}); err != nil {
+			slog.Error("Failed to mark switch undeliverable", "error", err, "message_id", msg.ID)
+			return
+		}
+		if marked > 0 && msg.Status != models.StatusError {
+			if err := services.RecordStatusTransition(database.DB, msg, msg.Status, models.StatusError, models.TransitionReasonUndeliverable, models.TransitionActorWorker); err != nil {
+				slog.Error("Failed to record status transition", "error", err, "message_id", msg.ID)
+			}
+		}
+		slog.Error("Switch came due with no delivery channel configured; moved to error status", "message_id", msg.ID, "user_id", msg.UserID)
+	} else if time.Since(state.loggedAt) >= undeliverableLogInterval {
+		slog.Error("Switch came due with no delivery channel configured; holding it until SMTP or a webhook is set up", "message_id", msg.ID, "user_id", msg.UserID)
+		state.loggedAt = time.Now()
 	}
+	w.recordError("heartbeats", msg.ID, errors.New("no delivery channel configured"))
+
+	if state.alerted || settings.NtfyURL == "" {
+		return
+	}
+	body := "A scheduled message is due but cannot be delivered because neither SMTP nor a webhook is configured. Configure a delivery channel in Aeterna settings."
+	if err := w.ntfy.Send(settings.NtfyURL, "Aeterna message undeliverable", body, services.NtfyPriorityUrgent, ""); err != nil {
+		slog.Error("Failed to send undeliverable alert", "error", err, "message_id", msg.ID)
+		return
+	}
+	state.alerted = true
+}
+
+// forgetUndeliverable drops the held switches that are not among due, which
+// the owner has checked in on or deleted since.
+func (w *Worker) forgetUndeliverable(due []models.Message) {
+	if len(w.undeliverable) == 0 {
+		return
+	}
+	stillDue := make(map[string]bool, len(due))
+	for _, msg := range due {
+		stillDue[msg.ID] = true
+	}
+	for id := range w.undeliverable {
+		if !stillDue[id] {
+			delete(w.undeliverable, id)
+		}
+	}
+}
+
+// deliveryBatch is a set of recipients that receive the same attachments.
+type deliveryBatch struct {
+	recipients  []string
+	attachments []services.EmailAttachment
+}
+
+// batchByAttachmentSelection groups recipients by the attachments selected for
+// them. Without any per-recipient selection every recipient shares one batch
+// carrying every attachment, so the message goes out as a single email.
+func batchByAttachmentSelection(recipientEmail string, attachments []models.Attachment, files []services.EmailAttachment) []deliveryBatch {
+	recipients := services.ParseRecipientEmails(recipientEmail)
+	if len(recipients) == 0 {
+		recipients = []string{recipientEmail}
+	}
+
+	restricted := false
+	for _, att := range attachments {
+		if strings.TrimSpace(att.RecipientEmail) != "" {
+			restricted = true
+			break
+		}
+	}
+	if !restricted {
+		return []deliveryBatch{{recipients: recipients, attachments: files}}
+	}
+
+	var batches []deliveryBatch
+	index := make(map[string]int)
+	for _, recipient := range recipients {
+		var key strings.Builder
+		var selected []services.EmailAttachment
+		for i, att := range attachments {
+			if att.DeliversTo(recipient) {
+				fmt.Fprintf(&key, "%d,", i)
+				selected = append(selected, files[i])
+			}
+		}
+		if pos, ok := index[key.String()]; ok {
+			batches[pos].recipients = append(batches[pos].recipients, recipient)
+			continue
+		}
+		index[key.String()] = len(batches)
+		batches = append(batches, deliveryBatch{recipients: []string{recipient}, attachments: selected})
+	}
+	return batches
 }
 
-func (w *Worker) sendOwnerNotification(settings models.Settings, msg models.Message, webhooks []models.Webhook) {
+func deliveredAt(msg models.Message) time.Time {
+	if msg.TriggeredAt != nil {
+		return *msg.TriggeredAt
+	}
+	return time.Now()
+}
+
+// sendOwnerNotification tells the owner a switch fired. failures lists the
+// deliveries that did not go out, with the reason for each.
+func (w *Worker) sendOwnerNotification(settings models.Settings, msg models.Message, webhooks []models.Webhook, failures []string) {
 	webhookInfo := ""
 	if len(webhooks) > 0 {
 		webhookInfo = "\n\nTriggered Webhooks:\n"
@@ -239,14 +1118,32 @@ func (w *Worker) sendOwnerNotification(settings models.Settings, msg models.Mess
 		}
 	}
 
-	subject := "Message delivered"
-	body := fmt.Sprintf(`Your scheduled message has been delivered as planned.
-
-Recipient: %s%s
+	contentInfo := ""
+	if settings.IncludeContentInOwnerNotification && msg.Content != "" {
+		content, err := w.crypto.DecryptWithContext(msg.Content, services.MessageContentContext(msg.ID))
+		if err != nil {
+			slog.Error("Failed to decrypt content for owner notification", "error", err, "message_id", msg.ID)
+		} else {
+			contentInfo = "\n\nDelivered Content:\n\n" + content
+		}
+	}
 
----
+	subject := "Message delivered"
+	intro := "Your scheduled message has been delivered as planned."
+	failureInfo := ""
+	if len(failures) > 0 {
+		subject = "Message delivery failed"
+		intro = "Your scheduled message was triggered, but not every delivery succeeded."
+		failureInfo = "\n\nFailed deliveries:\n"
+		for _, failure := range failures {
+			failureInfo += "- " + failure + "\n"
+		}
+	}
+	body := fmt.Sprintf(`%s
 
-Sent by Aeterna`, formatRecipients(msg.RecipientEmail), webhookInfo)
+Recipient: %s
+Delivered at: %s%s%s%s`, intro, formatRecipients(msg.RecipientEmail), services.FormatOwnerTime(settings, deliveredAt(msg)), failureInfo, webhookInfo, contentInfo)
+	body = services.AppendEmailFooter(settings, body)
 
 	err := w.email.SendPlain(settings, []string{settings.OwnerEmail}, subject, body)
 	if err != nil {
@@ -278,7 +1175,9 @@ func (w *Worker) checkFarewellLetters() {
 		if letter.UserID == "" {
 			continue
 		}
-		w.sendFarewellLetter(letter)
+		if err := runRecovered(func() { w.sendFarewellLetter(letter) }, "letter_id", letter.ID); err != nil {
+			w.recordError("farewell_letters", "", err)
+		}
 	}
 }
 
@@ -289,7 +1188,7 @@ func (w *Worker) sendFarewellLetter(letter models.FarewellLetter) {
 		return
 	}
 
-	decryptedSafeMarkdown, err := services.CryptoService{}.Decrypt(letter.Content)
+	decryptedSafeMarkdown, err := services.CryptoService{}.DecryptWithContext(letter.Content, services.FarewellLetterContext(letter.ID, services.FarewellContentColumn))
 	if err != nil {
 		slog.Error("Failed to decrypt farewell letter content", "letter_id", letter.ID, "error", err)
 		return
@@ -297,7 +1196,7 @@ func (w *Worker) sendFarewellLetter(letter models.FarewellLetter) {
 
 	var renderedHTML string
 	if strings.TrimSpace(letter.RenderedHTML) != "" {
-		decryptedHTML, htmlErr := services.CryptoService{}.Decrypt(letter.RenderedHTML)
+		decryptedHTML, htmlErr := services.CryptoService{}.DecryptWithContext(letter.RenderedHTML, services.FarewellLetterContext(letter.ID, services.FarewellRenderedHTMLColumn))
 		if htmlErr != nil {
 			slog.Warn("Failed to decrypt pre-rendered farewell HTML, using markdown fallback", "letter_id", letter.ID, "error", htmlErr)
 		} else {
@@ -333,6 +1232,7 @@ func (w *Worker) sendFarewellLetter(letter models.FarewellLetter) {
 		emailAttachments,
 	); err != nil {
 		slog.Error("Failed to send farewell letter", "letter_id", letter.ID, "recipient", letter.RecipientEmail, "error", err)
+		w.recordError("farewell_letters", "", err)
 		return
 	}
 
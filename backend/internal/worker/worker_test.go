@@ -0,0 +1,1065 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpyxn/aeterna/backend/internal/config"
+	"github.com/alpyxn/aeterna/backend/internal/database"
+	"github.com/alpyxn/aeterna/backend/internal/models"
+	"github.com/alpyxn/aeterna/backend/internal/ports"
+	"github.com/alpyxn/aeterna/backend/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	replacer := strings.NewReplacer("/", "_", " ", "_")
+	dsn := fmt.Sprintf("file:%s_%d?mode=memory&cache=shared&_foreign_keys=1", replacer.Replace(t.Name()), time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(
+		&models.Message{},
+		&models.MessageReminder{},
+		&models.Settings{},
+		&models.Attachment{},
+		&models.StatusTransition{},
+		&models.DeliveredAttachment{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	prev := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = prev })
+	return db
+}
+
+type fakeSettings struct {
+	ports.SettingsServicePort
+	settings models.Settings
+}
+
+func (f fakeSettings) Get(userID string) (models.Settings, error) {
+	out := f.settings
+	out.UserID = userID
+	return out, nil
+}
+
+// accountSettings serves each account its own settings.
+type accountSettings struct {
+	ports.SettingsServicePort
+	byUser map[string]models.Settings
+}
+
+func (f accountSettings) Get(userID string) (models.Settings, error) {
+	out := f.byUser[userID]
+	out.UserID = userID
+	return out, nil
+}
+
+type fakeWebhookStore struct {
+	ports.WebhookStorePort
+}
+
+func (fakeWebhookStore) ListEnabledForUser(string) ([]models.Webhook, error) {
+	return nil, nil
+}
+
+type fakeFiles struct {
+	ports.FileServicePort
+}
+
+func (fakeFiles) ListByMessageID(string, string) ([]models.Attachment, error) {
+	return nil, nil
+}
+
+type sentMail struct {
+	settings   models.Settings
+	recipients []string
+	subject    string
+	body       string
+}
+
+type fakeMailer struct {
+	plain     []sentMail
+	triggered []models.Message
+	// triggerErr, when set, fails every SendTriggeredMessage.
+	triggerErr error
+}
+
+func (f *fakeMailer) SendPlain(settings models.Settings, recipients []string, subject, body string) error {
+	f.plain = append(f.plain, sentMail{settings: settings, recipients: recipients, subject: subject, body: body})
+	return nil
+}
+
+func (f *fakeMailer) SendTriggeredMessage(_ models.Settings, msg models.Message, _ []services.EmailAttachment) error {
+	f.triggered = append(f.triggered, msg)
+	return f.triggerErr
+}
+
+func (f *fakeMailer) SendFarewellLetterPreRendered(models.Settings, string, string, string, string, []services.EmailAttachment) error {
+	return nil
+}
+
+func newTestWorker(mail *fakeMailer) *Worker {
+	w := New(
+		fakeSettings{settings: models.Settings{SMTPHost: "smtp.example.com", OwnerEmail: "owner@example.com", HeartbeatToken: "hb"}},
+		fakeWebhookStore{},
+		fakeFiles{},
+		nil,
+		services.MessageService{},
+		config.Config{Worker: config.WorkerConfig{BaseURL: "https://aeterna.example.com"}},
+	)
+	w.email = mail
+	return w
+}
+
+func createMessage(t *testing.T, db *gorm.DB, id string, lastSeen time.Time) {
+	t.Helper()
+	if err := db.Create(&models.Message{
+		ID: id, UserID: "u1", Content: "", KeyFragment: "v1",
+		ManagementToken: "tok-" + id, RecipientEmail: "friend@example.com",
+		TriggerDuration: 60, LastSeen: lastSeen, CreatedAt: lastSeen, Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// initTestKeyManager loads a fresh encryption key; the key manager is
+// process-wide, so only the first call in the package takes effect.
+func initTestKeyManager(t *testing.T) {
+	t.Helper()
+	key, err := services.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "enc.key")
+	if err := os.WriteFile(keyPath, []byte(key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	services.InitKeyManager(keyPath)
+}
+
+func TestCheckHeartbeatsTriggersOnlyDueMessages(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+	createMessage(t, db, "on-time", time.Now().Add(-10*time.Minute))
+
+	mail := &fakeMailer{}
+	newTestWorker(mail).checkHeartbeats()
+
+	if len(mail.triggered) != 1 || mail.triggered[0].ID != "due" || mail.triggered[0].RecipientEmail != "friend@example.com" {
+		t.Fatalf("expected only the due message to be delivered, got %+v", mail.triggered)
+	}
+	if len(mail.plain) != 1 || mail.plain[0].recipients[0] != "owner@example.com" || mail.plain[0].subject != "Message delivered" {
+		t.Fatalf("expected one owner delivery notification, got %+v", mail.plain)
+	}
+
+	var due, onTime models.Message
+	if err := db.First(&due, "id = ?", "due").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&onTime, "id = ?", "on-time").Error; err != nil {
+		t.Fatal(err)
+	}
+	if due.Status != models.StatusTriggered || due.TriggeredAt == nil {
+		t.Fatalf("due message should be marked triggered, got %s", due.Status)
+	}
+	if onTime.Status != models.StatusActive {
+		t.Fatalf("on-time message must stay active, got %s", onTime.Status)
+	}
+}
+
+func TestCheckHeartbeatsHoldsDeliveryUntilWindowOpens(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "windowed", time.Now().Add(-2*time.Hour))
+	hour := time.Now().UTC().Hour()
+	closed := models.DeliveryWindow{StartHour: (hour + 2) % 24, EndHour: (hour + 3) % 24, Timezone: "UTC"}
+	if err := db.Model(&models.Message{}).Where("id = ?", "windowed").Updates(map[string]any{
+		"delivery_window_start_hour": closed.StartHour,
+		"delivery_window_end_hour":   closed.EndHour,
+		"delivery_window_timezone":   closed.Timezone,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.checkHeartbeats()
+
+	if len(mail.triggered) != 0 {
+		t.Fatalf("expected delivery to wait for the window, got %+v", mail.triggered)
+	}
+	var held models.Message
+	if err := db.First(&held, "id = ?", "windowed").Error; err != nil {
+		t.Fatal(err)
+	}
+	want := closed.NextOpen(time.Now(), time.UTC)
+	if held.Status != models.StatusActive || held.DeliveryHeldUntil == nil || !held.DeliveryHeldUntil.Equal(want) {
+		t.Fatalf("expected the switch to stay active and be held until %s, got status=%s held_until=%v", want, held.Status, held.DeliveryHeldUntil)
+	}
+
+	if err := db.Model(&models.Message{}).Where("id = ?", "windowed").Updates(map[string]any{
+		"delivery_window_start_hour": hour,
+		"delivery_window_end_hour":   (hour + 1) % 24,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkHeartbeats()
+
+	if len(mail.triggered) != 1 || mail.triggered[0].ID != "windowed" {
+		t.Fatalf("expected delivery once the window is open, got %+v", mail.triggered)
+	}
+}
+
+func TestExtendedDeadlineHoldsTriggerAndReminders(t *testing.T) {
+	db := setupTestDB(t)
+	// Overdue by last_seen, but extended to 30 minutes from now.
+	createMessage(t, db, "m1", time.Now().Add(-2*time.Hour))
+	if err := db.Model(&models.Message{}).Where("id = ?", "m1").Update("extended_until", time.Now().UTC().Add(30*time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.MessageReminder{MessageID: "m1", MinutesBefore: 15, Channel: models.ReminderChannelEmail}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.checkHeartbeats()
+	w.checkReminders()
+	if len(mail.triggered) != 0 || len(mail.plain) != 0 {
+		t.Fatalf("expected an extended switch to neither trigger nor remind yet, got %d triggered, %d reminders", len(mail.triggered), len(mail.plain))
+	}
+
+	if err := db.Model(&models.Message{}).Where("id = ?", "m1").Update("extended_until", time.Now().UTC().Add(10*time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkReminders()
+	if len(mail.plain) != 1 {
+		t.Fatalf("expected the reminder once within 15 minutes of the extended deadline, got %d", len(mail.plain))
+	}
+
+	if err := db.Model(&models.Message{}).Where("id = ?", "m1").Update("extended_until", time.Now().UTC().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkHeartbeats()
+	if len(mail.triggered) != 1 {
+		t.Fatalf("expected the switch to trigger once the extension passed, got %+v", mail.triggered)
+	}
+}
+
+func TestCheckHeartbeatsWaitsForCreationGrace(t *testing.T) {
+	db := setupTestDB(t)
+	created := time.Now().Add(-60*time.Minute - 10*time.Second)
+	createMessage(t, db, "new", created)
+	// LastSeen stored a little before CreatedAt, as second rounding can do,
+	// already makes the switch due.
+	if err := db.Model(&models.Message{}).Where("id = ?", "new").Update("last_seen", created.Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.cfg.Worker.CreationGraceSeconds = 60
+	w.checkHeartbeats()
+
+	if len(mail.triggered) != 0 {
+		t.Fatalf("expected a new switch to wait out the creation grace, got %+v", mail.triggered)
+	}
+
+	w.cfg.Worker.CreationGraceSeconds = 0
+	w.checkHeartbeats()
+
+	if len(mail.triggered) != 1 || mail.triggered[0].ID != "new" {
+		t.Fatalf("expected delivery once the grace has passed, got %+v", mail.triggered)
+	}
+}
+
+func TestStatusRecordsRunsAndLastError(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "ok", time.Now().Add(-2*time.Hour))
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.runTick()
+
+	status := w.Status()
+	if status.LastRunAt == nil || status.LastTickAt == nil {
+		t.Fatalf("expected the tick to be recorded, got %+v", status)
+	}
+	if status.MessagesProcessed != 1 || status.LastError != nil {
+		t.Fatalf("expected one clean trigger, got processed=%d last_error=%+v", status.MessagesProcessed, status.LastError)
+	}
+
+	createMessage(t, db, "bounced", time.Now().Add(-2*time.Hour))
+	mail.triggerErr = errors.New("550 mailbox unavailable")
+	w.checkHeartbeats()
+
+	status = w.Status()
+	if status.MessagesProcessed != 2 {
+		t.Fatalf("messages_processed = %d, want 2", status.MessagesProcessed)
+	}
+	if status.LastError == nil || status.LastError.MessageID != "bounced" || status.LastError.Check != "heartbeats" ||
+		!strings.Contains(status.LastError.Error, "550 mailbox unavailable") {
+		t.Fatalf("expected the failed trigger email as last error, got %+v", status.LastError)
+	}
+}
+
+func TestMaintenanceModePausesTheWorker(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.cfg.App.MaintenanceMode = true
+	w.runTick()
+
+	if len(mail.triggered) != 0 || len(mail.plain) != 0 {
+		t.Fatalf("nothing may be sent in maintenance, got %+v / %+v", mail.triggered, mail.plain)
+	}
+	var msg models.Message
+	if err := db.First(&msg, "id = ?", "due").Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.Status != models.StatusActive {
+		t.Fatalf("status = %s, want the switch left active", msg.Status)
+	}
+	status := w.Status()
+	if !status.Maintenance || status.LastRunAt == nil || status.LastTickAt != nil {
+		t.Fatalf("expected a run but no completed tick in maintenance, got %+v", status)
+	}
+}
+
+func TestCheckRemindersSendsEachAccountWithItsOwnSMTPLogin(t *testing.T) {
+	db := setupTestDB(t)
+	// Two accounts on one relay with the same username; one batch of
+	// reminders must not carry either account's mail over the other's login.
+	for _, id := range []string{"m1", "m2"} {
+		createMessage(t, db, id, time.Now().Add(-50*time.Minute))
+		if err := db.Create(&models.MessageReminder{MessageID: id, MinutesBefore: 15, Channel: models.ReminderChannelEmail}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Model(&models.Message{}).Where("id = ?", "m2").Update("user_id", "u2").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	shared := models.Settings{SMTPHost: "smtp.example.com", SMTPPort: "587", SMTPUser: "shared", HeartbeatToken: "hb"}
+	first, second := shared, shared
+	first.SMTPPass, first.OwnerEmail = "one", "one@example.com"
+	second.SMTPPass, second.OwnerEmail = "two", "two@example.com"
+	w.settings = accountSettings{byUser: map[string]models.Settings{"u1": first, "u2": second}}
+	w.checkReminders()
+
+	if len(mail.plain) != 2 {
+		t.Fatalf("expected one reminder per account, got %d", len(mail.plain))
+	}
+	for _, sent := range mail.plain {
+		want := map[string]string{"u1": "one", "u2": "two"}[sent.settings.UserID]
+		if want == "" || sent.settings.SMTPPass != want || sent.recipients[0] != sent.settings.OwnerEmail {
+			t.Fatalf("reminder for %s sent with the wrong login: %+v", sent.settings.UserID, sent)
+		}
+	}
+}
+
+func TestCheckRemindersSendsDueReminderOnce(t *testing.T) {
+	db := setupTestDB(t)
+	// 50 minutes into a 60-minute switch: a 15-minute reminder is due, a
+	// 5-minute one is not.
+	createMessage(t, db, "m1", time.Now().Add(-50*time.Minute))
+	for _, minutes := range []int{15, 5} {
+		if err := db.Create(&models.MessageReminder{MessageID: "m1", MinutesBefore: minutes, Channel: models.ReminderChannelEmail}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.checkReminders()
+	w.checkReminders()
+
+	if len(mail.plain) != 1 {
+		t.Fatalf("expected one reminder email across two ticks, got %d", len(mail.plain))
+	}
+	if mail.plain[0].subject != "Check-in required" || !strings.Contains(mail.plain[0].body, "https://aeterna.example.com/api/quick-heartbeat/hb") {
+		t.Fatalf("unexpected reminder email: %+v", mail.plain[0])
+	}
+	if strings.Contains(mail.plain[0].body, "action=") {
+		t.Fatalf("action links are opt-in: %s", mail.plain[0].body)
+	}
+
+	var reminders []models.MessageReminder
+	if err := db.Order("minutes_before DESC").Find(&reminders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reminders[0].Sent || reminders[1].Sent {
+		t.Fatalf("only the 15-minute reminder should be marked sent: %+v", reminders)
+	}
+}
+
+func TestCheckRemindersSendsCreationReminderOnce(t *testing.T) {
+	db := setupTestDB(t)
+	// A year-long switch created two days ago, checked in an hour ago: the
+	// one-day creation reminder is due, the pre-trigger one is not.
+	created := time.Now().Add(-48 * time.Hour)
+	if err := db.Create(&models.Message{
+		ID: "m1", UserID: "u1", Content: "", KeyFragment: "v1",
+		ManagementToken: "tok-m1", RecipientEmail: "friend@example.com",
+		TriggerDuration: 365 * 24 * 60, LastSeen: time.Now().Add(-time.Hour), CreatedAt: created, Status: models.StatusActive,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, reminder := range []models.MessageReminder{
+		{MessageID: "m1", RelativeTo: models.ReminderRelativeToCreation, MinutesAfter: 24 * 60},
+		{MessageID: "m1", MinutesBefore: 7 * 24 * 60},
+	} {
+		if err := db.Create(&reminder).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.cfg.Worker.ReminderResendHours = 1
+	w.checkReminders()
+	if err := db.Model(&models.MessageReminder{}).Where("message_id = ?", "m1").
+		Update("last_reminder_at", time.Now().UTC().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkReminders()
+
+	if len(mail.plain) != 1 {
+		t.Fatalf("expected one creation reminder across two ticks, got %+v", mail.plain)
+	}
+	if mail.plain[0].subject != "Is this scheduled message still wanted?" || !strings.Contains(mail.plain[0].body, "friend@example.com") {
+		t.Fatalf("unexpected creation reminder email: %+v", mail.plain[0])
+	}
+}
+
+func TestCheckRemindersResendsOnCadence(t *testing.T) {
+	db := setupTestDB(t)
+	// 20 hours into a 24-hour switch: both the 12-hour and 10-hour reminders
+	// are due.
+	createMessage(t, db, "m1", time.Now().Add(-20*time.Hour))
+	if err := db.Model(&models.Message{}).Where("id = ?", "m1").Update("trigger_duration", 24*60).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, minutes := range []int{12 * 60, 10 * 60} {
+		if err := db.Create(&models.MessageReminder{MessageID: "m1", MinutesBefore: minutes, Channel: models.ReminderChannelEmail}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.cfg.Worker.ReminderResendHours = 12
+	w.checkReminders()
+	if len(mail.plain) != 2 {
+		t.Fatalf("expected both first reminders, got %d", len(mail.plain))
+	}
+	w.checkReminders()
+	if len(mail.plain) != 2 {
+		t.Fatalf("nothing should be re-sent before the interval passes, got %d", len(mail.plain))
+	}
+
+	stale := time.Now().UTC().Add(-13 * time.Hour)
+	if err := db.Model(&models.MessageReminder{}).Where("message_id = ?", "m1").Update("last_reminder_at", stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkReminders()
+	w.checkReminders()
+	if len(mail.plain) != 3 {
+		t.Fatalf("expected exactly one re-send per interval, got %d emails", len(mail.plain))
+	}
+
+	var reminders []models.MessageReminder
+	if err := db.Order("minutes_before ASC").Find(&reminders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reminders[0].LastReminderAt == nil || !reminders[0].LastReminderAt.After(stale.Add(time.Hour)) {
+		t.Fatalf("the re-sent reminder should carry a fresh last_reminder_at: %+v", reminders[0])
+	}
+	if reminders[1].LastReminderAt == nil || reminders[1].LastReminderAt.After(stale.Add(time.Minute)) {
+		t.Fatalf("the earlier reminder should not have been re-sent: %+v", reminders[1])
+	}
+
+	w.cfg.Worker.ReminderResendHours = 0
+	if err := db.Model(&models.MessageReminder{}).Where("message_id = ?", "m1").Update("last_reminder_at", stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkReminders()
+	if len(mail.plain) != 3 {
+		t.Fatalf("reminders must not be re-sent when the interval is 0, got %d", len(mail.plain))
+	}
+}
+
+func TestCheckHeartbeatsWithoutDeliveryChannel(t *testing.T) {
+	for _, tc := range []struct {
+		action string
+		want   models.MessageStatus
+	}{
+		{action: "", want: models.StatusActive},
+		{action: "hold", want: models.StatusActive},
+		{action: "error", want: models.StatusError},
+		{action: "trigger", want: models.StatusTriggered},
+	} {
+		t.Run("action="+tc.action, func(t *testing.T) {
+			db := setupTestDB(t)
+			createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+
+			mail := &fakeMailer{}
+			w := newTestWorker(mail)
+			w.settings = fakeSettings{settings: models.Settings{OwnerEmail: "owner@example.com"}}
+			w.cfg.Worker.UndeliverableAction = tc.action
+			w.checkHeartbeats()
+
+			var msg models.Message
+			if err := db.First(&msg, "id = ?", "due").Error; err != nil {
+				t.Fatal(err)
+			}
+			if msg.Status != tc.want {
+				t.Fatalf("status = %s, want %s", msg.Status, tc.want)
+			}
+			if len(mail.triggered) != 0 {
+				t.Fatalf("nothing can be emailed without SMTP, got %+v", mail.triggered)
+			}
+
+			var transitions []models.StatusTransition
+			if err := db.Where("message_id = ?", "due").Find(&transitions).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tc.want == models.StatusActive {
+				if len(transitions) != 0 {
+					t.Fatalf("a held switch records no transition, got %+v", transitions)
+				}
+				return
+			}
+			if len(transitions) != 1 || transitions[0].FromStatus != models.StatusActive || transitions[0].ToStatus != tc.want ||
+				transitions[0].Actor != models.TransitionActorWorker || transitions[0].UserID != "u1" {
+				t.Fatalf("unexpected transitions: %+v", transitions)
+			}
+		})
+	}
+}
+
+func TestCheckHeartbeatsAlertsUndeliverableOncePerDueCycle(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+
+	push := &fakeNtfy{}
+	w := newTestWorker(&fakeMailer{})
+	w.ntfy = push
+	w.settings = fakeSettings{settings: models.Settings{OwnerEmail: "owner@example.com", NtfyURL: "https://ntfy.sh/aeterna-owner"}}
+	w.checkHeartbeats()
+	w.checkHeartbeats()
+	if len(push.pushes) != 1 {
+		t.Fatalf("expected one alert across two ticks, got %d", len(push.pushes))
+	}
+
+	// A check-in takes the switch out of the due set and forgets it.
+	if err := db.Model(&models.Message{}).Where("id = ?", "due").Update("last_seen", time.Now().UTC()).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkHeartbeats()
+	if len(w.undeliverable) != 0 {
+		t.Fatalf("a checked-in switch should be forgotten, got %+v", w.undeliverable)
+	}
+
+	if err := db.Model(&models.Message{}).Where("id = ?", "due").Update("last_seen", time.Now().UTC().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkHeartbeats()
+	if len(push.pushes) != 2 {
+		t.Fatalf("coming due again should alert again, got %d alerts", len(push.pushes))
+	}
+
+	if err := db.Delete(&models.Message{}, "id = ?", "due").Error; err != nil {
+		t.Fatal(err)
+	}
+	w.checkHeartbeats()
+	if len(w.undeliverable) != 0 {
+		t.Fatalf("a deleted switch should be forgotten, got %+v", w.undeliverable)
+	}
+}
+
+func TestSimulateChangesNothing(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+	for _, minutes := range []int{15, 5} {
+		if err := db.Create(&models.MessageReminder{MessageID: "due", MinutesBefore: minutes, Channel: models.ReminderChannelEmail}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	sim, err := w.Simulate("u1", "due")
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if !sim.Due || sim.Action != "trigger" || !sim.Channels.Email || sim.Channels.Ntfy {
+		t.Fatalf("unexpected simulation: %+v", sim)
+	}
+	if len(sim.Reminders) != 2 || sim.Reminders[0].Final == sim.Reminders[1].Final {
+		t.Fatalf("expected both reminders, exactly one of them final: %+v", sim.Reminders)
+	}
+	if len(mail.plain) != 0 || len(mail.triggered) != 0 {
+		t.Fatalf("a simulation must not send anything: %+v", mail)
+	}
+	var msg models.Message
+	if err := db.First(&msg, "id = ?", "due").Error; err != nil {
+		t.Fatal(err)
+	}
+	var sent int64
+	db.Model(&models.MessageReminder{}).Where("sent = ?", true).Count(&sent)
+	if msg.Status != models.StatusActive || sent != 0 {
+		t.Fatalf("a simulation must not change state: status %s, %d reminders sent", msg.Status, sent)
+	}
+
+	w.settings = fakeSettings{}
+	if sim, err := w.Simulate("u1", "due"); err != nil || sim.Action != "hold" {
+		t.Fatalf("without a delivery channel the switch would be held, got %q (%v)", sim.Action, err)
+	}
+	if _, err := w.Simulate("someone-else", "due"); err == nil {
+		t.Fatal("another user's switch must not be simulated")
+	}
+}
+
+func TestCheckContentShreddingClearsDeliveredContentPastRetention(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.FarewellLetter{}, &models.FarewellAttachment{}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	for id, triggeredAt := range map[string]time.Time{"old": now.Add(-3 * 24 * time.Hour), "recent": now.Add(-time.Hour)} {
+		createMessage(t, db, id, triggeredAt)
+		if err := db.Model(&models.Message{}).Where("id = ?", id).Updates(map[string]any{
+			"status": models.StatusTriggered, "triggered_at": triggeredAt, "encrypted_content": "ciphertext",
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	createMessage(t, db, "active", now.Add(-30*24*time.Hour))
+	if err := db.Model(&models.Message{}).Where("id = ?", "active").Update("encrypted_content", "ciphertext").Error; err != nil {
+		t.Fatal(err)
+	}
+	for id, messageID := range map[string]string{"letter-old": "old", "letter-recent": "recent"} {
+		if err := db.Create(&models.FarewellLetter{
+			ID: id, UserID: "u1", MessageID: messageID, RecipientEmail: "friend@example.com", Subject: "Bye",
+			Content: "ciphertext", RawContent: "raw ciphertext", RenderedHTML: "html ciphertext", Status: models.FarewellStatusSent,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var deleted []string
+	w := newTestWorker(&fakeMailer{})
+	w.files = attachmentFiles{deleted: &deleted}
+	w.checkContentShredding()
+	var untouched models.Message
+	if err := db.First(&untouched, "id = ?", "old").Error; err != nil {
+		t.Fatal(err)
+	}
+	if untouched.ShreddedAt != nil || untouched.Content != "ciphertext" {
+		t.Fatalf("expected nothing shredded while SHRED_AFTER_DELIVERY is off, got %+v", untouched)
+	}
+
+	w.cfg.Worker.ShredAfterDelivery = true
+	w.cfg.Worker.ContentRetentionDays = 1
+	w.checkContentShredding()
+
+	var msgs []models.Message
+	if err := db.Order("id").Find(&msgs).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range msgs {
+		shredded := msg.ShreddedAt != nil
+		if want := msg.ID == "old"; shredded != want {
+			t.Fatalf("message %s: shredded = %v, want %v", msg.ID, shredded, want)
+		}
+		if shredded && (msg.Content != "" || msg.KeyFragment != "" || msg.RecipientEmail == "" || msg.Status != models.StatusTriggered) {
+			t.Fatalf("expected only content to be cleared, got %+v", msg)
+		}
+		if !shredded && msg.Content != "ciphertext" {
+			t.Fatalf("message %s lost its content: %+v", msg.ID, msg)
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != "old" {
+		t.Fatalf("expected only the shredded switch's retained attachments deleted, got %v", deleted)
+	}
+	var letters []models.FarewellLetter
+	if err := db.Order("id").Find(&letters).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, letter := range letters {
+		cleared := letter.Content == "" && letter.RawContent == "" && letter.RenderedHTML == ""
+		if want := letter.MessageID == "old"; cleared != want {
+			t.Fatalf("farewell letter %s: content cleared = %v, want %v (%+v)", letter.ID, cleared, want, letter)
+		}
+	}
+}
+
+func TestCheckContentShreddingWaitsForPendingFarewellLetters(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.FarewellLetter{}, &models.FarewellAttachment{}); err != nil {
+		t.Fatal(err)
+	}
+	triggeredAt := time.Now().UTC().Add(-3 * 24 * time.Hour)
+	createMessage(t, db, "old", triggeredAt)
+	if err := db.Model(&models.Message{}).Where("id = ?", "old").Updates(map[string]any{
+		"status": models.StatusTriggered, "triggered_at": triggeredAt, "encrypted_content": "ciphertext",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.FarewellLetter{
+		ID: "letter", UserID: "u1", MessageID: "old", RecipientEmail: "friend@example.com", Subject: "Bye",
+		Content: "ciphertext", DelayMinutes: 7 * 24 * 60, Status: models.FarewellStatusPending,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted []string
+	w := newTestWorker(&fakeMailer{})
+	w.files = attachmentFiles{deleted: &deleted}
+	w.cfg.Worker.ShredAfterDelivery = true
+	w.cfg.Worker.ContentRetentionDays = 1
+	w.checkContentShredding()
+
+	var msg models.Message
+	var letter models.FarewellLetter
+	if err := db.First(&msg, "id = ?", "old").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&letter, "id = ?", "letter").Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.ShreddedAt != nil || letter.Content != "ciphertext" || len(deleted) != 0 {
+		t.Fatalf("expected nothing shredded while a farewell letter is pending: %+v, %+v, %v", msg, letter, deleted)
+	}
+}
+
+func TestCheckRemindersSendsOneDigestPerOwner(t *testing.T) {
+	db := setupTestDB(t)
+	for _, id := range []string{"m1", "m2", "m3"} {
+		createMessage(t, db, id, time.Now().Add(-50*time.Minute))
+		if err := db.Create(&models.MessageReminder{MessageID: id, MinutesBefore: 15, Channel: models.ReminderChannelEmail}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.settings = fakeSettings{settings: models.Settings{SMTPHost: "smtp.example.com", OwnerEmail: "owner@example.com", HeartbeatToken: "hb", ReminderDigest: true}}
+	w.checkReminders()
+	w.checkReminders()
+
+	if len(mail.plain) != 1 {
+		t.Fatalf("expected a single digest across two ticks, got %+v", mail.plain)
+	}
+	digest := mail.plain[0]
+	if digest.subject != "Final check-in required: 3 messages need your check-in" {
+		t.Fatalf("subject = %q", digest.subject)
+	}
+	if strings.Count(digest.body, "friend@example.com") != 3 || strings.Count(digest.body, "/api/quick-heartbeat/hb") != 1 {
+		t.Fatalf("digest should list every message with one check-in link: %s", digest.body)
+	}
+
+	var unsent int64
+	if err := db.Model(&models.MessageReminder{}).Where("sent = ?", false).Count(&unsent).Error; err != nil {
+		t.Fatal(err)
+	}
+	if unsent != 0 {
+		t.Fatalf("%d reminders left unsent after the digest", unsent)
+	}
+}
+
+func TestReminderEmailOffersActionLinks(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "m1", time.Now().Add(-50*time.Minute))
+	if err := db.Create(&models.MessageReminder{MessageID: "m1", MinutesBefore: 15, Channel: models.ReminderChannelEmail}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.settings = fakeSettings{settings: models.Settings{SMTPHost: "smtp.example.com", OwnerEmail: "owner@example.com", HeartbeatToken: "hb", ReminderActions: true}}
+	w.checkReminders()
+
+	if len(mail.plain) != 1 {
+		t.Fatalf("expected one reminder email, got %d", len(mail.plain))
+	}
+	body := mail.plain[0].body
+	for _, want := range []string{
+		"Extend by 7 days: https://aeterna.example.com/api/quick-heartbeat/hb?action=extend&message=m1",
+		"Snooze 1 day: https://aeterna.example.com/api/quick-heartbeat/hb?action=snooze&message=m1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("reminder is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+type attachmentFiles struct {
+	ports.FileServicePort
+	attachments []models.Attachment
+	deleted     *[]string
+	// unreadable names an attachment that fails to decrypt.
+	unreadable string
+}
+
+func (f attachmentFiles) ListByMessageID(string, string) ([]models.Attachment, error) {
+	return f.attachments, nil
+}
+
+func (f attachmentFiles) GetDecrypted(_, attachmentID string) (string, string, []byte, error) {
+	if attachmentID == f.unreadable {
+		return "", "", nil, errors.New("authentication failed")
+	}
+	for _, att := range f.attachments {
+		if att.ID == attachmentID {
+			return att.Filename, att.MimeType, []byte("data"), nil
+		}
+	}
+	return "", "", nil, errors.New("not found")
+}
+
+func (f attachmentFiles) DeleteByMessageID(_, messageID string) error {
+	*f.deleted = append(*f.deleted, messageID)
+	return nil
+}
+
+func TestTriggerRecordsDeliveredAttachmentsBeforeCleanup(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+
+	var deleted []string
+	w := newTestWorker(&fakeMailer{})
+	w.files = attachmentFiles{
+		attachments: []models.Attachment{
+			{ID: "a1", MessageID: "due", Filename: "will.pdf", Size: 2048, MimeType: "application/pdf"},
+			{ID: "a2", MessageID: "due", Filename: "private.txt", Size: 12, MimeType: "text/plain", RecipientEmail: "friend@example.com"},
+			{ID: "a3", MessageID: "due", Filename: "photos.zip", Size: 4096, MimeType: "application/zip"},
+		},
+		deleted:    &deleted,
+		unreadable: "a3",
+	}
+	w.checkHeartbeats()
+
+	if len(deleted) != 1 || deleted[0] != "due" {
+		t.Fatalf("expected the files to be cleaned up after delivery, got %v", deleted)
+	}
+	var records []models.DeliveredAttachment
+	if err := db.Order("id ASC").Find(&records, "message_id = ?", "due").Error; err != nil {
+		t.Fatal(err)
+	}
+	// The file that could not be decrypted is deleted with the others, so it
+	// is recorded too: recipients should still learn that it existed.
+	if len(records) != 3 || records[0].Filename != "will.pdf" || records[0].Size != 2048 || records[0].Restricted || !records[1].Restricted || records[2].Filename != "photos.zip" {
+		t.Fatalf("expected every file recorded with its name and size, got %+v", records)
+	}
+}
+
+func TestOwnerNotificationExplainsFailedDelivery(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+
+	mail := &fakeMailer{triggerErr: &services.SMTPError{Stage: "RCPT TO", Code: 550, Permanent: true, Err: errors.New("550 mailbox unavailable")}}
+	newTestWorker(mail).checkHeartbeats()
+
+	if len(mail.plain) != 1 || mail.plain[0].subject != "Message delivery failed" {
+		t.Fatalf("expected one failure notification, got %+v", mail.plain)
+	}
+	body := mail.plain[0].body
+	if !strings.Contains(body, "friend@example.com") || !strings.Contains(body, "550 mailbox unavailable") || !strings.Contains(body, "rejected it permanently") {
+		t.Fatalf("notification should name the recipient and the reason, got %q", body)
+	}
+}
+
+func TestOwnerNotificationIncludesContentWhenEnabled(t *testing.T) {
+	initTestKeyManager(t)
+	for _, include := range []bool{false, true} {
+		t.Run(fmt.Sprintf("include=%v", include), func(t *testing.T) {
+			db := setupTestDB(t)
+			createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+			content, err := services.CryptoService{}.EncryptWithContext("Dear friend, the keys are in the drawer.", services.MessageContentContext("due"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Model(&models.Message{}).Where("id = ?", "due").Update("encrypted_content", content).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			mail := &fakeMailer{}
+			w := newTestWorker(mail)
+			w.settings = fakeSettings{settings: models.Settings{SMTPHost: "smtp.example.com", OwnerEmail: "owner@example.com", IncludeContentInOwnerNotification: include}}
+			w.checkHeartbeats()
+
+			if len(mail.plain) != 1 || mail.plain[0].subject != "Message delivered" {
+				t.Fatalf("expected one owner notification, got %+v", mail.plain)
+			}
+			if got := strings.Contains(mail.plain[0].body, "Delivered Content:\n\nDear friend, the keys are in the drawer."); got != include {
+				t.Fatalf("content included = %v, want %v:\n%s", got, include, mail.plain[0].body)
+			}
+		})
+	}
+}
+
+func TestStartupGraceHoldsDueSwitchesAndAsksForCheckIn(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.ApplicationSettings{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.EnsureApplicationSettingsRow(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (services.ApplicationSettingsService{}).RecordWorkerTick(time.Now().Add(-3 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	createMessage(t, db, "due", time.Now().Add(-2*time.Hour))
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.cfg.Worker.StartupGraceMinutes = 30
+	w.beginStartupGrace()
+	if !w.inStartupGrace() {
+		t.Fatal("a three-hour gap since the last tick should open the startup grace")
+	}
+	w.checkHeartbeats()
+	w.checkHeartbeats()
+
+	if len(mail.triggered) != 0 {
+		t.Fatalf("nothing may be delivered during the grace, got %+v", mail.triggered)
+	}
+	if len(mail.plain) != 1 || mail.plain[0].subject != "Urgent: check-in required after server outage" ||
+		!strings.Contains(mail.plain[0].body, "https://aeterna.example.com/api/quick-heartbeat/hb") {
+		t.Fatalf("expected one post-outage check-in across two ticks, got %+v", mail.plain)
+	}
+
+	w.graceUntil = time.Now().Add(-time.Second)
+	w.checkHeartbeats()
+	if len(mail.triggered) != 1 || mail.triggered[0].ID != "due" {
+		t.Fatalf("the switch should be delivered once the grace ends, got %+v", mail.triggered)
+	}
+}
+
+// panickingMailer panics while delivering one switch and otherwise records
+// like fakeMailer.
+type panickingMailer struct {
+	*fakeMailer
+	panicOn string
+}
+
+func (m panickingMailer) SendTriggeredMessage(settings models.Settings, msg models.Message, attachments []services.EmailAttachment) error {
+	if msg.ID == m.panicOn {
+		panic("smtp client exploded")
+	}
+	return m.fakeMailer.SendTriggeredMessage(settings, msg, attachments)
+}
+
+func TestPanickingSwitchDoesNotStopTheOthers(t *testing.T) {
+	db := setupTestDB(t)
+	for _, id := range []string{"a", "b", "c"} {
+		createMessage(t, db, id, time.Now().Add(-2*time.Hour))
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.email = panickingMailer{fakeMailer: mail, panicOn: "b"}
+	w.runTick()
+
+	if len(mail.triggered) != 2 {
+		t.Fatalf("expected the other two switches delivered, got %+v", mail.triggered)
+	}
+	for _, msg := range mail.triggered {
+		if msg.ID == "b" {
+			t.Fatalf("switch b panicked and must not be recorded as sent")
+		}
+	}
+	status := w.Status()
+	if status.LastError == nil || status.LastError.MessageID != "b" || !strings.Contains(status.LastError.Error, "smtp client exploded") {
+		t.Fatalf("expected the panic recorded against b, got %+v", status.LastError)
+	}
+	var b models.Message
+	if err := db.First(&b, "id = ?", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != models.StatusActive {
+		t.Fatalf("the panicking switch should stay active for the next tick, got %s", b.Status)
+	}
+}
+
+type ntfyPush struct {
+	topic, title, body, priority, click string
+}
+
+type fakeNtfy struct {
+	pushes []ntfyPush
+	err    error
+}
+
+func (f *fakeNtfy) Send(topicURL, title, message, priority, clickURL string) error {
+	f.pushes = append(f.pushes, ntfyPush{topic: topicURL, title: title, body: message, priority: priority, click: clickURL})
+	return f.err
+}
+
+func TestNtfyReminderNeverCarriesTheHeartbeatToken(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "m1", time.Now().Add(-50*time.Minute))
+	if err := db.Create(&models.MessageReminder{MessageID: "m1", MinutesBefore: 15, Channel: models.ReminderChannelNtfy}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mail := &fakeMailer{}
+	push := &fakeNtfy{}
+	w := newTestWorker(mail)
+	w.ntfy = push
+	w.settings = fakeSettings{settings: models.Settings{SMTPHost: "smtp.example.com", OwnerEmail: "owner@example.com", HeartbeatToken: "hb", NtfyURL: "https://ntfy.sh/aeterna-owner"}}
+	w.checkReminders()
+	w.checkReminders()
+
+	if len(push.pushes) != 1 || len(mail.plain) != 0 {
+		t.Fatalf("expected one push and no email across two ticks, got %+v / %+v", push.pushes, mail.plain)
+	}
+	got := push.pushes[0]
+	if got.topic != "https://ntfy.sh/aeterna-owner" || got.title != "Final Aeterna check-in required" || got.priority != services.NtfyPriorityUrgent {
+		t.Fatalf("unexpected push: %+v", got)
+	}
+	if got.click != "https://aeterna.example.com" || strings.Contains(got.click+got.body, "hb") {
+		t.Fatalf("the push must link to the dashboard, not the quick-heartbeat token: %+v", got)
+	}
+}
+
+func TestNtfyReminderFailureFallsBackToEmail(t *testing.T) {
+	db := setupTestDB(t)
+	createMessage(t, db, "m1", time.Now().Add(-50*time.Minute))
+	if err := db.Create(&models.MessageReminder{MessageID: "m1", MinutesBefore: 15, Channel: models.ReminderChannelNtfy}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mail := &fakeMailer{}
+	w := newTestWorker(mail)
+	w.ntfy = &fakeNtfy{err: errors.New("ntfy returned 502")}
+	w.settings = fakeSettings{settings: models.Settings{SMTPHost: "smtp.example.com", OwnerEmail: "owner@example.com", HeartbeatToken: "hb", NtfyURL: "https://ntfy.sh/aeterna-owner"}}
+	w.checkReminders()
+
+	if len(mail.plain) != 1 || mail.plain[0].subject != "Final check-in required" {
+		t.Fatalf("expected the reminder to fall back to email, got %+v", mail.plain)
+	}
+	var reminder models.MessageReminder
+	if err := db.First(&reminder, "message_id = ?", "m1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reminder.Sent {
+		t.Fatal("a reminder delivered by the email fallback should be marked sent")
+	}
+}
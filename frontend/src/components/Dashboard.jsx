@@ -132,7 +132,9 @@ export default function Dashboard() {
     const handleDelete = async (message) => {
         setActionLoading(message.id);
         try {
-            await apiRequest(`/messages/${message.id}`, {
+            // Delivered switches need force; the server keeps a record of the delivery.
+            const force = message.status === 'triggered' ? '?force=true' : '';
+            await apiRequest(`/messages/${message.id}${force}`, {
                 method: 'DELETE'
             });
             await fetchMessages();
@@ -621,7 +623,9 @@ export default function Dashboard() {
                                         <AlertDialogContent className="bg-dark-900 border-dark-700">
                                             <AlertDialogTitle>Delete Switch?</AlertDialogTitle>
                                             <AlertDialogDescription className="text-dark-400">
-                                                This will permanently delete this switch and all its attachments. The message will not be delivered.
+                                                {message.status === 'triggered'
+                                                    ? 'This switch has already been delivered. Its content and attachments will be deleted; a record of when and to whom it was delivered is kept.'
+                                                    : 'This will permanently delete this switch and all its attachments. The message will not be delivered.'}
                                             </AlertDialogDescription>
                                             <div className="flex justify-end gap-2 mt-4">
                                                 <AlertDialogCancel className="bg-dark-800 border-dark-700 text-dark-200 hover:bg-dark-700">Cancel</AlertDialogCancel>